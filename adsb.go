@@ -0,0 +1,279 @@
+// adsb.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// ADSBExchangeAPI
+
+// ADSBExchangeAPI is a LiveTrafficSource for real-world traffic that uses
+// an aggregator's HTTP API in the ADS-B Exchange v2 format, as provided by
+// adsb.lol and others.
+type ADSBExchangeAPI struct {
+	URL string // base URL; "/point/lat/lon/radius" is appended
+}
+
+const ADSBLolURL = "https://api.adsb.lol/v2"
+
+func NewADSBExchangeAPI(url string) *ADSBExchangeAPI {
+	return &ADSBExchangeAPI{URL: url}
+}
+
+func (a *ADSBExchangeAPI) Name() string { return "ADS-B" }
+
+func (a *ADSBExchangeAPI) PollInterval() time.Duration { return 5 * time.Second }
+
+func (a *ADSBExchangeAPI) Close() {}
+
+func (a *ADSBExchangeAPI) Fetch(center Point2LL, radius float32) ([]LiveTrack, error) {
+	// The API limits the radius to 250nm.
+	url := fmt.Sprintf("%s/point/%.4f/%.4f/%d", a.URL, center[1], center[0], int(min(radius, 250)))
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseADSBExchangeJSON(data, time.Now())
+}
+
+func parseADSBExchangeJSON(data []byte, now time.Time) ([]LiveTrack, error) {
+	var feed struct {
+		Aircraft []struct {
+			Hex     string   `json:"hex"`
+			Flight  string   `json:"flight"`
+			Type    string   `json:"t"`
+			AltBaro any      `json:"alt_baro"` // number or "ground"
+			GS      float32  `json:"gs"`
+			Track   float32  `json:"track"`
+			Squawk  string   `json:"squawk"`
+			Lat     *float32 `json:"lat"`
+			Lon     *float32 `json:"lon"`
+			SeenPos float32  `json:"seen_pos"`
+		} `json:"ac"`
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+
+	var tracks []LiveTrack
+	for _, ac := range feed.Aircraft {
+		if ac.Lat == nil || ac.Lon == nil {
+			continue
+		}
+
+		t := LiveTrack{
+			Callsign:     Select(strings.TrimSpace(ac.Flight) != "", strings.TrimSpace(ac.Flight), strings.ToUpper(ac.Hex)),
+			Position:     Point2LL{*ac.Lon, *ac.Lat},
+			Heading:      ac.Track,
+			Groundspeed:  ac.GS,
+			Updated:      now.Add(-time.Duration(ac.SeenPos * float32(time.Second))),
+			AircraftType: ac.Type,
+		}
+		if alt, ok := ac.AltBaro.(float64); ok {
+			t.Altitude = float32(alt)
+		}
+		if sq, err := ParseSquawk(ac.Squawk); err == nil {
+			t.Squawk, t.HaveSquawk = sq, true
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// SBSFeed
+
+// SBSFeed is a LiveTrafficSource that connects to a server that provides
+// ADS-B reports in the BaseStation (SBS) text format, such as a local
+// dump1090 receiver (which serves it on port 30003). Reports are read
+// continuously by a separate goroutine; Fetch returns the current state
+// of each aircraft.
+type SBSFeed struct {
+	address string
+
+	mu       sync.Mutex
+	aircraft map[string]*sbsAircraft // ICAO hex address -> state
+	err      error
+	done     chan struct{}
+	conn     net.Conn
+}
+
+type sbsAircraft struct {
+	LiveTrack
+	havePosition bool
+}
+
+// Aircraft that haven't been heard from in this long are dropped.
+const SBSAircraftTimeout = 60 * time.Second
+
+func NewSBSFeed(address string) *SBSFeed {
+	s := &SBSFeed{
+		address:  address,
+		aircraft: make(map[string]*sbsAircraft),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *SBSFeed) Name() string { return "SBS " + s.address }
+
+func (s *SBSFeed) PollInterval() time.Duration { return time.Second }
+
+func (s *SBSFeed) Close() {
+	close(s.done)
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.mu.Unlock()
+}
+
+// run reads reports from the server, reconnecting if the connection is
+// lost, until the SBSFeed is closed.
+func (s *SBSFeed) run() {
+	backoff := Backoff{Min: time.Second, Max: time.Minute}
+	for {
+		connected, err := s.read()
+		if connected {
+			backoff.Reset()
+		}
+
+		select {
+		case <-s.done:
+			return
+		default:
+			lg.Category(LogCategoryNetwork).Warnf("%s: %v", s.address, err)
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+
+		select {
+		case <-time.After(backoff.Next()):
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// read connects to the server and processes messages until the connection
+// is closed.
+func (s *SBSFeed) read() (connected bool, err error) {
+	conn, err := net.DialTimeout("tcp", s.address, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.conn, s.err = conn, nil
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		// Closed while we were connecting.
+		return true, nil
+	default:
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		s.mu.Lock()
+		s.handleMessage(scanner.Text(), time.Now())
+		s.mu.Unlock()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, fmt.Errorf("connection closed")
+}
+
+// handleMessage updates the aircraft state given a single SBS message;
+// s.mu must be held.
+func (s *SBSFeed) handleMessage(line string, now time.Time) {
+	// MSG,type,session,aircraft id,hex,flight id,date gen,time gen,date
+	// logged,time logged,callsign,altitude,ground speed,track,lat,lon,
+	// vertical rate,squawk,alert,emergency,spi,on ground
+	f := strings.Split(strings.TrimSpace(line), ",")
+	if len(f) < 18 || f[0] != "MSG" || f[4] == "" {
+		return
+	}
+
+	hex := strings.ToUpper(f[4])
+	ac, ok := s.aircraft[hex]
+	if !ok {
+		ac = &sbsAircraft{LiveTrack: LiveTrack{Callsign: hex}}
+		s.aircraft[hex] = ac
+	}
+	ac.Updated = now
+
+	parse := func(str string) (float32, bool) {
+		v, err := strconv.ParseFloat(str, 32)
+		return float32(v), err == nil
+	}
+
+	if cs := strings.TrimSpace(f[10]); cs != "" {
+		ac.Callsign = cs
+	}
+	if alt, ok := parse(f[11]); ok {
+		ac.Altitude = alt
+	}
+	if gs, ok := parse(f[12]); ok {
+		ac.Groundspeed = gs
+	}
+	if trk, ok := parse(f[13]); ok {
+		ac.Heading = trk
+	}
+	lat, latok := parse(f[14])
+	lon, lonok := parse(f[15])
+	if latok && lonok {
+		ac.Position = Point2LL{lon, lat}
+		ac.havePosition = true
+	}
+	if sq, err := ParseSquawk(f[17]); f[17] != "" && err == nil {
+		ac.Squawk, ac.HaveSquawk = sq, true
+	}
+}
+
+func (s *SBSFeed) Fetch(center Point2LL, radius float32) ([]LiveTrack, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var tracks []LiveTrack
+	for hex, ac := range s.aircraft {
+		if time.Since(ac.Updated) > SBSAircraftTimeout {
+			delete(s.aircraft, hex)
+		} else if ac.havePosition && nmdistance2ll(center, ac.Position) <= radius {
+			tracks = append(tracks, ac.LiveTrack)
+		}
+	}
+	return tracks, nil
+}
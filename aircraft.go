@@ -32,6 +32,9 @@ type Aircraft struct {
 	RedirectedHandoff         RedirectedHandoff
 	SPCOverrides              map[string]interface{}
 
+	// Outstanding coordination request (e.g., an APREQ), if any.
+	Coordination *CoordinationRequest
+
 	// The controller who gave approach clearance
 	ApproachController string
 
@@ -43,6 +46,7 @@ type Aircraft struct {
 
 	// Departure related state
 	Exit                       string
+	DepartureRunway            string
 	DepartureContactAltitude   float32
 	DepartureContactController string
 
@@ -55,6 +59,13 @@ type Aircraft struct {
 
 	// Who to try to hand off to at a waypoint with /ho
 	WaypointHandoffController string
+
+	// Abnormal situations set up by an instructor
+	NORDO     bool // radio failure: instructions are not received or read back
+	Emergency bool
+
+	// Pseudo-pilot flying the aircraft, if any
+	PseudoPilot string
 }
 
 type RedirectedHandoff struct {
@@ -63,6 +74,16 @@ type RedirectedHandoff struct {
 	RedirectedTo  string   // Controller callsign
 }
 
+// CoordinationRequest is a structured request from one controller to
+// another about an aircraft, e.g. "APREQ HEADING 360", that the receiving
+// controller accepts or denies.
+type CoordinationRequest struct {
+	FromController string // Controller callsign
+	ToController   string // Controller callsign
+	Request        string
+	Time           time.Time // Sim time when the request was made
+}
+
 type PilotResponse struct {
 	Message    string
 	Unexpected bool // should it be highlighted in the UI
@@ -202,6 +223,12 @@ func (ac *Aircraft) AssignAltitude(altitude int, afterSpeed bool) []RadioTransmi
 	return ac.transmitResponse(response)
 }
 
+func (ac *Aircraft) AssignSquawk(squawk Squawk) []RadioTransmission {
+	ac.AssignedSquawk = squawk
+	ac.Squawk = squawk
+	return ac.transmitResponse(PilotResponse{Message: "squawk " + squawk.String()})
+}
+
 func (ac *Aircraft) AssignSpeed(speed int, afterAltitude bool) []RadioTransmission {
 	resp := ac.Nav.AssignSpeed(float32(speed), afterAltitude)
 	return ac.transmitResponse(resp)
@@ -469,6 +496,7 @@ func (ac *Aircraft) InitializeDeparture(w *World, ap *Airport, departureAirport
 	}
 	ac.SecondaryScratchpad = dep.SecondaryScratchpad
 	ac.Exit = dep.Exit
+	ac.DepartureRunway = runway
 
 	if dep.Altitude == 0 {
 		ac.FlightPlan.Altitude = PlausibleFinalAltitude(w, ac.FlightPlan, perf)
@@ -31,9 +31,18 @@ type Airport struct {
 
 	ApproachRegions   map[string]*ApproachRegion `json:"approach_regions"`
 	ConvergingRunways []ConvergingRunways        `json:"converging_runways"`
+	ParallelRunways   []ParallelRunways          `json:"parallel_runways"`
 
 	ATPAVolumes           map[string]*ATPAVolume `json:"atpa_volumes"`
 	OmitArrivalScratchpad bool                   `json:"omit_arrival_scratchpad"`
+
+	// Optional: taxiway layout, used for the airport diagram.
+	Taxiways []Taxiway `json:"taxiways,omitempty"`
+}
+
+type Taxiway struct {
+	Name       string     `json:"name"`
+	Centerline []Point2LL `json:"centerline"`
 }
 
 type ConvergingRunways struct {
@@ -46,6 +55,15 @@ type ConvergingRunways struct {
 	RunwayIntersection     Point2LL                    // not in JSON, set during deserialize
 }
 
+// ParallelRunways describes a pair of runways used for simultaneous
+// independent approaches, with a no transgression zone (NTZ) between
+// their finals.
+type ParallelRunways struct {
+	Runways   [2]string `json:"runways"`
+	NTZWidth  float32   `json:"ntz_width"`  // feet; 2000 if not specified
+	NTZLength float32   `json:"ntz_length"` // nm from the thresholds; 15 if not specified
+}
+
 type ApproachRegion struct {
 	Runway           string  // set during deserialization
 	HeadingTolerance float32 `json:"heading_tolerance"`
@@ -85,6 +103,8 @@ type ATPAVolume struct {
 	ExcludedScratchpads []string `json:"excluded_scratchpads"`
 	Enable25nmApproach  bool     `json:"enable_2.5nm"`
 	Dist25nmApproach    float32  `json:"2.5nm_distance"`
+	GlidepathAngle      float32  `json:"glidepath_angle"` // degrees
+	ThresholdElevation  int      // set from the runway database
 }
 
 // returns a point along the reference line with given distance from the
@@ -132,6 +152,7 @@ type GhostAircraft struct {
 	Groundspeed         int
 	LeaderLineDirection CardinalOrdinalDirection
 	TrackId             string
+	PairIndex           int // index of the converging runway pair that generated it
 }
 
 func (ar *ApproachRegion) Inside(p Point2LL, alt float32, nmPerLongitude, magneticVariation float32) (lateral, vertical bool) {
@@ -154,34 +175,61 @@ func (ar *ApproachRegion) Inside(p Point2LL, alt float32, nmPerLongitude, magnet
 	return
 }
 
-func (ar *ApproachRegion) TryMakeGhost(callsign string, track RadarTrack, heading float32, scratchpad string,
-	forceGhost bool, offset float32, leaderDirection CardinalOrdinalDirection, runwayIntersection [2]float32,
-	nmPerLongitude float32, magneticVariation float32, other *ApproachRegion) *GhostAircraft {
+// GhostQualification records whether an aircraft qualifies to generate
+// a CRDA ghost for an approach region and, if not, why not.
+type GhostQualification int
+
+const (
+	GhostQualified = iota
+	GhostNotQualifiedLateral
+	GhostNotQualifiedHeading
+	GhostNotQualifiedAltitude
+	GhostNotQualifiedScratchpad
+)
+
+func (q GhostQualification) String() string {
+	return [...]string{"QUALIFIED", "OUTSIDE REGION", "HEADING", "ALTITUDE", "SCRATCHPAD"}[q]
+}
+
+// Qualify checks the approach region's qualification criteria for the
+// given track, returning the first one that it fails, if any.
+func (ar *ApproachRegion) Qualify(track RadarTrack, heading float32, scratchpad string, forceGhost bool,
+	nmPerLongitude float32, magneticVariation float32) GhostQualification {
 	// Start with lateral extent since even if it's forced, the aircraft still must be inside it.
 	lat, vert := ar.Inside(track.Position, float32(track.Altitude), nmPerLongitude, magneticVariation)
 	if !lat {
-		return nil
+		return GhostNotQualifiedLateral
 	}
 
 	if !forceGhost {
 		// Heading must be in range
 		if headingDifference(heading, ar.ReferenceLineHeading) > ar.HeadingTolerance {
-			return nil
+			return GhostNotQualifiedHeading
 		}
 
 		// Check vertical extent
 		if !vert {
-			return nil
+			return GhostNotQualifiedAltitude
 		}
 
 		if len(ar.ScratchpadPatterns) > 0 {
 			if !slices.ContainsFunc(ar.ScratchpadPatterns,
 				func(pat string) bool { return strings.Contains(scratchpad, pat) }) {
-				return nil
+				return GhostNotQualifiedScratchpad
 			}
 		}
 	}
 
+	return GhostQualified
+}
+
+func (ar *ApproachRegion) TryMakeGhost(callsign string, track RadarTrack, heading float32, scratchpad string,
+	forceGhost bool, offset float32, leaderDirection CardinalOrdinalDirection, runwayIntersection [2]float32,
+	nmPerLongitude float32, magneticVariation float32, other *ApproachRegion) *GhostAircraft {
+	if ar.Qualify(track, heading, scratchpad, forceGhost, nmPerLongitude, magneticVariation) != GhostQualified {
+		return nil
+	}
+
 	isectNm := ll2nm(runwayIntersection, nmPerLongitude)
 	remap := func(pll Point2LL) Point2LL {
 		// Switch to nm for transformations to compute ghost position
@@ -238,6 +286,13 @@ func (a *ATPAVolume) GetRect(nmPerLongitude, magneticVariation float32) [4]Point
 		nm2ll(quad[2], nmPerLongitude), nm2ll(quad[3], nmPerLongitude)}
 }
 
+// GlidepathAltitude returns the altitude of the nominal glidepath to the
+// runway at the given point.
+func (a *ATPAVolume) GlidepathAltitude(p Point2LL, nmPerLongitude float32) float32 {
+	d := nmdistance2ll(p, a.Threshold) * NauticalMilesToFeet
+	return float32(a.ThresholdElevation) + d*tan(radians(a.GlidepathAngle))
+}
+
 func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogger) {
 	if info, ok := database.Airports[icao]; !ok {
 		e.ErrorString("airport \"%s\" not found in airport database", icao)
@@ -249,6 +304,12 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 		e.ErrorString("Must specify \"location\" for airport")
 	}
 
+	for _, twy := range ap.Taxiways {
+		if len(twy.Centerline) < 2 {
+			e.ErrorString("taxiway \"%s\": must have at least two points in \"centerline\"", twy.Name)
+		}
+	}
+
 	for name, appr := range ap.Approaches {
 		e.Push("Approach " + name)
 
@@ -574,6 +635,24 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 		e.Pop()
 	}
 
+	for i, pair := range ap.ParallelRunways {
+		e.Push("Parallel runways " + pair.Runways[0] + "/" + pair.Runways[1])
+
+		for _, rwy := range pair.Runways {
+			if _, ok := LookupRunway(icao, rwy); !ok {
+				e.ErrorString("runway \"%s\" is unknown. Options: %s", rwy, database.Airports[icao].ValidRunways())
+			}
+		}
+		if pair.NTZWidth == 0 {
+			ap.ParallelRunways[i].NTZWidth = 2000
+		}
+		if pair.NTZLength == 0 {
+			ap.ParallelRunways[i].NTZLength = 15
+		}
+
+		e.Pop()
+	}
+
 	// Generate reasonable default ATPA volumes for any runways they aren't
 	// specified for.
 	if ap.ATPAVolumes == nil {
@@ -595,8 +674,10 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 
 		vol.Id = icao + rwy
 
-		if _, ok := LookupRunway(icao, rwy); !ok {
+		if r, ok := LookupRunway(icao, rwy); !ok {
 			e.ErrorString("runway \"%s\" is unknown. Options: %s", rwy, database.Airports[icao].ValidRunways())
+		} else {
+			vol.ThresholdElevation = Select(r.Elevation != 0, r.Elevation, database.Airports[icao].Elevation)
 		}
 
 		if vol.Threshold.IsZero() { // the location is set directly for default volumes
@@ -629,6 +710,9 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 		if vol.RightWidth == 0 {
 			vol.RightWidth = 2000
 		}
+		if vol.GlidepathAngle == 0 {
+			vol.GlidepathAngle = 3
+		}
 
 		e.Pop()
 	}
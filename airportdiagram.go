@@ -0,0 +1,304 @@
+// airportdiagram.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// AirportDiagramPane
+
+// AirportDiagramPane draws a north-up diagram of an airport's runways and,
+// if the scenario provides them, its taxiways, along with the aircraft
+// that are on or near the ground there.
+//
+// In surface movement mode, it gives an ASDE-X-style presentation: ground
+// tracks are drawn with their heading and an abbreviated datablock,
+// occupied runways are highlighted, and an alert is issued if an arrival
+// is within RunwayIncursionRange of a runway that is occupied.
+type AirportDiagramPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+
+	Airport string // ICAO
+
+	SurfaceMovement      bool
+	RunwayIncursionRange float32 // nm
+
+	airports []string // available airports, for the UI
+
+	// Incursions that have already been alerted, keyed by runway and the
+	// callsigns involved, so that the audio alert is only issued once.
+	alertedIncursions map[string]interface{}
+}
+
+func NewAirportDiagramPane() *AirportDiagramPane {
+	return &AirportDiagramPane{
+		FontIdentifier:       FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 14},
+		RunwayIncursionRange: 2,
+	}
+}
+
+func (ad *AirportDiagramPane) Name() string { return "Airport Diagram" }
+
+func (ad *AirportDiagramPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if ad.font = GetFont(ad.FontIdentifier); ad.font == nil {
+		ad.font = GetDefaultFont()
+		ad.FontIdentifier = ad.font.id
+	}
+	if w != nil {
+		ad.airports = SortedMapKeys(w.Airports)
+	}
+	if ad.RunwayIncursionRange == 0 {
+		ad.RunwayIncursionRange = 2
+	}
+	ad.alertedIncursions = make(map[string]interface{})
+}
+
+func (ad *AirportDiagramPane) Deactivate()                {}
+func (ad *AirportDiagramPane) CanTakeKeyboardFocus() bool { return false }
+
+func (ad *AirportDiagramPane) ResetWorld(w *World) {
+	ad.airports = SortedMapKeys(w.Airports)
+	if _, ok := w.Airports[ad.Airport]; !ok {
+		ad.Airport = ""
+		if len(w.ArrivalAirports) > 0 {
+			ad.Airport = SortedMapKeys(w.ArrivalAirports)[0]
+		} else if len(ad.airports) > 0 {
+			ad.Airport = ad.airports[0]
+		}
+	}
+}
+
+func (ad *AirportDiagramPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&ad.FontIdentifier, "Font"); changed {
+		ad.font = newFont
+	}
+	if imgui.BeginComboV("Airport", ad.Airport, imgui.ComboFlagsHeightLarge) {
+		for _, icao := range ad.airports {
+			if imgui.SelectableV(icao, icao == ad.Airport, 0, imgui.Vec2{}) {
+				ad.Airport = icao
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.Checkbox("Surface movement mode", &ad.SurfaceMovement)
+	if ad.SurfaceMovement {
+		imgui.SliderFloatV("Runway incursion alert range (nm)", &ad.RunwayIncursionRange, 0.5, 5, "%.1f", 0)
+	}
+}
+
+// runwaySegments returns the runways at the airport as pairs of
+// thresholds along with the runway identifiers at each end. Each runway is
+// only returned once, even though the database has entries for both of
+// its ends.
+func runwaySegments(icao string) (segs [][2]Point2LL, ids [][2]string) {
+	ap, ok := database.Airports[icao]
+	if !ok {
+		return
+	}
+
+	seen := make(map[string]interface{})
+	for _, rwy := range ap.Runways {
+		if _, ok := seen[rwy.Id]; ok {
+			continue
+		}
+		opp, ok := LookupOppositeRunway(icao, rwy.Id)
+		if !ok {
+			continue
+		}
+		seen[rwy.Id], seen[opp.Id] = nil, nil
+		segs = append(segs, [2]Point2LL{rwy.Threshold, opp.Threshold})
+		ids = append(ids, [2]string{rwy.Id, opp.Id})
+	}
+	return
+}
+
+func (ad *AirportDiagramPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	w := ctx.world
+	ap := w.GetAirport(ad.Airport)
+	if ap == nil {
+		return
+	}
+	nmPerLongitude := w.NmPerLongitude
+
+	rwys, rwyIds := runwaySegments(ad.Airport)
+
+	// Fit everything to the pane, preserving the aspect ratio.
+	e := EmptyExtent2D()
+	e = Union(e, ll2nm(ap.Location, nmPerLongitude))
+	for _, seg := range rwys {
+		e = Union(e, ll2nm(seg[0], nmPerLongitude))
+		e = Union(e, ll2nm(seg[1], nmPerLongitude))
+	}
+	for _, twy := range ap.Taxiways {
+		for _, p := range twy.Centerline {
+			e = Union(e, ll2nm(p, nmPerLongitude))
+		}
+	}
+	e = e.Expand(0.25)
+
+	margin := float32(2 * ad.font.size)
+	width, height := ctx.paneExtent.Width()-2*margin, ctx.paneExtent.Height()-2*margin
+	if width <= 0 || height <= 0 {
+		return
+	}
+	scale := min(width/e.Width(), height/e.Height())
+	center := e.Center()
+	windowFromLL := func(p Point2LL) [2]float32 {
+		pnm := sub2f(ll2nm(p, nmPerLongitude), center)
+		return [2]float32{ctx.paneExtent.Width()/2 + scale*pnm[0], ctx.paneExtent.Height()/2 + scale*pnm[1]}
+	}
+
+	rwyld := GetWideLinesDrawBuilder()
+	defer ReturnWideLinesDrawBuilder(rwyld)
+	twyld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(twyld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	twyColor := RGB{0.5, 0.5, 0.3}
+	for _, twy := range ap.Taxiways {
+		for i := 0; i+1 < len(twy.Centerline); i++ {
+			twyld.AddLine(windowFromLL(twy.Centerline[i]), windowFromLL(twy.Centerline[i+1]), twyColor)
+		}
+		if twy.Name != "" && len(twy.Centerline) > 0 {
+			mid := twy.Centerline[len(twy.Centerline)/2]
+			td.AddTextCentered(twy.Name, windowFromLL(mid), TextStyle{Font: ad.font, Color: twyColor})
+		}
+	}
+
+	for i, seg := range rwys {
+		p0, p1 := windowFromLL(seg[0]), windowFromLL(seg[1])
+		rwyld.AddLine(p0, p1, UITextColor)
+
+		// Label each end just beyond its threshold.
+		v := normalize2f(sub2f(p1, p0))
+		offset := scale2f(v, float32(ad.font.size))
+		style := TextStyle{Font: ad.font, Color: UITextHighlightColor}
+		td.AddTextCentered(rwyIds[i][0], sub2f(p0, offset), style)
+		td.AddTextCentered(rwyIds[i][1], add2f(p1, offset), style)
+	}
+
+	// Aircraft that are within the diagram and close to the ground.
+	elevation := float32(database.Airports[ad.Airport].Elevation)
+	for _, ac := range w.Aircraft {
+		if ac.Altitude() > elevation+1000 {
+			continue
+		}
+		pnm := ll2nm(ac.Position(), nmPerLongitude)
+		if !e.Inside(pnm) {
+			continue
+		}
+		p := windowFromLL(ac.Position())
+		rwyld.AddCircle(p, 3, 8, STARSSelectedAircraftColor)
+		label := ac.Callsign
+		if ad.SurfaceMovement {
+			// Heading line and abbreviated datablock.
+			hdg := ac.Heading() - w.MagneticVariation
+			v := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
+			rwyld.AddLine(p, add2f(p, scale2f(v, 12)), STARSSelectedAircraftColor)
+			if ac.FlightPlan != nil {
+				label += "\n" + ac.FlightPlan.BaseType()
+			}
+		}
+		td.AddText(label, add2f(p, [2]float32{5, 5}), TextStyle{Font: ad.font, Color: STARSSelectedAircraftColor})
+	}
+
+	if ad.SurfaceMovement {
+		ad.drawRunwayStatus(w, rwys, rwyIds, elevation, windowFromLL, rwyld, td, ctx.paneExtent)
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	cb.LineWidth(1)
+	twyld.GenerateCommands(cb)
+	rwyld.GenerateCommands(cb, 3)
+	td.GenerateCommands(cb)
+}
+
+// runwayIncursion records an arrival that is close to a runway that is
+// occupied by another aircraft.
+type runwayIncursion struct {
+	runway   string
+	arrival  *Aircraft
+	occupant *Aircraft
+}
+
+// drawRunwayStatus highlights the runways that are occupied, and draws
+// them in the alert color if there is a runway incursion.
+func (ad *AirportDiagramPane) drawRunwayStatus(w *World, rwys [][2]Point2LL, rwyIds [][2]string, elevation float32,
+	windowFromLL func(Point2LL) [2]float32, ld *WideLinesDrawBuilder, td *TextDrawBuilder, extent Extent2D) {
+	nmPerLongitude := w.NmPerLongitude
+
+	var incursions []runwayIncursion
+	for i, seg := range rwys {
+		p0, p1 := ll2nm(seg[0], nmPerLongitude), ll2nm(seg[1], nmPerLongitude)
+
+		// Aircraft on the ground within ~300' of the centerline occupy
+		// the runway.
+		var occupants []*Aircraft
+		for _, ac := range w.Aircraft {
+			if ac.IsAirborne() && ac.Altitude() > elevation+100 {
+				continue
+			}
+			if PointSegmentDistance(ll2nm(ac.Position(), nmPerLongitude), p0, p1) < 0.05 {
+				occupants = append(occupants, ac)
+			}
+		}
+		if len(occupants) == 0 {
+			continue
+		}
+
+		color := UICautionColor
+		for _, ac := range w.Aircraft {
+			appr := ac.Nav.Approach.Assigned
+			if appr == nil || ac.FlightPlan == nil || ac.FlightPlan.ArrivalAirport != ad.Airport ||
+				!ac.IsAirborne() || (appr.Runway != rwyIds[i][0] && appr.Runway != rwyIds[i][1]) {
+				continue
+			}
+			threshold := Select(appr.Runway == rwyIds[i][0], seg[0], seg[1])
+			if nmdistance2ll(ac.Position(), threshold) > ad.RunwayIncursionRange {
+				continue
+			}
+			for _, occ := range occupants {
+				if occ != ac {
+					incursions = append(incursions, runwayIncursion{runway: appr.Runway, arrival: ac, occupant: occ})
+					color = UIErrorColor
+				}
+			}
+		}
+
+		// Draw the occupied runway again on top in the status color.
+		ld.AddLine(windowFromLL(seg[0]), windowFromLL(seg[1]), color)
+	}
+
+	// Report the incursions at the top of the pane, with an audio alert
+	// for new ones.
+	seen := make(map[string]interface{})
+	y := extent.Height() - 1
+	for _, inc := range incursions {
+		id := inc.runway + " " + inc.arrival.Callsign + " " + inc.occupant.Callsign
+		seen[id] = nil
+		if _, ok := ad.alertedIncursions[id]; !ok {
+			ad.alertedIncursions[id] = nil
+			globalConfig.Audio.PlayOnce(AudioRunwayIncursion)
+		}
+
+		text := fmt.Sprintf("RWY %s INCURSION %s / %s", inc.runway, inc.arrival.Callsign, inc.occupant.Callsign)
+		td.AddText(text, [2]float32{2, y}, TextStyle{Font: ad.font, Color: UIErrorColor})
+		y -= float32(ad.font.size + 1)
+	}
+	for id := range ad.alertedIncursions {
+		if _, ok := seen[id]; !ok {
+			delete(ad.alertedIncursions, id)
+		}
+	}
+}
@@ -47,12 +47,18 @@ func printColumnHeader() {
 	fmt.Printf("\n")
 }
 
-func ParseARINC424(file []byte) (map[string]FAAAirport, map[string]Navaid, map[string]Fix) {
+func ParseARINC424(file []byte) (map[string]FAAAirport, map[string]Navaid, map[string]Fix, map[string][]Airway,
+	[]SpecialUseAirspace) {
 	start := time.Now()
 
 	airports := make(map[string]FAAAirport)
 	navaids := make(map[string]Navaid)
 	fixes := make(map[string]Fix)
+	airways := make(map[string][]Airway)
+	lastAirwaySeq := 0
+	var suas []SpecialUseAirspace
+	var suaSegments []suaBoundarySegment
+	lastSUAId, lastSUAMultiple := "", byte(0)
 
 	parseLLDigits := func(d, m, s []byte) float32 {
 		deg, err := strconv.Atoi(string(d))
@@ -189,8 +195,74 @@ func ParseARINC424(file []byte) (map[string]FAAAirport, map[string]Navaid, map[s
 					Id:       id,
 					Location: parseLatLong(line[32:41], line[41:51]),
 				}
+
+			case 'R': // enroute airway 4.1.6
+				if continuation := line[38]; continuation != '0' && continuation != '1' {
+					break
+				}
+				id := strings.TrimSpace(string(line[13:18]))
+				seq := parseInt(line[25:29])
+				fix := strings.TrimSpace(string(line[29:34]))
+
+				// The records for an airway are sorted by sequence
+				// number; airways that are made of disjoint pieces
+				// start over with a lower sequence number for each one.
+				awys := airways[id]
+				if n := len(awys); n == 0 || seq <= lastAirwaySeq {
+					airways[id] = append(awys, Airway{Name: id, Fixes: []string{fix}})
+				} else {
+					awys[n-1].Fixes = append(awys[n-1].Fixes, fix)
+				}
+				lastAirwaySeq = seq
+			}
+			// TODO: holding patterns, etc...
+
+		case 'U':
+			if line[5] != 'R' { // restrictive airspace 4.1.18
+				break
+			}
+			if continuation := line[24]; continuation != '0' && continuation != '1' {
+				break
+			}
+
+			// Region, type, and designation identify the airspace; the
+			// multiple code distinguishes its volumes.
+			id, multiple := string(line[6:19]), line[19]
+			if id != lastSUAId {
+				name := strings.TrimSpace(string(line[93:123]))
+				if name == "" {
+					name = strings.TrimSpace(string(line[9:19]))
+				}
+				suas = append(suas, SpecialUseAirspace{Name: name, Type: SUAType(line[8])})
+			}
+			sua := &suas[len(suas)-1]
+			if id != lastSUAId || multiple != lastSUAMultiple {
+				sua.Volumes = append(sua.Volumes, SUAVolume{})
+				suaSegments = nil
+			}
+			lastSUAId, lastSUAMultiple = id, multiple
+			vol := &sua.Volumes[len(sua.Volumes)-1]
+
+			if !empty(line[81:93]) {
+				vol.Floor = parseSUALimit(line[81:86])
+				vol.Ceiling = parseSUALimit(line[87:92])
+			}
+
+			seg := suaBoundarySegment{via: line[30]}
+			if seg.via != 'C' {
+				seg.p = parseLatLong(line[32:41], line[41:51])
+			}
+			if seg.via == 'C' || seg.via == 'L' || seg.via == 'R' {
+				seg.center = parseLatLong(line[51:60], line[60:70])
+				seg.radius = float32(parseInt(line[70:74])) / 10
+			}
+			suaSegments = append(suaSegments, seg)
+
+			if line[31] == 'E' { // end of the boundary
+				vol.Boundary = tessellateSUABoundary(suaSegments)
+				vol.Bounds = Extent2DFromPoints(vol.Boundary)
+				suaSegments = nil
 			}
-			// TODO: holding patterns, airways, etc...
 
 		case 'H': // Heliports
 			subsection := line[12]
@@ -298,7 +370,68 @@ func ParseARINC424(file []byte) (map[string]FAAAirport, map[string]Navaid, map[s
 		fmt.Printf("parsed ARINC242 in %s\n", time.Since(start))
 	}
 
-	return airports, navaids, fixes
+	return airports, navaids, fixes, airways, suas
+}
+
+// parseSUALimit parses a restrictive airspace altitude limit, which is
+// either a number of feet, a flight level, "GND", or "UNLTD".
+func parseSUALimit(s []byte) int {
+	switch strings.TrimSpace(string(s)) {
+	case "GND":
+		return 0
+	case "UNLTD":
+		return 99999
+	default:
+		return parseAltitude(s)
+	}
+}
+
+// suaBoundarySegment stores the information from a single restrictive
+// airspace boundary record.
+type suaBoundarySegment struct {
+	via    byte // 5.118: G/H: straight line, C: circle, L/R: counter/clockwise arc
+	p      Point2LL
+	center Point2LL
+	radius float32 // nm
+}
+
+// tessellateSUABoundary returns the polygon for an airspace boundary,
+// approximating arcs and circles with line segments.
+func tessellateSUABoundary(segs []suaBoundarySegment) [][2]float32 {
+	var pts [][2]float32
+	for i, seg := range segs {
+		nmPerLongitude := 60 * cos(radians(seg.center[1]))
+		// Returns the point at the given heading from the segment's center.
+		arcPoint := func(hdg float32) [2]float32 {
+			v := [2]float32{seg.radius * sin(radians(hdg)), seg.radius * cos(radians(hdg))}
+			return nm2ll(add2f(ll2nm(seg.center, nmPerLongitude), v), nmPerLongitude)
+		}
+
+		switch seg.via {
+		case 'C':
+			for hdg := float32(0); hdg < 360; hdg += 5 {
+				pts = append(pts, arcPoint(hdg))
+			}
+
+		case 'L', 'R':
+			pts = append(pts, seg.p)
+			next := segs[(i+1)%len(segs)].p
+			h0 := headingp2ll(seg.center, seg.p, nmPerLongitude, 0)
+			h1 := headingp2ll(seg.center, next, nmPerLongitude, 0)
+			// Degrees to sweep through, in the direction of the arc.
+			sweep := Select(seg.via == 'R', h1-h0, h0-h1)
+			for sweep < 0 {
+				sweep += 360
+			}
+			for d := float32(5); d < sweep; d += 5 {
+				pts = append(pts, arcPoint(Select(seg.via == 'R', h0+d, h0-d)))
+			}
+
+		default:
+			pts = append(pts, seg.p)
+		}
+	}
+	return pts
 }
 
 func tidyFAAApproachId(id string) string {
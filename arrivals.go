@@ -0,0 +1,279 @@
+// arrivals.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// ArrivalPane
+
+// ArrivalPane lists the inbound aircraft for each of the scenario's
+// arrival airports, sorted by distance from the field, along with their
+// landing sequence numbers for their runways. Clicking on an
+// arrival selects it, which highlights it on the radar scope.
+type ArrivalPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+
+	events   *EventsSubscription
+	selected string // callsign
+}
+
+func NewArrivalPane() *ArrivalPane {
+	return &ArrivalPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (ap *ArrivalPane) Name() string { return "Arrivals" }
+
+func (ap *ArrivalPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if ap.font = GetFont(ap.FontIdentifier); ap.font == nil {
+		ap.font = GetDefaultFont()
+		ap.FontIdentifier = ap.font.id
+	}
+	if ap.scrollbar == nil {
+		ap.scrollbar = NewVerticalScrollBar(4, false)
+	}
+	ap.events = eventStream.Subscribe()
+}
+
+func (ap *ArrivalPane) Deactivate() {
+	ap.events.Unsubscribe()
+	ap.events = nil
+}
+
+func (ap *ArrivalPane) ResetWorld(w *World)        { ap.selected = "" }
+func (ap *ArrivalPane) CanTakeKeyboardFocus() bool { return false }
+
+func (ap *ArrivalPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&ap.FontIdentifier, "Font"); changed {
+		ap.font = newFont
+	}
+}
+
+func (ap *ArrivalPane) processEvents(w *World) {
+	for _, event := range ap.events.Get() {
+		if event.Type == SelectedAircraftEvent && event.Callsign != ap.selected {
+			// Someone else changed the selection.
+			ap.selected = event.Callsign
+		}
+	}
+	if _, ok := w.Aircraft[ap.selected]; !ok {
+		ap.selected = ""
+	}
+}
+
+func (ap *ArrivalPane) selectAircraft(ac *Aircraft) {
+	if ap.selected == ac.Callsign {
+		ap.selected = ""
+	} else {
+		ap.selected = ac.Callsign
+		HighlightLocation(ac.Position(), 3*time.Second)
+	}
+	ap.events.PostEvent(Event{Type: SelectedAircraftEvent, Callsign: ap.selected})
+}
+
+func (ap *ArrivalPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	ap.processEvents(ctx.world)
+
+	type arrival struct {
+		ac       *Aircraft
+		distance float32
+	}
+	arrivals := make(map[string][]arrival)
+	for _, ac := range ctx.world.Aircraft {
+		if ac.IsDeparture() || ac.FlightPlan == nil {
+			continue
+		}
+		if _, ok := ctx.world.ArrivalAirports[ac.FlightPlan.ArrivalAirport]; !ok {
+			continue
+		}
+		d := nmdistance2ll(ac.Position(), ac.Nav.FlightState.ArrivalAirportLocation)
+		arrivals[ac.FlightPlan.ArrivalAirport] = append(arrivals[ac.FlightPlan.ArrivalAirport],
+			arrival{ac: ac, distance: d})
+	}
+
+	sequence := ComputeArrivalSequence(ctx.world)
+
+	type line struct {
+		text   string
+		ac     *Aircraft // nil for airport headers and blank lines
+		header bool
+	}
+	var lines []line
+	for _, airport := range SortedMapKeys(arrivals) {
+		if len(lines) > 0 {
+			lines = append(lines, line{})
+		}
+		lines = append(lines, line{text: airport, header: true})
+
+		arr := arrivals[airport]
+		slices.SortFunc(arr, func(a, b arrival) int {
+			if a.distance != b.distance {
+				return Select(a.distance < b.distance, -1, 1)
+			}
+			return strings.Compare(a.ac.Callsign, b.ac.Callsign)
+		})
+		for _, a := range arr {
+			rwy, appr := "", ""
+			if a.ac.Nav.Approach.Assigned != nil {
+				rwy = a.ac.Nav.Approach.Assigned.Runway
+				appr = a.ac.Nav.Approach.AssignedId
+			}
+			seq := "   "
+			if e, ok := sequence[a.ac.Callsign]; ok {
+				seq = fmt.Sprintf("#%-2d", e.Number)
+				rwy = e.Runway
+			}
+			text := fmt.Sprintf("%s %-8s %-4s %5.1f %-4s %s", seq, a.ac.Callsign, a.ac.FlightPlan.TypeWithoutSuffix(),
+				a.distance, rwy, appr)
+			lines = append(lines, line{text: text, ac: a.ac})
+		}
+	}
+
+	lineHeight := float32(ap.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	ap.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	headerStyle := TextStyle{Font: ap.font, Color: UITextHighlightColor}
+	textStyle := TextStyle{Font: ap.font, Color: UITextColor}
+	selectedStyle := TextStyle{Font: ap.font, Color: STARSSelectedAircraftColor}
+
+	indent := float32(2)
+	y := ctx.paneExtent.Height() - 1
+	for _, l := range lines[ap.scrollbar.Offset():] {
+		style := textStyle
+		if l.header {
+			style = headerStyle
+		} else if l.ac != nil {
+			if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+				if p := ctx.mouse.Pos; p[1] <= y && p[1] > y-lineHeight {
+					ap.selectAircraft(l.ac)
+				}
+			}
+			if l.ac.Callsign == ap.selected {
+				style = selectedStyle
+			}
+		}
+		td.AddText(l.text, [2]float32{indent, y}, style)
+
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	ap.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Arrival sequencing
+
+// ArrivalSequenceEntry gives an arrival's position in the landing
+// sequence for its runway.
+type ArrivalSequenceEntry struct {
+	Airport, Runway string
+	Number          int     // 1-based
+	Distance        float32 // nm to the threshold
+	ETA             float32 // minutes to the threshold
+}
+
+// ComputeArrivalSequence assigns landing sequence numbers for each runway
+// in use at the scenario's arrival airports, ordering the arrivals by
+// their estimated time to the threshold. Aircraft that have been assigned
+// an approach are sequenced to its runway; others are sequenced to the
+// airport's first active arrival runway. The sequence is recomputed from
+// scratch each time, so it reflects vectoring and speed changes.
+func ComputeArrivalSequence(w *World) map[string]ArrivalSequenceEntry {
+	type key struct{ airport, runway string }
+	sequences := make(map[key][]ArrivalSequenceEntry)
+	callsigns := make(map[key][]string)
+
+	for callsign, ac := range w.Aircraft {
+		if ac.IsDeparture() || ac.FlightPlan == nil || !ac.IsAirborne() {
+			continue
+		}
+		airport := ac.FlightPlan.ArrivalAirport
+		if _, ok := w.ArrivalAirports[airport]; !ok {
+			continue
+		}
+
+		runway := ""
+		if appr := ac.Nav.Approach.Assigned; appr != nil {
+			runway = appr.Runway
+		} else if idx := slices.IndexFunc(w.ArrivalRunways,
+			func(r ScenarioGroupArrivalRunway) bool { return r.Airport == airport }); idx != -1 {
+			runway = w.ArrivalRunways[idx].Runway
+		}
+		rwy, ok := LookupRunway(airport, runway)
+		if !ok {
+			continue
+		}
+
+		d := arrivalDistanceToThreshold(ac, rwy.Threshold)
+		gs := max(ac.Nav.FlightState.GS, 100)
+		k := key{airport: airport, runway: runway}
+		sequences[k] = append(sequences[k], ArrivalSequenceEntry{
+			Airport:  airport,
+			Runway:   runway,
+			Distance: d,
+			ETA:      60 * d / gs,
+		})
+		callsigns[k] = append(callsigns[k], callsign)
+	}
+
+	result := make(map[string]ArrivalSequenceEntry)
+	for k, seq := range sequences {
+		idx := make([]int, len(seq))
+		for i := range idx {
+			idx[i] = i
+		}
+		slices.SortFunc(idx, func(a, b int) int {
+			if seq[a].ETA != seq[b].ETA {
+				return Select(seq[a].ETA < seq[b].ETA, -1, 1)
+			}
+			return strings.Compare(callsigns[k][a], callsigns[k][b])
+		})
+		for n, i := range idx {
+			e := seq[i]
+			e.Number = n + 1
+			result[callsigns[k][i]] = e
+		}
+	}
+	return result
+}
+
+// arrivalDistanceToThreshold returns the distance the aircraft has to fly
+// to the given threshold: along its route if it's following one and
+// otherwise direct.
+func arrivalDistanceToThreshold(ac *Aircraft, threshold Point2LL) float32 {
+	nav := &ac.Nav
+	wps := nav.Waypoints
+	if nav.Heading.Assigned != nil || len(wps) < 2 {
+		return nmdistance2ll(ac.Position(), threshold)
+	}
+
+	// The last waypoint is the airport itself; skip it.
+	d := nmdistance2ll(ac.Position(), wps[0].Location)
+	for i := 0; i < len(wps)-2; i++ {
+		d += nmdistance2ll(wps[i].Location, wps[i+1].Location)
+	}
+	return d + nmdistance2ll(wps[len(wps)-2].Location, threshold)
+}
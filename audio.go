@@ -32,6 +32,10 @@ const (
 	AudioInboundHandoff
 	AudioCommandError
 	AudioHandoffAccepted
+	AudioMessageAlert
+	AudioRunwayIncursion
+	AudioTriggerAlert
+	AudioReminder
 	AudioNumTypes
 )
 
@@ -44,6 +48,10 @@ func (ae AudioType) String() string {
 		"Inbound Handoff",
 		"Command Error",
 		"Handoff Accepted",
+		"Message Alert",
+		"Runway Incursion",
+		"Alert Trigger",
+		"Reminder",
 	}[ae]
 }
 
@@ -143,20 +151,20 @@ func audioCallback(user unsafe.Pointer, ptr *C.uint8, size C.int) {
 func (a *AudioEngine) loadMP3(filename string) AudioEffect {
 	dec, pcm, err := minimp3.DecodeFull(LoadResource("audio/" + filename))
 	if err != nil {
-		lg.Errorf("%s: unable to decode mp3: %v", filename, err)
+		lg.Category(LogCategoryAudio).Errorf("%s: unable to decode mp3: %v", filename, err)
 	}
 	if dec.SampleRate != AudioSampleRate {
-		lg.Errorf("expected %d Hz sample rate, got %d", AudioSampleRate, dec.SampleRate)
+		lg.Category(LogCategoryAudio).Errorf("expected %d Hz sample rate, got %d", AudioSampleRate, dec.SampleRate)
 	}
 	if dec.Channels != 1 {
-		lg.Errorf("expected 1 channel, got %d", dec.Channels)
+		lg.Category(LogCategoryAudio).Errorf("expected 1 channel, got %d", dec.Channels)
 	}
 
 	return AudioEffect{pcm: pcm}
 }
 
 func (a *AudioEngine) Activate() error {
-	lg.Info("Starting to initialize audio")
+	lg.Category(LogCategoryAudio).Info("Starting to initialize audio")
 
 	spec := sdl.AudioSpec{
 		Freq:     AudioSampleRate,
@@ -175,8 +183,12 @@ func (a *AudioEngine) Activate() error {
 	a.effects[AudioInboundHandoff] = a.loadMP3("263124__pan14__sine-octaves-up-beep.mp3")
 	a.effects[AudioCommandError] = a.loadMP3("426888__thisusernameis__beep4.mp3")
 	a.effects[AudioHandoffAccepted] = a.loadMP3("321104__nsstudios__blip2.mp3")
+	a.effects[AudioMessageAlert] = a.loadMP3("263124__pan14__sine-octaves-up-beep.mp3")
+	a.effects[AudioRunwayIncursion] = a.loadMP3("ca.mp3")
+	a.effects[AudioTriggerAlert] = a.loadMP3("263124__pan14__sine-octaves-up-beep.mp3")
+	a.effects[AudioReminder] = a.loadMP3("321104__nsstudios__blip2.mp3")
 
-	lg.Info("Finished initializing audio")
+	lg.Category(LogCategoryAudio).Info("Finished initializing audio")
 	return nil
 }
 
@@ -186,7 +198,8 @@ func (a *AudioEngine) DrawUI() {
 
 	uiStartDisable(!a.AudioEnabled)
 	// Not all of the ones available in the engine are used, so only offer these up:
-	for _, i := range []AudioType{AudioConflictAlert, AudioInboundHandoff, AudioHandoffAccepted, AudioCommandError} {
+	for _, i := range []AudioType{AudioConflictAlert, AudioInboundHandoff, AudioHandoffAccepted, AudioCommandError,
+		AudioRunwayIncursion, AudioTriggerAlert, AudioReminder} {
 		if imgui.Checkbox(AudioType(i).String(), &a.EffectEnabled[i]) && a.EffectEnabled[i] {
 			n := Select(i == AudioConflictAlert, 5, 1)
 			for j := 0; j < n; j++ {
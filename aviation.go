@@ -12,6 +12,7 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -338,6 +339,36 @@ func StringIsSPC(code string) bool {
 	return slices.ContainsFunc(spcs, func(spc SPC) bool { return spc.Code == code })
 }
 
+// EmergencyCondition returns a code describing an aircraft's emergency or
+// priority-handling condition: "HJ", "RF", or "EM" for the corresponding
+// squawk codes and "MED" for medevac (lifeguard) flights, which are
+// identified either by their callsign or their flight plan remarks. The
+// empty string is returned if there is no such condition.
+func EmergencyCondition(squawk Squawk, callsign string, remarks string) string {
+	switch squawk {
+	case Squawk(0o7500):
+		return "HJ"
+	case Squawk(0o7600):
+		return "RF"
+	case Squawk(0o7700):
+		return "EM"
+	}
+
+	// Civil medevac flights may file with an "L" prefixed to their
+	// registration number.
+	if strings.HasPrefix(callsign, "MEDEVAC") || strings.HasPrefix(callsign, "LIFEGUARD") ||
+		(len(callsign) > 2 && callsign[0] == 'L' && callsign[1] == 'N' && callsign[2] >= '1' && callsign[2] <= '9') {
+		return "MED"
+	}
+	remarks = strings.ToUpper(remarks)
+	for _, r := range []string{"MEDEVAC", "LIFEGUARD", "STS/HOSP"} {
+		if strings.Contains(remarks, r) {
+			return "MED"
+		}
+	}
+	return ""
+}
+
 type RadarTrack struct {
 	Position    Point2LL
 	Altitude    int
@@ -387,6 +418,11 @@ type Fix struct {
 	Location Point2LL
 }
 
+type Airway struct {
+	Name  string
+	Fixes []string
+}
+
 func NewFlightPlan(r FlightRules, ac, dep, arr string) *FlightPlan {
 	return &FlightPlan{
 		Rules:            r,
@@ -424,6 +460,23 @@ func (fp FlightPlan) TypeWithoutSuffix() string {
 	}
 }
 
+// EquipmentSuffix returns the equipment suffix (e.g., "L") from the
+// aircraft type, or an empty string if there isn't one.
+func (fp FlightPlan) EquipmentSuffix() string {
+	actypeFields := strings.Split(fp.AircraftType, "/")
+	switch len(actypeFields) {
+	case 3:
+		return actypeFields[2]
+	case 2:
+		if actypeFields[0] == "H" || actypeFields[0] == "S" || actypeFields[0] == "J" {
+			return ""
+		}
+		return actypeFields[1]
+	default:
+		return ""
+	}
+}
+
 func PlausibleFinalAltitude(w *World, fp *FlightPlan, perf AircraftPerformance) (altitude int) {
 	// try to figure out direction of flight
 	dep, dok := database.Airports[fp.DepartureAirport]
@@ -1374,7 +1427,8 @@ type StaticDatabase struct {
 	Navaids             map[string]Navaid
 	Airports            map[string]FAAAirport
 	Fixes               map[string]Fix
-	Callsigns           map[string]string // 3 letter -> callsign
+	Airways             map[string][]Airway // some airways are made of multiple disjoint pieces
+	Callsigns           map[string]string   // 3 letter -> callsign
 	AircraftTypeAliases map[string]string
 	AircraftPerformance map[string]AircraftPerformance
 	Airlines            map[string]Airline
@@ -1382,6 +1436,7 @@ type StaticDatabase struct {
 	ARTCCs              map[string]ARTCC
 	TRACONs             map[string]TRACON
 	MVAs                map[string][]MVA // TRACON -> MVAs
+	SUAs                []SpecialUseAirspace
 }
 
 func (d StaticDatabase) LookupWaypoint(f string) (Point2LL, bool) {
@@ -1394,6 +1449,72 @@ func (d StaticDatabase) LookupWaypoint(f string) (Point2LL, bool) {
 	}
 }
 
+// ExpandRoute expands the airways in the given route into their
+// constituent fixes and checks that the fixes are known. "DCT" is
+// dropped, and procedures (identifiers that end with a digit, like
+// "CAMRN4") are passed through as is. All of the unknown or invalid
+// elements are reported in the returned error.
+func (d StaticDatabase) ExpandRoute(route string) ([]string, error) {
+	var result []string
+	var errs []string
+
+	fields := strings.Fields(strings.ToUpper(route))
+	for i, f := range fields {
+		if f == "DCT" {
+			continue
+		}
+		if _, ok := d.LookupWaypoint(f); ok {
+			result = append(result, f)
+			continue
+		}
+		if _, ok := d.Airports[f]; ok {
+			result = append(result, f)
+			continue
+		}
+		if awys, ok := d.Airways[f]; ok {
+			if i == 0 || i == len(fields)-1 {
+				errs = append(errs, f+": airway must be between two fixes")
+				continue
+			}
+			if fixes, ok := expandAirway(awys, fields[i-1], fields[i+1]); ok {
+				result = append(result, fixes...)
+			} else {
+				errs = append(errs, fmt.Sprintf("%s: %s and %s not both on airway", f, fields[i-1], fields[i+1]))
+			}
+			continue
+		}
+		if f[len(f)-1] >= '0' && f[len(f)-1] <= '9' {
+			// Presumably a SID or STAR
+			result = append(result, f)
+			continue
+		}
+		errs = append(errs, f+": unknown fix")
+	}
+
+	if len(errs) > 0 {
+		return result, errors.New(strings.Join(errs, ", "))
+	}
+	return result, nil
+}
+
+// expandAirway returns the fixes strictly between from and to along one of
+// the provided airway pieces, in order.
+func expandAirway(awys []Airway, from, to string) ([]string, bool) {
+	for _, awy := range awys {
+		i, j := slices.Index(awy.Fixes, from), slices.Index(awy.Fixes, to)
+		if i == -1 || j == -1 {
+			continue
+		}
+		if i <= j {
+			return DuplicateSlice(awy.Fixes[min(i+1, j):j]), true
+		}
+		fixes := DuplicateSlice(awy.Fixes[j+1 : i])
+		slices.Reverse(fixes)
+		return fixes, true
+	}
+	return nil, false
+}
+
 type AircraftPerformance struct {
 	Name string `json:"name"`
 	ICAO string `json:"icao"`
@@ -1429,6 +1550,111 @@ type AircraftPerformance struct {
 	} `json:"speed"`
 }
 
+///////////////////////////////////////////////////////////////////////////
+// Wake turbulence
+
+// WakeStandard specifies which wake turbulence categories and separation
+// minima are used.
+type WakeStandard int
+
+const (
+	// FAA consolidated wake turbulence (CWT) categories A-I (7110.126B).
+	WakeStandardCWT WakeStandard = iota
+	// RECAT-EU categories A-F.
+	WakeStandardRECATEU
+)
+
+func (ws WakeStandard) String() string {
+	return [...]string{"FAA CWT", "RECAT-EU"}[ws]
+}
+
+// CWT returns the aircraft's consolidated wake turbulence category. If
+// the performance database doesn't provide one, it is derived from the
+// aircraft's weight class. The empty string is returned if neither is
+// available.
+func (perf AircraftPerformance) CWT() string {
+	if cwt := perf.Category.CWT; len(cwt) == 1 && cwt[0] >= 'A' && cwt[0] <= 'I' {
+		return cwt
+	}
+	switch perf.WeightClass {
+	case "J":
+		return "A"
+	case "H":
+		return "D"
+	case "M":
+		return "E"
+	case "L":
+		return "F"
+	case "S+":
+		return "H"
+	case "S":
+		return "I"
+	default:
+		return ""
+	}
+}
+
+// WakeCategory returns the aircraft's wake turbulence category under the
+// given standard, or the empty string if it is unknown.
+func (perf AircraftPerformance) WakeCategory(ws WakeStandard) string {
+	cwt := perf.CWT()
+	if cwt == "" || ws == WakeStandardCWT {
+		return cwt
+	}
+	// RECAT-EU: super heavy, upper/lower heavy, upper/lower medium, and
+	// light. CWT D (non-pairwise heavy) is conservatively taken as a lower
+	// heavy and the B757 as an upper medium.
+	return string("ABCCDDEFF"[cwt[0]-'A'])
+}
+
+// WakeSeparation returns the required in-trail separation on approach in
+// nm for an aircraft of category back following one of category front
+// under the given wake standard. Zero is returned when only minimum radar
+// separation is required. Unknown categories require 10nm (7110.762).
+func WakeSeparation(ws WakeStandard, front, back string) float32 {
+	index := func(cat string, n int) int {
+		if len(cat) != 1 || cat[0] < 'A' || int(cat[0]-'A') >= n {
+			return n
+		}
+		return int(cat[0] - 'A')
+	}
+
+	if ws == WakeStandardRECATEU {
+		recatEUOnApproach := [7][7]float32{ // [front][back]
+			{0, 4, 5, 5, 6, 8, 10},       // Behind A
+			{0, 0, 4, 4, 5, 7, 10},       // Behind B
+			{0, 0, 0, 0, 4, 6, 10},       // Behind C
+			{0, 0, 0, 0, 0, 5, 10},       // Behind D
+			{0, 0, 0, 0, 0, 4, 10},       // Behind E
+			{0, 0, 0, 0, 0, 0, 10},       // Behind F
+			{10, 10, 10, 10, 10, 10, 10}, // Behind unknown
+		}
+		return recatEUOnApproach[index(front, 6)][index(back, 6)]
+	}
+
+	// 7110.126B TBL 5-5-2
+	cwtOnApproach := [10][10]float32{ // [front][back]
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind I
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind H
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind G
+		{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind F
+		{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind E
+		{6, 6, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind D
+		{6, 5, 3.5, 3.5, 3.5, 0, 0, 0, 0, 10},    // Behind C
+		{6, 5, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind B
+		{8, 8, 7, 7, 7, 6, 6, 5, 0, 10},          // Behind A
+		{10, 10, 10, 10, 10, 10, 10, 10, 10, 10}, // Behind NOWGT (No weight: 7110.762)
+	}
+	// The table is ordered I-A, so flip the indices.
+	cwtIndex := func(cat string) int {
+		if i := index(cat, 9); i < 9 {
+			return 8 - i
+		}
+		return 9
+	}
+	return cwtOnApproach[cwtIndex(front)][cwtIndex(back)]
+}
+
 type Airline struct {
 	ICAO     string `json:"icao"`
 	Name     string `json:"name"`
@@ -1458,7 +1684,7 @@ func InitializeStaticDatabase() *StaticDatabase {
 	go func() { db.Airlines, db.Callsigns = parseAirlines(); wg.Done() }()
 	var airports map[string]FAAAirport
 	wg.Add(1)
-	go func() { airports, db.Navaids, db.Fixes = parseCIFP(); wg.Done() }()
+	go func() { airports, db.Navaids, db.Fixes, db.Airways, db.SUAs = parseCIFP(); wg.Done() }()
 	wg.Add(1)
 	go func() { db.MagneticGrid = parseMagneticGrid(); wg.Done() }()
 	wg.Add(1)
@@ -1648,7 +1874,7 @@ func parseAirlines() (map[string]Airline, map[string]string) {
 
 // FAA Coded Instrument Flight Procedures (CIFP)
 // https://www.faa.gov/air_traffic/flight_info/aeronav/digital_products/cifp/download/
-func parseCIFP() (map[string]FAAAirport, map[string]Navaid, map[string]Fix) {
+func parseCIFP() (map[string]FAAAirport, map[string]Navaid, map[string]Fix, map[string][]Airway, []SpecialUseAirspace) {
 	cifp, err := fs.ReadFile(resourcesFS, "FAACIFP18.zst")
 	if err != nil {
 		panic(err)
@@ -1748,6 +1974,124 @@ func (m *MVA) Inside(p [2]float32) bool {
 	return true
 }
 
+///////////////////////////////////////////////////////////////////////////
+// SpecialUseAirspace
+
+// SpecialUseAirspace represents a restricted area, MOA, or other
+// restrictive airspace from the CIFP. Some are made of multiple volumes
+// with different boundaries or altitude limits.
+type SpecialUseAirspace struct {
+	Name    string // e.g., "R-2508" or "BIRCH MOA"
+	Type    SUAType
+	Volumes []SUAVolume
+}
+
+type SUAVolume struct {
+	Floor, Ceiling int // feet; floors are sometimes given AGL
+	Bounds         Extent2D
+	Boundary       [][2]float32 // lat-long
+}
+
+// SUAType is the restrictive airspace type, as given in the CIFP.
+type SUAType byte
+
+const (
+	SUAAlert       SUAType = 'A'
+	SUACaution     SUAType = 'C'
+	SUADanger      SUAType = 'D'
+	SUAMOA         SUAType = 'M'
+	SUAProhibited  SUAType = 'P'
+	SUARestricted  SUAType = 'R'
+	SUATraining    SUAType = 'T'
+	SUAWarning     SUAType = 'W'
+	SUAUnspecified SUAType = 'U'
+)
+
+func (t SUAType) String() string {
+	switch t {
+	case SUAAlert:
+		return "Alert"
+	case SUACaution:
+		return "Caution"
+	case SUADanger:
+		return "Danger"
+	case SUAMOA:
+		return "MOA"
+	case SUAProhibited:
+		return "Prohibited"
+	case SUARestricted:
+		return "Restricted"
+	case SUATraining:
+		return "Training"
+	case SUAWarning:
+		return "Warning"
+	default:
+		return "Unspecified"
+	}
+}
+
+// Inside returns the volume of the airspace that includes the given
+// point and altitude, if any.
+func (s *SpecialUseAirspace) Inside(p Point2LL, alt int) (*SUAVolume, bool) {
+	for i, v := range s.Volumes {
+		if alt >= v.Floor && alt <= v.Ceiling && v.Bounds.Inside(p) && PointInPolygon(p, v.Boundary) {
+			return &s.Volumes[i], true
+		}
+	}
+	return nil, false
+}
+
+// Bounds returns the lat-long bounding box of all of the airspace's
+// volumes.
+func (s *SpecialUseAirspace) Bounds() Extent2D {
+	e := EmptyExtent2D()
+	for _, v := range s.Volumes {
+		e = Union(Union(e, v.Bounds.p0), v.Bounds.p1)
+	}
+	return e
+}
+
+// ParseSUASchedule parses a schedule of the form "1400-2200,0000-0200",
+// where the times are Zulu, returning the corresponding intervals in
+// minutes after midnight. An interval may span midnight.
+func ParseSUASchedule(s string) ([][2]int, error) {
+	var sched [][2]int
+	for _, interval := range strings.Split(s, ",") {
+		interval = strings.TrimSpace(interval)
+		if interval == "" {
+			continue
+		}
+
+		start, end, ok := strings.Cut(interval, "-")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected HHMM-HHMM", interval)
+		}
+		var iv [2]int
+		for i, t := range [2]string{start, end} {
+			hhmm, err := strconv.Atoi(t)
+			if err != nil || len(t) != 4 || hhmm/100 > 23 || hhmm%100 > 59 {
+				return nil, fmt.Errorf("%s: invalid time", t)
+			}
+			iv[i] = 60*(hhmm/100) + hhmm%100
+		}
+		sched = append(sched, iv)
+	}
+	return sched, nil
+}
+
+// SUAScheduleActive returns true if the given time is in one of the
+// schedule's intervals.
+func SUAScheduleActive(sched [][2]int, t time.Time) bool {
+	t = t.UTC()
+	m := 60*t.Hour() + t.Minute()
+	return slices.ContainsFunc(sched, func(iv [2]int) bool {
+		if iv[0] <= iv[1] {
+			return m >= iv[0] && m < iv[1]
+		}
+		return m >= iv[0] || m < iv[1] // spans midnight
+	})
+}
+
 type MVALinearRing struct {
 	PosList string `xml:"posList"`
 }
@@ -2436,6 +2780,15 @@ func (ml *VideoMapLibrary) loadVideoMap(f io.ReadCloser, filename string, refere
 				ld.AddLineStrip(fl)
 			}
 			ld.GenerateCommands(&sm.CommandBuffer)
+
+			// And a simplified version for large ranges.
+			ld.Reset()
+			for _, lines := range sm.Lines {
+				fl := MapSlice(lines, func(p Point2LL) [2]float32 { return p })
+				ld.AddLineStrip(SimplifyPolyline(fl, STARSMapSimplifyTolerance))
+			}
+			ld.GenerateCommands(&sm.simplifiedCommandBuffer)
+			ReturnLinesDrawBuilder(ld)
 		}
 
 		// Clear out Lines so that the memory can be reclaimed since they
@@ -5,7 +5,9 @@
 package main
 
 import (
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestFrequencyFormat(t *testing.T) {
@@ -24,6 +26,143 @@ func TestFrequencyFormat(t *testing.T) {
 	}
 }
 
+func TestEquipmentSuffix(t *testing.T) {
+	for _, tc := range [][3]string{
+		{"B738/L", "B738", "L"},
+		{"H/B744/L", "H/B744", "L"},
+		{"H/B744", "H/B744", ""},
+		{"C172", "C172", ""},
+	} {
+		fp := FlightPlan{AircraftType: tc[0]}
+		if ty := fp.TypeWithoutSuffix(); ty != tc[1] {
+			t.Errorf("%s: got type %q; expected %q", tc[0], ty, tc[1])
+		}
+		if suffix := fp.EquipmentSuffix(); suffix != tc[2] {
+			t.Errorf("%s: got suffix %q; expected %q", tc[0], suffix, tc[2])
+		}
+	}
+}
+
+func TestExpandRoute(t *testing.T) {
+	db := StaticDatabase{
+		Fixes: map[string]Fix{"AAA": Fix{Id: "AAA"}, "BBB": Fix{Id: "BBB"}, "CCC": Fix{Id: "CCC"},
+			"DDD": Fix{Id: "DDD"}, "EEE": Fix{Id: "EEE"}},
+		Airports: map[string]FAAAirport{"KJFK": FAAAirport{Id: "KJFK"}},
+		Airways: map[string][]Airway{
+			"J1": []Airway{Airway{Name: "J1", Fixes: []string{"AAA", "BBB", "CCC", "DDD"}}},
+		},
+	}
+
+	for _, tc := range []struct {
+		route    string
+		expected []string
+		err      bool
+	}{
+		{route: "AAA J1 DDD KJFK", expected: []string{"AAA", "BBB", "CCC", "DDD", "KJFK"}},
+		{route: "DDD J1 BBB DCT EEE", expected: []string{"DDD", "CCC", "BBB", "EEE"}},
+		{route: "aaa j1 bbb camrn4", expected: []string{"AAA", "BBB", "CAMRN4"}},
+		{route: "AAA J1 EEE", err: true},
+		{route: "AAA XYZZY", err: true},
+		{route: "J1 AAA", err: true},
+	} {
+		fixes, err := db.ExpandRoute(tc.route)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.route)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.route, err)
+		} else if !slices.Equal(fixes, tc.expected) {
+			t.Errorf("%s: got %v; expected %v", tc.route, fixes, tc.expected)
+		}
+	}
+}
+
+func TestSUASchedule(t *testing.T) {
+	sched, err := ParseSUASchedule("1400-2200, 2330-0130")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(sched, [][2]int{{840, 1320}, {1410, 90}}) {
+		t.Errorf("got schedule %v", sched)
+	}
+
+	for _, test := range []struct {
+		hour, minute int
+		active       bool
+	}{{13, 59, false}, {14, 0, true}, {21, 59, true}, {22, 0, false}, {23, 45, true}, {0, 30, true}, {1, 30, false}} {
+		tm := time.Date(2024, 1, 1, test.hour, test.minute, 0, 0, time.UTC)
+		if SUAScheduleActive(sched, tm) != test.active {
+			t.Errorf("%02d%02d: expected active %v", test.hour, test.minute, test.active)
+		}
+	}
+
+	for _, bad := range []string{"1400", "1400-2500", "14:00-15:00", "900-1000"} {
+		if _, err := ParseSUASchedule(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestWakeSeparation(t *testing.T) {
+	for _, test := range []struct {
+		ws          WakeStandard
+		front, back string
+		sep         float32
+	}{
+		{WakeStandardCWT, "A", "I", 8},
+		{WakeStandardCWT, "C", "E", 3.5},
+		{WakeStandardCWT, "I", "A", 0},
+		{WakeStandardCWT, "B", "A", 0},
+		{WakeStandardCWT, "NOWGT", "F", 10},
+		{WakeStandardCWT, "F", "NOWGT", 10},
+		{WakeStandardRECATEU, "A", "B", 4},
+		{WakeStandardRECATEU, "B", "F", 7},
+		{WakeStandardRECATEU, "F", "A", 0},
+		{WakeStandardRECATEU, "G", "A", 10},
+	} {
+		if sep := WakeSeparation(test.ws, test.front, test.back); sep != test.sep {
+			t.Errorf("%s %s behind %s: got %.1f, expected %.1f", test.ws, test.back, test.front, sep, test.sep)
+		}
+	}
+
+	var perf AircraftPerformance
+	perf.WeightClass = "H"
+	if cwt := perf.CWT(); cwt != "D" {
+		t.Errorf("heavy weight class: got CWT %q, expected \"D\"", cwt)
+	}
+	perf.Category.CWT = "B"
+	if cat := perf.WakeCategory(WakeStandardRECATEU); cat != "B" {
+		t.Errorf("CWT B: got RECAT-EU %q, expected \"B\"", cat)
+	}
+	perf.Category.CWT = "G"
+	if cat := perf.WakeCategory(WakeStandardRECATEU); cat != "E" {
+		t.Errorf("CWT G: got RECAT-EU %q, expected \"E\"", cat)
+	}
+}
+
+func TestEmergencyCondition(t *testing.T) {
+	for _, test := range []struct {
+		squawk            Squawk
+		callsign, remarks string
+		cond              string
+	}{
+		{Squawk(0o7700), "AAL123", "", "EM"},
+		{Squawk(0o7600), "N123AB", "", "RF"},
+		{Squawk(0o7500), "UAL1", "", "HJ"},
+		{Squawk(0o1234), "LN123AB", "", "MED"},
+		{Squawk(0o1234), "N123AB", "/v/ lifeguard", "MED"},
+		{Squawk(0o1234), "LNX123", "", ""},
+		{Squawk(0o1234), "DAL99", "", ""},
+	} {
+		if cond := EmergencyCondition(test.squawk, test.callsign, test.remarks); cond != test.cond {
+			t.Errorf("%s %s %q: got %q, expected %q", test.squawk, test.callsign, test.remarks, cond, test.cond)
+		}
+	}
+}
+
 func TestParseSquawk(t *testing.T) {
 	for _, squawk := range []string{"11111", "7778", "0801", "9000"} {
 		if _, err := ParseSquawk(squawk); err == nil {
@@ -40,6 +179,34 @@ func TestParseSquawk(t *testing.T) {
 	}
 }
 
+func TestBeaconCodes(t *testing.T) {
+	if _, err := ParseBeaconCodeBank("0177-0101"); err == nil {
+		t.Errorf("expected error for reversed bank")
+	}
+	if _, err := ParseBeaconCodeBank("0101"); err == nil {
+		t.Errorf("expected error for missing range")
+	}
+
+	bank, err := ParseBeaconCodeBank("1177-1202")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 1200 is non-discrete, so only 1201 and 1202 are available.
+	inUse := map[Squawk]interface{}{Squawk(0o1177): nil}
+	for i := 0; i < 10; i++ {
+		if sq, err := AssignBeaconCode([]BeaconCodeBank{bank}, inUse); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if sq != Squawk(0o1201) && sq != Squawk(0o1202) {
+			t.Errorf("got unexpected code %s", sq)
+		}
+	}
+
+	inUse[Squawk(0o1201)], inUse[Squawk(0o1202)] = nil, nil
+	if _, err := AssignBeaconCode([]BeaconCodeBank{bank}, inUse); err != ErrNoAvailableBeaconCodes {
+		t.Errorf("expected ErrNoAvailableBeaconCodes, got %v", err)
+	}
+}
+
 func TestParseAltitudeRestriction(t *testing.T) {
 	type testcase struct {
 		s  string
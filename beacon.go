@@ -0,0 +1,113 @@
+// beacon.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BeaconCodeBank is a range of beacon codes that a facility assigns to
+// the aircraft it handles.
+type BeaconCodeBank struct {
+	First, Last Squawk
+}
+
+// Used when a facility doesn't specify its own banks.
+var defaultBeaconCodeBanks = []BeaconCodeBank{{First: Squawk(0o0101), Last: Squawk(0o6777)}}
+
+// ParseBeaconCodeBank parses a bank of codes specified as a range,
+// e.g. "0101-0177".
+func ParseBeaconCodeBank(s string) (BeaconCodeBank, error) {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		return BeaconCodeBank{}, fmt.Errorf("%s: expected a range of codes, e.g. \"0101-0177\"", s)
+	}
+	var b BeaconCodeBank
+	var err error
+	if b.First, err = ParseSquawk(first); err != nil {
+		return BeaconCodeBank{}, err
+	}
+	if b.Last, err = ParseSquawk(last); err != nil {
+		return BeaconCodeBank{}, err
+	}
+	if b.First > b.Last {
+		return BeaconCodeBank{}, fmt.Errorf("%s: first code is after last code", s)
+	}
+	return b, nil
+}
+
+// DiscreteSquawk returns true if the code identifies a single aircraft:
+// codes ending in "00", VFR 1200, and special purpose codes are shared.
+func DiscreteSquawk(sq Squawk) bool {
+	if sq%0o100 == 0 || sq == Squawk(0o1200) {
+		return false
+	}
+	isSPC, _ := SquawkIsSPC(sq)
+	return !isSPC
+}
+
+// AssignBeaconCode returns a discrete code from the given banks that
+// isn't in use. The search starts at a random code so that successive
+// aircraft don't all get nearby codes.
+func AssignBeaconCode(banks []BeaconCodeBank, inUse map[Squawk]interface{}) (Squawk, error) {
+	if len(banks) == 0 {
+		banks = defaultBeaconCodeBanks
+	}
+
+	n := 0
+	for _, b := range banks {
+		n += int(b.Last-b.First) + 1
+	}
+	// Map an index in [0,n) to a code.
+	code := func(i int) Squawk {
+		for _, b := range banks {
+			if nb := int(b.Last-b.First) + 1; i < nb {
+				return b.First + Squawk(i)
+			} else {
+				i -= nb
+			}
+		}
+		panic("unexpected index")
+	}
+
+	start := rand.Intn(n)
+	for i := 0; i < n; i++ {
+		sq := code((start + i) % n)
+		if _, ok := inUse[sq]; !ok && DiscreteSquawk(sq) {
+			return sq, nil
+		}
+	}
+	return Squawk(0), ErrNoAvailableBeaconCodes
+}
+
+// DuplicateBeaconCodes returns the callsigns of the aircraft that are
+// squawking each discrete code that more than one aircraft is squawking.
+func DuplicateBeaconCodes(aircraft map[string]*Aircraft) map[Squawk][]string {
+	codes := make(map[Squawk][]string)
+	for _, callsign := range SortedMapKeys(aircraft) {
+		if ac := aircraft[callsign]; ac.Mode != Standby && DiscreteSquawk(ac.Squawk) {
+			codes[ac.Squawk] = append(codes[ac.Squawk], callsign)
+		}
+	}
+
+	for sq, callsigns := range codes {
+		if len(callsigns) < 2 {
+			delete(codes, sq)
+		}
+	}
+	return codes
+}
+
+// squawksInUse returns the codes that are currently assigned to or being
+// squawked by aircraft.
+func squawksInUse(aircraft map[string]*Aircraft) map[Squawk]interface{} {
+	inUse := make(map[Squawk]interface{})
+	for _, ac := range aircraft {
+		inUse[ac.Squawk] = nil
+		inUse[ac.AssignedSquawk] = nil
+	}
+	return inUse
+}
@@ -0,0 +1,170 @@
+// briefing.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// ReliefBriefing returns a position relief briefing as text: the state of
+// the position that the controller being relieved should pass on to the
+// relieving controller.
+func (sp *STARSPane) ReliefBriefing(w *World) string {
+	var b strings.Builder
+	ps := &sp.CurrentPreferenceSet
+
+	fmt.Fprintf(&b, "RELIEF BRIEFING: %s %s\n", w.Callsign, w.CurrentTime().UTC().Format("1504Z"))
+
+	b.WriteString("\nWEATHER AND RUNWAYS\n")
+	if ps.CurrentATIS != "" {
+		fmt.Fprintf(&b, "  ATIS %s\n", ps.CurrentATIS)
+	}
+	rc := w.RunwayConfiguration()
+	for _, ap := range SortedMapKeys(rc.Airports) {
+		cfg := rc.Airports[ap]
+		fmt.Fprintf(&b, "  %s: arrivals %s, departures %s", ap, strings.Join(cfg.ArrivalRunways, " "),
+			strings.Join(cfg.DepartureRunways, " "))
+		if metar := w.GetMETAR(ap); metar != nil {
+			fmt.Fprintf(&b, ", wind %s, altimeter %s", metar.Wind, metar.Altimeter)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nRESTRICTIONS\n")
+	nr := 0
+	for _, gi := range ps.GIText {
+		if gi != "" {
+			fmt.Fprintf(&b, "  %s\n", gi)
+			nr++
+		}
+	}
+	if nr == 0 {
+		b.WriteString("  None\n")
+	}
+
+	var tracked, inbound []*Aircraft
+	for _, ac := range w.Aircraft {
+		if ac.TrackingController == w.Callsign {
+			tracked = append(tracked, ac)
+		} else if ac.HandoffTrackController == w.Callsign {
+			inbound = append(inbound, ac)
+		}
+	}
+	sort.Slice(tracked, func(i, j int) bool { return tracked[i].Callsign < tracked[j].Callsign })
+	sort.Slice(inbound, func(i, j int) bool { return inbound[i].Callsign < inbound[j].Callsign })
+
+	fmt.Fprintf(&b, "\nTRAFFIC (%d)\n", len(tracked))
+	for _, ac := range tracked {
+		fmt.Fprintf(&b, "  %s\n", reliefBriefingAircraft(ac))
+	}
+
+	b.WriteString("\nPENDING HANDOFFS\n")
+	nh := 0
+	for _, ac := range tracked {
+		if ac.HandoffTrackController != "" {
+			fmt.Fprintf(&b, "  %s to %s\n", ac.Callsign, briefingControllerName(w, ac.HandoffTrackController))
+			nh++
+		}
+	}
+	for _, ac := range inbound {
+		fmt.Fprintf(&b, "  %s from %s\n", ac.Callsign, briefingControllerName(w, ac.TrackingController))
+		nh++
+	}
+	if nh == 0 {
+		b.WriteString("  None\n")
+	}
+
+	b.WriteString("\nPOINT OUTS\n")
+	for _, callsign := range SortedMapKeys(sp.OutboundPointOuts) {
+		fmt.Fprintf(&b, "  %s to %s, not yet approved\n", callsign, sp.OutboundPointOuts[callsign])
+	}
+	for _, callsign := range SortedMapKeys(sp.InboundPointOuts) {
+		fmt.Fprintf(&b, "  %s from %s, not yet approved\n", callsign, sp.InboundPointOuts[callsign])
+	}
+	if len(sp.OutboundPointOuts)+len(sp.InboundPointOuts) == 0 {
+		b.WriteString("  None\n")
+	}
+
+	return b.String()
+}
+
+func briefingControllerName(w *World, callsign string) string {
+	if ctrl := w.GetControllerByCallsign(callsign); ctrl != nil && ctrl.SectorId != "" {
+		return ctrl.SectorId + " (" + callsign + ")"
+	}
+	return callsign
+}
+
+// reliefBriefingAircraft returns a one-line summary of an aircraft's
+// status and the instructions it has been given.
+func reliefBriefingAircraft(ac *Aircraft) string {
+	s := ac.Callsign
+	if fp := ac.FlightPlan; fp != nil {
+		s += " " + fp.AircraftType + " " + fp.DepartureAirport + "-" + fp.ArrivalAirport
+	}
+	s += " " + FormatAltitude(ac.Altitude())
+
+	nav := &ac.Nav
+	if alt := nav.Altitude.Assigned; alt != nil {
+		s += " assigned " + FormatAltitude(*alt)
+	} else if ac.TempAltitude != 0 {
+		s += " temp " + FormatAltitude(float32(ac.TempAltitude))
+	}
+	if hdg, ok := nav.AssignedHeading(); ok {
+		s += fmt.Sprintf(" heading %03d", int(hdg))
+	}
+	if spd := nav.Speed.Assigned; spd != nil {
+		s += fmt.Sprintf(" speed %d", int(*spd))
+	}
+	if nav.Approach.Cleared {
+		s += " cleared " + nav.Approach.AssignedId
+	} else if nav.Approach.AssignedId != "" {
+		s += " expecting " + nav.Approach.AssignedId
+	}
+	if ac.Scratchpad != "" {
+		s += " [" + ac.Scratchpad + "]"
+	}
+	return s
+}
+
+// drawReliefBriefingWindow shows the relief briefing for the first STARS
+// pane, if there is one.
+func drawReliefBriefingWindow(w *World, p Platform) {
+	var stars *STARSPane
+	globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+		if sp, ok := pane.(*STARSPane); ok && stars == nil {
+			stars = sp
+		}
+	})
+	if w == nil || stars == nil {
+		ui.showReliefBriefing = false
+		return
+	}
+
+	if ui.reliefBriefing == "" {
+		ui.reliefBriefing = stars.ReliefBriefing(w)
+	}
+
+	imgui.BeginV("Relief Briefing", &ui.showReliefBriefing, imgui.WindowFlagsAlwaysAutoResize)
+	if imgui.Button("Refresh") {
+		ui.reliefBriefing = stars.ReliefBriefing(w)
+	}
+	imgui.SameLine()
+	if imgui.Button("Copy to clipboard") {
+		p.GetClipboard().SetText(ui.reliefBriefing)
+	}
+	imgui.Separator()
+	imgui.Text(ui.reliefBriefing)
+	imgui.End()
+
+	if !ui.showReliefBriefing {
+		// Generate a fresh briefing the next time the window is opened.
+		ui.reliefBriefing = ""
+	}
+}
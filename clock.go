@@ -0,0 +1,335 @@
+// clock.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// ClockPane shows the current UTC and local times and the time elapsed in
+// the session, along with any number of named stopwatches and countdown
+// timers. The text is drawn at the largest size of the selected font that
+// fits in the pane. Timers are controlled by typing commands when the pane
+// has keyboard focus:
+//
+//	S (name): start or stop the named stopwatch, creating it if needed
+//	C (name) (minutes)[:(seconds)]: start a countdown timer
+//	R (name): reset a stopwatch or restart a countdown
+//	D (name): delete a timer
+type ClockPane struct {
+	FontIdentifier FontIdentifier
+	ShowLocal      bool
+
+	// Timers are saved with the configuration so that they're restored
+	// along with the rest of the session after a crash; see session.go.
+	Timers []*ClockTimer
+
+	font   *Font
+	input  string
+	cursor int
+	status string
+}
+
+// ClockTimer is either a stopwatch, which counts up, or a countdown timer
+// if Duration is non-zero. Timers run in wall-clock time so that they
+// keep going when the sim is paused.
+type ClockTimer struct {
+	Name     string
+	Duration time.Duration // countdown timers only
+	start    time.Time     // zero if stopped
+	elapsed  time.Duration // accumulated before start
+	expired  bool
+}
+
+// The font sizes that are available; see fontsInit.
+var clockFontSizes = []int{28, 24, 22, 20, 18, 16, 14, 13, 12, 11, 10, 9, 8, 7, 6}
+
+func NewClockPane() *ClockPane {
+	return &ClockPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 28},
+		ShowLocal:      true,
+	}
+}
+
+func (cp *ClockPane) Name() string { return "Clock" }
+
+func (cp *ClockPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if cp.font = GetFont(cp.FontIdentifier); cp.font == nil {
+		cp.font = GetDefaultFont()
+		cp.FontIdentifier = cp.font.id
+	}
+}
+
+func (cp *ClockPane) Deactivate()                {}
+func (cp *ClockPane) ResetWorld(w *World)        {}
+func (cp *ClockPane) CanTakeKeyboardFocus() bool { return true }
+
+func (cp *ClockPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&cp.FontIdentifier, "Font"); changed {
+		cp.font = newFont
+	}
+	imgui.Checkbox("Show local time", &cp.ShowLocal)
+}
+
+// clockTimerJSON is used to serialize ClockTimers including their
+// unexported state.
+type clockTimerJSON struct {
+	Name     string
+	Duration time.Duration
+	Start    time.Time
+	Elapsed  time.Duration
+	Expired  bool
+}
+
+func (t *ClockTimer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(clockTimerJSON{
+		Name:     t.Name,
+		Duration: t.Duration,
+		Start:    t.start,
+		Elapsed:  t.elapsed,
+		Expired:  t.expired,
+	})
+}
+
+func (t *ClockTimer) UnmarshalJSON(b []byte) error {
+	var tj clockTimerJSON
+	if err := json.Unmarshal(b, &tj); err != nil {
+		return err
+	}
+	*t = ClockTimer{
+		Name:     tj.Name,
+		Duration: tj.Duration,
+		start:    tj.Start,
+		elapsed:  tj.Elapsed,
+		expired:  tj.Expired,
+	}
+	return nil
+}
+
+func (t *ClockTimer) Elapsed() time.Duration {
+	if t.start.IsZero() {
+		return t.elapsed
+	}
+	return t.elapsed + time.Since(t.start)
+}
+
+func (t *ClockTimer) Running() bool { return !t.start.IsZero() }
+
+func (t *ClockTimer) Start() {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+}
+
+func (t *ClockTimer) Stop() {
+	if !t.start.IsZero() {
+		t.elapsed += time.Since(t.start)
+		t.start = time.Time{}
+	}
+}
+
+func (t *ClockTimer) Reset() {
+	t.elapsed, t.expired = 0, false
+	if !t.start.IsZero() {
+		t.start = time.Now()
+	}
+}
+
+func (t *ClockTimer) String() string {
+	if t.Duration == 0 {
+		return formatDuration(t.Elapsed())
+	}
+	return formatDuration(max(0, t.Duration-t.Elapsed()).Round(time.Second))
+}
+
+func (cp *ClockPane) getTimer(name string) *ClockTimer {
+	if idx := slices.IndexFunc(cp.Timers, func(t *ClockTimer) bool { return t.Name == name }); idx != -1 {
+		return cp.Timers[idx]
+	}
+	return nil
+}
+
+// runCommand executes a timer command entered by the user and returns an
+// error message if it's invalid.
+func (cp *ClockPane) runCommand(cmd string) string {
+	f := strings.Fields(strings.ToUpper(cmd))
+	if len(f) < 2 {
+		return "INVALID COMMAND"
+	}
+	name := f[1]
+	t := cp.getTimer(name)
+
+	switch f[0] {
+	case "S":
+		if len(f) != 2 {
+			return "INVALID COMMAND"
+		}
+		if t == nil {
+			t = &ClockTimer{Name: name}
+			cp.Timers = append(cp.Timers, t)
+		} else if t.Duration != 0 {
+			return name + " IS A COUNTDOWN"
+		}
+		if t.Running() {
+			t.Stop()
+		} else {
+			t.Start()
+		}
+
+	case "C":
+		if len(f) != 3 {
+			return "INVALID COMMAND"
+		}
+		min, sec, _ := strings.Cut(f[2], ":")
+		m, err := strconv.Atoi(min)
+		if err != nil || m < 0 {
+			return "INVALID TIME"
+		}
+		s := 0
+		if sec != "" {
+			if s, err = strconv.Atoi(sec); err != nil || s < 0 || s >= 60 {
+				return "INVALID TIME"
+			}
+		}
+		d := time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+		if d == 0 {
+			return "INVALID TIME"
+		}
+		if t == nil {
+			t = &ClockTimer{Name: name}
+			cp.Timers = append(cp.Timers, t)
+		}
+		t.Duration = d
+		t.Reset()
+		t.Start()
+
+	case "R":
+		if t == nil {
+			return name + ": NO SUCH TIMER"
+		}
+		t.Reset()
+
+	case "D":
+		if t == nil {
+			return name + ": NO SUCH TIMER"
+		}
+		cp.Timers = FilterSlice(cp.Timers, func(t *ClockTimer) bool { return t.Name != name })
+
+	default:
+		return "INVALID COMMAND"
+	}
+	return ""
+}
+
+func (cp *ClockPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	now := time.Now()
+	lines := []string{"UTC     " + now.UTC().Format("15:04:05")}
+	if cp.ShowLocal {
+		lines = append(lines, "LOCAL   "+now.Format("15:04:05"))
+	}
+	if ctx.world != nil && ctx.world.sessionStats != nil {
+		elapsed := ctx.world.CurrentTime().Sub(ctx.world.sessionStats.Start)
+		lines = append(lines, "SESSION "+formatDuration(elapsed))
+	}
+
+	var expired []bool
+	for _, t := range cp.Timers {
+		if t.Duration != 0 && !t.expired && t.Elapsed() >= t.Duration {
+			t.expired = true
+			t.Stop()
+			globalConfig.Audio.PlayOnce(AudioReminder)
+		}
+		state := ""
+		if t.Duration != 0 {
+			state = Select(t.expired, " EXP", " CD")
+		} else if !t.Running() {
+			state = " STOP"
+		}
+		lines = append(lines, fmt.Sprintf("%-7s %s%s", t.Name, t.String(), state))
+		expired = append(expired, t.expired)
+	}
+
+	// Leave room for the status and the command input line when we have
+	// focus.
+	nLines := len(lines) + Select(cp.status != "", 1, 0) + Select(ctx.haveFocus, 1, 0)
+	maxLen := 0
+	for _, l := range lines {
+		maxLen = max(maxLen, len(l))
+	}
+	maxLen = max(maxLen, max(len(cp.status), len(cp.input)+3))
+
+	// Find the largest size of the font that fits.
+	font := cp.font
+	for _, size := range clockFontSizes {
+		f := GetFont(FontIdentifier{Name: cp.FontIdentifier.Name, Size: size})
+		if f == nil {
+			continue
+		}
+		bx, _ := f.BoundText(strings.Repeat("0", maxLen), 0)
+		if float32(nLines*(f.size+1)) <= ctx.paneExtent.Height()-4 && float32(bx) <= ctx.paneExtent.Width()-4 {
+			font = f
+			break
+		}
+		font = f // smallest one if nothing fits
+	}
+	lineHeight := float32(font.size + 1)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ctx.SetWindowCoordinateMatrices(cb)
+
+	y := ctx.paneExtent.Height() - 2
+	style := TextStyle{Font: font, Color: UITextColor}
+	for i, line := range lines {
+		s := style
+		if ti := i - (len(lines) - len(cp.Timers)); ti >= 0 && expired[ti] && ctx.now.Second()&1 == 0 {
+			s.Color = UIErrorColor
+		}
+		td.AddText(line, [2]float32{2, y}, s)
+		y -= lineHeight
+	}
+
+	if cp.status != "" {
+		td.AddText(cp.status, [2]float32{2, y}, TextStyle{Font: font, Color: UIErrorColor})
+		y -= lineHeight
+	}
+
+	if ctx.haveFocus {
+		td.AddText("> ", [2]float32{2, y}, TextStyle{Font: font, Color: RGB{1, 1, .2}})
+		bx, _ := font.BoundText("> ", 0)
+		inputStyle := TextStyle{Font: font, Color: RGB{1, 1, .2}}
+		cursorStyle := TextStyle{Font: font, Color: RGB{1, 1, .2}, DrawBackground: true, BackgroundColor: RGB{1, 1, 1}}
+		if ctx.keyboard != nil {
+			ctx.keyboard.Input = strings.ToUpper(ctx.keyboard.Input)
+		}
+		if exit, _ := uiDrawTextEdit(&cp.input, &cp.cursor, ctx.keyboard, [2]float32{2 + float32(bx), y},
+			inputStyle, cursorStyle, cb); exit == TextEditReturnEnter {
+			if strings.TrimSpace(cp.input) != "" {
+				cp.status = cp.runCommand(cp.input)
+			}
+			cp.input, cp.cursor = "", 0
+		} else if exit == TextEditReturnTextChanged {
+			cp.status = ""
+		}
+
+		// Yellow border around the edges
+		ld := GetLinesDrawBuilder()
+		defer ReturnLinesDrawBuilder(ld)
+		w, h := ctx.paneExtent.Width(), ctx.paneExtent.Height()
+		ld.AddLineLoop([][2]float32{{0, 0}, {w, 0}, {w, h}, {0, h}})
+		cb.SetRGB(RGB{1, 1, 0})
+		ld.GenerateCommands(cb)
+	}
+
+	td.GenerateCommands(cb)
+}
@@ -33,7 +33,8 @@ import (
 // 21: STARS DCB drawing changes, so system list positions changed
 // 22: draw points using triangles, remove some CommandBuffer commands
 // 23: video map format update
-const CurrentConfigVersion = 23
+// 24: STARS emergency list
+const CurrentConfigVersion = 24
 
 // Slightly convoluted, but the full GlobalConfig definition is split into
 // the part with the Sim and the rest of it.  In this way, we can first
@@ -58,19 +59,54 @@ type GlobalConfigNoSim struct {
 	UIFontSize            int
 	EnableMSAA            bool
 
+	// Maximum frames per second, or 0 for no limit (beyond v-sync).
+	FrameRateLimit int
+	// Normally the frame rate is reduced when nothing is happening.
+	DisableIdleFrameRate bool
+
+	// Seconds between world updates from a multi-controller server.
+	RemoteUpdateInterval int
+
+	Retention RetentionPolicy
+
 	Audio AudioEngine
 
+	KeyBindings   *KeyBindings
+	HardwareInput HardwareInput
+	UserFonts     UserFontsConfig
+
 	DisplayRoot *DisplayNode
 
-	AskedDiscordOptIn        bool
-	InhibitDiscordActivity   AtomicBool
+	AskedDiscordOptIn      bool
+	InhibitDiscordActivity AtomicBool
+	// If set, the Discord activity doesn't include the position,
+	// frequency, or traffic count.
+	PrivateDiscordActivity   AtomicBool
 	NotifiedNewCommandSyntax bool
 	StartInFullScreen        bool
 
+	// Enables touchscreen gestures and larger hit targets; see touch.go.
+	TouchMode bool
+
+	// Locale code for UI text; empty for English. See i18n.go.
+	Language string
+
+	UITheme UITheme
+	Sync    ConfigSync
+
+	DisableSessionAutosave bool
+	CrashReports           CrashReportConfig
+	Screenshots            ScreenshotConfig
+	StreamerMode           StreamerMode
+	Webhooks               WebhookConfig
+
 	Callsign string
 
 	highlightedLocation        Point2LL
 	highlightedLocationEndTime time.Time
+
+	// Route being edited in the route amendment dialog, drawn on the scope.
+	previewRoute []Point2LL
 }
 
 type GlobalConfigSim struct {
@@ -211,6 +247,10 @@ func LoadOrMakeDefaultConfig() {
 	if globalConfig.UIFontSize == 0 {
 		globalConfig.UIFontSize = 16
 	}
+	if globalConfig.KeyBindings == nil {
+		globalConfig.KeyBindings = DefaultKeyBindings()
+	}
+	SetLanguage(globalConfig.Language)
 	globalConfig.Version = CurrentConfigVersion
 
 	if err := globalConfig.Audio.Activate(); err != nil {
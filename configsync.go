@@ -0,0 +1,390 @@
+// configsync.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// The configuration can be exported to a single file that can be shared
+// and imported elsewhere, e.g., so that a facility can publish a
+// recommended setup. It can also be synchronized via a user-provided URL
+// (pulled with GET and pushed with PUT) or git repository so that a
+// controller's setup follows them across machines.
+//
+// Exported configurations don't include settings that are specific to a
+// machine, like the window position, or the saved simulation. When a
+// configuration is imported, the settings that are in it replace the
+// current ones and the rest are left unchanged.
+
+// Top-level GlobalConfig fields that are neither exported nor imported.
+// (The sync, crash report, screenshot, and webhook settings are excluded
+// so that importing a facility's configuration doesn't change where the
+// user's configuration, crash reports, screenshots, and notifications are
+// sent; webhook URLs are also effectively secrets.)
+var configMachineSpecificKeys = []string{
+	"FullScreenMonitor", "InitialWindowSize", "InitialWindowPosition", "ImGuiSettings",
+	"WhatsNewIndex", "Sim", "Callsign", "Sync", "CrashReports", "Screenshots", "Webhooks",
+}
+
+// Name of the configuration file in a git sync repository.
+const configSyncFilename = "vice-config.json"
+
+type ConfigSync struct {
+	// HTTP(S) URL to GET the configuration from and PUT it to.
+	URL string
+	// Git repository URL; it's cloned into the "sync" directory next to
+	// the config file.
+	GitRepo string
+
+	// UI state
+	status     string
+	fileDialog *FileSelectDialogBox
+	pulled     chan configSyncResult
+	pushed     chan error
+}
+
+type configSyncResult struct {
+	config []byte
+	err    error
+}
+
+// exportConfig returns the JSON-encoded configuration without the
+// machine-specific settings.
+func exportConfig() ([]byte, error) {
+	var b bytes.Buffer
+	if err := globalConfig.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		return nil, err
+	}
+	for _, k := range configMachineSpecificKeys {
+		delete(m, k)
+	}
+	return json.MarshalIndent(m, "", "    ")
+}
+
+// importConfig replaces the current settings with the ones in the given
+// JSON-encoded configuration and then activates them.
+func importConfig(config []byte, w *World, r Renderer, eventStream *EventStream) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(config, &m); err != nil {
+		return err
+	}
+	for _, k := range configMachineSpecificKeys {
+		delete(m, k)
+	}
+
+	if v, ok := m["Version"]; ok {
+		var version int
+		if err := json.Unmarshal(v, &version); err != nil {
+			return err
+		}
+		if version > CurrentConfigVersion {
+			return fmt.Errorf("configuration is from a newer version of vice")
+		}
+	}
+
+	// Make sure that the configuration decodes before changing anything.
+	config, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var check GlobalConfigNoSim
+	if err := json.Unmarshal(config, &check); err != nil {
+		return err
+	}
+
+	_, newDisplay := m["DisplayRoot"]
+	if newDisplay {
+		globalConfig.DisplayRoot.VisitPanes(func(p Pane) { p.Deactivate() })
+	}
+
+	// The JSON decoder merges objects into existing maps and pointed-to
+	// values, so reset the ones that are being imported so that they are
+	// replaced instead.
+	gv := reflect.ValueOf(&globalConfig.GlobalConfigNoSim).Elem()
+	for k := range m {
+		if f := gv.FieldByName(k); f.IsValid() && f.CanSet() {
+			switch f.Kind() {
+			case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+				f.Set(reflect.Zero(f.Type()))
+			}
+		}
+	}
+	if err := json.Unmarshal(config, &globalConfig.GlobalConfigNoSim); err != nil {
+		return err
+	}
+
+	if newDisplay && globalConfig.DisplayRoot != nil && globalConfig.Version < CurrentConfigVersion {
+		globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
+			if up, ok := p.(PaneUpgrader); ok {
+				up.Upgrade(globalConfig.Version, CurrentConfigVersion)
+			}
+		})
+	}
+	globalConfig.Version = CurrentConfigVersion
+
+	if globalConfig.UIFontSize == 0 {
+		globalConfig.UIFontSize = 16
+	}
+	if globalConfig.KeyBindings == nil {
+		globalConfig.KeyBindings = DefaultKeyBindings()
+	}
+	SetLanguage(globalConfig.Language)
+	globalConfig.UITheme.Activate()
+	ui.font = GetFont(FontIdentifier{Name: "Roboto Regular", Size: globalConfig.UIFontSize})
+	fontsReloadRequested = true
+
+	if newDisplay {
+		wmInit()
+		wm.mouseConsumerOverride, wm.keyboardFocusPane, wm.keyboardFocusStack = nil, nil, nil
+
+		// As when a saved sim is restored at startup, the panes are only
+		// activated with the World and not reset for it so that the
+		// imported scope settings are kept.
+		globalConfig.Activate(w, r, eventStream)
+	}
+
+	return nil
+}
+
+var configSyncClient = &http.Client{Timeout: 30 * time.Second}
+
+func configPullURL(url string) ([]byte, error) {
+	resp, err := configSyncClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func configPushURL(url string, config []byte) error {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(config))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := configSyncClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+func configSyncGitDirectory() string {
+	return path.Join(path.Dir(configFilePath()), "sync")
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// updateConfigGitRepo clones the sync repository if it hasn't been cloned yet
+// (or if its URL has changed) and otherwise pulls the latest changes.
+func updateConfigGitRepo(repo string) error {
+	dir := configSyncGitDirectory()
+	if url, err := runGit(dir, "remote", "get-url", "origin"); err == nil && url == repo {
+		_, err := runGit(dir, "pull", "--ff-only")
+		return err
+	}
+
+	// Clone into a temporary directory so that the existing clone is only
+	// replaced if the new one succeeds.
+	tmp, err := os.MkdirTemp(path.Dir(dir), "sync-clone-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if _, err := runGit(tmp, "clone", "--", repo, "repo"); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(tmp, "repo"), dir)
+}
+
+func configPullGit(repo string) ([]byte, error) {
+	if err := updateConfigGitRepo(repo); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(configSyncGitDirectory(), configSyncFilename))
+}
+
+func configPushGit(repo string, config []byte) error {
+	if err := updateConfigGitRepo(repo); err != nil {
+		return err
+	}
+
+	dir := configSyncGitDirectory()
+	if err := os.WriteFile(filepath.Join(dir, configSyncFilename), config, 0o644); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "add", configSyncFilename); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "diff", "--cached", "--quiet"); err == nil {
+		// Nothing has changed.
+		return nil
+	}
+	if _, err := runGit(dir, "commit", "-m", "Update vice configuration"); err != nil {
+		return err
+	}
+	_, err := runGit(dir, "push")
+	return err
+}
+
+// Pull starts fetching the configuration in the background; it's imported
+// by DrawUI once it arrives.
+func (cs *ConfigSync) Pull() {
+	cs.pulled = make(chan configSyncResult, 1)
+	pulled, url, repo := cs.pulled, cs.URL, cs.GitRepo
+	go func() {
+		var r configSyncResult
+		if url != "" {
+			r.config, r.err = configPullURL(url)
+		} else {
+			r.config, r.err = configPullGit(repo)
+		}
+		pulled <- r
+	}()
+}
+
+// Push starts uploading the current configuration in the background.
+func (cs *ConfigSync) Push() {
+	config, err := exportConfig()
+	if err != nil {
+		cs.status = err.Error()
+		return
+	}
+
+	cs.pushed = make(chan error, 1)
+	pushed, url, repo := cs.pushed, cs.URL, cs.GitRepo
+	go func() {
+		if url != "" {
+			pushed <- configPushURL(url, config)
+		} else {
+			pushed <- configPushGit(repo, config)
+		}
+	}()
+}
+
+func (cs *ConfigSync) DrawUI(w *World, r Renderer, eventStream *EventStream) {
+	// Handle the results of pulls and pushes.
+	select {
+	case result := <-cs.pulled:
+		cs.pulled = nil
+		if result.err != nil {
+			cs.status = result.err.Error()
+		} else if err := importConfig(result.config, w, r, eventStream); err != nil {
+			cs.status = err.Error()
+		} else {
+			cs.status = Tr("Configuration synchronized")
+		}
+	case err := <-cs.pushed:
+		cs.pushed = nil
+		if err != nil {
+			cs.status = err.Error()
+		} else {
+			cs.status = Tr("Configuration uploaded")
+		}
+	default:
+	}
+
+	if imgui.Button(Tr("Export configuration...")) {
+		cs.fileDialog = NewDirectorySelectDialogBox(Tr("Export configuration to..."), "",
+			func(dir string) {
+				fn := filepath.Join(dir, "vice-config.json")
+				if config, err := exportConfig(); err != nil {
+					cs.status = err.Error()
+				} else if err := os.WriteFile(fn, config, 0o644); err != nil {
+					cs.status = err.Error()
+				} else {
+					cs.status = Tr("Saved") + " " + fn
+				}
+			})
+		cs.fileDialog.Activate()
+	}
+	imgui.SameLine()
+	if imgui.Button(Tr("Import configuration...")) {
+		cs.fileDialog = NewFileSelectDialogBox(Tr("Import configuration..."), []string{".json"}, "",
+			func(fn string) {
+				if config, err := os.ReadFile(fn); err != nil {
+					cs.status = err.Error()
+				} else if err := importConfig(config, w, r, eventStream); err != nil {
+					cs.status = err.Error()
+				} else {
+					cs.status = Tr("Imported") + " " + fn
+				}
+			})
+		cs.fileDialog.Activate()
+	}
+
+	imgui.Separator()
+	imgui.InputTextV(Tr("Sync URL"), &cs.URL, 0, nil)
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip(Tr("The configuration is downloaded with GET and uploaded with PUT"))
+	}
+	imgui.InputTextV(Tr("Sync git repository"), &cs.GitRepo, 0, nil)
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip(Tr("Used if no sync URL is given; the repository is cloned to ") + configSyncGitDirectory())
+	}
+
+	busy := cs.pulled != nil || cs.pushed != nil
+	disable := busy || (cs.URL == "" && cs.GitRepo == "")
+	uiStartDisable(disable)
+	if imgui.Button(Tr("Pull")) {
+		cs.status = Tr("Downloading configuration...")
+		cs.Pull()
+	}
+	imgui.SameLine()
+	if imgui.Button(Tr("Push")) {
+		cs.status = Tr("Uploading configuration...")
+		cs.Push()
+	}
+	uiEndDisable(disable)
+
+	if cs.status != "" {
+		imgui.Text(cs.status)
+	}
+	if cs.fileDialog != nil {
+		cs.fileDialog.Draw()
+	}
+}
@@ -0,0 +1,177 @@
+// controllers.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// ControllerPane
+
+// ControllerPane lists the controllers that are signed in along with their
+// frequencies, positions, and scope ranges. Each can be sent a private
+// message or a request for relief by clicking on the corresponding text;
+// hovering over a controller shows its approximate airspace on the radar
+// scope.
+type ControllerPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+
+	events  *EventsSubscription
+	hovered string // callsign of the controller the mouse is over
+}
+
+func NewControllerPane() *ControllerPane {
+	return &ControllerPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (cp *ControllerPane) Name() string { return "Controllers" }
+
+func (cp *ControllerPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if cp.font = GetFont(cp.FontIdentifier); cp.font == nil {
+		cp.font = GetDefaultFont()
+		cp.FontIdentifier = cp.font.id
+	}
+	if cp.scrollbar == nil {
+		cp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+	cp.events = eventStream.Subscribe()
+}
+
+func (cp *ControllerPane) Deactivate() {
+	cp.setHovered("")
+	cp.events.Unsubscribe()
+	cp.events = nil
+}
+
+func (cp *ControllerPane) ResetWorld(w *World)        { cp.hovered = "" }
+func (cp *ControllerPane) CanTakeKeyboardFocus() bool { return false }
+
+func (cp *ControllerPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&cp.FontIdentifier, "Font"); changed {
+		cp.font = newFont
+	}
+}
+
+// setHovered updates the controller that the mouse is over, letting the
+// radar scope know when it changes.
+func (cp *ControllerPane) setHovered(callsign string) {
+	if callsign != cp.hovered {
+		cp.hovered = callsign
+		cp.events.PostEvent(Event{Type: HighlightControllerAirspaceEvent, ToController: callsign})
+	}
+}
+
+// onlineControllers returns the human controllers, sorted by callsign.
+func onlineControllers(w *World) []*Controller {
+	var ctrls []*Controller
+	for _, ctrl := range w.GetAllControllers() {
+		if ctrl.IsHuman {
+			ctrls = append(ctrls, ctrl)
+		}
+	}
+	slices.SortFunc(ctrls, func(a, b *Controller) int { return strings.Compare(a.Callsign, b.Callsign) })
+	return ctrls
+}
+
+// withMessagesPane calls the provided function with the MessagesPane, if
+// there is one.
+func withMessagesPane(f func(mp *MessagesPane)) {
+	globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+		if mp, ok := pane.(*MessagesPane); ok {
+			f(mp)
+		}
+	})
+}
+
+func (cp *ControllerPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	w := ctx.world
+
+	// As in the CoordinationPane, each line is a series of text segments,
+	// some of which can be clicked.
+	type segment struct {
+		text  string
+		color RGB
+		click func()
+	}
+	type line struct {
+		callsign string
+		segments []segment
+	}
+	var lines []line
+	for _, ctrl := range onlineControllers(w) {
+		callsign := ctrl.Callsign
+		text := fmt.Sprintf("%-10s %-4s %s %3.0fnm %-24s ", callsign, ctrl.SectorId, ctrl.Frequency,
+			w.ControllerRange(callsign), ctrl.FullName)
+		l := line{callsign: callsign}
+		if callsign == w.Callsign {
+			l.segments = []segment{{text: text, color: UITextHighlightColor}}
+		} else {
+			l.segments = []segment{
+				{text: text, color: UITextColor},
+				{text: "CHAT", color: UITextHighlightColor,
+					click: func() { withMessagesPane(func(mp *MessagesPane) { mp.startPrivateMessage(callsign) }) }},
+				{text: " "},
+				{text: "RELIEF", color: UICautionColor,
+					click: func() {
+						withMessagesPane(func(mp *MessagesPane) { mp.sendPrivateMessage(w, callsign, "REQUEST RELIEF") })
+					}},
+			}
+		}
+		lines = append(lines, l)
+	}
+
+	lineHeight := float32(cp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	cp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	bx, _ := cp.font.BoundText("X", 0)
+	fw := float32(bx)
+	indent := float32(2)
+
+	hovered := ""
+	y := ctx.paneExtent.Height() - 1
+	for _, l := range lines[cp.scrollbar.Offset():] {
+		if ctx.mouse != nil {
+			if p := ctx.mouse.Pos; p[1] <= y && p[1] > y-lineHeight {
+				hovered = l.callsign
+			}
+		}
+
+		x := indent
+		for _, seg := range l.segments {
+			if seg.click != nil && ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+				p := ctx.mouse.Pos
+				if p[0] >= x && p[0] < x+fw*float32(len(seg.text)) && p[1] <= y && p[1] > y-lineHeight {
+					seg.click()
+				}
+			}
+			td.AddText(seg.text, [2]float32{x, y}, TextStyle{Font: cp.font, Color: seg.color})
+			x += fw * float32(len(seg.text))
+		}
+
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+	cp.setHovered(hovered)
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	cp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
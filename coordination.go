@@ -0,0 +1,170 @@
+// coordination.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// lookupCoordinationController returns the controller with the given
+// callsign or, failing that, sector id.
+func lookupCoordinationController(w *World, id string) *Controller {
+	if ctrl := w.GetControllerByCallsign(id); ctrl != nil {
+		return ctrl
+	}
+	for _, ctrl := range w.GetAllControllers() {
+		if ctrl.SectorId == id {
+			return ctrl
+		}
+	}
+	return nil
+}
+
+// coordinationEventMessage returns the text to report to the controller
+// that a coordination event was sent to.
+func coordinationEventMessage(e Event) string {
+	switch e.Type {
+	case CoordinationRequestEvent:
+		return fmt.Sprintf("%s: APREQ %s FOR %s", e.FromController, e.Message, e.Callsign)
+	case AcceptedCoordinationEvent:
+		return fmt.Sprintf("%s: APPROVED %s FOR %s", e.FromController, e.Message, e.Callsign)
+	case DeniedCoordinationEvent:
+		return fmt.Sprintf("%s: UNABLE %s FOR %s", e.FromController, e.Message, e.Callsign)
+	default:
+		return e.String()
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// CoordinationPane
+
+// CoordinationPane lists the outstanding coordination requests that
+// involve the current controller. Incoming requests can be accepted or
+// denied by clicking on the corresponding text.
+type CoordinationPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+}
+
+func NewCoordinationPane() *CoordinationPane {
+	return &CoordinationPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (cp *CoordinationPane) Name() string { return "Coordination" }
+
+func (cp *CoordinationPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if cp.font = GetFont(cp.FontIdentifier); cp.font == nil {
+		cp.font = GetDefaultFont()
+		cp.FontIdentifier = cp.font.id
+	}
+	if cp.scrollbar == nil {
+		cp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+}
+
+func (cp *CoordinationPane) Deactivate()                {}
+func (cp *CoordinationPane) ResetWorld(w *World)        {}
+func (cp *CoordinationPane) CanTakeKeyboardFocus() bool { return false }
+
+func (cp *CoordinationPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&cp.FontIdentifier, "Font"); changed {
+		cp.font = newFont
+	}
+}
+
+func (cp *CoordinationPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	w := ctx.world
+
+	var aircraft []*Aircraft
+	for _, ac := range w.Aircraft {
+		if coordinationPending(ac, w.Callsign) {
+			aircraft = append(aircraft, ac)
+		}
+	}
+	slices.SortFunc(aircraft, func(a, b *Aircraft) int {
+		return a.Coordination.Time.Compare(b.Coordination.Time)
+	})
+
+	// As in the RunwayConfigPane, each line is a series of text segments,
+	// some of which can be clicked.
+	type segment struct {
+		text  string
+		color RGB
+		click func()
+	}
+	var lines [][]segment
+	for _, ac := range aircraft {
+		co := ac.Coordination
+		age := w.CurrentTime().Sub(co.Time)
+		timer := fmt.Sprintf("%2d:%02d ", int(age.Minutes()), int(age.Seconds())%60)
+
+		if co.ToController == w.Callsign {
+			callsign := ac.Callsign
+			lines = append(lines, []segment{
+				{text: timer + "FROM " + fmt.Sprintf("%-8s", co.FromController), color: UITextColor},
+				{text: "APREQ " + co.Request + " FOR " + callsign + " ", color: UITextHighlightColor},
+				{text: "ACCEPT", color: UITextHighlightColor,
+					click: func() { w.AcceptCoordination(callsign, nil, nil) }},
+				{text: " "},
+				{text: "DENY", color: UIErrorColor,
+					click: func() { w.DenyCoordination(callsign, nil, nil) }},
+			})
+		} else {
+			lines = append(lines, []segment{
+				{text: timer + "TO   " + fmt.Sprintf("%-8s", co.ToController), color: UITextColor},
+				{text: "APREQ " + co.Request + " FOR " + ac.Callsign, color: UITextColor},
+			})
+		}
+	}
+
+	lineHeight := float32(cp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	cp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	bx, _ := cp.font.BoundText("X", 0)
+	fw := float32(bx)
+	indent := float32(2)
+
+	y := ctx.paneExtent.Height() - 1
+	for _, line := range lines[cp.scrollbar.Offset():] {
+		x := indent
+		for _, seg := range line {
+			if seg.click != nil && ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+				p := ctx.mouse.Pos
+				if p[0] >= x && p[0] < x+fw*float32(len(seg.text)) && p[1] <= y && p[1] > y-lineHeight {
+					seg.click()
+				}
+			}
+			td.AddText(seg.text, [2]float32{x, y}, TextStyle{Font: cp.font, Color: seg.color})
+			x += fw * float32(len(seg.text))
+		}
+
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	cp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
+
+// coordinationPending reports whether the aircraft has an outstanding
+// coordination request that the given controller is party to.
+func coordinationPending(ac *Aircraft, controller string) bool {
+	co := ac.Coordination
+	return co != nil && (co.FromController == controller || co.ToController == controller)
+}
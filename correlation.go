@@ -0,0 +1,118 @@
+// correlation.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Aircraft are identified by their callsigns, but with network traffic,
+// a pilot whose connection drops may come back a moment later as a "new"
+// aircraft with a different callsign, and the identities of two aircraft
+// may occasionally be swapped. In both cases, the STARS state that the
+// controller has set up for the track (leader line direction, J-rings,
+// cones, the datablock type, and so forth) is carried over so that it
+// follows the physical aircraft.
+
+// How long the state for an aircraft that has disappeared is kept in case
+// it reappears with a new callsign.
+const STARSRecorrelationWindow = 2 * time.Minute
+
+// An aircraft that appears within this distance of where an aircraft with
+// an identical flight plan disappeared (beyond the distance it could have
+// flown in the meantime) is taken to be the same one.
+const STARSRecorrelationDistance = 3 // nm
+
+type removedAircraftState struct {
+	callsign string
+	state    *STARSAircraftState
+	removed  time.Time // sim time
+}
+
+// rememberRemovedAircraft records the state of an aircraft that is no
+// longer in the world so that it can be re-correlated if it reappears.
+func (sp *STARSPane) rememberRemovedAircraft(callsign string, state *STARSAircraftState, now time.Time) {
+	if state.flightPlan == nil || state.track.Time.IsZero() {
+		return
+	}
+	sp.removedAircraft = append(sp.removedAircraft,
+		removedAircraftState{callsign: callsign, state: state, removed: now})
+}
+
+// recorrelateAircraft returns the state of a recently removed aircraft
+// that the given newly-seen aircraft appears to be, if there is one.
+func (sp *STARSPane) recorrelateAircraft(w *World, ac *Aircraft) *STARSAircraftState {
+	now := w.CurrentTime()
+	sp.removedAircraft = FilterSlice(sp.removedAircraft, func(r removedAircraftState) bool {
+		return now.Sub(r.removed) < STARSRecorrelationWindow
+	})
+	if ac.FlightPlan == nil {
+		return nil
+	}
+
+	for i, r := range sp.removedAircraft {
+		if !sameFlightPlan(ac.FlightPlan, r.state.flightPlan) {
+			continue
+		}
+		dt := now.Sub(r.state.track.Time)
+		flown := float32(r.state.track.Groundspeed) * float32(dt.Hours())
+		if nmdistance2ll(ac.Position(), r.state.track.Position) > flown+STARSRecorrelationDistance {
+			continue
+		}
+
+		lg.Info("re-correlated track", slog.String("callsign", r.callsign),
+			slog.String("new_callsign", ac.Callsign))
+		sp.removedAircraft = DeleteSliceElement(sp.removedAircraft, i)
+		return r.state
+	}
+	return nil
+}
+
+// sameFlightPlan returns true if the two flight plans are the same for the
+// purposes of re-correlation; there must be enough filed to distinguish
+// them from other aircraft's.
+func sameFlightPlan(a, b *FlightPlan) bool {
+	if a.DepartureAirport == "" || a.ArrivalAirport == "" || a.AircraftType == "" {
+		return false
+	}
+	return a.AircraftType == b.AircraftType && a.DepartureAirport == b.DepartureAirport &&
+		a.ArrivalAirport == b.ArrivalAirport && a.Altitude == b.Altitude && a.Route == b.Route
+}
+
+// swapCrossedTracks should be called before the radar tracks are updated;
+// it looks for pairs of aircraft that have each jumped to where the other
+// was and swaps their states so that they stay with the physical
+// aircraft.
+func (sp *STARSPane) swapCrossedTracks(w *World, now time.Time) {
+	type jump struct {
+		callsign string
+		from, to Point2LL
+	}
+	var jumps []jump
+	for callsign, state := range sp.Aircraft {
+		ac, ok := w.Aircraft[callsign]
+		if !ok || state.track.Time.IsZero() {
+			continue
+		}
+		dt := now.Sub(state.track.Time)
+		flown := float32(state.track.Groundspeed) * float32(dt.Hours())
+		if p := ac.Position(); nmdistance2ll(p, state.track.Position) > 2*flown+STARSRecorrelationDistance {
+			jumps = append(jumps, jump{callsign: callsign, from: state.track.Position, to: p})
+		}
+	}
+
+	for i := 0; i < len(jumps); i++ {
+		for j := i + 1; j < len(jumps); j++ {
+			a, b := jumps[i], jumps[j]
+			if nmdistance2ll(a.to, b.from) < STARSRecorrelationDistance &&
+				nmdistance2ll(b.to, a.from) < STARSRecorrelationDistance {
+				lg.Info("swapped crossed tracks", slog.String("callsign", a.callsign),
+					slog.String("other_callsign", b.callsign))
+				sp.Aircraft[a.callsign], sp.Aircraft[b.callsign] = sp.Aircraft[b.callsign], sp.Aircraft[a.callsign]
+			}
+		}
+	}
+}
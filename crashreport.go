@@ -0,0 +1,161 @@
+// crashreport.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Diagnostic reports are ZIP files with the information that's most
+// useful for tracking down bugs: the stack trace if vice crashed, recent
+// log lines, the configuration, and information about the system and GPU.
+// One is written automatically when vice crashes so that the user can
+// attach it to a bug report; if the user has opted in, it's also submitted
+// to the given URL. They can also be created on demand from the settings
+// window.
+
+type CrashReportConfig struct {
+	// If set, crash reports are POSTed to URL.
+	AutoSubmit bool
+	URL        string
+
+	// UI state
+	status string
+}
+
+// Description of the GPU and driver; set by the Renderer.
+var gpuDescription string
+
+func diagnosticReportDirectory() string {
+	return path.Join(path.Dir(configFilePath()), "reports")
+}
+
+// writeDiagnosticReport writes a diagnostic report ZIP file and returns
+// its filename. If vice crashed, the panic value and stack trace should be
+// provided.
+func writeDiagnosticReport(panicValue any, stack []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	add := func(name string, contents []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(contents)
+		return err
+	}
+
+	var info strings.Builder
+	fmt.Fprintf(&info, "vice %s\n", strings.TrimSpace(buildVersion))
+	fmt.Fprintf(&info, "Time: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&info, "OS: %s/%s, %d CPUs\n", runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	fmt.Fprintf(&info, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&info, "GPU: %s\n", gpuDescription)
+	if platform != nil {
+		fmt.Fprintf(&info, "Display: %v framebuffer %v, DPI scale %.2f\n", platform.DisplaySize(),
+			platform.FramebufferSize(), platform.DPIScale())
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(&info, "Memory: %d MB in use, %d GCs\n", mem.HeapAlloc/(1024*1024), mem.NumGC)
+	if panicValue != nil {
+		fmt.Fprintf(&info, "\nPanic: %v\n\n%s", panicValue, stack)
+	}
+	if err := add("report.txt", []byte(info.String())); err != nil {
+		return "", err
+	}
+
+	if err := add("recent-log.jsonl", []byte(strings.Join(recentLogLines.Lines(), "\n")+"\n")); err != nil {
+		return "", err
+	}
+
+	// The configuration may be in a bad state if we've crashed, so be
+	// careful when getting it.
+	config, err := func() (config []byte, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic encoding config: %v", r)
+			}
+		}()
+		return exportConfig()
+	}()
+	if err != nil {
+		config = []byte(err.Error())
+	}
+	if err := add("config.json", config); err != nil {
+		return "", err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	dir := diagnosticReportDirectory()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(dir, "vice-report-"+time.Now().Format("20060102-150405")+".zip")
+	if err := os.WriteFile(fn, buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+
+	if panicValue != nil && globalConfig != nil && globalConfig.CrashReports.AutoSubmit &&
+		globalConfig.CrashReports.URL != "" {
+		if err := submitDiagnosticReport(globalConfig.CrashReports.URL, buf.Bytes()); err != nil {
+			lg.Errorf("%s: unable to submit crash report: %v", globalConfig.CrashReports.URL, err)
+		} else {
+			lg.Infof("%s: submitted crash report", globalConfig.CrashReports.URL)
+		}
+	}
+
+	return fn, nil
+}
+
+func submitDiagnosticReport(url string, report []byte) error {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post(url, "application/zip", bytes.NewReader(report))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+func (cr *CrashReportConfig) DrawUI() {
+	imgui.Checkbox(Tr("Automatically submit crash reports"), &cr.AutoSubmit)
+	uiStartDisable(!cr.AutoSubmit)
+	imgui.InputTextV(Tr("Crash report URL"), &cr.URL, 0, nil)
+	uiEndDisable(!cr.AutoSubmit)
+
+	if imgui.Button(Tr("Create diagnostic report")) {
+		if fn, err := writeDiagnosticReport(nil, nil); err != nil {
+			cr.status = err.Error()
+		} else {
+			cr.status = Tr("Saved") + " " + fn
+		}
+	}
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip(Tr("Saves recent log messages, the configuration, and system information to attach to a bug report"))
+	}
+	if cr.status != "" {
+		imgui.Text(cr.status)
+	}
+}
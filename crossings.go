@@ -0,0 +1,126 @@
+// crossings.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// CrossingRestrictionPane
+
+// CrossingRestrictionPane lists the arrivals that need to descend to meet
+// an upcoming crossing restriction along their route, along with the
+// descent rate that is required to do so. Arrivals that can't make the
+// restriction are highlighted.
+type CrossingRestrictionPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+
+	OnlyTracked bool
+	// Required descent rates above this (ft/minute) are highlighted.
+	CautionRate int
+}
+
+func NewCrossingRestrictionPane() *CrossingRestrictionPane {
+	return &CrossingRestrictionPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+		OnlyTracked:    true,
+		CautionRate:    2000,
+	}
+}
+
+func (cp *CrossingRestrictionPane) Name() string { return "Crossing Restrictions" }
+
+func (cp *CrossingRestrictionPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if cp.font = GetFont(cp.FontIdentifier); cp.font == nil {
+		cp.font = GetDefaultFont()
+		cp.FontIdentifier = cp.font.id
+	}
+	if cp.scrollbar == nil {
+		cp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+	if cp.CautionRate == 0 {
+		cp.CautionRate = 2000
+	}
+}
+
+func (cp *CrossingRestrictionPane) Deactivate()                {}
+func (cp *CrossingRestrictionPane) ResetWorld(w *World)        {}
+func (cp *CrossingRestrictionPane) CanTakeKeyboardFocus() bool { return false }
+
+func (cp *CrossingRestrictionPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&cp.FontIdentifier, "Font"); changed {
+		cp.font = newFont
+	}
+	imgui.Checkbox("Only show aircraft we are tracking", &cp.OnlyTracked)
+	rate := int32(cp.CautionRate)
+	imgui.SliderInt("Caution descent rate (ft/minute)", &rate, 1000, 4000)
+	cp.CautionRate = int(rate)
+}
+
+func (cp *CrossingRestrictionPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	w := ctx.world
+
+	type advisory struct {
+		ac *Aircraft
+		DescentAdvisory
+	}
+	var advisories []advisory
+	for _, ac := range w.Aircraft {
+		if ac.IsDeparture() || !ac.IsAirborne() || (cp.OnlyTracked && ac.TrackingController != w.Callsign) {
+			continue
+		}
+		if da := ac.Nav.GetDescentAdvisory(); da != nil {
+			advisories = append(advisories, advisory{ac: ac, DescentAdvisory: *da})
+		}
+	}
+	// Most urgent first
+	slices.SortFunc(advisories, func(a, b advisory) int {
+		if a.RequiredRate != b.RequiredRate {
+			return Select(a.RequiredRate > b.RequiredRate, -1, 1)
+		}
+		return strings.Compare(a.ac.Callsign, b.ac.Callsign)
+	})
+
+	lineHeight := float32(cp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	cp.scrollbar.Update(len(advisories), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	indent := float32(2)
+	y := ctx.paneExtent.Height() - 1
+	for _, a := range advisories[cp.scrollbar.Offset():] {
+		color := UITextColor
+		if a.Unable {
+			color = UIErrorColor
+		} else if a.RequiredRate > float32(cp.CautionRate) {
+			color = UICautionColor
+		}
+
+		text := fmt.Sprintf("%-8s %05d %-5s %-20s %5.1fnm %s", a.ac.Callsign, int(a.ac.Altitude()),
+			a.Fix, a.Restriction.Summary(), a.Distance, a.DescentAdvisory.String())
+		td.AddText(text, [2]float32{indent, y}, TextStyle{Font: cp.font, Color: color})
+
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	cp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
@@ -0,0 +1,74 @@
+// deadreckoning.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"time"
+)
+
+// Positions are not extrapolated more than this far past the last report
+// for an aircraft; beyond that, the data is stale and a track that keeps
+// moving would be misleading.
+const DeadReckoningLimit = 60 * time.Second
+
+// Limits on the interval between world updates from a remote server. The
+// server warns about controllers it hasn't heard from in 5 seconds, so the
+// maximum needs to be comfortably less than that.
+const (
+	MinRemoteUpdateInterval = 1
+	MaxRemoteUpdateInterval = 4
+)
+
+// deadReckon returns the position of an aircraft that was at p at the
+// time of its last report, assuming that it has continued along the given
+// true heading at the given groundspeed for the time dt since then.
+func deadReckon(p Point2LL, trueHeading, gs float32, dt time.Duration, nmPerLongitude float32) Point2LL {
+	dt = min(max(dt, 0), DeadReckoningLimit)
+	dist := gs * float32(dt.Hours())
+	v := scale2f([2]float32{sin(radians(trueHeading)), cos(radians(trueHeading))}, dist)
+	return nm2ll(add2f(ll2nm(p, nmPerLongitude), v), nmPerLongitude)
+}
+
+// positionReport records where an aircraft was according to the most
+// recent world update.
+type positionReport struct {
+	Position Point2LL
+	Time     time.Time // sim time
+}
+
+// recordPositionReports should be called when new aircraft arrive in a
+// world update; it records their positions so that they can be
+// extrapolated until the next update.
+func (w *World) recordPositionReports(t time.Time) {
+	w.positionReports = make(map[string]positionReport)
+	for callsign, ac := range w.Aircraft {
+		w.positionReports[callsign] = positionReport{Position: ac.Position(), Time: t}
+	}
+}
+
+// deadReckonAircraft updates the positions of the aircraft to account for
+// the time that has passed since their last reported positions.
+func (w *World) deadReckonAircraft() {
+	now := w.CurrentTime()
+	for callsign, ac := range w.Aircraft {
+		if r, ok := w.positionReports[callsign]; ok {
+			fs := &ac.Nav.FlightState
+			fs.Position = deadReckon(r.Position, fs.Heading-fs.MagneticVariation, fs.GS,
+				now.Sub(r.Time), w.NmPerLongitude)
+		}
+	}
+}
+
+// updateInterval returns the time between requests for world updates.
+func (w *World) updateInterval() time.Duration {
+	// Wait in seconds between update fetches; no less than 50ms
+	rate := clamp(1/w.SimRate, 0.05, 1)
+	if w.SimName != "" {
+		// Updates from a remote server may be less frequent to reduce
+		// bandwidth; aircraft positions are extrapolated in between.
+		rate = max(rate, float32(globalConfig.RemoteUpdateInterval))
+	}
+	return time.Duration(rate * float32(time.Second))
+}
@@ -0,0 +1,172 @@
+// departures.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// DeparturePane
+
+// DeparturePane lists the departures that have not yet been handed off,
+// grouped by departure airport and runway. Departures can be marked as
+// released by clicking on them, after which the time since release is
+// shown. Departures drop off the list once they are airborne and on a
+// radar controller's frequency.
+type DeparturePane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+
+	// callsign -> release time
+	released map[string]time.Time
+}
+
+func NewDeparturePane() *DeparturePane {
+	return &DeparturePane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (dp *DeparturePane) Name() string { return "Departures" }
+
+func (dp *DeparturePane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if dp.font = GetFont(dp.FontIdentifier); dp.font == nil {
+		dp.font = GetDefaultFont()
+		dp.FontIdentifier = dp.font.id
+	}
+	if dp.scrollbar == nil {
+		dp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+	dp.released = make(map[string]time.Time)
+}
+
+func (dp *DeparturePane) Deactivate()                {}
+func (dp *DeparturePane) ResetWorld(w *World)        { clear(dp.released) }
+func (dp *DeparturePane) CanTakeKeyboardFocus() bool { return false }
+
+func (dp *DeparturePane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&dp.FontIdentifier, "Font"); changed {
+		dp.font = newFont
+	}
+}
+
+// pendingDepartures returns the departures that should be listed, sorted
+// by departure airport, runway, and callsign.
+func (dp *DeparturePane) pendingDepartures(w *World) []*Aircraft {
+	var deps []*Aircraft
+	for _, ac := range w.Aircraft {
+		if !ac.IsDeparture() || ac.FlightPlan == nil {
+			continue
+		}
+		if ac.IsAirborne() && ac.ControllingController != "" {
+			// Gone and handed off.
+			continue
+		}
+		deps = append(deps, ac)
+	}
+
+	slices.SortFunc(deps, func(a, b *Aircraft) int {
+		if c := strings.Compare(a.FlightPlan.DepartureAirport, b.FlightPlan.DepartureAirport); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.DepartureRunway, b.DepartureRunway); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Callsign, b.Callsign)
+	})
+	return deps
+}
+
+func (dp *DeparturePane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	now := ctx.world.CurrentTime()
+
+	// Forget about the release status of aircraft that are gone.
+	for callsign := range dp.released {
+		if _, ok := ctx.world.Aircraft[callsign]; !ok {
+			delete(dp.released, callsign)
+		}
+	}
+
+	type line struct {
+		text     string
+		callsign string // non-empty for departures, which can be clicked
+		header   bool
+	}
+	var lines []line
+	group := ""
+	for _, ac := range dp.pendingDepartures(ctx.world) {
+		if g := ac.FlightPlan.DepartureAirport + " " + ac.DepartureRunway; g != group {
+			if group != "" {
+				lines = append(lines, line{})
+			}
+			group = g
+			lines = append(lines, line{text: g, header: true})
+		}
+
+		var b strings.Builder
+		if t, ok := dp.released[ac.Callsign]; ok {
+			d := now.Sub(t)
+			fmt.Fprintf(&b, "REL %2d:%02d ", int(d.Minutes()), int(d.Seconds())%60)
+		} else {
+			b.WriteString("          ")
+		}
+		fp := ac.FlightPlan
+		fmt.Fprintf(&b, "%-8s %-4s %-5s %03d %s %s", ac.Callsign, fp.TypeWithoutSuffix(),
+			ac.Exit, fp.Altitude/100, ac.AssignedSquawk, fp.ArrivalAirport)
+		lines = append(lines, line{text: b.String(), callsign: ac.Callsign})
+	}
+
+	lineHeight := float32(dp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	dp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	headerStyle := TextStyle{Font: dp.font, Color: UITextHighlightColor}
+	textStyle := TextStyle{Font: dp.font, Color: UITextColor}
+	releasedStyle := TextStyle{Font: dp.font, Color: UICautionColor}
+
+	indent := float32(2)
+	y := ctx.paneExtent.Height() - 1
+	for _, l := range lines[dp.scrollbar.Offset():] {
+		style := textStyle
+		if l.header {
+			style = headerStyle
+		} else if l.callsign != "" {
+			_, released := dp.released[l.callsign]
+			style = Select(released, releasedStyle, textStyle)
+
+			// Clicking on a departure toggles its release status.
+			if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+				if p := ctx.mouse.Pos; p[1] <= y && p[1] > y-lineHeight {
+					if released {
+						delete(dp.released, l.callsign)
+					} else {
+						dp.released[l.callsign] = now
+					}
+				}
+			}
+		}
+		td.AddText(l.text, [2]float32{indent, y}, style)
+
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	dp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
@@ -22,8 +22,10 @@ var (
 	ErrInvalidController            = errors.New("Invalid controller")
 	ErrInvalidFacility              = errors.New("Invalid facility")
 	ErrInvalidHeading               = errors.New("Invalid heading")
+	ErrNoAvailableBeaconCodes       = errors.New("No beacon codes are available")
 	ErrNoAircraftForCallsign        = errors.New("No aircraft exists with specified callsign")
 	ErrNoController                 = errors.New("No controller with that callsign")
+	ErrNoCoordinationRequest        = errors.New("No coordination request pending for aircraft")
 	ErrNotLaunchController          = errors.New("Not signed in as the launch controller")
 	ErrNoFlightPlan                 = errors.New("No flight plan has been filed for aircraft")
 	ErrNoValidArrivalFound          = errors.New("Unable to find a valid arrival")
@@ -51,6 +53,7 @@ var (
 	ErrRPCVersionMismatch        = errors.New("Client and server RPC versions don't match")
 	ErrRestoringSavedState       = errors.New("Errors during state restoration")
 	ErrInvalidPassword           = errors.New("Invalid password")
+	ErrReconnectFailed           = errors.New("Unable to reconnect to the vice server")
 )
 
 var errorStringToError = map[string]error{
@@ -62,8 +65,10 @@ var errorStringToError = map[string]error{
 	ErrInvalidController.Error():            ErrInvalidController,
 	ErrInvalidFacility.Error():              ErrInvalidFacility,
 	ErrInvalidHeading.Error():               ErrInvalidHeading,
+	ErrNoAvailableBeaconCodes.Error():       ErrNoAvailableBeaconCodes,
 	ErrNoAircraftForCallsign.Error():        ErrNoAircraftForCallsign,
 	ErrNoController.Error():                 ErrNoController,
+	ErrNoCoordinationRequest.Error():        ErrNoCoordinationRequest,
 	ErrNoFlightPlan.Error():                 ErrNoFlightPlan,
 	ErrNoValidDepartureFound.Error():        ErrNoValidDepartureFound,
 	ErrNotBeingHandedOffToMe.Error():        ErrNotBeingHandedOffToMe,
@@ -139,8 +144,10 @@ var starsErrorRemap = map[error]*STARSError{
 	ErrInvalidController:            ErrSTARSIllegalPosition,
 	ErrInvalidFacility:              ErrSTARSIllegalTrack,
 	ErrInvalidHeading:               ErrSTARSIllegalValue,
+	ErrNoAvailableBeaconCodes:       ErrSTARSIllegalCode,
 	ErrNoAircraftForCallsign:        ErrSTARSNoFlight,
 	ErrNoController:                 ErrSTARSIllegalSector,
+	ErrNoCoordinationRequest:        ErrSTARSIllegalTrack,
 	ErrNoFlightPlan:                 ErrSTARSIllegalFlight,
 	ErrNotBeingHandedOffToMe:        ErrSTARSIllegalTrack,
 	ErrNotPointedOutToMe:            ErrSTARSIllegalTrack,
@@ -110,6 +110,14 @@ func (e *EventStream) Post(event Event) {
 	}
 }
 
+// Len returns the number of events currently held in the stream.
+func (e *EventStream) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.events)
+}
+
 // Get returns all of the events from the stream since the last time Get
 // was called with the given id.  Note that events before an id was created
 // with Subscribe are never reported for that id.
@@ -199,6 +207,12 @@ const (
 	HandoffControllEvent
 	SetGlobalLeaderLineEvent
 	TrackClickedEvent
+	SelectedAircraftEvent
+	CoordinationRequestEvent
+	AcceptedCoordinationEvent
+	DeniedCoordinationEvent
+	HighlightControllerAirspaceEvent
+	PseudoPilotInstructionEvent
 	NumEventTypes
 )
 
@@ -207,7 +221,9 @@ func (t EventType) String() string {
 		"OfferedHandoff", "AcceptedHandoff", "AcceptedRedirectedHandoffEvent", "CanceledHandoff", "RejectedHandoff",
 		"RadioTransmission", "StatusMessage", "ServerBroadcastMessage", "GlobalMessage",
 		"AcknowledgedPointOut", "RejectedPointOut", "Ident", "HandoffControll",
-		"SetGlobalLeaderLine", "TrackClicked"}[t]
+		"SetGlobalLeaderLine", "TrackClicked", "SelectedAircraft",
+		"CoordinationRequest", "AcceptedCoordination", "DeniedCoordination",
+		"HighlightControllerAirspace", "PseudoPilotInstruction"}[t]
 }
 
 type Event struct {
@@ -225,7 +241,7 @@ func (e *Event) String() string {
 	case RadioTransmissionEvent:
 		return fmt.Sprintf("%s: callsign %s controller %s->%s message %s type %v",
 			e.Type, e.Callsign, e.FromController, e.ToController, e.Message, e.RadioTransmissionType)
-	case TrackClickedEvent:
+	case TrackClickedEvent, SelectedAircraftEvent:
 		return fmt.Sprintf("%s: %s", e.Type, e.Callsign)
 	default:
 		return fmt.Sprintf("%s: callsign %s controller %s->%s message %s",
@@ -7,6 +7,7 @@ package main
 import (
 	"C"
 	"fmt"
+	"hash/crc32"
 	"image"
 	"image/color"
 	"math"
@@ -37,7 +38,9 @@ var (
 	FontAwesomeIconBug                 = faUsedIcons["Bug"]
 	FontAwesomeIconCaretDown           = faUsedIcons["CaretDown"]
 	FontAwesomeIconCaretRight          = faUsedIcons["CaretRight"]
+	FontAwesomeIconChalkboardTeacher   = faUsedIcons["ChalkboardTeacher"]
 	FontAwesomeIconCheckSquare         = faUsedIcons["CheckSquare"]
+	FontAwesomeIconClipboardList       = faUsedIcons["ClipboardList"]
 	FontAwesomeIconCog                 = faUsedIcons["Cog"]
 	FontAwesomeIconCompressAlt         = faUsedIcons["CompressAlt"]
 	FontAwesomeIconCopyright           = faUsedIcons["Copyright"]
@@ -79,7 +82,9 @@ var (
 		"Bug":                 FontAwesomeString("Bug"),
 		"CaretDown":           FontAwesomeString("CaretDown"),
 		"CaretRight":          FontAwesomeString("CaretRight"),
+		"ChalkboardTeacher":   FontAwesomeString("ChalkboardTeacher"),
 		"CheckSquare":         FontAwesomeString("CheckSquare"),
+		"ClipboardList":       FontAwesomeString("ClipboardList"),
 		"CompressAlt":         FontAwesomeString("CompressAlt"),
 		"Cog":                 FontAwesomeString("Cog"),
 		"Copyright":           FontAwesomeString("Copyright"),
@@ -122,6 +127,8 @@ type Font struct {
 	ifont imgui.Font // may be unset if the font isn't used with imgui (e.g. the STARS fonts)
 	id    FontIdentifier
 	texId uint32 // texture that holds the glyph texture atlas
+	// Version of the font that can be drawn at arbitrary scales; see sdf.go.
+	scalable *Font
 }
 
 // While the following could be found via the imgui.FontGlyph interface, cgo calls into C++ code are
@@ -147,6 +154,10 @@ type FontIdentifier struct {
 // Internal: lookup the glyph for a rune in imgui's font atlas and then
 // copy over the necessary information into our Glyph structure.
 func (f *Font) createGlyph(ch rune) *Glyph {
+	if f.ifont == 0 {
+		// Bitmap fonts only have the glyphs that they were created with.
+		return &Glyph{}
+	}
 	ig := f.ifont.FindGlyph(ch)
 	return &Glyph{X0: ig.X0(), Y0: ig.Y0(), X1: ig.X1(), Y1: ig.Y1(),
 		U0: ig.U0(), V0: ig.V0(), U1: ig.U1(), V1: ig.V1(),
@@ -205,8 +216,64 @@ func ptrToUint16Slice(p unsafe.Pointer) []uint16 {
 }
 
 func fontsInit(r Renderer, platform Platform) {
-	lg.Info("Starting to initialize fonts")
+	lg.Category(LogCategoryRender).Info("Starting to initialize fonts")
 	fonts = make(map[FontIdentifier]*Font)
+
+	loadTTFFonts(r, platform)
+
+	// The STARS fonts are bitmaps and don't come in via TTF files so get
+	// handled specially.
+	initializeSTARSFonts(r)
+
+	loadUserBitmapFonts(r)
+
+	lg.Category(LogCategoryRender).Info("Finished initializing fonts")
+}
+
+// fontsReload re-rasterizes the TTF fonts for the platform's current DPI
+// scale and reloads the user's fonts; it should be called when the window
+// moves to a monitor with a different scale or the user fonts have
+// changed. The existing Font objects are updated in place so that panes'
+// references to them remain valid. imgui's font atlas can't be cleared,
+// so new fonts are added to it alongside the old ones; each font is only
+// added once per scaled size.
+func fontsReload(r Renderer, platform Platform) {
+	lg.Category(LogCategoryRender).Infof("Reloading fonts for DPI scale %f", platform.DPIScale())
+	textures := make(map[uint32]interface{})
+	for _, font := range fonts {
+		if font.ifont != 0 {
+			textures[font.texId] = nil
+			if font.scalable != nil {
+				textures[font.scalable.texId] = nil
+			}
+		}
+	}
+	for id := range textures {
+		r.DestroyTexture(id)
+	}
+	loadTTFFonts(r, platform)
+	loadUserBitmapFonts(r)
+}
+
+// Glyph ranges for the Font Awesome icons; they're allocated once since
+// imgui holds on to them.
+var faGlyphRange, faBrandsGlyphRange imgui.GlyphRanges
+
+// atlasFonts records the fonts that have already been added to imgui's
+// font atlas, indexed by name and scaled size. The checksum of the font
+// data is included so that user fonts that have been edited are added
+// anew.
+var atlasFonts = make(map[atlasFontKey]imgui.Font)
+
+type atlasFontKey struct {
+	name     string
+	size     float32
+	checksum uint32
+}
+
+// loadTTFFonts rasterizes the TTF fonts at all of the available sizes,
+// scaled for the display's DPI, into imgui's font atlas.
+func loadTTFFonts(r Renderer, platform Platform) {
 	io := imgui.CurrentIO()
 
 	// Given a map that specifies the icons used in an icon font, returns
@@ -237,12 +304,21 @@ func fontsInit(r Renderer, platform Platform) {
 	// Decompress and get the glyph ranges for the Font Awesome fonts just once.
 	faTTF := LoadResource("fonts/Font Awesome 5 Free-Solid-900.otf.zst")
 	fabrTTF := LoadResource("fonts/Font Awesome 5 Brands-Regular-400.otf.zst")
-	faGlyphRange := glyphRangeForIcons(faUsedIcons)
-	faBrandsGlyphRange := glyphRangeForIcons(faBrandsUsedIcons)
+	if faGlyphRange == 0 {
+		faGlyphRange = glyphRangeForIcons(faUsedIcons)
+		faBrandsGlyphRange = glyphRangeForIcons(faBrandsUsedIcons)
+	}
 
-	add := func(filename string, mono bool, name string) {
-		ttf := LoadResource("fonts/" + filename)
-		for _, size := range []int{6, 7, 8, 9, 10, 11, 12, 13, 14, 16, 18, 20, 22, 24, 28} {
+	sizes := []int{6, 7, 8, 9, 10, 11, 12, 13, 14, 16, 18, 20, 22, 24, 28}
+	type family struct {
+		name  string
+		sizes []int
+	}
+	var families []family
+	addTTF := func(ttf []byte, mono bool, name string, sizes []int, fontConfig imgui.FontConfig) {
+		families = append(families, family{name: name, sizes: sizes})
+		checksum := crc32.ChecksumIEEE(ttf)
+		for _, size := range sizes {
 			sp := float32(size)
 			if runtime.GOOS == "windows" {
 				if dpis := platform.DPIScale(); dpis > 1 {
@@ -255,25 +331,38 @@ func fontsInit(r Renderer, platform Platform) {
 				sp = float32(int(sp + 0.5))
 			}
 
-			ifont := io.Fonts().AddFontFromMemoryTTFV(ttf, sp, imgui.DefaultFontConfig, imgui.EmptyGlyphRanges)
-
-			config := imgui.NewFontConfig()
-			config.SetMergeMode(true)
-			// Scale down the font size by an ad-hoc factor to (generally)
-			// make the icon sizes match the font's character sizes.
-			io.Fonts().AddFontFromMemoryTTFV(faTTF, .8*sp, config, faGlyphRange)
-			io.Fonts().AddFontFromMemoryTTFV(fabrTTF, .8*sp, config, faBrandsGlyphRange)
+			key := atlasFontKey{name: name, size: sp, checksum: checksum}
+			ifont, ok := atlasFonts[key]
+			if !ok {
+				ifont = io.Fonts().AddFontFromMemoryTTFV(ttf, sp, fontConfig, imgui.EmptyGlyphRanges)
+
+				config := imgui.NewFontConfig()
+				config.SetMergeMode(true)
+				// Scale down the font size by an ad-hoc factor to (generally)
+				// make the icon sizes match the font's character sizes.
+				io.Fonts().AddFontFromMemoryTTFV(faTTF, .8*sp, config, faGlyphRange)
+				io.Fonts().AddFontFromMemoryTTFV(fabrTTF, .8*sp, config, faBrandsGlyphRange)
+				atlasFonts[key] = ifont
+			}
 
 			id := FontIdentifier{Name: name, Size: size}
-			fonts[id] = &Font{
-				glyphs: make(map[rune]*Glyph),
-				size:   int(sp),
-				mono:   mono,
-				ifont:  ifont,
-				id:     id,
+			if f, ok := fonts[id]; ok {
+				// Rescaling; reset the cached glyphs.
+				*f = Font{glyphs: make(map[rune]*Glyph), size: int(sp), mono: mono, ifont: ifont, id: id}
+			} else {
+				fonts[id] = &Font{
+					glyphs: make(map[rune]*Glyph),
+					size:   int(sp),
+					mono:   mono,
+					ifont:  ifont,
+					id:     id,
+				}
 			}
 		}
 	}
+	add := func(filename string, mono bool, name string) {
+		addTTF(LoadResource("fonts/"+filename), mono, name, sizes, imgui.DefaultFontConfig)
+	}
 
 	add("Roboto-Regular.ttf.zst", false, "Roboto Regular")
 	add("RobotoMono-Medium.ttf.zst", false, "Roboto Mono")
@@ -284,8 +373,10 @@ func fontsInit(r Renderer, platform Platform) {
 	add("Flight-Strip-Printer.ttf.zst", true, "Flight Strip Printer")
 	add("Inconsolata_Condensed-Regular.ttf.zst", true, "Inconsolata Condensed Regular")
 
+	loadUserTTFFonts(sizes, addTTF)
+
 	img := io.Fonts().TextureDataRGBA32()
-	lg.Infof("Fonts texture used %.1f MB", float32(img.Width*img.Height*4)/(1024*1024))
+	lg.Category(LogCategoryRender).Infof("Fonts texture used %.1f MB", float32(img.Width*img.Height*4)/(1024*1024))
 	rgb8Image := &image.RGBA{
 		Pix:    unsafe.Slice((*uint8)(img.Pixels), 4*img.Width*img.Height),
 		Stride: 4 * img.Width,
@@ -296,14 +387,21 @@ func fontsInit(r Renderer, platform Platform) {
 	// Patch up the texture id after the atlas was created with the
 	// TextureDataRGBA32 call above.
 	for _, font := range fonts {
-		font.texId = atlasId
+		if font.ifont != 0 {
+			font.texId = atlasId
+		}
 	}
 
-	// The STARS fonts are bitmaps and don't come in via TTF files so get
-	// handled specially.
-	initializeSTARSFonts(r)
-
-	lg.Info("Finished initializing fonts")
+	// Make the scalable fonts from the largest size of each one.
+	for _, fam := range families {
+		n := len(fam.sizes)
+		src := fonts[FontIdentifier{Name: fam.name, Size: fam.sizes[n-1]}]
+		var others []*Font
+		for _, size := range fam.sizes[:n-1] {
+			others = append(others, fonts[FontIdentifier{Name: fam.name, Size: size}])
+		}
+		buildScalableFonts(r, rgb8Image, src, others)
+	}
 }
 
 // GetAllFonts returns a FontIdentifier slice that gives identifiers for
@@ -340,14 +438,24 @@ func DrawFontPicker(id *FontIdentifier, label string) (newFont *Font, changed bo
 			if font.Name != lastFontName {
 				lastFontName = font.Name
 				// Use the 14pt version of the font in the combo box.
+				// User fonts may not have that size (or be bitmap fonts,
+				// which imgui can't use), in which case the default is used.
 				displayFont := GetFont(FontIdentifier{Name: font.Name, Size: 14})
-				imgui.PushFont(displayFont.ifont)
+				if displayFont != nil && displayFont.ifont != 0 {
+					imgui.PushFont(displayFont.ifont)
+				}
 				if imgui.SelectableV(font.Name, id.Name == font.Name, 0, imgui.Vec2{}) {
 					id.Name = font.Name
 					changed = true
-					newFont = GetFont(*id)
+					if newFont = GetFont(*id); newFont == nil {
+						// Pick the first available size.
+						newFont = GetFont(font)
+						*id = font
+					}
+				}
+				if displayFont != nil && displayFont.ifont != 0 {
+					imgui.PopFont()
 				}
-				imgui.PopFont()
 			}
 		}
 		imgui.EndCombo()
@@ -589,6 +697,7 @@ func initializeSTARSFonts(r Renderer) {
 	for _, font := range newFonts {
 		font.texId = atlasId
 		fonts[font.id] = font // add them to the global table
+		buildScalableFonts(r, atlas, font, nil)
 	}
 }
 
@@ -0,0 +1,351 @@
+// fsd.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// FSDBackend
+
+// FSDBackend is a NetworkBackend for a server that speaks the FSD
+// protocol used by online flight simulation networks, such as a private
+// FSD server.
+type FSDBackend struct {
+	name           string
+	defaultAddress string
+}
+
+const FSDDefaultAddress = "localhost:6809"
+
+func (b FSDBackend) Name() string           { return b.name }
+func (b FSDBackend) DefaultAddress() string { return b.defaultAddress }
+func (b FSDBackend) RequiresLogin() bool    { return true }
+
+func (b FSDBackend) Connect(settings NetworkSettings) (LiveTrafficSource, error) {
+	if _, _, err := net.SplitHostPort(settings.Address); err != nil {
+		return nil, err
+	}
+	if settings.Callsign == "" || settings.CID == "" {
+		return nil, errors.New("A callsign and CID must be provided")
+	}
+	// Fields are separated by colons and packets by newlines, so they
+	// can't appear in anything that we send.
+	for _, s := range []string{settings.Callsign, settings.CID, settings.Password} {
+		if strings.ContainsAny(s, ":\r\n") {
+			return nil, errors.New("The callsign, CID, and password may not contain colons")
+		}
+	}
+	return NewFSDClient(settings), nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// FSDClient
+
+// FSDClient is a LiveTrafficSource that logs in to an FSD server as an
+// observer and reports the pilots that the server sends position updates
+// for. As with SBSFeed, packets are read continuously by a separate
+// goroutine that reconnects as needed; Fetch returns the current state of
+// each pilot.
+type FSDClient struct {
+	settings NetworkSettings
+
+	mu     sync.Mutex
+	pilots map[string]*fsdPilot // callsign -> state
+	err    error
+	done   chan struct{}
+	conn   net.Conn
+	// Our position and visibility range, as of the most recent Fetch; the
+	// server uses them to decide which pilots to send updates for.
+	center Point2LL
+	radius float32
+}
+
+type fsdPilot struct {
+	LiveTrack
+	havePosition bool
+}
+
+// Pilots that haven't sent a position update in this long are dropped.
+const FSDPilotTimeout = 60 * time.Second
+
+const (
+	fsdProtocolRevision = 9
+	fsdRatingObserver   = 1
+	fsdFacilityObserver = 0
+	fsdObserverFreq     = "99998" // 199.998, i.e., no frequency
+)
+
+func NewFSDClient(settings NetworkSettings) *FSDClient {
+	f := &FSDClient{
+		settings: settings,
+		pilots:   make(map[string]*fsdPilot),
+		done:     make(chan struct{}),
+	}
+	go f.run()
+	return f
+}
+
+func (f *FSDClient) Name() string { return "FSD " + f.settings.Address }
+
+// Pilot clients send position updates every 5 seconds.
+func (f *FSDClient) PollInterval() time.Duration { return 5 * time.Second }
+
+func (f *FSDClient) Close() {
+	close(f.done)
+	f.mu.Lock()
+	if f.conn != nil {
+		f.conn.Close()
+	}
+	f.mu.Unlock()
+}
+
+// run reads packets from the server, reconnecting if the connection is
+// lost, until the FSDClient is closed.
+func (f *FSDClient) run() {
+	backoff := Backoff{Min: 5 * time.Second, Max: 5 * time.Minute}
+	for {
+		connected, err := f.read()
+		if connected {
+			backoff.Reset()
+		}
+
+		select {
+		case <-f.done:
+			return
+		default:
+			lg.Category(LogCategoryNetwork).Warnf("%s: %v", f.settings.Address, err)
+			f.mu.Lock()
+			f.err = err
+			f.mu.Unlock()
+		}
+
+		select {
+		case <-time.After(backoff.Next()):
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// read connects and logs in to the server and processes packets until the
+// connection is closed or the server reports an error.
+func (f *FSDClient) read() (connected bool, err error) {
+	conn, err := net.DialTimeout("tcp", f.settings.Address, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		f.mu.Lock()
+		f.conn = nil
+		f.mu.Unlock()
+		conn.Close()
+	}()
+
+	f.mu.Lock()
+	f.conn, f.err = conn, nil
+	err = f.send("#AA%s:SERVER:%s:%s:%s:%d:%d", f.settings.Callsign, f.settings.Callsign,
+		f.settings.CID, f.settings.Password, fsdRatingObserver, fsdProtocolRevision)
+	if err == nil {
+		err = f.sendPosition()
+	}
+	f.mu.Unlock()
+	if err != nil {
+		return true, err
+	}
+
+	select {
+	case <-f.done:
+		// Closed while we were connecting.
+		return true, nil
+	default:
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		f.mu.Lock()
+		err := f.handlePacket(scanner.Text(), time.Now())
+		f.mu.Unlock()
+		if err != nil {
+			return true, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, fmt.Errorf("connection closed")
+}
+
+// send sends a single packet to the server, if connected; f.mu must be
+// held.
+func (f *FSDClient) send(format string, args ...any) error {
+	if f.conn == nil {
+		return nil
+	}
+	f.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := fmt.Fprintf(f.conn, format+"\r\n", args...)
+	return err
+}
+
+// sendPosition sends an ATC position update with our position and
+// visibility range; f.mu must be held.
+func (f *FSDClient) sendPosition() error {
+	if f.radius == 0 {
+		// Fetch hasn't been called yet.
+		return nil
+	}
+	return f.send("%%%s:%s:%d:%d:%d:%.5f:%.5f:0", f.settings.Callsign, fsdObserverFreq, fsdFacilityObserver,
+		int(f.radius), fsdRatingObserver, f.center[1], f.center[0])
+}
+
+// handlePacket updates the pilots' state given a single packet from the
+// server; f.mu must be held. An error is returned if the server reports
+// one that ends the session.
+func (f *FSDClient) handlePacket(line string, now time.Time) error {
+	line = strings.TrimRight(line, "\r")
+	switch {
+	case strings.HasPrefix(line, "@"):
+		return f.handlePilotPosition(strings.Split(line[1:], ":"), now)
+
+	case strings.HasPrefix(line, "$FP"):
+		f.handleFlightPlan(strings.Split(line[3:], ":"), now)
+
+	case strings.HasPrefix(line, "#DP"):
+		// Pilot disconnected: #DP<callsign>:<cid>
+		delete(f.pilots, strings.Split(line[3:], ":")[0])
+
+	case strings.HasPrefix(line, "$PI"):
+		// Ping: $PI<from>:<to>:<data>
+		if fields := strings.Split(line[3:], ":"); len(fields) >= 3 {
+			return f.send("$PO%s:%s:%s", f.settings.Callsign, fields[0], fields[2])
+		}
+
+	case strings.HasPrefix(line, "$ER"):
+		// Error: $ER<from>:<to>:<code>:<parameter>:<message>
+		fields := strings.Split(line[3:], ":")
+		if len(fields) < 5 {
+			return nil
+		}
+		switch code, _ := strconv.Atoi(fields[2]); code {
+		case 7, 8, 9:
+			// No such callsign, no flight plan, or no weather profile;
+			// these are replies to requests and don't end the session.
+			return nil
+		default:
+			return errors.New(fields[4])
+		}
+	}
+	return nil
+}
+
+// handlePilotPosition handles a pilot position update:
+// @<mode>:<callsign>:<squawk>:<rating>:<lat>:<lon>:<altitude>:<groundspeed>:<pbh>:<pressure delta>
+func (f *FSDClient) handlePilotPosition(fields []string, now time.Time) error {
+	if len(fields) < 9 {
+		return nil
+	}
+
+	parse := func(str string) (float32, bool) {
+		v, err := strconv.ParseFloat(str, 32)
+		return float32(v), err == nil
+	}
+	lat, latok := parse(fields[4])
+	lon, lonok := parse(fields[5])
+	if !latok || !lonok {
+		return nil
+	}
+
+	callsign := fields[1]
+	p, ok := f.pilots[callsign]
+	if !ok {
+		p = &fsdPilot{LiveTrack: LiveTrack{Callsign: callsign}}
+		f.pilots[callsign] = p
+	}
+	if p.AircraftType == "" && !p.havePosition {
+		// The server only sends flight plans when they are filed or
+		// amended, so ask for the flight plan of pilots who were already
+		// connected.
+		if err := f.send("$CQ%s:SERVER:FP:%s", f.settings.Callsign, callsign); err != nil {
+			return err
+		}
+	}
+
+	p.Position = Point2LL{lon, lat}
+	p.havePosition = true
+	p.Updated = now
+	if alt, ok := parse(fields[6]); ok {
+		p.Altitude = alt
+	}
+	if gs, ok := parse(fields[7]); ok {
+		p.Groundspeed = gs
+	}
+	// Pitch, bank, and heading are packed into 10 bits each; the heading
+	// is in bits 2-11.
+	if pbh, err := strconv.ParseInt(fields[8], 10, 64); err == nil {
+		p.Heading = float32((uint32(pbh)>>2)&0x3ff) * 360 / 1024
+	}
+	if sq, err := ParseSquawk(fields[2]); err == nil {
+		// The mode is "S" if the transponder is in standby.
+		p.Squawk, p.HaveSquawk = sq, fields[0] != "S"
+	}
+	return nil
+}
+
+// handleFlightPlan handles a flight plan:
+// $FP<callsign>:<to>:<rules>:<type>:<tas>:<departure>:<departure time>:<actual departure time>:
+// <altitude>:<arrival>:<hours enroute>:<minutes enroute>:<hours fuel>:<minutes fuel>:<alternate>:<remarks>:<route>
+func (f *FSDClient) handleFlightPlan(fields []string, now time.Time) {
+	if len(fields) < 17 {
+		return
+	}
+
+	callsign := fields[0]
+	p, ok := f.pilots[callsign]
+	if !ok {
+		// Keep it around for a while in case a position update follows.
+		p = &fsdPilot{LiveTrack: LiveTrack{Callsign: callsign, Updated: now}}
+		f.pilots[callsign] = p
+	}
+	p.Rules = Select(fields[2] == "V", FlightRules(VFR), FlightRules(IFR))
+	p.AircraftType = fields[3]
+	p.DepartureAirport = fields[5]
+	p.FiledAltitude = parseFiledAltitude(fields[8])
+	p.ArrivalAirport = fields[9]
+	p.Remarks = fields[15]
+	p.Route = fields[16]
+}
+
+func (f *FSDClient) Fetch(center Point2LL, radius float32) ([]LiveTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	f.center, f.radius = center, radius
+	if err := f.sendPosition(); err != nil {
+		return nil, err
+	}
+
+	var tracks []LiveTrack
+	for callsign, p := range f.pilots {
+		if time.Since(p.Updated) > FSDPilotTimeout {
+			delete(f.pilots, callsign)
+		} else if p.havePosition && nmdistance2ll(center, p.Position) <= radius {
+			tracks = append(tracks, p.LiveTrack)
+		}
+	}
+	return tracks, nil
+}
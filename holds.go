@@ -0,0 +1,265 @@
+// holds.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// HoldsPane
+
+// HoldsPane manages holding patterns that the controller has defined.
+// Each one is drawn as a racetrack on the radar scope; the pane lists the
+// aircraft that have been assigned to each hold along with how long they
+// have been holding and their expect further clearance (EFC) times.
+type HoldsPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+
+	Holds []ControllerHold
+
+	// UI state for adding holds and aircraft
+	newHold     ControllerHold
+	newCallsign []string // per hold
+	newEFC      []string // per hold
+}
+
+// ControllerHold describes a holding pattern and the aircraft in it.
+type ControllerHold struct {
+	Fix           string
+	InboundCourse int // magnetic
+	RightTurns    bool
+	LegLength     float32 // nm
+	Aircraft      []HoldingAircraft
+}
+
+type HoldingAircraft struct {
+	Callsign string
+	EFC      string    // Zulu, HHMM; may be empty
+	Entered  time.Time // sim time; zero until the pane first sees it
+}
+
+// Approximate turn radius for the ends of the racetrack, corresponding to
+// a standard rate turn at a typical holding speed.
+const holdTurnRadius = 1.5 // nm
+
+func NewHoldsPane() *HoldsPane {
+	return &HoldsPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (hp *HoldsPane) Name() string { return "Holds" }
+
+func (hp *HoldsPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if hp.font = GetFont(hp.FontIdentifier); hp.font == nil {
+		hp.font = GetDefaultFont()
+		hp.FontIdentifier = hp.font.id
+	}
+	if hp.scrollbar == nil {
+		hp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+	hp.newHold = ControllerHold{RightTurns: true, LegLength: 5}
+}
+
+func (hp *HoldsPane) Deactivate()                {}
+func (hp *HoldsPane) ResetWorld(w *World)        { hp.Holds = nil }
+func (hp *HoldsPane) CanTakeKeyboardFocus() bool { return false }
+
+func (hp *HoldsPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&hp.FontIdentifier, "Font"); changed {
+		hp.font = newFont
+	}
+
+	imgui.Separator()
+	imgui.Text("New hold")
+	nh := &hp.newHold
+	imgui.InputTextV("Fix", &nh.Fix, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+	course := int32(nh.InboundCourse)
+	imgui.SliderInt("Inbound course", &course, 1, 360)
+	nh.InboundCourse = int(course)
+	imgui.Checkbox("Right turns", &nh.RightTurns)
+	imgui.SliderFloatV("Leg length (nm)", &nh.LegLength, 2, 20, "%.0f", 0)
+	if imgui.Button("Add hold") && nh.Fix != "" {
+		hp.Holds = append(hp.Holds, *nh)
+		nh.Fix = ""
+	}
+
+	for len(hp.newCallsign) < len(hp.Holds) {
+		hp.newCallsign = append(hp.newCallsign, "")
+		hp.newEFC = append(hp.newEFC, "")
+	}
+
+	deleteIndex := -1
+	for i := range hp.Holds {
+		h := &hp.Holds[i]
+		imgui.PushID(strconv.Itoa(i))
+		imgui.Separator()
+		imgui.Text(h.Description())
+		imgui.SameLine()
+		if imgui.Button("Delete") {
+			deleteIndex = i
+		}
+
+		imgui.InputTextV("Callsign", &hp.newCallsign[i], imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+		imgui.InputTextV("EFC (HHMM Zulu)", &hp.newEFC[i], imgui.InputTextFlagsCharsDecimal, nil)
+		if imgui.Button("Hold aircraft") && hp.newCallsign[i] != "" {
+			h.Aircraft = append(h.Aircraft, HoldingAircraft{Callsign: hp.newCallsign[i], EFC: hp.newEFC[i]})
+			hp.newCallsign[i], hp.newEFC[i] = "", ""
+		}
+
+		removeIndex := -1
+		for j, ac := range h.Aircraft {
+			imgui.PushID(ac.Callsign)
+			imgui.Text(ac.Callsign)
+			imgui.SameLine()
+			imgui.InputTextV("EFC", &h.Aircraft[j].EFC, imgui.InputTextFlagsCharsDecimal, nil)
+			imgui.SameLine()
+			if imgui.Button("Release") {
+				removeIndex = j
+			}
+			imgui.PopID()
+		}
+		if removeIndex != -1 {
+			h.Aircraft = DeleteSliceElement(h.Aircraft, removeIndex)
+		}
+		imgui.PopID()
+	}
+	if deleteIndex != -1 {
+		hp.Holds = DeleteSliceElement(hp.Holds, deleteIndex)
+		hp.newCallsign = DeleteSliceElement(hp.newCallsign, deleteIndex)
+		hp.newEFC = DeleteSliceElement(hp.newEFC, deleteIndex)
+	}
+}
+
+func (h *ControllerHold) Description() string {
+	return fmt.Sprintf("%s %03d inbound, %s turns, %.0fnm legs", h.Fix, h.InboundCourse,
+		Select(h.RightTurns, "right", "left"), h.LegLength)
+}
+
+// Racetrack returns a polyline that approximates the holding pattern's
+// racetrack, or nil if the fix is unknown.
+func (h *ControllerHold) Racetrack(w *World) []Point2LL {
+	fix, ok := w.Locate(h.Fix)
+	if !ok {
+		return nil
+	}
+	nmPerLongitude := w.NmPerLongitude
+
+	// All of this is done in nm coordinates: d is the inbound direction
+	// and p points from the inbound leg toward the outbound leg.
+	hdg := radians(float32(h.InboundCourse) - w.MagneticVariation)
+	d := [2]float32{sin(hdg), cos(hdg)}
+	p := Select(h.RightTurns, [2]float32{d[1], -d[0]}, [2]float32{-d[1], d[0]})
+
+	f := ll2nm(fix, nmPerLongitude)
+	a := sub2f(f, scale2f(d, h.LegLength)) // start of the inbound leg
+	r := float32(holdTurnRadius)
+
+	var pts [][2]float32
+	pts = append(pts, a, f)
+	// Turn to the outbound leg, then the turn back to the inbound leg.
+	const n = 16
+	for i := 1; i <= n; i++ {
+		theta := float32(i) / n * math.Pi
+		v := add2f(scale2f(p, -cos(theta)), scale2f(d, sin(theta)))
+		pts = append(pts, add2f(add2f(f, scale2f(p, r)), scale2f(v, r)))
+	}
+	for i := 1; i <= n; i++ {
+		theta := float32(i) / n * math.Pi
+		v := sub2f(scale2f(p, cos(theta)), scale2f(d, sin(theta)))
+		pts = append(pts, add2f(add2f(a, scale2f(p, r)), scale2f(v, r)))
+	}
+
+	return MapSlice(pts, func(p [2]float32) Point2LL { return nm2ll(p, nmPerLongitude) })
+}
+
+// efcTime returns the time corresponding to an EFC given as HHMM that is
+// closest to now, allowing for EFCs on either side of midnight.
+func efcTime(efc string, now time.Time) (time.Time, bool) {
+	hhmm, err := strconv.Atoi(efc)
+	if err != nil || len(efc) != 4 || hhmm/100 > 23 || hhmm%100 > 59 {
+		return time.Time{}, false
+	}
+	now = now.UTC()
+	t := time.Date(now.Year(), now.Month(), now.Day(), hhmm/100, hhmm%100, 0, 0, time.UTC)
+	if now.Sub(t) > 12*time.Hour {
+		t = t.Add(24 * time.Hour)
+	} else if t.Sub(now) > 12*time.Hour {
+		t = t.Add(-24 * time.Hour)
+	}
+	return t, true
+}
+
+func (hp *HoldsPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	w := ctx.world
+	now := w.CurrentTime()
+
+	type line struct {
+		text  string
+		color RGB
+	}
+	var lines []line
+	for i := range hp.Holds {
+		h := &hp.Holds[i]
+
+		// Drop aircraft that have left the sim and start the clock for
+		// newly-added ones.
+		h.Aircraft = FilterSlice(h.Aircraft, func(ac HoldingAircraft) bool {
+			_, ok := w.Aircraft[ac.Callsign]
+			return ok
+		})
+		for j := range h.Aircraft {
+			if h.Aircraft[j].Entered.IsZero() {
+				h.Aircraft[j].Entered = now
+			}
+		}
+
+		lines = append(lines, line{text: h.Description(), color: UITextHighlightColor})
+		for _, ac := range h.Aircraft {
+			held := now.Sub(ac.Entered)
+			text := fmt.Sprintf("  %-8s %02d:%02d", ac.Callsign, int(held.Minutes()), int(held.Seconds())%60)
+			color := UITextColor
+			if efc, ok := efcTime(ac.EFC, now); ok {
+				text += " EFC " + ac.EFC
+				if now.After(efc) {
+					color = UICautionColor
+				}
+			}
+			lines = append(lines, line{text: text, color: color})
+		}
+	}
+
+	lineHeight := float32(hp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	hp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	indent := float32(2)
+	y := ctx.paneExtent.Height() - 1
+	for _, l := range lines[hp.scrollbar.Offset():] {
+		td.AddText(l.text, [2]float32{indent, y}, TextStyle{Font: hp.font, Color: l.color})
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	hp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
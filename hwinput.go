@@ -0,0 +1,224 @@
+// hwinput.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Hardware input devices (game controllers, button boxes, and the like)
+// can have their buttons bound to the same commands that keys can be
+// bound to (see keymap.go). Devices are polled once per frame and the
+// commands for newly pressed buttons are queued up; they are run by the
+// STARS scope. Devices like the Stream Deck that are configured to send
+// keystrokes don't need anything special here; their keys can be bound
+// directly in the keymaps.
+
+// InputDevice is a source of button presses.
+type InputDevice interface {
+	// ID returns an identifier for the device that is stable across
+	// runs, so that it can be used to save its configuration.
+	ID() string
+	Name() string
+	// Buttons returns the current state of each of the device's buttons.
+	Buttons() []bool
+}
+
+// joystickDevice is an InputDevice for the joysticks and game controllers
+// that GLFW supports.
+type joystickDevice struct {
+	joy glfw.Joystick
+}
+
+func (j joystickDevice) ID() string   { return j.joy.GetGUID() }
+func (j joystickDevice) Name() string { return j.joy.GetName() }
+
+func (j joystickDevice) Buttons() []bool {
+	var b []bool
+	for _, a := range j.joy.GetButtons() {
+		b = append(b, a == glfw.Press)
+	}
+	return b
+}
+
+// getInputDevices returns all of the currently-connected devices.
+func getInputDevices() []InputDevice {
+	var devices []InputDevice
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		if joy.Present() {
+			devices = append(devices, joystickDevice{joy: joy})
+		}
+	}
+	return devices
+}
+
+// InputDeviceConfig stores the button bindings for a single device.
+type InputDeviceConfig struct {
+	Name     string
+	Enabled  bool
+	Bindings map[int]string // button index -> command
+}
+
+type HardwareInput struct {
+	Devices map[string]*InputDeviceConfig // indexed by InputDevice ID()
+
+	devices     []InputDevice
+	lastButtons map[string][]bool
+	commands    []string
+	lastPoll    int
+	// State for binding a button: when learnWaiting is set, the next
+	// button pressed on learnDevice is selected.
+	learnDevice  string
+	learnWaiting bool
+	learnButton  int
+	learnCommand string
+}
+
+// Update polls the connected devices, queueing the commands for any
+// buttons that have been pressed since the last call. It returns true if
+// any button was pressed.
+func (hi *HardwareInput) Update() bool {
+	if hi.Devices == nil {
+		hi.Devices = make(map[string]*InputDeviceConfig)
+	}
+	if hi.lastButtons == nil {
+		hi.lastButtons = make(map[string][]bool)
+	}
+
+	// Checking for newly-connected devices is relatively expensive, so
+	// only do it every so often.
+	if hi.lastPoll == 0 {
+		hi.devices = getInputDevices()
+		for _, dev := range hi.devices {
+			if _, ok := hi.Devices[dev.ID()]; !ok {
+				hi.Devices[dev.ID()] = &InputDeviceConfig{
+					Name:     dev.Name(),
+					Enabled:  true,
+					Bindings: make(map[int]string),
+				}
+			}
+		}
+	}
+	hi.lastPoll = (hi.lastPoll + 1) % 60
+
+	// Commands that weren't taken last frame (e.g., because there's no
+	// STARS scope) are dropped.
+	hi.commands = nil
+
+	pressed := false
+	for _, dev := range hi.devices {
+		id := dev.ID()
+		buttons := dev.Buttons()
+		last := hi.lastButtons[id]
+		for i, b := range buttons {
+			if !b || (i < len(last) && last[i]) {
+				continue
+			}
+			pressed = true
+			if id == hi.learnDevice && hi.learnWaiting {
+				hi.learnButton = i
+				hi.learnWaiting = false
+			} else if cfg := hi.Devices[id]; cfg.Enabled {
+				if cmd, ok := cfg.Bindings[i]; ok {
+					hi.commands = append(hi.commands, cmd)
+				}
+			}
+		}
+		hi.lastButtons[id] = buttons
+	}
+	return pressed
+}
+
+// TakeCommands returns the queued commands and clears the queue.
+func (hi *HardwareInput) TakeCommands() []string {
+	cmds := hi.commands
+	hi.commands = nil
+	return cmds
+}
+
+func (hi *HardwareInput) DrawUI() {
+	if len(hi.Devices) == 0 {
+		imgui.Text("No input devices have been detected.")
+		return
+	}
+
+	for _, id := range SortedMapKeys(hi.Devices) {
+		cfg := hi.Devices[id]
+		connected := slices.ContainsFunc(hi.devices, func(d InputDevice) bool { return d.ID() == id })
+
+		imgui.PushID(id)
+		label := cfg.Name + Select(connected, "", " (not connected)")
+		if imgui.TreeNodeV(label, imgui.TreeNodeFlagsDefaultOpen) {
+			imgui.Checkbox("Enabled", &cfg.Enabled)
+
+			flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg | imgui.TableFlagsSizingStretchProp
+			if len(cfg.Bindings) > 0 && imgui.BeginTableV("buttons", 3, flags, imgui.Vec2{}, 0) {
+				imgui.TableSetupColumn("Button")
+				imgui.TableSetupColumn("Command")
+				imgui.TableSetupColumn("")
+				imgui.TableHeadersRow()
+
+				remove := -1
+				for _, button := range SortedMapKeys(cfg.Bindings) {
+					imgui.PushID(strconv.Itoa(button))
+					imgui.TableNextRow()
+					imgui.TableNextColumn()
+					imgui.Text(strconv.Itoa(button + 1))
+					imgui.TableNextColumn()
+					imgui.Text(cfg.Bindings[button])
+					imgui.TableNextColumn()
+					if imgui.Button(FontAwesomeIconTrash) {
+						remove = button
+					}
+					imgui.PopID()
+				}
+				imgui.EndTable()
+				if remove != -1 {
+					delete(cfg.Bindings, remove)
+				}
+			}
+
+			if connected {
+				learned := hi.learnDevice == id && !hi.learnWaiting
+				if hi.learnDevice == id && hi.learnWaiting {
+					imgui.Text("Press a button on the device...")
+				} else {
+					if imgui.Button("Select button") {
+						hi.learnDevice, hi.learnWaiting = id, true
+					}
+					if learned {
+						imgui.SameLine()
+						imgui.Text(fmt.Sprintf("Button %d", hi.learnButton+1))
+					}
+				}
+				if imgui.BeginComboV("Command", hi.learnCommand, imgui.ComboFlagsHeightLarge) {
+					for _, cmd := range keyCommands {
+						if imgui.SelectableV(cmd.Name, cmd.Name == hi.learnCommand, 0, imgui.Vec2{}) {
+							hi.learnCommand = cmd.Name
+						}
+						if imgui.IsItemHovered() {
+							imgui.SetTooltip(cmd.Description)
+						}
+					}
+					imgui.EndCombo()
+				}
+				if learned && hi.learnCommand != "" && imgui.Button("Bind") {
+					cfg.Bindings[hi.learnButton] = hi.learnCommand
+					hi.learnDevice = ""
+				}
+			}
+			if !connected && imgui.Button("Forget device") {
+				delete(hi.Devices, id)
+			}
+			imgui.TreePop()
+		}
+		imgui.PopID()
+	}
+}
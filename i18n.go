@@ -0,0 +1,210 @@
+// i18n.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// UI text is translated by looking up the English text in the current
+// locale's table of translations; text without a translation is shown in
+// English. Locales are JSON files that map English strings to their
+// translations; the "_name" entry gives the name of the language to show
+// in the UI. They're loaded from resources/locales and from the locales
+// directory next to the config file, where users can add their own or
+// override the built-in ones. (Datablocks and other scope content are
+// standard and are never translated.)
+
+type Locale struct {
+	Code    string
+	Name    string
+	Strings map[string]string
+}
+
+var (
+	currentLocale *Locale
+	// English strings that have been looked up but that the current
+	// locale doesn't have a translation for; these can be exported to
+	// make it easier to add translations.
+	untranslated = make(map[string]interface{})
+)
+
+// Tr returns the translation of the given UI text for the current locale.
+func Tr(s string) string {
+	if currentLocale == nil {
+		return s
+	}
+	if t, ok := currentLocale.Strings[s]; ok && t != "" {
+		return t
+	}
+	untranslated[s] = nil
+	return s
+}
+
+// Trf translates the given format string and then formats it with the
+// provided arguments.
+func Trf(format string, args ...interface{}) string {
+	return fmt.Sprintf(Tr(format), args...)
+}
+
+func userLocalesDirectory() string {
+	return path.Join(path.Dir(configFilePath()), "locales")
+}
+
+func parseLocale(code string, b []byte) (*Locale, error) {
+	var strs map[string]string
+	if err := json.Unmarshal(b, &strs); err != nil {
+		return nil, err
+	}
+	l := &Locale{Code: code, Name: strs["_name"], Strings: strs}
+	if l.Name == "" {
+		l.Name = code
+	}
+	return l, nil
+}
+
+// loadLocales returns all of the available locales, indexed by their
+// code (the file name without the extension). User locales are merged
+// with built-in ones with the same code, taking precedence.
+func loadLocales() map[string]*Locale {
+	locales := make(map[string]*Locale)
+	add := func(filename string, b []byte) {
+		code := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		l, err := parseLocale(code, b)
+		if err != nil {
+			lg.Errorf("%s: %v", filename, err)
+			return
+		}
+		if existing, ok := locales[code]; ok {
+			for k, v := range l.Strings {
+				existing.Strings[k] = v
+			}
+			if _, ok := l.Strings["_name"]; ok {
+				existing.Name = l.Name
+			}
+		} else {
+			locales[code] = l
+		}
+	}
+
+	if entries, err := fs.ReadDir(resourcesFS, "locales"); err == nil {
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".json") {
+				fn := path.Join("locales", e.Name())
+				if b, err := fs.ReadFile(resourcesFS, fn); err != nil {
+					lg.Errorf("%s: %v", fn, err)
+				} else {
+					add(fn, b)
+				}
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir(userLocalesDirectory()); err == nil {
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".json") {
+				fn := filepath.Join(userLocalesDirectory(), e.Name())
+				if b, err := os.ReadFile(fn); err != nil {
+					lg.Errorf("%s: %v", fn, err)
+				} else {
+					add(fn, b)
+				}
+			}
+		}
+	}
+
+	return locales
+}
+
+// SetLanguage sets the locale used for UI text; the empty string selects
+// English.
+func SetLanguage(code string) {
+	clear(untranslated)
+	if code == "" {
+		currentLocale = nil
+		return
+	}
+	if l, ok := loadLocales()[code]; ok {
+		currentLocale = l
+	} else {
+		lg.Errorf("%s: language not found", code)
+		currentLocale = nil
+	}
+}
+
+// exportUntranslated writes a locale file with the strings that have been
+// shown without a translation so far, for use as a starting point for
+// translating them.
+func exportUntranslated() (string, error) {
+	strs := make(map[string]string)
+	if currentLocale != nil {
+		for k, v := range currentLocale.Strings {
+			strs[k] = v
+		}
+	}
+	for s := range untranslated {
+		strs[s] = ""
+	}
+
+	code := "untranslated"
+	if currentLocale != nil {
+		code = currentLocale.Code
+	}
+	if err := os.MkdirAll(userLocalesDirectory(), 0o755); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(userLocalesDirectory(), code+"-template.json")
+
+	b, err := json.MarshalIndent(strs, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return fn, os.WriteFile(fn, b, 0o644)
+}
+
+var languageUIStatus string
+
+func drawLanguageUI() {
+	locales := loadLocales()
+	current := "English"
+	if currentLocale != nil {
+		current = currentLocale.Name
+	}
+
+	if imgui.BeginComboV(Tr("Language"), current, imgui.ComboFlagsHeightLarge) {
+		if imgui.SelectableV("English", currentLocale == nil, 0, imgui.Vec2{}) {
+			globalConfig.Language = ""
+			SetLanguage("")
+		}
+		for _, code := range SortedMapKeys(locales) {
+			l := locales[code]
+			if imgui.SelectableV(l.Name, currentLocale != nil && currentLocale.Code == code, 0, imgui.Vec2{}) {
+				globalConfig.Language = code
+				SetLanguage(code)
+			}
+		}
+		imgui.EndCombo()
+	}
+
+	imgui.Text(Tr("Additional translations can be added to") + " " + userLocalesDirectory())
+	if imgui.Button(Tr("Export untranslated text")) {
+		if fn, err := exportUntranslated(); err != nil {
+			languageUIStatus = err.Error()
+		} else {
+			languageUIStatus = Tr("Saved") + " " + fn
+		}
+	}
+	if languageUIStatus != "" {
+		imgui.Text(languageUIStatus)
+	}
+}
@@ -0,0 +1,133 @@
+// instructor.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// InstructorEventType enumerates the abnormal situations that the
+// instructor (the controller with launch control) can inject into a
+// running sim for training.
+type InstructorEventType int
+
+const (
+	InstructorRadioFailure InstructorEventType = iota
+	InstructorRestoreRadio
+	InstructorEmergency
+	InstructorMissedApproach
+	InstructorPopUpVFR
+	InstructorWindShift
+)
+
+func (t InstructorEventType) String() string {
+	return [...]string{"Radio failure", "Restore radio", "Emergency", "Missed approach",
+		"Pop-up VFR", "Wind shift"}[t]
+}
+
+type InstructorEvent struct {
+	Type       InstructorEventType
+	Callsign   string // aircraft for radio failures, emergencies, and missed approaches
+	Controller string // who a pop-up VFR calls
+	Wind       Wind   // new wind for a wind shift
+}
+
+// InstructorConsole is the window that the instructor uses to inject
+// events.
+type InstructorConsole struct {
+	callsign   string
+	controller string
+	wind       Wind
+	status     string
+}
+
+func (ic *InstructorConsole) Draw(w *World, show *bool) {
+	if ic.controller == "" {
+		ic.controller = w.Callsign
+		ic.wind = w.Wind
+	}
+	inject := func(ev InstructorEvent, what string) {
+		ic.status = what
+		w.InjectInstructorEvent(ev, func(err error) { ic.status = what + ": " + err.Error() })
+	}
+
+	imgui.BeginV("Instructor", show, imgui.WindowFlagsAlwaysAutoResize)
+
+	imgui.Text("Aircraft")
+	if _, ok := w.Aircraft[ic.callsign]; !ok {
+		ic.callsign = ""
+	}
+	if imgui.BeginComboV("Callsign", ic.callsign, imgui.ComboFlagsHeightLarge) {
+		for _, callsign := range SortedMapKeys(w.Aircraft) {
+			if imgui.SelectableV(callsign, callsign == ic.callsign, 0, imgui.Vec2{}) {
+				ic.callsign = callsign
+			}
+		}
+		imgui.EndCombo()
+	}
+
+	uiStartDisable(ic.callsign == "")
+	ac := w.Aircraft[ic.callsign]
+	if ac != nil && ac.NORDO {
+		if imgui.Button("Restore radio") {
+			inject(InstructorEvent{Type: InstructorRestoreRadio, Callsign: ic.callsign},
+				ic.callsign+" radio restored")
+		}
+	} else if imgui.Button("Radio failure (NORDO)") {
+		inject(InstructorEvent{Type: InstructorRadioFailure, Callsign: ic.callsign}, ic.callsign+" NORDO")
+	}
+	imgui.SameLine()
+	if imgui.Button("Declare emergency") {
+		inject(InstructorEvent{Type: InstructorEmergency, Callsign: ic.callsign},
+			ic.callsign+" declared an emergency")
+	}
+	imgui.SameLine()
+	uiStartDisable(ac == nil || !ac.Nav.Approach.Cleared)
+	if imgui.Button("Missed approach") {
+		inject(InstructorEvent{Type: InstructorMissedApproach, Callsign: ic.callsign},
+			ic.callsign+" going around")
+	}
+	uiEndDisable(ac == nil || !ac.Nav.Approach.Cleared)
+	uiEndDisable(ic.callsign == "")
+
+	imgui.Separator()
+	imgui.Text("Pop-up VFR")
+	if imgui.BeginComboV("Calls", ic.controller, imgui.ComboFlagsHeightLarge) {
+		for _, callsign := range SortedMapKeys(w.Controllers) {
+			if imgui.SelectableV(callsign, callsign == ic.controller, 0, imgui.Vec2{}) {
+				ic.controller = callsign
+			}
+		}
+		imgui.EndCombo()
+	}
+	if imgui.Button("Launch pop-up VFR") {
+		inject(InstructorEvent{Type: InstructorPopUpVFR, Controller: ic.controller},
+			"Launched pop-up VFR calling "+ic.controller)
+	}
+
+	imgui.Separator()
+	imgui.Text(fmt.Sprintf("Wind: currently %03d at %d", w.Wind.Direction, w.Wind.Speed) +
+		Select(w.Wind.Gust != 0, fmt.Sprintf(" gust %d", w.Wind.Gust), ""))
+	imgui.SliderInt("Direction", &ic.wind.Direction, 0, 359)
+	imgui.SliderInt("Speed", &ic.wind.Speed, 0, 60)
+	imgui.SliderInt("Gust", &ic.wind.Gust, 0, 80)
+	if imgui.Button("Shift wind") {
+		wind := ic.wind
+		if wind.Gust <= wind.Speed {
+			wind.Gust = 0
+		}
+		inject(InstructorEvent{Type: InstructorWindShift, Wind: wind},
+			fmt.Sprintf("Wind shifted to %03d at %d", wind.Direction, wind.Speed))
+	}
+
+	if ic.status != "" {
+		imgui.Separator()
+		imgui.Text(ic.status)
+	}
+
+	imgui.End()
+}
@@ -0,0 +1,450 @@
+// keymap.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Keyboard shortcuts are given by keymaps that map key chords (e.g.,
+// "Ctrl+F3") to named commands. The first keymap is the base one and is
+// always active; the others are layers that can be enabled and disabled
+// (including via a "layer:" command bound to a key) and take precedence
+// over the ones before them when enabled.
+
+// KeyChord is a key along with the modifier keys that are held with it.
+type KeyChord struct {
+	Key                 Key
+	Control, Alt, Shift bool
+}
+
+var keyNames = map[Key]string{
+	KeyEnter: "Enter", KeyUpArrow: "Up", KeyDownArrow: "Down", KeyLeftArrow: "Left",
+	KeyRightArrow: "Right", KeyHome: "Home", KeyEnd: "End", KeyDelete: "Delete",
+	KeyEscape: "Esc", KeyTab: "Tab", KeyPageUp: "PageUp", KeyPageDown: "PageDown",
+	KeyF1: "F1", KeyF2: "F2", KeyF3: "F3", KeyF4: "F4", KeyF5: "F5", KeyF6: "F6",
+	KeyF7: "F7", KeyF8: "F8", KeyF9: "F9", KeyF10: "F10", KeyF11: "F11", KeyF12: "F12",
+	KeyY: "Y", KeyZ: "Z",
+}
+
+func init() {
+	for i := 0; i < 10; i++ {
+		keyNames[Key(int(Key0)+i)] = fmt.Sprintf("%d", i)
+		keyNames[Key(int(KeyKP0)+i)] = fmt.Sprintf("KP%d", i)
+	}
+}
+
+func (kc KeyChord) String() string {
+	var s string
+	if kc.Control {
+		s += "Ctrl+"
+	}
+	if kc.Alt {
+		s += "Alt+"
+	}
+	if kc.Shift {
+		s += "Shift+"
+	}
+	return s + keyNames[kc.Key]
+}
+
+// ParseKeyChord parses a key chord of the form returned by
+// KeyChord.String, e.g. "Ctrl+Shift+F3". Modifiers and key names are not
+// case-sensitive.
+func ParseKeyChord(s string) (KeyChord, error) {
+	var kc KeyChord
+	f := strings.Split(s, "+")
+	for _, mod := range f[:len(f)-1] {
+		switch strings.ToLower(strings.TrimSpace(mod)) {
+		case "ctrl", "control":
+			kc.Control = true
+		case "alt":
+			kc.Alt = true
+		case "shift":
+			kc.Shift = true
+		default:
+			return KeyChord{}, fmt.Errorf("%s: unknown modifier key", mod)
+		}
+	}
+
+	name := strings.TrimSpace(f[len(f)-1])
+	for key, kn := range keyNames {
+		if strings.EqualFold(kn, name) {
+			kc.Key = key
+			return kc, nil
+		}
+	}
+	return KeyChord{}, fmt.Errorf("%s: unknown key", name)
+}
+
+// PressedKeyChords returns the chords corresponding to the keys pressed
+// in the current frame. Digits that are entered while Control or Alt are
+// held are returned as Key0-Key9 chords, since they are not otherwise
+// reported as pressed keys.
+func PressedKeyChords(keyboard *KeyboardState) []KeyChord {
+	mods := KeyChord{
+		Control: keyboard.IsPressed(KeyControl),
+		Alt:     keyboard.IsPressed(KeyAlt),
+		Shift:   keyboard.IsPressed(KeyShift),
+	}
+
+	var chords []KeyChord
+	for key := range keyboard.Pressed {
+		if _, ok := keyNames[key]; ok {
+			kc := mods
+			kc.Key = key
+			chords = append(chords, kc)
+		}
+	}
+	if mods.Control || mods.Alt {
+		for _, ch := range keyboard.Input {
+			if ch >= '0' && ch <= '9' {
+				kc := mods
+				kc.Key = Key(int(Key0) + int(ch-'0'))
+				chords = append(chords, kc)
+			}
+		}
+	}
+	return chords
+}
+
+// ConsumeKeyChord removes the character that corresponds to a chord that
+// has been handled from the keyboard input so that it isn't also
+// processed as text.
+func ConsumeKeyChord(keyboard *KeyboardState, kc KeyChord) {
+	var ch string
+	if kc.Key >= Key0 && kc.Key <= Key9 {
+		ch = fmt.Sprintf("%d", kc.Key-Key0)
+	} else if kc.Key >= KeyKP0 && kc.Key <= KeyKP9 {
+		ch = fmt.Sprintf("%d", kc.Key-KeyKP0)
+	} else if kc.Key == KeyY || kc.Key == KeyZ {
+		ch = keyNames[kc.Key]
+		if !kc.Shift {
+			ch = strings.ToLower(ch)
+		}
+	} else {
+		return
+	}
+	keyboard.Input = strings.Replace(keyboard.Input, ch, "", 1)
+}
+
+// KeyCommand describes a command that can be bound to a key.
+type KeyCommand struct {
+	Name        string
+	Description string
+}
+
+// LayerCommandPrefix is the prefix for commands that toggle a keymap
+// layer; it is followed by the layer's name.
+const LayerCommandPrefix = "layer:"
+
+var keyCommands = []KeyCommand{
+	{"focus-messages", "Move keyboard focus to the messages pane"},
+	{"stars.recenter", "Recenter the scope"},
+	{"stars.clear", "Clear input and return to the minimal command mode"},
+	{"stars.cancel", "Cancel input and return to the main DCB menu"},
+	{"stars.maps", "Maps command mode / DCB menu"},
+	{"stars.brite", "DCB brightness menu"},
+	{"stars.initiate-control", "Initiate control command mode"},
+	{"stars.leader-length", "Leader line length spinner"},
+	{"stars.terminate-control", "Terminate control command mode"},
+	{"stars.char-size", "DCB character size menu"},
+	{"stars.handoff", "Handoff command mode"},
+	{"stars.flight-data", "Flight data command mode"},
+	{"stars.aux-menu", "Toggle the auxiliary DCB menu"},
+	{"stars.multifunc", "Multi-function command mode"},
+	{"stars.toggle-dcb", "Show or hide the DCB"},
+	{"stars.range-rings", "Range ring spacing spinner"},
+	{"stars.vfr-plan", "VFR flight plan command mode"},
+	{"stars.range", "Range spinner"},
+	{"stars.site", "DCB radar site menu"},
+	{"stars.collision-alert", "Collision alert command mode"},
+	{"stars.zoom-in", "Decrease the scope range"},
+	{"stars.zoom-out", "Increase the scope range"},
+	{"stars.select-aircraft", "Select the aircraft under the cursor"},
+	{"stars.full-trail", "Show or hide the complete path flown by the aircraft under the cursor or the selected aircraft"},
+	{"stars.accept-handoff", "Accept the handoff of the aircraft under the cursor or else of an inbound aircraft"},
+	{"stars.toggle-weather", "Hide or restore the weather display"},
+	{"stars.undo", "Undo the last change to the scope settings"},
+	{"stars.redo", "Redo the last undone change to the scope settings"},
+	{"screenshot", "Save a screenshot of the scope and copy it to the clipboard"},
+	{"screenshot-window", "Save a screenshot of the entire window and copy it to the clipboard"},
+}
+
+func init() {
+	for i := 0; i < 10; i++ {
+		keyCommands = append(keyCommands,
+			KeyCommand{fmt.Sprintf("stars.bookmark-recall-%d", i), fmt.Sprintf("Recall bookmark %d", i)},
+			KeyCommand{fmt.Sprintf("stars.bookmark-save-%d", i), fmt.Sprintf("Save the current view to bookmark %d", i)})
+	}
+	for i := 1; i <= NumSTARSPreferenceSets; i++ {
+		keyCommands = append(keyCommands,
+			KeyCommand{fmt.Sprintf("stars.prefset-%d", i), fmt.Sprintf("Switch to preference set %d", i)})
+	}
+	for i := 1; i <= 9; i++ {
+		keyCommands = append(keyCommands,
+			KeyCommand{fmt.Sprintf("stars.leader-direction-%d", i),
+				fmt.Sprintf("Point the leader line of the aircraft under the cursor in numpad direction %d", i)})
+	}
+}
+
+// Keymap is a set of key bindings.
+type Keymap struct {
+	Name     string
+	Enabled  bool              // ignored for the base keymap, which is always active
+	Bindings map[string]string // KeyChord.String() -> command
+}
+
+type KeyBindings struct {
+	Keymaps []*Keymap
+
+	// UI state
+	newChord   string
+	newCommand string
+	status     string
+	fileDialog *FileSelectDialogBox
+}
+
+// DefaultKeyBindings returns the standard STARS keyboard shortcuts, along
+// with a disabled layer that maps the numeric keypad to leader line
+// directions.
+func DefaultKeyBindings() *KeyBindings {
+	base := &Keymap{
+		Name:    "Default",
+		Enabled: true,
+		Bindings: map[string]string{
+			"Tab":      "focus-messages",
+			"End":      "stars.clear",
+			"Esc":      "stars.cancel",
+			"Ctrl+F1":  "stars.recenter",
+			"Ctrl+F2":  "stars.maps",
+			"F3":       "stars.initiate-control",
+			"Ctrl+F3":  "stars.brite",
+			"F4":       "stars.terminate-control",
+			"Ctrl+F4":  "stars.leader-length",
+			"F5":       "stars.handoff",
+			"Ctrl+F5":  "stars.char-size",
+			"F6":       "stars.flight-data",
+			"F7":       "stars.multifunc",
+			"Ctrl+F7":  "stars.aux-menu",
+			"Ctrl+F8":  "stars.toggle-dcb",
+			"F9":       "stars.vfr-plan",
+			"Ctrl+F9":  "stars.range-rings",
+			"Ctrl+F10": "stars.range",
+			"F11":      "stars.collision-alert",
+			"Ctrl+F11": "stars.site",
+			"Ctrl+Z":   "stars.undo",
+			"Ctrl+Y":   "stars.redo",
+			"Ctrl+T":   "stars.full-trail",
+			"F12":      "screenshot",
+			"Ctrl+F12": "screenshot-window",
+		},
+	}
+	numpad := &Keymap{Name: "Numpad leader directions", Bindings: make(map[string]string)}
+	for i := 0; i < 10; i++ {
+		base.Bindings[fmt.Sprintf("Ctrl+%d", i)] = fmt.Sprintf("stars.bookmark-recall-%d", i)
+		base.Bindings[fmt.Sprintf("Ctrl+Alt+%d", i)] = fmt.Sprintf("stars.bookmark-save-%d", i)
+		if i > 0 {
+			numpad.Bindings[fmt.Sprintf("KP%d", i)] = fmt.Sprintf("stars.leader-direction-%d", i)
+		}
+	}
+	return &KeyBindings{Keymaps: []*Keymap{base, numpad}}
+}
+
+// Lookup returns the command bound to the given chord, if any.
+func (kb *KeyBindings) Lookup(kc KeyChord) (string, bool) {
+	s := kc.String()
+	for i := len(kb.Keymaps) - 1; i >= 0; i-- {
+		km := kb.Keymaps[i]
+		if i > 0 && !km.Enabled {
+			continue
+		}
+		if cmd, ok := km.Bindings[s]; ok {
+			return cmd, true
+		}
+	}
+	return "", false
+}
+
+// ToggleLayer enables or disables the named keymap layer.
+func (kb *KeyBindings) ToggleLayer(name string) {
+	for _, km := range kb.Keymaps[1:] {
+		if km.Name == name {
+			km.Enabled = !km.Enabled
+		}
+	}
+}
+
+// RunLayerCommand handles layer toggling commands and returns true if the
+// command was one.
+func (kb *KeyBindings) RunLayerCommand(cmd string) bool {
+	if name, ok := strings.CutPrefix(cmd, LayerCommandPrefix); ok {
+		kb.ToggleLayer(name)
+		return true
+	}
+	return false
+}
+
+func (kb *KeyBindings) Export(filename string) error {
+	b, err := json.MarshalIndent(kb.Keymaps, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0o644)
+}
+
+// Import adds the keymaps in the given file, replacing any existing ones
+// with the same names.
+func (kb *KeyBindings) Import(filename string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var keymaps []*Keymap
+	if err := json.Unmarshal(b, &keymaps); err != nil {
+		return err
+	}
+
+	for _, km := range keymaps {
+		for chord, cmd := range km.Bindings {
+			if _, err := ParseKeyChord(chord); err != nil {
+				return fmt.Errorf("%s: %w", km.Name, err)
+			}
+			if !strings.HasPrefix(cmd, LayerCommandPrefix) &&
+				!slices.ContainsFunc(keyCommands, func(c KeyCommand) bool { return c.Name == cmd }) {
+				return fmt.Errorf("%s: %s: unknown command", km.Name, cmd)
+			}
+		}
+	}
+	for _, km := range keymaps {
+		if idx := slices.IndexFunc(kb.Keymaps, func(k *Keymap) bool { return k.Name == km.Name }); idx != -1 {
+			kb.Keymaps[idx] = km
+		} else {
+			kb.Keymaps = append(kb.Keymaps, km)
+		}
+	}
+	return nil
+}
+
+func (kb *KeyBindings) DrawUI() {
+	for i, km := range kb.Keymaps {
+		imgui.PushID(km.Name)
+		if i == 0 {
+			imgui.Text(km.Name + " (always active)")
+		} else {
+			imgui.Checkbox(km.Name, &km.Enabled)
+		}
+
+		flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg | imgui.TableFlagsSizingStretchProp
+		if imgui.BeginTableV("bindings", 3, flags, imgui.Vec2{}, 0) {
+			imgui.TableSetupColumn("Key")
+			imgui.TableSetupColumn("Command")
+			imgui.TableSetupColumn("")
+			imgui.TableHeadersRow()
+
+			var remove string
+			for _, chord := range SortedMapKeys(km.Bindings) {
+				imgui.PushID(chord)
+				imgui.TableNextRow()
+				imgui.TableNextColumn()
+				imgui.Text(chord)
+				imgui.TableNextColumn()
+				imgui.Text(km.Bindings[chord])
+				imgui.TableNextColumn()
+				if imgui.Button(FontAwesomeIconTrash) {
+					remove = chord
+				}
+				imgui.PopID()
+			}
+			imgui.EndTable()
+			if remove != "" {
+				delete(km.Bindings, remove)
+			}
+		}
+
+		imgui.InputTextV("Key##new", &kb.newChord, 0, nil)
+		if imgui.IsItemHovered() {
+			imgui.SetTooltip("E.g., F3, Ctrl+Alt+1, KP8, Shift+PageUp")
+		}
+		if imgui.BeginComboV("Command##new", kb.newCommand, imgui.ComboFlagsHeightLarge) {
+			for _, cmd := range keyCommands {
+				if imgui.SelectableV(cmd.Name, cmd.Name == kb.newCommand, 0, imgui.Vec2{}) {
+					kb.newCommand = cmd.Name
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip(cmd.Description)
+				}
+			}
+			for _, other := range kb.Keymaps[1:] {
+				cmd := LayerCommandPrefix + other.Name
+				if imgui.SelectableV(cmd, cmd == kb.newCommand, 0, imgui.Vec2{}) {
+					kb.newCommand = cmd
+				}
+			}
+			imgui.EndCombo()
+		}
+		if imgui.Button("Bind") {
+			if kc, err := ParseKeyChord(kb.newChord); err != nil {
+				kb.status = err.Error()
+			} else if kb.newCommand == "" {
+				kb.status = "No command selected"
+			} else {
+				km.Bindings[kc.String()] = kb.newCommand
+				kb.status = ""
+			}
+		}
+		imgui.Separator()
+		imgui.PopID()
+	}
+
+	if imgui.Button("Add layer") {
+		kb.Keymaps = append(kb.Keymaps, &Keymap{
+			Name:     fmt.Sprintf("Layer %d", len(kb.Keymaps)),
+			Bindings: make(map[string]string),
+		})
+	}
+	imgui.SameLine()
+	if imgui.Button("Import keymaps...") {
+		kb.fileDialog = NewFileSelectDialogBox("Import keymaps...", []string{".json"}, "",
+			func(filename string) {
+				if err := kb.Import(filename); err != nil {
+					kb.status = err.Error()
+				} else {
+					kb.status = "Imported " + filename
+				}
+			})
+		kb.fileDialog.Activate()
+	}
+	imgui.SameLine()
+	if imgui.Button("Export keymaps...") {
+		kb.fileDialog = NewDirectorySelectDialogBox("Export keymaps to...", "",
+			func(dir string) {
+				fn := dir + string(os.PathSeparator) + "vice-keymaps.json"
+				if err := kb.Export(fn); err != nil {
+					kb.status = err.Error()
+				} else {
+					kb.status = "Saved " + fn
+				}
+			})
+		kb.fileDialog.Activate()
+	}
+	imgui.SameLine()
+	if imgui.Button("Restore defaults") {
+		kb.Keymaps = DefaultKeyBindings().Keymaps
+	}
+
+	if kb.status != "" {
+		imgui.Text(kb.status)
+	}
+	if kb.fileDialog != nil {
+		kb.fileDialog.Draw()
+	}
+}
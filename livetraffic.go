@@ -0,0 +1,623 @@
+// livetraffic.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// LiveTrafficSource
+
+// LiveTrack is the most recent report of an aircraft from a source of
+// live traffic.
+type LiveTrack struct {
+	Callsign    string
+	Position    Point2LL
+	Altitude    float32 // feet
+	Heading     float32 // true
+	Groundspeed float32
+	Squawk      Squawk
+	HaveSquawk  bool
+	Updated     time.Time
+
+	// Flight plan information; may be empty.
+	Rules            FlightRules
+	AircraftType     string
+	DepartureAirport string
+	ArrivalAirport   string
+	FiledAltitude    int
+	Route            string
+	Remarks          string
+}
+
+// LiveTrafficSource is implemented by things that provide the positions
+// of aircraft that aren't part of the simulation, e.g., online networks.
+type LiveTrafficSource interface {
+	Name() string
+	// Fetch returns the aircraft within radius nm of the center. It is
+	// called from a separate goroutine and may block.
+	Fetch(center Point2LL, radius float32) ([]LiveTrack, error)
+	// PollInterval returns how often Fetch should be called.
+	PollInterval() time.Duration
+	// Close is called when the source is no longer being used.
+	Close()
+}
+
+///////////////////////////////////////////////////////////////////////////
+// NetworkBackend
+
+// NetworkSettings holds the user-specified settings used to connect to a
+// NetworkBackend.
+type NetworkSettings struct {
+	Address  string // host:port
+	Callsign string
+	CID      string
+	Password string
+}
+
+// NetworkBackend is a network or server that live traffic can be received
+// from; the user selects one and provides its settings when they start
+// observing.
+type NetworkBackend interface {
+	Name() string
+	// DefaultAddress returns the address of the server to connect to if
+	// the user doesn't give one, or "" if the backend doesn't connect to
+	// a user-specified server.
+	DefaultAddress() string
+	// RequiresLogin returns true if a callsign, CID, and password must be
+	// provided.
+	RequiresLogin() bool
+	// Connect returns a LiveTrafficSource for the backend. It doesn't
+	// block; the source connects to the server asynchronously, so errors
+	// returned here are for invalid settings.
+	Connect(settings NetworkSettings) (LiveTrafficSource, error)
+}
+
+// feedBackend is a NetworkBackend for sources that don't require a login.
+type feedBackend struct {
+	name           string
+	defaultAddress string
+	new            func(address string) LiveTrafficSource
+}
+
+func (b feedBackend) Name() string           { return b.name }
+func (b feedBackend) DefaultAddress() string { return b.defaultAddress }
+func (b feedBackend) RequiresLogin() bool    { return false }
+
+func (b feedBackend) Connect(settings NetworkSettings) (LiveTrafficSource, error) {
+	if b.defaultAddress != "" {
+		if _, _, err := net.SplitHostPort(settings.Address); err != nil {
+			return nil, err
+		}
+	}
+	return b.new(settings.Address), nil
+}
+
+// networkBackends lists the available sources of live traffic, in the
+// order they are offered in the UI.
+var networkBackends = []NetworkBackend{
+	feedBackend{name: "VATSIM", new: func(string) LiveTrafficSource { return NewVATSIMDataFeed() }},
+	feedBackend{name: "IVAO", new: func(string) LiveTrafficSource { return NewIVAOWhazzup() }},
+	FSDBackend{name: "FSD server", defaultAddress: FSDDefaultAddress},
+	feedBackend{name: "ADS-B (adsb.lol)", new: func(string) LiveTrafficSource { return NewADSBExchangeAPI(ADSBLolURL) }},
+	feedBackend{name: "ADS-B (SBS server)", defaultAddress: "localhost:30003",
+		new: func(addr string) LiveTrafficSource { return NewSBSFeed(addr) }},
+	feedBackend{name: "vice relay", defaultAddress: DefaultLiveTrafficRelayAddress,
+		new: func(addr string) LiveTrafficSource { return NewViceRelaySource(addr) }},
+}
+
+// lookupNetworkBackend returns the NetworkBackend with the given name, or
+// the first one if there is no such backend.
+func lookupNetworkBackend(name string) NetworkBackend {
+	for _, b := range networkBackends {
+		if b.Name() == name {
+			return b
+		}
+	}
+	return networkBackends[0]
+}
+
+// parseFiledAltitude converts a flight plan altitude as filed on an online
+// network (e.g., "FL350", "F350", "A050", or "12000") to feet.
+func parseFiledAltitude(s string) int {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	scale := 1
+	if strings.HasPrefix(s, "FL") {
+		s, scale = s[2:], 100
+	} else if strings.HasPrefix(s, "F") || strings.HasPrefix(s, "A") {
+		s, scale = s[1:], 100
+	}
+	alt, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return alt * scale
+}
+
+///////////////////////////////////////////////////////////////////////////
+// VATSIMDataFeed
+
+// VATSIMDataFeed is a LiveTrafficSource that uses the public VATSIM data
+// feed; no login is required.
+type VATSIMDataFeed struct {
+	URL string
+}
+
+const VATSIMDataFeedURL = "https://data.vatsim.net/v3/vatsim-data.json"
+
+func NewVATSIMDataFeed() *VATSIMDataFeed {
+	return &VATSIMDataFeed{URL: VATSIMDataFeedURL}
+}
+
+func (v *VATSIMDataFeed) Name() string { return "VATSIM" }
+
+// The feed is regenerated every 15 seconds.
+func (v *VATSIMDataFeed) PollInterval() time.Duration { return 15 * time.Second }
+
+func (v *VATSIMDataFeed) Close() {}
+
+func (v *VATSIMDataFeed) Fetch(center Point2LL, radius float32) ([]LiveTrack, error) {
+	resp, err := http.Get(v.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", v.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseVATSIMData(data, center, radius)
+}
+
+// parseVATSIMData returns the pilots in the given VATSIM data feed JSON
+// that are within radius nm of center.
+func parseVATSIMData(data []byte, center Point2LL, radius float32) ([]LiveTrack, error) {
+	var feed struct {
+		Pilots []struct {
+			Callsign    string    `json:"callsign"`
+			Latitude    float32   `json:"latitude"`
+			Longitude   float32   `json:"longitude"`
+			Altitude    float32   `json:"altitude"`
+			Groundspeed float32   `json:"groundspeed"`
+			Heading     float32   `json:"heading"`
+			Transponder string    `json:"transponder"`
+			LastUpdated time.Time `json:"last_updated"`
+			FlightPlan  *struct {
+				FlightRules   string `json:"flight_rules"`
+				AircraftShort string `json:"aircraft_short"`
+				Departure     string `json:"departure"`
+				Arrival       string `json:"arrival"`
+				Altitude      string `json:"altitude"`
+				Route         string `json:"route"`
+				Remarks       string `json:"remarks"`
+			} `json:"flight_plan"`
+		} `json:"pilots"`
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+
+	var tracks []LiveTrack
+	for _, p := range feed.Pilots {
+		pos := Point2LL{p.Longitude, p.Latitude}
+		if nmdistance2ll(center, pos) > radius {
+			continue
+		}
+
+		t := LiveTrack{
+			Callsign:    p.Callsign,
+			Position:    pos,
+			Altitude:    p.Altitude,
+			Heading:     p.Heading,
+			Groundspeed: p.Groundspeed,
+			Updated:     p.LastUpdated,
+		}
+		if sq, err := ParseSquawk(p.Transponder); err == nil {
+			t.Squawk, t.HaveSquawk = sq, true
+		}
+		if fp := p.FlightPlan; fp != nil {
+			t.Rules = Select(fp.FlightRules == "V", FlightRules(VFR), FlightRules(IFR))
+			t.AircraftType = fp.AircraftShort
+			t.DepartureAirport = fp.Departure
+			t.ArrivalAirport = fp.Arrival
+			t.FiledAltitude = parseFiledAltitude(fp.Altitude)
+			t.Route = fp.Route
+			t.Remarks = fp.Remarks
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// IVAOWhazzup
+
+// IVAOWhazzup is a LiveTrafficSource that uses IVAO's public whazzup
+// tracker feed.
+type IVAOWhazzup struct {
+	URL string
+}
+
+const IVAOWhazzupURL = "https://api.ivao.aero/v2/tracker/whazzup"
+
+func NewIVAOWhazzup() *IVAOWhazzup {
+	return &IVAOWhazzup{URL: IVAOWhazzupURL}
+}
+
+func (v *IVAOWhazzup) Name() string { return "IVAO" }
+
+func (v *IVAOWhazzup) PollInterval() time.Duration { return 15 * time.Second }
+
+func (v *IVAOWhazzup) Close() {}
+
+func (v *IVAOWhazzup) Fetch(center Point2LL, radius float32) ([]LiveTrack, error) {
+	resp, err := http.Get(v.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", v.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseIVAOWhazzup(data, center, radius)
+}
+
+func parseIVAOWhazzup(data []byte, center Point2LL, radius float32) ([]LiveTrack, error) {
+	var feed struct {
+		Clients struct {
+			Pilots []struct {
+				Callsign  string `json:"callsign"`
+				LastTrack *struct {
+					Latitude    float32   `json:"latitude"`
+					Longitude   float32   `json:"longitude"`
+					Altitude    float32   `json:"altitude"`
+					GroundSpeed float32   `json:"groundSpeed"`
+					Heading     float32   `json:"heading"`
+					Transponder int       `json:"transponder"`
+					Timestamp   time.Time `json:"timestamp"`
+				} `json:"lastTrack"`
+				FlightPlan *struct {
+					FlightRules string `json:"flightRules"`
+					AircraftId  string `json:"aircraftId"`
+					DepartureId string `json:"departureId"`
+					ArrivalId   string `json:"arrivalId"`
+					Level       string `json:"level"`
+					Route       string `json:"route"`
+					Remarks     string `json:"remarks"`
+				} `json:"flightPlan"`
+			} `json:"pilots"`
+		} `json:"clients"`
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+
+	var tracks []LiveTrack
+	for _, p := range feed.Clients.Pilots {
+		lt := p.LastTrack
+		if lt == nil {
+			continue
+		}
+		pos := Point2LL{lt.Longitude, lt.Latitude}
+		if nmdistance2ll(center, pos) > radius {
+			continue
+		}
+
+		t := LiveTrack{
+			Callsign:    p.Callsign,
+			Position:    pos,
+			Altitude:    lt.Altitude,
+			Heading:     lt.Heading,
+			Groundspeed: lt.GroundSpeed,
+			Updated:     lt.Timestamp,
+		}
+		// The transponder code is given as the decimal digits of the
+		// squawk code.
+		if sq, err := ParseSquawk(fmt.Sprintf("%04d", lt.Transponder)); err == nil {
+			t.Squawk, t.HaveSquawk = sq, true
+		}
+		if fp := p.FlightPlan; fp != nil {
+			t.Rules = Select(fp.FlightRules == "V", FlightRules(VFR), FlightRules(IFR))
+			t.AircraftType = fp.AircraftId
+			t.DepartureAirport = fp.DepartureId
+			t.ArrivalAirport = fp.ArrivalId
+			t.FiledAltitude = parseFiledAltitude(fp.Level)
+			t.Route = fp.Route
+			t.Remarks = fp.Remarks
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// LiveTraffic
+
+// LiveTraffic periodically fetches aircraft from a LiveTrafficSource and
+// displays them in place of the simulation's aircraft.
+type LiveTraffic struct {
+	source LiveTrafficSource
+	relay  *LiveTrafficRelay // may be nil
+
+	updates chan liveTrafficUpdate
+	done    chan struct{}
+
+	tracks     []LiveTrack
+	lastUpdate time.Time
+	err        error
+}
+
+type liveTrafficUpdate struct {
+	tracks []LiveTrack
+	err    error
+}
+
+// StartLiveTraffic starts fetching aircraft within radius nm of the given
+// center from the source. If relay is non-nil, the aircraft are also
+// published to it.
+func StartLiveTraffic(source LiveTrafficSource, center Point2LL, radius float32, relay *LiveTrafficRelay) *LiveTraffic {
+	lt := &LiveTraffic{
+		source:  source,
+		relay:   relay,
+		updates: make(chan liveTrafficUpdate, 1),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		// Back off if the source is unavailable so that we don't hammer
+		// it; the most recent aircraft continue to be shown meanwhile.
+		backoff := Backoff{Min: source.PollInterval(), Max: 5 * time.Minute}
+		for {
+			tracks, err := source.Fetch(center, radius)
+			if err == nil && relay != nil {
+				relay.Publish(source.Name(), tracks)
+			}
+			select {
+			case lt.updates <- liveTrafficUpdate{tracks: tracks, err: err}:
+			case <-lt.done:
+				return
+			}
+
+			delay := source.PollInterval()
+			if err != nil {
+				delay = backoff.Next()
+			} else {
+				backoff.Reset()
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-lt.done:
+				return
+			}
+		}
+	}()
+
+	return lt
+}
+
+func (lt *LiveTraffic) Stop() {
+	close(lt.done)
+	lt.source.Close()
+	if lt.relay != nil {
+		lt.relay.Close()
+	}
+}
+
+// Update takes the most recent aircraft from the source, if any, and
+// updates the world's aircraft accordingly.
+func (lt *LiveTraffic) Update(w *World) {
+	select {
+	case u := <-lt.updates:
+		if u.err != nil {
+			lg.Category(LogCategoryNetwork).Warnf("%s: %v", lt.source.Name(), u.err)
+			lt.err = u.err
+		} else {
+			lt.tracks, lt.err = u.tracks, nil
+			lt.lastUpdate = time.Now()
+			if w.scenarioRecorder != nil {
+				w.scenarioRecorder.Record(u.tracks)
+			}
+		}
+	default:
+	}
+
+	// Live traffic is always shown in real time.
+	w.SimTime = time.Now()
+	lt.tracks = globalConfig.Retention.dropLostTracks(lt.tracks, w.SimTime)
+
+	aircraft := make(map[string]*Aircraft)
+	for _, t := range lt.tracks {
+		aircraft[t.Callsign] = lt.makeAircraft(t, w)
+	}
+	w.Aircraft = aircraft
+}
+
+func (lt *LiveTraffic) makeAircraft(t LiveTrack, w *World) *Aircraft {
+	// Sources update infrequently, so extrapolate from the last report.
+	pos := t.Position
+	if !t.Updated.IsZero() {
+		pos = deadReckon(pos, t.Heading, t.Groundspeed, time.Since(t.Updated), w.NmPerLongitude)
+	}
+
+	ac := &Aircraft{
+		Callsign: t.Callsign,
+		Squawk:   t.Squawk,
+		Mode:     Select(t.HaveSquawk, TransponderMode(Charlie), TransponderMode(Standby)),
+		Nav: Nav{
+			FlightState: FlightState{
+				Position:          pos,
+				Heading:           NormalizeHeading(t.Heading + w.MagneticVariation),
+				Altitude:          t.Altitude,
+				GS:                t.Groundspeed,
+				IAS:               t.Groundspeed,
+				MagneticVariation: w.MagneticVariation,
+				NmPerLongitude:    w.NmPerLongitude,
+			},
+		},
+	}
+
+	// STARS expects every aircraft to have a flight plan (e.g., when an
+	// alert forces a full datablock), so tracks without flight plan
+	// information get an empty one with the rules inferred from the
+	// squawk code.
+	rules := t.Rules
+	if rules == UNKNOWN {
+		rules = Select(t.Squawk == Squawk(0o1200), FlightRules(VFR), FlightRules(IFR))
+	}
+	ac.FlightPlan = &FlightPlan{
+		Rules:            rules,
+		AircraftType:     t.AircraftType,
+		DepartureAirport: t.DepartureAirport,
+		ArrivalAirport:   t.ArrivalAirport,
+		Altitude:         t.FiledAltitude,
+		Route:            t.Route,
+		Remarks:          t.Remarks,
+	}
+	if t.AircraftType != "" {
+		// Only tracks with a flight plan are associated.
+		ac.AssignedSquawk = t.Squawk
+		if perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]; ok {
+			ac.Nav.Perf = perf
+		}
+	}
+	return ac
+}
+
+func (lt *LiveTraffic) Status() string {
+	s := fmt.Sprintf("%s: %d aircraft", lt.source.Name(), len(lt.tracks))
+	if lt.relay != nil {
+		s += fmt.Sprintf(", relaying on port %d", lt.relay.Port())
+	}
+	if !lt.lastUpdate.IsZero() {
+		age := time.Since(lt.lastUpdate)
+		s += fmt.Sprintf(", updated %ds ago", int(age.Seconds()))
+		if age > 3*lt.source.PollInterval() {
+			s += " (STALE)"
+		}
+	}
+	if lt.err != nil {
+		s += "\nError: " + lt.err.Error()
+	}
+	return s
+}
+
+// drawLiveTrafficUI draws the settings UI for starting and stopping the
+// display of live traffic.
+func (w *World) drawLiveTrafficUI() {
+	if w.liveTrafficRadius == 0 {
+		w.liveTrafficRadius = 100
+	}
+
+	backend := lookupNetworkBackend(w.liveTrafficSource)
+	w.liveTrafficSource = backend.Name()
+	settings := &w.liveTrafficSettings
+
+	if w.liveTraffic == nil {
+		imgui.Text("Show live traffic from an online network or ADS-B in place of the simulation's aircraft.")
+		if imgui.BeginComboV("Source", w.liveTrafficSource, imgui.ComboFlagsHeightLarge) {
+			for _, b := range networkBackends {
+				if imgui.SelectableV(b.Name(), b.Name() == w.liveTrafficSource, 0, imgui.Vec2{}) {
+					w.liveTrafficSource = b.Name()
+					settings.Address = b.DefaultAddress()
+				}
+			}
+			imgui.EndCombo()
+		}
+		if backend.DefaultAddress() != "" {
+			if settings.Address == "" {
+				settings.Address = backend.DefaultAddress()
+			}
+			imgui.InputTextV("Address", &settings.Address, imgui.InputTextFlagsCharsNoBlank, nil)
+		}
+		if backend.RequiresLogin() {
+			imgui.InputTextV("Callsign", &settings.Callsign, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+			imgui.InputTextV("CID", &settings.CID, imgui.InputTextFlagsCharsNoBlank, nil)
+			imgui.InputTextV("Password", &settings.Password, imgui.InputTextFlagsPassword, nil)
+		}
+		imgui.SliderFloatV("Radius (nm)", &w.liveTrafficRadius, 25, 250, "%.0f", 0)
+		imgui.Checkbox("Relay to other vice instances", &w.liveTrafficRelay)
+		if w.liveTrafficRelay {
+			if w.liveTrafficRelayAddress == "" {
+				w.liveTrafficRelayAddress = DefaultLiveTrafficRelayAddress
+			}
+			imgui.InputTextV("Relay address", &w.liveTrafficRelayAddress, imgui.InputTextFlagsCharsNoBlank, nil)
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip(fmt.Sprintf("Anyone who can connect to this address can receive the traffic; only use\n"+
+					"a LAN address (or :%d for all interfaces) on a trusted network", LiveTrafficRelayPort))
+			}
+		}
+		if imgui.Button("Start observing") {
+			var relay *LiveTrafficRelay
+			w.liveTrafficErr = nil
+			if w.liveTrafficRelay {
+				if relay, w.liveTrafficErr = NewLiveTrafficRelay(w.liveTrafficRelayAddress); w.liveTrafficErr != nil {
+					lg.Category(LogCategoryNetwork).Errorf("live traffic relay: %v", w.liveTrafficErr)
+				}
+			}
+
+			var source LiveTrafficSource
+			if w.liveTrafficErr == nil {
+				if source, w.liveTrafficErr = backend.Connect(*settings); w.liveTrafficErr != nil && relay != nil {
+					relay.Close()
+				}
+			}
+
+			if w.liveTrafficErr == nil {
+				// The simulation's aircraft aren't shown, so there's no
+				// reason for it to keep running.
+				if !w.SimIsPaused {
+					w.ToggleSimPause()
+				}
+				w.liveTraffic = StartLiveTraffic(source, w.Center, w.liveTrafficRadius, relay)
+			}
+		}
+		if w.liveTrafficErr != nil {
+			imgui.Text("Error: " + w.liveTrafficErr.Error())
+		}
+	} else {
+		imgui.Text(w.liveTraffic.Status())
+		if imgui.Button("Stop observing") {
+			w.liveTraffic.Stop()
+			w.liveTraffic = nil
+		}
+
+		imgui.SameLine()
+		if w.scenarioRecorder == nil {
+			if imgui.Button("Record scenario") {
+				w.scenarioRecorder = NewScenarioRecorder()
+			}
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip("Record the arrivals and departures so that they can be exported as a scenario")
+			}
+		} else if imgui.Button("Discard recording") {
+			w.scenarioRecorder = nil
+		}
+	}
+
+	if w.scenarioRecorder != nil {
+		imgui.Separator()
+		w.scenarioRecorder.DrawUI(w)
+	}
+}
@@ -0,0 +1,129 @@
+// livetraffic_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// An untyped live track (e.g., from ADS-B) below the MVA gets a low
+// altitude alert, which forces a full datablock; formatting it shouldn't
+// require flight plan information.
+func TestLiveTrafficUntypedTrackBelowMVA(t *testing.T) {
+	ring := [][2]float32{{-74, 40}, {-73.8, 40}, {-73.8, 40.2}, {-74, 40.2}, {-74, 40}}
+	savedDatabase := database
+	database = &StaticDatabase{
+		MVAs: map[string][]MVA{
+			"TST": {{MinimumLimit: 3000, Bounds: Extent2DFromPoints(ring), ExteriorRing: ring}},
+		},
+	}
+	defer func() { database = savedDatabase }()
+
+	w := &World{
+		TRACON:         "TST",
+		Callsign:       "TST_APP",
+		NmPerLongitude: 45,
+		Aircraft:       make(map[string]*Aircraft),
+	}
+
+	for _, sq := range []Squawk{0o1200, 0o4321} {
+		lt := &LiveTraffic{}
+		ac := lt.makeAircraft(LiveTrack{
+			Callsign:    "N123AB",
+			Position:    Point2LL{-73.9, 40.1},
+			Altitude:    1500,
+			Groundspeed: 120,
+			Squawk:      sq,
+			HaveSquawk:  true,
+		}, w)
+		if ac.FlightPlan == nil {
+			t.Fatalf("%s: no flight plan for untyped live track", sq)
+		}
+		if expected := Select(sq == 0o1200, FlightRules(VFR), FlightRules(IFR)); ac.FlightPlan.Rules != expected {
+			t.Errorf("%s: got rules %s, expected %s", sq, ac.FlightPlan.Rules, expected)
+		}
+		w.Aircraft[ac.Callsign] = ac
+
+		now := time.Now()
+		sp := &STARSPane{
+			Aircraft: map[string]*STARSAircraftState{
+				ac.Callsign: {track: RadarTrack{Position: ac.Position(), Altitude: 1500, Groundspeed: 120, Time: now}},
+			},
+		}
+		sp.updateMSAWs(w)
+		if !sp.Aircraft[ac.Callsign].MSAW {
+			t.Fatalf("%s: expected MSAW for track below the MVA", sq)
+		}
+
+		ctx := &PaneContext{world: w, now: now}
+		if dt := sp.datablockType(ctx, ac); dt != FullDatablock {
+			t.Errorf("%s: expected full datablock, got %v", sq, dt)
+		}
+		dbs := sp.formatDatablocks(ctx, ac)
+		if len(dbs) == 0 {
+			t.Fatalf("%s: no datablocks", sq)
+		}
+		if !strings.Contains(dbs[0].Lines[0].Text, "LA") {
+			t.Errorf("%s: expected LA alert in datablock, got %q", sq, dbs[0].Lines[0].Text)
+		}
+	}
+}
+
+func TestFSDClientPackets(t *testing.T) {
+	// Not connected, so nothing is sent.
+	f := &FSDClient{settings: NetworkSettings{Callsign: "TST_OBS"}, pilots: make(map[string]*fsdPilot)}
+	now := time.Now()
+
+	for _, p := range []string{
+		"@N:AAL123:1234:1:40.10000:-73.90000:5000:250:1024:0\r", // heading 90
+		"$FPAAL123:*A:I:H/B763/L:450:KJFK:1200:1200:FL350:KLAX:5:30:7:0:KONT:/v/:DCT GREKI J60",
+		"@S:N123AB:1200:1:40.20000:-73.80000:1500:90:0:0",
+		"@N:DAL1:2345:1:45.00000:-80.00000:35000:450:0:0", // out of range
+		"$ERserver:TST_OBS:008:DAL1:No flightplan",        // not fatal
+	} {
+		if err := f.handlePacket(p, now); err != nil {
+			t.Fatalf("%q: %v", p, err)
+		}
+	}
+
+	tracks, err := f.Fetch(Point2LL{-73.9, 40.1}, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("got %d tracks, expected 2: %+v", len(tracks), tracks)
+	}
+	for _, tr := range tracks {
+		switch tr.Callsign {
+		case "AAL123":
+			if tr.Heading != 90 || tr.Altitude != 5000 || tr.Groundspeed != 250 || tr.Squawk != 0o1234 || !tr.HaveSquawk {
+				t.Errorf("AAL123: unexpected track %+v", tr)
+			}
+			if tr.Rules != IFR || tr.AircraftType != "H/B763/L" || tr.DepartureAirport != "KJFK" ||
+				tr.ArrivalAirport != "KLAX" || tr.FiledAltitude != 35000 || tr.Route != "DCT GREKI J60" {
+				t.Errorf("AAL123: unexpected flight plan %+v", tr)
+			}
+		case "N123AB":
+			if tr.HaveSquawk || tr.AircraftType != "" {
+				t.Errorf("N123AB: unexpected track %+v", tr)
+			}
+		default:
+			t.Errorf("%s: unexpected track", tr.Callsign)
+		}
+	}
+
+	if err := f.handlePacket("#DPAAL123:123456", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.pilots["AAL123"]; ok {
+		t.Errorf("AAL123 not deleted after disconnect")
+	}
+
+	if err := f.handlePacket("$ERserver:TST_OBS:006::Invalid CID/password", now); err == nil {
+		t.Errorf("expected error for invalid CID/password")
+	}
+}
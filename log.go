@@ -5,12 +5,15 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path"
 	"runtime"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -20,8 +23,22 @@ type Logger struct {
 	*slog.Logger
 	logFile string
 	start   time.Time
+	// Loggers for each of the LogCategories; only set for the top-level
+	// Logger returned by NewLogger.
+	categories map[string]*Logger
 }
 
+// Log messages from the various subsystems are tagged with a category so
+// that they can be filtered, e.g., in the log viewer pane.
+const (
+	LogCategoryNetwork = "network"
+	LogCategoryRender  = "render"
+	LogCategorySim     = "sim"
+	LogCategoryAudio   = "audio"
+)
+
+var LogCategories = []string{LogCategoryNetwork, LogCategoryRender, LogCategorySim, LogCategoryAudio}
+
 func NewLogger(server bool, level string) *Logger {
 	var w *lumberjack.Logger
 
@@ -61,12 +78,16 @@ func NewLogger(server bool, level string) *Logger {
 		fmt.Fprintf(os.Stderr, "%s: invalid log level", level)
 	}
 
-	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl})
+	h := slog.NewJSONHandler(io.MultiWriter(w, recentLogLines), &slog.HandlerOptions{Level: lvl})
 	l := &Logger{
 		Logger:  slog.New(h),
 		logFile: w.Filename,
 		start:   time.Now(),
 	}
+	l.categories = make(map[string]*Logger)
+	for _, c := range LogCategories {
+		l.categories[c] = l.With(slog.String("category", c))
+	}
 
 	// Start out the logs with some basic information about the system
 	// we're running on and the build of vice that's being used.
@@ -166,6 +187,69 @@ func (l *Logger) Errorf(msg string, args ...any) {
 	}
 }
 
+// LogLineBuffer is an io.Writer that holds the most recently written lines
+// of the log so that they can be included in crash reports and shown in
+// the log viewer.
+type LogLineBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int // index to write the next line to once the buffer is full
+	total int // total number of lines written
+}
+
+const RecentLogLines = 500
+
+var recentLogLines = &LogLineBuffer{}
+
+func (lb *LogLineBuffer) Write(b []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	for _, line := range bytes.Split(bytes.TrimRight(b, "\n"), []byte("\n")) {
+		if len(lb.lines) < RecentLogLines {
+			lb.lines = append(lb.lines, string(line))
+		} else {
+			lb.lines[lb.next] = string(line)
+			lb.next = (lb.next + 1) % RecentLogLines
+		}
+		lb.total++
+	}
+	return len(b), nil
+}
+
+// Lines returns the buffered lines, oldest first.
+func (lb *LogLineBuffer) Lines() []string {
+	lines, _ := lb.LinesSince(0)
+	return lines
+}
+
+// LinesSince returns the buffered lines that were written after the first
+// n lines, oldest first, as well as the total number of lines that have
+// been written, which can be passed to a subsequent call to get just the
+// new lines.
+func (lb *LogLineBuffer) LinesSince(n int) ([]string, int) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lines := append(DuplicateSlice(lb.lines[lb.next:]), lb.lines[:lb.next]...)
+	if skip := len(lines) - (lb.total - n); skip > 0 {
+		lines = lines[skip:]
+	}
+	return lines, lb.total
+}
+
+// Category returns a Logger that tags its messages with the given
+// category.
+func (l *Logger) Category(c string) *Logger {
+	if l == nil {
+		return nil
+	}
+	if cl, ok := l.categories[c]; ok {
+		return cl
+	}
+	return l.With(slog.String("category", c))
+}
+
 func (l *Logger) With(args ...any) *Logger {
 	return &Logger{
 		Logger:  l.Logger.With(args...),
@@ -179,6 +263,7 @@ func (l *Logger) With(args ...any) *Logger {
 type Stats struct {
 	render    RendererStats
 	renderUI  RendererStats
+	update    time.Duration
 	drawImgui time.Duration
 	drawPanes time.Duration
 	startTime time.Time
@@ -203,6 +288,7 @@ func (stats Stats) LogValue() slog.Value {
 		slog.Float64("mallocs_per_second", mallocsPerSecond),
 		slog.Int64("active_mallocs", int64(mem.Mallocs-mem.Frees)),
 		slog.Int64("memory_in_use", int64(mem.HeapAlloc)),
+		slog.Duration("update", stats.update),
 		slog.Duration("draw_panes", stats.drawPanes),
 		slog.Duration("draw_imgui", stats.drawImgui),
 		slog.Any("render", stats.render),
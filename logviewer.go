@@ -0,0 +1,185 @@
+// logviewer.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Maximum number of log messages the LogViewerPane holds on to.
+const maxLogViewerEntries = 2000
+
+// LogViewerPane shows the recent log messages so that problems can be
+// tracked down without needing to run vice from a terminal or dig up the
+// log file. Messages can be filtered by level, category, and text.
+type LogViewerPane struct {
+	FontIdentifier FontIdentifier
+	// Messages below this level aren't shown.
+	MinLevel slog.Level
+	// Categories that aren't shown; messages without a category are
+	// listed under "other".
+	HiddenCategories map[string]bool
+	Filter           string
+
+	font      *Font
+	scrollbar *ScrollBar
+	entries   []logViewerEntry
+	// Number of log lines that have been read from recentLogLines.
+	linesRead int
+}
+
+type logViewerEntry struct {
+	Time     time.Time
+	Level    slog.Level
+	Category string
+	Message  string
+}
+
+func NewLogViewerPane() *LogViewerPane {
+	return &LogViewerPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 14},
+		MinLevel:       slog.LevelInfo,
+	}
+}
+
+func (lv *LogViewerPane) Name() string { return "Log Viewer" }
+
+func (lv *LogViewerPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if lv.font = GetFont(lv.FontIdentifier); lv.font == nil {
+		lv.font = GetDefaultFont()
+		lv.FontIdentifier = lv.font.id
+	}
+	if lv.scrollbar == nil {
+		lv.scrollbar = NewVerticalScrollBar(4, true)
+	}
+	if lv.HiddenCategories == nil {
+		lv.HiddenCategories = make(map[string]bool)
+	}
+}
+
+func (lv *LogViewerPane) Deactivate()                {}
+func (lv *LogViewerPane) ResetWorld(w *World)        {}
+func (lv *LogViewerPane) CanTakeKeyboardFocus() bool { return false }
+
+func (lv *LogViewerPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&lv.FontIdentifier, "Font"); changed {
+		lv.font = newFont
+	}
+
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	if imgui.BeginComboV("Minimum level", lv.MinLevel.String(), 0) {
+		for _, l := range levels {
+			if imgui.SelectableV(l.String(), l == lv.MinLevel, 0, imgui.Vec2{}) {
+				lv.MinLevel = l
+			}
+		}
+		imgui.EndCombo()
+	}
+	if lg != nil && !lg.Enabled(nil, lv.MinLevel) {
+		imgui.Text("Messages at this level are only logged if vice is started with -loglevel debug.")
+	}
+
+	imgui.Text("Categories:")
+	for _, c := range append(DuplicateSlice(LogCategories), "other") {
+		imgui.SameLine()
+		show := !lv.HiddenCategories[c]
+		if imgui.Checkbox(c, &show) {
+			lv.HiddenCategories[c] = !show
+		}
+	}
+	imgui.InputTextV("Filter", &lv.Filter, 0, nil)
+
+	if imgui.Button("Clear") {
+		lv.entries = nil
+	}
+	imgui.SameLine()
+	if imgui.Button("Copy to clipboard") {
+		var s strings.Builder
+		for _, e := range lv.visibleEntries() {
+			s.WriteString(e.String() + "\n")
+		}
+		platform.GetClipboard().SetText(s.String())
+	}
+	if lg != nil {
+		imgui.Text("Log file: " + lg.logFile)
+	}
+}
+
+func (e logViewerEntry) String() string {
+	s := e.Time.Format("15:04:05") + fmt.Sprintf(" %-5s ", e.Level.String())
+	if e.Category != "" {
+		s += "[" + e.Category + "] "
+	}
+	return s + e.Message
+}
+
+// update adds the log messages that have been written since the last
+// call.
+func (lv *LogViewerPane) update() {
+	var lines []string
+	lines, lv.linesRead = recentLogLines.LinesSince(lv.linesRead)
+
+	for _, line := range lines {
+		var rec struct {
+			Time     time.Time  `json:"time"`
+			Level    slog.Level `json:"level"`
+			Category string     `json:"category"`
+			Message  string     `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			// Not from the JSON handler; show it as is.
+			rec.Time, rec.Level, rec.Message = time.Now(), slog.LevelInfo, line
+		}
+		lv.entries = append(lv.entries, logViewerEntry(rec))
+	}
+	if n := len(lv.entries); n > maxLogViewerEntries {
+		lv.entries = lv.entries[n-maxLogViewerEntries:]
+	}
+}
+
+func (lv *LogViewerPane) visibleEntries() []logViewerEntry {
+	filter := strings.ToLower(lv.Filter)
+	return FilterSlice(lv.entries, func(e logViewerEntry) bool {
+		category := Select(e.Category == "", "other", e.Category)
+		return e.Level >= lv.MinLevel && !lv.HiddenCategories[category] &&
+			(filter == "" || strings.Contains(strings.ToLower(e.Message), filter))
+	})
+}
+
+func (lv *LogViewerPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	lv.update()
+	entries := lv.visibleEntries()
+
+	lineHeight := float32(lv.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	lv.scrollbar.Update(len(entries), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	// Draw the most recent messages at the bottom, going up from there.
+	y := lineHeight
+	for i := len(entries) - 1 - lv.scrollbar.Offset(); i >= 0 && y <= ctx.paneExtent.Height(); i-- {
+		e := entries[i]
+		style := TextStyle{Font: lv.font, Color: UITextColor}
+		if e.Level >= slog.LevelError {
+			style.Color = UIErrorColor
+		} else if e.Level >= slog.LevelWarn {
+			style.Color = UICautionColor
+		}
+		td.AddText(e.String(), [2]float32{2, y}, style)
+		y += lineHeight
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	lv.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
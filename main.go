@@ -10,6 +10,7 @@ package main
 
 import (
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -72,6 +73,12 @@ var (
 	showRoutes        = flag.String("routes", "", "display the STARS, SIDs, and approaches known for the given airport")
 	listMaps          = flag.String("listmaps", "", "path to a video map file to list maps of (e.g., resources/videomaps/ZNY-videomaps.gob.zst)")
 	listScenarios     = flag.Bool("listscenarios", false, "list all of the available scenarios")
+	headless          = flag.Bool("headless", false, "run without showing the vice window, resuming the saved simulation; use with -snapshot or -snapshothttp")
+	snapshotFile      = flag.String("snapshot", "", "periodically write a PNG image of the STARS scope to this file")
+	snapshotHTTP      = flag.String("snapshothttp", "", "serve a PNG image of the STARS scope at this address (e.g., :8080)")
+	snapshotInterval  = flag.Duration("snapshotinterval", 30*time.Second, "time between STARS scope snapshots")
+	metricsHTTP       = flag.String("metricshttp", "", "serve Prometheus metrics at this address (e.g., localhost:9100)")
+	devmode           = flag.Bool("devmode", false, "enable developer tools, including the profiler overlay")
 )
 
 func init() {
@@ -111,6 +118,7 @@ func main() {
 	}
 	absPath(memprofile)
 	absPath(cpuprofile)
+	absPath(snapshotFile)
 
 	writeMemProfile := func() {
 		f, err := os.Create(*memprofile)
@@ -240,11 +248,18 @@ func main() {
 		if os.Getenv("DELVE_GOVERSION") == "" { // hack: don't catch panics when debugging..
 			defer func() {
 				if err := recover(); err != nil {
-					lg.Error("Caught panic!", slog.String("stack", string(debug.Stack())))
+					stack := debug.Stack()
+					lg.Error("Caught panic!", slog.String("stack", string(stack)))
+
+					report, rerr := writeDiagnosticReport(err, stack)
+					if rerr != nil {
+						lg.Errorf("Unable to write diagnostic report: %v", rerr)
+						report = "the vice.slog file for this session"
+					}
 					ShowFatalErrorDialog(renderer, platform,
 						"Unfortunately an unexpected error has occurred and vice is unable to recover.\n"+
-							"Apologies! Please do file a bug and include the vice.log file for this session\nso that "+
-							"this bug can be fixed.\n\nError: %v", err)
+							"Apologies! Please do file a bug and include %s\nso that "+
+							"this bug can be fixed.\n\nError: %v", report, err)
 				}
 
 				// Clean up in backwards order from how things were created.
@@ -261,6 +276,7 @@ func main() {
 		context = imguiInit()
 
 		LoadOrMakeDefaultConfig()
+		autosave := beginSession()
 
 		multisample := runtime.GOOS != "darwin"
 		platform, err = NewGLFWPlatform(imgui.CurrentIO(), globalConfig.InitialWindowSize,
@@ -282,7 +298,9 @@ func main() {
 
 		localServer = <-localSimServerChan
 
-		if globalConfig.Sim != nil && !*resetSim {
+		// If the previous session crashed, the user is offered its
+		// autosave (below) rather than the sim from the last clean exit.
+		if globalConfig.Sim != nil && !*resetSim && autosave == nil {
 			if err := globalConfig.Sim.PostLoad(mapLibrary); err != nil {
 				lg.Errorf("Error in Sim PostLoad: %v", err)
 			} else {
@@ -303,11 +321,31 @@ func main() {
 		wmInit()
 
 		uiInit(renderer, platform, eventStream)
+		if *devmode {
+			profiler = NewProfiler()
+		}
 
 		globalConfig.Activate(world, renderer, eventStream)
 
 		if world == nil {
-			uiShowConnectDialog(false)
+			if *headless {
+				lg.Errorf("-headless requires a saved simulation to resume")
+				os.Exit(1)
+			}
+			if autosave != nil {
+				uiShowSessionRestoreDialog(autosave, mapLibrary, renderer, eventStream)
+			} else {
+				uiShowConnectDialog(false)
+			}
+		}
+
+		var snapshotter *ScopeSnapshotter
+		if *snapshotFile != "" || *snapshotHTTP != "" {
+			snapshotter = NewScopeSnapshotter(*snapshotInterval, *snapshotFile, *snapshotHTTP)
+		}
+
+		if *metricsHTTP != "" {
+			metrics = NewMetricsServer(*metricsHTTP)
 		}
 
 		if !globalConfig.AskedDiscordOptIn {
@@ -329,6 +367,7 @@ func main() {
 		stopConnectingRemoteServer := false
 		frameIndex := 0
 		stats.startTime = time.Now()
+		lastFrame, lastActivity := time.Now(), time.Now()
 		for {
 			select {
 			case nw := <-newWorldChan:
@@ -345,6 +384,7 @@ func main() {
 					globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
 						p.ResetWorld(world)
 					})
+					finishSessionRestore(world, renderer, eventStream)
 				}
 
 			case remoteServerConn := <-remoteSimServerChan:
@@ -376,21 +416,47 @@ func main() {
 			} else {
 				platform.SetWindowTitle("vice: " + world.GetWindowTitle())
 				// Update discord RPC
-				SetDiscordStatus(discordStatus{
-					totalDepartures: world.TotalDepartures,
-					totalArrivals:   world.TotalArrivals,
-					callsign:        world.Callsign,
-					start:           simStartTime,
-				})
+				SetDiscordStatus(world.DiscordStatus(simStartTime))
 			}
 
+			globalConfig.Webhooks.Update(world)
+			updateSessionRestore(world)
+			autosaveSession(world)
+
 			if remoteServer == nil && time.Since(lastRemoteServerAttempt) > 10*time.Second && !stopConnectingRemoteServer {
 				lastRemoteServerAttempt = time.Now()
 				remoteSimServerChan = TryConnectRemoteServer(*serverAddress)
 			}
 
-			// Inform imgui about input events from the user.
-			platform.ProcessEvents()
+			// Inform imgui about input events from the user. If the frame
+			// rate is limited, wait for input until the next frame is due.
+			// Input ends the wait early, so afterward sleep for whatever
+			// remains of the (now non-idle) frame interval.
+			var input bool
+			if fps := targetFrameRate(globalConfig, time.Since(lastActivity)); fps > 0 {
+				input = platform.WaitEvents(time.Second/time.Duration(fps) - time.Since(lastFrame))
+			} else {
+				input = platform.ProcessEvents()
+			}
+			if input {
+				lastActivity = time.Now()
+			}
+			if fps := targetFrameRate(globalConfig, time.Since(lastActivity)); fps > 0 {
+				if d := time.Second/time.Duration(fps) - time.Since(lastFrame); d > 0 {
+					time.Sleep(d)
+				}
+			}
+			lastFrame = time.Now()
+			if platform.DPIScaleChanged() || fontsReloadRequested {
+				fontsReloadRequested = false
+				fontsReload(renderer, platform)
+			}
+			if globalConfig.HardwareInput.Update() {
+				input = true
+			}
+			if input || (world != nil && world.Connected() && !world.SimIsPaused && len(world.Aircraft) > 0) {
+				lastActivity = lastFrame
+			}
 
 			stats.redraws++
 
@@ -407,11 +473,19 @@ func main() {
 			if world != nil {
 				world.GetUpdates(eventStream,
 					func(err error) {
+						if isRPCServerError(err) && world.TryReconnect() {
+							eventStream.Post(Event{
+								Type:    StatusMessageEvent,
+								Message: "Lost connection to the vice server; reconnecting...",
+							})
+							return
+						}
+
 						eventStream.Post(Event{
 							Type:    StatusMessageEvent,
 							Message: "Error getting update from server: " + err.Error(),
 						})
-						if isRPCServerError(err) {
+						if isRPCServerError(err) || errors.Is(err, ErrReconnectFailed) {
 							uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{
 								message: "Lost connection to the vice server.",
 							}), true)
@@ -423,6 +497,7 @@ func main() {
 						}
 					})
 			}
+			timeMarker(&stats.update)
 
 			platform.NewFrame()
 			imgui.NewFrame()
@@ -430,6 +505,12 @@ func main() {
 			// Generate and render vice draw lists
 			if world != nil {
 				wmDrawPanes(platform, renderer, world, &stats)
+				if snapshotter != nil {
+					snapshotter.Capture(platform, renderer)
+				}
+				if world.timelapse != nil {
+					world.timelapse.Capture(platform, renderer, world)
+				}
 			} else {
 				commandBuffer := GetCommandBuffer()
 				commandBuffer.ClearRGB(RGB{})
@@ -437,11 +518,19 @@ func main() {
 				ReturnCommandBuffer(commandBuffer)
 			}
 
+			updateScreenshot(platform, renderer, false, eventStream)
 			timeMarker(&stats.drawPanes)
 
 			// Draw the user interface
 			drawUI(platform, renderer, world, eventStream, &stats)
+			updateScreenshot(platform, renderer, true, eventStream)
 			timeMarker(&stats.drawImgui)
+			if profilerEnabled() {
+				profiler.RecordFrame(&stats)
+			}
+			if metrics != nil {
+				metrics.RecordFrame(&stats, world, eventStream)
+			}
 
 			// Wait for vsync
 			platform.PostRender()
@@ -460,6 +549,7 @@ func main() {
 				if world != nil {
 					world.Disconnect()
 				}
+				endSession()
 				break
 			}
 		}
@@ -470,3 +560,22 @@ func main() {
 		writeMemProfile()
 	}
 }
+
+// Frame rate used when there has been no user input and no aircraft have
+// moved for IdleTimeout; this keeps vice from using a lot of power when
+// it's left running but nothing is happening.
+const IdleFrameRate = 4
+const IdleTimeout = 10 * time.Second
+
+// targetFrameRate returns the frame rate that the next frame should be
+// limited to, given the time since there was last activity, or 0 if there
+// is no limit.
+func targetFrameRate(config *GlobalConfig, sinceActivity time.Duration) int {
+	fps := config.FrameRateLimit
+	if !config.DisableIdleFrameRate && sinceActivity > IdleTimeout {
+		if fps == 0 || fps > IdleFrameRate {
+			fps = IdleFrameRate
+		}
+	}
+	return fps
+}
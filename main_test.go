@@ -0,0 +1,38 @@
+// main_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTargetFrameRate(t *testing.T) {
+	active, idle := time.Second, IdleTimeout+time.Second
+	for _, test := range []struct {
+		limit         int
+		disableIdle   bool
+		sinceActivity time.Duration
+		fps           int
+	}{
+		// Active: only the user's limit applies.
+		{0, false, active, 0},
+		{30, false, active, 30},
+		{2, false, active, 2},
+		// Idle: limited to IdleFrameRate unless the user's limit is lower.
+		{0, false, idle, IdleFrameRate},
+		{30, false, idle, IdleFrameRate},
+		{2, false, idle, 2},
+		// Idle with the idle frame rate disabled.
+		{0, true, idle, 0},
+		{30, true, idle, 30},
+	} {
+		config := &GlobalConfig{FrameRateLimit: test.limit, DisableIdleFrameRate: test.disableIdle}
+		if fps := targetFrameRate(config, test.sinceActivity); fps != test.fps {
+			t.Errorf("targetFrameRate(limit %d, disable idle %v, %s) gave %d, expected %d",
+				test.limit, test.disableIdle, test.sinceActivity, fps, test.fps)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// maptiles.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// MapTiles
+
+// MapTiles provides a raster map or shaded terrain underlay for radar
+// scopes, using standard web mercator ("slippy map") tiles. As with the
+// WeatherRadar, tiles are fetched in a separate goroutine; they are cached
+// on disk so that they only need to be downloaded once.
+type MapTiles struct {
+	source   MapTileSource
+	renderer Renderer
+
+	// The tiles needed for the current view are sent to the fetching
+	// goroutine via reqChan; tile images come back via imgChan.
+	reqChan chan []mapTileKey
+	imgChan chan mapTileImage
+
+	view    [2]Point2LL // center, (range, 0) of the current view
+	visible []mapTileKey
+	texId   map[mapTileKey]uint32
+}
+
+type MapTileSource int
+
+const (
+	MapTilesNone MapTileSource = iota
+	MapTilesStreet
+	MapTilesSatellite
+	MapTilesTerrain
+)
+
+func (s MapTileSource) String() string {
+	return [...]string{"None", "Street", "Satellite", "Terrain"}[s]
+}
+
+// maxZoom returns the maximum zoom level for which the source has tiles.
+func (s MapTileSource) maxZoom() int {
+	return Select(s == MapTilesTerrain, 15, 17)
+}
+
+// url returns the URL from which the given tile can be fetched.
+func (s MapTileSource) url(k mapTileKey) string {
+	switch s {
+	case MapTilesStreet:
+		return fmt.Sprintf("https://tile.openstreetmap.org/%d/%d/%d.png", k.z, k.x, k.y)
+	case MapTilesSatellite:
+		return fmt.Sprintf("https://server.arcgisonline.com/ArcGIS/rest/services/World_Imagery/MapServer/tile/%d/%d/%d",
+			k.z, k.y, k.x)
+	case MapTilesTerrain:
+		// Elevation data derived from SRTM and other sources, encoded in
+		// the "terrarium" format; see shadeTerrain().
+		return fmt.Sprintf("https://s3.amazonaws.com/elevation-tiles-prod/terrarium/%d/%d/%d.png", k.z, k.x, k.y)
+	default:
+		return ""
+	}
+}
+
+type mapTileKey struct {
+	z, x, y int
+}
+
+type mapTileImage struct {
+	key mapTileKey
+	img image.Image
+}
+
+// Don't request more tiles than this for a single view.
+const maxMapTiles = 64
+
+// Activate starts fetching tiles from the given source.
+func (m *MapTiles) Activate(source MapTileSource, r Renderer) {
+	if m.reqChan != nil && source == m.source {
+		return
+	}
+	m.Deactivate()
+
+	m.source = source
+	m.renderer = r
+	m.reqChan = make(chan []mapTileKey, 8)
+	m.imgChan = make(chan mapTileImage, maxMapTiles)
+	m.view = [2]Point2LL{}
+	m.visible = nil
+	m.texId = make(map[mapTileKey]uint32)
+
+	go fetchMapTiles(source, m.reqChan, m.imgChan)
+}
+
+// Deactivate stops fetching tiles and frees the textures for the ones that
+// have been fetched. The goroutine exits once it has finished with any
+// tile it is currently fetching.
+func (m *MapTiles) Deactivate() {
+	if m.reqChan != nil {
+		close(m.reqChan)
+		m.reqChan = nil
+	}
+	for _, id := range m.texId {
+		m.renderer.DestroyTexture(id)
+	}
+	m.texId = nil
+}
+
+// mapTilesForView returns the tiles that cover the given view with a
+// reasonable level of detail.
+func mapTilesForView(center Point2LL, rangenm float32, maxZoom int) []mapTileKey {
+	// Pick the zoom level so that the view is about 4 tiles across.
+	lat := float64(center[1])
+	nmPerTileAtZ0 := 360 * 60 * math.Cos(lat*math.Pi/180)
+	z := int(math.Round(math.Log2(nmPerTileAtZ0 / (float64(2*rangenm) / 4))))
+	z = clamp(z, 3, maxZoom)
+
+	// Tile coordinates of the corners of the view, expanded a bit since
+	// the pane's aspect ratio isn't known here.
+	dlat := float64(rangenm) / 60 * 1.5
+	dlong := dlat / math.Cos(lat*math.Pi/180)
+	x0, y0 := mapTileXY(lat+dlat, float64(center[0])-dlong, z)
+	x1, y1 := mapTileXY(lat-dlat, float64(center[0])+dlong, z)
+
+	var keys []mapTileKey
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if len(keys) < maxMapTiles {
+				keys = append(keys, mapTileKey{z: z, x: x, y: y})
+			}
+		}
+	}
+	return keys
+}
+
+// mapTileXY returns the coordinates of the web mercator tile at zoom
+// level z that includes the given point.
+func mapTileXY(lat, long float64, z int) (int, int) {
+	n := math.Exp2(float64(z))
+	latr := lat * math.Pi / 180
+	x := (long + 180) / 360 * n
+	y := (1 - math.Log(math.Tan(latr)+1/math.Cos(latr))/math.Pi) / 2 * n
+	return clamp(int(x), 0, int(n)-1), clamp(int(y), 0, int(n)-1)
+}
+
+// mapTileCorner returns the latitude-longitude of the upper-left corner of
+// the given tile.
+func mapTileCorner(x, y, z int) Point2LL {
+	n := math.Exp2(float64(z))
+	long := float64(x)/n*360 - 180
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/n))) * 180 / math.Pi
+	return Point2LL{float32(long), float32(lat)}
+}
+
+// fetchMapTiles runs asynchronously in a goroutine, receiving lists of
+// tiles from reqChan and returning their images via imgChan. If a new
+// request arrives while the tiles from a previous one are being fetched,
+// the remainder of the previous request is abandoned.
+func fetchMapTiles(source MapTileSource, reqChan chan []mapTileKey, imgChan chan mapTileImage) {
+	cacheDir := ""
+	if dir, err := os.UserCacheDir(); err == nil {
+		cacheDir = filepath.Join(dir, "Vice", "tiles", strings.ToLower(source.String()))
+	}
+
+	for keys := range reqChan {
+		for _, k := range keys {
+			// Skip ahead if there's a more recent request.
+			if len(reqChan) > 0 {
+				break
+			}
+
+			img, err := loadMapTile(source, k, cacheDir)
+			if err != nil {
+				lg.Info("map tile", slog.Any("tile", k), slog.Any("error", err))
+				continue
+			}
+			if source == MapTilesTerrain {
+				img = shadeTerrain(img, k)
+			}
+			imgChan <- mapTileImage{key: k, img: img}
+		}
+	}
+}
+
+// loadMapTile returns the image for the given tile, from the disk cache if
+// it is available there and otherwise from the network.
+func loadMapTile(source MapTileSource, k mapTileKey, cacheDir string) (image.Image, error) {
+	fn := ""
+	if cacheDir != "" {
+		fn = filepath.Join(cacheDir, fmt.Sprintf("%d", k.z), fmt.Sprintf("%d", k.x), fmt.Sprintf("%d", k.y))
+		if b, err := os.ReadFile(fn); err == nil {
+			img, _, err := image.Decode(bytes.NewReader(b))
+			return img, err
+		}
+	}
+
+	req, err := http.NewRequest("GET", source.url(k), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Tile servers require that clients identify themselves.
+	req.Header.Set("User-Agent", "vice/"+buildVersion)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source.url(k), resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	if fn != "" {
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err == nil {
+			if err := os.WriteFile(fn, b, 0o644); err != nil {
+				lg.Warnf("%s: unable to cache map tile: %v", fn, err)
+			}
+		}
+	}
+	return img, nil
+}
+
+// Draw draws the tiles from the given source that are available for the
+// current view with the given brightness, starting or stopping tile
+// fetching as needed if the source has changed.
+func (m *MapTiles) Draw(ctx *PaneContext, source MapTileSource, center Point2LL, rangenm float32, brightness float32,
+	transforms ScopeTransformations, cb *CommandBuffer) {
+	if source == MapTilesNone {
+		if m.reqChan != nil {
+			m.Deactivate()
+		}
+		return
+	}
+	m.Activate(source, ctx.renderer)
+
+	// Request the tiles we don't already have if the view has changed.
+	if view := [2]Point2LL{center, {rangenm, 0}}; view != m.view {
+		m.view = view
+		m.visible = mapTilesForView(center, rangenm, source.maxZoom())
+
+		// Free the textures of tiles that are no longer visible; they
+		// will be quickly reloaded from the disk cache if needed again.
+		var need []mapTileKey
+		for k, id := range m.texId {
+			if !slices.Contains(m.visible, k) {
+				ctx.renderer.DestroyTexture(id)
+				delete(m.texId, k)
+			}
+		}
+		for _, k := range m.visible {
+			if _, ok := m.texId[k]; !ok {
+				need = append(need, k)
+			}
+		}
+
+		select {
+		case m.reqChan <- need:
+		default:
+			// As with weather, it's fine to drop requests if the user is
+			// dragging the scope around.
+		}
+	}
+
+	// Make textures for any tiles that have arrived.
+	for done := false; !done; {
+		select {
+		case ti := <-m.imgChan:
+			if !slices.Contains(m.visible, ti.key) {
+				// The view changed since it was requested.
+				break
+			}
+			if id, ok := m.texId[ti.key]; ok {
+				ctx.renderer.UpdateTextureFromImage(id, ti.img, false)
+			} else {
+				m.texId[ti.key] = ctx.renderer.CreateTextureFromImage(ti.img, false)
+			}
+		default:
+			done = true
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.SetRGBA(RGBA{1, 1, 1, brightness})
+	cb.Blend()
+	tb := GetTexturedTrianglesDrawBuilder()
+	defer ReturnTexturedTrianglesDrawBuilder(tb)
+	for _, k := range m.visible {
+		id, ok := m.texId[k]
+		if !ok {
+			continue
+		}
+		p0, p1 := mapTileCorner(k.x, k.y, k.z), mapTileCorner(k.x+1, k.y+1, k.z)
+		tb.Reset()
+		tb.AddQuad(p0, [2]float32{p1[0], p0[1]}, p1, [2]float32{p0[0], p1[1]},
+			[2]float32{0, 0}, [2]float32{1, 0}, [2]float32{1, 1}, [2]float32{0, 1})
+		cb.EnableTexture(id)
+		tb.GenerateCommands(cb)
+		cb.DisableTexture()
+	}
+	cb.DisableBlend()
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Terrain
+
+// terrainBands gives the colors used for terrain elevation, in 1000'
+// bands starting at sea level; higher terrain uses the last color. They
+// are chosen so that it's easy to compare terrain to the MVAs, which are
+// generally specified in hundreds of feet.
+var terrainBands = []RGB{
+	{0.16, 0.32, 0.16}, {0.22, 0.40, 0.18}, {0.32, 0.46, 0.20}, {0.44, 0.50, 0.22},
+	{0.54, 0.52, 0.26}, {0.60, 0.50, 0.28}, {0.62, 0.44, 0.26}, {0.60, 0.38, 0.24},
+	{0.56, 0.32, 0.24}, {0.54, 0.30, 0.30}, {0.58, 0.40, 0.42}, {0.66, 0.54, 0.58},
+	{0.76, 0.70, 0.74}, {0.86, 0.84, 0.86},
+}
+
+// shadeTerrain converts a tile of elevation data in the terrarium
+// encoding to a hillshaded image where the color indicates the elevation.
+// Water is left transparent.
+func shadeTerrain(img image.Image, k mapTileKey) image.Image {
+	b := img.Bounds()
+	nx, ny := b.Dx(), b.Dy()
+
+	// Decode elevations (in feet).
+	elev := make([]float32, nx*ny)
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			meters := float32(r>>8)*256 + float32(g>>8) + float32(bl>>8)/256 - 32768
+			elev[x+y*nx] = meters * 3.28084
+		}
+	}
+	at := func(x, y int) float32 {
+		return elev[clamp(x, 0, nx-1)+clamp(y, 0, ny-1)*nx]
+	}
+
+	// Horizontal size of a pixel (in feet), at the tile's northern edge.
+	lat := mapTileCorner(k.x, k.y, k.z)[1]
+	pixelFeet := 131479659 * cos(radians(lat)) / float32(nx) / float32(math.Exp2(float64(k.z)))
+
+	// Light from the northwest, 45 degrees above the horizon.
+	light := [3]float32{-0.5, 0.5, 0.7071}
+
+	shaded := image.NewRGBA(image.Rect(0, 0, nx, ny))
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			e := at(x, y)
+			if e <= 0 {
+				continue
+			}
+
+			// Surface normal from central differences; note that image y
+			// increases to the south.
+			dx := (at(x+1, y) - at(x-1, y)) / (2 * pixelFeet)
+			dy := (at(x, y-1) - at(x, y+1)) / (2 * pixelFeet)
+			n := [3]float32{-dx, -dy, 1}
+			nlen := sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])
+			shade := max(0, (n[0]*light[0]+n[1]*light[1]+n[2]*light[2])/nlen)
+			shade = lerp(shade, 0.35, 1) // don't go completely black
+
+			c := terrainBands[min(int(e/1000), len(terrainBands)-1)]
+			shaded.Set(x, y, color.RGBA{
+				R: uint8(255 * shade * c.R),
+				G: uint8(255 * shade * c.G),
+				B: uint8(255 * shade * c.B),
+				A: 255,
+			})
+		}
+	}
+	return shaded
+}
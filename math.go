@@ -349,6 +349,46 @@ func PointSegmentDistance(p, v, w [2]float32) float32 {
 	return distance2f(p, proj)
 }
 
+// SimplifyPolyline returns an approximation of the given polyline with
+// vertices removed such that no removed vertex is more than tolerance
+// from the simplified polyline, using the Ramer-Douglas-Peucker
+// algorithm. The endpoints are always preserved.
+func SimplifyPolyline(pts [][2]float32, tolerance float32) [][2]float32 {
+	if len(pts) <= 2 {
+		return pts
+	}
+
+	keep := make([]bool, len(pts))
+	keep[0], keep[len(pts)-1] = true, true
+
+	// Use an explicit stack of spans rather than recursing, since video
+	// map polylines may have many thousands of vertices.
+	stack := [][2]int{{0, len(pts) - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		maxDist, maxIdx := float32(0), -1
+		for i := span[0] + 1; i < span[1]; i++ {
+			if d := PointSegmentDistance(pts[i], pts[span[0]], pts[span[1]]); d > maxDist {
+				maxDist, maxIdx = d, i
+			}
+		}
+		if maxDist > tolerance {
+			keep[maxIdx] = true
+			stack = append(stack, [2]int{span[0], maxIdx}, [2]int{maxIdx, span[1]})
+		}
+	}
+
+	var s [][2]float32
+	for i, p := range pts {
+		if keep[i] {
+			s = append(s, p)
+		}
+	}
+	return s
+}
+
 // ClosestPointOnLine returns the closest point on the (infinite) line to
 // the given point p.
 func ClosestPointOnLine(line [2][2]float32, p [2]float32) [2]float32 {
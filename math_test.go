@@ -5,6 +5,7 @@
 package main
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -349,3 +350,25 @@ func TestLCM(t *testing.T) {
 		}
 	}
 }
+
+func TestSimplifyPolyline(t *testing.T) {
+	// A nearly-straight line with a single significant corner.
+	pts := [][2]float32{{0, 0}, {1, 0.01}, {2, -0.01}, {3, 0}, {3.01, 1}, {3, 2}}
+	s := SimplifyPolyline(pts, 0.1)
+	expected := [][2]float32{{0, 0}, {3, 0}, {3, 2}}
+	if !slices.Equal(s, expected) {
+		t.Errorf("simplified %v: expected %v, got %v", pts, expected, s)
+	}
+
+	// With a tiny tolerance, nothing should be removed.
+	if s := SimplifyPolyline(pts, 0.001); !slices.Equal(s, pts) {
+		t.Errorf("simplified %v with small tolerance: got %v", pts, s)
+	}
+
+	// Degenerate cases
+	for _, p := range [][][2]float32{nil, {{1, 1}}, {{0, 0}, {5, 5}}} {
+		if s := SimplifyPolyline(p, 1); !slices.Equal(s, p) {
+			t.Errorf("simplified %v: got %v", p, s)
+		}
+	}
+}
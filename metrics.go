@@ -0,0 +1,108 @@
+// metrics.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// MetricsServer serves performance metrics in the Prometheus text format
+// so that long sessions can be graphed with Prometheus and Grafana. It's
+// enabled with the -metricshttp option. Values are recorded once per
+// frame; memory statistics are gathered when the metrics are scraped.
+type MetricsServer struct {
+	mu sync.Mutex
+
+	frames           int64
+	frameTime        time.Duration // most recent frame
+	frameTimeSum     time.Duration
+	aircraft         int
+	trackedAircraft  int
+	networkLatency   time.Duration // most recent world update
+	worldUpdates     int64
+	eventQueueLength int
+}
+
+var metrics *MetricsServer
+
+// NewMetricsServer returns a MetricsServer that serves the metrics at
+// /metrics at the given address (e.g., "localhost:9100").
+func NewMetricsServer(address string) *MetricsServer {
+	m := &MetricsServer{}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	go func() {
+		lg.Category(LogCategoryNetwork).Infof("Serving metrics at %s/metrics", address)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			lg.Category(LogCategoryNetwork).Errorf("%s: metrics server: %v", address, err)
+		}
+	}()
+
+	return m
+}
+
+// RecordFrame should be called at the end of each frame.
+func (m *MetricsServer) RecordFrame(stats *Stats, w *World, eventStream *EventStream) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.frames++
+	m.frameTime = stats.update + stats.drawPanes + stats.drawImgui
+	m.frameTimeSum += m.frameTime
+	m.aircraft, m.trackedAircraft = 0, 0
+	if w != nil {
+		m.aircraft = len(w.Aircraft)
+		for _, ac := range w.Aircraft {
+			if ac.TrackingController == w.Callsign {
+				m.trackedAircraft++
+			}
+		}
+	}
+	m.eventQueueLength = eventStream.Len()
+}
+
+func (m *MetricsServer) RecordNetworkLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.networkLatency = d
+	m.worldUpdates++
+}
+
+// ServeHTTP writes the current metrics.
+func (m *MetricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("vice_frames_total", "counter", "Number of frames drawn.", m.frames)
+	metric("vice_frame_seconds", "gauge", "Time to update and draw the most recent frame.",
+		m.frameTime.Seconds())
+	metric("vice_frame_seconds_total", "counter", "Total time spent updating and drawing frames.",
+		m.frameTimeSum.Seconds())
+	metric("vice_aircraft", "gauge", "Number of aircraft in the simulation.", m.aircraft)
+	metric("vice_tracked_aircraft", "gauge", "Number of aircraft tracked by the controller.",
+		m.trackedAircraft)
+	metric("vice_network_latency_seconds", "gauge", "Response time of the most recent world update.",
+		m.networkLatency.Seconds())
+	metric("vice_world_updates_total", "counter", "Number of world updates received.", m.worldUpdates)
+	metric("vice_event_queue_length", "gauge", "Number of events in the event stream.", m.eventQueueLength)
+	metric("vice_memory_heap_bytes", "gauge", "Bytes of allocated heap objects.", mem.HeapAlloc)
+	metric("vice_memory_sys_bytes", "gauge", "Bytes of memory obtained from the OS.", mem.Sys)
+	metric("vice_gc_total", "counter", "Number of completed garbage collections.", mem.NumGC)
+	metric("vice_goroutines", "gauge", "Number of goroutines.", runtime.NumGoroutine())
+}
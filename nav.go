@@ -1386,6 +1386,65 @@ func (nav *Nav) getUpcomingSpeedRestrictionWaypoint() (*Waypoint, float32, float
 	return nil, 0, 0
 }
 
+// DescentAdvisory describes the descent needed for an arrival to meet an
+// upcoming crossing restriction.
+type DescentAdvisory struct {
+	Fix          string
+	Restriction  AltitudeRestriction
+	Distance     float32 // along the route, in nm
+	RequiredRate float32 // ft/minute
+	Unable       bool    // the required rate exceeds the aircraft's capabilities
+}
+
+// String returns a short summary of the advisory, e.g. "needs 2500 fpm".
+func (da DescentAdvisory) String() string {
+	if da.Unable {
+		return "unable"
+	}
+	return fmt.Sprintf("needs %d fpm", int(da.RequiredRate+50)/100*100)
+}
+
+// GetDescentAdvisory returns the advisory for the upcoming crossing
+// restriction that requires the highest rate of descent, given the
+// aircraft's current altitude and groundspeed. nil is returned if no
+// descent is needed to meet the restrictions along the route.
+func (nav *Nav) GetDescentAdvisory() *DescentAdvisory {
+	if nav.FlightState.IsDeparture || nav.FlightState.GS < 1 {
+		return nil
+	}
+
+	var advisory *DescentAdvisory
+	var d float32
+	for i, wp := range nav.Waypoints {
+		if i == 0 {
+			d = nmdistance2ll(nav.FlightState.Position, wp.Location)
+		} else {
+			d += nmdistance2ll(wp.Location, nav.Waypoints[i-1].Location)
+		}
+
+		r := wp.AltitudeRestriction
+		if nfa, ok := nav.FixAssignments[wp.Fix]; ok && nfa.Arrive.Altitude != nil {
+			r = nfa.Arrive.Altitude
+		}
+		if r == nil || r.Range[1] == 0 || nav.FlightState.Altitude <= r.Range[1] {
+			continue
+		}
+
+		minutes := d / nav.FlightState.GS * 60
+		rate := (nav.FlightState.Altitude - r.Range[1]) / max(minutes, 1./60)
+		if advisory == nil || rate > advisory.RequiredRate {
+			advisory = &DescentAdvisory{
+				Fix:          wp.Fix,
+				Restriction:  *r,
+				Distance:     d,
+				RequiredRate: rate,
+				Unable:       rate > nav.Perf.Rate.Descent,
+			}
+		}
+	}
+	return advisory
+}
+
 // distanceToEndOfApproach returns the remaining distance to the last
 // waypoint (usually runway threshold) of the currently assigned approach.
 func (nav *Nav) distanceToEndOfApproach() (float32, error) {
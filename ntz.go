@@ -0,0 +1,211 @@
+// ntz.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+)
+
+// For simultaneous independent approaches to parallel runways, a final
+// monitor watches a no transgression zone (NTZ) between the two finals.
+// Here, aircraft on the approach to either runway are monitored when both
+// are active arrival runways: their closure rate toward the adjacent
+// final is computed, a caution is issued if one is predicted to enter the
+// NTZ soon, and an alert is issued once one is in it.
+
+type NTZStatus int
+
+const (
+	NTZStatusNone    NTZStatus = iota
+	NTZStatusCaution           // predicted to enter the NTZ
+	NTZStatusAlert             // in the NTZ or beyond it
+)
+
+// Aircraft predicted to enter the NTZ within this many seconds are given
+// a caution.
+const STARSNTZCautionTime = 10
+
+// The closure rate, in knots, toward the adjacent final at which it is
+// shown in the datablock.
+const STARSNTZMinClosure = 5
+
+// ntzGeometry describes the NTZ with respect to one of the runways' final
+// approach course, in nm coordinates.
+type ntzGeometry struct {
+	threshold [2]float32
+	u         [2]float32 // along the final, away from the runway
+	n         [2]float32 // perpendicular to the final, toward the other one
+	// Distance between the finals, half of the NTZ's width, and its length.
+	separation, halfWidth, length float32
+}
+
+func makeNTZGeometry(icao string, pair ParallelRunways, i int, nmPerLongitude, magneticVariation float32) (ntzGeometry, bool) {
+	rwy, ok := LookupRunway(icao, pair.Runways[i])
+	if !ok {
+		return ntzGeometry{}, false
+	}
+	other, ok := LookupRunway(icao, pair.Runways[i^1])
+	if !ok {
+		return ntzGeometry{}, false
+	}
+
+	t, to := ll2nm(rwy.Threshold, nmPerLongitude), ll2nm(other.Threshold, nmPerLongitude)
+	hdg := rwy.Heading - magneticVariation + 180
+	u := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
+	n := [2]float32{-u[1], u[0]}
+	if dot(sub2f(to, t), n) < 0 {
+		n = scale2f(n, -1)
+	}
+
+	return ntzGeometry{
+		threshold:  t,
+		u:          u,
+		n:          n,
+		separation: dot(sub2f(to, t), n),
+		halfWidth:  pair.NTZWidth / 2 / NauticalMilesToFeet,
+		length:     pair.NTZLength,
+	}, true
+}
+
+// offsets returns the distance of the given point along the final and
+// its lateral offset from the final toward the other one.
+func (g ntzGeometry) offsets(p [2]float32) (along, lateral float32) {
+	d := sub2f(p, g.threshold)
+	return dot(d, g.u), dot(d, g.n)
+}
+
+// edge returns the lateral offset of the near side of the NTZ.
+func (g ntzGeometry) edge() float32 {
+	return g.separation/2 - g.halfWidth
+}
+
+func (g ntzGeometry) outline(nmPerLongitude float32) [4]Point2LL {
+	pt := func(along, lateral float32) Point2LL {
+		return nm2ll(add2f(g.threshold, add2f(scale2f(g.u, along), scale2f(g.n, lateral))), nmPerLongitude)
+	}
+	near, far := g.edge(), g.separation/2+g.halfWidth
+	return [4]Point2LL{pt(0, near), pt(g.length, near), pt(g.length, far), pt(0, far)}
+}
+
+// activeParallelRunways calls the given function for each pair of
+// parallel runways that are both in use for arrivals.
+func activeParallelRunways(w *World, f func(icao string, pair ParallelRunways)) {
+	rc := w.RunwayConfiguration()
+	for _, icao := range SortedMapKeys(w.Airports) {
+		for _, pair := range w.Airports[icao].ParallelRunways {
+			if rc.IsArrivalRunway(icao, pair.Runways[0]) && rc.IsArrivalRunway(icao, pair.Runways[1]) {
+				f(icao, pair)
+			}
+		}
+	}
+}
+
+func (sp *STARSPane) updateNTZMonitor(aircraft []*Aircraft, w *World) {
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		state.NTZMonitored, state.NTZClosure, state.NTZStatus = false, 0, NTZStatusNone
+	}
+	if !sp.NTZMonitor {
+		return
+	}
+
+	activeParallelRunways(w, func(icao string, pair ParallelRunways) {
+		for i, rwy := range pair.Runways {
+			g, ok := makeNTZGeometry(icao, pair, i, w.NmPerLongitude, w.MagneticVariation)
+			if !ok {
+				continue
+			}
+
+			for _, ac := range aircraft {
+				if vol := ac.ATPAVolume(); vol == nil || vol.Id != icao+rwy {
+					continue
+				}
+				state := sp.Aircraft[ac.Callsign]
+				v, ok := state.GroundVelocity(w.NmPerLongitude)
+				if !ok {
+					continue
+				}
+				along, lateral := g.offsets(ll2nm(state.TrackPosition(), w.NmPerLongitude))
+				if along < 0 || along > g.length {
+					continue
+				}
+
+				state.NTZMonitored = true
+				state.NTZClosure = int(dot(v, g.n))
+				if lateral >= g.edge() {
+					state.NTZStatus = NTZStatusAlert
+				} else if state.NTZClosure > 0 &&
+					(g.edge()-lateral)/float32(state.NTZClosure)*3600 < STARSNTZCautionTime {
+					state.NTZStatus = NTZStatusCaution
+				}
+			}
+		}
+	})
+
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		if state.NTZStatus == NTZStatusAlert && !state.ntzAlerted {
+			globalConfig.Audio.PlayOnce(AudioConflictAlert)
+		}
+		state.ntzAlerted = state.NTZStatus == NTZStatusAlert
+	}
+}
+
+// ntzClosure returns the text for the NTZ closure rate datablock field
+// and, if it should be highlighted, the color to draw it in.
+func (sp *STARSPane) ntzClosure(state *STARSAircraftState) (text string, color RGB, highlight bool) {
+	if !state.NTZMonitored || (state.NTZClosure < STARSNTZMinClosure && state.NTZStatus == NTZStatusNone) {
+		return
+	}
+
+	text = fmt.Sprintf(" C%02d", min(max(state.NTZClosure, 0), 99))
+	switch state.NTZStatus {
+	case NTZStatusCaution:
+		return text, STARSATPAWarningColor, true
+	case NTZStatusAlert:
+		return text, STARSTextAlertColor, true
+	default:
+		return text, RGB{}, false
+	}
+}
+
+func (sp *STARSPane) drawNTZs(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if !sp.NTZMonitor {
+		return
+	}
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	ps := sp.CurrentPreferenceSet
+	w := ctx.world
+	activeParallelRunways(w, func(icao string, pair ParallelRunways) {
+		g, ok := makeNTZGeometry(icao, pair, 0, w.NmPerLongitude, w.MagneticVariation)
+		if !ok {
+			return
+		}
+
+		// The NTZ is drawn in the alert color if an aircraft on either
+		// final is in it.
+		alert := false
+		for callsign, state := range sp.Aircraft {
+			if ac, ok := w.Aircraft[callsign]; ok && state.NTZStatus == NTZStatusAlert {
+				if vol := ac.ATPAVolume(); vol != nil && (vol.Id == icao+pair.Runways[0] || vol.Id == icao+pair.Runways[1]) {
+					alert = true
+				}
+			}
+		}
+		color := ps.Brightness.Lines.ScaleRGB(Select(alert, STARSTextAlertColor, STARSATPAWarningColor))
+
+		quad := g.outline(w.NmPerLongitude)
+		for i := range quad {
+			ld.AddLine(quad[i], quad[(i+1)%len(quad)], color)
+		}
+	})
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+}
@@ -22,15 +22,17 @@ type OpenGL2Renderer struct {
 // NewOpenGL2Renderer creates an OpenGL context and creates a texture for the imgui fonts.
 // Thus, all font creation must be finished before the renderer is created.
 func NewOpenGL2Renderer() (Renderer, error) {
-	lg.Info("Starting OpenGL2Renderer initialization")
+	lg.Category(LogCategoryRender).Info("Starting OpenGL2Renderer initialization")
 	if err := gl.Init(); err != nil {
 		return nil, fmt.Errorf("failed to initialize OpenGL: %w", err)
 	}
 	vendor, renderer := gl.GetString(gl.VENDOR), gl.GetString(gl.RENDERER)
 	v, r := (*C.char)(unsafe.Pointer(vendor)), (*C.char)(unsafe.Pointer(renderer))
-	lg.Infof("OpenGL vendor %s renderer %s", C.GoString(v), C.GoString(r))
+	version := C.GoString((*C.char)(unsafe.Pointer(gl.GetString(gl.VERSION))))
+	lg.Category(LogCategoryRender).Infof("OpenGL vendor %s renderer %s", C.GoString(v), C.GoString(r))
+	gpuDescription = fmt.Sprintf("OpenGL vendor %s, renderer %s, version %s", C.GoString(v), C.GoString(r), version)
 
-	lg.Info("Finished OpenGL2Renderer initialization")
+	lg.Category(LogCategoryRender).Info("Finished OpenGL2Renderer initialization")
 	return &OpenGL2Renderer{
 		createdTextures: make(map[uint32]int),
 	}, nil
@@ -42,6 +44,32 @@ func (ogl2 *OpenGL2Renderer) Dispose() {
 	}
 }
 
+func (ogl2 *OpenGL2Renderer) ReadPixels(x, y, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == 0 || height == 0 {
+		return img
+	}
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(int32(x), int32(y), int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE,
+		gl.Ptr(img.Pix))
+
+	// OpenGL returns the rows bottom-up, so flip them.
+	row := make([]byte, img.Stride)
+	for y0, y1 := 0, height-1; y0 < y1; y0, y1 = y0+1, y1-1 {
+		r0, r1 := img.Pix[y0*img.Stride:(y0+1)*img.Stride], img.Pix[y1*img.Stride:(y1+1)*img.Stride]
+		copy(row, r0)
+		copy(r0, r1)
+		copy(r1, row)
+	}
+	// The framebuffer's alpha isn't meaningful; make the image opaque.
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	return img
+}
+
 func (ogl2 *OpenGL2Renderer) createdTexture(texid uint32, bytes int) {
 	_, exists := ogl2.createdTextures[texid]
 
@@ -52,9 +80,9 @@ func (ogl2 *OpenGL2Renderer) createdTexture(texid uint32, bytes int) {
 	mb := float32(total) / (1024 * 1024)
 
 	if exists {
-		lg.Infof("Updated tex id %d: %d bytes -> %.2f MiB of textures total", texid, bytes, mb)
+		lg.Category(LogCategoryRender).Infof("Updated tex id %d: %d bytes -> %.2f MiB of textures total", texid, bytes, mb)
 	} else {
-		lg.Infof("Created tex id %d: %d bytes -> %.2f MiB of textures total", texid, bytes, mb)
+		lg.Category(LogCategoryRender).Infof("Created tex id %d: %d bytes -> %.2f MiB of textures total", texid, bytes, mb)
 	}
 }
 
@@ -174,6 +202,13 @@ func (ogl2 *OpenGL2Renderer) RenderCommandBuffer(cb *CommandBuffer) RendererStat
 		case RendererDisableBlend:
 			gl.Disable(gl.BLEND)
 
+		case RendererAlphaTest:
+			gl.Enable(gl.ALPHA_TEST)
+			gl.AlphaFunc(gl.GREATER, float())
+
+		case RendererDisableAlphaTest:
+			gl.Disable(gl.ALPHA_TEST)
+
 		case RendererSetRGBA:
 			r := float()
 			g := float()
@@ -268,6 +303,7 @@ func (ogl2 *OpenGL2Renderer) RenderCommandBuffer(cb *CommandBuffer) RendererStat
 			gl.Disable(gl.SCISSOR_TEST)
 			// viewport?
 			gl.Disable(gl.BLEND)
+			gl.Disable(gl.ALPHA_TEST)
 			gl.DisableClientState(gl.VERTEX_ARRAY)
 			gl.DisableClientState(gl.COLOR_ARRAY)
 			gl.DisableClientState(gl.TEXTURE_COORD_ARRAY)
@@ -279,7 +315,7 @@ func (ogl2 *OpenGL2Renderer) RenderCommandBuffer(cb *CommandBuffer) RendererStat
 			stats.Merge(s2)
 
 		default:
-			lg.Error("unhandled command")
+			lg.Category(LogCategoryRender).Error("unhandled command")
 		}
 	}
 
@@ -6,7 +6,9 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -137,6 +139,28 @@ const (
 	KeyF11
 	KeyF12
 	KeyV
+	KeyY
+	KeyZ
+	Key0 // Key0-Key9 are only reported when Control or Alt is held
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+	KeyKP0
+	KeyKP1
+	KeyKP2
+	KeyKP3
+	KeyKP4
+	KeyKP5
+	KeyKP6
+	KeyKP7
+	KeyKP8
+	KeyKP9
 )
 
 type KeyboardState struct {
@@ -197,12 +221,24 @@ func NewKeyboardState(p Platform) *KeyboardState {
 	if imgui.IsKeyPressed(imgui.GetKeyIndex(imgui.KeyV)) {
 		keyboard.Pressed[KeyV] = nil
 	}
+	if imgui.IsKeyPressed(imgui.GetKeyIndex(imgui.KeyY)) {
+		keyboard.Pressed[KeyY] = nil
+	}
+	if imgui.IsKeyPressed(imgui.GetKeyIndex(imgui.KeyZ)) {
+		keyboard.Pressed[KeyZ] = nil
+	}
 	const ImguiF1 = 290
 	for i := 0; i < 12; i++ {
 		if imgui.IsKeyPressed(ImguiF1 + i) {
 			keyboard.Pressed[Key(int(KeyF1)+i)] = nil
 		}
 	}
+	const ImguiKP0 = 320
+	for i := 0; i < 10; i++ {
+		if imgui.IsKeyPressed(ImguiKP0 + i) {
+			keyboard.Pressed[Key(int(KeyKP0)+i)] = nil
+		}
+	}
 	io := imgui.CurrentIO()
 	if io.KeyShiftPressed() {
 		keyboard.Pressed[KeyShift] = nil
@@ -245,18 +281,63 @@ func unmarshalPane(paneType string, data []byte) (Pane, error) {
 		// nil pane
 		return nil, nil
 
+	case "*main.AirportDiagramPane":
+		return unmarshalPaneHelper[*AirportDiagramPane](data)
+
+	case "*main.ArrivalPane":
+		return unmarshalPaneHelper[*ArrivalPane](data)
+
+	case "*main.ControllerPane":
+		return unmarshalPaneHelper[*ControllerPane](data)
+
+	case "*main.ClockPane":
+		return unmarshalPaneHelper[*ClockPane](data)
+
+	case "*main.CoordinationPane":
+		return unmarshalPaneHelper[*CoordinationPane](data)
+
+	case "*main.CrossingRestrictionPane":
+		return unmarshalPaneHelper[*CrossingRestrictionPane](data)
+
+	case "*main.DeparturePane":
+		return unmarshalPaneHelper[*DeparturePane](data)
+
 	case "*main.EmptyPane":
 		return unmarshalPaneHelper[*EmptyPane](data)
 
 	case "*main.FlightStripPane":
 		return unmarshalPaneHelper[*FlightStripPane](data)
 
+	case "*main.HoldsPane":
+		return unmarshalPaneHelper[*HoldsPane](data)
+
+	case "*main.LogViewerPane":
+		return unmarshalPaneHelper[*LogViewerPane](data)
+
 	case "*main.MessagesPane":
 		return unmarshalPaneHelper[*MessagesPane](data)
 
+	case "*main.RemindersPane":
+		return unmarshalPaneHelper[*RemindersPane](data)
+
+	case "*main.RunwayConfigPane":
+		return unmarshalPaneHelper[*RunwayConfigPane](data)
+
+	case "*main.SectorLoadPane":
+		return unmarshalPaneHelper[*SectorLoadPane](data)
+
+	case "*main.SessionStatsPane":
+		return unmarshalPaneHelper[*SessionStatsPane](data)
+
+	case "*main.SpeedAdvisoryPane":
+		return unmarshalPaneHelper[*SpeedAdvisoryPane](data)
+
 	case "*main.STARSPane":
 		return unmarshalPaneHelper[*STARSPane](data)
 
+	case "*main.TimelinePane":
+		return unmarshalPaneHelper[*TimelinePane](data)
+
 	default:
 		lg.Errorf("%s: Unhandled type in config file", paneType)
 		return NewEmptyPane(), nil
@@ -300,8 +381,9 @@ type FlightStripPane struct {
 	AddPushed                 bool
 	CollectDeparturesArrivals bool
 
-	strips        []string // callsigns
-	addedAircraft map[string]interface{}
+	strips         []string // callsigns
+	addedAircraft  map[string]interface{}
+	lastCompaction time.Time
 
 	mouseDragging       bool
 	lastMousePos        [2]float32
@@ -394,6 +476,15 @@ func (fsp *FlightStripPane) processEvents(w *World) {
 		_, ok := w.Aircraft[callsign]
 		return ok
 	})
+	if time.Since(fsp.lastCompaction) > RetentionCompactInterval {
+		for callsign := range fsp.addedAircraft {
+			if _, ok := w.Aircraft[callsign]; !ok {
+				delete(fsp.addedAircraft, callsign)
+			}
+		}
+		fsp.addedAircraft = compactMap(fsp.addedAircraft)
+		fsp.lastCompaction = time.Now()
+	}
 
 	remove := func(c string) {
 		fsp.strips = FilterSlice(fsp.strips, func(callsign string) bool { return callsign != c })
@@ -572,7 +663,7 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		x := indent
 
 		// First column; 3 entries
-		td.AddText(callsign, [2]float32{x, y}, style)
+		td.AddText(screenshotCallsign(callsign), [2]float32{x, y}, style)
 		if fp != nil {
 			td.AddText(fp.AircraftType, [2]float32{x, y - fh*3/2}, style)
 			td.AddText(fp.Rules.String(), [2]float32{x, y - fh*3}, style)
@@ -799,11 +890,27 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 ///////////////////////////////////////////////////////////////////////////
 // MessagesPane
 
+// Message channels; radio transmissions go to a channel named by the
+// frequency of the controller they were addressed to and private
+// messages to a channel named by "@" followed by the other controller's
+// callsign. Messages with an empty channel (command echoes, errors,
+// status messages, ...) are shown in all channels.
+const (
+	MessageChannelAll    = "ALL"
+	MessageChannelGlobal = "GLOBAL"
+)
+
+// Maximum number of messages saved in the scrollback.
+const maxMessageScrollback = 1000
+
 type Message struct {
-	contents string
-	system   bool
-	error    bool
-	global   bool
+	Contents string
+	Channel  string
+	Time     time.Time
+	System   bool
+	Error    bool
+	Global   bool
+	Alert    bool // matched one of the alert patterns
 }
 
 type CLIInput struct {
@@ -816,7 +923,24 @@ type MessagesPane struct {
 	font           *Font
 	scrollbar      *ScrollBar
 	events         *EventsSubscription
-	messages       []Message
+
+	// The scrollback is saved in the config file so that it persists
+	// across sessions; private messages aren't saved.
+	Messages []Message
+
+	// Radio transmissions to other controllers are only shown if this is
+	// set.
+	MonitorOtherFrequencies bool
+
+	// Regular expressions; incoming messages that match any of them are
+	// highlighted and trigger an audio alert.
+	AlertPatterns   []string
+	alertRegexps    []*regexp.Regexp
+	newAlertPattern string
+	alertPatternErr error
+
+	channel string // currently selected channel; "" -> all
+	search  string // only show messages that contain this, if set
 
 	// Command-input-related
 	input         CLIInput
@@ -831,6 +955,17 @@ func NewMessagesPane() *MessagesPane {
 	}
 }
 
+// MarshalJSON omits private messages from the scrollback that is saved
+// in the config file.
+func (mp *MessagesPane) MarshalJSON() ([]byte, error) {
+	type messagesPane MessagesPane
+	saved := messagesPane(*mp)
+	saved.Messages = FilterSlice(mp.Messages, func(msg Message) bool {
+		return !strings.HasPrefix(msg.Channel, "@")
+	})
+	return json.Marshal(saved)
+}
+
 func (mp *MessagesPane) Name() string { return "Messages" }
 
 func (mp *MessagesPane) Activate(w *World, r Renderer, eventStream *EventStream) {
@@ -842,6 +977,19 @@ func (mp *MessagesPane) Activate(w *World, r Renderer, eventStream *EventStream)
 		mp.scrollbar = NewVerticalScrollBar(4, true)
 	}
 	mp.events = eventStream.Subscribe()
+
+	// Invalid patterns are dropped so that AlertPatterns and alertRegexps
+	// stay in sync.
+	mp.alertRegexps = nil
+	mp.AlertPatterns = FilterSlice(mp.AlertPatterns, func(pat string) bool {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			lg.Errorf("%s: invalid alert pattern: %v", pat, err)
+			return false
+		}
+		mp.alertRegexps = append(mp.alertRegexps, re)
+		return true
+	})
 }
 
 func (mp *MessagesPane) Deactivate() {
@@ -850,7 +998,12 @@ func (mp *MessagesPane) Deactivate() {
 }
 
 func (mp *MessagesPane) ResetWorld(w *World) {
-	mp.messages = nil
+	// Keep the scrollback from earlier sessions but mark where this one
+	// starts.
+	mp.channel = ""
+	if len(mp.Messages) > 0 {
+		mp.addMessage(Message{Contents: "----- " + time.Now().Format(time.DateTime) + " -----", System: true})
+	}
 }
 
 func (mp *MessagesPane) CanTakeKeyboardFocus() bool { return true }
@@ -859,30 +1012,148 @@ func (mp *MessagesPane) DrawUI() {
 	if newFont, changed := DrawFontPicker(&mp.FontIdentifier, "Font"); changed {
 		mp.font = newFont
 	}
+	imgui.Checkbox("Show radio transmissions on other controllers' frequencies", &mp.MonitorOtherFrequencies)
+	if imgui.Button("Clear scrollback") {
+		mp.Messages = nil
+	}
+
+	imgui.Text("Alert patterns (regular expressions)")
+	for i := 0; i < len(mp.AlertPatterns); i++ {
+		imgui.Text(mp.AlertPatterns[i])
+		imgui.SameLine()
+		if imgui.Button("Remove##" + strconv.Itoa(i)) {
+			mp.AlertPatterns = DeleteSliceElement(mp.AlertPatterns, i)
+			mp.alertRegexps = DeleteSliceElement(mp.alertRegexps, i)
+			i--
+		}
+	}
+	imgui.InputTextV("##newalert", &mp.newAlertPattern, 0, nil)
+	imgui.SameLine()
+	if imgui.Button("Add") && mp.newAlertPattern != "" {
+		var re *regexp.Regexp
+		if re, mp.alertPatternErr = regexp.Compile(mp.newAlertPattern); mp.alertPatternErr == nil {
+			mp.AlertPatterns = append(mp.AlertPatterns, mp.newAlertPattern)
+			mp.alertRegexps = append(mp.alertRegexps, re)
+			mp.newAlertPattern = ""
+			// Presumably the user wants to hear them...
+			globalConfig.Audio.EffectEnabled[AudioMessageAlert] = true
+		}
+	}
+	if mp.alertPatternErr != nil {
+		imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+		imgui.Text(mp.alertPatternErr.Error())
+		imgui.PopStyleColor()
+	}
 }
 
-func (mp *MessagesPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
-	mp.processEvents(ctx.world)
+// addMessage adds the given message to the scrollback. Messages that
+// aren't from the user are checked against the alert patterns.
+func (mp *MessagesPane) addMessage(msg Message) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if !msg.System && !msg.Error {
+		if slices.ContainsFunc(mp.alertRegexps, func(re *regexp.Regexp) bool { return re.MatchString(msg.Contents) }) {
+			msg.Alert = true
+			globalConfig.Audio.PlayOnce(AudioMessageAlert)
+		}
+	}
 
-	if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
-		wmTakeKeyboardFocus(mp, false)
+	mp.Messages = append(mp.Messages, msg)
+	if n := len(mp.Messages); n > maxMessageScrollback {
+		mp.Messages = mp.Messages[n-maxMessageScrollback:]
 	}
-	mp.processKeyboard(ctx)
+}
 
-	nLines := len(mp.messages) + 1 /* prompt */
-	lineHeight := float32(mp.font.size + 1)
-	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
-	mp.scrollbar.Update(nLines, visibleLines, ctx)
+// channels returns the names of the channels to show as tabs: all of
+// them, our frequency, global messages, and then any other channels that
+// have messages.
+func (mp *MessagesPane) channels(w *World) []string {
+	ch := []string{MessageChannelAll}
+	if ctrl := w.GetControllerByCallsign(w.Callsign); ctrl != nil {
+		ch = append(ch, ctrl.Frequency.String())
+	}
+	ch = append(ch, MessageChannelGlobal)
 
-	drawWidth := ctx.paneExtent.Width()
-	if mp.scrollbar.Visible() {
-		drawWidth -= float32(mp.scrollbar.PixelExtent())
+	other := make(map[string]interface{})
+	for _, msg := range mp.Messages {
+		if msg.Channel != "" && !slices.Contains(ch, msg.Channel) && !mp.hidden(msg) {
+			other[msg.Channel] = nil
+		}
 	}
+	return append(ch, SortedMapKeys(other)...)
+}
+
+// hidden indicates whether the given message shouldn't be shown at all;
+// private messages aren't shown in streamer mode so that they aren't
+// broadcast to viewers.
+func (mp *MessagesPane) hidden(msg Message) bool {
+	return streamerModeEnabled() && strings.HasPrefix(msg.Channel, "@")
+}
+
+// visibleMessages returns the messages in the current channel that match
+// the current search string, if any.
+func (mp *MessagesPane) visibleMessages() []Message {
+	if mp.channel == "" && mp.search == "" && !streamerModeEnabled() {
+		return mp.Messages
+	}
+	return FilterSlice(mp.Messages, func(msg Message) bool {
+		if mp.hidden(msg) {
+			return false
+		}
+		if mp.channel != "" && msg.Channel != "" && msg.Channel != mp.channel {
+			return false
+		}
+		return mp.search == "" || strings.Contains(strings.ToUpper(msg.Contents), mp.search)
+	})
+}
+
+func (mp *MessagesPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	mp.processEvents(ctx.world)
+
+	lineHeight := float32(mp.font.size + 1)
+	bx, _ := mp.font.BoundText("X", 0)
+	fw := float32(bx)
+	indent := float32(2)
 
 	td := GetTextDrawBuilder()
 	defer ReturnTextDrawBuilder(td)
 
-	indent := float32(2)
+	// Channel tabs along the top; clicking on one selects it.
+	tabY := ctx.paneExtent.Height() - 1
+	if ctx.world != nil {
+		x := indent
+		for _, ch := range mp.channels(ctx.world) {
+			label := " " + ch + " "
+			sel := ch == mp.channel || (ch == MessageChannelAll && mp.channel == "")
+			if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+				if p := ctx.mouse.Pos; p[0] >= x && p[0] < x+fw*float32(len(label)) && p[1] <= tabY && p[1] > tabY-lineHeight {
+					mp.channel = Select(ch == MessageChannelAll, "", ch)
+					sel = true
+				}
+			}
+			style := TextStyle{Font: mp.font, Color: Select(sel, UITextHighlightColor, UITextColor)}
+			if sel {
+				style.DrawBackground = true
+				style.BackgroundColor = UIControlColor
+			}
+			td.AddText(label, [2]float32{x, tabY}, style)
+			x += fw * float32(len(label)+1)
+		}
+	}
+
+	if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+		wmTakeKeyboardFocus(mp, false)
+	}
+	mp.processKeyboard(ctx)
+
+	messages := mp.visibleMessages()
+	nLines := len(messages) + 1 /* prompt */
+	if mp.search != "" {
+		nLines++
+	}
+	visibleLines := int((ctx.paneExtent.Height() - lineHeight) / lineHeight)
+	mp.scrollbar.Update(nLines, visibleLines, ctx)
 
 	scrollOffset := mp.scrollbar.Offset()
 	y := lineHeight
@@ -911,12 +1182,30 @@ func (mp *MessagesPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	}
 	y += lineHeight
 
-	for i := scrollOffset; i < min(len(mp.messages), visibleLines+scrollOffset+1); i++ {
+	if mp.search != "" {
+		text := fmt.Sprintf("SEARCH \"%s\": %d MATCHES", mp.search, len(messages))
+		td.AddText(text, [2]float32{indent, y}, TextStyle{Font: mp.font, Color: UICautionColor})
+		y += lineHeight
+	}
+
+	var callsign string
+	if ctx.world != nil {
+		callsign = ctx.world.Callsign
+	}
+	for i := scrollOffset; i < min(len(messages), visibleLines+scrollOffset+1); i++ {
+		if y > tabY-lineHeight {
+			break
+		}
+
 		// TODO? wrap text
-		msg := mp.messages[len(mp.messages)-1-i]
+		msg := messages[len(messages)-1-i]
 
-		s := TextStyle{Font: mp.font, Color: msg.Color()}
-		td.AddText(msg.contents, [2]float32{indent, y}, s)
+		s := TextStyle{Font: mp.font, Color: msg.Color(callsign)}
+		contents := msg.Contents
+		if mp.channel == "" && msg.Channel != "" && !msg.Global {
+			contents = "[" + msg.Channel + "] " + contents
+		}
+		td.AddText(contents, [2]float32{indent, y}, s)
 		y += lineHeight
 	}
 
@@ -950,8 +1239,8 @@ func (mp *MessagesPane) processKeyboard(ctx *PaneContext) {
 		})
 	}
 
-	// Grab keyboard input
-	if len(mp.input.cmd) > 0 && mp.input.cmd[0] == '/' {
+	// Grab keyboard input; case is preserved for global and private messages.
+	if len(mp.input.cmd) > 0 && (mp.input.cmd[0] == '/' || mp.input.cmd[0] == '@') {
 		mp.input.InsertAtCursor(ctx.keyboard.Input)
 	} else {
 		mp.input.InsertAtCursor(strings.ToUpper(ctx.keyboard.Input))
@@ -1012,6 +1301,8 @@ func (mp *MessagesPane) processKeyboard(ctx *PaneContext) {
 	if ctx.keyboard.IsPressed(KeyEscape) {
 		if mp.input.cursor > 0 {
 			mp.input = CLIInput{}
+		} else {
+			mp.search = ""
 		}
 	}
 
@@ -1020,11 +1311,20 @@ func (mp *MessagesPane) processKeyboard(ctx *PaneContext) {
 	}
 }
 
-func (msg *Message) Color() RGB {
+// Color returns the color to draw the message with; callsign is the
+// user's controller callsign.
+func (msg *Message) Color(callsign string) RGB {
 	switch {
-	case msg.error:
+	case msg.Error:
 		return RGB{.9, .1, .1}
-	case msg.global:
+	case msg.Alert:
+		return UICautionColor
+	case callsign != "" && !msg.System && slices.Contains(strings.Fields(msg.Contents), callsign):
+		// Highlight messages that mention us
+		return UITextHighlightColor
+	case strings.HasPrefix(msg.Channel, "@"):
+		return RGB{0.3, 0.8, 1}
+	case msg.Global:
 		return RGB{0.012, 0.78, 0.016}
 	default:
 		return RGB{1, 1, 1}
@@ -1039,22 +1339,61 @@ func (mp *MessagesPane) runCommands(w *World) {
 			FromController: w.Callsign,
 			Message:        w.Callsign + ": " + mp.input.cmd[1:],
 		})
-		mp.messages = append(mp.messages, Message{contents: w.Callsign + ": " + mp.input.cmd[1:], global: true})
+		mp.addMessage(Message{Contents: w.Callsign + ": " + mp.input.cmd[1:], Channel: MessageChannelGlobal,
+			Global: true})
+		mp.history = append(mp.history, mp.input)
+		mp.input = CLIInput{}
+		return
+	} else if mp.input.cmd[0] == '@' {
+		// Private message: @(controller) (message)
+		id, msg, _ := strings.Cut(mp.input.cmd[1:], " ")
+		mp.history = append(mp.history, mp.input)
+		mp.input = CLIInput{}
+		if ctrl := lookupCoordinationController(w, strings.ToUpper(id)); ctrl == nil {
+			mp.addMessage(Message{Contents: id + ": no such controller", Error: true})
+		} else if msg = strings.TrimSpace(msg); msg != "" {
+			mp.sendPrivateMessage(w, ctrl.Callsign, msg)
+		}
+		return
+	} else if mp.input.cmd[0] == '?' {
+		// Search the scrollback; "?" by itself ends the search.
+		mp.search = strings.TrimSpace(strings.ToUpper(mp.input.cmd[1:]))
 		mp.history = append(mp.history, mp.input)
 		mp.input = CLIInput{}
 		return
 	}
 
 	callsign, cmd, ok := strings.Cut(mp.input.cmd, " ")
-	mp.messages = append(mp.messages, Message{contents: "> " + mp.input.cmd})
+	mp.addMessage(Message{Contents: "> " + mp.input.cmd, System: true})
 	mp.history = append(mp.history, mp.input)
 	mp.input = CLIInput{}
 
-	if ok {
+	if strings.ToUpper(callsign) == "APREQ" {
+		mp.runCoordinationCommand(w, strings.ToUpper(cmd))
+	} else if strings.ToUpper(callsign) == "AMEND" {
+		// AMEND (callsign): open the route amendment dialog
+		if ac := w.GetAircraft(strings.TrimSpace(cmd), true /*abbreviated*/); ac == nil {
+			mp.addMessage(Message{Contents: cmd + ": no such aircraft", Error: true})
+		} else if ac.TrackingController != w.Callsign {
+			mp.addMessage(Message{Contents: ac.Callsign + ": not tracked by " + w.Callsign, Error: true})
+		} else {
+			uiShowModalDialog(NewModalDialogBox(&RouteAmendmentModalClient{w: w, callsign: ac.Callsign}), false)
+		}
+	} else if strings.ToUpper(callsign) == "REMIND" {
+		mp.runReminderCommand(w, cmd)
+	} else if strings.ToUpper(callsign) == "FIND" {
+		// FIND (fix): highlight the fix, navaid, or airport on the scope
+		if pos, ok := w.Locate(strings.TrimSpace(cmd)); ok {
+			HighlightLocation(pos, 5*time.Second)
+			mp.addMessage(Message{Contents: strings.TrimSpace(cmd) + ": " + formatLatLong(pos), System: true})
+		} else {
+			mp.addMessage(Message{Contents: cmd + ": no such fix", Error: true})
+		}
+	} else if ok {
 		if ac := w.GetAircraft(callsign, true /*abbreviated*/); ac != nil {
 			w.RunAircraftCommands(ac.Callsign, cmd, func(errorString string, remainingCommands string) {
 				if errorString != "" {
-					mp.messages = append(mp.messages, Message{contents: errorString, error: true})
+					mp.addMessage(Message{Contents: errorString, Error: true})
 				}
 				if remainingCommands != "" && mp.input.cmd == "" {
 					mp.input.cmd = callsign + " " + remainingCommands
@@ -1062,10 +1401,96 @@ func (mp *MessagesPane) runCommands(w *World) {
 				}
 			})
 		} else {
-			mp.messages = append(mp.messages, Message{contents: callsign + ": no such aircraft", error: true})
+			mp.addMessage(Message{Contents: callsign + ": no such aircraft", Error: true})
 		}
 	} else {
-		mp.messages = append(mp.messages, Message{contents: "invalid command: " + callsign, error: true})
+		mp.addMessage(Message{Contents: "invalid command: " + callsign, Error: true})
+	}
+}
+
+// runReminderCommand handles the REMIND command:
+//
+//	REMIND (callsign) (minutes)[:(seconds)] [note]: set a reminder
+//	REMIND (callsign): cancel the aircraft's reminders
+func (mp *MessagesPane) runReminderCommand(w *World, args string) {
+	f := strings.Fields(args)
+	if len(f) == 0 {
+		mp.addMessage(Message{Contents: "REMIND: callsign required", Error: true})
+		return
+	}
+	ac := w.GetAircraft(f[0], true /*abbreviated*/)
+	if ac == nil {
+		mp.addMessage(Message{Contents: f[0] + ": no such aircraft", Error: true})
+		return
+	}
+
+	if len(f) == 1 {
+		n := w.CancelReminders(ac.Callsign)
+		mp.addMessage(Message{Contents: fmt.Sprintf("%s: cancelled %d reminder(s)", ac.Callsign, n), System: true})
+		return
+	}
+
+	_, d, note, err := parseReminderCommand(args)
+	if err != nil {
+		mp.addMessage(Message{Contents: "REMIND: " + err.Error(), Error: true})
+		return
+	}
+	w.AddReminder(ac.Callsign, d, note)
+	mp.addMessage(Message{Contents: fmt.Sprintf("%s: reminder set for %s", ac.Callsign, formatDuration(d)), System: true})
+}
+
+// sendPrivateMessage sends the message to the given controller and adds it
+// to that controller's private channel, which is then selected.
+func (mp *MessagesPane) sendPrivateMessage(w *World, callsign string, msg string) {
+	w.SendGlobalMessage(GlobalMessage{
+		FromController: w.Callsign,
+		ToController:   callsign,
+		Message:        w.Callsign + ": " + msg,
+	})
+	mp.addMessage(Message{Contents: w.Callsign + ": " + msg, Channel: "@" + callsign, Global: true})
+	mp.channel = "@" + callsign
+}
+
+// startPrivateMessage selects the private channel for the given controller
+// and starts a message to them in the command input.
+func (mp *MessagesPane) startPrivateMessage(callsign string) {
+	mp.channel = "@" + callsign
+	mp.input = CLIInput{cmd: "@" + callsign + " "}
+	mp.input.cursor = len(mp.input.cmd)
+	wmTakeKeyboardFocus(mp, false)
+}
+
+// runCoordinationCommand handles the commands that follow "APREQ":
+//
+//	APREQ (controller) (request) FOR (callsign)
+//	APREQ ACCEPT (callsign)
+//	APREQ DENY (callsign)
+//
+// where the controller may be given by callsign or sector id.
+func (mp *MessagesPane) runCoordinationCommand(w *World, cmd string) {
+	reportError := func(err error) {
+		mp.addMessage(Message{Contents: err.Error(), Error: true})
+	}
+
+	f := strings.Fields(cmd)
+	if len(f) == 2 && (f[0] == "ACCEPT" || f[0] == "DENY") {
+		if ac := w.GetAircraft(f[1], true /*abbreviated*/); ac == nil {
+			reportError(ErrNoAircraftForCallsign)
+		} else if f[0] == "ACCEPT" {
+			w.AcceptCoordination(ac.Callsign, nil, reportError)
+		} else {
+			w.DenyCoordination(ac.Callsign, nil, reportError)
+		}
+	} else if n := len(f); n >= 4 && f[n-2] == "FOR" {
+		if ctrl := lookupCoordinationController(w, f[0]); ctrl == nil {
+			reportError(ErrNoController)
+		} else if ac := w.GetAircraft(f[n-1], true /*abbreviated*/); ac == nil {
+			reportError(ErrNoAircraftForCallsign)
+		} else {
+			w.RequestCoordination(ac.Callsign, ctrl.Callsign, strings.Join(f[1:n-2], " "), nil, reportError)
+		}
+	} else {
+		reportError(ErrInvalidCommandSyntax)
 	}
 }
 
@@ -1095,6 +1520,7 @@ func (ci *CLIInput) DeleteAfterCursor() {
 
 func (mp *MessagesPane) processEvents(w *World) {
 	lastRadioCallsign := ""
+	lastRadioController := ""
 	var lastRadioType RadioTransmissionType
 	var unexpectedTransmission bool
 	var transmissions []string
@@ -1122,57 +1548,76 @@ func (mp *MessagesPane) processEvents(w *World) {
 			}
 		}
 
+		ctrl := w.GetControllerByCallsign(lastRadioController)
+		if ctrl == nil {
+			return
+		}
+
 		response := strings.Join(transmissions, ", ")
 		var msg Message
 		if lastRadioType == RadioTransmissionContact {
-			ctrl := w.Controllers[w.Callsign]
 			fullName := ctrl.FullName
 			if ac := w.Aircraft[callsign]; ac != nil && ac.IsDeparture() {
 				// Always refer to the controller as "departure" for departing aircraft.
 				fullName = strings.ReplaceAll(fullName, "approach", "departure")
 			}
-			msg = Message{contents: fullName + ", " + radioCallsign + ", " + response}
+			msg = Message{Contents: fullName + ", " + radioCallsign + ", " + response}
 		} else {
 			if len(response) > 0 {
 				response = strings.ToUpper(response[:1]) + response[1:]
 			}
-			msg = Message{contents: response + ". " + radioCallsign, error: unexpectedTransmission}
+			msg = Message{Contents: response + ". " + radioCallsign, Error: unexpectedTransmission}
 		}
+		msg.Channel = ctrl.Frequency.String()
 		lg.Debug("radio_transmission", slog.String("callsign", callsign), slog.Any("message", msg))
-		mp.messages = append(mp.messages, msg)
+		mp.addMessage(msg)
 	}
 
 	for _, event := range mp.events.Get() {
 		switch event.Type {
 		case RadioTransmissionEvent:
-			if event.ToController == w.Callsign {
-				if event.Callsign != lastRadioCallsign || event.RadioTransmissionType != lastRadioType {
+			if event.ToController == w.Callsign || mp.MonitorOtherFrequencies {
+				if event.Callsign != lastRadioCallsign || event.RadioTransmissionType != lastRadioType ||
+					event.ToController != lastRadioController {
 					if len(transmissions) > 0 {
 						addTransmissions()
 						transmissions = nil
 						unexpectedTransmission = false
 					}
 					lastRadioCallsign = event.Callsign
+					lastRadioController = event.ToController
 					lastRadioType = event.RadioTransmissionType
 				}
 				transmissions = append(transmissions, event.Message)
 				unexpectedTransmission = unexpectedTransmission || (event.RadioTransmissionType == RadioTransmissionUnexpected)
 			}
+		case PseudoPilotInstructionEvent:
+			if event.ToController == w.Callsign {
+				mp.addMessage(Message{Contents: event.FromController + " to " + event.Callsign + ": " + event.Message,
+					Alert: true})
+			}
+		case CoordinationRequestEvent, AcceptedCoordinationEvent, DeniedCoordinationEvent:
+			if event.ToController == w.Callsign {
+				mp.addMessage(Message{Contents: coordinationEventMessage(event), System: true})
+			}
 		case GlobalMessageEvent:
-			if event.FromController != w.Callsign {
-				mp.messages = append(mp.messages, Message{contents: event.Message, global: true})
+			if event.FromController == w.Callsign {
+				// We added it when it was sent.
+			} else if event.ToController == "" {
+				mp.addMessage(Message{Contents: event.Message, Channel: MessageChannelGlobal, Global: true})
+			} else if event.ToController == w.Callsign {
+				mp.addMessage(Message{Contents: event.Message, Channel: "@" + event.FromController, Global: true})
 			}
 		case StatusMessageEvent:
 			// Don't spam the same message repeatedly; look in the most recent 5.
-			n := len(mp.messages)
+			n := len(mp.Messages)
 			start := max(0, n-5)
-			if !slices.ContainsFunc(mp.messages[start:],
-				func(m Message) bool { return m.contents == event.Message }) {
-				mp.messages = append(mp.messages,
-					Message{
-						contents: event.Message,
-						system:   true,
-					})
+			if !slices.ContainsFunc(mp.Messages[start:],
+				func(m Message) bool { return m.Contents == event.Message }) {
+				mp.addMessage(Message{
+					Contents: event.Message,
+					System:   true,
+				})
 			}
 
 		case TrackClickedEvent:
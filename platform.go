@@ -12,6 +12,7 @@ import (
 	"math"
 	"runtime"
 	"strconv"
+	"time"
 
 	"github.com/go-gl/gl/v2.1/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
@@ -26,6 +27,9 @@ type Platform interface {
 	// ProcessEvents handles all pending window events. Returns true if
 	// there were any events and false otherwise.
 	ProcessEvents() bool
+	// WaitEvents is like ProcessEvents, but if there are no pending
+	// events, it waits up to the specified amount of time for one.
+	WaitEvents(timeout time.Duration) bool
 	// PostRender performs the buffer swap.
 	PostRender()
 	// Dispose is called when the application is shutting down and is when
@@ -67,6 +71,10 @@ type Platform interface {
 	EndCaptureMouse()
 	// Scaling factor to account for Retina-style displays
 	DPIScale() float32
+	// DPIScaleChanged returns true if the DPI scale has changed (e.g.,
+	// due to the window being moved to another monitor) since the last
+	// time it was called.
+	DPIScaleChanged() bool
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -87,6 +95,15 @@ type GLFWPlatform struct {
 	multisample            bool
 	windowTitle            string
 	mouseCapture           Extent2D
+	dpiScaleChanged        bool
+
+	// When running headless, the window is never shown and the contents
+	// of its default framebuffer are undefined (pixels that fail the
+	// pixel ownership test needn't be rendered), so everything is drawn
+	// into an offscreen framebuffer instead.
+	offscreenFramebuffer  uint32
+	offscreenRenderbuffer uint32
+	offscreenSize         [2]int
 }
 
 // NewGLFWPlatform returns a new instance of a GLFWPlatform with a window
@@ -145,7 +162,9 @@ func NewGLFWPlatform(io imgui.IO, windowSize [2]int, windowPosition [2]int, mult
 		return nil, fmt.Errorf("failed to create window: %w", err)
 	}
 	window.SetPos(windowPosition[0], windowPosition[1])
-	window.Show()
+	if !*headless {
+		window.Show()
+	}
 	window.MakeContextCurrent()
 
 	platform := &GLFWPlatform{
@@ -159,6 +178,10 @@ func NewGLFWPlatform(io imgui.IO, windowSize [2]int, windowPosition [2]int, mult
 	platform.EnableVSync(true)
 
 	glfw.SetMonitorCallback(platform.MonitorCallback)
+	window.SetContentScaleCallback(func(w *glfw.Window, x, y float32) {
+		lg.Infof("Window content scale changed to %f, %f", x, y)
+		platform.dpiScaleChanged = true
+	})
 
 	lg.Info("Finished GLFW initialization")
 	return platform, nil
@@ -167,12 +190,30 @@ func NewGLFWPlatform(io imgui.IO, windowSize [2]int, windowPosition [2]int, mult
 func (g *GLFWPlatform) DPIScale() float32 {
 	if runtime.GOOS == "windows" {
 		sx, sy := g.window.GetContentScale()
-		return float32(int((sx + sy) / 2))
+		return (sx + sy) / 2
 	} else {
 		return g.FramebufferSize()[0] / g.DisplaySize()[0]
 	}
 }
 
+func (g *GLFWPlatform) DPIScaleChanged() bool {
+	changed := g.dpiScaleChanged
+	g.dpiScaleChanged = false
+	return changed
+}
+
+// UIScale returns the factor by which sizes that are specified in pixels
+// (point sizes, offsets, hit-testing distances) should be scaled so that
+// they have a consistent physical size. Window coordinates on Macs are
+// already in points, but on Windows they're in pixels, so they must be
+// scaled by the monitor's DPI scale.
+func UIScale(p Platform) float32 {
+	if runtime.GOOS == "windows" {
+		return p.DPIScale()
+	}
+	return 1
+}
+
 func (g *GLFWPlatform) EnableVSync(sync bool) {
 	if sync {
 		glfw.SwapInterval(1)
@@ -233,10 +274,18 @@ func (g *GLFWPlatform) CancelShouldStop() {
 }
 
 func (g *GLFWPlatform) ProcessEvents() bool {
+	return g.WaitEvents(0)
+}
+
+func (g *GLFWPlatform) WaitEvents(timeout time.Duration) bool {
 	g.inputCharacters = ""
 	g.anyEvents = false
 
-	glfw.PollEvents()
+	if timeout > 0 {
+		glfw.WaitEventsTimeout(timeout.Seconds())
+	} else {
+		glfw.PollEvents()
+	}
 
 	if g.anyEvents {
 		return true
@@ -281,6 +330,9 @@ func (g *GLFWPlatform) NewFrame() {
 	if g.multisample {
 		gl.Enable(gl.MULTISAMPLE)
 	}
+	if *headless {
+		g.bindOffscreenFramebuffer()
+	}
 
 	// Setup display size (every frame to accommodate for window resizing)
 	displaySize := g.DisplaySize()
@@ -347,6 +399,28 @@ func (g *GLFWPlatform) PostRender() {
 	g.window.SwapBuffers()
 }
 
+// bindOffscreenFramebuffer binds the offscreen framebuffer that is used
+// when running headless, (re)allocating its storage if the window's
+// framebuffer size has changed.
+func (g *GLFWPlatform) bindOffscreenFramebuffer() {
+	if g.offscreenFramebuffer == 0 {
+		gl.GenFramebuffers(1, &g.offscreenFramebuffer)
+		gl.GenRenderbuffers(1, &g.offscreenRenderbuffer)
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, g.offscreenFramebuffer)
+
+	w, h := g.window.GetFramebufferSize()
+	if size := [2]int{w, h}; size != g.offscreenSize {
+		g.offscreenSize = size
+		gl.BindRenderbuffer(gl.RENDERBUFFER, g.offscreenRenderbuffer)
+		gl.RenderbufferStorage(gl.RENDERBUFFER, gl.RGBA8, int32(w), int32(h))
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, g.offscreenRenderbuffer)
+		if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+			lg.Errorf("offscreen framebuffer incomplete: status 0x%x", status)
+		}
+	}
+}
+
 func (g *GLFWPlatform) setKeyMapping() {
 	// Keyboard mapping. ImGui will use those indices to peek into the io.KeysDown[] array.
 	g.imguiIO.KeyMap(imgui.KeyTab, int(glfw.KeyTab))
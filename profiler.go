@@ -0,0 +1,147 @@
+// profiler.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// The profiler overlay shows graphs of where time is going over the last
+// few seconds so that users can see (and report) what's happening when
+// the frame rate drops. It's only available when vice is run with
+// -devmode; no measurements are taken unless it's being shown.
+
+// Number of samples that are shown in each graph.
+const ProfilerHistoryLength = 300
+
+type Profiler struct {
+	frame, update, drawPanes, drawUI *RingBuffer[float32] // ms
+	paneTimes                        map[Pane]*RingBuffer[float32]
+	paneBytes                        map[Pane]int // command buffer size for the last frame
+	commandBufferBytes               *RingBuffer[float32]
+	drawCalls                        *RingBuffer[float32]
+	gcPauses                         *RingBuffer[float32] // ms per frame
+	networkLatency                   *RingBuffer[float32] // ms per world update
+
+	lastNumGC int64
+	gcStats   debug.GCStats
+}
+
+var profiler *Profiler
+
+func newProfilerHistory() *RingBuffer[float32] {
+	return NewRingBuffer[float32](ProfilerHistoryLength)
+}
+
+func NewProfiler() *Profiler {
+	p := &Profiler{
+		frame:              newProfilerHistory(),
+		update:             newProfilerHistory(),
+		drawPanes:          newProfilerHistory(),
+		drawUI:             newProfilerHistory(),
+		paneTimes:          make(map[Pane]*RingBuffer[float32]),
+		paneBytes:          make(map[Pane]int),
+		commandBufferBytes: newProfilerHistory(),
+		drawCalls:          newProfilerHistory(),
+		gcPauses:           newProfilerHistory(),
+		networkLatency:     newProfilerHistory(),
+	}
+	debug.ReadGCStats(&p.gcStats)
+	p.lastNumGC = p.gcStats.NumGC
+	return p
+}
+
+// profilerEnabled indicates whether measurements should be recorded.
+func profilerEnabled() bool {
+	return profiler != nil && ui.showProfiler
+}
+
+func ms(d time.Duration) float32 { return float32(d.Microseconds()) / 1000 }
+
+// RecordPane records the time spent in a pane's Draw method and the
+// number of bytes of drawing commands it generated.
+func (p *Profiler) RecordPane(pane Pane, d time.Duration, bytes int) {
+	rb, ok := p.paneTimes[pane]
+	if !ok {
+		rb = newProfilerHistory()
+		p.paneTimes[pane] = rb
+	}
+	rb.Add(ms(d))
+	p.paneBytes[pane] = bytes
+}
+
+func (p *Profiler) RecordNetworkLatency(d time.Duration) {
+	p.networkLatency.Add(ms(d))
+}
+
+// RecordFrame should be called at the end of each frame with its Stats.
+func (p *Profiler) RecordFrame(stats *Stats) {
+	p.frame.Add(ms(stats.update + stats.drawPanes + stats.drawImgui))
+	p.update.Add(ms(stats.update))
+	p.drawPanes.Add(ms(stats.drawPanes))
+	p.drawUI.Add(ms(stats.drawImgui))
+	p.commandBufferBytes.Add(float32(stats.render.bufferBytes+stats.renderUI.bufferBytes) / 1024)
+	p.drawCalls.Add(float32(stats.render.nDrawCalls + stats.renderUI.nDrawCalls))
+
+	// Sum the pauses for the collections since the last frame; Pause
+	// holds the most recent ones first.
+	debug.ReadGCStats(&p.gcStats)
+	var pause time.Duration
+	for i := 0; i < int(p.gcStats.NumGC-p.lastNumGC) && i < len(p.gcStats.Pause); i++ {
+		pause += p.gcStats.Pause[i]
+	}
+	p.lastNumGC = p.gcStats.NumGC
+	p.gcPauses.Add(ms(pause))
+
+	// Forget about panes that have been removed.
+	for pane := range p.paneTimes {
+		if !wmPaneIsPresent(pane, globalConfig.DisplayRoot) {
+			delete(p.paneTimes, pane)
+			delete(p.paneBytes, pane)
+		}
+	}
+}
+
+func (p *Profiler) Draw() {
+	imgui.BeginV("Profiler", &ui.showProfiler, imgui.WindowFlagsAlwaysAutoResize|imgui.WindowFlagsNoSavedSettings)
+
+	graph := func(label string, rb *RingBuffer[float32], units string) {
+		values := make([]float32, rb.Size())
+		var maxValue float32
+		for i := range values {
+			values[i] = rb.Get(i)
+			maxValue = max(maxValue, values[i])
+		}
+		overlay := ""
+		if len(values) > 0 {
+			overlay = fmt.Sprintf("%.2f %s (max %.2f)", values[len(values)-1], units, maxValue)
+		}
+		imgui.PlotLinesV(label, values, 0, overlay, 0, max(maxValue, 1), imgui.Vec2{X: 300, Y: 40})
+	}
+
+	graph("Frame", p.frame, "ms")
+	graph("Update/events", p.update, "ms")
+	graph("Draw panes", p.drawPanes, "ms")
+	graph("Draw UI", p.drawUI, "ms")
+
+	if imgui.CollapsingHeader("Panes") {
+		globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+			if rb, ok := p.paneTimes[pane]; ok {
+				graph(fmt.Sprintf("%s (%d KB)##%p", pane.Name(), p.paneBytes[pane]/1024, pane), rb, "ms")
+			}
+		})
+	}
+
+	graph("Command buffers", p.commandBufferBytes, "KB")
+	graph("Draw calls", p.drawCalls, "")
+	graph("GC pauses", p.gcPauses, "ms")
+	graph("Network latency", p.networkLatency, "ms")
+
+	imgui.End()
+}
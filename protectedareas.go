@@ -0,0 +1,233 @@
+// protectedareas.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Each active arrival runway has two protected areas: the ILS critical
+// area beyond the departure end of the runway, where the localizer
+// antenna is, and the missed approach protected airspace, which extends
+// from the threshold past the departure end and widens with distance.
+// When an arrival is on final within the alert distance of the runway,
+// other aircraft that are inside either area or are predicted to enter
+// one within a minute are alerted.
+
+// Defaults for ProtectedAreaSettings' fields that are zero.
+const (
+	DefaultCriticalAreaLength   = 2000 // ft
+	DefaultCriticalAreaWidth    = 800  // ft
+	DefaultMissedApproachLength = 5    // nm beyond the departure end
+	DefaultMissedApproachWidth  = 2    // nm at the far end
+	DefaultProtectedAreaCeiling = 3000 // ft above the runway
+	DefaultProtectedAreaAlert   = 6    // nm
+)
+
+// Half the width of the missed approach area at the runway threshold.
+const missedApproachThresholdHalfWidth = 0.25 // nm
+
+type ProtectedAreaSettings struct {
+	Show   bool
+	Alerts bool
+
+	CriticalAreaLength, CriticalAreaWidth     int     // ft
+	MissedApproachLength, MissedApproachWidth float32 // nm
+	Ceiling                                   int     // ft above the runway
+	// Alerts are issued when there is an arrival on final within this
+	// many nm of the threshold.
+	AlertDistance float32
+}
+
+type RunwayProtectedAreas struct {
+	Airport, Runway string
+	Elevation       int
+	CriticalArea    [4]Point2LL
+	MissedApproach  [4]Point2LL
+}
+
+func (s *ProtectedAreaSettings) get(v, def float32) float32 {
+	return Select(v > 0, v, def)
+}
+
+// Areas returns the protected areas for the given runway; false is
+// returned if the runway's opposite end isn't known.
+func (s *ProtectedAreaSettings) Areas(icao, id string, nmPerLongitude, magneticVariation float32) (RunwayProtectedAreas, bool) {
+	rwy, ok := LookupRunway(icao, id)
+	if !ok {
+		return RunwayProtectedAreas{}, false
+	}
+	opp, ok := LookupOppositeRunway(icao, id)
+	if !ok {
+		return RunwayProtectedAreas{}, false
+	}
+
+	p0, p1 := ll2nm(rwy.Threshold, nmPerLongitude), ll2nm(opp.Threshold, nmPerLongitude)
+	hdg := rwy.Heading - magneticVariation
+	v := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
+	vp := [2]float32{-v[1], v[0]} // perp
+
+	quad := func(a [2]float32, wa float32, b [2]float32, wb float32) [4]Point2LL {
+		return [4]Point2LL{
+			nm2ll(add2f(a, scale2f(vp, -wa)), nmPerLongitude), nm2ll(add2f(b, scale2f(vp, -wb)), nmPerLongitude),
+			nm2ll(add2f(b, scale2f(vp, wb)), nmPerLongitude), nm2ll(add2f(a, scale2f(vp, wa)), nmPerLongitude)}
+	}
+
+	length := s.get(float32(s.CriticalAreaLength), DefaultCriticalAreaLength) / NauticalMilesToFeet
+	width := s.get(float32(s.CriticalAreaWidth), DefaultCriticalAreaWidth) / NauticalMilesToFeet
+	maLength := s.get(s.MissedApproachLength, DefaultMissedApproachLength)
+	maWidth := s.get(s.MissedApproachWidth, DefaultMissedApproachWidth)
+
+	return RunwayProtectedAreas{
+		Airport:        icao,
+		Runway:         id,
+		Elevation:      rwy.Elevation,
+		CriticalArea:   quad(p1, width/2, add2f(p1, scale2f(v, length)), width/2),
+		MissedApproach: quad(p0, missedApproachThresholdHalfWidth, add2f(p1, scale2f(v, maLength)), maWidth/2),
+	}, true
+}
+
+// Inside reports whether the given point is inside either of the
+// protected areas.
+func (s *ProtectedAreaSettings) Inside(pa RunwayProtectedAreas, p Point2LL, alt int) bool {
+	if alt > pa.Elevation+int(s.get(float32(s.Ceiling), DefaultProtectedAreaCeiling)) {
+		return false
+	}
+	return PointInPolygon2LL(p, pa.CriticalArea[:]) || PointInPolygon2LL(p, pa.MissedApproach[:])
+}
+
+// activeProtectedAreas returns the protected areas for all of the active
+// arrival runways.
+func (sp *STARSPane) activeProtectedAreas(w *World) []RunwayProtectedAreas {
+	var areas []RunwayProtectedAreas
+	rc := w.RunwayConfiguration()
+	for _, icao := range SortedMapKeys(rc.Airports) {
+		for _, id := range rc.Airports[icao].ArrivalRunways {
+			if pa, ok := sp.ProtectedAreas.Areas(icao, id, w.NmPerLongitude, w.MagneticVariation); ok {
+				areas = append(areas, pa)
+			}
+		}
+	}
+	return areas
+}
+
+// updateProtectedAreaAlerts should be called after the aircraft's final
+// approach status has been updated; see updateGlidepathDeviations.
+func (sp *STARSPane) updateProtectedAreaAlerts(aircraft []*Aircraft, w *World) {
+	for _, ac := range aircraft {
+		sp.Aircraft[ac.Callsign].ProtectedAreaAlert = ""
+	}
+	sp.protectedAreaAlerts = nil
+	if !sp.ProtectedAreas.Alerts {
+		return
+	}
+
+	alertDistance := sp.ProtectedAreas.get(sp.ProtectedAreas.AlertDistance, DefaultProtectedAreaAlert)
+	for _, pa := range sp.activeProtectedAreas(w) {
+		rwy, ok := LookupRunway(pa.Airport, pa.Runway)
+		if !ok {
+			continue
+		}
+
+		// Is there an arrival close in on final?
+		onFinal := func(ac *Aircraft) bool {
+			state := sp.Aircraft[ac.Callsign]
+			vol := ac.ATPAVolume()
+			return state.OnFinal && vol != nil && vol.Id == pa.Airport+pa.Runway
+		}
+		if !slices.ContainsFunc(aircraft, func(ac *Aircraft) bool {
+			return onFinal(ac) &&
+				nmdistance2ll(sp.Aircraft[ac.Callsign].TrackPosition(), rwy.Threshold) <= alertDistance
+		}) {
+			continue
+		}
+
+		for _, ac := range aircraft {
+			state := sp.Aircraft[ac.Callsign]
+			if onFinal(ac) || !state.HaveHeading() || state.ProtectedAreaAlert != "" {
+				continue
+			}
+
+			// Check the current position and where the aircraft's vector
+			// will take it over the next minute.
+			hv := state.HeadingVector(w.NmPerLongitude, w.MagneticVariation)
+			for i := 0; i <= 4; i++ {
+				p := add2ll(state.TrackPosition(), Point2LL(scale2f(hv, float32(i)/4)))
+				if sp.ProtectedAreas.Inside(pa, p, state.TrackAltitude()) {
+					state.ProtectedAreaAlert = pa.Airport + " " + pa.Runway
+					if !slices.Contains(sp.protectedAreaAlerts, state.ProtectedAreaAlert) {
+						sp.protectedAreaAlerts = append(sp.protectedAreaAlerts, state.ProtectedAreaAlert)
+					}
+					break
+				}
+			}
+		}
+	}
+
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		if state.ProtectedAreaAlert != "" && !state.protectedAreaAlerted && ac.TrackingController == w.Callsign {
+			globalConfig.Audio.PlayOnce(AudioConflictAlert)
+		}
+		state.protectedAreaAlerted = state.ProtectedAreaAlert != ""
+	}
+}
+
+// drawProtectedAreas outlines the protected areas of the active arrival
+// runways; runways with alerts are drawn in the alert color.
+func (sp *STARSPane) drawProtectedAreas(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if !sp.ProtectedAreas.Show {
+		return
+	}
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	ps := sp.CurrentPreferenceSet
+	for _, pa := range sp.activeProtectedAreas(ctx.world) {
+		color := STARSMapColor
+		if slices.Contains(sp.protectedAreaAlerts, pa.Airport+" "+pa.Runway) {
+			color = STARSTextAlertColor
+		}
+		color = ps.Brightness.VideoGroupA.ScaleRGB(color)
+
+		for _, quad := range [][4]Point2LL{pa.CriticalArea, pa.MissedApproach} {
+			for i := range quad {
+				ld.AddLine(quad[i], quad[(i+1)%len(quad)], color)
+			}
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+}
+
+func (sp *STARSPane) drawProtectedAreasUI() {
+	s := &sp.ProtectedAreas
+	imgui.Checkbox("Show ILS critical and missed approach areas for arrival runways", &s.Show)
+	imgui.Checkbox("Alert for aircraft entering the areas while an arrival is on final", &s.Alerts)
+
+	intSetting := func(label string, v *int, def int, lo, hi int32, format string) {
+		i := int32(Select(*v > 0, *v, def))
+		if imgui.SliderIntV(label, &i, lo, hi, format, 0) {
+			*v = int(i)
+		}
+	}
+	floatSetting := func(label string, v *float32, def float32, lo, hi float32) {
+		f := s.get(*v, def)
+		if imgui.SliderFloatV(label, &f, lo, hi, "%.1f nm", 0) {
+			*v = f
+		}
+	}
+	intSetting("Critical area length", &s.CriticalAreaLength, DefaultCriticalAreaLength, 500, 5000, "%d ft")
+	intSetting("Critical area width", &s.CriticalAreaWidth, DefaultCriticalAreaWidth, 200, 2000, "%d ft")
+	floatSetting("Missed approach length", &s.MissedApproachLength, DefaultMissedApproachLength, 1, 10)
+	floatSetting("Missed approach width", &s.MissedApproachWidth, DefaultMissedApproachWidth, 0.5, 5)
+	intSetting("Protected ceiling", &s.Ceiling, DefaultProtectedAreaCeiling, 1000, 10000, "%d ft")
+	floatSetting("Alert when an arrival is within", &s.AlertDistance, DefaultProtectedAreaAlert, 1, 15)
+}
@@ -0,0 +1,136 @@
+// pseudopilot.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Pseudo-pilots are users who join a multi-controller sim to fly some of
+// its aircraft rather than to control them, as in a sweatbox training
+// session. Instructions that controllers issue to those aircraft are
+// relayed to their pseudo-pilot, who then enters the corresponding
+// commands; the aircraft's readbacks go to the controlling controller as
+// usual.
+
+// PseudoPilotPosition is the position to select when joining a sim as a
+// pseudo-pilot; pseudo-pilots are then given callsigns starting with
+// PseudoPilotCallsignPrefix.
+const (
+	PseudoPilotPosition       = "Pseudo-pilot"
+	PseudoPilotCallsignPrefix = "PILOT"
+)
+
+func isPseudoPilot(callsign string) bool {
+	return strings.HasPrefix(callsign, PseudoPilotCallsignPrefix)
+}
+
+// pseudoPilotCallsign returns an unused callsign for a new pseudo-pilot.
+// Assumes the lock is already held.
+func (s *Sim) pseudoPilotCallsign() string {
+	for i := 1; ; i++ {
+		if callsign := fmt.Sprintf("%s%d", PseudoPilotCallsignPrefix, i); !s.controllerIsSignedIn(callsign) {
+			return callsign
+		}
+	}
+}
+
+// assignPseudoPilot gives the aircraft to the signed-in pseudo-pilot who
+// is flying the fewest aircraft. If there are no pseudo-pilots, the sim
+// continues to fly it. Assumes the lock is already held.
+func (s *Sim) assignPseudoPilot(ac *Aircraft) {
+	counts := make(map[string]int)
+	for _, sc := range s.controllers {
+		if isPseudoPilot(sc.Callsign) {
+			counts[sc.Callsign] = 0
+		}
+	}
+	if len(counts) == 0 {
+		ac.PseudoPilot = ""
+		return
+	}
+
+	for _, other := range s.World.Aircraft {
+		if _, ok := counts[other.PseudoPilot]; ok {
+			counts[other.PseudoPilot]++
+		}
+	}
+
+	ac.PseudoPilot = ""
+	for _, pilot := range SortedMapKeys(counts) {
+		if ac.PseudoPilot == "" || counts[pilot] < counts[ac.PseudoPilot] {
+			ac.PseudoPilot = pilot
+		}
+	}
+	s.lg.Info("assigned pseudo-pilot", slog.String("callsign", ac.Callsign),
+		slog.String("pilot", ac.PseudoPilot))
+}
+
+// releasePseudoPilotAircraft reassigns the aircraft flown by a
+// pseudo-pilot who has signed off. Assumes the lock is already held.
+func (s *Sim) releasePseudoPilotAircraft(pilot string) {
+	for _, ac := range s.World.Aircraft {
+		if ac.PseudoPilot == pilot {
+			s.assignPseudoPilot(ac)
+		}
+	}
+}
+
+// dispatchPseudoPilotCommand runs a command that a pseudo-pilot has
+// entered for one of their aircraft. Assumes the lock is already held.
+func (s *Sim) dispatchPseudoPilotCommand(pilot string, callsign string,
+	cmd func(*Controller, *Aircraft) []RadioTransmission) error {
+	ac, ok := s.World.Aircraft[callsign]
+	if !ok {
+		return ErrNoAircraftForCallsign
+	} else if ac.PseudoPilot != pilot {
+		return ErrOtherControllerHasTrack
+	}
+
+	// The command is run as if the controlling controller had issued it
+	// so that the readback goes to them.
+	ctrl := s.World.GetControllerByCallsign(ac.ControllingController)
+	if ctrl == nil {
+		return ErrNoController
+	}
+
+	radioTransmissions := cmd(ctrl, ac)
+	s.lg.Info("pseudo-pilot command", slog.String("callsign", ac.Callsign),
+		slog.String("pilot", pilot), slog.Any("radio_transmissions", radioTransmissions))
+	if !ac.NORDO {
+		PostRadioEvents(ac.Callsign, radioTransmissions, s)
+	}
+	return nil
+}
+
+// RelayToPseudoPilot checks whether the given aircraft commands from a
+// controller are for an aircraft that is flown by a pseudo-pilot. If so,
+// they are passed along to the pseudo-pilot and true is returned.
+func (s *Sim) RelayToPseudoPilot(token, callsign, commands string) bool {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	sc, ok := s.controllers[token]
+	if !ok || isPseudoPilot(sc.Callsign) {
+		return false
+	}
+	ac, ok := s.World.Aircraft[callsign]
+	if !ok || ac.PseudoPilot == "" || ac.ControllingController != sc.Callsign {
+		return false
+	}
+
+	if !ac.NORDO {
+		s.eventStream.Post(Event{
+			Type:           PseudoPilotInstructionEvent,
+			Callsign:       ac.Callsign,
+			FromController: sc.Callsign,
+			ToController:   ac.PseudoPilot,
+			Message:        commands,
+		})
+	}
+	return true
+}
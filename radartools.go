@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"time"
 )
 
@@ -29,18 +30,44 @@ type WeatherRadar struct {
 
 	// Radar images are fetched and processed in a separate goroutine;
 	// updated radar center locations are sent from the main thread via
-	// reqChan and command buffers to draw each of the 6 weather levels are
-	// returned by cbChan.
-	reqChan chan Point2LL
-	cbChan  chan [NumWxLevels]CommandBuffer
+	// reqChan and command buffers to draw each of the 6 weather levels
+	// are returned by stripChan, one strip of the image at a time.
+	reqChan   chan Point2LL
+	stripChan chan WeatherStrip
 
 	// Texture id for each wx level's image.
 	texId [NumWxLevels]uint32
-	wxCb  [NumWxLevels]CommandBuffer
+	wxCb  [NumWxStrips][NumWxLevels]CommandBuffer
+
+	// The most recently requested center and the center and fetch time
+	// of the image currently displayed; used to determine whether the
+	// image is stale.
+	center      Point2LL
+	imageCenter Point2LL
+	lastUpdate  time.Time
+}
+
+// WeatherStrip stores the command buffers for one horizontal strip of a
+// weather radar image.
+type WeatherStrip struct {
+	Index   int
+	Center  Point2LL // center of the image the strip is from
+	Fetched time.Time
+	Cb      [NumWxLevels]CommandBuffer
 }
 
 const NumWxLevels = 6
 
+// Radar images are converted in this many horizontal strips, each of which
+// is sent to the main thread as soon as it's ready so that new images are
+// displayed progressively rather than after the whole image has been
+// processed.
+const NumWxStrips = 8
+
+// The weather image is considered stale if it hasn't been updated in this
+// long (e.g., due to network errors).
+const WxStaleTime = 5 * time.Minute
+
 // Block size in pixels of the quads in the converted radar image used for
 // display.
 const WxBlockRes = 4
@@ -54,14 +81,17 @@ const WxLatLongExtent = 2.5
 // latitude-longitude coordinates.
 func (w *WeatherRadar) Activate(center Point2LL, r Renderer) {
 	if w.active {
-		w.reqChan <- center
+		// Activate may be called every frame, so don't block if the
+		// fetching goroutine hasn't caught up.
+		w.UpdateCenter(center)
 		return
 	}
 	w.active = true
 
+	w.center = center
 	w.reqChan = make(chan Point2LL, 1000) // lots of buffering
 	w.reqChan <- center
-	w.cbChan = make(chan [NumWxLevels]CommandBuffer, 8)
+	w.stripChan = make(chan WeatherStrip, 2*NumWxStrips)
 
 	if w.texId[0] == 0 {
 		// Create a small texture for each weather level
@@ -99,7 +129,7 @@ func (w *WeatherRadar) Activate(center Point2LL, r Renderer) {
 		}
 	}
 
-	go fetchWeather(w.reqChan, w.cbChan)
+	go fetchWeather(w.reqChan, w.stripChan)
 }
 
 // Deactivate causes the WeatherRadar to stop fetching weather updates.
@@ -113,6 +143,7 @@ func (w *WeatherRadar) Deactivate() {
 // UpdateCenter provides a new center point for the radar image, causing a
 // new image to be fetched.
 func (w *WeatherRadar) UpdateCenter(center Point2LL) {
+	w.center = center
 	select {
 	case w.reqChan <- center:
 		// success
@@ -123,6 +154,18 @@ func (w *WeatherRadar) UpdateCenter(center Point2LL) {
 	}
 }
 
+// Stale returns true if weather is being displayed but the current image is
+// either out of date or was fetched for a center point far from the
+// current one.
+func (w *WeatherRadar) Stale() bool {
+	if !w.active || w.lastUpdate.IsZero() {
+		return false
+	}
+	d := sub2ll(w.center, w.imageCenter)
+	return time.Since(w.lastUpdate) > WxStaleTime ||
+		abs(d[0]) > WxLatLongExtent/2 || abs(d[1]) > WxLatLongExtent/2
+}
+
 // A single scanline of this color map, converted to RGB bytes:
 // https://opengeo.ncep.noaa.gov/geoserver/styles/reflectivity.png
 //
@@ -268,9 +311,9 @@ func invertRadarReflectivity(rgb [3]byte) float32 {
 
 // fetchWeather runs asynchronously in a goroutine, receiving requests from
 // reqChan, fetching corresponding radar images from the NOAA, and sending
-// the results back on cbChan.  New images are also automatically
+// the results back on stripChan.  New images are also automatically
 // fetched periodically, with a wait time specified by the delay parameter.
-func fetchWeather(reqChan chan Point2LL, cbChan chan [NumWxLevels]CommandBuffer) {
+func fetchWeather(reqChan chan Point2LL, stripChan chan WeatherStrip) {
 	// NOAA posts new maps every 2 minutes, so fetch a new map at minimum
 	// every 100s to stay current.
 	fetchRate := 100 * time.Second
@@ -290,7 +333,7 @@ func fetchWeather(reqChan chan Point2LL, cbChan chan [NumWxLevels]CommandBuffer)
 				}
 			} else {
 				// The channel is closed; wrap up.
-				close(cbChan)
+				close(stripChan)
 				return
 			}
 		case <-time.After(fetchRate):
@@ -335,22 +378,30 @@ func fetchWeather(reqChan chan Point2LL, cbChan chan [NumWxLevels]CommandBuffer)
 			lg.Infof("Weather error: %s", err)
 			continue
 		}
-		defer resp.Body.Close()
 
 		img, err := png.Decode(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			lg.Infof("Weather error: %s", err)
 			continue
 		}
 
-		// Send the command buffers back to the main thread.
-		cbChan <- makeWeatherCommandBuffers(img, rb)
+		// Send the command buffers back to the main thread as each strip
+		// is finished.
+		fetched := time.Now()
+		makeWeatherCommandBuffers(img, rb, func(index int, cb [NumWxLevels]CommandBuffer) {
+			stripChan <- WeatherStrip{Index: index, Center: center, Fetched: fetched, Cb: cb}
+		})
 
 		lg.Info("finish weather fetch")
 	}
 }
 
-func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]CommandBuffer {
+// makeWeatherCommandBuffers converts the radar image to command buffers
+// for each weather level. The image is processed in NumWxStrips strips and
+// the provided callback is called with the command buffers for each one
+// as it is completed.
+func makeWeatherCommandBuffers(img image.Image, rb Extent2D, strip func(int, [NumWxLevels]CommandBuffer)) {
 	// Convert the Image returned by png.Decode to a simple 8-bit RGBA image.
 	rgba := image.NewRGBA(img.Bounds())
 	draw.Draw(rgba, img.Bounds(), img, image.Point{}, draw.Over)
@@ -358,14 +409,25 @@ func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]Comman
 	ny, nx := img.Bounds().Dy(), img.Bounds().Dx()
 	if ny%WxBlockRes != 0 || nx%WxBlockRes != 0 {
 		lg.Errorf("invalid weather image resolution; must be multiple of WxBlockRes")
-		return [NumWxLevels]CommandBuffer{}
+		return
 	}
 	nby, nbx := ny/WxBlockRes, nx/WxBlockRes
 
-	// First determine the weather level for each WxBlockRes*WxBlockRes
-	// block of the image.
+	tb := GetTexturedTrianglesDrawBuilder()
+	defer ReturnTexturedTrianglesDrawBuilder(tb)
+
 	levels := make([]int, nbx*nby)
-	for y := 0; y < nby; y++ {
+	for s := 0; s < NumWxStrips; s++ {
+		y0, y1 := s*nby/NumWxStrips, (s+1)*nby/NumWxStrips
+		computeWeatherLevels(rgba, levels, nbx, y0, y1)
+		strip(s, generateWeatherCommandBuffers(levels, nbx, nby, y0, y1, rb, tb))
+	}
+}
+
+// computeWeatherLevels determines the weather level for each
+// WxBlockRes*WxBlockRes block of the image in the block rows [y0,y1).
+func computeWeatherLevels(rgba *image.RGBA, levels []int, nbx, y0, y1 int) {
+	for y := y0; y < y1; y++ {
 		for x := 0; x < nbx; x++ {
 			avg := float32(0)
 			for dy := 0; dy < WxBlockRes; dy++ {
@@ -380,14 +442,15 @@ func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]Comman
 			levels[x+y*nbx] = level
 		}
 	}
+}
 
-	// Now generate the command buffer for each weather level.  We don't
-	// draw anything for level==0, so the indexing into cb is off by 1
-	// below.
+// generateWeatherCommandBuffers generates the command buffer for each
+// weather level for the block rows [y0,y1).
+func generateWeatherCommandBuffers(levels []int, nbx, nby, y0, y1 int, rb Extent2D,
+	tb *TexturedTrianglesDrawBuilder) [NumWxLevels]CommandBuffer {
+	// We don't draw anything for level==0, so the indexing into cb is off
+	// by 1 below.
 	var cb [NumWxLevels]CommandBuffer
-	tb := GetTexturedTrianglesDrawBuilder()
-	defer ReturnTexturedTrianglesDrawBuilder(tb)
-
 	for level := 1; level <= NumWxLevels; level++ {
 		tb.Reset()
 
@@ -396,7 +459,7 @@ func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]Comman
 		// to make this too complicated... So we'll consider block
 		// scanlines and quads across neighbors that are the same level
 		// when we find them.
-		for y := 0; y < nby; y++ {
+		for y := y0; y < y1; y++ {
 			for x := 0; x < nbx; x++ {
 				// Skip ahead until we reach a block at the level we currently care about.
 				if levels[x+y*nbx] != level {
@@ -437,23 +500,34 @@ func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]Comman
 // available, it returns rather than stalling waiting for it).
 func (w *WeatherRadar) Draw(ctx *PaneContext, intensity float32, contrast float32,
 	active [NumWxLevels]bool, transforms ScopeTransformations, cb *CommandBuffer) {
-	select {
-	case w.wxCb = <-w.cbChan:
-		// got updated command buffers, yaay.  Note that we always go ahead
-		// and drain the cbChan, even if if the WeatherRadar is inactive.
+	// Take all of the strips that are ready, but don't wait for any more.
+	// Note that we always go ahead and drain the stripChan, even if the
+	// WeatherRadar is inactive.
+	for stop := false; !stop; {
+		select {
+		case strip, ok := <-w.stripChan:
+			if ok {
+				w.wxCb[strip.Index] = strip.Cb
+				w.imageCenter, w.lastUpdate = strip.Center, strip.Fetched
+			} else {
+				stop = true
+			}
 
-	default:
-		// no message
+		default:
+			stop = true
+		}
 	}
 
 	if w.active {
 		transforms.LoadLatLongViewingMatrices(cb)
 		cb.SetRGBA(RGBA{1, 1, 1, intensity})
 		cb.Blend()
-		for i, wcb := range w.wxCb {
+		for i := 0; i < NumWxLevels; i++ {
 			if active[i] {
 				cb.EnableTexture(w.texId[i])
-				cb.Call(wcb)
+				for _, strip := range w.wxCb {
+					cb.Call(strip[i])
+				}
 				cb.DisableTexture()
 			}
 		}
@@ -467,10 +541,13 @@ func (w *WeatherRadar) Draw(ctx *PaneContext, intensity float32, contrast float3
 // DrawCompass emits drawing commands to draw compass heading directions at
 // the edges of the current window. It takes a center point p in lat-long
 // coordinates, transformation functions and the radar scope's current
-// rotation angle, if any.  Drawing commands are added to the provided
-// command buffer, which is assumed to have projection matrices set up for
-// drawing using window coordinates.
-func DrawCompass(p Point2LL, ctx *PaneContext, rotationAngle float32, font *Font, color RGB,
+// rotation angle, if any; the rotation angle should include the magnetic
+// variation if true headings are to be shown. If majorTicks is set, the
+// ticks at 10 degree increments are drawn longer than the others.
+// Drawing commands are added to the provided command buffer, which is
+// assumed to have projection matrices set up for drawing using window
+// coordinates.
+func DrawCompass(p Point2LL, ctx *PaneContext, rotationAngle float32, majorTicks bool, font *Font, color RGB,
 	paneBounds Extent2D, transforms ScopeTransformations, cb *CommandBuffer) {
 	// Window coordinates of the center point.
 	// TODO: should we explicitly handle the case of this being outside the window?
@@ -496,8 +573,9 @@ func DrawCompass(p Point2LL, ctx *PaneContext, rotationAngle float32, font *Font
 
 		// Draw a short line from the intersection point at the edge to the
 		// point ten pixels back inside the window toward the center.
+		tick := Select(majorTicks && int(h)%10 == 0, float32(20), float32(10))
 		pEdge := add2f(pw, scale2f(dir, t))
-		pInset := add2f(pw, scale2f(dir, t-10))
+		pInset := add2f(pw, scale2f(dir, t-tick))
 		ld.AddLine(pEdge, pInset, color)
 
 		// Every 10 degrees draw a heading label.
@@ -515,7 +593,7 @@ func DrawCompass(p Point2LL, ctx *PaneContext, rotationAngle float32, font *Font
 
 			// Initial inset to place the text--a little past the end of
 			// the line.
-			pText := add2f(pw, scale2f(dir, t-14))
+			pText := add2f(pw, scale2f(dir, t-tick-4))
 
 			// Finer text positioning depends on which edge of the window
 			// pane we're on; this is made more grungy because text drawing
@@ -547,24 +625,46 @@ func DrawCompass(p Point2LL, ctx *PaneContext, rotationAngle float32, font *Font
 	td.GenerateCommands(cb)
 }
 
-// DrawRangeRings draws ten circles around the specified lat-long point in
-// steps of the specified radius (in nm).
-func DrawRangeRings(ctx *PaneContext, center Point2LL, radius float32, color RGB, transforms ScopeTransformations,
+// RangeRings specifies a set of concentric rings around a point.
+type RangeRings struct {
+	Center   Point2LL
+	Interval float32 // nm between rings
+	Radius   float32 // nm to the outermost ring; if 0, they go off the scope
+}
+
+// DrawRangeRings draws the given sets of range rings. If a font is
+// provided, each ring is labeled with its distance from its center.
+func DrawRangeRings(ctx *PaneContext, rings []RangeRings, color RGB, font *Font, transforms ScopeTransformations,
 	cb *CommandBuffer) {
 	pixelDistanceNm := transforms.PixelDistanceNM(ctx.world.NmPerLongitude)
-	centerWindow := transforms.WindowFromLatLongP(center)
 
 	ld := GetColoredLinesDrawBuilder()
 	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
 
-	for i := 1; i < 40; i++ {
-		// Radius of this ring in pixels
-		r := float32(i) * radius / pixelDistanceNm
-		ld.AddCircle(centerWindow, r, 360, color)
+	for _, rr := range rings {
+		if rr.Interval <= 0 {
+			continue
+		}
+		centerWindow := transforms.WindowFromLatLongP(rr.Center)
+		n := Select(rr.Radius > 0, int(rr.Radius/rr.Interval+0.001), 39)
+		for i := 1; i <= n; i++ {
+			// Radius of this ring in pixels
+			r := float32(i) * rr.Interval / pixelDistanceNm
+			ld.AddCircle(centerWindow, r, 360, color)
+
+			if font != nil {
+				label := strconv.FormatFloat(float64(float32(i)*rr.Interval), 'f', -1, 32)
+				td.AddText(label, add2f(centerWindow, [2]float32{2, r + float32(font.size)}),
+					TextStyle{Font: font, Color: color})
+			}
+		}
 	}
 
 	transforms.LoadWindowViewingMatrices(cb)
 	ld.GenerateCommands(cb)
+	td.GenerateCommands(cb)
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -666,6 +766,28 @@ func (st *ScopeTransformations) PixelDistanceNM(nmPerLongitude float32) float32
 
 // If the user has run the "find" command to highlight a point in the
 // world, draw a red circle around that point for a few seconds.
+// DrawRoutePreview draws the route that is being edited in the route
+// amendment dialog, if any.
+func DrawRoutePreview(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if len(globalConfig.previewRoute) < 2 {
+		return
+	}
+
+	ld := GetWideLinesDrawBuilder()
+	defer ReturnWideLinesDrawBuilder(ld)
+	ld.AddPolyline(UITextHighlightColor, MapSlice(globalConfig.previewRoute, transforms.WindowFromLatLongP))
+
+	transforms.LoadWindowViewingMatrices(cb)
+	ld.GenerateCommands(cb, 2)
+}
+
+// HighlightLocation causes the given location to be marked with a
+// flashing ring on radar scopes for the specified amount of time.
+func HighlightLocation(p Point2LL, d time.Duration) {
+	globalConfig.highlightedLocation = p
+	globalConfig.highlightedLocationEndTime = time.Now().Add(d)
+}
+
 func DrawHighlighted(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
 	remaining := time.Until(globalConfig.highlightedLocationEndTime)
 	if remaining < 0 {
@@ -681,11 +803,14 @@ func DrawHighlighted(ctx *PaneContext, transforms ScopeTransformations, cb *Comm
 
 	p := transforms.WindowFromLatLongP(globalConfig.highlightedLocation)
 	radius := float32(10) // 10 pixel radius
-	ld := GetColoredLinesDrawBuilder()
-	defer ReturnColoredLinesDrawBuilder(ld)
-	ld.AddCircle(p, radius, 360, color)
+	ld := GetWideLinesDrawBuilder()
+	defer ReturnWideLinesDrawBuilder(ld)
+	ld.AddCircle(p, radius, 90, color)
+	// Add a ring that pulses outward to make it easier to spot.
+	sec := float32(remaining.Seconds())
+	pulse := sec - floor(sec)
+	ld.AddCircle(p, radius+30*(1-pulse), 90, lerpRGB(pulse, RGB{}, color))
 
 	transforms.LoadWindowViewingMatrices(cb)
-	cb.LineWidth(3)
-	ld.GenerateCommands(cb)
+	ld.GenerateCommands(cb, 3)
 }
@@ -0,0 +1,183 @@
+// reconnect.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// If there's no response to a world update request from a remote
+	// server in this long, the connection is assumed to have been lost.
+	// This matches the time after which the server signs off controllers
+	// it hasn't heard from.
+	SimConnectionTimeout = 15 * time.Second
+
+	// Reconnection attempts are abandoned after this long.
+	SimReconnectTimeout = 5 * time.Minute
+)
+
+// SimReconnector tries to reestablish a lost connection to a remote
+// multi-controller server. Until it succeeds, the World keeps its most
+// recent aircraft and controllers so that the scope still shows the last
+// known state of things.
+type SimReconnector struct {
+	lostTime time.Time
+	backoff  Backoff
+
+	nextAttempt time.Time
+	attempts    int
+	pending     bool
+	result      chan simReconnectResult
+	failed      bool // gave up
+}
+
+type simReconnectResult struct {
+	server *SimServer
+	token  string
+	err    error
+}
+
+// TryReconnect should be called when the connection to the server has
+// been lost. It returns true if the World will try to reconnect
+// automatically; otherwise (e.g., the sim is running locally or earlier
+// attempts to reconnect were unsuccessful), the caller should handle the
+// disconnection.
+func (w *World) TryReconnect() bool {
+	if w.simProxy == nil || localServer == nil || w.simProxy.Client == localServer.RPCClient || w.SimName == "" {
+		return false
+	}
+	if w.reconnector != nil {
+		return !w.reconnector.failed
+	}
+
+	lg.Category(LogCategoryNetwork).Warnf("Lost connection to %s; trying to reconnect", *serverAddress)
+
+	// Make sure that the old connection is shut down; outstanding calls
+	// will never complete, so we don't wait for them.
+	w.simProxy.Client.Close()
+	w.updateCall = nil
+	w.pendingCalls = nil
+	remoteServer = nil
+
+	w.reconnector = &SimReconnector{
+		lostTime: time.Now(),
+		backoff:  Backoff{Min: time.Second, Max: 30 * time.Second},
+		result:   make(chan simReconnectResult, 1),
+	}
+	return true
+}
+
+// Reconnecting returns true if the connection to the server has been lost
+// and the World is trying to reestablish it.
+func (w *World) Reconnecting() bool {
+	return w.reconnector != nil && !w.reconnector.failed
+}
+
+// ConnectionStatus returns a short description of the state of the
+// connection to the server if it's degraded and an empty string
+// otherwise.
+func (w *World) ConnectionStatus() string {
+	if !w.Reconnecting() {
+		return ""
+	}
+	r := w.reconnector
+	s := fmt.Sprintf("reconnecting, data %ds old", int(time.Since(r.lostTime).Seconds()))
+	if r.attempts > 0 {
+		s += fmt.Sprintf(", attempt %d", r.attempts)
+	}
+	return s
+}
+
+func (w *World) updateReconnect(eventStream *EventStream, onErr func(error)) {
+	r := w.reconnector
+
+	select {
+	case res := <-r.result:
+		r.pending = false
+		if res.err == nil {
+			lg.Category(LogCategoryNetwork).Infof("Reconnected to %s after %s", *serverAddress, time.Since(r.lostTime))
+			w.simProxy.Client = res.server.RPCClient
+			w.simProxy.ControllerToken = res.token
+			remoteServer = res.server
+			w.reconnector = nil
+			// Get a fresh world update, including the current aircraft,
+			// flight plans, and controllers, right away.
+			w.lastUpdateRequest = time.Time{}
+
+			eventStream.Post(Event{
+				Type:    StatusMessageEvent,
+				Message: "Reconnected to the vice server.",
+			})
+			return
+		}
+
+		lg.Category(LogCategoryNetwork).Warnf("Reconnection attempt %d: %v", r.attempts, res.err)
+		if errors.Is(res.err, ErrRPCVersionMismatch) || time.Since(r.lostTime) > SimReconnectTimeout {
+			r.failed = true
+			onErr(fmt.Errorf("%w: %v", ErrReconnectFailed, res.err))
+			return
+		}
+		r.nextAttempt = time.Now().Add(r.backoff.Next())
+
+	default:
+	}
+
+	if !r.pending && time.Now().After(r.nextAttempt) {
+		r.pending = true
+		r.attempts++
+		go r.attempt(w.simProxy.ControllerToken, w.SimName, w.Callsign, w.simPassword)
+	}
+}
+
+func (r *SimReconnector) attempt(token, simName, callsign, password string) {
+	client, err := getClient(*serverAddress)
+	if err != nil {
+		r.result <- simReconnectResult{err: err}
+		return
+	}
+
+	var so SignOnResult
+	if err := client.CallWithTimeout("SimManager.SignOn", ViceRPCVersion, &so); err != nil {
+		client.Close()
+		r.result <- simReconnectResult{err: TryDecodeError(err)}
+		return
+	}
+	server := &SimServer{
+		RPCClient:   client,
+		name:        "Network (Multi-controller)",
+		configs:     so.Configurations,
+		runningSims: so.RunningSims,
+	}
+
+	// If the server hasn't signed us off yet, we can carry on with the
+	// same controller token.
+	var wu SimWorldUpdate
+	if err := client.CallWithTimeout("Sim.GetWorldUpdate", token, &wu); err == nil {
+		r.result <- simReconnectResult{server: server, token: token}
+		return
+	} else if err = TryDecodeError(err); err != ErrNoSimForControllerToken && err != ErrInvalidControllerToken {
+		client.Close()
+		r.result <- simReconnectResult{err: err}
+		return
+	}
+
+	// Otherwise sign on again at the same position.
+	config := &NewSimConfiguration{
+		NewSimType:                NewSimJoinRemote,
+		SelectedRemoteSim:         simName,
+		SelectedRemoteSimPosition: callsign,
+		RemoteSimPassword:         password,
+	}
+	var result NewSimResult
+	if err := client.CallWithTimeout("SimManager.New", config, &result); err != nil {
+		client.Close()
+		r.result <- simReconnectResult{err: TryDecodeError(err)}
+		return
+	}
+	r.result <- simReconnectResult{server: server, token: result.ControllerToken}
+}
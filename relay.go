@@ -0,0 +1,181 @@
+// relay.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// LiveTrafficRelay makes the live traffic that one vice instance receives
+// available to other vice instances on the local network (e.g., a tower
+// and a radar position in the same room) so that only one of them needs
+// to connect to the network or ADS-B source. Connections to the relay
+// aren't authenticated, so anyone who can reach its address can fetch
+// the traffic; by default it only listens on localhost and it should only
+// be bound to other interfaces on trusted networks.
+type LiveTrafficRelay struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	source  string
+	tracks  []LiveTrack
+	updated time.Time
+}
+
+const LiveTrafficRelayPort = ViceServerPort + 1
+
+var DefaultLiveTrafficRelayAddress = fmt.Sprintf("localhost:%d", LiveTrafficRelayPort)
+
+type LiveTrafficRelayReply struct {
+	Source  string
+	Tracks  []LiveTrack
+	Updated time.Time
+}
+
+// NewLiveTrafficRelay starts serving live traffic to other vice instances
+// at the given address, which is of the form host:port.
+func NewLiveTrafficRelay(addr string) (*LiveTrafficRelay, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &LiveTrafficRelay{listener: l}
+	server := rpc.NewServer()
+	if err := server.RegisterName("LiveTrafficRelay", &liveTrafficRelayDispatcher{r: r}); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	go func() {
+		lg.Category(LogCategoryNetwork).Infof("Relaying live traffic on %s", l.Addr())
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				// The listener has been closed.
+				lg.Category(LogCategoryNetwork).Infof("%s: live traffic relay stopped: %v", l.Addr(), err)
+				return
+			}
+			lg.Category(LogCategoryNetwork).Infof("%s: new live traffic relay connection", conn.RemoteAddr())
+
+			if cc, err := MakeCompressedConn(conn); err != nil {
+				lg.Category(LogCategoryNetwork).Errorf("MakeCompressedConn: %v", err)
+			} else {
+				go server.ServeCodec(MakeGOBServerCodec(cc))
+			}
+		}
+	}()
+
+	return r, nil
+}
+
+// Publish updates the aircraft that are sent to other instances.
+func (r *LiveTrafficRelay) Publish(source string, tracks []LiveTrack) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.source, r.tracks, r.updated = source, tracks, time.Now()
+}
+
+// Port returns the port that the relay is listening on.
+func (r *LiveTrafficRelay) Port() int {
+	return r.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (r *LiveTrafficRelay) Close() {
+	r.listener.Close()
+}
+
+type liveTrafficRelayDispatcher struct {
+	r *LiveTrafficRelay
+}
+
+func (d *liveTrafficRelayDispatcher) GetTracks(_ int, reply *LiveTrafficRelayReply) error {
+	d.r.mu.Lock()
+	defer d.r.mu.Unlock()
+	*reply = LiveTrafficRelayReply{Source: d.r.source, Tracks: d.r.tracks, Updated: d.r.updated}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// ViceRelaySource
+
+// ViceRelaySource is a LiveTrafficSource that gets its aircraft from
+// another vice instance that is running a LiveTrafficRelay.
+type ViceRelaySource struct {
+	address string
+
+	// Fetch is called from the polling goroutine but Close is called from
+	// the main thread, so access to client is protected by mu.
+	mu     sync.Mutex
+	client *RPCClient
+	closed bool
+}
+
+func NewViceRelaySource(address string) *ViceRelaySource {
+	return &ViceRelaySource{address: address}
+}
+
+func (v *ViceRelaySource) Name() string { return "vice relay " + v.address }
+
+func (v *ViceRelaySource) PollInterval() time.Duration { return 2 * time.Second }
+
+func (v *ViceRelaySource) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.closed = true
+	if v.client != nil {
+		v.client.Close()
+		v.client = nil
+	}
+}
+
+func (v *ViceRelaySource) Fetch(center Point2LL, radius float32) ([]LiveTrack, error) {
+	v.mu.Lock()
+	client := v.client
+	v.mu.Unlock()
+
+	if client == nil {
+		// Connect without holding the lock so that Close doesn't block
+		// on it.
+		var err error
+		if client, err = getClient(v.address); err != nil {
+			return nil, err
+		}
+
+		v.mu.Lock()
+		if v.closed {
+			v.mu.Unlock()
+			client.Close()
+			return nil, fmt.Errorf("%s: relay source closed", v.address)
+		}
+		v.client = client
+		v.mu.Unlock()
+	}
+
+	var reply LiveTrafficRelayReply
+	if err := client.CallWithTimeout("LiveTrafficRelay.GetTracks", 0, &reply); err != nil {
+		// Reconnect next time, unless Close has already taken care of
+		// the client.
+		v.mu.Lock()
+		if v.client == client {
+			v.client.Close()
+			v.client = nil
+		}
+		v.mu.Unlock()
+		return nil, err
+	}
+	if reply.Updated.IsZero() {
+		return nil, fmt.Errorf("%s: no traffic has been received yet", v.address)
+	}
+
+	return FilterSlice(reply.Tracks, func(t LiveTrack) bool {
+		return nmdistance2ll(center, t.Position) <= radius
+	}), nil
+}
@@ -0,0 +1,183 @@
+// reminders.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reminder is a countdown timer that the user has attached to an
+// aircraft, e.g. as a reminder to climb it in a few minutes. When it
+// expires, a chime is played and the aircraft's datablock flashes for
+// ReminderFlashDuration.
+type Reminder struct {
+	Callsign string
+	Note     string
+	Expires  time.Time // sim time
+	expired  bool
+}
+
+const ReminderFlashDuration = time.Minute
+
+// AddReminder sets a reminder for the given aircraft that expires after
+// the given amount of sim time.
+func (w *World) AddReminder(callsign string, d time.Duration, note string) {
+	w.reminders = append(w.reminders, &Reminder{
+		Callsign: callsign,
+		Note:     note,
+		Expires:  w.CurrentTime().Add(d),
+	})
+}
+
+// CancelReminders removes all of the reminders for the given aircraft and
+// returns how many there were.
+func (w *World) CancelReminders(callsign string) int {
+	n := len(w.reminders)
+	w.reminders = FilterSlice(w.reminders, func(r *Reminder) bool { return r.Callsign != callsign })
+	return n - len(w.reminders)
+}
+
+// Reminders returns the active reminders, sorted by expiration time.
+func (w *World) Reminders() []*Reminder {
+	return w.reminders
+}
+
+// ReminderExpired returns true if a reminder for the aircraft has expired
+// and its datablock should be flashing.
+func (w *World) ReminderExpired(callsign string) bool {
+	for _, r := range w.reminders {
+		if r.Callsign == callsign && r.expired {
+			return true
+		}
+	}
+	return false
+}
+
+// updateReminders should be called once per frame; it plays the chime for
+// newly expired reminders and removes the ones that are done flashing or
+// whose aircraft are gone.
+func (w *World) updateReminders() {
+	now := w.CurrentTime()
+	for _, r := range w.reminders {
+		if !r.expired && now.After(r.Expires) {
+			r.expired = true
+			globalConfig.Audio.PlayOnce(AudioReminder)
+		}
+	}
+
+	w.reminders = FilterSlice(w.reminders, func(r *Reminder) bool {
+		_, ok := w.Aircraft[r.Callsign]
+		return ok && now.Before(r.Expires.Add(ReminderFlashDuration))
+	})
+	// Keep them sorted so that the soonest to expire come first.
+	slices.SortStableFunc(w.reminders, func(a, b *Reminder) int { return a.Expires.Compare(b.Expires) })
+}
+
+// parseReminderCommand parses the arguments to the REMIND command,
+// "callsign time [note]", where time is given as minutes or MM:SS.
+func parseReminderCommand(args string) (callsign string, d time.Duration, note string, err error) {
+	f := strings.Fields(args)
+	if len(f) < 2 {
+		err = ErrInvalidCommandSyntax
+		return
+	}
+	callsign, note = f[0], strings.Join(f[2:], " ")
+
+	min, sec, haveSec := strings.Cut(f[1], ":")
+	m, merr := strconv.Atoi(min)
+	s, serr := 0, error(nil)
+	if haveSec {
+		s, serr = strconv.Atoi(sec)
+	}
+	if merr != nil || serr != nil || m < 0 || s < 0 || s >= 60 || m*60+s == 0 {
+		err = ErrInvalidCommandSyntax
+		return
+	}
+	d = time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	return
+}
+
+///////////////////////////////////////////////////////////////////////////
+// RemindersPane
+
+// RemindersPane lists the active reminders along with the time remaining
+// for each.
+type RemindersPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+}
+
+func NewRemindersPane() *RemindersPane {
+	return &RemindersPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (rp *RemindersPane) Name() string { return "Reminders" }
+
+func (rp *RemindersPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if rp.font = GetFont(rp.FontIdentifier); rp.font == nil {
+		rp.font = GetDefaultFont()
+		rp.FontIdentifier = rp.font.id
+	}
+	if rp.scrollbar == nil {
+		rp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+}
+
+func (rp *RemindersPane) Deactivate()                {}
+func (rp *RemindersPane) ResetWorld(w *World)        {}
+func (rp *RemindersPane) CanTakeKeyboardFocus() bool { return false }
+
+func (rp *RemindersPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&rp.FontIdentifier, "Font"); changed {
+		rp.font = newFont
+	}
+}
+
+func (rp *RemindersPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	reminders := ctx.world.Reminders()
+	now := ctx.world.CurrentTime()
+
+	lineHeight := float32(rp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	rp.scrollbar.Update(len(reminders), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	y := ctx.paneExtent.Height() - 1
+	if len(reminders) == 0 {
+		td.AddText("No reminders. Set them with REMIND callsign minutes [note].", [2]float32{2, y},
+			TextStyle{Font: rp.font, Color: UITextColor})
+	}
+	for _, r := range reminders[rp.scrollbar.Offset():] {
+		style := TextStyle{Font: rp.font, Color: UITextColor}
+		remaining := "EXPIRED"
+		if !r.expired {
+			s := int(r.Expires.Sub(now).Seconds()) + 1
+			remaining = fmt.Sprintf("%d:%02d", s/60, s%60)
+		} else if ctx.now.Second()&1 == 0 {
+			style.Color = UIErrorColor
+		}
+		td.AddText(fmt.Sprintf("%-8s %7s %s", r.Callsign, remaining, r.Note), [2]float32{2, y}, style)
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	rp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
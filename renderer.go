@@ -44,6 +44,11 @@ type Renderer interface {
 	// rendered.
 	RenderCommandBuffer(*CommandBuffer) RendererStats
 
+	// ReadPixels returns the contents of the specified rectangle of the
+	// framebuffer, given in framebuffer coordinates with the origin at the
+	// lower left. The returned image is top-down.
+	ReadPixels(x, y, width, height int) *image.RGBA
+
 	// Dispose releases resources allocated by the renderer.
 	Dispose()
 }
@@ -127,6 +132,8 @@ const (
 	RendererDrawQuads                   // 2 int32: offset to the index buffer, count
 	RendererCallBuffer                  // 1 int32: buffer index
 	RendererResetState                  // no args
+	RendererAlphaTest                   // float32: alpha threshold
+	RendererDisableAlphaTest            // no args
 )
 
 // CommandBuffer encodes a sequence of rendering commands in an
@@ -186,7 +193,7 @@ func (cb *CommandBuffer) appendFloats(floats ...float32) {
 func (cb *CommandBuffer) appendInts(ints ...int) {
 	for _, i := range ints {
 		if i != int(uint32(i)) {
-			lg.Errorf("%d: attempting to add non-32-bit value to CommandBuffer", i)
+			lg.Category(LogCategoryRender).Errorf("%d: attempting to add non-32-bit value to CommandBuffer", i)
 		}
 		cb.Buf = append(cb.Buf, uint32(i))
 	}
@@ -202,7 +209,7 @@ func (cb *CommandBuffer) FloatSlice(start, length int) []float32 {
 		return nil
 	}
 	if start%4 != 0 {
-		lg.Errorf("%d: unaligned offset passed to FloatSlice", start)
+		lg.Category(LogCategoryRender).Errorf("%d: unaligned offset passed to FloatSlice", start)
 	}
 	ptr := (*float32)(unsafe.Pointer(&cb.Buf[start/4]))
 	return unsafe.Slice(ptr, length)
@@ -303,6 +310,20 @@ func (cb *CommandBuffer) DisableBlend() {
 	cb.appendInts(RendererDisableBlend)
 }
 
+// AlphaTest adds a command to the command buffer that enables the alpha
+// test so that only fragments with alpha greater than the given threshold
+// are drawn.
+func (cb *CommandBuffer) AlphaTest(threshold float32) {
+	cb.appendInts(RendererAlphaTest)
+	cb.appendFloats(threshold)
+}
+
+// DisableAlphaTest adds a command to the command buffer that disables the
+// alpha test.
+func (cb *CommandBuffer) DisableAlphaTest() {
+	cb.appendInts(RendererDisableAlphaTest)
+}
+
 // Float2Buffer stores the provided slice of [2]float32 values in the
 // CommandBuffer and returns the byte offset where the first value of the
 // slice is stored; this offset can then be passed to commands like
@@ -336,6 +357,22 @@ func (cb *CommandBuffer) RGBBuffer(buf []RGB) int {
 	return offset
 }
 
+// RGBABuffer stores the provided slice of RGBA values in the command
+// buffer and returns the byte offset where the first value of the slice is
+// stored.
+func (cb *CommandBuffer) RGBABuffer(buf []RGBA) int {
+	cb.appendInts(RendererFloatBuffer, 4*len(buf))
+	offset := 4 * len(cb.Buf)
+
+	n := 4 * len(buf)
+	cb.growFor(n)
+	start := len(cb.Buf)
+	cb.Buf = cb.Buf[:start+n]
+	copy(cb.Buf[start:start+n], unsafe.Slice((*uint32)(unsafe.Pointer(&buf[0])), n))
+
+	return offset
+}
+
 // IntBuffer stores the provided slice of int32 values in the command buffer
 // and returns the byte offset where the first value of the slice is stored.
 func (cb *CommandBuffer) IntBuffer(buf []int32) int {
@@ -478,6 +515,45 @@ func (cb *CommandBuffer) Call(sub CommandBuffer) {
 	cb.called = append(cb.called, sub)
 }
 
+// CachedCommandBuffer retains a CommandBuffer across frames so that
+// geometry that rarely changes doesn't need to be regenerated every time
+// it is drawn. The key should encode all of the inputs that the
+// generated commands depend on; the commands are regenerated only when
+// it changes.
+type CachedCommandBuffer[K comparable] struct {
+	cb    CommandBuffer
+	key   K
+	valid bool
+}
+
+// Statistics about how effective the CachedCommandBuffers are; they are
+// reported in the performance window.
+var commandBufferCacheStats struct {
+	hits, misses int
+}
+
+// Get returns the cached command buffer if it was generated using the
+// given key and otherwise regenerates it by calling the provided
+// function. The returned CommandBuffer should be passed to
+// CommandBuffer.Call; it remains valid until the next call to Get.
+func (c *CachedCommandBuffer[K]) Get(key K, generate func(cb *CommandBuffer)) CommandBuffer {
+	if !c.valid || c.key != key {
+		c.cb.Reset()
+		generate(&c.cb)
+		c.key, c.valid = key, true
+		commandBufferCacheStats.misses++
+	} else {
+		commandBufferCacheStats.hits++
+	}
+	return c.cb
+}
+
+// Invalidate forces the commands to be regenerated the next time Get is
+// called.
+func (c *CachedCommandBuffer[K]) Invalidate() {
+	c.valid = false
+}
+
 // ResetState adds a command to the comment buffer that resets all of the
 // assorted graphics state (scissor rectangle, blending, texturing, vertex
 // arrays, etc.) to default values.
@@ -681,6 +757,13 @@ func (l *ColoredLinesDrawBuilder) AddCircle(p [2]float32, radius float32, nsegs
 	}
 }
 
+func (l *ColoredLinesDrawBuilder) AddLatLongCircle(p Point2LL, nmPerLongitude float32, r float32, nsegs int, color RGB) {
+	l.LinesDrawBuilder.AddLatLongCircle(p, nmPerLongitude, r, nsegs)
+	for i := 0; i < nsegs; i++ {
+		l.color = append(l.color, color, color)
+	}
+}
+
 func (l *ColoredLinesDrawBuilder) GenerateCommands(cb *CommandBuffer) (int, int) {
 	if len(l.indices) == 0 {
 		return 0, 0
@@ -707,6 +790,170 @@ func ReturnColoredLinesDrawBuilder(ld *ColoredLinesDrawBuilder) {
 	coloredLinesDrawBuilderPool.Put(ld)
 }
 
+// WideLinesDrawBuilder draws antialiased lines and polylines with round
+// joins and caps. Rather than relying on the renderer's LineWidth, which
+// gives jagged lines that break apart at corners when they're more than a
+// pixel or two wide, the lines are expanded into triangles, with a
+// one-pixel band around their edges where the alpha falls off to zero.
+// All of its points are in window coordinates and the line width is
+// specified in pixels when GenerateCommands is called.
+type WideLinesDrawBuilder struct {
+	p     [][2]float32
+	lines []wideLine
+
+	// Geometry generated by GenerateCommands
+	vertices [][2]float32
+	rgba     []RGBA
+	indices  []int32
+}
+
+// wideLine is a polyline given by the points p[start:end].
+type wideLine struct {
+	start, end int
+	loop       bool
+	color      RGB
+}
+
+func (l *WideLinesDrawBuilder) Reset() {
+	l.p = l.p[:0]
+	l.lines = l.lines[:0]
+	l.vertices = l.vertices[:0]
+	l.rgba = l.rgba[:0]
+	l.indices = l.indices[:0]
+}
+
+func (l *WideLinesDrawBuilder) AddLine(p0, p1 [2]float32, color RGB) {
+	l.AddPolyline(color, [][2]float32{p0, p1})
+}
+
+// AddPolyline adds a sequence of connected line segments.
+func (l *WideLinesDrawBuilder) AddPolyline(color RGB, p [][2]float32) {
+	l.lines = append(l.lines, wideLine{start: len(l.p), end: len(l.p) + len(p), color: color})
+	l.p = append(l.p, p...)
+}
+
+// AddLineLoop adds a closed polyline; the last point is connected to the
+// first.
+func (l *WideLinesDrawBuilder) AddLineLoop(color RGB, p [][2]float32) {
+	l.lines = append(l.lines, wideLine{start: len(l.p), end: len(l.p) + len(p), loop: true, color: color})
+	l.p = append(l.p, p...)
+}
+
+// AddCircle adds the outline of a circle with specified radius and color
+// centered at the specified point p. The nsegs parameter specifies the
+// tessellation rate for the circle.
+func (l *WideLinesDrawBuilder) AddCircle(p [2]float32, radius float32, nsegs int, color RGB) {
+	pts := MapSlice(GetCirclePoints(nsegs), func(c [2]float32) [2]float32 { return add2f(p, scale2f(c, radius)) })
+	l.AddLineLoop(color, pts)
+}
+
+func (l *WideLinesDrawBuilder) addVertex(p [2]float32, color RGB, alpha float32) int32 {
+	l.vertices = append(l.vertices, p)
+	l.rgba = append(l.rgba, RGBA{R: color.R, G: color.G, B: color.B, A: alpha})
+	return int32(len(l.vertices) - 1)
+}
+
+// addSegment adds a quad for the line from p0 to p1 with feathered edges.
+func (l *WideLinesDrawBuilder) addSegment(p0, p1 [2]float32, hw, feather float32, color RGB) {
+	d := sub2f(p1, p0)
+	if length2f(d) == 0 {
+		return
+	}
+	n := normalize2f([2]float32{-d[1], d[0]})
+	inner, outer := scale2f(n, hw), scale2f(n, hw+feather)
+
+	// Vertices across the line, from the outer edge on one side to the
+	// outer edge on the other side, at each endpoint.
+	var idx [2][4]int32
+	for i, p := range [2][2]float32{p0, p1} {
+		idx[i][0] = l.addVertex(add2f(p, outer), color, 0)
+		idx[i][1] = l.addVertex(add2f(p, inner), color, 1)
+		idx[i][2] = l.addVertex(sub2f(p, inner), color, 1)
+		idx[i][3] = l.addVertex(sub2f(p, outer), color, 0)
+	}
+	for j := 0; j < 3; j++ {
+		a, b, c, d := idx[0][j], idx[0][j+1], idx[1][j+1], idx[1][j]
+		l.indices = append(l.indices, a, b, c, a, c, d)
+	}
+}
+
+// addDisc adds a filled disc with feathered edges; these give the round
+// joins and caps.
+func (l *WideLinesDrawBuilder) addDisc(p [2]float32, hw, feather float32, color RGB) {
+	// Use enough segments that the edges of the disc are smooth.
+	nsegs := clamp(int(2*math.Pi*hw/2), 8, 64)
+	circle := GetCirclePoints(nsegs)
+
+	center := l.addVertex(p, color, 1)
+	start := int32(len(l.vertices))
+	for _, c := range circle {
+		l.addVertex(add2f(p, scale2f(c, hw)), color, 1)
+		l.addVertex(add2f(p, scale2f(c, hw+feather)), color, 0)
+	}
+	for i := 0; i < nsegs; i++ {
+		i0, i1 := start+int32(2*i), start+int32(2*((i+1)%nsegs))
+		l.indices = append(l.indices, center, i0, i1)
+		l.indices = append(l.indices, i0, i0+1, i1+1, i0, i1+1, i1)
+	}
+}
+
+// GenerateCommands adds commands to draw the lines with the given width
+// (in pixels) to the command buffer.
+func (l *WideLinesDrawBuilder) GenerateCommands(cb *CommandBuffer, width float32) {
+	if len(l.lines) == 0 {
+		return
+	}
+
+	// Window coordinates may be scaled with respect to pixels on high-DPI
+	// displays; the feathered edge should always be a single pixel.
+	feather := 1 / platform.DPIScale()
+	hw := max(width/2-feather/2, 0)
+
+	l.vertices, l.rgba, l.indices = l.vertices[:0], l.rgba[:0], l.indices[:0]
+	for _, line := range l.lines {
+		p := l.p[line.start:line.end]
+		for i := 0; i+1 < len(p); i++ {
+			l.addSegment(p[i], p[i+1], hw, feather, line.color)
+		}
+		if line.loop && len(p) > 2 {
+			l.addSegment(p[len(p)-1], p[0], hw, feather, line.color)
+		}
+		// Joins at all of the interior points, and caps at the ends.
+		if hw > 0 {
+			for _, pt := range p {
+				l.addDisc(pt, hw, feather, line.color)
+			}
+		}
+	}
+	if len(l.indices) == 0 {
+		return
+	}
+
+	cb.Blend()
+	pv := cb.Float2Buffer(l.vertices)
+	cb.VertexArray(pv, 2, 2*4)
+	rgba := cb.RGBABuffer(l.rgba)
+	cb.RGB32Array(rgba, 4, 4*4)
+	ind := cb.IntBuffer(l.indices)
+	cb.DrawTriangles(ind, len(l.indices))
+	cb.DisableColorArray()
+	cb.DisableVertexArray()
+	cb.DisableBlend()
+}
+
+// WideLinesDrawBuilders are managed using a sync.Pool so that their buf
+// slice allocations persist across multiple uses.
+var wideLinesDrawBuilderPool = sync.Pool{New: func() any { return &WideLinesDrawBuilder{} }}
+
+func GetWideLinesDrawBuilder() *WideLinesDrawBuilder {
+	return wideLinesDrawBuilderPool.Get().(*WideLinesDrawBuilder)
+}
+
+func ReturnWideLinesDrawBuilder(ld *WideLinesDrawBuilder) {
+	ld.Reset()
+	wideLinesDrawBuilderPool.Put(ld)
+}
+
 // TrianglesDrawBuilder collects triangles to be batched up in a single
 // draw call. Note that it does not allow specifying per-vertex or
 // per-triangle color; rather, the current color as specified by a call to
@@ -904,6 +1151,8 @@ func ReturnTexturedTrianglesDrawBuilder(td *TexturedTrianglesDrawBuilder) {
 type TextDrawBuilder struct {
 	// Vertex/index buffers for regular text and drop shadows, if enabled.
 	regular map[uint32]*TextBuffers // Map from texid to buffers
+	// Vertex/index buffers for text drawn using scalable fonts.
+	scalable map[uint32]*TextBuffers
 
 	// Buffers for background quads, if specified (shared for all tex ids)
 	background struct {
@@ -930,12 +1179,13 @@ func (t *TextBuffers) Reset() {
 }
 
 // Add updates the buffers to draw the given glyph with the given color,
-// with upper-left coordinates specified by p.
-func (t *TextBuffers) Add(p [2]float32, glyph *Glyph, color RGB) {
+// with upper-left coordinates specified by p. The glyph's extent is
+// scaled by the given scale factor.
+func (t *TextBuffers) Add(p [2]float32, glyph *Glyph, color RGB, scale float32) {
 	// Get the vertex positions and texture coordinates for the
 	// glyph.
 	u0, v0, u1, v1 := glyph.U0, glyph.V0, glyph.U1, glyph.V1
-	x0, y0, x1, y1 := glyph.X0, glyph.Y0, glyph.X1, glyph.Y1
+	x0, y0, x1, y1 := scale*glyph.X0, scale*glyph.Y0, scale*glyph.X1, scale*glyph.Y1
 
 	// Add the quad for the glyph to the vertex/index buffers
 	startIdx := int32(len(t.p))
@@ -980,14 +1230,29 @@ type TextStyle struct {
 	// BackgroundColor specifies the color of the background; it is only used if
 	// DrawBackground is grue.
 	BackgroundColor RGB
+	// Scale, if non-zero, causes the text to be drawn with the font's
+	// scalable version (see sdf.go), scaled by the given amount relative
+	// to the font's size. Text drawn this way can be positioned at
+	// sub-pixel locations.
+	Scale float32
+}
+
+// scalable returns the font and scale factor to use for drawing text with
+// the style.
+func (s TextStyle) scalable() (*Font, float32) {
+	if s.Scale != 0 && s.Font.scalable != nil {
+		return s.Font.scalable, s.Scale
+	}
+	return nil, 1
 }
 
 // AddTextCentered draws the specified text centered at the specified
 // position p.
 func (td *TextDrawBuilder) AddTextCentered(text string, p [2]float32, style TextStyle) {
 	bx, by := style.Font.BoundText(text, 0)
-	p[0] -= float32(bx) / 2
-	p[1] += float32(by) / 2
+	_, scale := style.scalable()
+	p[0] -= scale * float32(bx) / 2
+	p[1] += scale * float32(by) / 2
 	td.AddText(text, p, style)
 }
 
@@ -1007,8 +1272,10 @@ func (td *TextDrawBuilder) AddTextMulti(text []string, p [2]float32, styles []Te
 	for i := range text {
 		style := styles[i]
 
+		sf, scale := style.scalable()
+
 		// Total between subsequent lines, vertically.
-		dy := float32(style.Font.size + style.LineSpacing)
+		dy := scale * float32(style.Font.size+style.LineSpacing)
 
 		// Bounds for the current line's background box, if needed
 		bx0, by0 := px, py
@@ -1036,7 +1303,12 @@ func (td *TextDrawBuilder) AddTextMulti(text []string, p [2]float32, styles []Te
 		}
 
 		for _, ch := range text[i] {
-			glyph := style.Font.LookupGlyph(ch)
+			var glyph *Glyph
+			if sf != nil {
+				glyph = sf.lookupScalableGlyph(ch)
+			} else {
+				glyph = style.Font.LookupGlyph(ch)
+			}
 
 			if ch == '\n' {
 				// End of line handling. First emit the background quad, if
@@ -1060,18 +1332,26 @@ func (td *TextDrawBuilder) AddTextMulti(text []string, p [2]float32, styles []Te
 			// Don't do any drawing if the glyph is marked as invisible;
 			// beyond the small perf. cost, we'll end up getting "?" and
 			// the like if we do this anyway.
-			if glyph.Visible {
+			if glyph.Visible && sf != nil {
+				if td.scalable == nil {
+					td.scalable = make(map[uint32]*TextBuffers)
+				}
+				if _, ok := td.scalable[sf.texId]; !ok {
+					td.scalable[sf.texId] = &TextBuffers{}
+				}
+				td.scalable[sf.texId].Add([2]float32{px, py}, glyph, style.Color, scale)
+			} else if glyph.Visible {
 				if td.regular == nil {
 					td.regular = make(map[uint32]*TextBuffers)
 				}
 				if _, ok := td.regular[style.Font.texId]; !ok {
 					td.regular[style.Font.texId] = &TextBuffers{}
 				}
-				td.regular[style.Font.texId].Add([2]float32{px, py}, glyph, style.Color)
+				td.regular[style.Font.texId].Add([2]float32{px, py}, glyph, style.Color, 1)
 			}
 
 			// Visible or not, advance the x cursor position to move to the next character.
-			px += glyph.AdvanceX
+			px += scale * glyph.AdvanceX
 		}
 
 		// Make sure we emit a background quad for the last line even if it
@@ -1087,6 +1367,9 @@ func (td *TextDrawBuilder) Reset() {
 	for _, regular := range td.regular {
 		regular.Reset()
 	}
+	for _, scalable := range td.scalable {
+		scalable.Reset()
+	}
 
 	td.background.p = td.background.p[:0]
 	td.background.rgb = td.background.rgb[:0]
@@ -1128,6 +1411,21 @@ func (td *TextDrawBuilder) GenerateCommands(cb *CommandBuffer) {
 		regular.GenerateCommands(cb)
 	}
 
+	// Scalable text is drawn with the alpha test rather than blending so
+	// that the edges are crisp; the distance field's alpha is 0.5 at
+	// glyph edges.
+	if len(td.scalable) > 0 {
+		cb.DisableBlend()
+		cb.AlphaTest(0.5)
+		for _, id := range SortedMapKeys(td.scalable) {
+			if scalable := td.scalable[id]; len(scalable.indices) > 0 {
+				cb.EnableTexture(id)
+				scalable.GenerateCommands(cb)
+			}
+		}
+		cb.DisableAlphaTest()
+	}
+
 	// Clean up after ourselves.
 	cb.DisableVertexArray()
 	cb.DisableColorArray()
@@ -1218,7 +1516,7 @@ func GenerateImguiCommandBuffer(cb *CommandBuffer) {
 
 		for _, command := range commandList.Commands() {
 			if command.HasUserCallback() {
-				lg.Error("Unexpected user callback in imgui draw list")
+				lg.Category(LogCategoryRender).Error("Unexpected user callback in imgui draw list")
 			} else {
 				clipRect := command.ClipRect()
 				cb.Scissor(int(clipRect.X), int(fbHeight)-int(clipRect.W),
@@ -0,0 +1,78 @@
+// retention.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// vice may be left running for hours, e.g., when observing live traffic,
+// so per-aircraft state has to be discarded once the aircraft are gone.
+// Tracks that haven't been updated for a while are dropped, bookkeeping
+// for aircraft that have left is periodically removed, and maps that have
+// grown large are reallocated, since Go maps never shrink on their own.
+
+// Default time after which live traffic tracks that haven't been updated
+// are dropped.
+const DefaultLostTrackTimeout = 10 // minutes
+
+// How often per-aircraft bookkeeping is cleaned up.
+const RetentionCompactInterval = time.Minute
+
+type RetentionPolicy struct {
+	// Minutes after which live traffic tracks that haven't been updated
+	// are dropped; DefaultLostTrackTimeout is used if zero.
+	LostTrackTimeout int
+	// If set, lost tracks are never dropped.
+	RetainLostTracks bool
+}
+
+func (rp *RetentionPolicy) lostTrackTimeout() time.Duration {
+	minutes := Select(rp.LostTrackTimeout > 0, rp.LostTrackTimeout, DefaultLostTrackTimeout)
+	return time.Duration(minutes) * time.Minute
+}
+
+// dropLostTracks returns the given tracks without the ones that haven't
+// been updated within the lost track timeout. The returned slice is newly
+// allocated if any are dropped so that the old one can be reclaimed.
+func (rp *RetentionPolicy) dropLostTracks(tracks []LiveTrack, now time.Time) []LiveTrack {
+	if rp.RetainLostTracks {
+		return tracks
+	}
+
+	timeout := rp.lostTrackTimeout()
+	lost := func(t LiveTrack) bool { return !t.Updated.IsZero() && now.Sub(t.Updated) > timeout }
+	if !slices.ContainsFunc(tracks, lost) {
+		return tracks
+	}
+	return FilterSlice(tracks, func(t LiveTrack) bool { return !lost(t) })
+}
+
+// compactMap returns a copy of the given map; Go maps keep their storage
+// after items are deleted from them, so this allows the memory for a map
+// that was once much larger to be reclaimed.
+func compactMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func (rp *RetentionPolicy) DrawUI() {
+	drop := !rp.RetainLostTracks
+	if imgui.Checkbox(Tr("Drop lost live traffic tracks"), &drop) {
+		rp.RetainLostTracks = !drop
+	}
+	uiStartDisable(!drop)
+	timeout := int32(Select(rp.LostTrackTimeout > 0, rp.LostTrackTimeout, DefaultLostTrackTimeout))
+	if imgui.SliderIntV(Tr("Lost track timeout"), &timeout, 1, 60, "%d min", 0) {
+		rp.LostTrackTimeout = int(timeout)
+	}
+	uiEndDisable(!drop)
+}
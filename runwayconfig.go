@@ -0,0 +1,301 @@
+// runwayconfig.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// RunwayConfiguration
+
+// RunwayConfiguration records which runways are in use at each of the
+// scenario's airports. It is client-side state that is initialized from
+// the scenario's arrival and departure runways and may then be edited in
+// the RunwayConfigPane; STARS derives its CRDA and ATPA runway selections
+// from it and draws extended centerlines for the runways that have them
+// enabled.
+type RunwayConfiguration struct {
+	Airports map[string]*AirportRunwayConfiguration
+
+	// Generation is incremented each time the configuration changes so
+	// that users of it can cheaply detect updates.
+	Generation int
+}
+
+type AirportRunwayConfiguration struct {
+	ArrivalRunways   []string
+	DepartureRunways []string
+	// Runways with extended centerlines drawn and, of those, the ones
+	// that also have their final approach fixes marked.
+	Centerlines    []string
+	CenterlineFAFs []string
+}
+
+func MakeRunwayConfiguration(w *World) *RunwayConfiguration {
+	rc := &RunwayConfiguration{Airports: make(map[string]*AirportRunwayConfiguration)}
+
+	get := func(airport string) *AirportRunwayConfiguration {
+		if _, ok := rc.Airports[airport]; !ok {
+			rc.Airports[airport] = &AirportRunwayConfiguration{}
+		}
+		return rc.Airports[airport]
+	}
+	for _, rwy := range w.ArrivalRunways {
+		ap := get(rwy.Airport)
+		if !slices.Contains(ap.ArrivalRunways, rwy.Runway) {
+			ap.ArrivalRunways = append(ap.ArrivalRunways, rwy.Runway)
+			ap.Centerlines = append(ap.Centerlines, rwy.Runway)
+		}
+	}
+	for _, rwy := range w.DepartureRunways {
+		ap := get(rwy.Airport)
+		if !slices.Contains(ap.DepartureRunways, rwy.Runway) {
+			ap.DepartureRunways = append(ap.DepartureRunways, rwy.Runway)
+		}
+	}
+
+	return rc
+}
+
+// IsArrivalRunway reports whether the given runway is in use for
+// arrivals. Airports that have no configuration are unrestricted.
+func (rc *RunwayConfiguration) IsArrivalRunway(airport, runway string) bool {
+	ap, ok := rc.Airports[airport]
+	return !ok || slices.Contains(ap.ArrivalRunways, runway)
+}
+
+// IsDepartureRunway reports whether the given runway is in use for
+// departures. Airports that have no configuration are unrestricted.
+func (rc *RunwayConfiguration) IsDepartureRunway(airport, runway string) bool {
+	ap, ok := rc.Airports[airport]
+	return !ok || slices.Contains(ap.DepartureRunways, runway)
+}
+
+// ATPAVolumeActive reports whether the runway that the given ATPA volume
+// is associated with is in use for arrivals.
+func (rc *RunwayConfiguration) ATPAVolumeActive(vol *ATPAVolume) bool {
+	for airport := range rc.Airports {
+		if rwy, ok := strings.CutPrefix(vol.Id, airport); ok {
+			return rc.IsArrivalRunway(airport, rwy)
+		}
+	}
+	return true
+}
+
+// ShowCenterline reports whether the runway's extended centerline should
+// be drawn and, if so, whether its final approach fixes should be marked.
+func (rc *RunwayConfiguration) ShowCenterline(airport, runway string) (show bool, faf bool) {
+	if ap, ok := rc.Airports[airport]; ok && slices.Contains(ap.Centerlines, runway) {
+		return true, slices.Contains(ap.CenterlineFAFs, runway)
+	}
+	return false, false
+}
+
+func (rc *RunwayConfiguration) ToggleArrivalRunway(airport, runway string) {
+	ap := rc.getAirport(airport)
+	ap.ArrivalRunways = toggleRunway(ap.ArrivalRunways, runway)
+	rc.Generation++
+}
+
+func (rc *RunwayConfiguration) ToggleDepartureRunway(airport, runway string) {
+	ap := rc.getAirport(airport)
+	ap.DepartureRunways = toggleRunway(ap.DepartureRunways, runway)
+	rc.Generation++
+}
+
+func (rc *RunwayConfiguration) ToggleCenterline(airport, runway string) {
+	ap := rc.getAirport(airport)
+	ap.Centerlines = toggleRunway(ap.Centerlines, runway)
+	rc.Generation++
+}
+
+func (rc *RunwayConfiguration) ToggleCenterlineFAF(airport, runway string) {
+	ap := rc.getAirport(airport)
+	ap.CenterlineFAFs = toggleRunway(ap.CenterlineFAFs, runway)
+	rc.Generation++
+}
+
+// toggleRunway adds the runway to the slice if it isn't in it and removes
+// it otherwise.
+func toggleRunway(runways []string, runway string) []string {
+	if idx := slices.Index(runways, runway); idx != -1 {
+		return DeleteSliceElement(runways, idx)
+	}
+	return append(runways, runway)
+}
+
+// SetRunways replaces the airport's arrival and departure runways.
+func (rc *RunwayConfiguration) SetRunways(airport string, arrivals, departures []string) {
+	ap := rc.getAirport(airport)
+	ap.ArrivalRunways, ap.DepartureRunways = arrivals, departures
+	rc.Generation++
+}
+
+func (rc *RunwayConfiguration) getAirport(airport string) *AirportRunwayConfiguration {
+	if _, ok := rc.Airports[airport]; !ok {
+		rc.Airports[airport] = &AirportRunwayConfiguration{}
+	}
+	return rc.Airports[airport]
+}
+
+// ExtendedCenterline returns the line segments for the runway's extended
+// centerline out to the given length in nm, including tick marks across
+// it every nm, with longer ones every 5nm.
+func ExtendedCenterline(rwy Runway, length float32, nmPerLongitude, magneticVariation float32) [][2]Point2LL {
+	p0 := ll2nm(rwy.Threshold, nmPerLongitude)
+	hdg := rwy.Heading - magneticVariation + 180
+	v := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
+	vp := [2]float32{-v[1], v[0]} // perp
+
+	segs := [][2]Point2LL{{rwy.Threshold, nm2ll(add2f(p0, scale2f(v, length)), nmPerLongitude)}}
+	for d := 1; d <= int(length); d++ {
+		w := Select(d%5 == 0, float32(0.4), float32(0.2))
+		p := add2f(p0, scale2f(v, float32(d)))
+		segs = append(segs, [2]Point2LL{nm2ll(add2f(p, scale2f(vp, -w)), nmPerLongitude),
+			nm2ll(add2f(p, scale2f(vp, w)), nmPerLongitude)})
+	}
+	return segs
+}
+
+///////////////////////////////////////////////////////////////////////////
+// RunwayConfigPane
+
+type RunwayConfigPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+}
+
+func NewRunwayConfigPane() *RunwayConfigPane {
+	return &RunwayConfigPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (rp *RunwayConfigPane) Name() string { return "Runway Configuration" }
+
+func (rp *RunwayConfigPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if rp.font = GetFont(rp.FontIdentifier); rp.font == nil {
+		rp.font = GetDefaultFont()
+		rp.FontIdentifier = rp.font.id
+	}
+	if rp.scrollbar == nil {
+		rp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+}
+
+func (rp *RunwayConfigPane) Deactivate()                {}
+func (rp *RunwayConfigPane) ResetWorld(w *World)        {}
+func (rp *RunwayConfigPane) CanTakeKeyboardFocus() bool { return false }
+
+func (rp *RunwayConfigPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&rp.FontIdentifier, "Font"); changed {
+		rp.font = newFont
+	}
+}
+
+func (rp *RunwayConfigPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	rc := ctx.world.RunwayConfiguration()
+
+	// Each line is a series of text segments; the ones with a non-nil
+	// toggle function can be clicked to change the configuration.
+	type segment struct {
+		text   string
+		active bool
+		toggle func()
+	}
+	var lines [][]segment
+
+	for _, icao := range SortedMapKeys(ctx.world.Airports) {
+		ap, ok := database.Airports[icao]
+		if !ok {
+			continue
+		}
+		lines = append(lines, []segment{{text: icao, active: true}})
+
+		for _, rwy := range ap.Runways {
+			id := rwy.Id
+			showCL, showFAF := rc.ShowCenterline(icao, id)
+			line := []segment{
+				{text: "  " + id + strings.Repeat(" ", max(0, 5-len(id))), active: true},
+				{text: "ARR", active: rc.IsArrivalRunway(icao, id),
+					toggle: func() { rc.ToggleArrivalRunway(icao, id) }},
+				{text: " "},
+				{text: "DEP", active: rc.IsDepartureRunway(icao, id),
+					toggle: func() { rc.ToggleDepartureRunway(icao, id) }},
+				{text: " "},
+				{text: "CL", active: showCL,
+					toggle: func() { rc.ToggleCenterline(icao, id) }},
+			}
+			if showCL {
+				// Final approach fixes can only be marked on centerlines.
+				line = append(line, segment{text: " "}, segment{text: "FAF", active: showFAF,
+					toggle: func() { rc.ToggleCenterlineFAF(icao, id) }})
+			}
+			lines = append(lines, line)
+		}
+
+		// Approaches to the active arrival runways
+		var appr []string
+		for _, name := range SortedMapKeys(ctx.world.Airports[icao].Approaches) {
+			if rc.IsArrivalRunway(icao, ctx.world.Airports[icao].Approaches[name].Runway) {
+				appr = append(appr, name)
+			}
+		}
+		if len(appr) > 0 {
+			lines = append(lines, []segment{{text: "  APPR " + strings.Join(appr, " "), active: true}})
+		}
+		lines = append(lines, nil)
+	}
+
+	lineHeight := float32(rp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	rp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	activeStyle := TextStyle{Font: rp.font, Color: UITextHighlightColor}
+	inactiveStyle := TextStyle{Font: rp.font, Color: UIControlColor}
+	textStyle := TextStyle{Font: rp.font, Color: UITextColor}
+
+	bx, _ := rp.font.BoundText("X", 0)
+	fw := float32(bx)
+	indent := float32(2)
+
+	y := ctx.paneExtent.Height() - 1
+	for _, line := range lines[rp.scrollbar.Offset():] {
+		x := indent
+		for _, seg := range line {
+			style := textStyle
+			if seg.toggle != nil {
+				style = Select(seg.active, activeStyle, inactiveStyle)
+
+				if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+					p := ctx.mouse.Pos
+					if p[0] >= x && p[0] < x+fw*float32(len(seg.text)) && p[1] <= y && p[1] > y-lineHeight {
+						seg.toggle()
+					}
+				}
+			}
+			td.AddText(seg.text, [2]float32{x, y}, style)
+			x += fw * float32(len(seg.text))
+		}
+
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	rp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
@@ -51,8 +51,19 @@ type AirspaceAwareness struct {
 	AircraftType        []string `json:"aircraft_type"`
 }
 
+// LOARestriction is an altitude restriction from a letter of agreement
+// that aircraft must meet at a fix in their route when they are handed
+// off to the receiving controller.
+type LOARestriction struct {
+	ReceivingController string   `json:"receiving_controller"`
+	Fix                 string   `json:"fix"`
+	Altitude            string   `json:"altitude"` // e.g., "11000", "17000-"
+	Airports            []string `json:"airports"` // departure or arrival; all if empty
+}
+
 type STARSFacilityAdaptation struct {
 	AirspaceAwareness   []AirspaceAwareness `json:"airspace_awareness"`
+	LOAs                []LOARestriction    `json:"letters_of_agreement"`
 	ForceQLToSelf       bool                `json:"force_ql_self"`
 	AllowLongScratchpad [2]bool             `json:"allow_long_scratchpad"` // [0] is for the primary. [1] is for the secondary
 	VideoMapNames       []string            `json:"stars_maps"`
@@ -65,6 +76,8 @@ type STARSFacilityAdaptation struct {
 	Range               float32                          `json:"range"`
 	Scratchpads         map[string]string                `json:"scratchpads"`
 	VideoMapFile        string                           `json:"video_map_file"`
+	BeaconCodeBankNames []string                         `json:"beacon_code_banks"`
+	BeaconCodeBanks     []BeaconCodeBank
 }
 
 type STARSControllerConfig struct {
@@ -745,6 +758,27 @@ func (sg *ScenarioGroup) PostDeserialize(e *ErrorLogger, simConfigurations map[s
 		e.Pop()
 	}
 
+	for _, loa := range sg.STARSFacilityAdaptation.LOAs {
+		e.Push("letters_of_agreement")
+
+		if _, ok := sg.locate(loa.Fix); !ok {
+			e.ErrorString(loa.Fix + ": fix unknown")
+		}
+		if _, err := ParseAltitudeRestriction(loa.Altitude); err != nil {
+			e.Error(err)
+		}
+		if _, ok := sg.ControlPositions[loa.ReceivingController]; !ok {
+			e.ErrorString(loa.ReceivingController + ": controller unknown")
+		}
+		for _, ap := range loa.Airports {
+			if _, ok := sg.Airports[ap]; !ok {
+				e.ErrorString(ap + ": airport unknown")
+			}
+		}
+
+		e.Pop()
+	}
+
 	for callsign, ctrl := range sg.ControlPositions {
 		e.Push("Controller " + callsign)
 
@@ -867,6 +901,15 @@ func (s *STARSFacilityAdaptation) PostDeserialize(e *ErrorLogger, sg *ScenarioGr
 		s.Range = 50
 	}
 
+	s.BeaconCodeBanks = nil
+	for _, str := range s.BeaconCodeBankNames {
+		if bank, err := ParseBeaconCodeBank(str); err != nil {
+			e.Error(err)
+		} else {
+			s.BeaconCodeBanks = append(s.BeaconCodeBanks, bank)
+		}
+	}
+
 	for name, rs := range s.RadarSites {
 		e.Push("Radar site " + name)
 		if p, ok := sg.locate(rs.PositionString); rs.PositionString == "" || !ok {
@@ -0,0 +1,261 @@
+// scenariorecorder.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// ScenarioRecorder records the arrivals and departures seen in live
+// traffic so that they can be exported as the starting point for a
+// scenario that reflects real-world traffic flows.
+type ScenarioRecorder struct {
+	Start   time.Time
+	flights map[string]*recordedFlight
+	order   []*recordedFlight // in the order they were first seen
+
+	dirDialog *FileSelectDialogBox
+	status    string
+}
+
+type recordedFlight struct {
+	callsign         string
+	aircraftType     string
+	departureAirport string
+	arrivalAirport   string
+	filedAltitude    int
+	route            string
+	firstSeen        time.Time
+	path             []LiveTrack
+}
+
+// Recorded paths are reduced to waypoints roughly this far apart, with at
+// most RecordedMaxWaypoints of them.
+const (
+	RecordedWaypointSpacing = 10 // nm
+	RecordedMaxWaypoints    = 25
+)
+
+func NewScenarioRecorder() *ScenarioRecorder {
+	return &ScenarioRecorder{
+		Start:   time.Now(),
+		flights: make(map[string]*recordedFlight),
+	}
+}
+
+// Record should be called with each new set of live traffic tracks.
+func (sr *ScenarioRecorder) Record(tracks []LiveTrack) {
+	for _, t := range tracks {
+		f, ok := sr.flights[t.Callsign]
+		if !ok {
+			f = &recordedFlight{callsign: t.Callsign, firstSeen: time.Now()}
+			sr.flights[t.Callsign] = f
+			sr.order = append(sr.order, f)
+		}
+
+		// Flight plans may show up after the aircraft does.
+		if t.DepartureAirport != "" || t.ArrivalAirport != "" {
+			f.aircraftType, f.departureAirport, f.arrivalAirport = t.AircraftType, t.DepartureAirport, t.ArrivalAirport
+			f.filedAltitude, f.route = t.FiledAltitude, t.Route
+		}
+
+		if n := len(f.path); n == 0 || nmdistance2ll(f.path[n-1].Position, t.Position) >= RecordedWaypointSpacing {
+			f.path = append(f.path, t)
+		}
+	}
+}
+
+// recordedAirline returns the airline ICAO code for an airline callsign
+// (e.g., "AAL" for AAL123) and an empty string for general aviation
+// callsigns.
+func recordedAirline(callsign string) string {
+	if len(callsign) < 4 || !isAllLetters(callsign[:3]) || callsign[3] < '0' || callsign[3] > '9' {
+		return ""
+	}
+	if _, ok := database.Airlines[callsign[:3]]; !ok {
+		return ""
+	}
+	return callsign[:3]
+}
+
+func isAllLetters(s string) bool {
+	for _, ch := range s {
+		if ch < 'A' || ch > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Export returns a scenario group fragment in the format of vice's
+// scenario JSON files with the recorded flows: arrivals are given routes
+// based on their recorded paths, departures are listed by airport, and
+// the arrival rates reflect what was observed. Callsigns are replaced with
+// airline codes, which is what scenarios specify anyway; a schedule of
+// when each flight appeared, with sequentially-numbered callsigns, is
+// included for reference. Departure exits and the arrivals' initial
+// controllers must be filled in by hand.
+func (sr *ScenarioRecorder) Export(w *World) ([]byte, error) {
+	type schedule struct {
+		Offset    string `json:"offset"`
+		Callsign  string `json:"callsign"`
+		Type      string `json:"type"`
+		Departure string `json:"departure"`
+		Arrival   string `json:"arrival"`
+	}
+	type departure struct {
+		Exit        string             `json:"exit"`
+		Destination string             `json:"destination"`
+		Altitude    int                `json:"altitude,omitempty"`
+		Route       string             `json:"route"`
+		Airlines    []DepartureAirline `json:"airlines"`
+	}
+	type arrival struct {
+		Waypoints       string                      `json:"waypoints"`
+		Route           string                      `json:"route,omitempty"`
+		CruiseAltitude  int                         `json:"cruise_altitude,omitempty"`
+		InitialAltitude int                         `json:"initial_altitude"`
+		InitialSpeed    int                         `json:"initial_speed"`
+		Description     string                      `json:"description"`
+		Airlines        map[string][]ArrivalAirline `json:"airlines"`
+	}
+
+	fixes := make(map[string]string)
+	arrivals := make(map[string][]arrival)
+	departures := make(map[string][]departure)
+	rates := make(map[string]int)
+	var sched []schedule
+	seq := make(map[string]int) // for sanitized callsigns
+
+	elapsed := time.Since(sr.Start)
+	for i, f := range sr.order {
+		airline := recordedAirline(f.callsign)
+		_, isDeparture := w.DepartureAirports[f.departureAirport]
+		_, isArrival := w.ArrivalAirports[f.arrivalAirport]
+		if !isDeparture && !isArrival {
+			continue
+		}
+
+		prefix := Select(airline != "", airline, "N")
+		seq[prefix]++
+		sched = append(sched, schedule{
+			Offset:    formatDuration(f.firstSeen.Sub(sr.Start)),
+			Callsign:  fmt.Sprintf("%s%d", prefix, seq[prefix]),
+			Type:      Select(isArrival, "arrival", "departure"),
+			Departure: f.departureAirport,
+			Arrival:   f.arrivalAirport,
+		})
+		if airline == "" {
+			// General aviation aircraft aren't spawned from airlines.
+			continue
+		}
+
+		if isArrival {
+			var wps []string
+			step := max(1, (len(f.path)+RecordedMaxWaypoints-1)/RecordedMaxWaypoints)
+			for j := 0; j < len(f.path); j += step {
+				fix := fmt.Sprintf("REC%03d%02d", i, len(wps))
+				fixes[fix] = f.path[j].Position.DMSString()
+				wps = append(wps, fix)
+			}
+			if len(wps) < 2 {
+				// Not enough of the route was seen to be useful.
+				continue
+			}
+
+			arrivals[f.arrivalAirport] = append(arrivals[f.arrivalAirport], arrival{
+				Waypoints:       strings.Join(wps, " "),
+				Route:           f.route,
+				CruiseAltitude:  f.filedAltitude,
+				InitialAltitude: 100 * int((f.path[0].Altitude+50)/100),
+				InitialSpeed:    int(f.path[0].Groundspeed),
+				Description:     "recorded " + f.firstSeen.UTC().Format("2006-01-02 1504Z"),
+				Airlines: map[string][]ArrivalAirline{
+					f.arrivalAirport: []ArrivalAirline{{ICAO: airline, Airport: f.departureAirport}},
+				},
+			})
+			rates[f.arrivalAirport]++
+		} else {
+			departures[f.departureAirport] = append(departures[f.departureAirport], departure{
+				Destination: f.arrivalAirport,
+				Altitude:    f.filedAltitude,
+				Route:       f.route,
+				Airlines:    []DepartureAirline{{ICAO: airline}},
+			})
+		}
+	}
+
+	// Convert counts to hourly rates.
+	for ap, n := range rates {
+		rates[ap] = max(1, int(float64(n)/elapsed.Hours()+0.5))
+	}
+
+	groups := make(map[string][]arrival)
+	groupRates := make(map[string]map[string]int)
+	for ap, arr := range arrivals {
+		groups["REC-"+ap] = arr
+		groupRates["REC-"+ap] = map[string]int{ap: rates[ap]}
+	}
+	airports := make(map[string]map[string][]departure)
+	for ap, dep := range departures {
+		airports[ap] = map[string][]departure{"departures": dep}
+	}
+
+	return json.MarshalIndent(struct {
+		Fixes         map[string]string                 `json:"fixes"`
+		ArrivalGroups map[string][]arrival              `json:"arrival_groups"`
+		Airports      map[string]map[string][]departure `json:"airports"`
+		Scenarios     map[string]interface{}            `json:"scenarios"`
+		Schedule      []schedule                        `json:"recorded_schedule"`
+	}{
+		Fixes:         fixes,
+		ArrivalGroups: groups,
+		Airports:      airports,
+		Scenarios: map[string]interface{}{
+			"Recorded " + sr.Start.UTC().Format("2006-01-02 1504Z"): map[string]interface{}{
+				"arrivals": groupRates,
+			},
+		},
+		Schedule: sched,
+	}, "", "    ")
+}
+
+func (sr *ScenarioRecorder) NumFlights() int {
+	return len(sr.order)
+}
+
+func (sr *ScenarioRecorder) DrawUI(w *World) {
+	imgui.Text(fmt.Sprintf("Recorded %d aircraft over %s", sr.NumFlights(), formatDuration(time.Since(sr.Start))))
+	if imgui.Button("Export scenario...") {
+		sr.dirDialog = NewDirectorySelectDialogBox("Save recorded scenario to...", "",
+			func(dir string) {
+				fn := path.Join(dir, "vice-recorded-"+time.Now().Format("20060102-150405")+".json")
+				b, err := sr.Export(w)
+				if err == nil {
+					err = os.WriteFile(fn, b, 0o644)
+				}
+				if err != nil {
+					lg.Errorf("%s: %v", fn, err)
+					sr.status = "Error: " + err.Error()
+				} else {
+					sr.status = "Saved " + fn
+				}
+			})
+		sr.dirDialog.Activate()
+	}
+	if sr.status != "" {
+		imgui.Text(sr.status)
+	}
+	if sr.dirDialog != nil {
+		sr.dirDialog.Draw()
+	}
+}
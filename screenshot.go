@@ -0,0 +1,209 @@
+// screenshot.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Screenshots of a pane or of the entire window can be taken with a key
+// command so that setups and situations can be shared, e.g., on Discord.
+// They're saved as PNG files and copied to the clipboard. Optionally, the
+// callsigns shown in datablocks and flight strips are replaced with
+// made-up ones in the screenshot.
+
+type ScreenshotConfig struct {
+	// Directory to save screenshots in; if empty, they're saved in the
+	// "screenshots" directory next to the config file.
+	Directory          string
+	AnonymizeCallsigns bool
+
+	// UI state
+	dirDialog *FileSelectDialogBox
+}
+
+var screenshot struct {
+	requested bool
+	pane      Pane // nil for the entire window
+	// Set for the frame that's captured; callsigns are anonymized while
+	// it's drawn, if enabled.
+	capturing bool
+	// Random salt so that anonymized callsigns differ across sessions.
+	salt uint32
+}
+
+func (sc *ScreenshotConfig) directory() string {
+	if sc.Directory != "" {
+		return sc.Directory
+	}
+	return path.Join(path.Dir(configFilePath()), "screenshots")
+}
+
+// requestScreenshot requests a screenshot of the given pane, or of the
+// entire window if pane is nil. It's captured at the end of the next frame.
+func requestScreenshot(pane Pane) {
+	screenshot.requested, screenshot.pane = true, pane
+}
+
+// screenshotCallsign returns the callsign to draw for the given one; it's
+// anonymized if a screenshot is being captured and the user has asked for
+// that.
+func screenshotCallsign(callsign string) string {
+	if !screenshot.capturing || !globalConfig.Screenshots.AnonymizeCallsigns {
+		return callsign
+	}
+
+	if screenshot.salt == 0 {
+		screenshot.salt = uint32(time.Now().UnixNano()) | 1
+	}
+	h := fnv.New32a()
+	h.Write([]byte(callsign))
+	v := h.Sum32() ^ screenshot.salt
+
+	// Keep the form of the callsign, replacing letters with letters and
+	// digits with digits, so that it still looks like a callsign.
+	anon := []byte(callsign)
+	for i, ch := range anon {
+		if ch >= 'A' && ch <= 'Z' {
+			anon[i] = 'A' + byte(v%26)
+			v = v/26 + uint32(i+1)*2654435761
+		} else if ch >= '0' && ch <= '9' {
+			anon[i] = '0' + byte(v%10)
+			v = v/10 + uint32(i+1)*2654435761
+		}
+	}
+	return string(anon)
+}
+
+// updateScreenshot should be called after the panes have been drawn
+// (with afterUI false) and again after the UI has been drawn (with
+// afterUI true). When a screenshot has been requested, the following
+// frame is drawn for it and then captured.
+func updateScreenshot(p Platform, r Renderer, afterUI bool, eventStream *EventStream) {
+	if !screenshot.requested {
+		return
+	}
+	if !screenshot.capturing {
+		if !afterUI {
+			screenshot.capturing = true
+		}
+		return
+	}
+	if afterUI != (screenshot.pane == nil) {
+		// Panes are captured before the UI is drawn on top of them.
+		return
+	}
+
+	var img *image.RGBA
+	if screenshot.pane == nil {
+		fb := p.FramebufferSize()
+		img = r.ReadPixels(0, 0, int(fb[0]), int(fb[1]))
+	} else if extent, ok := wm.paneExtents[screenshot.pane]; ok {
+		img = readPanePixels(p, r, extent)
+	}
+	screenshot.requested, screenshot.capturing, screenshot.pane = false, false, nil
+	if img == nil {
+		return
+	}
+
+	dir := globalConfig.Screenshots.directory()
+	saved, failed, copied := Tr("Saved screenshot"), Tr("Unable to save screenshot"), Tr("and copied it to the clipboard")
+	go func() {
+		fn, err := saveScreenshot(img, dir)
+		msg := saved + " " + fn
+		if err != nil {
+			lg.Errorf("screenshot: %v", err)
+			msg = failed + ": " + err.Error()
+		} else if err := copyImageToClipboard(fn); err != nil {
+			lg.Warnf("%s: unable to copy to clipboard: %v", fn, err)
+		} else {
+			msg += " " + copied
+		}
+		eventStream.Post(Event{Type: StatusMessageEvent, Message: msg})
+	}()
+}
+
+func saveScreenshot(img *image.RGBA, dir string) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(dir, "vice-"+time.Now().Format("20060102-150405")+".png")
+	return fn, os.WriteFile(fn, buf.Bytes(), 0o644)
+}
+
+// copyImageToClipboard copies the given PNG file to the system clipboard.
+// Neither GLFW nor imgui support images on the clipboard, so this is done
+// using each system's command-line tools.
+func copyImageToClipboard(fn string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e",
+			fmt.Sprintf("set the clipboard to (read (POSIX file %q) as «class PNGf»)", fn))
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command",
+			"Add-Type -AssemblyName System.Windows.Forms,System.Drawing; "+
+				fmt.Sprintf("[System.Windows.Forms.Clipboard]::SetImage([System.Drawing.Image]::FromFile('%s'))",
+					strings.ReplaceAll(fn, "'", "''")))
+	default:
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			cmd = exec.Command("wl-copy", "--type", "image/png")
+		} else {
+			cmd = exec.Command("xclip", "-selection", "clipboard", "-t", "image/png", "-i")
+		}
+		cmd.Stdin = f
+	}
+
+	// xclip and wl-copy fork a process that stays in the background to
+	// serve the clipboard once they have read the image; the command
+	// itself exits then, so Run doesn't block. (Their output mustn't be
+	// captured, however, since the background process holds on to it.)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", cmd.Path, err)
+	}
+	return nil
+}
+
+func (sc *ScreenshotConfig) DrawUI() {
+	imgui.Checkbox(Tr("Anonymize callsigns in screenshots"), &sc.AnonymizeCallsigns)
+
+	imgui.Text(Tr("Screenshot directory") + ": " + sc.directory())
+	imgui.SameLine()
+	if imgui.Button(Tr("Choose...")) {
+		sc.dirDialog = NewDirectorySelectDialogBox(Tr("Select screenshot directory..."), sc.Directory,
+			func(dir string) { sc.Directory = dir })
+		sc.dirDialog.Activate()
+	}
+	if sc.dirDialog != nil {
+		sc.dirDialog.Draw()
+	}
+
+	if imgui.Button(Tr("Take screenshot")) {
+		requestScreenshot(nil)
+	}
+	imgui.Text(Tr("Screenshots can also be taken with the screenshot key commands."))
+}
@@ -0,0 +1,191 @@
+// sdf.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"image"
+	"image/color"
+)
+
+// Text drawn with bitmap fonts can only be drawn at the sizes that the
+// fonts were rasterized at, and, since the atlas textures use nearest
+// filtering, it shimmers when drawn at fractional pixel positions (e.g.,
+// as the scope is panned). Therefore, each font also has a "scalable"
+// version that stores a signed distance field (SDF) for each glyph: the
+// alpha channel stores the distance to the glyph's edge, remapped so that
+// 0.5 is on the edge. With bilinear texture filtering and an alpha test
+// at 0.5, this gives crisp glyph edges at any scale and smooth motion at
+// sub-pixel positions. (See Green, "Improved Alpha-Tested Magnification
+// for Vector Textures and Special Effects", SIGGRAPH 2007.)
+//
+// Scalable text is drawn by setting TextStyle.Scale.
+
+// Maximum distance in pixels of the source glyphs that is represented in
+// the distance field; it also gives the padding around each glyph.
+const sdfSpread = 4
+
+// Width of the SDF atlas textures; their height is set as needed.
+const sdfAtlasWidth = 1024
+
+// sdfGlyphSource gives the source bitmap for a single glyph.
+type sdfGlyphSource struct {
+	ch    rune
+	glyph *Glyph
+	rect  image.Rectangle // in the source atlas
+}
+
+// buildScalableFonts computes an SDF atlas from the glyphs of the font src,
+// whose glyph bitmaps are in the given atlas image. It then sets the
+// scalable fonts for src and the given fonts, which should be other sizes
+// of the same font; their glyph metrics are scaled to match their sizes.
+func buildScalableFonts(r Renderer, atlas *image.RGBA, src *Font, sizes []*Font) {
+	// Gather the glyphs; imgui fonts create their Glyphs lazily, so make
+	// sure that the ASCII ones are there.
+	if src.ifont != 0 {
+		for ch := rune(32); ch < 127; ch++ {
+			src.LookupGlyph(ch)
+		}
+	}
+	var glyphs []sdfGlyphSource
+	addGlyph := func(ch rune, g *Glyph) {
+		if g == nil || !g.Visible {
+			return
+		}
+		b := atlas.Bounds()
+		rect := image.Rect(int(g.U0*float32(b.Dx())+0.5), int(g.V0*float32(b.Dy())+0.5),
+			int(g.U1*float32(b.Dx())+0.5), int(g.V1*float32(b.Dy())+0.5))
+		glyphs = append(glyphs, sdfGlyphSource{ch: ch, glyph: g, rect: rect})
+	}
+	for ch, g := range src.lowGlyphs {
+		addGlyph(rune(ch), g)
+	}
+	for _, ch := range SortedMapKeys(src.glyphs) {
+		addGlyph(ch, src.glyphs[ch])
+	}
+	if len(glyphs) == 0 {
+		return
+	}
+
+	// Lay out the padded glyphs in rows to find the size of the atlas.
+	type placement struct{ x, y int }
+	places := make([]placement, len(glyphs))
+	x, y, rowHeight := 0, 0, 0
+	for i, g := range glyphs {
+		w, h := g.rect.Dx()+2*sdfSpread, g.rect.Dy()+2*sdfSpread
+		if x+w > sdfAtlasWidth {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+		places[i] = placement{x, y}
+		x += w
+		rowHeight = max(rowHeight, h)
+	}
+	res := [2]int{sdfAtlasWidth, y + rowHeight}
+
+	sdf := image.NewRGBA(image.Rectangle{Max: image.Point{X: res[0], Y: res[1]}})
+	for i, g := range glyphs {
+		computeGlyphSDF(atlas, g.rect, sdf, places[i].x, places[i].y)
+	}
+	texId := r.CreateTextureFromImage(sdf, false /* linear filtering */)
+
+	for _, f := range append([]*Font{src}, sizes...) {
+		// Scale factor from the source font's metrics to this one's.
+		s := float32(f.size) / float32(src.size)
+		sf := &Font{
+			glyphs: make(map[rune]*Glyph),
+			size:   f.size,
+			mono:   f.mono,
+			id:     f.id,
+			texId:  texId,
+		}
+		for i, g := range glyphs {
+			sg := g.glyph
+			pad := float32(sdfSpread)
+			p := places[i]
+			ng := &Glyph{
+				X0:       s * (sg.X0 - pad),
+				Y0:       s * (sg.Y0 - pad),
+				X1:       s * (sg.X1 + pad),
+				Y1:       s * (sg.Y1 + pad),
+				U0:       float32(p.x) / float32(res[0]),
+				V0:       float32(p.y) / float32(res[1]),
+				U1:       float32(p.x+g.rect.Dx()+2*sdfSpread) / float32(res[0]),
+				V1:       float32(p.y+g.rect.Dy()+2*sdfSpread) / float32(res[1]),
+				AdvanceX: s * sg.AdvanceX,
+				Visible:  true,
+			}
+			if g.ch < 128 {
+				sf.lowGlyphs[g.ch] = ng
+			} else {
+				sf.glyphs[g.ch] = ng
+			}
+		}
+		// Invisible glyphs (space, etc.) still need to advance the cursor.
+		for ch, g := range src.lowGlyphs {
+			if g != nil && !g.Visible {
+				sf.lowGlyphs[ch] = &Glyph{AdvanceX: s * g.AdvanceX}
+			}
+		}
+		f.scalable = sf
+	}
+}
+
+// computeGlyphSDF computes the signed distance field for the glyph in the
+// given rectangle of the source atlas and stores it in dst with its
+// upper-left corner at (dx, dy). The glyph is padded by sdfSpread pixels
+// on each side.
+func computeGlyphSDF(src *image.RGBA, rect image.Rectangle, dst *image.RGBA, dx, dy int) {
+	// Find which pixels are inside the glyph, including the padding, so
+	// that the search below is just array lookups.
+	w, h := rect.Dx()+2*sdfSpread, rect.Dy()+2*sdfSpread
+	mask := make([]bool, w*h)
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			mask[(y+sdfSpread)*w+x+sdfSpread] = src.RGBAAt(rect.Min.X+x, rect.Min.Y+y).A >= 128
+		}
+	}
+	inside := func(x, y int) bool {
+		return x >= 0 && x < w && y >= 0 && y < h && mask[y*w+x]
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			in := mask[y*w+x]
+
+			// Brute-force search for the closest pixel with the opposite
+			// state; the glyphs are small enough that this is fine.
+			d2 := sdfSpread * sdfSpread * 2
+			for oy := -sdfSpread; oy <= sdfSpread; oy++ {
+				for ox := -sdfSpread; ox <= sdfSpread; ox++ {
+					if r2 := ox*ox + oy*oy; r2 < d2 && inside(x+ox, y+oy) != in {
+						d2 = r2
+					}
+				}
+			}
+
+			// The edge is half a pixel from the center of the closest
+			// pixel with the opposite state.
+			d := sqrt(float32(d2)) - 0.5
+			v := clamp(0.5+Select(in, d, -d)/(2*sdfSpread), 0, 1)
+			dst.SetRGBA(dx+x, dy+y, color.RGBA{R: 255, G: 255, B: 255, A: uint8(255 * v)})
+		}
+	}
+}
+
+var sdfMissingGlyph = &Glyph{}
+
+// lookupScalableGlyph returns the glyph for the given rune in a scalable
+// font; runes that don't have SDF glyphs (e.g., icons) are skipped.
+func (f *Font) lookupScalableGlyph(ch rune) *Glyph {
+	var g *Glyph
+	if int(ch) < len(f.lowGlyphs) {
+		g = f.lowGlyphs[ch]
+	} else {
+		g = f.glyphs[ch]
+	}
+	if g == nil {
+		return sdfMissingGlyph
+	}
+	return g
+}
@@ -0,0 +1,212 @@
+// searchpalette.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// SearchPalette is a window, opened with Control-P, that fuzzily matches
+// what the user types against aircraft callsigns, fixes, airports, and UI
+// commands. Pressing Enter selects and centers the scope on the aircraft
+// or location, or runs the command.
+type SearchPalette struct {
+	query       string
+	lastQuery   string
+	results     []searchResult
+	selected    int
+	takeFocus   bool
+	eventStream *EventStream
+}
+
+type searchResult struct {
+	label  string
+	kind   string
+	score  int
+	action func()
+}
+
+// The palette is opened with Control-P; 80 is GLFW's key code for P (see
+// GLFWPlatform's key mapping for imgui).
+const imguiKeyP = 80
+
+// Fixes and airports farther than this from the scope center aren't
+// offered as search results.
+const SearchPaletteFixRadius = 250 // nm
+
+// Maximum number of results shown.
+const SearchPaletteMaxResults = 15
+
+func NewSearchPalette(eventStream *EventStream) *SearchPalette {
+	return &SearchPalette{takeFocus: true, eventStream: eventStream}
+}
+
+// searchCenterScopes centers all of the STARS scopes on the given point.
+func searchCenterScopes(p Point2LL) {
+	globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+		if sp, ok := pane.(*STARSPane); ok {
+			sp.CurrentPreferenceSet.CurrentCenter = p
+		}
+	})
+}
+
+func (sp *SearchPalette) commands(w *World, p Platform) []searchResult {
+	cmd := func(label string, action func()) searchResult {
+		return searchResult{label: label, kind: "Command", action: action}
+	}
+	cmds := []searchResult{
+		cmd("Open settings window", w.ToggleActivateSettingsWindow),
+		cmd("Show scenario information", w.ToggleShowScenarioInfoWindow),
+		cmd("Show position relief briefing", func() { ui.showReliefBriefing = !ui.showReliefBriefing }),
+		cmd("Show keyboard commands", uiToggleShowKeyboardWindow),
+		cmd("Show rendering performance statistics", func() { ui.showPerformanceWindow = !ui.showPerformanceWindow }),
+		cmd("Show about dialog", func() { ui.showAboutDialog = !ui.showAboutDialog }),
+		cmd("Toggle full-screen mode", func() { p.EnableFullScreen(!p.IsFullScreen()) }),
+		cmd("Start new simulation", func() { uiShowConnectDialog(true) }),
+	}
+	if profiler != nil {
+		cmds = append(cmds, cmd("Show profiler overlay", func() { ui.showProfiler = !ui.showProfiler }))
+	}
+	if w.Connected() {
+		cmds = append(cmds, cmd(Select(w.SimIsPaused, "Resume simulation", "Pause simulation"), w.ToggleSimPause))
+	}
+	if w.LaunchConfig.Controller == w.Callsign {
+		cmds = append(cmds, cmd("Show instructor console", func() { ui.showInstructorConsole = true }))
+	}
+	return cmds
+}
+
+// search updates the results to reflect the current query.
+func (sp *SearchPalette) search(w *World, p Platform) {
+	sp.results, sp.selected = nil, 0
+	if sp.query == "" {
+		return
+	}
+
+	add := func(label, kind string, action func()) {
+		if score, ok := fuzzyMatch(sp.query, label); ok {
+			sp.results = append(sp.results, searchResult{label: label, kind: kind, score: score, action: action})
+		}
+	}
+	locate := func(pos Point2LL) func() {
+		return func() {
+			HighlightLocation(pos, 5*time.Second)
+			searchCenterScopes(pos)
+		}
+	}
+
+	for callsign, ac := range w.Aircraft {
+		add(callsign, "Aircraft", func() {
+			sp.eventStream.Post(Event{Type: SelectedAircraftEvent, Callsign: ac.Callsign})
+			HighlightLocation(ac.Position(), 3*time.Second)
+			searchCenterScopes(ac.Position())
+		})
+	}
+
+	// Fixes and airports are only matched once the query is long enough
+	// that there's a chance of the results being useful.
+	if len(sp.query) >= 2 {
+		center := w.GetInitialCenter()
+		seen := make(map[string]interface{})
+		addLocation := func(name, kind string, pos Point2LL) {
+			if _, ok := seen[name]; ok || nmdistance2ll(center, pos) > SearchPaletteFixRadius {
+				return
+			}
+			seen[name] = nil
+			add(name, kind, locate(pos))
+		}
+
+		for name, ap := range w.AllAirports() {
+			addLocation(name, "Airport", ap.Location)
+		}
+		for name, pos := range w.Fixes {
+			addLocation(name, "Fix", pos)
+		}
+		for name, nav := range database.Navaids {
+			addLocation(name, "Navaid", nav.Location)
+		}
+		for name, ap := range database.Airports {
+			addLocation(name, "Airport", ap.Location)
+		}
+		for name, fix := range database.Fixes {
+			addLocation(name, "Fix", fix.Location)
+		}
+	}
+
+	for _, c := range sp.commands(w, p) {
+		add(c.label, c.kind, c.action)
+	}
+
+	slices.SortFunc(sp.results, func(a, b searchResult) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if a.label < b.label {
+			return -1
+		} else if a.label > b.label {
+			return 1
+		}
+		return 0
+	})
+	if len(sp.results) > SearchPaletteMaxResults {
+		sp.results = sp.results[:SearchPaletteMaxResults]
+	}
+}
+
+// Draw draws the palette and handles user input; it returns false once
+// the palette should be closed.
+func (sp *SearchPalette) Draw(w *World, p Platform) bool {
+	open := true
+	ds := p.DisplaySize()
+	imgui.SetNextWindowPosV(imgui.Vec2{ds[0] / 2, ds[1] / 4}, imgui.ConditionAppearing, imgui.Vec2{0.5, 0})
+	imgui.SetNextWindowSizeV(imgui.Vec2{500, 0}, imgui.ConditionAppearing)
+	imgui.BeginV("Search", &open, imgui.WindowFlagsNoCollapse|imgui.WindowFlagsNoSavedSettings)
+
+	if sp.takeFocus {
+		imgui.SetKeyboardFocusHere()
+		sp.takeFocus = false
+	}
+	enter := imgui.InputTextV("##query", &sp.query, imgui.InputTextFlagsEnterReturnsTrue, nil)
+	if sp.query != sp.lastQuery {
+		sp.search(w, p)
+		sp.lastQuery = sp.query
+	}
+
+	if imgui.IsKeyPressed(imgui.GetKeyIndex(imgui.KeyDownArrow)) {
+		sp.selected = max(0, min(sp.selected+1, len(sp.results)-1))
+	}
+	if imgui.IsKeyPressed(imgui.GetKeyIndex(imgui.KeyUpArrow)) {
+		sp.selected = max(sp.selected-1, 0)
+	}
+	if imgui.IsKeyPressed(imgui.GetKeyIndex(imgui.KeyEscape)) {
+		open = false
+	}
+
+	run := -1
+	for i, r := range sp.results {
+		if imgui.SelectableV(r.label+"  ("+r.kind+")", i == sp.selected, 0, imgui.Vec2{}) {
+			run = i
+		}
+	}
+	if enter {
+		if sp.selected < len(sp.results) {
+			run = sp.selected
+		} else {
+			// Keep the focus in the input field if there was nothing to
+			// run.
+			sp.takeFocus = true
+		}
+	}
+	if run != -1 {
+		sp.results[run].action()
+		open = false
+	}
+
+	imgui.End()
+	return open
+}
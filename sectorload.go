@@ -0,0 +1,227 @@
+// sectorload.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// SectorLoadPane
+
+// SectorLoadPane graphs the number of aircraft in the user's airspace now
+// and projected over the coming minutes, so that it's evident ahead of
+// time when a sector split should be requested. Each aircraft is
+// projected along its remaining route at its current groundspeed (or
+// along its current heading once the route runs out), climbing or
+// descending toward its assigned altitude or its route's next altitude
+// restriction; aircraft that haven't entered the system yet aren't
+// counted.
+type SectorLoadPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+
+	// Time in the future that is projected, in minutes.
+	Horizon int
+	// Counts at or above this are drawn in the caution color.
+	SplitThreshold int
+}
+
+const (
+	DefaultSectorLoadHorizon   = 45
+	DefaultSectorLoadThreshold = 12
+	// Assumed climb and descent rate for the projection.
+	sectorLoadVerticalRate = 1500 // fpm
+)
+
+func NewSectorLoadPane() *SectorLoadPane {
+	return &SectorLoadPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+		Horizon:        DefaultSectorLoadHorizon,
+		SplitThreshold: DefaultSectorLoadThreshold,
+	}
+}
+
+func (sl *SectorLoadPane) Name() string { return "Sector Load" }
+
+func (sl *SectorLoadPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if sl.font = GetFont(sl.FontIdentifier); sl.font == nil {
+		sl.font = GetDefaultFont()
+		sl.FontIdentifier = sl.font.id
+	}
+	if sl.Horizon == 0 {
+		sl.Horizon = DefaultSectorLoadHorizon
+	}
+	if sl.SplitThreshold == 0 {
+		sl.SplitThreshold = DefaultSectorLoadThreshold
+	}
+}
+
+func (sl *SectorLoadPane) Deactivate()                {}
+func (sl *SectorLoadPane) ResetWorld(w *World)        {}
+func (sl *SectorLoadPane) CanTakeKeyboardFocus() bool { return false }
+
+func (sl *SectorLoadPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&sl.FontIdentifier, "Font"); changed {
+		sl.font = newFont
+	}
+	horizon := int32(sl.Horizon)
+	imgui.SliderInt("Time horizon (minutes)", &horizon, 30, 60)
+	sl.Horizon = int(horizon)
+	threshold := int32(sl.SplitThreshold)
+	imgui.SliderInt("Split threshold (aircraft)", &threshold, 1, 40)
+	sl.SplitThreshold = int(threshold)
+}
+
+// projectPosition returns where the aircraft is expected to be after the
+// given time.
+func projectPosition(ac *Aircraft, dt time.Duration, nmPerLongitude, magneticVariation float32) (Point2LL, float32) {
+	p := ll2nm(ac.Position(), nmPerLongitude)
+	alt := ac.Altitude()
+	dist := ac.GS() * float32(dt.Hours())
+
+	hdg := ac.Heading() - magneticVariation
+	v := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
+
+	// The altitude the aircraft is working toward; without an assigned
+	// altitude, it's the first altitude restriction in its route.
+	target := alt
+	if a := ac.Nav.Altitude.Assigned; a != nil {
+		target = *a
+	} else if idx := slices.IndexFunc(ac.Nav.Waypoints, func(wp Waypoint) bool { return wp.AltitudeRestriction != nil }); idx != -1 {
+		target = ac.Nav.Waypoints[idx].AltitudeRestriction.TargetAltitude(alt)
+	} else if a := ac.Nav.Altitude.Cleared; a != nil {
+		target = *a
+	}
+	dalt := sectorLoadVerticalRate * float32(dt.Minutes())
+	if alt < target {
+		alt = min(alt+dalt, target)
+	} else {
+		alt = max(alt-dalt, target)
+	}
+
+	for _, wp := range ac.Nav.Waypoints {
+		wpn := ll2nm(wp.Location, nmPerLongitude)
+		d := distance2f(p, wpn)
+		if d > dist {
+			return nm2ll(add2f(p, scale2f(normalize2f(sub2f(wpn, p)), dist)), nmPerLongitude), alt
+		}
+		if d > 0 {
+			v = normalize2f(sub2f(wpn, p))
+		}
+		p, dist = wpn, dist-d
+	}
+	return nm2ll(add2f(p, scale2f(v, dist)), nmPerLongitude), alt
+}
+
+// sectorCounts returns the number of aircraft projected to be in the
+// user's airspace at each minute from now through the horizon.
+func (sl *SectorLoadPane) sectorCounts(w *World) []int {
+	volumes := append(append([]ControllerAirspaceVolume{}, w.ApproachAirspace...), w.DepartureAirspace...)
+
+	counts := make([]int, sl.Horizon+1)
+	for _, ac := range w.Aircraft {
+		if !ac.IsAirborne() {
+			continue
+		}
+		for m := range counts {
+			p, alt := projectPosition(ac, time.Duration(m)*time.Minute, w.NmPerLongitude, w.MagneticVariation)
+			if in, _ := InAirspace(p, alt, volumes); in {
+				counts[m]++
+			}
+		}
+	}
+	return counts
+}
+
+func (sl *SectorLoadPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	trid := GetColoredTrianglesDrawBuilder()
+	defer ReturnColoredTrianglesDrawBuilder(trid)
+
+	textStyle := TextStyle{Font: sl.font, Color: UITextColor}
+	lineHeight := float32(sl.font.size + 1)
+	width, height := ctx.paneExtent.Width(), ctx.paneExtent.Height()
+
+	if len(ctx.world.ApproachAirspace) == 0 && len(ctx.world.DepartureAirspace) == 0 {
+		td.AddText("No airspace defined", [2]float32{0, height - 1}, textStyle)
+		ctx.SetWindowCoordinateMatrices(cb)
+		td.GenerateCommands(cb)
+		return
+	}
+
+	counts := sl.sectorCounts(ctx.world)
+	peak, peakMinute := 0, 0
+	for m, c := range counts {
+		if c > peak {
+			peak, peakMinute = c, m
+		}
+	}
+	summary := fmt.Sprintf("NOW %d  PEAK %d AT +%d MIN", counts[0], peak, peakMinute)
+	td.AddText(summary, [2]float32{0, height - 1},
+		Select(peak >= sl.SplitThreshold, TextStyle{Font: sl.font, Color: UICautionColor}, textStyle))
+
+	// Minutes from now run along the x axis and the aircraft count is
+	// along y; leave room for labels at the left, top, and bottom.
+	bx, _ := sl.font.BoundText("XX", 0)
+	x0, x1 := float32(bx)+4, width-4
+	y0, y1 := 1.5*lineHeight, height-1.5*lineHeight
+	if x1 <= x0 || y1 <= y0 {
+		return
+	}
+	maxCount := max(peak, sl.SplitThreshold) + 1
+	xForMinute := func(m int) float32 { return lerp(float32(m)/float32(sl.Horizon), x0, x1) }
+	yForCount := func(c int) float32 { return lerp(float32(c)/float32(maxCount), y0, y1) }
+
+	// Axes, count labels, and minute ticks
+	ld.AddLine([2]float32{x0, y0}, [2]float32{x1, y0}, UITextColor)
+	ld.AddLine([2]float32{x0, y0}, [2]float32{x0, y1}, UITextColor)
+	step := max(1, maxCount/5)
+	for c := step; c < maxCount; c += step {
+		y := yForCount(c)
+		ld.AddLine([2]float32{x0, y}, [2]float32{x1, y}, UIControlColor)
+		td.AddText(fmt.Sprintf("%2d", c), [2]float32{0, y + lineHeight/2}, textStyle)
+	}
+	for m := 0; m <= sl.Horizon; m += 5 {
+		x := xForMinute(m)
+		ld.AddLine([2]float32{x, y0}, [2]float32{x, y0 - 4}, UITextColor)
+		if m%15 == 0 {
+			td.AddTextCentered(fmt.Sprintf("%d", m), [2]float32{x, y0 - 4 - lineHeight/2}, textStyle)
+		}
+	}
+
+	// One bar per minute
+	bw := (x1 - x0) / float32(len(counts))
+	for m, c := range counts {
+		if c == 0 {
+			continue
+		}
+		color := Select(c >= sl.SplitThreshold, UICautionColor, UITextHighlightColor.Scale(0.6))
+		xa := x0 + float32(m)*bw + 1
+		xb := xa + max(bw-2, 1)
+		y := yForCount(c)
+		trid.AddQuad([2]float32{xa, y0}, [2]float32{xb, y0}, [2]float32{xb, y}, [2]float32{xa, y}, color)
+	}
+
+	// The split threshold
+	yt := yForCount(sl.SplitThreshold)
+	ld.AddLine([2]float32{x0, yt}, [2]float32{x1, yt}, UIErrorColor)
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	trid.GenerateCommands(cb)
+	ld.GenerateCommands(cb)
+	td.GenerateCommands(cb)
+}
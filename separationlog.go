@@ -0,0 +1,211 @@
+// separationlog.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Converging aircraft that are within this distance beyond the lateral
+// minimum (and not vertically separated) are logged as separation
+// warnings even if separation isn't actually lost.
+const SeparationWarningBuffer = 1.5 // nm
+
+// SeparationEvent records a pair of aircraft that lost or nearly lost
+// separation.
+type SeparationEvent struct {
+	Callsigns  [2]string // sorted alphabetically
+	Start, End time.Time // sim time; End is zero while the event is ongoing
+	Violation  bool      // separation was actually lost at some point
+
+	// Lateral and vertical separation at the point of closest approach,
+	// along with the aircraft positions and altitudes at that time.
+	MinDistance  float32 // nm
+	MinVertical  int     // feet
+	ClosestTime  time.Time
+	Positions    [2]Point2LL
+	Altitudes    [2]int
+	ReplayFrames [2]int // timelapse frames at the start and the closest approach; -1 if not recording
+}
+
+func (e *SeparationEvent) Duration(now time.Time) time.Duration {
+	if e.End.IsZero() {
+		return now.Sub(e.Start)
+	}
+	return e.End.Sub(e.Start)
+}
+
+// SeparationSample describes a pair of aircraft that are currently too
+// close; it is provided to SeparationLog.Update.
+type SeparationSample struct {
+	Callsigns [2]string
+	Positions [2]Point2LL
+	Altitudes [2]int
+	Violation bool
+}
+
+// SeparationLog keeps a record of all of the separation warnings and
+// violations over the course of a session so that they can be reviewed
+// afterward.
+type SeparationLog struct {
+	Events []*SeparationEvent
+	active map[[2]string]*SeparationEvent
+}
+
+func NewSeparationLog() *SeparationLog {
+	return &SeparationLog{active: make(map[[2]string]*SeparationEvent)}
+}
+
+// Update should be called after each radar track update with all of the
+// pairs of aircraft that are currently in conflict. replayFrame gives the
+// index of the current timelapse frame, if one is being recorded, and -1
+// otherwise.
+func (sl *SeparationLog) Update(now time.Time, samples []SeparationSample, replayFrame int) {
+	seen := make(map[[2]string]interface{})
+	for _, s := range samples {
+		seen[s.Callsigns] = nil
+
+		e, ok := sl.active[s.Callsigns]
+		if !ok {
+			e = &SeparationEvent{
+				Callsigns:    s.Callsigns,
+				Start:        now,
+				MinDistance:  1e30,
+				ReplayFrames: [2]int{replayFrame, replayFrame},
+			}
+			sl.active[s.Callsigns] = e
+			sl.Events = append(sl.Events, e)
+			lg.Infof("%s/%s: separation event started", s.Callsigns[0], s.Callsigns[1])
+		}
+
+		e.Violation = e.Violation || s.Violation
+		if d := nmdistance2ll(s.Positions[0], s.Positions[1]); d < e.MinDistance {
+			e.MinDistance = d
+			e.MinVertical = abs(s.Altitudes[0] - s.Altitudes[1])
+			e.ClosestTime = now
+			e.Positions = s.Positions
+			e.Altitudes = s.Altitudes
+			e.ReplayFrames[1] = replayFrame
+		}
+	}
+
+	for callsigns, e := range sl.active {
+		if _, ok := seen[callsigns]; !ok {
+			e.End = now
+			delete(sl.active, callsigns)
+		}
+	}
+}
+
+// Counts returns the number of warnings and violations in the log.
+func (sl *SeparationLog) Counts() (warnings, violations int) {
+	for _, e := range sl.Events {
+		if e.Violation {
+			violations++
+		} else {
+			warnings++
+		}
+	}
+	return
+}
+
+// Report returns a text report of all of the events in the log suitable
+// for debriefing after a session.
+func (sl *SeparationLog) Report(callsign string, now time.Time) string {
+	var b strings.Builder
+	warnings, violations := sl.Counts()
+	fmt.Fprintf(&b, "SEPARATION REPORT: %s %s\n", callsign, now.UTC().Format("2006-01-02 1504Z"))
+	fmt.Fprintf(&b, "%d violations, %d warnings\n", violations, warnings)
+
+	for i, e := range sl.Events {
+		ts := func(t time.Time) string { return t.UTC().Format("15:04:05Z") }
+		what := Select(e.Violation, "VIOLATION", "WARNING")
+
+		fmt.Fprintf(&b, "\n%d. %s %s / %s\n", i+1, what, e.Callsigns[0], e.Callsigns[1])
+		fmt.Fprintf(&b, "   Start %s, duration %s", ts(e.Start), formatDuration(e.Duration(now)))
+		if e.End.IsZero() {
+			b.WriteString(" (ongoing)")
+		}
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "   Closest approach at %s: %.2f nm, %d ft\n", ts(e.ClosestTime), e.MinDistance,
+			e.MinVertical)
+		for j := range e.Callsigns {
+			fmt.Fprintf(&b, "     %-8s %s %s\n", e.Callsigns[j], e.Positions[j].DMSString(),
+				FormatAltitude(float32(e.Altitudes[j])))
+		}
+		if e.ReplayFrames[0] != -1 {
+			fmt.Fprintf(&b, "   Timelapse frames %d-%d\n", e.ReplayFrames[0], e.ReplayFrames[1])
+		}
+	}
+	return b.String()
+}
+
+// WriteReport writes the report to a file in the given directory and
+// returns its name.
+func (sl *SeparationLog) WriteReport(dir, callsign string, now time.Time) (string, error) {
+	fn := path.Join(dir, "vice-separation-"+time.Now().Format("20060102-150405")+".txt")
+	return fn, os.WriteFile(fn, []byte(sl.Report(callsign, now)), 0o644)
+}
+
+// updateSeparationLog finds the pairs of visible aircraft that have lost
+// or nearly lost separation and records them in the World's
+// SeparationLog.
+func (sp *STARSPane) updateSeparationLog(ctx *PaneContext, aircraft []*Aircraft) {
+	w := ctx.world
+	if w.separationLog == nil {
+		w.separationLog = NewSeparationLog()
+	}
+
+	inCAVolumes := func(state *STARSAircraftState) bool {
+		for _, vol := range w.InhibitCAVolumes() {
+			if vol.Inside(state.TrackPosition(), state.TrackAltitude()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// aircraft is sorted by callsign, so the pairs are as well.
+	var samples []SeparationSample
+	for i, ac := range aircraft {
+		sa := sp.Aircraft[ac.Callsign]
+		if inCAVolumes(sa) {
+			continue
+		}
+		for _, oac := range aircraft[i+1:] {
+			sb := sp.Aircraft[oac.Callsign]
+			if inCAVolumes(sb) {
+				continue
+			}
+
+			d := nmdistance2ll(sa.TrackPosition(), sb.TrackPosition())
+			// As with CA, allow a small slop for floating-point error
+			if d > LateralMinimum+SeparationWarningBuffer || abs(sa.TrackAltitude()-sb.TrackAltitude()) > VerticalMinimum-5 {
+				continue
+			}
+			violation := d <= LateralMinimum
+			if !violation && sp.diverging(ac, oac) {
+				continue
+			}
+
+			samples = append(samples, SeparationSample{
+				Callsigns: [2]string{ac.Callsign, oac.Callsign},
+				Positions: [2]Point2LL{sa.TrackPosition(), sb.TrackPosition()},
+				Altitudes: [2]int{sa.TrackAltitude(), sb.TrackAltitude()},
+				Violation: violation,
+			})
+		}
+	}
+
+	frame := -1
+	if w.timelapse != nil && w.timelapse.recording {
+		frame = w.timelapse.nFrames
+	}
+	w.separationLog.Update(w.CurrentTime(), samples, frame)
+}
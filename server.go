@@ -103,6 +103,14 @@ func (s *SimProxy) SetLaunchConfig(lc LaunchConfig) *rpc.Call {
 		}, nil, nil)
 }
 
+func (s *SimProxy) InjectInstructorEvent(ev InstructorEvent) *rpc.Call {
+	return s.Client.Go("Sim.InjectInstructorEvent",
+		&InstructorEventArgs{
+			ControllerToken: s.ControllerToken,
+			Event:           ev,
+		}, nil, nil)
+}
+
 func (s *SimProxy) TakeOrReturnLaunchControl() *rpc.Call {
 	return s.Client.Go("Sim.TakeOrReturnLaunchControl", s.ControllerToken, nil, nil)
 }
@@ -123,6 +131,14 @@ func (s *SimProxy) SetScratchpad(callsign string, scratchpad string) *rpc.Call {
 	}, nil, nil)
 }
 
+func (s *SimProxy) AmendFlightPlan(callsign string, fp FlightPlan) *rpc.Call {
+	return s.Client.Go("Sim.AmendFlightPlan", &AmendFlightPlanArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		FlightPlan:      fp,
+	}, nil, nil)
+}
+
 func (s *SimProxy) SetSecondaryScratchpad(callsign string, scratchpad string) *rpc.Call {
 	return s.Client.Go("Sim.SetSecondaryScratchpad", &SetScratchpadArgs{
 		ControllerToken: s.ControllerToken,
@@ -172,6 +188,7 @@ func (s *SimProxy) GlobalMessage(global GlobalMessage) *rpc.Call {
 		ControllerToken: s.ControllerToken,
 		Message:         global.Message,
 		FromController:  global.FromController,
+		ToController:    global.ToController,
 	}, nil, nil)
 }
 
@@ -228,6 +245,29 @@ func (s *SimProxy) RejectPointOut(callsign string) *rpc.Call {
 	}, nil, nil)
 }
 
+func (s *SimProxy) RequestCoordination(callsign, controller, request string) *rpc.Call {
+	return s.Client.Go("Sim.RequestCoordination", &CoordinationArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		Controller:      controller,
+		Request:         request,
+	}, nil, nil)
+}
+
+func (s *SimProxy) AcceptCoordination(callsign string) *rpc.Call {
+	return s.Client.Go("Sim.AcceptCoordination", &CoordinationArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+	}, nil, nil)
+}
+
+func (s *SimProxy) DenyCoordination(callsign string) *rpc.Call {
+	return s.Client.Go("Sim.DenyCoordination", &CoordinationArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+	}, nil, nil)
+}
+
 func (s *SimProxy) ToggleSPCOverride(callsign string, spc string) *rpc.Call {
 	return s.Client.Go("Sim.ToggleSPCOverride", &ToggleSPCArgs{
 		ControllerToken: s.ControllerToken,
@@ -236,6 +276,21 @@ func (s *SimProxy) ToggleSPCOverride(callsign string, spc string) *rpc.Call {
 	}, nil, nil)
 }
 
+func (s *SimProxy) SetSquawk(callsign string, squawk Squawk) *rpc.Call {
+	return s.Client.Go("Sim.SetSquawk", &SquawkArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		Squawk:          squawk,
+	}, nil, nil)
+}
+
+func (s *SimProxy) SetSquawkAutomatic(callsign string) *rpc.Call {
+	return s.Client.Go("Sim.SetSquawkAutomatic", &SquawkArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+	}, nil, nil)
+}
+
 func (s *SimProxy) SetTemporaryAltitude(callsign string, alt int) *rpc.Call {
 	return s.Client.Go("Sim.SetTemporaryAltitude", &AssignAltitudeArgs{
 		ControllerToken: s.ControllerToken,
@@ -556,7 +611,7 @@ func (sm *SimManager) Broadcast(m *SimBroadcastMessage, _ *struct{}) error {
 	sm.mu.Lock(lg)
 	defer sm.mu.Unlock(sm.lg)
 
-	lg.Infof("Broadcasting message: %s", m.Message)
+	lg.Category(LogCategoryNetwork).Infof("Broadcasting message: %s", m.Message)
 
 	for _, sim := range sm.activeSims {
 		sim.mu.Lock(sim.lg)
@@ -574,7 +629,7 @@ func (sm *SimManager) Broadcast(m *SimBroadcastMessage, _ *struct{}) error {
 func BroadcastMessage(hostname, msg, password string) {
 	client, err := getClient(hostname)
 	if err != nil {
-		lg.Errorf("unable to get client for broadcast: %v", err)
+		lg.Category(LogCategoryNetwork).Errorf("unable to get client for broadcast: %v", err)
 		return
 	}
 
@@ -584,7 +639,7 @@ func BroadcastMessage(hostname, msg, password string) {
 	}, nil)
 
 	if err != nil {
-		lg.Errorf("broadcast error: %v", err)
+		lg.Category(LogCategoryNetwork).Errorf("broadcast error: %v", err)
 	}
 }
 
@@ -659,6 +714,19 @@ func (sd *SimDispatcher) SetLaunchConfig(lc *SetLaunchConfigArgs, _ *struct{}) e
 	}
 }
 
+type InstructorEventArgs struct {
+	ControllerToken string
+	Event           InstructorEvent
+}
+
+func (sd *SimDispatcher) InjectInstructorEvent(ie *InstructorEventArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[ie.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.InjectInstructorEvent(ie.ControllerToken, ie.Event)
+	}
+}
+
 func (sd *SimDispatcher) TogglePause(token string, _ *struct{}) error {
 	if sim, ok := sd.sm.ControllerTokenToSim(token); !ok {
 		return ErrNoSimForControllerToken
@@ -689,6 +757,20 @@ func (sd *SimDispatcher) SetSecondaryScratchpad(a *SetScratchpadArgs, _ *struct{
 	}
 }
 
+type AmendFlightPlanArgs struct {
+	ControllerToken string
+	Callsign        string
+	FlightPlan      FlightPlan
+}
+
+func (sd *SimDispatcher) AmendFlightPlan(a *AmendFlightPlanArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.AmendFlightPlan(a.ControllerToken, a.Callsign, a.FlightPlan)
+	}
+}
+
 type SetGlobalLeaderLineArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -778,6 +860,12 @@ type PointOutArgs struct {
 	Callsign        string
 	Controller      string
 }
+type CoordinationArgs struct {
+	ControllerToken string
+	Callsign        string
+	Controller      string
+	Request         string
+}
 type ForceQLArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -786,6 +874,7 @@ type ForceQLArgs struct {
 type GlobalMessageArgs struct {
 	ControllerToken string
 	FromController  string
+	ToController    string
 	Message         string
 }
 
@@ -837,6 +926,30 @@ func (sd *SimDispatcher) RejectPointOut(po *PointOutArgs, _ *struct{}) error {
 	}
 }
 
+func (sd *SimDispatcher) RequestCoordination(co *CoordinationArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[co.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.RequestCoordination(co.ControllerToken, co.Callsign, co.Controller, co.Request)
+	}
+}
+
+func (sd *SimDispatcher) AcceptCoordination(co *CoordinationArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[co.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.AcceptCoordination(co.ControllerToken, co.Callsign)
+	}
+}
+
+func (sd *SimDispatcher) DenyCoordination(co *CoordinationArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[co.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.DenyCoordination(co.ControllerToken, co.Callsign)
+	}
+}
+
 type ToggleSPCArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -851,6 +964,28 @@ func (sd *SimDispatcher) ToggleSPCOverride(ts *ToggleSPCArgs, _ *struct{}) error
 	}
 }
 
+type SquawkArgs struct {
+	ControllerToken string
+	Callsign        string
+	Squawk          Squawk
+}
+
+func (sd *SimDispatcher) SetSquawk(sq *SquawkArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[sq.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.SetSquawk(sq.ControllerToken, sq.Callsign, sq.Squawk)
+	}
+}
+
+func (sd *SimDispatcher) SetSquawkAutomatic(sq *SquawkArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[sq.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.SetSquawkAutomatic(sq.ControllerToken, sq.Callsign)
+	}
+}
+
 type AssignAltitudeArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -895,6 +1030,10 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 		return ErrNoSimForControllerToken
 	}
 
+	if sim.RelayToPseudoPilot(token, callsign, cmds.Commands) {
+		return nil
+	}
+
 	commands := strings.Fields(cmds.Commands)
 
 	for i, command := range commands {
@@ -1305,7 +1444,7 @@ func (sd *SimDispatcher) LaunchAircraft(ls *LaunchAircraftArgs, _ *struct{}) err
 func RunSimServer() {
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", *serverPort))
 	if err != nil {
-		lg.Errorf("tcp listen: %v", err)
+		lg.Category(LogCategoryNetwork).Errorf("tcp listen: %v", err)
 		return
 	}
 
@@ -1342,7 +1481,7 @@ func TryConnectRemoteServer(hostname string) chan *SimServerConnection {
 			if err := client.CallWithTimeout("SimManager.SignOn", ViceRPCVersion, &so); err != nil {
 				ch <- &SimServerConnection{err: err}
 			} else {
-				lg.Debugf("%s: server returned configuration in %s", hostname, time.Since(start))
+				lg.Category(LogCategoryNetwork).Debugf("%s: server returned configuration in %s", hostname, time.Since(start))
 				ch <- &SimServerConnection{
 					server: &SimServer{
 						RPCClient:   client,
@@ -1374,7 +1513,7 @@ func LaunchLocalSimServer() (chan *SimServer, *VideoMapLibrary, error) {
 
 		client, err := getClient(fmt.Sprintf("localhost:%d", port))
 		if err != nil {
-			lg.Errorf("unable to get client: %v", err)
+			lg.Category(LogCategoryNetwork).Errorf("unable to get client: %v", err)
 			os.Exit(1)
 		}
 
@@ -1403,11 +1542,11 @@ func runServer(l net.Listener, isLocal bool) (chan map[string]map[string]*SimCon
 
 		sm := NewSimManager(scenarioGroups, simConfigurations, mapLib, lg)
 		if err := server.Register(sm); err != nil {
-			lg.Errorf("unable to register SimManager: %v", err)
+			lg.Category(LogCategoryNetwork).Errorf("unable to register SimManager: %v", err)
 			os.Exit(1)
 		}
 		if err := server.RegisterName("Sim", &SimDispatcher{sm: sm}); err != nil {
-			lg.Errorf("unable to register SimDispatcher: %v", err)
+			lg.Category(LogCategoryNetwork).Errorf("unable to register SimDispatcher: %v", err)
 			os.Exit(1)
 		}
 
@@ -1415,15 +1554,15 @@ func runServer(l net.Listener, isLocal bool) (chan map[string]map[string]*SimCon
 
 		ch <- simConfigurations
 
-		lg.Infof("Listening on %+v", l)
+		lg.Category(LogCategoryNetwork).Infof("Listening on %+v", l)
 
 		for {
 			conn, err := l.Accept()
-			lg.Infof("%s: new connection", conn.RemoteAddr())
+			lg.Category(LogCategoryNetwork).Infof("%s: new connection", conn.RemoteAddr())
 			if err != nil {
-				lg.Errorf("Accept error: %v", err)
+				lg.Category(LogCategoryNetwork).Errorf("Accept error: %v", err)
 			} else if cc, err := MakeCompressedConn(MakeLoggingConn(conn)); err != nil {
-				lg.Errorf("MakeCompressedConn: %v", err)
+				lg.Category(LogCategoryNetwork).Errorf("MakeCompressedConn: %v", err)
 			} else {
 				codec := MakeGOBServerCodec(cc)
 				codec = MakeLoggingServerCodec(conn.RemoteAddr().String(), codec)
@@ -1449,21 +1588,21 @@ func launchHTTPStats(sm *SimManager) {
 	launchTime = time.Now()
 	http.HandleFunc("/sup", func(w http.ResponseWriter, r *http.Request) {
 		statsHandler(w, r, sm)
-		lg.Infof("%s: served stats request", r.URL.String())
+		lg.Category(LogCategoryNetwork).Infof("%s: served stats request", r.URL.String())
 	})
 	http.HandleFunc("/vice-logs/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		if f, err := os.Open("." + r.URL.String()); err == nil {
 			if n, err := io.Copy(w, f); err != nil {
-				lg.Errorf("%s: %v", r.URL.String(), err)
+				lg.Category(LogCategoryNetwork).Errorf("%s: %v", r.URL.String(), err)
 			} else {
-				lg.Infof("%s: served %d bytes", r.URL.String(), n)
+				lg.Category(LogCategoryNetwork).Infof("%s: served %d bytes", r.URL.String(), n)
 			}
 		}
 	})
 
 	if err := http.ListenAndServe(":6502", nil); err != nil {
-		lg.Errorf("Failed to start HTTP server for stats: %v\n", err)
+		lg.Category(LogCategoryNetwork).Errorf("Failed to start HTTP server for stats: %v\n", err)
 	}
 }
 
@@ -0,0 +1,246 @@
+// session.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// The configuration is normally only saved when vice exits, so if it
+// crashes, any setup done during the session would be lost. Therefore,
+// the session's state (the configuration, which includes the scope
+// settings, annotations, timers, and aircraft state, as well as the sim if
+// it's running locally) is periodically autosaved. A lock file records
+// that vice is running; if it's still there at startup, then vice didn't
+// exit cleanly and the user is offered the chance to restore the autosaved
+// session, including reconnecting to a remote sim.
+
+const SessionAutosaveInterval = time.Minute
+
+// Restoring a remote session is abandoned if the server can't be reached
+// in this long.
+const SessionRestoreTimeout = 30 * time.Second
+
+type SessionAutosave struct {
+	Time   time.Time
+	Config json.RawMessage // as returned by exportConfig
+
+	// Set if the sim was running locally.
+	Sim *Sim
+
+	// Set if the user was connected to a remote sim, for rejoining it.
+	RemoteSim string
+	Callsign  string
+	Password  string
+}
+
+func sessionLockFilePath() string {
+	return path.Join(path.Dir(configFilePath()), "session.lock")
+}
+
+func sessionAutosaveFilePath() string {
+	return path.Join(path.Dir(configFilePath()), "autosave.json")
+}
+
+// beginSession records that vice is running. If the previous session
+// didn't exit cleanly, it returns that session's autosave, if available.
+func beginSession() *SessionAutosave {
+	_, err := os.Stat(sessionLockFilePath())
+	crashed := err == nil
+
+	if err := os.WriteFile(sessionLockFilePath(), []byte(strconv.Itoa(os.Getpid())), 0o600); err != nil {
+		lg.Errorf("%s: %v", sessionLockFilePath(), err)
+	}
+
+	if !crashed {
+		return nil
+	}
+	lg.Warnf("Previous session didn't exit cleanly")
+
+	b, err := os.ReadFile(sessionAutosaveFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			lg.Errorf("%s: %v", sessionAutosaveFilePath(), err)
+		}
+		return nil
+	}
+	var sa SessionAutosave
+	if err := json.Unmarshal(b, &sa); err != nil {
+		lg.Errorf("%s: %v", sessionAutosaveFilePath(), err)
+		return nil
+	}
+	return &sa
+}
+
+// endSession should be called when vice exits cleanly.
+func endSession() {
+	os.Remove(sessionAutosaveFilePath())
+	os.Remove(sessionLockFilePath())
+}
+
+var (
+	lastSessionAutosave time.Time
+	sessionAutosaving   atomic.Bool
+)
+
+// autosaveSession saves the session state if it's time to do so.
+func autosaveSession(w *World) {
+	if globalConfig.DisableSessionAutosave || time.Since(lastSessionAutosave) < SessionAutosaveInterval ||
+		sessionAutosaving.Load() {
+		return
+	}
+	lastSessionAutosave = time.Now()
+
+	config, err := exportConfig()
+	if err != nil {
+		lg.Errorf("autosave: %v", err)
+		return
+	}
+	sa := SessionAutosave{Time: time.Now(), Config: config}
+
+	if w != nil && w.simProxy != nil {
+		if localServer != nil && w.simProxy.Client == localServer.RPCClient {
+			if sim, err := w.GetSerializeSim(); err != nil {
+				lg.Errorf("autosave: %v", err)
+			} else {
+				sim.PreSave()
+				sa.Sim = sim
+			}
+		} else if w.SimName != "" {
+			sa.RemoteSim, sa.Callsign, sa.Password = w.SimName, w.Callsign, w.simPassword
+		}
+	}
+
+	// Encoding the sim may take a moment, so do that and the writing in
+	// the background. The file is written under a temporary name and then
+	// renamed so that a crash while it's being written doesn't leave a
+	// partial autosave.
+	sessionAutosaving.Store(true)
+	go func() {
+		defer sessionAutosaving.Store(false)
+
+		b, err := json.Marshal(sa)
+		if err != nil {
+			lg.Errorf("autosave: %v", err)
+			return
+		}
+		fn := sessionAutosaveFilePath()
+		if err := os.WriteFile(fn+".tmp", b, 0o600); err != nil {
+			lg.Errorf("%s: %v", fn, err)
+		} else if err := os.Rename(fn+".tmp", fn); err != nil {
+			lg.Errorf("%s: %v", fn, err)
+		}
+	}()
+}
+
+// sessionRestore holds the autosave that's being restored while waiting
+// for the connection to its sim.
+var sessionRestore struct {
+	autosave *SessionAutosave
+	start    time.Time
+}
+
+// uiShowSessionRestoreDialog asks the user whether the autosaved session
+// should be restored.
+func uiShowSessionRestoreDialog(sa *SessionAutosave, ml *VideoMapLibrary, r Renderer, eventStream *EventStream) {
+	query := Tr("vice didn't exit normally last time. Restore the session that was saved at") + " " +
+		sa.Time.Format("15:04:05") + "?"
+	if sa.RemoteSim != "" {
+		query += "\n" + Tr("You will be reconnected to") + " " + sa.RemoteSim + " (" + sa.Callsign + ")."
+	}
+
+	uiShowModalDialog(NewModalDialogBox(&YesOrNoModalClient{
+		title: "Restore Session",
+		query: query,
+		ok: func() {
+			if err := startSessionRestore(sa, ml, r, eventStream); err != nil {
+				ShowErrorDialog("Unable to restore the session: %v", err)
+				uiShowConnectDialog(false)
+			}
+		},
+		notok: func() { uiShowConnectDialog(false) },
+	}), true)
+}
+
+func startSessionRestore(sa *SessionAutosave, ml *VideoMapLibrary, r Renderer, eventStream *EventStream) error {
+	if sa.Sim != nil {
+		if err := sa.Sim.PostLoad(ml); err != nil {
+			return err
+		}
+		var result NewSimResult
+		if err := localServer.Call("SimManager.Add", sa.Sim, &result); err != nil {
+			return err
+		}
+		result.World.simProxy = &SimProxy{
+			ControllerToken: result.ControllerToken,
+			Client:          localServer.RPCClient,
+		}
+		sessionRestore.autosave = sa
+		newWorldChan <- result.World
+	} else if sa.RemoteSim != "" {
+		// updateSessionRestore rejoins once we're connected to the server.
+		sessionRestore.autosave, sessionRestore.start = sa, time.Now()
+	} else {
+		// There wasn't a sim, so just restore the configuration.
+		if err := importConfig(sa.Config, nil, r, eventStream); err != nil {
+			return err
+		}
+		uiShowConnectDialog(false)
+	}
+	return nil
+}
+
+// updateSessionRestore should be called each frame; it rejoins the remote
+// sim of a session that is being restored once the connection to the
+// server has been established.
+func updateSessionRestore(w *World) {
+	sa := sessionRestore.autosave
+	if sa == nil || sa.RemoteSim == "" || w != nil || sessionRestore.start.IsZero() {
+		return
+	}
+
+	if remoteServer == nil {
+		if time.Since(sessionRestore.start) > SessionRestoreTimeout {
+			sessionRestore.autosave = nil
+			ShowErrorDialog("Unable to connect to the vice server to restore the session.")
+			uiShowConnectDialog(false)
+		}
+		return
+	}
+
+	sessionRestore.start = time.Time{} // only try once
+	config := &NewSimConfiguration{
+		TRACONName:                globalConfig.LastTRACON,
+		NewSimType:                NewSimJoinRemote,
+		selectedServer:            remoteServer,
+		SelectedRemoteSim:         sa.RemoteSim,
+		SelectedRemoteSimPosition: sa.Callsign,
+		RemoteSimPassword:         sa.Password,
+	}
+	if err := config.Start(); err != nil {
+		sessionRestore.autosave = nil
+		ShowErrorDialog("Unable to rejoin %s: %v", sa.RemoteSim, err)
+		uiShowConnectDialog(false)
+	}
+}
+
+// finishSessionRestore should be called when a new World is connected; if
+// a session is being restored, its configuration is applied.
+func finishSessionRestore(w *World, r Renderer, eventStream *EventStream) {
+	sa := sessionRestore.autosave
+	if sa == nil {
+		return
+	}
+	sessionRestore.autosave = nil
+
+	if err := importConfig(sa.Config, w, r, eventStream); err != nil {
+		ShowErrorDialog("Unable to restore the session's configuration: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+// sessionstats.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// SessionStats
+
+// SessionStats keeps track of the user's workload over the course of a
+// session: how many aircraft of each type of operation they worked, how
+// many handoffs they made and received, the most aircraft they were
+// tracking at once, and how long each aircraft was on their frequency.
+type SessionStats struct {
+	Start time.Time // sim time
+
+	Arrivals, Departures, Overflights int
+	HandoffsGiven, HandoffsTaken      int
+	PeakTracks                        int
+	PeakTracksTime                    time.Time
+
+	Aircraft map[string]*SessionAircraftStats
+
+	events     *EventsSubscription
+	lastUpdate time.Time
+}
+
+type SessionAircraftStats struct {
+	Callsign      string
+	AircraftType  string
+	Operation     string // "Arrival", "Departure", or "Overflight"
+	FirstTracked  time.Time
+	LastTracked   time.Time
+	TimeOnFreq    time.Duration
+	HandoffsGiven int
+	HandoffsTaken int
+}
+
+func NewSessionStats(w *World, eventStream *EventStream) *SessionStats {
+	return &SessionStats{
+		Start:    w.CurrentTime(),
+		Aircraft: make(map[string]*SessionAircraftStats),
+		events:   eventStream.Subscribe(),
+	}
+}
+
+// Update should be called once per frame; it processes handoff events and
+// accumulates the time that aircraft have been tracked by and in contact
+// with the user.
+func (ss *SessionStats) Update(w *World) {
+	for _, event := range ss.events.Get() {
+		if event.Type != AcceptedHandoffEvent && event.Type != AcceptedRedirectedHandoffEvent {
+			continue
+		}
+		if event.FromController == w.Callsign {
+			ss.HandoffsGiven++
+			if ac, ok := ss.Aircraft[event.Callsign]; ok {
+				ac.HandoffsGiven++
+			}
+		} else if event.ToController == w.Callsign {
+			ss.HandoffsTaken++
+			if ac, ok := w.Aircraft[event.Callsign]; ok {
+				ss.getAircraft(ac, w).HandoffsTaken++
+			}
+		}
+	}
+
+	now := w.CurrentTime()
+	dt := Select(ss.lastUpdate.IsZero(), 0, now.Sub(ss.lastUpdate))
+	ss.lastUpdate = now
+
+	tracks := 0
+	for _, ac := range w.Aircraft {
+		if ac.TrackingController != w.Callsign {
+			continue
+		}
+		tracks++
+
+		as := ss.getAircraft(ac, w)
+		as.LastTracked = now
+		if ac.ControllingController == w.Callsign {
+			as.TimeOnFreq += dt
+		}
+	}
+	if tracks > ss.PeakTracks {
+		ss.PeakTracks, ss.PeakTracksTime = tracks, now
+	}
+}
+
+// getAircraft returns the statistics for the given aircraft, adding it to
+// the session's operations if it hasn't been seen before.
+func (ss *SessionStats) getAircraft(ac *Aircraft, w *World) *SessionAircraftStats {
+	if as, ok := ss.Aircraft[ac.Callsign]; ok {
+		return as
+	}
+
+	as := &SessionAircraftStats{
+		Callsign:     ac.Callsign,
+		Operation:    "Overflight",
+		FirstTracked: w.CurrentTime(),
+	}
+	if fp := ac.FlightPlan; fp != nil {
+		as.AircraftType = fp.AircraftType
+		if _, ok := w.DepartureAirports[fp.DepartureAirport]; ok {
+			as.Operation = "Departure"
+		} else if _, ok := w.ArrivalAirports[fp.ArrivalAirport]; ok {
+			as.Operation = "Arrival"
+		}
+	}
+	switch as.Operation {
+	case "Arrival":
+		ss.Arrivals++
+	case "Departure":
+		ss.Departures++
+	default:
+		ss.Overflights++
+	}
+
+	ss.Aircraft[ac.Callsign] = as
+	return as
+}
+
+func (ss *SessionStats) Close() {
+	ss.events.Unsubscribe()
+}
+
+// WriteCSV writes a summary of the session followed by a line for each
+// aircraft to a CSV file in the given directory, returning its name.
+func (ss *SessionStats) WriteCSV(dir string) (string, error) {
+	fn := path.Join(dir, "vice-session-"+time.Now().Format("20060102-150405")+".csv")
+	f, err := os.Create(fn)
+	if err != nil {
+		return "", err
+	}
+
+	cw := csv.NewWriter(f)
+	ts := func(t time.Time) string { return t.UTC().Format("15:04:05") }
+	itoa := strconv.Itoa
+
+	cw.WriteAll([][]string{
+		{"Session start", ts(ss.Start)},
+		{"Arrivals", itoa(ss.Arrivals)},
+		{"Departures", itoa(ss.Departures)},
+		{"Overflights", itoa(ss.Overflights)},
+		{"Handoffs given", itoa(ss.HandoffsGiven)},
+		{"Handoffs taken", itoa(ss.HandoffsTaken)},
+		{"Peak tracks", itoa(ss.PeakTracks), ts(ss.PeakTracksTime)},
+		{},
+		{"Callsign", "Type", "Operation", "First tracked", "Last tracked", "Time on frequency (s)",
+			"Handoffs given", "Handoffs taken"},
+	})
+	for _, callsign := range SortedMapKeys(ss.Aircraft) {
+		as := ss.Aircraft[callsign]
+		cw.Write([]string{as.Callsign, as.AircraftType, as.Operation, ts(as.FirstTracked),
+			ts(as.LastTracked), fmt.Sprintf("%.0f", as.TimeOnFreq.Seconds()),
+			itoa(as.HandoffsGiven), itoa(as.HandoffsTaken)})
+	}
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return fn, f.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////
+// SessionStatsPane
+
+type SessionStatsPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+	scrollbar      *ScrollBar
+
+	world     *World
+	dirDialog *FileSelectDialogBox
+	status    string
+}
+
+func NewSessionStatsPane() *SessionStatsPane {
+	return &SessionStatsPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+	}
+}
+
+func (sp *SessionStatsPane) Name() string { return "Session Statistics" }
+
+func (sp *SessionStatsPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if sp.font = GetFont(sp.FontIdentifier); sp.font == nil {
+		sp.font = GetDefaultFont()
+		sp.FontIdentifier = sp.font.id
+	}
+	if sp.scrollbar == nil {
+		sp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+}
+
+func (sp *SessionStatsPane) Deactivate()                {}
+func (sp *SessionStatsPane) ResetWorld(w *World)        {}
+func (sp *SessionStatsPane) CanTakeKeyboardFocus() bool { return false }
+
+func (sp *SessionStatsPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&sp.FontIdentifier, "Font"); changed {
+		sp.font = newFont
+	}
+
+	haveStats := sp.world != nil && sp.world.sessionStats != nil
+	uiStartDisable(!haveStats)
+	if imgui.Button("Export CSV...") {
+		stats := sp.world.sessionStats
+		sp.dirDialog = NewDirectorySelectDialogBox("Save session statistics to...", "",
+			func(dir string) {
+				if fn, err := stats.WriteCSV(dir); err != nil {
+					lg.Errorf("%s: %v", dir, err)
+					sp.status = "Error: " + err.Error()
+				} else {
+					sp.status = "Saved " + fn
+				}
+			})
+		sp.dirDialog.Activate()
+	}
+	uiEndDisable(!haveStats)
+
+	haveLog := sp.world != nil && sp.world.separationLog != nil
+	uiStartDisable(!haveLog)
+	if imgui.Button("Export separation report...") {
+		w, sl := sp.world, sp.world.separationLog
+		sp.dirDialog = NewDirectorySelectDialogBox("Save separation report to...", "",
+			func(dir string) {
+				if fn, err := sl.WriteReport(dir, w.Callsign, w.CurrentTime()); err != nil {
+					lg.Errorf("%s: %v", dir, err)
+					sp.status = "Error: " + err.Error()
+				} else {
+					sp.status = "Saved " + fn
+				}
+			})
+		sp.dirDialog.Activate()
+	}
+	uiEndDisable(!haveLog)
+	if sp.status != "" {
+		imgui.Text(sp.status)
+	}
+
+	if sp.dirDialog != nil {
+		sp.dirDialog.Draw()
+	}
+}
+
+func (sp *SessionStatsPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil || ctx.world.sessionStats == nil {
+		return
+	}
+	ss := ctx.world.sessionStats
+	sp.world = ctx.world
+
+	now := ctx.world.CurrentTime()
+	elapsed := now.Sub(ss.Start)
+	lines := []string{
+		fmt.Sprintf("SESSION  %s", formatDuration(elapsed)),
+		fmt.Sprintf("ARRIVALS     %3d", ss.Arrivals),
+		fmt.Sprintf("DEPARTURES   %3d", ss.Departures),
+		fmt.Sprintf("OVERFLIGHTS  %3d", ss.Overflights),
+		fmt.Sprintf("HANDOFFS     %3d GIVEN %3d TAKEN", ss.HandoffsGiven, ss.HandoffsTaken),
+		fmt.Sprintf("PEAK TRACKS  %3d AT %s", ss.PeakTracks, ss.PeakTracksTime.UTC().Format("1504Z")),
+	}
+	if sl := ctx.world.separationLog; sl != nil {
+		warnings, violations := sl.Counts()
+		lines = append(lines, fmt.Sprintf("SEPARATION   %3d VIOL  %3d WARN", violations, warnings))
+	}
+	lines = append(lines, "", "CALLSIGN TYPE OPER  ON FREQ")
+	for _, callsign := range SortedMapKeys(ss.Aircraft) {
+		as := ss.Aircraft[callsign]
+		op := map[string]string{"Arrival": "ARR", "Departure": "DEP", "Overflight": "OVR"}[as.Operation]
+		lines = append(lines, fmt.Sprintf("%-8s %-4s %-4s %8s", as.Callsign, as.AircraftType, op,
+			formatDuration(as.TimeOnFreq)))
+	}
+
+	lineHeight := float32(sp.font.size + 1)
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	sp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	style := TextStyle{Font: sp.font, Color: UITextColor}
+	y := ctx.paneExtent.Height() - 1
+	for _, line := range lines[sp.scrollbar.Offset():] {
+		td.AddText(line, [2]float32{2, y}, style)
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	sp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
+
+// formatDuration returns the given duration as H:MM:SS.
+func formatDuration(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+}
@@ -683,7 +683,8 @@ func (c *NewSimConfiguration) DrawUI() bool {
 		}
 
 		// Handle the case of someone else signing in to the position
-		if _, ok := rs.AvailablePositions[c.SelectedRemoteSimPosition]; c.SelectedRemoteSimPosition != "Observer" && !ok {
+		if _, ok := rs.AvailablePositions[c.SelectedRemoteSimPosition]; c.SelectedRemoteSimPosition != "Observer" &&
+			c.SelectedRemoteSimPosition != PseudoPilotPosition && !ok {
 			c.SelectedRemoteSimPosition = SortedMapKeys(rs.AvailablePositions)[0]
 		}
 
@@ -700,6 +701,9 @@ func (c *NewSimConfiguration) DrawUI() bool {
 			if imgui.SelectableV("Observer", "Observer" == c.SelectedRemoteSimPosition, 0, imgui.Vec2{}) {
 				c.SelectedRemoteSimPosition = "Observer"
 			}
+			if imgui.SelectableV(PseudoPilotPosition, PseudoPilotPosition == c.SelectedRemoteSimPosition, 0, imgui.Vec2{}) {
+				c.SelectedRemoteSimPosition = PseudoPilotPosition
+			}
 
 			imgui.EndCombo()
 		}
@@ -781,6 +785,7 @@ func (c *NewSimConfiguration) Start() error {
 		ControllerToken: result.ControllerToken,
 		Client:          c.selectedServer.RPCClient,
 	}
+	result.World.simPassword = Select(c.NewSimType == NewSimJoinRemote, c.RemoteSimPassword, c.Password)
 
 	globalConfig.LastTRACON = c.TRACONName
 
@@ -868,7 +873,7 @@ func (sc *ServerController) LogValue() slog.Value {
 
 func NewSim(ssc NewSimConfiguration, scenarioGroups map[string]map[string]*ScenarioGroup, isLocal bool,
 	mapLib *VideoMapLibrary, lg *Logger) *Sim {
-	lg = lg.With(slog.String("sim_name", ssc.NewSimName))
+	lg = lg.Category(LogCategorySim).With(slog.String("sim_name", ssc.NewSimName))
 
 	tracon, ok := scenarioGroups[ssc.TRACONName]
 	if !ok {
@@ -1175,6 +1180,11 @@ func (s *Sim) LogValue() slog.Value {
 }
 
 func (s *Sim) SignOn(callsign string) (*World, string, error) {
+	if callsign == PseudoPilotPosition {
+		s.mu.Lock(s.lg)
+		callsign = s.pseudoPilotCallsign()
+		s.mu.Unlock(s.lg)
+	}
 	if err := s.signOn(callsign); err != nil {
 		return nil, "", err
 	}
@@ -1206,7 +1216,7 @@ func (s *Sim) signOn(callsign string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
 
-	if callsign != "Observer" {
+	if callsign != "Observer" && !isPseudoPilot(callsign) {
 		if s.controllerIsSignedIn(callsign) {
 			return ErrControllerAlreadySignedIn
 		}
@@ -1255,6 +1265,10 @@ func (s *Sim) SignOff(token string) error {
 		delete(s.controllers, token)
 		delete(s.World.Controllers, ctrl.Callsign)
 
+		if isPseudoPilot(ctrl.Callsign) {
+			s.releasePseudoPilotAircraft(ctrl.Callsign)
+		}
+
 		s.eventStream.Post(Event{
 			Type:    StatusMessageEvent,
 			Message: ctrl.Callsign + " has signed off.",
@@ -1319,6 +1333,7 @@ func (s *Sim) PostEvent(e Event) {
 type GlobalMessage struct {
 	Message        string
 	FromController string
+	ToController   string // for private messages; empty otherwise
 }
 
 type SimWorldUpdate struct {
@@ -1333,6 +1348,7 @@ type SimWorldUpdate struct {
 	Events          []Event
 	TotalDepartures int
 	TotalArrivals   int
+	Wind            Wind
 }
 
 func (wu *SimWorldUpdate) UpdateWorld(w *World, eventStream *EventStream) {
@@ -1344,10 +1360,12 @@ func (wu *SimWorldUpdate) UpdateWorld(w *World, eventStream *EventStream) {
 	w.LaunchConfig = wu.LaunchConfig
 
 	w.SimTime = wu.Time
+	w.recordPositionReports(wu.Time)
 	w.SimIsPaused = wu.SimIsPaused
 	w.SimRate = wu.SimRate
 	w.TotalDepartures = wu.TotalDepartures
 	w.TotalArrivals = wu.TotalArrivals
+	w.Wind = wu.Wind
 
 	// Important: do this after updating aircraft, controllers, etc.,
 	// so that they reflect any changes the events are flagging.
@@ -1381,9 +1399,10 @@ func (s *Sim) GetWorldUpdate(token string, update *SimWorldUpdate) error {
 			LaunchConfig:    s.LaunchConfig,
 			SimIsPaused:     s.Paused,
 			SimRate:         s.SimRate,
-			Events:          ctrl.events.Get(),
+			Events:          s.eventsForController(ctrl),
 			TotalDepartures: s.TotalDepartures,
 			TotalArrivals:   s.TotalArrivals,
+			Wind:            s.World.Wind,
 		})
 
 		if err != nil {
@@ -1393,11 +1412,21 @@ func (s *Sim) GetWorldUpdate(token string, update *SimWorldUpdate) error {
 	}
 }
 
+// eventsForController returns the controller's pending events; private
+// messages between controllers are only delivered to the sender and the
+// recipient.
+func (s *Sim) eventsForController(ctrl *ServerController) []Event {
+	return FilterSlice(ctrl.events.Get(), func(e Event) bool {
+		return e.Type != GlobalMessageEvent || e.ToController == "" ||
+			e.ToController == ctrl.Callsign || e.FromController == ctrl.Callsign
+	})
+}
+
 func (s *Sim) Activate(lg *Logger) {
 	if s.Name == "" {
-		s.lg = lg
+		s.lg = lg.Category(LogCategorySim)
 	} else {
-		s.lg = lg.With(slog.String("sim_name", s.Name))
+		s.lg = lg.Category(LogCategorySim).With(slog.String("sim_name", s.Name))
 	}
 
 	if s.controllers == nil {
@@ -1561,6 +1590,24 @@ func (s *Sim) updateState() {
 		}
 	}
 
+	// Virtual controllers approve all coordination requests after a short delay.
+	for _, ac := range s.World.Aircraft {
+		if co := ac.Coordination; co != nil && now.Sub(co.Time) > 5*time.Second &&
+			!s.controllerIsSignedIn(co.ToController) {
+			s.eventStream.Post(Event{
+				Type:           AcceptedCoordinationEvent,
+				FromController: co.ToController,
+				ToController:   co.FromController,
+				Callsign:       ac.Callsign,
+				Message:        co.Request,
+			})
+			s.lg.Info("automatic coordination accept", slog.String("callsign", ac.Callsign),
+				slog.String("by", co.ToController), slog.String("to", co.FromController))
+
+			ac.Coordination = nil
+		}
+	}
+
 	// Update the simulation state once a second.
 	if now.Sub(s.lastSimUpdate) >= time.Second {
 		s.lastSimUpdate = now
@@ -1956,6 +2003,7 @@ func (s *Sim) launchAircraftNoLock(ac Aircraft) {
 	}
 
 	s.World.Aircraft[ac.Callsign] = &ac
+	s.assignPseudoPilot(&ac)
 
 	ac.Nav.Check(s.lg)
 
@@ -2005,6 +2053,10 @@ func (s *Sim) dispatchCommand(token string, callsign string,
 // e.g., turns after handoffs.
 func (s *Sim) dispatchControllingCommand(token string, callsign string,
 	cmd func(*Controller, *Aircraft) []RadioTransmission) error {
+	if sc, ok := s.controllers[token]; ok && isPseudoPilot(sc.Callsign) {
+		return s.dispatchPseudoPilotCommand(sc.Callsign, callsign, cmd)
+	}
+
 	return s.dispatchCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) error {
 			if ac.ControllingController != ctrl.Callsign {
@@ -2012,7 +2064,14 @@ func (s *Sim) dispatchControllingCommand(token string, callsign string,
 			}
 			return nil
 		},
-		cmd)
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			if ac.NORDO {
+				// The pilot doesn't hear the instruction, so there's no
+				// readback and nothing changes.
+				return nil
+			}
+			return cmd(ctrl, ac)
+		})
 }
 
 // Commands that are allowed by tracking controller only.
@@ -2036,6 +2095,7 @@ func (s *Sim) GlobalMessage(global GlobalMessageArgs) error {
 		Type:           GlobalMessageEvent,
 		Message:        global.Message,
 		FromController: global.FromController,
+		ToController:   global.ToController,
 	})
 
 	return nil
@@ -2052,6 +2112,17 @@ func (s *Sim) SetScratchpad(token, callsign, scratchpad string) error {
 		})
 }
 
+func (s *Sim) AmendFlightPlan(token, callsign string, fp FlightPlan) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	return s.dispatchTrackingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			ac.FlightPlan = &fp
+			return nil
+		})
+}
+
 func (s *Sim) SetSecondaryScratchpad(token, callsign, scratchpad string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
@@ -2297,6 +2368,17 @@ func (s *Sim) CancelHandoff(token, callsign string) error {
 
 	return s.dispatchTrackingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			to := Select(ac.RedirectedHandoff.RedirectedTo != "", ac.RedirectedHandoff.RedirectedTo,
+				ac.HandoffTrackController)
+			if to != "" {
+				s.eventStream.Post(Event{
+					Type:           CanceledHandoffEvent,
+					FromController: ctrl.Callsign,
+					ToController:   to,
+					Callsign:       ac.Callsign,
+				})
+			}
+
 			delete(s.Handoffs, ac.Callsign)
 			ac.HandoffTrackController = ""
 			ac.RedirectedHandoff = RedirectedHandoff{}
@@ -2305,6 +2387,9 @@ func (s *Sim) CancelHandoff(token, callsign string) error {
 }
 
 func (s *Sim) RedirectHandoff(token, callsign, controller string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
 	return s.dispatchCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) error {
 			if octrl := s.World.GetControllerByCallsign(controller); octrl == nil {
@@ -2324,15 +2409,26 @@ func (s *Sim) RedirectHandoff(token, callsign, controller string) error {
 			if ac.RedirectedHandoff.ShouldFallbackToHandoff(ctrl.Callsign, octrl.Callsign) {
 				ac.HandoffTrackController = ac.RedirectedHandoff.Redirector[0]
 				ac.RedirectedHandoff = RedirectedHandoff{}
-				return nil
+			} else {
+				ac.RedirectedHandoff.AddRedirector(ctrl)
+				ac.RedirectedHandoff.RedirectedTo = octrl.Callsign
 			}
-			ac.RedirectedHandoff.AddRedirector(ctrl)
-			ac.RedirectedHandoff.RedirectedTo = octrl.Callsign
+
+			// The new recipient sees it as a regular handoff offer.
+			s.eventStream.Post(Event{
+				Type:           OfferedHandoffEvent,
+				FromController: ctrl.Callsign,
+				ToController:   octrl.Callsign,
+				Callsign:       ac.Callsign,
+			})
 			return nil
 		})
 }
 
 func (s *Sim) AcceptRedirectedHandoff(token, callsign string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
 	return s.dispatchCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) error {
 			return nil
@@ -2481,6 +2577,74 @@ func (s *Sim) RejectPointOut(token, callsign string) error {
 		})
 }
 
+func (s *Sim) RequestCoordination(token, callsign, controller, request string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	return s.dispatchCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) error {
+			if octrl := s.World.GetControllerByCallsign(controller); octrl == nil {
+				return ErrNoController
+			} else if octrl.Callsign == ctrl.Callsign {
+				// Can't coordinate with ourself
+				return ErrInvalidController
+			}
+			return nil
+		},
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			s.eventStream.Post(Event{
+				Type:           CoordinationRequestEvent,
+				FromController: ctrl.Callsign,
+				ToController:   controller,
+				Callsign:       ac.Callsign,
+				Message:        request,
+			})
+
+			// A new request replaces any earlier one that is still outstanding.
+			ac.Coordination = &CoordinationRequest{
+				FromController: ctrl.Callsign,
+				ToController:   controller,
+				Request:        request,
+				Time:           s.SimTime,
+			}
+			return nil
+		})
+}
+
+func (s *Sim) AcceptCoordination(token, callsign string) error {
+	return s.respondToCoordination(token, callsign, AcceptedCoordinationEvent)
+}
+
+func (s *Sim) DenyCoordination(token, callsign string) error {
+	return s.respondToCoordination(token, callsign, DeniedCoordinationEvent)
+}
+
+func (s *Sim) respondToCoordination(token, callsign string, eventType EventType) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	return s.dispatchCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) error {
+			if ac.Coordination == nil || ac.Coordination.ToController != ctrl.Callsign {
+				return ErrNoCoordinationRequest
+			}
+			return nil
+		},
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			// As with point outs, "to" and "from" are swapped in the
+			// event since they are w.r.t. the original request.
+			s.eventStream.Post(Event{
+				Type:           eventType,
+				FromController: ctrl.Callsign,
+				ToController:   ac.Coordination.FromController,
+				Callsign:       ac.Callsign,
+				Message:        ac.Coordination.Request,
+			})
+			ac.Coordination = nil
+			return nil
+		})
+}
+
 func (s *Sim) ToggleSPCOverride(token, callsign, spc string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
@@ -2492,6 +2656,35 @@ func (s *Sim) ToggleSPCOverride(token, callsign, spc string) error {
 		})
 }
 
+func (s *Sim) SetSquawk(token, callsign string, squawk Squawk) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	return s.dispatchTrackingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.AssignSquawk(squawk)
+		})
+}
+
+func (s *Sim) SetSquawkAutomatic(token, callsign string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	var squawk Squawk
+	return s.dispatchCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) error {
+			if ac.TrackingController != ctrl.Callsign {
+				return ErrOtherControllerHasTrack
+			}
+			var err error
+			squawk, err = s.World.assignSquawk()
+			return err
+		},
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.AssignSquawk(squawk)
+		})
+}
+
 func (s *Sim) AssignAltitude(token, callsign string, altitude int, afterSpeed bool) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
@@ -2750,6 +2943,101 @@ func (s *Sim) GoAround(token, callsign string) error {
 		})
 }
 
+func (s *Sim) InjectInstructorEvent(token string, ev InstructorEvent) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	ctrl, ok := s.controllers[token]
+	if !ok {
+		return ErrInvalidControllerToken
+	} else if ctrl.Callsign != s.LaunchConfig.Controller {
+		return ErrNotLaunchController
+	}
+
+	s.lg.Info("instructor event", slog.String("controller", ctrl.Callsign), slog.Any("event", ev))
+
+	if ev.Type == InstructorPopUpVFR {
+		return s.spawnPopUpVFR(ev.Controller)
+	} else if ev.Type == InstructorWindShift {
+		s.World.Wind = ev.Wind
+		return nil
+	}
+
+	ac, ok := s.World.Aircraft[ev.Callsign]
+	if !ok {
+		return ErrNoAircraftForCallsign
+	}
+
+	var rt []RadioTransmission
+	switch ev.Type {
+	case InstructorRadioFailure:
+		ac.NORDO = true
+		ac.Squawk = 0o7600
+
+	case InstructorRestoreRadio:
+		ac.NORDO = false
+		ac.Squawk = ac.AssignedSquawk
+
+	case InstructorEmergency:
+		ac.Emergency = true
+		ac.Squawk = 0o7700
+		rt = []RadioTransmission{RadioTransmission{
+			Controller: ac.ControllingController,
+			Message:    "mayday, mayday, mayday, we're declaring an emergency",
+			Type:       RadioTransmissionUnexpected,
+		}}
+
+	case InstructorMissedApproach:
+		if !ac.Nav.Approach.Cleared {
+			return ErrNotClearedForApproach
+		}
+		rt = ac.GoAround()
+		for i := range rt {
+			rt[i].Type = RadioTransmissionUnexpected
+		}
+
+	default:
+		return ErrInvalidCommandSyntax
+	}
+
+	if !ac.NORDO {
+		PostRadioEvents(ac.Callsign, rt, s)
+	}
+	return nil
+}
+
+// spawnPopUpVFR launches a VFR aircraft along one of the arrival routes that
+// calls the given controller requesting flight following.
+func (s *Sim) spawnPopUpVFR(controller string) error {
+	if s.World.GetControllerByCallsign(controller) == nil {
+		return ErrNoController
+	}
+	groups := SortedMapKeys(s.LaunchConfig.ArrivalGroupRates)
+	if len(groups) == 0 {
+		return ErrNoValidArrivalFound
+	}
+	group := SampleSlice(groups)
+	airport := SampleSlice(SortedMapKeys(s.LaunchConfig.ArrivalGroupRates[group]))
+
+	ac, err := s.World.CreateArrival(group, airport, false)
+	if err != nil {
+		return err
+	}
+	ac.FlightPlan.Rules = VFR
+	ac.Squawk, ac.AssignedSquawk = 0o1200, 0o1200
+	ac.TrackingController = ""
+	ac.ControllingController = controller
+	ac.WaypointHandoffController = ""
+
+	s.launchAircraftNoLock(*ac)
+	PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+		Controller: controller,
+		Message:    "VFR request, looking for flight following to " + airport,
+		Type:       RadioTransmissionContact,
+	}}, s)
+	return nil
+}
+
 func (s *Sim) ContactTower(token, callsign string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
@@ -0,0 +1,142 @@
+// snapshot.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ScopeSnapshotter periodically captures an image of the STARS scope and
+// writes it to a PNG file and/or serves it via HTTP. It's generally used
+// along with the -headless option, which resumes the saved simulation
+// without showing the window, so that, for example, a live traffic
+// picture can be included on an event's web page.
+type ScopeSnapshotter struct {
+	interval time.Duration
+	filename string
+
+	lastCapture time.Time
+	encoding    atomic.Bool // an image is being encoded and saved
+
+	mu      sync.Mutex
+	png     []byte // most recent image
+	updated time.Time
+}
+
+// NewScopeSnapshotter returns a ScopeSnapshotter that captures an image
+// at the given interval. Images are written to filename if it is non-empty
+// and are served at the given address (e.g., ":8080") if httpAddress is
+// non-empty.
+func NewScopeSnapshotter(interval time.Duration, filename, httpAddress string) *ScopeSnapshotter {
+	s := &ScopeSnapshotter{
+		interval: max(interval, time.Second),
+		filename: filename,
+	}
+
+	if httpAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/", s)
+		go func() {
+			lg.Infof("Serving scope snapshots at %s", httpAddress)
+			if err := http.ListenAndServe(httpAddress, mux); err != nil {
+				lg.Errorf("%s: snapshot server: %v", httpAddress, err)
+			}
+		}()
+	}
+
+	return s
+}
+
+// ServeHTTP serves the most recent snapshot as a PNG image.
+func (s *ScopeSnapshotter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	img, updated := s.png, s.updated
+	s.mu.Unlock()
+
+	if img == nil {
+		http.Error(w, "No snapshot is available yet.", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "scope.png", updated, bytes.NewReader(img))
+}
+
+// Capture should be called after the panes have been rendered but before
+// the UI is drawn on top of them. If it's time for a new snapshot, it
+// reads back the pixels of the first STARS pane; encoding the image and
+// writing it out happens asynchronously.
+func (s *ScopeSnapshotter) Capture(p Platform, r Renderer) {
+	if s.encoding.Load() || time.Since(s.lastCapture) < s.interval {
+		return
+	}
+
+	extent, ok := starsPaneExtent()
+	if !ok {
+		return
+	}
+	img := readPanePixels(p, r, extent)
+
+	s.lastCapture = time.Now()
+	s.encoding.Store(true)
+	go s.save(img, s.lastCapture)
+}
+
+func (s *ScopeSnapshotter) save(img *image.RGBA, t time.Time) {
+	defer s.encoding.Store(false)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		lg.Errorf("scope snapshot: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.png, s.updated = buf.Bytes(), t
+	s.mu.Unlock()
+
+	if s.filename != "" {
+		// Write to a temporary file and then rename it so that readers
+		// never see a partially-written image.
+		tmp := filepath.Join(filepath.Dir(s.filename), "."+filepath.Base(s.filename)+".tmp")
+		if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+			lg.Errorf("%s: %v", tmp, err)
+		} else if err := os.Rename(tmp, s.filename); err != nil {
+			lg.Errorf("%s: %v", s.filename, err)
+		}
+	}
+}
+
+// starsPaneExtent returns the window-coordinate extent of the first STARS
+// pane, if there is one.
+func starsPaneExtent() (Extent2D, bool) {
+	var stars *STARSPane
+	globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+		if sp, ok := pane.(*STARSPane); ok && stars == nil {
+			stars = sp
+		}
+	})
+	if stars == nil {
+		return Extent2D{}, false
+	}
+	extent, ok := wm.paneExtents[stars]
+	return extent, ok
+}
+
+// readPanePixels returns the pixels in the framebuffer covered by the
+// given window-coordinate extent.
+func readPanePixels(p Platform, r Renderer, extent Extent2D) *image.RGBA {
+	scale := p.FramebufferSize()[1] / p.DisplaySize()[1]
+	return r.ReadPixels(int(scale*extent.p0[0]), int(scale*extent.p0[1]),
+		int(scale*extent.Width()), int(scale*extent.Height()))
+}
@@ -0,0 +1,181 @@
+// speedadvisory.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// SpeedAdvisoryPane
+
+// SpeedAdvisoryPane suggests a speed for a trailing aircraft so that it
+// reaches a merge point with the specified spacing behind its leader. The
+// merge point may be a fix; if none is given, the leader's sequenced
+// runway threshold (or its destination airport) is used.
+type SpeedAdvisoryPane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+
+	Leader, Trailer string
+	MergeFix        string
+	Spacing         float32 // nm
+}
+
+func NewSpeedAdvisoryPane() *SpeedAdvisoryPane {
+	return &SpeedAdvisoryPane{
+		FontIdentifier: FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+		Spacing:        5,
+	}
+}
+
+func (sa *SpeedAdvisoryPane) Name() string { return "Speed Advisory" }
+
+func (sa *SpeedAdvisoryPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if sa.font = GetFont(sa.FontIdentifier); sa.font == nil {
+		sa.font = GetDefaultFont()
+		sa.FontIdentifier = sa.font.id
+	}
+	if sa.Spacing == 0 {
+		sa.Spacing = 5
+	}
+}
+
+func (sa *SpeedAdvisoryPane) Deactivate() {}
+
+func (sa *SpeedAdvisoryPane) ResetWorld(w *World) {
+	sa.Leader, sa.Trailer, sa.MergeFix = "", "", ""
+}
+
+func (sa *SpeedAdvisoryPane) CanTakeKeyboardFocus() bool { return false }
+
+func (sa *SpeedAdvisoryPane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&sa.FontIdentifier, "Font"); changed {
+		sa.font = newFont
+	}
+	flags := imgui.InputTextFlagsCharsUppercase | imgui.InputTextFlagsCharsNoBlank
+	imgui.InputTextV("Leader", &sa.Leader, flags, nil)
+	imgui.InputTextV("Trailer", &sa.Trailer, flags, nil)
+	imgui.InputTextV("Merge fix (blank for runway)", &sa.MergeFix, flags, nil)
+	imgui.SliderFloatV("Spacing (nm)", &sa.Spacing, 2.5, 20, "%.1f", 0)
+}
+
+// SpeedAdvisory is the result of a speed advisory computation.
+type SpeedAdvisory struct {
+	// Spacing behind the leader when the trailer reaches the merge point
+	// if both maintain their current groundspeeds.
+	ProjectedSpacing float32
+	// Suggested indicated airspeed for the trailer, rounded to 10 knots.
+	IAS int
+	// The suggested speed is outside the trailer's performance envelope
+	// and has been limited to it.
+	Limited bool
+}
+
+// ComputeSpeedAdvisory returns a speed for the trailer so that it reaches
+// the merge point when the leader is the given spacing beyond it. The
+// distances are to the merge point and the leader is assumed to maintain
+// its groundspeed after passing it. Groundspeed is converted to IAS using
+// the ratio of the trailer's current IAS to its groundspeed, which
+// accounts for both altitude and wind.
+func ComputeSpeedAdvisory(leaderDist, leaderGS, trailerDist, trailerGS, trailerIAS, spacing float32,
+	perf AircraftPerformance) (SpeedAdvisory, bool) {
+	if leaderGS <= 0 || trailerGS <= 0 || trailerDist <= leaderDist {
+		return SpeedAdvisory{}, false
+	}
+
+	leaderTime := leaderDist / leaderGS // hours
+	trailerTime := trailerDist / trailerGS
+	adv := SpeedAdvisory{ProjectedSpacing: leaderGS * (trailerTime - leaderTime)}
+
+	// Time the trailer should take to get to the merge point.
+	targetTime := leaderTime + spacing/leaderGS
+	gs := trailerDist / targetTime
+	ias := gs * trailerIAS / trailerGS
+
+	if lo := perf.Speed.Landing; lo > 0 && ias < lo {
+		ias, adv.Limited = lo, true
+	}
+	if hi := min(perf.Speed.MaxTAS, 250); hi > 0 && ias > hi {
+		// Generally these will be below 10,000' so stay at or below 250.
+		ias, adv.Limited = hi, true
+	}
+	adv.IAS = int(ias/10+0.5) * 10
+
+	return adv, true
+}
+
+func (sa *SpeedAdvisoryPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+	w := ctx.world
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	var lines []string
+	color := UITextColor
+	leader, trailer := w.Aircraft[sa.Leader], w.Aircraft[sa.Trailer]
+	if leader == nil || trailer == nil {
+		lines = append(lines, "Specify the leader and trailer in the pane's settings.")
+	} else {
+		// Find the merge point and the distances to it.
+		var leaderDist, trailerDist float32
+		merge := sa.MergeFix
+		if merge != "" {
+			if p, ok := w.Locate(merge); ok {
+				leaderDist = nmdistance2ll(leader.Position(), p)
+				trailerDist = nmdistance2ll(trailer.Position(), p)
+			} else {
+				lines = append(lines, merge+": unknown fix")
+				color = UIErrorColor
+			}
+		} else if e, ok := ComputeArrivalSequence(w)[leader.Callsign]; ok {
+			rwy, _ := LookupRunway(e.Airport, e.Runway)
+			merge = "RWY " + e.Runway
+			leaderDist = arrivalDistanceToThreshold(leader, rwy.Threshold)
+			trailerDist = arrivalDistanceToThreshold(trailer, rwy.Threshold)
+		} else {
+			p := leader.Nav.FlightState.ArrivalAirportLocation
+			merge = leader.Nav.FlightState.ArrivalAirport.Fix
+			leaderDist = nmdistance2ll(leader.Position(), p)
+			trailerDist = nmdistance2ll(trailer.Position(), p)
+		}
+
+		if len(lines) == 0 {
+			lines = append(lines, fmt.Sprintf("%s -> %s at %s: %.1fnm now, %.1fnm target", leader.Callsign,
+				trailer.Callsign, merge, nmdistance2ll(leader.Position(), trailer.Position()), sa.Spacing))
+
+			fs := trailer.Nav.FlightState
+			adv, ok := ComputeSpeedAdvisory(leaderDist, leader.Nav.FlightState.GS, trailerDist, fs.GS, fs.IAS,
+				sa.Spacing, trailer.Nav.Perf)
+			if !ok {
+				lines = append(lines, trailer.Callsign+" is not behind "+leader.Callsign)
+				color = UICautionColor
+			} else {
+				lines = append(lines, fmt.Sprintf("Projected at %s: %.1fnm", merge, adv.ProjectedSpacing))
+				text := fmt.Sprintf("Suggest %s speed %d (now %.0f)", trailer.Callsign, adv.IAS, fs.IAS)
+				if adv.Limited {
+					text += " - UNABLE, LIMITED"
+					color = UICautionColor
+				}
+				lines = append(lines, text)
+			}
+		}
+	}
+
+	indent := float32(2)
+	y := ctx.paneExtent.Height() - 1
+	for _, l := range lines {
+		td.AddText(l, [2]float32{indent, y}, TextStyle{Font: sa.font, Color: color})
+		y -= float32(sa.font.size + 1)
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	td.GenerateCommands(cb)
+}
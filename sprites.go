@@ -0,0 +1,195 @@
+// sprites.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// Filled polygons like track symbols and history dots are drawn as
+// "sprites": each one is a single textured quad that takes its shape from
+// a texture atlas of pre-rasterized polygons and its color from a
+// per-vertex color. Tessellating each one into triangles takes 3 vertices
+// for each edge of the polygon, so with thousands of them on the scope,
+// vertex generation and upload start to add up; sprites only take 4
+// vertices, and they're all drawn in a single draw call.
+
+// Number of sides of the polygons in the sprite atlas.
+var spritePolygonSides = []int{8, 16, 32}
+
+const (
+	// Resolution of each polygon in the atlas.
+	spriteCellSize = 64
+	// Radius of the polygons' vertices in the atlas cells; there's a bit
+	// of padding so that texture filtering doesn't bleed in from
+	// neighboring cells.
+	spritePolygonRadius = 30
+)
+
+type spriteAtlas struct {
+	texid uint32
+	// Texture coordinates of each polygon's cell, indexed by the number
+	// of sides.
+	uv map[int][2][2]float32
+}
+
+var (
+	theSpriteAtlas *spriteAtlas
+	spriteAtlasMu  sync.Mutex
+)
+
+// getSpriteAtlas returns the sprite atlas, creating it the first time it
+// is needed.
+func getSpriteAtlas(r Renderer) *spriteAtlas {
+	spriteAtlasMu.Lock()
+	defer spriteAtlasMu.Unlock()
+
+	if theSpriteAtlas != nil {
+		return theSpriteAtlas
+	}
+
+	n := len(spritePolygonSides)
+	img := image.NewRGBA(image.Rect(0, 0, n*spriteCellSize, spriteCellSize))
+	atlas := &spriteAtlas{uv: make(map[int][2][2]float32)}
+
+	for cell, sides := range spritePolygonSides {
+		// Rotate by 1/2 the angular spacing so that there are horizontal
+		// and vertical edges at the sides.
+		rot := rotator2f(360 / (2 * float32(sides)))
+		pts := MapSlice(GetCirclePoints(sides), func(p [2]float32) [2]float32 {
+			return scale2f(rot(p), spritePolygonRadius)
+		})
+
+		inside := func(p [2]float32) bool {
+			// The polygon is convex, so p is inside if it's on the same
+			// side of all of the edges.
+			var pos, neg bool
+			for i := range pts {
+				e := sub2f(pts[(i+1)%len(pts)], pts[i])
+				d := sub2f(p, pts[i])
+				if c := e[0]*d[1] - e[1]*d[0]; c > 0 {
+					pos = true
+				} else if c < 0 {
+					neg = true
+				}
+			}
+			return !(pos && neg)
+		}
+
+		// Supersample to get fractional coverage at the edges.
+		const ss = 4
+		for y := 0; y < spriteCellSize; y++ {
+			for x := 0; x < spriteCellSize; x++ {
+				covered := 0
+				for sy := 0; sy < ss; sy++ {
+					for sx := 0; sx < ss; sx++ {
+						p := [2]float32{float32(x) + (float32(sx)+0.5)/ss - spriteCellSize/2,
+							float32(y) + (float32(sy)+0.5)/ss - spriteCellSize/2}
+						if inside(p) {
+							covered++
+						}
+					}
+				}
+				a := uint8(255 * covered / (ss * ss))
+				img.SetRGBA(cell*spriteCellSize+x, y, color.RGBA{R: 255, G: 255, B: 255, A: a})
+			}
+		}
+
+		u0, u1 := float32(cell)/float32(n), float32(cell+1)/float32(n)
+		atlas.uv[sides] = [2][2]float32{{u0, 0}, {u1, 1}}
+	}
+
+	atlas.texid = r.CreateTextureFromImage(img, false)
+	theSpriteAtlas = atlas
+	return atlas
+}
+
+// SpriteDrawBuilder accumulates filled polygons to be drawn as sprites.
+type SpriteDrawBuilder struct {
+	p       [][2]float32
+	uv      [][2]float32
+	rgb     []RGB
+	indices []int32
+	sides   []int // for looking up the uvs once the atlas is available
+}
+
+func (s *SpriteDrawBuilder) Reset() {
+	s.p = s.p[:0]
+	s.uv = s.uv[:0]
+	s.rgb = s.rgb[:0]
+	s.indices = s.indices[:0]
+	s.sides = s.sides[:0]
+}
+
+// AddPolygon adds a filled regular polygon centered at p with the given
+// radius to be drawn. The number of sides is rounded up to the nearest
+// one available in the sprite atlas.
+func (s *SpriteDrawBuilder) AddPolygon(p [2]float32, radius float32, sides int, rgb RGB) {
+	idx := int32(len(s.p))
+	// Expand the quad so that the polygon's vertices are at the given
+	// radius.
+	r := radius * spriteCellSize / 2 / spritePolygonRadius
+	s.p = append(s.p, add2f(p, [2]float32{-r, -r}), add2f(p, [2]float32{r, -r}),
+		add2f(p, [2]float32{r, r}), add2f(p, [2]float32{-r, r}))
+	s.rgb = append(s.rgb, rgb, rgb, rgb, rgb)
+	s.indices = append(s.indices, idx, idx+1, idx+2, idx+3)
+
+	for _, n := range spritePolygonSides {
+		if n >= sides || n == spritePolygonSides[len(spritePolygonSides)-1] {
+			s.sides = append(s.sides, n)
+			break
+		}
+	}
+}
+
+func (s *SpriteDrawBuilder) GenerateCommands(cb *CommandBuffer, r Renderer) {
+	if len(s.indices) == 0 {
+		return
+	}
+
+	atlas := getSpriteAtlas(r)
+	s.uv = s.uv[:0]
+	for _, n := range s.sides {
+		uv := atlas.uv[n]
+		s.uv = append(s.uv, uv[0], [2]float32{uv[1][0], uv[0][1]}, uv[1], [2]float32{uv[0][0], uv[1][1]})
+	}
+
+	p := cb.Float2Buffer(s.p)
+	cb.VertexArray(p, 2, 2*4)
+	rgb := cb.RGBBuffer(s.rgb)
+	cb.RGB32Array(rgb, 3, 3*4)
+	uv := cb.Float2Buffer(s.uv)
+	cb.TexCoordArray(uv, 2, 2*4)
+
+	// Use the alpha test rather than blending so that the edges are crisp
+	// (as they were when the polygons were drawn with triangles) and the
+	// sprites don't need to be sorted.
+	cb.EnableTexture(atlas.texid)
+	cb.AlphaTest(0.5)
+
+	ind := cb.IntBuffer(s.indices)
+	cb.DrawQuads(ind, len(s.indices))
+
+	cb.DisableAlphaTest()
+	cb.DisableTexture()
+	cb.DisableTexCoordArray()
+	cb.DisableColorArray()
+	cb.DisableVertexArray()
+}
+
+// SpriteDrawBuilders are managed using a sync.Pool so that their buf
+// slice allocations persist across multiple uses.
+var spriteDrawBuilderPool = sync.Pool{New: func() any { return &SpriteDrawBuilder{} }}
+
+func GetSpriteDrawBuilder() *SpriteDrawBuilder {
+	return spriteDrawBuilderPool.Get().(*SpriteDrawBuilder)
+}
+
+func ReturnSpriteDrawBuilder(s *SpriteDrawBuilder) {
+	s.Reset()
+	spriteDrawBuilderPool.Put(s)
+}
@@ -9,6 +9,7 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"math"
 	"runtime"
 	"slices"
@@ -31,6 +32,43 @@ const STARSTriangleCharacter = string(rune(0x80))
 // Filled upward-pointing triangle
 const STARSFilledUpTriangle = string(rune(0x1e))
 
+// Up and down arrows, used for the altitude trend in datablocks.
+const (
+	STARSUpArrow   = string(rune(0x16))
+	STARSDownArrow = string(rune(0x1a))
+)
+
+// Altitude-coded colors: altitudes between the ones given are
+// interpolated, and ones above the last are given its color.
+type AltitudeColorStop struct {
+	Altitude int
+	Color    RGB
+}
+
+var STARSAltitudeGradient = []AltitudeColorStop{
+	{0, RGB{.9, .2, .1}},
+	{5000, RGB{1, .7, .1}},
+	{10000, RGB{.3, .9, .2}},
+	{20000, RGB{.1, .8, .9}},
+	{30000, RGB{.3, .4, 1}},
+	{40000, RGB{.8, .3, 1}},
+}
+
+// altitudeColor returns the color for the given altitude from the
+// gradient, which must be sorted by altitude.
+func altitudeColor(g []AltitudeColorStop, alt int) RGB {
+	if alt <= g[0].Altitude {
+		return g[0].Color
+	}
+	for i := 1; i < len(g); i++ {
+		if alt < g[i].Altitude {
+			x := float32(alt-g[i-1].Altitude) / float32(g[i].Altitude-g[i-1].Altitude)
+			return lerpRGB(x, g[i-1].Color, g[i].Color)
+		}
+	}
+	return g[len(g)-1].Color
+}
+
 var (
 	STARSBackgroundColor    = RGB{.2, .2, .2} // at 100 contrast
 	STARSListColor          = RGB{.1, .9, .1}
@@ -46,12 +84,28 @@ var (
 		RGB{.16, .16, .43},
 		RGB{.12, .12, .35},
 	}
+	// Routes drawn for an arrival airport are colored by STAR.
+	STARSArrivalRouteColors = [...]RGB{
+		RGB{.9, .6, .2},
+		RGB{.3, .8, .9},
+		RGB{.9, .4, .8},
+		RGB{.6, .9, .3},
+		RGB{.9, .9, .4},
+		RGB{.6, .5, 1},
+		RGB{1, .5, .5},
+		RGB{.4, .9, .7},
+	}
 	STARSJRingConeColor         = RGB{.5, .5, 1}
 	STARSTrackedAircraftColor   = RGB{1, 1, 1}
 	STARSUntrackedAircraftColor = RGB{0, 1, 0}
 	STARSInboundPointOutColor   = RGB{1, 1, 0}
 	STARSGhostColor             = RGB{1, 1, 0}
 	STARSSelectedAircraftColor  = RGB{0, 1, 1}
+	STARSEmergencyColor         = RGB{1, .3, 1}
+
+	STARSSUAActiveColor   = RGB{.9, .3, .3}
+	STARSSUAMOAColor      = RGB{.9, .7, .2}
+	STARSSUAInactiveColor = RGB{.35, .35, .45}
 
 	STARSATPAWarningColor = RGB{1, 1, 0}
 	STARSATPAAlertColor   = RGB{1, .215, 0}
@@ -75,12 +129,19 @@ type STARSPane struct {
 	systemMaps map[int]*STARSMap
 
 	weatherRadar WeatherRadar
+	mapTiles     MapTiles
+
+	// Static geometry that only needs to be regenerated when the scope's
+	// range, center, or the relevant brightness settings change.
+	rangeRingsCache CachedCommandBuffer[starsRangeRingsKey]
+	compassCache    CachedCommandBuffer[starsCompassKey]
 
 	systemFont        [6]*Font
 	systemOutlineFont [6]*Font
 	dcbFont           [3]*Font // 0, 1, 2 only
 
-	fusedTrackVertices [][2]float32
+	fusedTrackRadius float32
+	fusedTrackSides  int
 
 	events *EventsSubscription
 
@@ -96,11 +157,138 @@ type STARSPane struct {
 	// map[string]interface{}.
 	AutoTrackDepartures bool `json:"autotrack_departures"`
 	LockDisplay         bool
-	AirspaceAwareness   struct {
+
+	// Airspace display options: TintOutsideAirspace draws the position
+	// symbol of our tracks that have left our airspace in the alert
+	// color; FilterAirspaceAltitudes limits the drawn airspace volumes to
+	// those overlapping the associated altitude filter.
+	TintOutsideAirspace     bool
+	FilterAirspaceAltitudes bool
+	AirspaceAwareness       struct {
 		Interfacility bool
 		Intrafacility bool
 	}
 
+	// How to draw aircraft that are talking to other controllers; one of
+	// the FrequencyFilter* values below.
+	FrequencyFilter int
+
+	// Show the flight plan of the aircraft under the mouse cursor.
+	FlightPlanTooltip bool
+
+	// Show each arrival's landing sequence number for its runway in its
+	// datablock; arrivalSequence is updated with the radar tracks.
+	ShowSequenceNumbers bool
+	arrivalSequence     map[string]ArrivalSequenceEntry
+
+	// Show an arrow in full datablocks for climbing and descending
+	// aircraft, optionally followed by the vertical rate in hundreds of
+	// feet per minute.
+	ShowAltitudeTrend bool
+	ShowVerticalRate  bool
+
+	// Show how far aircraft established on a final are above or below
+	// the glidepath in their full datablocks.
+	ShowGlidepathDeviation bool
+
+	// Normally Mode C altitudes that are obviously wrong aren't used for
+	// tracks; see filterModeC.
+	DisableModeCFilter bool
+
+	// Length of the extended runway centerlines, in nm;
+	// STARSDefaultCenterlineLength is used if zero. Which runways have
+	// them is set in the runway configuration.
+	CenterlineLength int
+
+	// Whether vector lines (PTLs) show where the aircraft is going over
+	// the ground or where it is pointed; one of the STARSVector* values
+	// below.
+	VectorMode int
+
+	// Color tracks and history trails according to their altitude
+	// rather than with the usual STARS colors. AltitudeGradient is used
+	// if it has been set and STARSAltitudeGradient otherwise.
+	AltitudeColoring bool
+	AltitudeGradient []AltitudeColorStop
+
+	// The items that are time-shared in field 5 of full datablocks, in
+	// order, and how long each datablock variant is displayed.
+	Field5TimeSharing     []STARSTimeSharedEntry
+	DatablockCycleSeconds int
+
+	// Wake turbulence categories shown in datablocks and the separation
+	// minima used for ATPA and the minimum separation tool.
+	WakeStandard WakeStandard
+
+	// Raster map underlay drawn beneath everything else.
+	MapTileUnderlay   int // MapTiles* value
+	MapTileBrightness float32
+
+	// Beyond this range (in nm), video maps are drawn with simplified
+	// lines and route and fix labels aren't drawn, since they are
+	// unreadable at that point anyway.
+	ReducedDetailRange float32
+
+	// Special-use airspace display and alerting. SUAs are hot if they have
+	// been marked so or if their schedule says they are active. Only
+	// those near the scope's center are considered.
+	ShowSUA     bool
+	SUAAlerts   bool
+	SUASettings map[string]*STARSSUASettings
+	nearbySUAs  []*SpecialUseAirspace
+
+	// ILS critical areas and missed approach protected airspace for the
+	// active arrival runways; protectedAreaAlerts holds the airports and
+	// runways of the ones with alerts.
+	ProtectedAreas      ProtectedAreaSettings
+	protectedAreaAlerts []string
+
+	// Vector being previewed with the vectoring aid, if any.
+	vectoringAid *STARSVectoringAid
+
+	// Altitude and route being probed for conflicts, if any.
+	trialPlan *STARSTrialPlan
+
+	// Monitor the no transgression zones between the finals of active
+	// parallel runways.
+	NTZMonitor bool
+
+	// User-drawn regions with alert rules; wipGeofence is the one that is
+	// currently being drawn, if any.
+	Geofences   []STARSGeofence
+	wipGeofence *STARSGeofence
+
+	AlertTriggers []STARSAlertTrigger
+
+	// Cursor position readout; bearings and distances are given from
+	// MouseReadoutFix, or the scope center if it's empty.
+	ShowMouseReadout bool
+	FindRecenters    bool // .FIND always recenters the scope
+	MouseReadoutFix  string
+	readout          mouseReadout
+
+	// Recalled bookmarks are smoothly panned and zoomed to if
+	// AnimateBookmarks is set.
+	AnimateBookmarks bool
+	viewAnimation    *struct {
+		From, To STARSView
+		Start    time.Time
+	}
+
+	// Actions performed at given Zulu times; a banner that one posts is
+	// displayed until scheduledBannerEnd.
+	ScheduledEvents    []STARSScheduledEvent
+	lastScheduleCheck  time.Time
+	scheduledBanner    string
+	scheduledBannerEnd time.Time
+
+	// Free text and symbols drawn on the scope; the UI state records the
+	// tool and text to use for the next one placed.
+	Annotations    []STARSAnnotation
+	annotationTool int
+	annotationText string
+	wipAnnotation  *STARSAnnotation
+
 	// callsign -> controller id
 	InboundPointOuts  map[string]string
 	OutboundPointOuts map[string]string
@@ -113,6 +301,10 @@ type STARSPane struct {
 
 	CAAircraft []CAAircraft
 
+	// Mode C intruder alerts between our tracks and untracked VFR aircraft.
+	ModeCIntruderAlerts bool
+	MCIAircraft         []MCIAircraft
+
 	// For CRDA
 	ConvergingRunways []STARSConvergingRunways
 
@@ -123,23 +315,60 @@ type STARSPane struct {
 
 	dwellAircraft     string
 	drawRouteAircraft string
+	drawRouteAirport  string // arrival airport for which all routes are drawn
 
 	commandMode       CommandMode
 	multiFuncPrefix   string
 	previewAreaOutput string
 	previewAreaInput  string
 
-	HavePlayedSPCAlertSound map[string]interface{}
+	// Callsign and receiving controller of a handoff that was held for an
+	// LOA violation; if it is entered again, it is sent anyway.
+	loaOverride string
+
+	// Aircraft with an emergency or priority-handling condition that
+	// hasn't yet been acknowledged, in the order they were detected.
+	emergencies []string
+
+	// Discrete beacon codes that more than one aircraft is squawking,
+	// updated with the radar tracks. squawkReassignments holds aircraft
+	// that the user has asked to be given new codes via the UI; they are
+	// sent to the server the next time the pane is drawn.
+	duplicateBeacons    map[Squawk][]string
+	squawkReassignments []string
 
 	lastTrackUpdate        time.Time
 	lastHistoryTrackUpdate time.Time
 	discardTracks          bool
+	lastCompaction         time.Time // see compactAircraftState
+	removedAircraft        []removedAircraftState
 
 	drawApproachAirspace  bool
 	drawDepartureAirspace bool
 
+	// Controller whose approximate airspace is shown, if set; this comes
+	// from hovering over the controller in the ControllerPane.
+	highlightedController string
+
+	// Last RunwayConfiguration generation that CRDA was updated for and
+	// whether each of the ConvergingRunways pairs was active then.
+	runwayConfigGeneration int
+	crdaPairActive         []bool
+
+	// Aircraft selected via a SelectedAircraftEvent from another pane.
+	externallySelected string
+
 	// The start of a RBL--one click received, waiting for the second.
 	wipRBL *STARSRangeBearingLine
+
+	touchGestures TouchGestures
+
+	// Weather levels that were displayed before the weather was hidden
+	// via the stars.toggle-weather command.
+	hiddenWeatherLevels [6]bool
+
+	// Changes to the CurrentPreferenceSet, for stars.undo and stars.redo.
+	prefsUndo *UndoHistory[STARSPreferenceSet]
 }
 
 type STARSRangeBearingLine struct {
@@ -177,6 +406,50 @@ type CAAircraft struct {
 	SoundEnd     time.Time
 }
 
+// STARSTimeSharedField identifies one of the items that may be
+// time-shared in field 5 of the full datablock.
+type STARSTimeSharedField int
+
+const (
+	STARSTimeShareSpeed             STARSTimeSharedField = iota // groundspeed and wake category
+	STARSTimeShareType                                          // aircraft type
+	STARSTimeShareRequestedAltitude                             // if enabled in the preference set or for the track
+	STARSTimeShareDestination                                   // arrival airport
+)
+
+func (f STARSTimeSharedField) String() string {
+	return [...]string{"Speed", "Aircraft type", "Requested altitude", "Destination"}[f]
+}
+
+type STARSTimeSharedEntry struct {
+	Field   STARSTimeSharedField
+	Enabled bool
+}
+
+func defaultField5TimeSharing() []STARSTimeSharedEntry {
+	return []STARSTimeSharedEntry{
+		{Field: STARSTimeShareSpeed, Enabled: true},
+		{Field: STARSTimeShareType, Enabled: true},
+		{Field: STARSTimeShareRequestedAltitude, Enabled: true},
+		{Field: STARSTimeShareDestination, Enabled: false},
+	}
+}
+
+// MCIAircraft records an untracked VFR aircraft squawking 1200 with Mode
+// C that is in proximity to one of our tracks.
+type MCIAircraft struct {
+	Callsigns    [2]string // tracked, intruder
+	TrafficCall  string    // intruder's position relative to the tracked aircraft
+	Acknowledged bool
+}
+
+// Mode C intruder alerts are issued if the aircraft are, or are predicted
+// to be within the next minute, within these distances of each other.
+const (
+	STARSMCILateral  = 3    // nm
+	STARSMCIVertical = 1000 // feet
+)
+
 type QuickLookPosition struct {
 	Callsign string
 	Id       string
@@ -224,6 +497,7 @@ type STARSConvergingRunways struct {
 	ConvergingRunways
 	ApproachRegions [2]*ApproachRegion
 	Airport         string
+	AirportICAO     string
 	Index           int
 }
 
@@ -268,7 +542,10 @@ func (s *STARSDatablock) Duplicate() STARSDatablock {
 	return sd
 }
 
-func (s *STARSDatablock) BoundText(font *Font) (int, int) {
+// BoundText returns the size of the datablock's text; if scale is
+// non-zero, the text will be drawn with the scalable version of the font
+// at that scale.
+func (s *STARSDatablock) BoundText(font *Font, scale float32) (int, int) {
 	text := ""
 	for i, l := range s.Lines {
 		text += l.Text
@@ -276,15 +553,20 @@ func (s *STARSDatablock) BoundText(font *Font) (int, int) {
 			text += "\n"
 		}
 	}
-	return font.BoundText(text, 0)
+	w, h := font.BoundText(text, 0)
+	if scale != 0 {
+		w, h = int(ceil(scale*float32(w))), int(ceil(scale*float32(h)))
+	}
+	return w, h
 }
 
 func (s *STARSDatablock) DrawText(td *TextDrawBuilder, pt [2]float32, font *Font, baseColor RGB,
-	brightness STARSBrightness) {
+	brightness STARSBrightness, scale float32) {
 	style := TextStyle{
 		Font:        font,
 		Color:       brightness.ScaleRGB(baseColor),
-		LineSpacing: 0}
+		LineSpacing: 0,
+		Scale:       scale}
 
 	for _, line := range s.Lines {
 		haveFormatting := len(line.Colors) > 0
@@ -300,7 +582,8 @@ func (s *STARSDatablock) DrawText(td *TextDrawBuilder, pt [2]float32, font *Font
 					style := TextStyle{
 						Font:        font,
 						Color:       brightness.ScaleRGB(spanColor),
-						LineSpacing: 0}
+						LineSpacing: 0,
+						Scale:       scale}
 					pt = td.AddText(line.Text[start:end], pt, style)
 					start = end
 				}
@@ -346,6 +629,12 @@ type CRDARunwayPairState struct {
 	Enabled     bool
 	Mode        CRDAMode
 	RunwayState [2]CRDARunwayState
+
+	// Ghost presentation options
+	ReducedGhostDatablock bool    // callsign only in full datablocks, groundspeed only in partial
+	GhostSuffix           string  // appended to the callsign in ghost datablocks
+	OverrideTieOffset     bool    // use TieOffset rather than the scenario's tie offset
+	TieOffset             float32 // nm
 }
 
 func (c *STARSConvergingRunways) getRunwaysString() string {
@@ -422,6 +711,21 @@ type STARSAircraftState struct {
 	historyTracks      [10]RadarTrack
 	historyTracksIndex int
 
+	// Mode C altitudes that fail the sanity checks in filterModeC aren't
+	// used for the track; modeCInvalid is set while that's the case.
+	modeCInvalid      bool
+	pendingModeC      int
+	pendingModeCCount int
+
+	// Most recent flight plan, for re-correlating the track if the
+	// aircraft reappears with a different callsign.
+	flightPlan *FlightPlan
+
+	// All of the aircraft's radar tracks since it was first seen; the
+	// complete path is drawn if ShowFullTrail is set.
+	fullTrail     []RadarTrack
+	ShowFullTrail bool
+
 	DatablockType            DatablockType
 	FullLDBEndTime           time.Time // If the LDB displays the groundspeed. When to stop
 	DisplayRequestedAltitude *bool     // nil if unspecified
@@ -440,6 +744,11 @@ type STARSAircraftState struct {
 	ATPALeadAircraftCallsign string
 	POFlashingEndTime        time.Time
 
+	// Feet above (positive) or below the nominal glidepath for aircraft
+	// established on a final; OnFinal indicates whether it's valid.
+	OnFinal            bool
+	GlidepathDeviation int
+
 	// These are only set if a leader line direction was specified for this
 	// aircraft individually:
 	LeaderLineDirection       *CardinalOrdinalDirection
@@ -464,11 +773,51 @@ type STARSAircraftState struct {
 	MSAWAcknowledged bool
 	MSAWSoundEnd     time.Time
 
+	// Name of the active special-use airspace the aircraft is in or is
+	// predicted to enter, if any.
+	SUAAlert string
+
+	// Airport and runway of the protected area the aircraft is in or is
+	// predicted to enter while an arrival is on final, if any.
+	ProtectedAreaAlert   string
+	protectedAreaAlerted bool
+
+	// NTZ monitoring for simultaneous parallel approaches; the closure
+	// rate toward the adjacent final is in knots.
+	NTZMonitored bool
+	NTZClosure   int
+	NTZStatus    NTZStatus
+	ntzAlerted   bool
+
+	// Geofence alerts: insideGeofences records which of them the aircraft
+	// was inside at the last radar update, so that entries and exits can
+	// be detected.
+	GeofenceAlert    bool
+	GeofenceAlertEnd time.Time
+	insideGeofences  map[string]interface{}
+
+	// Name of the alert trigger that most recently fired for the
+	// aircraft, if it's still being flagged; matchedTriggers records the
+	// ones whose conditions it met at the last radar update.
+	TriggerAlert    string
+	TriggerAlertEnd time.Time
+	matchedTriggers map[string]interface{}
+
+	// Holding is set when the track history looks like a holding
+	// pattern; holdSamples are the recent headings used to detect that.
+	Holding     bool
+	holdSamples []STARSHoldSample
+
 	FirstSeen           time.Time
 	FirstRadarTrack     time.Time
 	HaveEnteredAirspace bool
 
-	CWTCategory string // cache this for performance
+	WakeCategory string // cache this for performance
+
+	// Emergency is the aircraft's EmergencyCondition, if any. Its
+	// datablock flashes until the controller acknowledges it.
+	Emergency             string
+	EmergencyAcknowledged bool
 
 	IdentStart, IdentEnd    time.Time
 	OutboundHandoffAccepted bool
@@ -506,6 +855,59 @@ func (s *STARSAircraftState) TrackAltitude() int {
 	return s.track.Altitude
 }
 
+// AltitudeText returns the track's altitude in hundreds of feet for
+// datablocks, or "XXX" if its Mode C altitude is invalid.
+func (s *STARSAircraftState) AltitudeText() string {
+	if s.modeCInvalid {
+		return "XXX"
+	}
+	return fmt.Sprintf("%03d", (s.TrackAltitude()+50)/100)
+}
+
+// Limits for Mode C sanity checks: altitudes below the minimum and ones
+// that change by more than the maximum jump between successive tracks are
+// treated as erroneous, unless the new altitude is consistently reported
+// for the given number of tracks.
+const (
+	STARSModeCMinAltitude   = -1500 // feet; there are airports below sea level
+	STARSModeCMaxJump       = 5000  // feet
+	STARSModeCConfirmTracks = 3
+)
+
+// filterModeC returns the altitude to use for a new track given the
+// reported Mode C altitude alt, where last is the aircraft's previous
+// track. Erroneous altitudes are replaced with the last valid one and
+// flagged so that the altitude filters and conflict alerts aren't fooled
+// by glitches in the data.
+func (s *STARSAircraftState) filterModeC(alt int, last RadarTrack) int {
+	haveLast := !last.Time.IsZero()
+	reject := func() int {
+		s.modeCInvalid = true
+		return Select(haveLast, last.Altitude, max(alt, 0))
+	}
+
+	if alt < STARSModeCMinAltitude {
+		s.pendingModeCCount = 0
+		return reject()
+	}
+	if haveLast && abs(alt-last.Altitude) > STARSModeCMaxJump {
+		// Accept the new altitude only once it's been reported
+		// consistently.
+		if s.pendingModeCCount > 0 && abs(alt-s.pendingModeC) <= STARSModeCMaxJump {
+			s.pendingModeCCount++
+		} else {
+			s.pendingModeCCount = 1
+		}
+		s.pendingModeC = alt
+		if s.pendingModeCCount < STARSModeCConfirmTracks {
+			return reject()
+		}
+	}
+
+	s.modeCInvalid, s.pendingModeCCount = false, 0
+	return alt
+}
+
 func (s *STARSAircraftState) TrackDeltaAltitude() int {
 	if s.previousTrack.Position.IsZero() {
 		// No previous track
@@ -514,6 +916,32 @@ func (s *STARSAircraftState) TrackDeltaAltitude() int {
 	return s.track.Altitude - s.previousTrack.Altitude
 }
 
+// TrackVerticalRate returns the aircraft's vertical speed in feet per
+// minute, estimated from its track history. Tracks from roughly the last
+// half minute are used so that the rate isn't too sensitive to the
+// altitude being rounded in each one. The second return value is false if
+// there aren't enough tracks to estimate it.
+func (s *STARSAircraftState) TrackVerticalRate() (int, bool) {
+	ref := s.previousTrack
+	n := min(s.historyTracksIndex, len(s.historyTracks))
+	for i := 0; i < n; i++ {
+		t := s.historyTracks[(s.historyTracksIndex-1-i)%len(s.historyTracks)]
+		dt := s.track.Time.Sub(t.Time)
+		if dt > 45*time.Second {
+			break
+		}
+		if !t.Time.IsZero() && t.Time.Before(ref.Time) {
+			ref = t
+		}
+	}
+
+	if ref.Time.IsZero() || !s.track.Time.After(ref.Time) {
+		return 0, false
+	}
+	dt := s.track.Time.Sub(ref.Time).Minutes()
+	return int(float64(s.track.Altitude-ref.Altitude) / dt), true
+}
+
 func (s *STARSAircraftState) TrackPosition() Point2LL {
 	return s.track.Position
 }
@@ -544,6 +972,35 @@ func (s *STARSAircraftState) HeadingVector(nmPerLongitude, magneticVariation flo
 	return nm2ll(v, nmPerLongitude)
 }
 
+// Tracks from up to this long ago are used to estimate an aircraft's
+// ground velocity.
+const STARSGroundVelocityWindow = 30 * time.Second
+
+// GroundVelocity returns the aircraft's velocity over the ground in
+// knots, in nm coordinates. It's estimated from the tracks over
+// STARSGroundVelocityWindow so that it's steadier than the direction
+// between the last two tracks. The second return value is false if there
+// aren't enough tracks to estimate it.
+func (s *STARSAircraftState) GroundVelocity(nmPerLongitude float32) ([2]float32, bool) {
+	ref := s.previousTrack
+	n := min(s.historyTracksIndex, len(s.historyTracks))
+	for i := 0; i < n; i++ {
+		t := s.historyTracks[(s.historyTracksIndex-1-i)%len(s.historyTracks)]
+		if s.track.Time.Sub(t.Time) > STARSGroundVelocityWindow {
+			break
+		}
+		if !t.Time.IsZero() && !t.Position.IsZero() && t.Time.Before(ref.Time) {
+			ref = t
+		}
+	}
+
+	if ref.Time.IsZero() || ref.Position.IsZero() || !s.track.Time.After(ref.Time) {
+		return [2]float32{}, false
+	}
+	d := sub2f(ll2nm(s.track.Position, nmPerLongitude), ll2nm(ref.Position, nmPerLongitude))
+	return scale2f(d, float32(1/s.track.Time.Sub(ref.Time).Hours())), true
+}
+
 func (s *STARSAircraftState) TrackHeading(nmPerLongitude float32) float32 {
 	if !s.HaveHeading() {
 		return 0
@@ -551,6 +1008,23 @@ func (s *STARSAircraftState) TrackHeading(nmPerLongitude float32) float32 {
 	return headingp2ll(s.previousTrack.Position, s.track.Position, nmPerLongitude, 0)
 }
 
+// Maximum number of tracks that are kept for an aircraft's full trail;
+// beyond that, every other one is discarded so that the trail still covers
+// the aircraft's entire path, at a lower resolution.
+const STARSFullTrailMaxLength = 4096
+
+func (s *STARSAircraftState) recordFullTrail() {
+	if len(s.fullTrail) == STARSFullTrailMaxLength {
+		n := 0
+		for i := 0; i < len(s.fullTrail); i += 2 {
+			s.fullTrail[n] = s.fullTrail[i]
+			n++
+		}
+		s.fullTrail = s.fullTrail[:n]
+	}
+	s.fullTrail = append(s.fullTrail, s.track)
+}
+
 func (s *STARSAircraftState) LostTrack(now time.Time) bool {
 	// Only return true if we have at least one valid track from the past
 	// but haven't heard from the aircraft recently.
@@ -569,6 +1043,43 @@ type STARSMap struct {
 	Id            int
 	Lines         [][]Point2LL
 	CommandBuffer CommandBuffer
+
+	// Commands for a version of the map with simplified lines that is
+	// drawn when the scope's range is large; may be empty, in which case
+	// CommandBuffer is used.
+	simplifiedCommandBuffer CommandBuffer
+}
+
+// STARSMapSimplifyTolerance is the maximum distance in degrees (~0.25nm)
+// that vertices may be displaced when video maps are simplified.
+const STARSMapSimplifyTolerance = 0.004
+
+// mapCommands returns the commands to draw the map at the given range.
+func (sm *STARSMap) mapCommands(simplify bool) CommandBuffer {
+	if simplify && sm.simplifiedCommandBuffer.Buf != nil {
+		return sm.simplifiedCommandBuffer
+	}
+	return sm.CommandBuffer
+}
+
+// The inputs that the cached range ring and compass commands depend on.
+type starsRangeRingsKey struct {
+	center     Point2LL
+	radius     int
+	additional string // formatted STARSPreferenceSet AdditionalRangeRings
+	labels     bool
+	color      RGB
+	transforms ScopeTransformations
+}
+
+type starsCompassKey struct {
+	center     Point2LL
+	rotation   float32
+	majorTicks bool
+	font       *Font
+	color      RGB
+	paneExtent Extent2D
+	transforms ScopeTransformations
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -588,6 +1099,11 @@ type STARSPreferenceSet struct {
 
 	RangeRingsCenter Point2LL
 	RangeRingRadius  int
+	RangeRingLabels  bool
+
+	// Range rings around other points (e.g., satellite airports) that are
+	// drawn along with the main ones.
+	AdditionalRangeRings []STARSRangeRings
 
 	// TODO? cursor speed
 
@@ -665,9 +1181,27 @@ type STARSPreferenceSet struct {
 	TopDownMode     bool
 	GroundRangeMode bool
 
+	// User rotation of the scope, in degrees.
+	Rotation float32
+
+	Compass struct {
+		TrueNorth      bool // show true rather than magnetic headings
+		MajorTicks     bool // longer ticks every 10 degrees
+		FollowRotation bool // orient headings to match the scope's rotation
+	}
+
+	// Datablocks can be drawn with scalable text (see sdf.go) so that
+	// their size isn't limited to the bitmap fonts' sizes.
+	ScalableDatablocks struct {
+		Enabled bool
+		Scale   float32 // relative to the DCB character size; 0 -> 1
+	}
+
 	Bookmarks [10]struct {
+		Name        string
 		Center      Point2LL
 		Range       float32
+		Rotation    float32
 		TopDownMode bool
 	}
 
@@ -746,6 +1280,11 @@ type STARSPreferenceSet struct {
 		Visible  bool
 		Lines    int
 	}
+	EmergencyList struct {
+		Position [2]float32
+		Visible  bool
+		Lines    int
+	}
 	SignOnList struct {
 		Position [2]float32
 		Visible  bool
@@ -837,6 +1376,8 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 	ps.RangeRingsCenter = ps.Center
 	ps.RangeRingRadius = 5
 
+	ps.Compass.FollowRotation = true
+
 	ps.RadarTrackHistory = 5
 	ps.RadarTrackHistoryRate = 4.5
 
@@ -907,6 +1448,10 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 	ps.CoastList.Lines = 5
 	ps.CoastList.Visible = false
 
+	ps.EmergencyList.Position = [2]float32{.8, .8}
+	ps.EmergencyList.Lines = 5
+	ps.EmergencyList.Visible = true
+
 	ps.SignOnList.Position = [2]float32{.8, .9}
 	ps.SignOnList.Visible = true
 
@@ -931,10 +1476,23 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 }
 
 func (ps *STARSPreferenceSet) Duplicate() STARSPreferenceSet {
+	// Use slices.Clone and maps.Clone, which preserve nil, so that the
+	// duplicate is reflect.DeepEqual to the original.
 	dupe := *ps
-	dupe.SelectedBeaconCodes = DuplicateSlice(ps.SelectedBeaconCodes)
-	dupe.CRDA.RunwayPairState = DuplicateSlice(ps.CRDA.RunwayPairState)
-	dupe.SystemMapVisible = DuplicateMap(ps.SystemMapVisible)
+	dupe.SelectedBeaconCodes = slices.Clone(ps.SelectedBeaconCodes)
+	dupe.CRDA.RunwayPairState = slices.Clone(ps.CRDA.RunwayPairState)
+	dupe.SystemMapVisible = maps.Clone(ps.SystemMapVisible)
+	dupe.AdditionalRangeRings = slices.Clone(ps.AdditionalRangeRings)
+	dupe.ControllerLeaderLineDirections = maps.Clone(ps.ControllerLeaderLineDirections)
+	dupe.QuickLookPositions = slices.Clone(ps.QuickLookPositions)
+	if ps.OtherControllerLeaderLineDirection != nil {
+		dir := *ps.OtherControllerLeaderLineDirection
+		dupe.OtherControllerLeaderLineDirection = &dir
+	}
+	if ps.UnassociatedLeaderLineDirection != nil {
+		dir := *ps.UnassociatedLeaderLineDirection
+		dupe.UnassociatedLeaderLineDirection = &dir
+	}
 	return dupe
 }
 
@@ -1105,9 +1663,23 @@ func (sp *STARSPane) Activate(w *World, r Renderer, eventStream *EventStream) {
 	}
 	sp.CurrentPreferenceSet.Activate(w, sp)
 
-	if sp.HavePlayedSPCAlertSound == nil {
-		sp.HavePlayedSPCAlertSound = make(map[string]interface{})
+	if sp.MapTileBrightness == 0 {
+		sp.MapTileBrightness = 0.5
+	}
+	if sp.Field5TimeSharing == nil {
+		sp.Field5TimeSharing = defaultField5TimeSharing()
+	}
+	if sp.DatablockCycleSeconds == 0 {
+		sp.DatablockCycleSeconds = 2
+	}
+	if sp.ReducedDetailRange == 0 {
+		sp.ReducedDetailRange = 100
+	}
+	if sp.SUASettings == nil {
+		sp.SUASettings = make(map[string]*STARSSUASettings)
 	}
+	sp.updateNearbySUAs()
+
 	if sp.InboundPointOuts == nil {
 		sp.InboundPointOuts = make(map[string]string)
 	}
@@ -1120,6 +1692,7 @@ func (sp *STARSPane) Activate(w *World, r Renderer, eventStream *EventStream) {
 	if sp.queryUnassociated == nil {
 		sp.queryUnassociated = NewTransientMap[string, interface{}]()
 	}
+	sp.prefsUndo = NewUndoHistory(func(ps STARSPreferenceSet) STARSPreferenceSet { return ps.Duplicate() })
 
 	sp.initializeFonts()
 
@@ -1157,6 +1730,7 @@ func (sp *STARSPane) Deactivate() {
 	sp.events = nil
 
 	sp.weatherRadar.Deactivate()
+	sp.mapTiles.Deactivate()
 }
 
 func (sp *STARSPane) ResetWorld(w *World) {
@@ -1180,12 +1754,14 @@ func (sp *STARSPane) ResetWorld(w *World) {
 	ps.SystemMapVisible = make(map[int]interface{})
 
 	sp.systemMaps = sp.makeSystemMaps(w)
+	sp.updateNearbySUAs()
 
 	ps.CurrentATIS = ""
 	for i := range ps.GIText {
 		ps.GIText[i] = ""
 	}
 	ps.RadarSiteSelected = ""
+	sp.removedAircraft = nil
 
 	sp.ConvergingRunways = nil
 	for _, name := range SortedMapKeys(w.Airports) {
@@ -1195,8 +1771,9 @@ func (sp *STARSPane) ResetWorld(w *World) {
 				ConvergingRunways: pair,
 				ApproachRegions: [2]*ApproachRegion{ap.ApproachRegions[pair.Runways[0]],
 					ap.ApproachRegions[pair.Runways[1]]},
-				Airport: name[1:], // drop the leading "K"
-				Index:   idx + 1,  // 1-based
+				Airport:     name[1:], // drop the leading "K"
+				AirportICAO: name,
+				Index:       idx + 1, // 1-based
 			})
 		}
 	}
@@ -1208,6 +1785,8 @@ func (sp *STARSPane) ResetWorld(w *World) {
 
 	sp.lastTrackUpdate = time.Time{} // force update
 	sp.lastHistoryTrackUpdate = time.Time{}
+	sp.crdaPairActive = nil
+	sp.updateCRDARunways(w)
 }
 
 func (sp *STARSPane) makeSystemMaps(w *World) map[int]*STARSMap {
@@ -1288,13 +1867,224 @@ func (sp *STARSPane) makeSystemMaps(w *World) map[int]*STARSMap {
 func (sp *STARSPane) DrawUI() {
 	imgui.Checkbox("Auto track departures", &sp.AutoTrackDepartures)
 	imgui.Checkbox("Lock display", &sp.LockDisplay)
+	imgui.Checkbox("Show cursor position readout", &sp.ShowMouseReadout)
+	imgui.Checkbox("Recenter the scope on locations found with .FIND", &sp.FindRecenters)
+	if sp.ShowMouseReadout {
+		imgui.InputTextV("Readout reference fix (default: scope center)", &sp.MouseReadoutFix,
+			imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+	}
+	imgui.Checkbox("Tint tracks outside of our airspace", &sp.TintOutsideAirspace)
+	imgui.Checkbox("Only draw airspace within the altitude filters", &sp.FilterAirspaceAltitudes)
+	imgui.Checkbox("Show flight plan when hovering over aircraft", &sp.FlightPlanTooltip)
+	imgui.Checkbox("Show arrival sequence numbers in datablocks", &sp.ShowSequenceNumbers)
+	filterModeC := !sp.DisableModeCFilter
+	if imgui.Checkbox("Filter out erroneous Mode C altitudes", &filterModeC) {
+		sp.DisableModeCFilter = !filterModeC
+	}
+	imgui.Checkbox("Show altitude trend arrows in datablocks", &sp.ShowAltitudeTrend)
+	if sp.ShowAltitudeTrend {
+		imgui.SameLine()
+		imgui.Checkbox("Include vertical rate", &sp.ShowVerticalRate)
+	}
+	imgui.Checkbox("Mode C intruder alerts for VFR traffic near tracked aircraft", &sp.ModeCIntruderAlerts)
+	imgui.Checkbox("Show glidepath deviation for aircraft on final", &sp.ShowGlidepathDeviation)
+	imgui.Checkbox("Monitor NTZs for simultaneous parallel approaches", &sp.NTZMonitor)
+	imgui.Checkbox("Color tracks and history trails by altitude", &sp.AltitudeColoring)
+	if sp.AltitudeColoring && imgui.TreeNode("Altitude colors") {
+		sp.drawAltitudeGradientUI()
+		imgui.TreePop()
+	}
+
+	imgui.Text("Wake turbulence categories:")
+	ws := int(sp.WakeStandard)
+	for _, std := range []WakeStandard{WakeStandardCWT, WakeStandardRECATEU} {
+		imgui.SameLine()
+		imgui.RadioButtonInt(std.String(), &ws, int(std))
+	}
+	if WakeStandard(ws) != sp.WakeStandard {
+		sp.WakeStandard = WakeStandard(ws)
+		// Have processEvents recompute them.
+		for _, state := range sp.Aircraft {
+			state.WakeCategory = ""
+		}
+	}
+
+	imgui.Text("Aircraft on other frequencies:")
+	imgui.SameLine()
+	imgui.RadioButtonInt("Show", &sp.FrequencyFilter, FrequencyFilterShow)
+	imgui.SameLine()
+	imgui.RadioButtonInt("Dim", &sp.FrequencyFilter, FrequencyFilterDim)
+	imgui.SameLine()
+	imgui.RadioButtonInt("Hide untracked", &sp.FrequencyFilter, FrequencyFilterHide)
+
+	length := int32(Select(sp.CenterlineLength > 0, sp.CenterlineLength, STARSDefaultCenterlineLength))
+	if imgui.SliderIntV("Extended centerline length", &length, 5, 30, "%d nm", 0) {
+		sp.CenterlineLength = int(length)
+	}
+
+	imgui.Text("Vector lines:")
+	imgui.SameLine()
+	imgui.RadioButtonInt("Ground track", &sp.VectorMode, STARSVectorTrack)
+	imgui.SameLine()
+	imgui.RadioButtonInt("Heading (wind-corrected)", &sp.VectorMode, STARSVectorHeading)
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip("The ground track is shown if the wind isn't known")
+	}
+
+	imgui.Text("Map underlay:")
+	for _, src := range []MapTileSource{MapTilesNone, MapTilesStreet, MapTilesSatellite, MapTilesTerrain} {
+		imgui.SameLine()
+		imgui.RadioButtonInt(src.String(), &sp.MapTileUnderlay, int(src))
+	}
+	if sp.MapTileUnderlay != int(MapTilesNone) {
+		imgui.SliderFloatV("Map underlay brightness", &sp.MapTileBrightness, 0.05, 1, "%.2f", 0)
+	}
+	imgui.SliderFloatV("Reduce map and label detail beyond range (nm)", &sp.ReducedDetailRange, 20, 256, "%.0f", 0)
+
+	if imgui.CollapsingHeader("Special Use Airspace") {
+		sp.drawSUAUI()
+	}
+	if imgui.CollapsingHeader("ILS Protected Areas") {
+		sp.drawProtectedAreasUI()
+	}
+	if imgui.CollapsingHeader("Geofences") {
+		sp.drawGeofenceUI()
+	}
+	if imgui.CollapsingHeader("Alert triggers") {
+		sp.drawAlertTriggerUI()
+	}
+	if imgui.CollapsingHeader("Compass") {
+		ps := &sp.CurrentPreferenceSet
+		imgui.Text("Headings:")
+		imgui.SameLine()
+		tn := Select(ps.Compass.TrueNorth, 1, 0)
+		imgui.RadioButtonInt("Magnetic", &tn, 0)
+		imgui.SameLine()
+		imgui.RadioButtonInt("True", &tn, 1)
+		ps.Compass.TrueNorth = tn == 1
+		imgui.Checkbox("Longer tick marks every 10 degrees", &ps.Compass.MajorTicks)
+		imgui.Checkbox("Orient headings to the scope rotation", &ps.Compass.FollowRotation)
+	}
+	if imgui.CollapsingHeader("Datablock text") {
+		ps := &sp.CurrentPreferenceSet
+		imgui.Checkbox("Scalable datablock text", &ps.ScalableDatablocks.Enabled)
+		if ps.ScalableDatablocks.Enabled {
+			if ps.ScalableDatablocks.Scale == 0 {
+				ps.ScalableDatablocks.Scale = 1
+			}
+			imgui.SliderFloatV("Text scale", &ps.ScalableDatablocks.Scale, 0.5, 3, "%.2f", 0)
+		}
+	}
+	if imgui.CollapsingHeader("Range rings") {
+		sp.drawRangeRingsUI()
+	}
+	if imgui.CollapsingHeader("Bookmarks") {
+		sp.drawBookmarksUI()
+	}
+	if imgui.CollapsingHeader("Scheduled events") {
+		sp.drawScheduledEventsUI()
+	}
+	if imgui.CollapsingHeader("Annotations") {
+		sp.drawAnnotationUI()
+	}
+	if imgui.CollapsingHeader("Datablock Time Sharing") {
+		sp.drawTimeSharingUI()
+	}
+	if imgui.CollapsingHeader("Duplicate Beacon Codes") {
+		sp.drawDuplicateBeaconUI()
+	}
+
+	ps := &sp.CurrentPreferenceSet
+	if len(sp.ConvergingRunways) > 0 && len(ps.CRDA.RunwayPairState) == len(sp.ConvergingRunways) &&
+		imgui.CollapsingHeader("CRDA Ghosts") {
+		for i, pair := range sp.ConvergingRunways {
+			pairState := &ps.CRDA.RunwayPairState[i]
+			imgui.PushID(strconv.Itoa(i))
+			imgui.Text(pair.Airport + " " + pair.getRunwaysString())
+			imgui.Checkbox("Reduced datablock", &pairState.ReducedGhostDatablock)
+			imgui.InputTextV("Callsign suffix", &pairState.GhostSuffix,
+				imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+			if imgui.Checkbox("Override tie mode offset", &pairState.OverrideTieOffset) &&
+				pairState.OverrideTieOffset && pairState.TieOffset == 0 {
+				pairState.TieOffset = pair.TieOffset
+			}
+			if pairState.OverrideTieOffset {
+				imgui.SliderFloatV("Tie offset (nm)", &pairState.TieOffset, 0, 5, "%.1f", 0)
+			}
+			imgui.PopID()
+		}
+	}
+}
+
+func (sp *STARSPane) drawAltitudeGradientUI() {
+	if len(sp.AltitudeGradient) == 0 {
+		// Start out editing a copy of the default.
+		sp.AltitudeGradient = slices.Clone(STARSAltitudeGradient)
+	}
+
+	changed := false
+	for i := 0; i < len(sp.AltitudeGradient); i++ {
+		stop := &sp.AltitudeGradient[i]
+		imgui.PushID(strconv.Itoa(i))
+
+		alt := int32(stop.Altitude)
+		imgui.SetNextItemWidth(100)
+		if imgui.InputIntV("##alt", &alt, 1000, 5000, imgui.InputTextFlagsEnterReturnsTrue) {
+			stop.Altitude = int(alt)
+			changed = true
+		}
+		imgui.SameLine()
+		rgb := [3]float32{stop.Color.R, stop.Color.G, stop.Color.B}
+		if imgui.ColorEdit3("##color", &rgb) {
+			stop.Color = RGB{R: rgb[0], G: rgb[1], B: rgb[2]}
+		}
+		imgui.SameLine()
+		uiStartDisable(len(sp.AltitudeGradient) == 1)
+		if imgui.Button("Remove") {
+			sp.AltitudeGradient = DeleteSliceElement(sp.AltitudeGradient, i)
+			i--
+		}
+		uiEndDisable(len(sp.AltitudeGradient) == 1)
+
+		imgui.PopID()
+	}
+
+	if imgui.Button("Add altitude") {
+		last := sp.AltitudeGradient[len(sp.AltitudeGradient)-1]
+		sp.AltitudeGradient = append(sp.AltitudeGradient,
+			AltitudeColorStop{Altitude: last.Altitude + 5000, Color: last.Color})
+	}
+	imgui.SameLine()
+	if imgui.Button("Reset to default") {
+		sp.AltitudeGradient = nil
+	}
+
+	if changed {
+		slices.SortStableFunc(sp.AltitudeGradient, func(a, b AltitudeColorStop) int {
+			return a.Altitude - b.Altitude
+		})
+	}
 }
 
 func (sp *STARSPane) CanTakeKeyboardFocus() bool { return true }
 
 func (sp *STARSPane) processEvents(w *World) {
-	// First handle changes in world.Aircraft
+	// First handle changes in world.Aircraft. See if any aircraft we have
+	// state for have been removed; their state is held on to for a bit in
+	// case they reappear with a new callsign.
+	for callsign, state := range sp.Aircraft {
+		if _, ok := w.Aircraft[callsign]; !ok {
+			sp.rememberRemovedAircraft(callsign, state, w.CurrentTime())
+			delete(sp.Aircraft, callsign)
+		}
+	}
+
 	for callsign, ac := range w.Aircraft {
+		if _, ok := sp.Aircraft[callsign]; !ok {
+			if sa := sp.recorrelateAircraft(w, ac); sa != nil {
+				sp.Aircraft[callsign] = sa
+			}
+		}
 		if _, ok := sp.Aircraft[callsign]; !ok {
 			// First we've seen it; create the *STARSAircraftState for it
 			sa := &STARSAircraftState{}
@@ -1304,26 +2094,38 @@ func (sp *STARSPane) processEvents(w *World) {
 			sa.GlobalLeaderLineDirection = ac.GlobalLeaderLineDirection
 			sa.UseGlobalLeaderLine = sa.GlobalLeaderLineDirection != nil
 			sa.FirstSeen = w.CurrentTime()
-			sa.CWTCategory = getCwtCategory(ac)
 
 			sp.Aircraft[callsign] = sa
 		}
+		if state := sp.Aircraft[callsign]; state.WakeCategory == "" {
+			// Also recomputed if the wake standard is changed.
+			state.WakeCategory = sp.getWakeCategory(ac)
+		}
 
-		if ok, _ := SquawkIsSPC(ac.Squawk); ok {
-			if _, ok := sp.HavePlayedSPCAlertSound[ac.Callsign]; !ok {
-				sp.HavePlayedSPCAlertSound[ac.Callsign] = nil
-				//globalConfig.AudioSettings.HandleEvent(AudioEventAlert)
+		state := sp.Aircraft[callsign]
+		state.flightPlan = ac.FlightPlan
+		if cond := EmergencyCondition(ac.Squawk, ac.Callsign, ac.FlightPlan.Remarks); cond != state.Emergency {
+			state.Emergency = cond
+			if cond != "" {
+				state.EmergencyAcknowledged = false
+				if !slices.Contains(sp.emergencies, callsign) {
+					sp.emergencies = append(sp.emergencies, callsign)
+				}
+				globalConfig.Audio.PlayOnce(AudioEmergencySquawk)
 			}
 		}
 	}
 
-	// See if any aircraft we have state for have been removed
-	for callsign := range sp.Aircraft {
-		if _, ok := w.Aircraft[callsign]; !ok {
-			delete(sp.Aircraft, callsign)
-		}
+	if time.Since(sp.lastCompaction) > RetentionCompactInterval {
+		sp.compactAircraftState(w)
+		sp.lastCompaction = time.Now()
 	}
 
+	sp.emergencies = FilterSlice(sp.emergencies, func(callsign string) bool {
+		_, ok := w.Aircraft[callsign]
+		return ok
+	})
+
 	// Filter out any removed aircraft from the CA list
 	sp.CAAircraft = FilterSlice(sp.CAAircraft, func(ca CAAircraft) bool {
 		_, a := w.Aircraft[ca.Callsigns[0]]
@@ -1432,6 +2234,18 @@ func (sp *STARSPane) processEvents(w *World) {
 				state.GlobalLeaderLineDirection = event.LeaderLineDirection
 				state.UseGlobalLeaderLine = state.GlobalLeaderLineDirection != nil
 			}
+
+		case SelectedAircraftEvent:
+			if state, ok := sp.Aircraft[sp.externallySelected]; ok {
+				state.IsSelected = false
+			}
+			sp.externallySelected = event.Callsign
+			if state, ok := sp.Aircraft[event.Callsign]; ok {
+				state.IsSelected = true
+			}
+
+		case HighlightControllerAirspaceEvent:
+			sp.highlightedController = event.ToController
 		}
 	}
 }
@@ -1463,6 +2277,41 @@ func (sp *STARSPane) updateMSAWs(w *World) {
 	}
 }
 
+// updateSUAAlerts flags the aircraft that are inside an active special
+// use airspace or that are predicted to enter one in the next two
+// minutes.
+func (sp *STARSPane) updateSUAAlerts(w *World) {
+	now := w.CurrentTime()
+	var active []*SpecialUseAirspace
+	if sp.SUAAlerts {
+		active = FilterSlice(sp.nearbySUAs, func(sua *SpecialUseAirspace) bool { return sp.suaActive(sua, now) })
+	}
+
+	for callsign, ac := range w.Aircraft {
+		state := sp.Aircraft[callsign]
+		alert := ""
+		if len(active) > 0 && ac.IsAirborne() && state.HaveHeading() {
+			// HeadingVector gives the expected change in position over
+			// one minute; check every 30 seconds.
+			hv := state.HeadingVector(w.NmPerLongitude, w.MagneticVariation)
+			for i := 0; i <= 4 && alert == ""; i++ {
+				p := add2ll(state.track.Position, Point2LL(scale2f(hv, float32(i)/2)))
+				for _, sua := range active {
+					if _, ok := sua.Inside(p, state.track.Altitude); ok {
+						alert = sua.Name
+						break
+					}
+				}
+			}
+		}
+
+		if alert != "" && alert != state.SUAAlert && ac.TrackingController == w.Callsign {
+			globalConfig.Audio.PlayOnce(AudioConflictAlert)
+		}
+		state.SUAAlert = alert
+	}
+}
+
 func (sp *STARSPane) Upgrade(from, to int) {
 	if from < 8 {
 		sp.CurrentPreferenceSet.Brightness.DCB = 60
@@ -1561,21 +2410,74 @@ func (sp *STARSPane) Upgrade(from, to int) {
 			update(&sp.PreferenceSets[i])
 		}
 	}
+	if from < 24 {
+		// Emergency list added
+		update := func(ps *STARSPreferenceSet) {
+			ps.EmergencyList.Position = [2]float32{.8, .8}
+			ps.EmergencyList.Lines = 5
+			ps.EmergencyList.Visible = true
+		}
+		update(&sp.CurrentPreferenceSet)
+		for i := range sp.PreferenceSets {
+			update(&sp.PreferenceSets[i])
+		}
+	}
+}
+
+// updateCRDARunways enables CRDA for the runway pairs where both runways
+// are active arrival runways. After the initial configuration has been
+// applied, only the pairs that have become active or inactive are
+// updated when the runway configuration changes, so that the user's
+// settings for the others are left alone.
+func (sp *STARSPane) updateCRDARunways(w *World) {
+	rc := w.RunwayConfiguration()
+	initial := sp.crdaPairActive == nil
+	if !initial && rc.Generation == sp.runwayConfigGeneration {
+		return
+	}
+	sp.runwayConfigGeneration = rc.Generation
+	if initial {
+		sp.crdaPairActive = make([]bool, len(sp.ConvergingRunways))
+	}
+
+	ps := &sp.CurrentPreferenceSet
+	for i, pair := range sp.ConvergingRunways {
+		active := rc.IsArrivalRunway(pair.AirportICAO, pair.Runways[0]) &&
+			rc.IsArrivalRunway(pair.AirportICAO, pair.Runways[1])
+		if (initial || active != sp.crdaPairActive[i]) && i < len(ps.CRDA.RunwayPairState) {
+			ps.CRDA.RunwayPairState[i].Enabled = active
+		}
+		sp.crdaPairActive[i] = active
+	}
 }
 
 func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	sp.processEvents(ctx.world)
+	sp.updateCRDARunways(ctx.world)
 	sp.updateRadarTracks(ctx)
 
+	for _, callsign := range sp.squawkReassignments {
+		ctx.world.SetSquawkAutomatic(callsign, nil, func(err error) { sp.displayError(err) })
+	}
+	sp.squawkReassignments = nil
+
 	ps := sp.CurrentPreferenceSet
 
 	// Clear to background color
 	cb.ClearRGB(ps.Brightness.BackgroundContrast.ScaleRGB(STARSBackgroundColor))
 
 	sp.processKeyboardInput(ctx)
+	for _, cmd := range globalConfig.HardwareInput.TakeCommands() {
+		if !globalConfig.KeyBindings.RunLayerCommand(cmd) {
+			sp.runKeyCommand(cmd, ctx)
+		}
+	}
+	sp.updateViewAnimation()
+	sp.prefsUndo.Update(sp.CurrentPreferenceSet)
+	ps = sp.CurrentPreferenceSet
 
 	transforms := GetScopeTransformations(ctx.paneExtent, ctx.world.MagneticVariation, ctx.world.NmPerLongitude,
-		ps.CurrentCenter, float32(ps.Range), 0)
+		ps.CurrentCenter, float32(ps.Range), ps.Rotation)
 
 	paneExtent := ctx.paneExtent
 	if ps.DisplayDCB {
@@ -1596,6 +2498,9 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		}
 	}
 
+	sp.mapTiles.Draw(ctx, MapTileSource(sp.MapTileUnderlay), ps.CurrentCenter, float32(ps.Range),
+		sp.MapTileBrightness, transforms, cb)
+
 	weatherBrightness := float32(ps.Brightness.Weather) / float32(100)
 	weatherContrast := float32(ps.Brightness.WxContrast) / float32(100)
 	sp.weatherRadar.Draw(ctx, weatherBrightness, weatherContrast, ps.DisplayWeatherLevel,
@@ -1603,14 +2508,32 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 
 	if ps.Brightness.RangeRings > 0 {
 		color := ps.Brightness.RangeRings.ScaleRGB(STARSRangeRingColor)
+		key := starsRangeRingsKey{
+			center:     ps.RangeRingsCenter,
+			radius:     ps.RangeRingRadius,
+			additional: fmt.Sprint(ps.AdditionalRangeRings),
+			labels:     ps.RangeRingLabels,
+			color:      color,
+			transforms: transforms,
+		}
 		cb.LineWidth(1)
-		DrawRangeRings(ctx, ps.RangeRingsCenter, float32(ps.RangeRingRadius), color, transforms, cb)
+		cb.Call(sp.rangeRingsCache.Get(key, func(cb *CommandBuffer) {
+			rings := []RangeRings{{Center: key.center, Interval: float32(key.radius)}}
+			for _, rr := range ps.AdditionalRangeRings {
+				if p, ok := ctx.world.Locate(rr.Center); ok {
+					rings = append(rings, RangeRings{Center: p, Interval: float32(rr.Interval), Radius: float32(rr.Radius)})
+				}
+			}
+			DrawRangeRings(ctx, rings, color, Select(key.labels, sp.systemFont[ps.CharSize.Tools], nil),
+				transforms, cb)
+		}))
 	}
 
 	transforms.LoadWindowViewingMatrices(cb)
 
 	// Maps
 	cb.LineWidth(1)
+	reduceDetail := ps.Range > sp.ReducedDetailRange
 	videoMaps, _ := ctx.world.GetVideoMaps()
 	for i, disp := range ps.DisplayVideoMap {
 		if !disp {
@@ -1624,7 +2547,7 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		}
 		cb.SetRGB(color)
 		transforms.LoadLatLongViewingMatrices(cb)
-		cb.Call(vmap.CommandBuffer)
+		cb.Call(vmap.mapCommands(reduceDetail))
 	}
 
 	for _, idx := range SortedMapKeys(ps.SystemMapVisible) {
@@ -1635,10 +2558,11 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	}
 
 	ctx.world.DrawScenarioRoutes(transforms, sp.systemFont[ps.CharSize.Tools],
-		ps.Brightness.Lists.ScaleRGB(STARSListColor), cb)
+		ps.Brightness.Lists.ScaleRGB(STARSListColor), !reduceDetail, cb)
 
 	sp.drawCRDARegions(ctx, transforms, cb)
 	sp.drawSelectedRoute(ctx, transforms, cb)
+	sp.drawArrivalRoutes(ctx, transforms, cb)
 
 	transforms.LoadWindowViewingMatrices(cb)
 
@@ -1646,7 +2570,24 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		cb.LineWidth(1)
 		cbright := ps.Brightness.Compass.ScaleRGB(STARSCompassColor)
 		font := sp.systemFont[ps.CharSize.Tools]
-		DrawCompass(ps.CurrentCenter, ctx, 0, font, cbright, paneExtent, transforms, cb)
+		// The scope is drawn magnetic-up (plus any user rotation), so
+		// true headings are offset by the magnetic variation.
+		rotation := Select(ps.Compass.TrueNorth, ctx.world.MagneticVariation, 0)
+		if ps.Compass.FollowRotation {
+			rotation += ps.Rotation
+		}
+		key := starsCompassKey{
+			center:     ps.CurrentCenter,
+			rotation:   rotation,
+			majorTicks: ps.Compass.MajorTicks,
+			font:       font,
+			color:      cbright,
+			paneExtent: paneExtent,
+			transforms: transforms,
+		}
+		cb.Call(sp.compassCache.Get(key, func(cb *CommandBuffer) {
+			DrawCompass(key.center, ctx, key.rotation, key.majorTicks, font, cbright, paneExtent, transforms, cb)
+		}))
 	}
 
 	// Per-aircraft stuff: tracks, datablocks, vector lines, range rings, ...
@@ -1660,17 +2601,37 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		return aircraft[i].Callsign < aircraft[j].Callsign
 	})
 
+	sp.updateScheduledEvents(ctx)
 	sp.drawSystemLists(aircraft, ctx, ctx.paneExtent, transforms, cb)
 
+	// Aircraft that are hidden by the frequency filter are still included
+	// in the lists.
+	if sp.FrequencyFilter == FrequencyFilterHide {
+		aircraft = FilterSlice(aircraft, func(ac *Aircraft) bool {
+			return onFrequency(ctx.world, ac) || ac.TrackingController == ctx.world.Callsign
+		})
+	}
+
+	sp.drawSUAs(ctx, transforms, cb)
+	sp.drawGeofences(ctx, transforms, cb)
+	sp.drawAnnotations(ctx, transforms, cb)
+	sp.drawHolds(ctx, transforms, cb)
+	sp.drawCenterlines(ctx, transforms, cb)
+	sp.drawProtectedAreas(ctx, transforms, cb)
+	sp.drawNTZs(ctx, transforms, cb)
 	sp.drawHistoryTrails(aircraft, ctx, transforms, cb)
+	sp.drawFullTrails(aircraft, ctx, transforms, cb)
 
 	sp.drawPTLs(aircraft, ctx, transforms, cb)
 	sp.drawRingsAndCones(aircraft, ctx, transforms, cb)
 	sp.drawRBLs(aircraft, ctx, transforms, cb)
 	sp.drawMinSep(ctx, transforms, cb)
+	sp.drawVectoringAid(ctx, transforms, cb)
+	sp.drawTrialPlan(ctx, transforms, cb)
 	sp.drawAirspace(ctx, transforms, cb)
 
 	DrawHighlighted(ctx, transforms, cb)
+	DrawRoutePreview(ctx, transforms, cb)
 
 	sp.drawLeaderLines(aircraft, ctx, transforms, cb)
 	sp.drawTracks(aircraft, ctx, transforms, cb)
@@ -1680,6 +2641,9 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	sp.drawGhosts(ghosts, ctx, transforms, cb)
 	sp.consumeMouseEvents(ctx, ghosts, transforms, cb)
 	sp.drawMouseCursor(ctx, paneExtent, transforms, cb)
+	sp.drawMouseReadout(ctx, paneExtent, transforms, cb)
+	sp.drawStreamerOverlay(ctx, transforms, cb)
+	sp.drawAltitudeLegend(ctx, transforms, cb)
 
 	// Play the CA sound if any CAs or MSAWs are unacknowledged
 	playAlertSound := !ps.DisableCAWarnings && slices.ContainsFunc(sp.CAAircraft,
@@ -1730,6 +2694,7 @@ func (sp *STARSPane) updateRadarTracks(ctx *PaneContext) {
 	}
 	sp.lastTrackUpdate = now
 
+	sp.swapCrossedTracks(w, now)
 	for callsign, state := range sp.Aircraft {
 		ac, ok := w.Aircraft[callsign]
 		if !ok {
@@ -1737,17 +2702,31 @@ func (sp *STARSPane) updateRadarTracks(ctx *PaneContext) {
 			continue
 		}
 
+		alt := int(ac.Altitude())
+		if !sp.DisableModeCFilter {
+			alt = state.filterModeC(alt, state.track)
+		}
+
 		state.previousTrack = state.track
 		state.track = RadarTrack{
 			Position:    ac.Position(),
-			Altitude:    int(ac.Altitude()),
+			Altitude:    alt,
 			Groundspeed: int(ac.Nav.FlightState.GS),
 			Time:        now,
 		}
+		state.recordFullTrail()
 	}
 
-	// Update low altitude alerts now that we have updated tracks
+	// Update low altitude and SUA alerts now that we have updated tracks
 	sp.updateMSAWs(w)
+	sp.updateSUAAlerts(w)
+	sp.updateGeofenceAlerts(w)
+	sp.updateAlertTriggers(w)
+	sp.updateHoldDetection(w)
+	sp.duplicateBeacons = DuplicateBeaconCodes(w.Aircraft)
+	if sp.ShowSequenceNumbers {
+		sp.arrivalSequence = ComputeArrivalSequence(w)
+	}
 
 	// History tracks are updated after a radar track update, only if
 	// H_RATE seconds have elapsed (4-94).
@@ -1767,7 +2746,13 @@ func (sp *STARSPane) updateRadarTracks(ctx *PaneContext) {
 	})
 
 	sp.updateCAAircraft(ctx, aircraft)
+	sp.updateSeparationLog(ctx, aircraft)
+	sp.updateMCIAircraft(ctx, aircraft)
 	sp.updateInTrailDistance(aircraft, w)
+	sp.updateGlidepathDeviations(aircraft, w)
+	sp.updateProtectedAreaAlerts(aircraft, w)
+	sp.updateNTZMonitor(aircraft, w)
+	sp.updateTrialPlan(aircraft, w)
 }
 
 func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
@@ -1775,14 +2760,26 @@ func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
 		return
 	}
 
-	if ctx.keyboard.IsPressed(KeyTab) {
-		// focus back to the MessagesPane
-		globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
-			if mp, ok := pane.(*MessagesPane); ok {
-				wmTakeKeyboardFocus(mp, false)
-				delete(ctx.keyboard.Pressed, KeyTab) // prevent cycling back and forth
+	// Handle bound key chords first so that digits that are part of a
+	// chord are removed from the input before it's added to the preview
+	// area.
+	kb := globalConfig.KeyBindings
+	for _, kc := range PressedKeyChords(ctx.keyboard) {
+		handled := false
+		if cmd, ok := kb.Lookup(kc); ok {
+			handled = kb.RunLayerCommand(cmd) || sp.runKeyCommand(cmd, ctx)
+		}
+		if !handled && kc.Control {
+			// Control-modified commands that don't apply (e.g., DCB menus
+			// when the DCB isn't shown) fall back to the unmodified key.
+			kc.Control = false
+			if cmd, ok := kb.Lookup(kc); ok {
+				handled = kb.RunLayerCommand(cmd) || sp.runKeyCommand(cmd, ctx)
 			}
-		})
+		}
+		if handled {
+			ConsumeKeyChord(ctx.keyboard, kc)
+		}
 	}
 
 	input := strings.ToUpper(ctx.keyboard.Input)
@@ -1792,158 +2789,279 @@ func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
 	}
 	sp.previewAreaInput += strings.Replace(input, "`", STARSTriangleCharacter, -1)
 
-	ps := &sp.CurrentPreferenceSet
-
-	if ctx.keyboard.IsPressed(KeyControl) && len(input) == 1 && unicode.IsDigit(rune(input[0])) {
-		idx := byte(input[0]) - '0'
-		// This test should be redundant given the IsDigit check, but just to be safe...
-		if int(idx) < len(ps.Bookmarks) {
-			if ctx.keyboard.IsPressed(KeyAlt) {
-				// Record bookmark
-				ps.Bookmarks[idx].Center = ps.CurrentCenter
-				ps.Bookmarks[idx].Range = ps.Range
-				ps.Bookmarks[idx].TopDownMode = ps.TopDownMode
-			} else {
-				// Recall bookmark
-				ps.Center = ps.Bookmarks[idx].Center
-				ps.CurrentCenter = ps.Bookmarks[idx].Center
-				ps.Range = ps.Bookmarks[idx].Range
-				ps.TopDownMode = ps.Bookmarks[idx].TopDownMode
-			}
+	// Text editing keys aren't remappable.
+	if ctx.keyboard.IsPressed(KeyBackspace) {
+		if len(sp.previewAreaInput) > 0 {
+			// We need to be careful to deal with UTF8 for the triangle...
+			r := []rune(sp.previewAreaInput)
+			sp.previewAreaInput = string(r[:len(r)-1])
+		} else {
+			sp.multiFuncPrefix = ""
 		}
 	}
-
-	for key := range ctx.keyboard.Pressed {
-		switch key {
-		case KeyBackspace:
-			if len(sp.previewAreaInput) > 0 {
-				// We need to be careful to deal with UTF8 for the triangle...
-				r := []rune(sp.previewAreaInput)
-				sp.previewAreaInput = string(r[:len(r)-1])
-			} else {
-				sp.multiFuncPrefix = ""
+	if ctx.keyboard.IsPressed(KeyEnter) {
+		if status := sp.executeSTARSCommand(sp.previewAreaInput, ctx); status.err != nil {
+			sp.displayError(status.err)
+		} else {
+			if status.clear {
+				sp.resetInputState()
 			}
+			sp.previewAreaOutput = status.output
+		}
+	}
+}
 
-		case KeyEnd:
-			sp.resetInputState()
-			sp.commandMode = CommandModeMin
+// runKeyCommand runs a command bound to a key (see keymap.go) and returns
+// false if the command doesn't apply in the current state.
+func (sp *STARSPane) runKeyCommand(cmd string, ctx *PaneContext) bool {
+	ps := &sp.CurrentPreferenceSet
 
-		case KeyEnter:
-			if status := sp.executeSTARSCommand(sp.previewAreaInput, ctx); status.err != nil {
-				sp.displayError(status.err)
-			} else {
-				if status.clear {
-					sp.resetInputState()
-				}
-				sp.previewAreaOutput = status.output
-			}
-
-		case KeyEscape:
-			sp.resetInputState()
-			sp.activeDCBMenu = DCBMenuMain
-			// Also disable any mouse capture from spinners, just in case
-			// the user is mashing escape to get out of one.
-			sp.disableMenuSpinner(ctx)
-			sp.wipRBL = nil
+	var idx int
+	if n, err := fmt.Sscanf(cmd, "stars.bookmark-recall-%d", &idx); n == 1 && err == nil {
+		if idx >= 0 && idx < len(ps.Bookmarks) {
+			sp.recallBookmark(idx)
+		}
+		return true
+	}
+	if n, err := fmt.Sscanf(cmd, "stars.bookmark-save-%d", &idx); n == 1 && err == nil {
+		if idx >= 0 && idx < len(ps.Bookmarks) {
+			sp.saveBookmark(idx)
+		}
+		return true
+	}
+	if n, err := fmt.Sscanf(cmd, "stars.prefset-%d", &idx); n == 1 && err == nil {
+		if idx--; idx < 0 || idx >= len(sp.PreferenceSets) {
+			return false
+		}
+		sp.SelectedPreferenceSet = idx
+		sp.CurrentPreferenceSet = sp.PreferenceSets[idx]
+		sp.weatherRadar.Activate(sp.CurrentPreferenceSet.Center, ctx.renderer)
+		return true
+	}
 
-		case KeyF1:
-			if ctx.keyboard.IsPressed(KeyControl) {
-				// Recenter
-				ps.Center = ctx.world.GetInitialCenter()
-				ps.CurrentCenter = ps.Center
-			}
+	// Commands that apply to the aircraft under the mouse cursor.
+	closestAircraft := func() *Aircraft {
+		if ctx.mouse == nil {
+			return nil
+		}
+		transforms := GetScopeTransformations(ctx.paneExtent, ctx.world.MagneticVariation, ctx.world.NmPerLongitude,
+			ps.CurrentCenter, float32(ps.Range), ps.Rotation)
+		ac, _ := sp.tryGetClosestAircraft(ctx.world, ctx.mouse.Pos, transforms)
+		return ac
+	}
+	if dir, ok := strings.CutPrefix(cmd, "stars.leader-direction-"); ok {
+		ac := closestAircraft()
+		if ac == nil {
+			return false
+		}
+		if err := sp.setLeaderLine(ctx, ac, dir); err != nil {
+			sp.displayError(err)
+		}
+		return true
+	}
 
-		case KeyF2:
-			if ctx.keyboard.IsPressed(KeyControl) {
-				if ps.DisplayDCB {
-					sp.disableMenuSpinner(ctx)
-					sp.activeDCBMenu = DCBMenuMaps
+	switch cmd {
+	case "screenshot":
+		requestScreenshot(sp)
+	case "screenshot-window":
+		requestScreenshot(nil)
+	case "focus-messages":
+		globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+			if mp, ok := pane.(*MessagesPane); ok {
+				wmTakeKeyboardFocus(mp, false)
+				if ctx.keyboard != nil {
+					delete(ctx.keyboard.Pressed, KeyTab) // prevent cycling back and forth
 				}
-				sp.resetInputState()
-				sp.commandMode = CommandModeMaps
-			}
-
-		case KeyF3:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.disableMenuSpinner(ctx)
-				sp.activeDCBMenu = DCBMenuBrite
-			} else {
-				sp.resetInputState()
-				sp.commandMode = CommandModeInitiateControl
 			}
+		})
 
-		case KeyF4:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.activeDCBMenu = DCBMenuMain
-				sp.activateMenuSpinner(MakeLeaderLineLengthSpinner(&ps.LeaderLineLength))
-				sp.resetInputState()
-				sp.commandMode = CommandModeLDR
-			} else {
-				sp.resetInputState()
-				sp.commandMode = CommandModeTerminateControl
+	case "stars.accept-handoff":
+		inbound := func(ac *Aircraft) bool {
+			return ac.HandoffTrackController == ctx.world.Callsign && ac.RedirectedHandoff.RedirectedTo == ""
+		}
+		if ac := closestAircraft(); ac != nil && inbound(ac) {
+			sp.acceptHandoff(ctx, ac.Callsign)
+			break
+		}
+		for _, callsign := range SortedMapKeys(ctx.world.Aircraft) {
+			if inbound(ctx.world.Aircraft[callsign]) {
+				sp.acceptHandoff(ctx, callsign)
+				return true
 			}
+		}
+		return false
 
-		case KeyF5:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.disableMenuSpinner(ctx)
-				sp.activeDCBMenu = DCBMenuCharSize
-			} else {
-				sp.resetInputState()
-				sp.commandMode = CommandModeHandOff
-			}
+	case "stars.toggle-weather":
+		if slices.Contains(ps.DisplayWeatherLevel[:], true) {
+			sp.hiddenWeatherLevels = ps.DisplayWeatherLevel
+			ps.DisplayWeatherLevel = [6]bool{}
+		} else if slices.Contains(sp.hiddenWeatherLevels[:], true) {
+			ps.DisplayWeatherLevel = sp.hiddenWeatherLevels
+		} else {
+			ps.DisplayWeatherLevel = [6]bool{true, true, true, true, true, true}
+		}
 
-		case KeyF6:
-			sp.resetInputState()
-			sp.commandMode = CommandModeFlightData
+	case "stars.undo", "stars.redo":
+		undo := Select(cmd == "stars.undo", sp.prefsUndo.Undo, sp.prefsUndo.Redo)
+		prev, ok := undo(sp.CurrentPreferenceSet)
+		if !ok {
+			return false
+		}
+		sp.viewAnimation = nil
+		if prev.Center != ps.Center {
+			sp.weatherRadar.Activate(prev.Center, ctx.renderer)
+		}
+		sp.CurrentPreferenceSet = prev
 
-		case KeyF7:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.disableMenuSpinner(ctx)
-				if sp.activeDCBMenu == DCBMenuMain {
-					sp.activeDCBMenu = DCBMenuAux
-				} else {
-					sp.activeDCBMenu = DCBMenuMain
+	case "stars.full-trail":
+		ac := closestAircraft()
+		if ac == nil {
+			// Fall back to the selected aircraft, if there is one.
+			for _, callsign := range SortedMapKeys(sp.Aircraft) {
+				if sp.Aircraft[callsign].IsSelected {
+					ac = ctx.world.Aircraft[callsign]
+					break
 				}
-			} else {
-				sp.resetInputState()
-				sp.commandMode = CommandModeMultiFunc
 			}
+		}
+		if ac == nil {
+			return false
+		}
+		state := sp.Aircraft[ac.Callsign]
+		state.ShowFullTrail = !state.ShowFullTrail
 
-		case KeyF8:
-			if ctx.keyboard.IsPressed(KeyControl) {
-				sp.disableMenuSpinner(ctx)
-				ps.DisplayDCB = !ps.DisplayDCB
-			}
+	case "stars.select-aircraft":
+		ac := closestAircraft()
+		if ac == nil {
+			return false
+		}
+		sp.events.PostEvent(Event{Type: SelectedAircraftEvent, Callsign: ac.Callsign})
 
-		case KeyF9:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.disableMenuSpinner(ctx)
-				sp.activateMenuSpinner(MakeRangeRingRadiusSpinner(&ps.RangeRingRadius))
-				sp.resetInputState()
-				sp.commandMode = CommandModeRangeRings
-			} else {
-				sp.resetInputState()
-				sp.commandMode = CommandModeVFRPlan
-			}
+	case "stars.clear":
+		sp.resetInputState()
+		sp.commandMode = CommandModeMin
 
-		case KeyF10:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.disableMenuSpinner(ctx)
-				sp.activateMenuSpinner(MakeRadarRangeSpinner(&ps.Range))
-				sp.resetInputState()
-				sp.commandMode = CommandModeRange
-			}
+	case "stars.cancel":
+		sp.resetInputState()
+		sp.activeDCBMenu = DCBMenuMain
+		// Also disable any mouse capture from spinners, just in case
+		// the user is mashing escape to get out of one.
+		sp.disableMenuSpinner(ctx)
+		sp.wipRBL = nil
 
-		case KeyF11:
-			if ctx.keyboard.IsPressed(KeyControl) && ps.DisplayDCB {
-				sp.disableMenuSpinner(ctx)
-				sp.activeDCBMenu = DCBMenuSite
-			} else {
-				sp.resetInputState()
-				sp.commandMode = CommandModeCollisionAlert
-			}
+	case "stars.recenter":
+		ps.Center = ctx.world.GetInitialCenter()
+		ps.CurrentCenter = ps.Center
+
+	case "stars.maps":
+		if ps.DisplayDCB {
+			sp.disableMenuSpinner(ctx)
+			sp.activeDCBMenu = DCBMenuMaps
+		}
+		sp.resetInputState()
+		sp.commandMode = CommandModeMaps
+
+	case "stars.brite":
+		if !ps.DisplayDCB {
+			return false
+		}
+		sp.disableMenuSpinner(ctx)
+		sp.activeDCBMenu = DCBMenuBrite
+
+	case "stars.initiate-control":
+		sp.resetInputState()
+		sp.commandMode = CommandModeInitiateControl
+
+	case "stars.leader-length":
+		if !ps.DisplayDCB {
+			return false
+		}
+		sp.activeDCBMenu = DCBMenuMain
+		sp.activateMenuSpinner(MakeLeaderLineLengthSpinner(&ps.LeaderLineLength))
+		sp.resetInputState()
+		sp.commandMode = CommandModeLDR
+
+	case "stars.terminate-control":
+		sp.resetInputState()
+		sp.commandMode = CommandModeTerminateControl
+
+	case "stars.char-size":
+		if !ps.DisplayDCB {
+			return false
+		}
+		sp.disableMenuSpinner(ctx)
+		sp.activeDCBMenu = DCBMenuCharSize
+
+	case "stars.handoff":
+		sp.resetInputState()
+		sp.commandMode = CommandModeHandOff
+
+	case "stars.flight-data":
+		sp.resetInputState()
+		sp.commandMode = CommandModeFlightData
+
+	case "stars.aux-menu":
+		if !ps.DisplayDCB {
+			return false
+		}
+		sp.disableMenuSpinner(ctx)
+		if sp.activeDCBMenu == DCBMenuMain {
+			sp.activeDCBMenu = DCBMenuAux
+		} else {
+			sp.activeDCBMenu = DCBMenuMain
+		}
+
+	case "stars.multifunc":
+		sp.resetInputState()
+		sp.commandMode = CommandModeMultiFunc
+
+	case "stars.toggle-dcb":
+		sp.disableMenuSpinner(ctx)
+		ps.DisplayDCB = !ps.DisplayDCB
+
+	case "stars.range-rings":
+		if !ps.DisplayDCB {
+			return false
+		}
+		sp.disableMenuSpinner(ctx)
+		sp.activateMenuSpinner(MakeRangeRingRadiusSpinner(&ps.RangeRingRadius))
+		sp.resetInputState()
+		sp.commandMode = CommandModeRangeRings
+
+	case "stars.vfr-plan":
+		sp.resetInputState()
+		sp.commandMode = CommandModeVFRPlan
+
+	case "stars.range":
+		if !ps.DisplayDCB {
+			return false
+		}
+		sp.disableMenuSpinner(ctx)
+		sp.activateMenuSpinner(MakeRadarRangeSpinner(&ps.Range))
+		sp.resetInputState()
+		sp.commandMode = CommandModeRange
+
+	case "stars.zoom-in":
+		ps.Range = clamp(ps.Range-1, 6, 256)
+
+	case "stars.zoom-out":
+		ps.Range = clamp(ps.Range+1, 6, 256)
+
+	case "stars.site":
+		if !ps.DisplayDCB {
+			return false
 		}
+		sp.disableMenuSpinner(ctx)
+		sp.activeDCBMenu = DCBMenuSite
+
+	case "stars.collision-alert":
+		sp.resetInputState()
+		sp.commandMode = CommandModeCollisionAlert
+
+	default:
+		return false
 	}
+	return true
 }
 
 func (sp *STARSPane) disableMenuSpinner(ctx *PaneContext) {
@@ -1960,13 +3078,33 @@ func (sp *STARSPane) getAircraftIndex(ac *Aircraft) int {
 	if idx, ok := sp.AircraftToIndex[ac.Callsign]; ok {
 		return idx
 	} else {
-		idx := len(sp.AircraftToIndex) + 1
+		// Use the lowest free index; ones for aircraft that have left are
+		// released by compactAircraftState.
+		idx := 1
+		for _, ok := sp.IndexToAircraft[idx]; ok; _, ok = sp.IndexToAircraft[idx] {
+			idx++
+		}
 		sp.AircraftToIndex[ac.Callsign] = idx
 		sp.IndexToAircraft[idx] = ac.Callsign
 		return idx
 	}
 }
 
+// compactAircraftState releases the list indices of aircraft that are
+// no longer in the world and reallocates the per-aircraft maps so that
+// long sessions don't accumulate state for aircraft that are long gone.
+func (sp *STARSPane) compactAircraftState(w *World) {
+	for callsign, idx := range sp.AircraftToIndex {
+		if _, ok := w.Aircraft[callsign]; !ok {
+			delete(sp.AircraftToIndex, callsign)
+			delete(sp.IndexToAircraft, idx)
+		}
+	}
+	sp.Aircraft = compactMap(sp.Aircraft)
+	sp.AircraftToIndex = compactMap(sp.AircraftToIndex)
+	sp.IndexToAircraft = compactMap(sp.IndexToAircraft)
+}
+
 func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status STARSCommandStatus) {
 	// If there's an active spinner, it gets keyboard input.
 	if activeSpinner != nil {
@@ -2008,6 +3146,53 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 		}
 		return callsign
 	}
+	// Keyboard entry of a command that would otherwise require slewing to
+	// an aircraft: the aircraft is identified by its full callsign or its
+	// beacon code, given as the last field of the command. Only accepting
+	// or initiating a handoff, entering a scratchpad or altitude, setting
+	// the leader line direction, and the J-ring can be entered this way.
+	executeTargetedCommand := func() (STARSCommandStatus, bool) {
+		f := strings.Fields(cmd)
+		if len(f) == 0 || len(f) > 2 {
+			return STARSCommandStatus{}, false
+		}
+
+		id := f[len(f)-1]
+		ac := ctx.world.GetAircraft(id, false)
+		if ac == nil && len(id) == 4 {
+			for _, vac := range sp.visibleAircraft(ctx.world) {
+				if vac.Squawk.String() == id {
+					ac = vac
+					break
+				}
+			}
+		}
+		if ac == nil {
+			return STARSCommandStatus{}, false
+		}
+
+		acCmd := strings.Join(f[:len(f)-1], " ")
+		isAlphanumeric := func(s string) bool {
+			return !strings.ContainsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		}
+		switch {
+		case acCmd == "":
+			// Accept a handoff
+			if ac.HandoffTrackController != ctx.world.Callsign && ac.RedirectedHandoff.RedirectedTo != ctx.world.Callsign {
+				return STARSCommandStatus{}, false
+			}
+		case strings.HasPrefix(acCmd, "*J"), strings.HasPrefix(acCmd, "+"):
+			// J-ring, temporary altitude, or secondary scratchpad
+		case len(acCmd) <= 4 && isAlphanumeric(acCmd):
+			// Handoff, scratchpad, pilot-reported altitude, or leader
+			// line direction
+		default:
+			return STARSCommandStatus{}, false
+		}
+
+		ghosts := sp.getGhostAircraft(sp.visibleAircraft(ctx.world), ctx)
+		return sp.executeSTARSAircraftCommand(ctx, acCmd, ac, false, ghosts)
+	}
 
 	ps := &sp.CurrentPreferenceSet
 	switch sp.commandMode {
@@ -2107,10 +3292,26 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 
 		case ".ROUTE":
 			sp.drawRouteAircraft = ""
+			sp.drawRouteAirport = ""
 			status.clear = true
 			return
 		}
 
+		if airport, ok := strings.CutPrefix(cmd, ".ROUTE "); ok {
+			// Draw the routes of all aircraft arriving at the airport;
+			// entering it again turns them off.
+			if _, ok := ctx.world.ArrivalAirports[airport]; !ok {
+				status.err = ErrSTARSIllegalAirport
+			} else if sp.drawRouteAirport == airport {
+				sp.drawRouteAirport = ""
+				status.clear = true
+			} else {
+				sp.drawRouteAirport = airport
+				status.clear = true
+			}
+			return
+		}
+
 		if len(cmd) > 5 && cmd[:2] == "**" { // Force QL
 			// Manual 6-69
 			cmd = cmd[2:]
@@ -2194,10 +3395,14 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 					status.clear = true
 					return
 				}
-			} else if f[0] == ".FIND" {
+			} else if f[0] == ".FIND" && (len(f) == 2 || (len(f) == 3 && f[2] == "C")) {
+				// .FIND (fix): highlight it; .FIND (fix) C: also recenter
+				// the scope on it.
 				if pos, ok := ctx.world.Locate(f[1]); ok {
-					globalConfig.highlightedLocation = pos
-					globalConfig.highlightedLocationEndTime = ctx.now.Add(5 * time.Second)
+					HighlightLocation(pos, 5*time.Second)
+					if len(f) == 3 || sp.FindRecenters {
+						ps.CurrentCenter = pos
+					}
 					status.clear = true
 					return
 				} else {
@@ -2228,6 +3433,11 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 				status.clear = true
 				return
 			} else {
+				if _, _, err := sp.parseQuickLookPositions(ctx, cmd); err != nil {
+					if st, ok := executeTargetedCommand(); ok {
+						return st
+					}
+				}
 				status.clear, sp.previewAreaInput, status.err = sp.updateQL(ctx, cmd)
 				return
 			}
@@ -2973,6 +4183,9 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 				case 'C':
 					updateList(cmd[1:], &ps.CoastList.Visible, &ps.CoastList.Lines)
 					return
+				case 'E':
+					updateList(cmd[1:], &ps.EmergencyList.Visible, &ps.EmergencyList.Lines)
+					return
 				case 'S':
 					updateList(cmd[1:], &ps.SignOnList.Visible, nil)
 					return
@@ -3060,11 +4273,11 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 		f := strings.Fields(cmd)
 		if len(f) == 1 {
 			callsign := lookupCallsign(f[0], false)
-			status.err = ctx.world.SetSquawkAutomatic(callsign)
+			ctx.world.SetSquawkAutomatic(callsign, nil, func(err error) { sp.displayError(err) })
 		} else if len(f) == 2 {
 			if squawk, err := ParseSquawk(f[1]); err == nil {
 				callsign := lookupCallsign(f[0], false)
-				status.err = ctx.world.SetSquawk(callsign, squawk)
+				ctx.world.SetSquawk(callsign, squawk, nil, func(err error) { sp.displayError(err) })
 			} else {
 				status.err = ErrSTARSIllegalCode
 			}
@@ -3352,12 +4565,60 @@ func (sp *STARSPane) handoffTrack(ctx *PaneContext, callsign string, controller
 		return ErrSTARSIllegalPosition
 	}
 
+	if warning := sp.checkLOA(ctx.world, callsign, control.Callsign); warning != "" &&
+		sp.loaOverride != callsign+" "+control.Callsign {
+		sp.loaOverride = callsign + " " + control.Callsign
+		return NewSTARSError(warning)
+	}
+	sp.loaOverride = ""
+
 	ctx.world.HandoffTrack(callsign, control.Callsign, nil,
 		func(err error) { sp.displayError(err) })
 
 	return nil
 }
 
+// checkLOA returns a warning if the aircraft won't meet an LOA altitude
+// restriction for handoffs to the given controller. The altitude the
+// aircraft is expected to cross the fix at is its assigned altitude or,
+// if it doesn't have one, the fix's charted crossing restriction or its
+// current altitude.
+func (sp *STARSPane) checkLOA(w *World, callsign string, controller string) string {
+	ac, ok := w.Aircraft[callsign]
+	if !ok || ac.FlightPlan == nil {
+		return ""
+	}
+
+	for _, loa := range w.STARSFacilityAdaptation.LOAs {
+		if loa.ReceivingController != controller {
+			continue
+		}
+		if len(loa.Airports) > 0 && !slices.Contains(loa.Airports, ac.FlightPlan.DepartureAirport) &&
+			!slices.Contains(loa.Airports, ac.FlightPlan.ArrivalAirport) {
+			continue
+		}
+		idx := slices.IndexFunc(ac.Nav.Waypoints, func(wp Waypoint) bool { return wp.Fix == loa.Fix })
+		if idx == -1 {
+			continue
+		}
+		ar, err := ParseAltitudeRestriction(loa.Altitude)
+		if err != nil {
+			continue
+		}
+
+		alt := ac.Altitude()
+		if ac.Nav.Altitude.Assigned != nil {
+			alt = *ac.Nav.Altitude.Assigned
+		} else if wpar := ac.Nav.Waypoints[idx].AltitudeRestriction; wpar != nil {
+			alt = wpar.TargetAltitude(alt)
+		}
+		if abs(ar.TargetAltitude(alt)-alt) > 100 {
+			return "LOA: MUST BE " + strings.ToUpper(ar.Summary()) + " BY " + loa.Fix
+		}
+	}
+	return ""
+}
+
 // returns the controller responsible for the aircraft given its altitude
 // and route.
 func calculateAirspace(ctx *PaneContext, callsign string) (string, error) {
@@ -3564,739 +4825,813 @@ func (sp *STARSPane) executeSTARSClickedCommand(ctx *PaneContext, cmd string, mo
 	}
 
 	if ac != nil {
-		state := sp.Aircraft[ac.Callsign]
-
-		switch sp.commandMode {
-		case CommandModeNone:
-			if cmd == "" {
-				if time.Until(state.RDIndicatorEnd) > 0 {
-					if state.OutboundHandoffAccepted {
-						state.OutboundHandoffAccepted = false
-						state.OutboundHandoffFlashEnd = ctx.now
-					}
-					state.RDIndicatorEnd = time.Time{}
-					status.clear = true
-					return
-				} else if ac.RedirectedHandoff.RedirectedTo == ctx.world.Callsign || ac.RedirectedHandoff.GetLastRedirector() == ctx.world.Callsign {
-					sp.acceptRedirectedHandoff(ctx, ac.Callsign)
-					status.clear = true
-					return
-				} else if ac.HandoffTrackController == ctx.world.Callsign && ac.RedirectedHandoff.RedirectedTo == "" {
-					status.clear = true
-					sp.acceptHandoff(ctx, ac.Callsign)
-					return
-				} else if slices.Contains(ac.ForceQLControllers, ctx.world.Callsign) {
-					sp.RemoveForceQL(ctx, ac.Callsign, ctx.world.Callsign)
-					status.clear = true
-					return
-				} else if slices.ContainsFunc(sp.CAAircraft, func(ca CAAircraft) bool {
-					return (ca.Callsigns[0] == ac.Callsign || ca.Callsigns[1] == ac.Callsign) &&
-						!ca.Acknowledged
-				}) {
-					// Acknowledged a CA
-					for i, ca := range sp.CAAircraft {
-						if ca.Callsigns[0] == ac.Callsign || ca.Callsigns[1] == ac.Callsign {
-							status.clear = true
-							sp.CAAircraft[i].Acknowledged = true
-							return
-						}
-					}
-				} else if state.MSAW && !state.MSAWAcknowledged {
-					// Acknowledged a MSAW
-					state.MSAWAcknowledged = true
-				} else if ac.HandoffTrackController != "" && ac.HandoffTrackController != ctx.world.Callsign &&
-					ac.TrackingController == ctx.world.Callsign {
-					// cancel offered handoff offered
-					status.clear = true
-					sp.cancelHandoff(ctx, ac.Callsign)
-					return
-				} else if _, ok := sp.InboundPointOuts[ac.Callsign]; ok {
-					// ack point out
-					sp.acknowledgePointOut(ctx, ac.Callsign)
-					status.clear = true
-					return
-				} else if state.PointedOut {
-					state.PointedOut = false
-					status.clear = true
-					return
-				} else if state.ForceQL {
-					state.ForceQL = false
-					status.clear = true
-				} else if _, ok := sp.RejectedPointOuts[ac.Callsign]; ok {
-					// ack rejected point out
-					delete(sp.RejectedPointOuts, ac.Callsign)
-					status.clear = true
-					return
-				} else if state.OutboundHandoffAccepted {
-					// ack an accepted handoff
-					status.clear = true
-					state.OutboundHandoffAccepted = false
-					state.OutboundHandoffFlashEnd = ctx.now
-
-					return
-				} else if ctx.keyboard != nil {
-					_, ctrl := ctx.keyboard.Pressed[KeyControl]
-					_, shift := ctx.keyboard.Pressed[KeyShift]
-					if ctrl && shift {
-						// initiate track, CRC style
-						status.clear = true
-						sp.initiateTrack(ctx, ac.Callsign)
-						return
-					}
-				}
-				if db := sp.datablockType(ctx, ac); db == LimitedDatablock && state.FullLDBEndTime.Before(ctx.now) {
-					state.FullLDBEndTime = ctx.now.Add(5 * time.Second)
-					// do not collapse datablock if user is tracking the aircraft
-				} else if db == FullDatablock && ac.TrackingController != ctx.world.Callsign {
-					state.DatablockType = PartialDatablock
-				} else {
-					state.DatablockType = FullDatablock
-				}
-
-				if ac.TrackingController == ctx.world.Callsign {
-					status.output = slewAircaft(ctx.world, ac)
-				}
-
-			} else if cmd == "." {
-				if err := sp.setScratchpad(ctx, ac.Callsign, "", false, true); err != nil {
-					status.err = err
-				} else {
-					status.clear = true
-				}
-				return
-			} else if cmd == "+" {
-				if err := sp.setScratchpad(ctx, ac.Callsign, "", true, true); err != nil {
-					status.err = err
-				} else {
-					status.clear = true
-				}
-				return
-			} else if cmd == "*" {
-				from := sp.Aircraft[ac.Callsign].TrackPosition()
-				sp.scopeClickHandler = func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
-					p := transforms.LatLongFromWindowP(pw)
-					hdg := headingp2ll(from, p, ac.NmPerLongitude(), ac.MagneticVariation())
-					dist := nmdistance2ll(from, p)
+		clickedGhost := ghost != nil && ghostDistance < acDistance
+		if status, handled := sp.executeSTARSAircraftCommand(ctx, cmd, ac, clickedGhost, ghosts); handled {
+			return status
+		}
+	}
 
-					status.output = fmt.Sprintf("%03d/%.2f", int(hdg+.5), dist)
-					status.clear = true
-					return
-				}
-				return
-			} else if (unicode.IsDigit(rune(cmd[0])) && len(cmd) == 1) ||
-				(len(cmd) == 2 && unicode.IsDigit(rune(cmd[1]))) {
-				// 6-81: set locally, 6-101: set system wide
-				if err := sp.setLeaderLine(ctx, ac, cmd); err != nil {
-					status.err = err
-				} else {
-					status.clear = true
-				}
-				return
-			} else if cmd == "?" {
-				ctx.world.PrintInfo(ac)
-				status.clear = true
-				return
-			} else if cmd == "*J" {
-				// remove j-ring for aircraft
-				state.JRingRadius = 0
-				status.clear = true
-				return
-			} else if cmd == "*P" {
-				// remove cone for aircraft
-				state.ConeLength = 0
-				status.clear = true
-				return
-			} else if cmd == "*T" {
-				// range bearing line
-				sp.wipRBL = &STARSRangeBearingLine{}
-				sp.wipRBL.P[0].Callsign = ac.Callsign
-				sp.scopeClickHandler = rblSecondClickHandler(ctx, sp)
-				// Do not clear the input area to allow entering a fix for the second location
-				return
-			} else if StringIsSPC(cmd) {
-				ctx.world.ToggleSPCOverride(ac.Callsign, cmd, nil,
-					func(err error) { sp.displayError(err) })
-				status.clear = true
-				return
-			} else if cmd == "UN" {
-				ctx.world.RejectPointOut(ac.Callsign, nil,
-					func(err error) { sp.displayError(err) })
-				status.clear = true
-				return
-			} else if lc := len(cmd); lc >= 2 && cmd[0:2] == "**" { // Force QL. You need to specify a TCP unless otherwise specified in STARS config
-				// STARS Manual 6-70 (On slew). Cannot go interfacility
-				// TODO: Or can be used to accept a pointout as a handoff.
+	// No aircraft selected
+	if sp.commandMode == CommandModeNone {
+		if cmd == "*T" {
+			sp.wipRBL = &STARSRangeBearingLine{}
+			sp.wipRBL.P[0].Loc = transforms.LatLongFromWindowP(mousePosition)
+			sp.scopeClickHandler = rblSecondClickHandler(ctx, sp)
+			return
+		}
+	}
 
-				if cmd == "**" { // Non specified TCP
-					if ctx.world.STARSFacilityAdaptation.ForceQLToSelf && ac.TrackingController == ctx.world.Callsign {
-						state.ForceQL = true
-						status.clear = true
-						return
-					} else {
-						status.err = ErrSTARSIllegalPosition
-						return
-					}
-				} else {
-					tcps := strings.Split(cmd[2:], " ")
-					if len(tcps) > 0 && tcps[0] == "ALL" {
-						// Force QL for all TCP
-						// Find user fac
-						for _, control := range ctx.world.Controllers {
-							if control.Callsign == ctx.world.Callsign && !control.ERAMFacility {
-								sp.forceQL(ctx, ac.Callsign, ctx.world.Callsign)
-							}
-						}
-					}
-					for _, tcp := range tcps {
-						control := sp.lookupControllerForId(ctx, tcp, ac.Callsign)
-						if control == nil {
-							status.err = ErrSTARSIllegalPosition
-							return
-						}
-						sp.forceQL(ctx, ac.Callsign, control.Callsign)
-					}
-					status.clear = true
-					return
-				}
+	if sp.commandMode == CommandModeMultiFunc {
+		cmd = sp.multiFuncPrefix + cmd
+		if cmd == "D*" {
+			pll := transforms.LatLongFromWindowP(mousePosition)
+			format := func(v float32) string {
+				v = abs(v)
+				d := int(v)
+				v = 60 * (v - float32(d))
+				return fmt.Sprintf("%d %.2f", d, v)
+			}
+			status.output = fmt.Sprintf("%s / %s", format(pll.Latitude()), format(pll.Longitude()))
+			status.clear = true
+			return
+		} else if cmd == "P" {
+			ps.PreviewAreaPosition = transforms.NormalizedFromWindowP(mousePosition)
+			status.clear = true
+			return
+		} else if cmd == "S" {
+			ps.SSAList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.SSAList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "T" {
+			ps.TABList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.TABList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TV" {
+			ps.VFRList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.VFRList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TM" {
+			ps.AlertList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.AlertList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TC" {
+			ps.CoastList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.CoastList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TE" {
+			ps.EmergencyList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.EmergencyList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TS" {
+			ps.SignOnList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.SignOnList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TX" {
+			ps.VideoMapsList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.VideoMapsList.Visible = true
+			status.clear = true
+			return
+		} else if cmd == "TN" {
+			ps.CRDAStatusList.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.CRDAStatusList.Visible = true
+			status.clear = true
+			return
+		} else if len(cmd) == 2 && cmd[0] == 'P' {
+			if idx, err := strconv.Atoi(cmd[1:]); err == nil && idx > 0 && idx <= 3 {
+				ps.TowerLists[idx-1].Position = transforms.NormalizedFromWindowP(mousePosition)
+				ps.TowerLists[idx-1].Visible = true
+				status.clear = true
+				return
+			}
+		}
+	}
+
+	if cmd != "" {
+		status.err = ErrSTARSCommandFormat
+	}
+	return
+}
 
-			} else if cmd == "*D+" {
-				// TODO: this and the following two should give ILL FNCT if
-				// there's no j-ring/[A]TPA cone being displayed for the
-				// track (6-173).
+// executeSTARSAircraftCommand executes a command that applies to a
+// specific aircraft, which may have been selected either by slewing to it
+// or by entering its callsign or beacon code after the command. handled
+// is false if the command isn't an aircraft-specific one.
+func (sp *STARSPane) executeSTARSAircraftCommand(ctx *PaneContext, cmd string, ac *Aircraft, clickedGhost bool,
+	ghosts []*GhostAircraft) (status STARSCommandStatus, handled bool) {
+	handled = true
+	ps := &sp.CurrentPreferenceSet
+	state := sp.Aircraft[ac.Callsign]
 
-				// toggle TPA size display
-				if state.DisplayTPASize == nil {
-					b := ps.DisplayTPASize // new variable; don't alias ps.DisplayTPASize!
-					state.DisplayTPASize = &b
+	switch sp.commandMode {
+	case CommandModeNone:
+		if cmd == "" {
+			if time.Until(state.RDIndicatorEnd) > 0 {
+				if state.OutboundHandoffAccepted {
+					state.OutboundHandoffAccepted = false
+					state.OutboundHandoffFlashEnd = ctx.now
 				}
-				*state.DisplayTPASize = !*state.DisplayTPASize
+				state.RDIndicatorEnd = time.Time{}
 				status.clear = true
 				return
-			} else if cmd == "*D+E" {
-				// enable TPA size display
-				b := true
-				state.DisplayTPASize = &b
+			} else if ac.RedirectedHandoff.RedirectedTo == ctx.world.Callsign || ac.RedirectedHandoff.GetLastRedirector() == ctx.world.Callsign {
+				sp.acceptRedirectedHandoff(ctx, ac.Callsign)
 				status.clear = true
 				return
-			} else if cmd == "*D+I" {
-				// inhibit TPA size display
-				b := false
-				state.DisplayTPASize = &b
+			} else if ac.HandoffTrackController == ctx.world.Callsign && ac.RedirectedHandoff.RedirectedTo == "" {
 				status.clear = true
+				sp.acceptHandoff(ctx, ac.Callsign)
 				return
-			} else if cmd == "*AE" {
-				// Enable ATPA warning/alert cones for the track
-				// TODO: for this and *AI and the two *B commands below, we
-				// should issue an error if not IFR, not displaying FDB, or
-				// not in ATPA approach volume (6-176).
-				b := true
-				state.DisplayATPAWarnAlert = &b
+			} else if slices.Contains(ac.ForceQLControllers, ctx.world.Callsign) {
+				sp.RemoveForceQL(ctx, ac.Callsign, ctx.world.Callsign)
 				status.clear = true
 				return
-			} else if cmd == "*AI" {
-				// Inhibit ATPA warning/alert cones for the track
-				b := false
-				state.DisplayATPAWarnAlert = &b
+			} else if slices.ContainsFunc(sp.CAAircraft, func(ca CAAircraft) bool {
+				return (ca.Callsigns[0] == ac.Callsign || ca.Callsigns[1] == ac.Callsign) &&
+					!ca.Acknowledged
+			}) {
+				// Acknowledged a CA
+				for i, ca := range sp.CAAircraft {
+					if ca.Callsigns[0] == ac.Callsign || ca.Callsigns[1] == ac.Callsign {
+						status.clear = true
+						sp.CAAircraft[i].Acknowledged = true
+						return
+					}
+				}
+			} else if idx := slices.IndexFunc(sp.MCIAircraft, func(mci MCIAircraft) bool {
+				return mci.Callsigns[0] == ac.Callsign && !mci.Acknowledged
+			}); idx != -1 {
+				// Acknowledged a Mode C intruder
+				sp.MCIAircraft[idx].Acknowledged = true
 				status.clear = true
 				return
-			} else if cmd == "*BE" {
-				// Enable ATPA monitor cones for the track
-				b := true
-				state.DisplayATPAMonitor = &b
+			} else if state.MSAW && !state.MSAWAcknowledged {
+				// Acknowledged a MSAW
+				state.MSAWAcknowledged = true
+			} else if ac.HandoffTrackController != "" && ac.HandoffTrackController != ctx.world.Callsign &&
+				ac.TrackingController == ctx.world.Callsign {
+				// cancel offered handoff offered
 				status.clear = true
+				sp.cancelHandoff(ctx, ac.Callsign)
 				return
-			} else if cmd == "*BI" {
-				// Inhibit ATPA monitor cones for the track
-				b := false
-				state.DisplayATPAMonitor = &b
+			} else if _, ok := sp.InboundPointOuts[ac.Callsign]; ok {
+				// ack point out
+				sp.acknowledgePointOut(ctx, ac.Callsign)
 				status.clear = true
 				return
-			} else if alt, err := strconv.Atoi(cmd); err == nil && len(cmd) == 3 {
-				state.pilotAltitude = alt * 100
+			} else if state.PointedOut {
+				state.PointedOut = false
 				status.clear = true
 				return
-			} else if len(cmd) == 5 && cmd[:2] == "++" {
-				if alt, err := strconv.Atoi(cmd[2:]); err == nil {
-					status.err = amendFlightPlan(ctx.world, ac.Callsign, func(fp *FlightPlan) {
-						fp.Altitude = alt * 100
-					})
-					status.clear = true
-				} else {
-					status.err = ErrSTARSCommandFormat
-				}
-				return
-			} else if len(cmd) >= 2 && cmd[0] == '+' {
-				if alt, err := strconv.Atoi(cmd[1:]); err == nil {
-					sp.setTemporaryAltitude(ctx, ac.Callsign, alt*100)
-					status.clear = true
-				} else {
-					if err := sp.setScratchpad(ctx, ac.Callsign, cmd[1:], true, true); err != nil {
-						status.err = err
-					} else {
-						status.clear = true
-					}
-				}
+			} else if state.ForceQL {
+				state.ForceQL = false
+				status.clear = true
+			} else if _, ok := sp.RejectedPointOuts[ac.Callsign]; ok {
+				// ack rejected point out
+				delete(sp.RejectedPointOuts, ac.Callsign)
+				status.clear = true
 				return
-			} else if cmd == ".ROUTE" {
-				sp.drawRouteAircraft = ac.Callsign
+			} else if state.OutboundHandoffAccepted {
+				// ack an accepted handoff
 				status.clear = true
+				state.OutboundHandoffAccepted = false
+				state.OutboundHandoffFlashEnd = ctx.now
+
 				return
-			} else if len(cmd) > 2 && cmd[:2] == "*J" {
-				if r, err := strconv.Atoi(cmd[2:]); err == nil {
-					if r < 1 || r > 30 {
-						status.err = ErrSTARSIllegalValue
-					} else {
-						state.JRingRadius = float32(r)
-						state.ConeLength = 0 // can't have both
-					}
+			} else if ctx.keyboard != nil {
+				_, ctrl := ctx.keyboard.Pressed[KeyControl]
+				_, shift := ctx.keyboard.Pressed[KeyShift]
+				if ctrl && shift {
+					// initiate track, CRC style
 					status.clear = true
-				} else if r, err := strconv.ParseFloat(cmd[2:], 32); err == nil {
-					if r < 1 || r > 30 {
-						status.err = ErrSTARSIllegalValue
-					} else {
-						state.JRingRadius = float32(r)
-						state.ConeLength = 0 // can't have both
-					}
-					status.clear = true
-				} else {
-					status.err = ErrSTARSIllegalParam
+					sp.initiateTrack(ctx, ac.Callsign)
+					return
 				}
+			}
+			if db := sp.datablockType(ctx, ac); db == LimitedDatablock && state.FullLDBEndTime.Before(ctx.now) {
+				state.FullLDBEndTime = ctx.now.Add(5 * time.Second)
+				// do not collapse datablock if user is tracking the aircraft
+			} else if db == FullDatablock && ac.TrackingController != ctx.world.Callsign {
+				state.DatablockType = PartialDatablock
+			} else {
+				state.DatablockType = FullDatablock
+			}
+
+			if ac.TrackingController == ctx.world.Callsign {
+				status.output = slewAircaft(ctx.world, ac)
+			}
+			return
+		} else if cmd == "." {
+			if err := sp.setScratchpad(ctx, ac.Callsign, "", false, true); err != nil {
+				status.err = err
+			} else {
+				status.clear = true
+			}
+			return
+		} else if cmd == "+" {
+			if err := sp.setScratchpad(ctx, ac.Callsign, "", true, true); err != nil {
+				status.err = err
+			} else {
+				status.clear = true
+			}
+			return
+		} else if cmd == "*" {
+			from := sp.Aircraft[ac.Callsign].TrackPosition()
+			sp.scopeClickHandler = func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
+				p := transforms.LatLongFromWindowP(pw)
+				hdg := headingp2ll(from, p, ac.NmPerLongitude(), ac.MagneticVariation())
+				dist := nmdistance2ll(from, p)
+
+				status.output = fmt.Sprintf("%03d/%.2f", int(hdg+.5), dist)
+				status.clear = true
 				return
-			} else if len(cmd) > 2 && cmd[:2] == "*P" {
-				if r, err := strconv.Atoi(cmd[2:]); err == nil {
-					if r < 1 || r > 30 {
-						status.err = ErrSTARSIllegalValue
-					} else {
-						state.ConeLength = float32(r)
-						state.JRingRadius = 0 // can't have both
-					}
-					status.clear = true
-				} else if r, err := strconv.ParseFloat(cmd[2:], 32); err == nil {
-					if r < 1 || r > 30 {
-						status.err = ErrSTARSIllegalValue
-					} else {
-						state.ConeLength = float32(r)
-						state.JRingRadius = 0 // can't have both
-					}
-					status.clear = true
-				} else {
-					status.err = ErrSTARSIllegalParam
-				}
+			}
+			return
+		} else if (unicode.IsDigit(rune(cmd[0])) && len(cmd) == 1) ||
+			(len(cmd) == 2 && unicode.IsDigit(rune(cmd[1]))) {
+			// 6-81: set locally, 6-101: set system wide
+			if err := sp.setLeaderLine(ctx, ac, cmd); err != nil {
+				status.err = err
+			} else {
+				status.clear = true
+			}
+			return
+		} else if cmd == "?" {
+			ctx.world.PrintInfo(ac)
+			status.clear = true
+			return
+		} else if cmd == "*J" {
+			// remove j-ring for aircraft
+			state.JRingRadius = 0
+			status.clear = true
+			return
+		} else if cmd == "*E" {
+			// acknowledge emergency
+			if state.Emergency == "" || state.EmergencyAcknowledged {
+				status.err = ErrSTARSIllegalTrack
 				return
-			} else if lc := len(cmd); lc >= 2 && cmd[lc-1] == '*' { // Some sort of pointout
-				// First check for errors. (Manual 6-73)
+			}
+			state.EmergencyAcknowledged = true
+			sp.emergencies = FilterSlice(sp.emergencies, func(cs string) bool { return cs != ac.Callsign })
+			status.clear = true
+			return
+		} else if cmd == "*P" {
+			// remove cone for aircraft
+			state.ConeLength = 0
+			status.clear = true
+			return
+		} else if cmd == "*T" {
+			// range bearing line
+			sp.wipRBL = &STARSRangeBearingLine{}
+			sp.wipRBL.P[0].Callsign = ac.Callsign
+			sp.scopeClickHandler = rblSecondClickHandler(ctx, sp)
+			// Do not clear the input area to allow entering a fix for the second location
+			return
+		} else if StringIsSPC(cmd) {
+			ctx.world.ToggleSPCOverride(ac.Callsign, cmd, nil,
+				func(err error) { sp.displayError(err) })
+			status.clear = true
+			return
+		} else if tp, ok := strings.CutPrefix(cmd, "TP"); ok && (tp == "" || tp[0] == ' ' || unicode.IsDigit(rune(tp[0]))) {
+			// Trial plan; see trialplan.go
+			if tp == "" {
+				sp.trialPlan = nil
+				status.clear = true
+			} else if sp.trialPlan, status.err = parseTrialPlan(ctx.world, ac, tp); status.err == nil {
+				sp.updateTrialPlan(sp.visibleAircraft(ctx.world), ctx.world)
+				status.output = sp.trialPlan.output()
+				status.clear = true
+			}
+			return
+		} else if cmd == "UN" {
+			ctx.world.RejectPointOut(ac.Callsign, nil,
+				func(err error) { sp.displayError(err) })
+			status.clear = true
+			return
+		} else if lc := len(cmd); lc >= 2 && cmd[0:2] == "**" { // Force QL. You need to specify a TCP unless otherwise specified in STARS config
+			// STARS Manual 6-70 (On slew). Cannot go interfacility
+			// TODO: Or can be used to accept a pointout as a handoff.
 
-				// Check if arrival
-				for _, airport := range ctx.world.ArrivalAirports {
-					if airport.Name == ac.FlightPlan.ArrivalAirport {
-						status.err = ErrSTARSIllegalTrack
-						return
-					}
-				}
-				// Check if being handed off, pointed out or suspended (TODO suspended)
-				if sp.OutboundPointOuts[ac.Callsign] != "" || sp.InboundPointOuts[ac.Callsign] != "" ||
-					(ac.HandoffTrackController != "" && ac.HandoffTrackController != ctx.world.Callsign) {
-					status.err = ErrSTARSIllegalTrack
+			if cmd == "**" { // Non specified TCP
+				if ctx.world.STARSFacilityAdaptation.ForceQLToSelf && ac.TrackingController == ctx.world.Callsign {
+					state.ForceQL = true
+					status.clear = true
 					return
-				}
-
-				control := sp.lookupControllerForId(ctx, strings.TrimSuffix(cmd, "*"), ac.Callsign)
-				if control == nil {
-					status.err = ErrSTARSIllegalPosition
 				} else {
-					status.clear = true
-					sp.pointOut(ctx, ac.Callsign, control.Callsign)
+					status.err = ErrSTARSIllegalPosition
+					return
 				}
-				return
-
-			} else if len(cmd) > 0 {
-				// See if cmd works as a sector id; if so, make it a handoff.
-				control := sp.lookupControllerForId(ctx, cmd, ac.Callsign)
-				if control != nil {
-					if ac.HandoffTrackController == ctx.world.Callsign || ac.RedirectedHandoff.RedirectedTo == ctx.world.Callsign { // Redirect
-						if ac.RedirectedHandoff.ShouldFallbackToHandoff(ctx.world.Callsign, control.Callsign) {
-							sp.Aircraft[ac.Callsign].DatablockType = PartialDatablock
-						} else {
-							sp.Aircraft[ac.Callsign].DatablockType = FullDatablock
+			} else {
+				tcps := strings.Split(cmd[2:], " ")
+				if len(tcps) > 0 && tcps[0] == "ALL" {
+					// Force QL for all TCP
+					// Find user fac
+					for _, control := range ctx.world.Controllers {
+						if control.Callsign == ctx.world.Callsign && !control.ERAMFacility {
+							sp.forceQL(ctx, ac.Callsign, ctx.world.Callsign)
 						}
-						sp.redirectHandoff(ctx, ac.Callsign, control.Callsign)
-						status.clear = true
-					} else if err := sp.handoffTrack(ctx, ac.Callsign, cmd); err == nil {
-						status.clear = true
-					} else {
-						status.err = err
 					}
-				} else {
-					// Try setting the scratchpad
-					if err := sp.setScratchpad(ctx, ac.Callsign, cmd, false, true); err != nil {
-						status.err = err
-					} else {
-						status.clear = true
+				}
+				for _, tcp := range tcps {
+					control := sp.lookupControllerForId(ctx, tcp, ac.Callsign)
+					if control == nil {
+						status.err = ErrSTARSIllegalPosition
+						return
 					}
+					sp.forceQL(ctx, ac.Callsign, control.Callsign)
 				}
+				status.clear = true
 				return
 			}
 
-		case CommandModeInitiateControl:
-			// TODO: error if cmd != ""?
+		} else if cmd == "*D+" {
+			// TODO: this and the following two should give ILL FNCT if
+			// there's no j-ring/[A]TPA cone being displayed for the
+			// track (6-173).
+
+			// toggle TPA size display
+			if state.DisplayTPASize == nil {
+				b := ps.DisplayTPASize // new variable; don't alias ps.DisplayTPASize!
+				state.DisplayTPASize = &b
+			}
+			*state.DisplayTPASize = !*state.DisplayTPASize
 			status.clear = true
-			sp.initiateTrack(ctx, ac.Callsign)
 			return
-
-		case CommandModeTerminateControl:
-			// TODO: error if cmd != ""?
+		} else if cmd == "*D+E" {
+			// enable TPA size display
+			b := true
+			state.DisplayTPASize = &b
 			status.clear = true
-			sp.dropTrack(ctx, ac.Callsign)
 			return
-
-		case CommandModeHandOff:
-			if cmd == "" {
+		} else if cmd == "*D+I" {
+			// inhibit TPA size display
+			b := false
+			state.DisplayTPASize = &b
+			status.clear = true
+			return
+		} else if cmd == "*AE" {
+			// Enable ATPA warning/alert cones for the track
+			// TODO: for this and *AI and the two *B commands below, we
+			// should issue an error if not IFR, not displaying FDB, or
+			// not in ATPA approach volume (6-176).
+			b := true
+			state.DisplayATPAWarnAlert = &b
+			status.clear = true
+			return
+		} else if cmd == "*AI" {
+			// Inhibit ATPA warning/alert cones for the track
+			b := false
+			state.DisplayATPAWarnAlert = &b
+			status.clear = true
+			return
+		} else if cmd == "*BE" {
+			// Enable ATPA monitor cones for the track
+			b := true
+			state.DisplayATPAMonitor = &b
+			status.clear = true
+			return
+		} else if cmd == "*BI" {
+			// Inhibit ATPA monitor cones for the track
+			b := false
+			state.DisplayATPAMonitor = &b
+			status.clear = true
+			return
+		} else if alt, err := strconv.Atoi(cmd); err == nil && len(cmd) == 3 {
+			state.pilotAltitude = alt * 100
+			status.clear = true
+			return
+		} else if len(cmd) == 5 && cmd[:2] == "++" {
+			if alt, err := strconv.Atoi(cmd[2:]); err == nil {
+				status.err = amendFlightPlan(ctx.world, ac.Callsign, func(fp *FlightPlan) {
+					fp.Altitude = alt * 100
+				})
 				status.clear = true
-				sp.cancelHandoff(ctx, ac.Callsign)
 			} else {
-				if err := sp.handoffTrack(ctx, ac.Callsign, cmd); err != nil {
+				status.err = ErrSTARSCommandFormat
+			}
+			return
+		} else if len(cmd) >= 2 && cmd[0] == '+' {
+			if alt, err := strconv.Atoi(cmd[1:]); err == nil {
+				sp.setTemporaryAltitude(ctx, ac.Callsign, alt*100)
+				status.clear = true
+			} else {
+				if err := sp.setScratchpad(ctx, ac.Callsign, cmd[1:], true, true); err != nil {
 					status.err = err
 				} else {
 					status.clear = true
 				}
 			}
 			return
-
-		case CommandModeVFRPlan:
-			// TODO: implement
-			status.err = ErrSTARSCommandFormat
+		} else if cmd == ".ROUTE" {
+			sp.drawRouteAircraft = ac.Callsign
+			status.clear = true
 			return
-
-		case CommandModeMultiFunc:
-			switch sp.multiFuncPrefix {
-			case "B":
-				if cmd == "" {
-					state.DisplayReportedBeacon = !state.DisplayReportedBeacon
-					status.clear = true
+		} else if len(cmd) > 2 && cmd[:2] == "*J" {
+			if r, err := strconv.Atoi(cmd[2:]); err == nil {
+				if r < 1 || r > 30 {
+					status.err = ErrSTARSIllegalValue
 				} else {
-					status.err = ErrSTARSCommandFormat
+					state.JRingRadius = float32(r)
+					state.ConeLength = 0 // can't have both
 				}
-				return
-
-			case "D":
-				if cmd == "" {
-					status.output, status.err = sp.flightPlanSTARS(ctx.world, ac)
-					if status.err == nil {
-						status.clear = true
-					}
+				status.clear = true
+			} else if r, err := strconv.ParseFloat(cmd[2:], 32); err == nil {
+				if r < 1 || r > 30 {
+					status.err = ErrSTARSIllegalValue
 				} else {
-					status.err = ErrSTARSCommandFormat
+					state.JRingRadius = float32(r)
+					state.ConeLength = 0 // can't have both
 				}
-				return
-
-			case "L": // Leader line
-				if err := sp.setLeaderLine(ctx, ac, cmd); err != nil {
-					status.err = err
+				status.clear = true
+			} else {
+				status.err = ErrSTARSIllegalParam
+			}
+			return
+		} else if len(cmd) > 2 && cmd[:2] == "*P" {
+			if r, err := strconv.Atoi(cmd[2:]); err == nil {
+				if r < 1 || r > 30 {
+					status.err = ErrSTARSIllegalValue
 				} else {
-					status.clear = true
+					state.ConeLength = float32(r)
+					state.JRingRadius = 0 // can't have both
+				}
+				status.clear = true
+			} else if r, err := strconv.ParseFloat(cmd[2:], 32); err == nil {
+				if r < 1 || r > 30 {
+					status.err = ErrSTARSIllegalValue
+				} else {
+					state.ConeLength = float32(r)
+					state.JRingRadius = 0 // can't have both
+				}
+				status.clear = true
+			} else {
+				status.err = ErrSTARSIllegalParam
+			}
+			return
+		} else if lc := len(cmd); lc >= 2 && cmd[lc-1] == '*' { // Some sort of pointout
+			// First check for errors. (Manual 6-73)
+
+			// Check if arrival
+			for _, airport := range ctx.world.ArrivalAirports {
+				if airport.Name == ac.FlightPlan.ArrivalAirport {
+					status.err = ErrSTARSIllegalTrack
+					return
 				}
+			}
+			// Check if being handed off, pointed out or suspended (TODO suspended)
+			if sp.OutboundPointOuts[ac.Callsign] != "" || sp.InboundPointOuts[ac.Callsign] != "" ||
+				(ac.HandoffTrackController != "" && ac.HandoffTrackController != ctx.world.Callsign) {
+				status.err = ErrSTARSIllegalTrack
 				return
+			}
 
-			case "M":
-				if cmd == "" {
-					state.displayPilotAltitude = !state.displayPilotAltitude
+			control := sp.lookupControllerForId(ctx, strings.TrimSuffix(cmd, "*"), ac.Callsign)
+			if control == nil {
+				status.err = ErrSTARSIllegalPosition
+			} else {
+				status.clear = true
+				sp.pointOut(ctx, ac.Callsign, control.Callsign)
+			}
+			return
+
+		} else if len(cmd) > 0 {
+			// See if cmd works as a sector id; if so, make it a handoff.
+			control := sp.lookupControllerForId(ctx, cmd, ac.Callsign)
+			if control != nil {
+				if ac.HandoffTrackController == ctx.world.Callsign || ac.RedirectedHandoff.RedirectedTo == ctx.world.Callsign { // Redirect
+					if ac.RedirectedHandoff.ShouldFallbackToHandoff(ctx.world.Callsign, control.Callsign) {
+						sp.Aircraft[ac.Callsign].DatablockType = PartialDatablock
+					} else {
+						sp.Aircraft[ac.Callsign].DatablockType = FullDatablock
+					}
+					sp.redirectHandoff(ctx, ac.Callsign, control.Callsign)
+					status.clear = true
+				} else if err := sp.handoffTrack(ctx, ac.Callsign, cmd); err == nil {
 					status.clear = true
 				} else {
-					status.err = ErrSTARSCommandFormat
+					status.err = err
 				}
-				return
+			} else {
+				// Try setting the scratchpad
+				if err := sp.setScratchpad(ctx, ac.Callsign, cmd, false, true); err != nil {
+					status.err = err
+				} else {
+					status.clear = true
+				}
+			}
+			return
+		}
 
-			case "N":
-				// CRDA
-				if cmd == "" {
-					clickedGhost := ghost != nil && ghostDistance < acDistance
-					if clickedGhost {
-						state.Ghost.State = GhostStateSuppressed
-					} else if slices.ContainsFunc(ghosts, func(g *GhostAircraft) bool { return g.Callsign == ac.Callsign }) {
-						state.Ghost.State = GhostStateRegular
+	case CommandModeInitiateControl:
+		// TODO: error if cmd != ""?
+		status.clear = true
+		sp.initiateTrack(ctx, ac.Callsign)
+		return
+
+	case CommandModeTerminateControl:
+		// TODO: error if cmd != ""?
+		status.clear = true
+		sp.dropTrack(ctx, ac.Callsign)
+		return
+
+	case CommandModeHandOff:
+		if cmd == "" {
+			status.clear = true
+			sp.cancelHandoff(ctx, ac.Callsign)
+		} else {
+			if err := sp.handoffTrack(ctx, ac.Callsign, cmd); err != nil {
+				status.err = err
+			} else {
+				status.clear = true
+			}
+		}
+		return
+
+	case CommandModeVFRPlan:
+		// TODO: implement
+		status.err = ErrSTARSCommandFormat
+		return
+
+	case CommandModeMultiFunc:
+		switch sp.multiFuncPrefix {
+		case "B":
+			if cmd == "" {
+				state.DisplayReportedBeacon = !state.DisplayReportedBeacon
+				status.clear = true
+			} else {
+				status.err = ErrSTARSCommandFormat
+			}
+			return
+
+		case "D":
+			if cmd == "" {
+				status.output, status.err = sp.flightPlanSTARS(ctx.world, ac)
+				if status.err == nil {
+					status.clear = true
+				}
+			} else {
+				status.err = ErrSTARSCommandFormat
+			}
+			return
+
+		case "L": // Leader line
+			if err := sp.setLeaderLine(ctx, ac, cmd); err != nil {
+				status.err = err
+			} else {
+				status.clear = true
+			}
+			return
+
+		case "M":
+			if cmd == "" {
+				state.displayPilotAltitude = !state.displayPilotAltitude
+				status.clear = true
+			} else {
+				status.err = ErrSTARSCommandFormat
+			}
+			return
+
+		case "N":
+			// CRDA
+			if cmd == "" {
+				if clickedGhost {
+					state.Ghost.State = GhostStateSuppressed
+				} else if slices.ContainsFunc(ghosts, func(g *GhostAircraft) bool { return g.Callsign == ac.Callsign }) {
+					state.Ghost.State = GhostStateRegular
+				} else {
+					status.err = ErrSTARSIllegalTrack
+				}
+			} else if cmd == "*" {
+				if clickedGhost {
+					// 6-27: display track information in preview area (as an arrival)
+					if fp, err := sp.flightPlanSTARS(ctx.world, ac); err != nil {
+						status.err = err
 					} else {
-						status.err = ErrSTARSIllegalTrack
+						status.output = fp
+						status.clear = true
 					}
-				} else if cmd == "*" {
-					clickedGhost := ghost != nil && ghostDistance < acDistance
-					if clickedGhost {
-						// 6-27: display track information in preview area (as an arrival)
-						if fp, err := sp.flightPlanSTARS(ctx.world, ac); err != nil {
-							status.err = err
-						} else {
-							status.output = fp
-							status.clear = true
-						}
+				} else {
+					// 6-29: force/unforce ghost qualification
+					if !slices.ContainsFunc(ghosts, func(g *GhostAircraft) bool { return g.Callsign == ac.Callsign }) {
+						status.err = ErrSTARSIllegalTrack
 					} else {
-						// 6-29: force/unforce ghost qualification
-						if !slices.ContainsFunc(ghosts, func(g *GhostAircraft) bool { return g.Callsign == ac.Callsign }) {
-							status.err = ErrSTARSIllegalTrack
-						} else {
-							// Is it inside an enabled approach region?
-							for i, pairState := range ps.CRDA.RunwayPairState {
-								if !pairState.Enabled {
+						// Is it inside an enabled approach region?
+						for i, pairState := range ps.CRDA.RunwayPairState {
+							if !pairState.Enabled {
+								continue
+							}
+							for j, rwyState := range pairState.RunwayState {
+								if !rwyState.Enabled {
 									continue
 								}
-								for j, rwyState := range pairState.RunwayState {
-									if !rwyState.Enabled {
-										continue
-									}
-									region := sp.ConvergingRunways[i].ApproachRegions[j]
-									if lat, _ := region.Inside(state.TrackPosition(), float32(state.TrackAltitude()),
-										ctx.world.NmPerLongitude, ctx.world.MagneticVariation); lat {
-										// All good. Whew
-										if state.Ghost.State == GhostStateForced {
-											state.Ghost.State = GhostStateRegular
-										} else {
-											state.Ghost.State = GhostStateForced
-										}
-										status.clear = true
-										return
+								region := sp.ConvergingRunways[i].ApproachRegions[j]
+								if lat, _ := region.Inside(state.TrackPosition(), float32(state.TrackAltitude()),
+									ctx.world.NmPerLongitude, ctx.world.MagneticVariation); lat {
+									// All good. Whew
+									if state.Ghost.State == GhostStateForced {
+										state.Ghost.State = GhostStateRegular
+									} else {
+										state.Ghost.State = GhostStateForced
 									}
+									status.clear = true
+									return
 								}
 							}
-							status.err = ErrSTARSIllegalTrack
 						}
-					}
-				} else {
-					status.err = ErrSTARSCommandFormat
-				}
-				return
-
-			case "Q":
-				if cmd == "" {
-					if ac.TrackingController != ctx.world.Callsign && ac.ControllingController != ctx.world.Callsign {
 						status.err = ErrSTARSIllegalTrack
-					} else {
-						status.clear = true
-						state.InhibitMSAW = true
 					}
-				} else {
-					status.err = ErrSTARSCommandFormat
 				}
-				return
-
-			case "R":
-				switch cmd {
-				case "":
-					if ps.PTLAll || (ps.PTLOwn && ac.TrackingController == ctx.world.Callsign) {
-						status.err = ErrSTARSIllegalTrack // 6-13
-					} else {
-						state.DisplayPTL = !state.DisplayPTL
-						status.clear = true
+			} else if cmd == "?" {
+				// Report whether the aircraft qualifies for a ghost for
+				// each enabled runway and, if not, why.
+				var lines []string
+				force := state.Ghost.State == GhostStateForced || ps.CRDA.ForceAllGhosts
+				heading := Select(state.HaveHeading(), state.TrackHeading(ac.NmPerLongitude()), ac.Heading())
+				for i, pairState := range ps.CRDA.RunwayPairState {
+					if !pairState.Enabled {
+						continue
 					}
-					return
-				case "A": // toggle requested altitude: 6-108
-					if sp.datablockType(ctx, ac) != FullDatablock {
-						status.err = ErrSTARSIllegalFunction
-					} else {
-						if state.DisplayRequestedAltitude == nil {
-							b := ps.DisplayRequestedAltitude // inherit from system-wide
-							state.DisplayRequestedAltitude = &b
+					for j, rwyState := range pairState.RunwayState {
+						if !rwyState.Enabled {
+							continue
 						}
-						*state.DisplayRequestedAltitude = !*state.DisplayRequestedAltitude
-						status.clear = true
-					}
-					return
-				case "AE": // enable requested altitude: 6-108
-					if sp.datablockType(ctx, ac) != FullDatablock {
-						status.err = ErrSTARSIllegalFunction
-					} else {
-						b := true
-						state.DisplayRequestedAltitude = &b
-						status.clear = true
-					}
-					return
-				case "AI": // inhibit requested altitude: 6-108
-					if sp.datablockType(ctx, ac) != FullDatablock {
-						status.err = ErrSTARSIllegalFunction
-					} else {
-						b := false
-						state.DisplayRequestedAltitude = &b
-						status.clear = true
+						pair := sp.ConvergingRunways[i]
+						q := pair.ApproachRegions[j].Qualify(state.track, heading, ac.Scratchpad, force,
+							ac.NmPerLongitude(), ac.MagneticVariation())
+						lines = append(lines, pair.Airport+" "+pair.Runways[j]+" "+q.String())
 					}
-					return
 				}
-
-			case "V":
-				if cmd == "" {
-					if ac.TrackingController != ctx.world.Callsign && ac.ControllingController != ctx.world.Callsign {
-						status.err = ErrSTARSIllegalTrack
-					} else {
-						state.DisableMSAW = !state.DisableMSAW
-						status.clear = true
-					}
+				if len(lines) == 0 {
+					status.err = ErrSTARSIllegalFunction
 				} else {
-					status.err = ErrSTARSCommandFormat
+					status.output = strings.Join(lines, "\n")
+					status.clear = true
 				}
-				return
+			} else {
+				status.err = ErrSTARSCommandFormat
+			}
+			return
 
-			case "Y":
-				isSecondary := false
-				if len(cmd) > 0 && cmd[0] == '+' {
-					isSecondary = true
-					cmd = cmd[1:]
+		case "Q":
+			if cmd == "" {
+				if ac.TrackingController != ctx.world.Callsign && ac.ControllingController != ctx.world.Callsign {
+					status.err = ErrSTARSIllegalTrack
+				} else {
+					status.clear = true
+					state.InhibitMSAW = true
 				}
+			} else {
+				status.err = ErrSTARSCommandFormat
+			}
+			return
 
-				if cmd == "" {
-					// Clear pilot reported altitude and scratchpad
-					state.pilotAltitude = 0
-					if err := sp.setScratchpad(ctx, ac.Callsign, "", isSecondary, false); err != nil {
-						status.err = err
-					} else {
-						status.clear = true
-					}
-					return
+		case "R":
+			switch cmd {
+			case "":
+				if ps.PTLAll || (ps.PTLOwn && ac.TrackingController == ctx.world.Callsign) {
+					status.err = ErrSTARSIllegalTrack // 6-13
 				} else {
-					// Is it an altitude or a scratchpad update?
-					if alt, err := strconv.Atoi(cmd); err == nil && len(cmd) == 3 {
-						state.pilotAltitude = alt * 100
-						status.clear = true
-					} else {
-						if err := sp.setScratchpad(ctx, ac.Callsign, cmd, isSecondary, false); err != nil {
-							status.err = err
-						} else {
-							status.clear = true
-						}
+					state.DisplayPTL = !state.DisplayPTL
+					status.clear = true
+				}
+				return
+			case "A": // toggle requested altitude: 6-108
+				if sp.datablockType(ctx, ac) != FullDatablock {
+					status.err = ErrSTARSIllegalFunction
+				} else {
+					if state.DisplayRequestedAltitude == nil {
+						b := ps.DisplayRequestedAltitude // inherit from system-wide
+						state.DisplayRequestedAltitude = &b
 					}
-					return
+					*state.DisplayRequestedAltitude = !*state.DisplayRequestedAltitude
+					status.clear = true
 				}
-			case "O": //Pointout history
-				if ac.TrackingController != ctx.world.Callsign {
-					status.err = ErrSTARSIllegalTrack
-					return
+				return
+			case "AE": // enable requested altitude: 6-108
+				if sp.datablockType(ctx, ac) != FullDatablock {
+					status.err = ErrSTARSIllegalFunction
+				} else {
+					b := true
+					state.DisplayRequestedAltitude = &b
+					status.clear = true
+				}
+				return
+			case "AI": // inhibit requested altitude: 6-108
+				if sp.datablockType(ctx, ac) != FullDatablock {
+					status.err = ErrSTARSIllegalFunction
+				} else {
+					b := false
+					state.DisplayRequestedAltitude = &b
+					status.clear = true
 				}
-
-				status.output = strings.Join(ac.PointOutHistory, " ")
-				status.clear = true
 				return
 			}
 
-		case CommandModeFlightData:
+		case "V":
 			if cmd == "" {
-				status.clear = true
-				status.err = ctx.world.SetSquawkAutomatic(ac.Callsign)
-				return
-			} else {
-				if squawk, err := ParseSquawk(cmd); err == nil {
-					status.err = ctx.world.SetSquawk(ac.Callsign, squawk)
+				if ac.TrackingController != ctx.world.Callsign && ac.ControllingController != ctx.world.Callsign {
+					status.err = ErrSTARSIllegalTrack
 				} else {
-					status.err = ErrSTARSIllegalParam
+					state.DisableMSAW = !state.DisableMSAW
+					status.clear = true
 				}
-				status.clear = true
-				return
+			} else {
+				status.err = ErrSTARSCommandFormat
 			}
+			return
 
-		case CommandModeCollisionAlert:
-			if cmd == "K" {
-				state := sp.Aircraft[ac.Callsign]
-				state.DisableCAWarnings = !state.DisableCAWarnings
-				status.clear = true
-				// TODO: check should we set sp.commandMode = CommandMode
-				// (applies here and also to others similar...)
-				return
+		case "Y":
+			isSecondary := false
+			if len(cmd) > 0 && cmd[0] == '+' {
+				isSecondary = true
+				cmd = cmd[1:]
 			}
 
-		case CommandModeMin:
 			if cmd == "" {
-				sp.MinSepAircraft[0] = ac.Callsign
-				sp.scopeClickHandler = func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
-					if ac, _ := sp.tryGetClosestAircraft(ctx.world, pw, transforms); ac != nil {
-						sp.MinSepAircraft[1] = ac.Callsign
-						status.clear = true
+				// Clear pilot reported altitude and scratchpad
+				state.pilotAltitude = 0
+				if err := sp.setScratchpad(ctx, ac.Callsign, "", isSecondary, false); err != nil {
+					status.err = err
+				} else {
+					status.clear = true
+				}
+				return
+			} else {
+				// Is it an altitude or a scratchpad update?
+				if alt, err := strconv.Atoi(cmd); err == nil && len(cmd) == 3 {
+					state.pilotAltitude = alt * 100
+					status.clear = true
+				} else {
+					if err := sp.setScratchpad(ctx, ac.Callsign, cmd, isSecondary, false); err != nil {
+						status.err = err
 					} else {
-						status.err = ErrSTARSNoFlight
+						status.clear = true
 					}
-					return
 				}
-			} else {
-				status.err = ErrSTARSCommandFormat
 				return
 			}
-		}
-	}
+		case "O": //Pointout history
+			if ac.TrackingController != ctx.world.Callsign {
+				status.err = ErrSTARSIllegalTrack
+				return
+			}
 
-	// No aircraft selected
-	if sp.commandMode == CommandModeNone {
-		if cmd == "*T" {
-			sp.wipRBL = &STARSRangeBearingLine{}
-			sp.wipRBL.P[0].Loc = transforms.LatLongFromWindowP(mousePosition)
-			sp.scopeClickHandler = rblSecondClickHandler(ctx, sp)
+			status.output = strings.Join(ac.PointOutHistory, " ")
+			status.clear = true
 			return
 		}
-	}
 
-	if sp.commandMode == CommandModeMultiFunc {
-		cmd = sp.multiFuncPrefix + cmd
-		if cmd == "D*" {
-			pll := transforms.LatLongFromWindowP(mousePosition)
-			format := func(v float32) string {
-				v = abs(v)
-				d := int(v)
-				v = 60 * (v - float32(d))
-				return fmt.Sprintf("%d %.2f", d, v)
-			}
-			status.output = fmt.Sprintf("%s / %s", format(pll.Latitude()), format(pll.Longitude()))
-			status.clear = true
-			return
-		} else if cmd == "P" {
-			ps.PreviewAreaPosition = transforms.NormalizedFromWindowP(mousePosition)
-			status.clear = true
-			return
-		} else if cmd == "S" {
-			ps.SSAList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.SSAList.Visible = true
-			status.clear = true
-			return
-		} else if cmd == "T" {
-			ps.TABList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.TABList.Visible = true
-			status.clear = true
-			return
-		} else if cmd == "TV" {
-			ps.VFRList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.VFRList.Visible = true
-			status.clear = true
-			return
-		} else if cmd == "TM" {
-			ps.AlertList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.AlertList.Visible = true
-			status.clear = true
-			return
-		} else if cmd == "TC" {
-			ps.CoastList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.CoastList.Visible = true
-			status.clear = true
-			return
-		} else if cmd == "TS" {
-			ps.SignOnList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.SignOnList.Visible = true
+	case CommandModeFlightData:
+		if cmd == "" {
 			status.clear = true
+			ctx.world.SetSquawkAutomatic(ac.Callsign, nil, func(err error) { sp.displayError(err) })
 			return
-		} else if cmd == "TX" {
-			ps.VideoMapsList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.VideoMapsList.Visible = true
+		} else {
+			if squawk, err := ParseSquawk(cmd); err == nil {
+				ctx.world.SetSquawk(ac.Callsign, squawk, nil, func(err error) { sp.displayError(err) })
+			} else {
+				status.err = ErrSTARSIllegalParam
+			}
 			status.clear = true
 			return
-		} else if cmd == "TN" {
-			ps.CRDAStatusList.Position = transforms.NormalizedFromWindowP(mousePosition)
-			ps.CRDAStatusList.Visible = true
+		}
+
+	case CommandModeCollisionAlert:
+		if cmd == "K" {
+			state := sp.Aircraft[ac.Callsign]
+			state.DisableCAWarnings = !state.DisableCAWarnings
 			status.clear = true
+			// TODO: check should we set sp.commandMode = CommandMode
+			// (applies here and also to others similar...)
 			return
-		} else if len(cmd) == 2 && cmd[0] == 'P' {
-			if idx, err := strconv.Atoi(cmd[1:]); err == nil && idx > 0 && idx <= 3 {
-				ps.TowerLists[idx-1].Position = transforms.NormalizedFromWindowP(mousePosition)
-				ps.TowerLists[idx-1].Visible = true
-				status.clear = true
+		}
+
+	case CommandModeMin:
+		if cmd == "" {
+			sp.MinSepAircraft[0] = ac.Callsign
+			sp.scopeClickHandler = func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
+				if ac, _ := sp.tryGetClosestAircraft(ctx.world, pw, transforms); ac != nil {
+					sp.MinSepAircraft[1] = ac.Callsign
+					status.clear = true
+				} else {
+					status.err = ErrSTARSNoFlight
+				}
 				return
 			}
+			return
+		} else {
+			status.err = ErrSTARSCommandFormat
+			return
 		}
 	}
 
-	if cmd != "" {
-		status.err = ErrSTARSCommandFormat
-	}
-	return
+	return status, false
 }
 
 // Returns the cardinal-ordinal direction associated with the numbpad keys,
@@ -4768,6 +6103,47 @@ func (sp *STARSPane) DrawDCB(ctx *PaneContext, transforms ScopeTransformations,
 	return paneExtent
 }
 
+// datablockFont returns the font to use for datablocks; it's one size
+// larger than the one selected in streamer mode so that datablocks are
+// legible in the video stream.
+func (sp *STARSPane) datablockFont() *Font {
+	size := sp.CurrentPreferenceSet.CharSize.Datablocks
+	if streamerModeEnabled() {
+		size = min(size+1, len(sp.systemFont)-1)
+	}
+	return sp.systemFont[size]
+}
+
+// drawStreamerOverlay draws the streamer mode legend and controller
+// information, if enabled.
+func (sp *STARSPane) drawStreamerOverlay(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if !streamerModeEnabled() {
+		return
+	}
+
+	ps := sp.CurrentPreferenceSet
+	info := []string{"POSITION " + ctx.world.Callsign}
+	if ctrl := ctx.world.GetControllerByCallsign(ctx.world.Callsign); ctrl != nil {
+		info = append(info, "FREQ "+ctrl.Frequency.String())
+	}
+	if ps.CurrentATIS != "" {
+		info = append(info, "ATIS "+ps.CurrentATIS)
+	}
+
+	legend := []streamerLegendEntry{
+		{label: "TRACKED BY ME", color: STARSTrackedAircraftColor},
+		{label: "OTHER TRAFFIC", color: STARSUntrackedAircraftColor},
+		{label: "POINT OUT", color: STARSInboundPointOutColor},
+		{label: "SELECTED", color: STARSSelectedAircraftColor},
+		{label: "EMERGENCY", color: STARSEmergencyColor},
+		{label: "CONFLICT ALERT", color: STARSTextAlertColor},
+	}
+
+	transforms.LoadWindowViewingMatrices(cb)
+	globalConfig.StreamerMode.DrawOverlay(info, legend, sp.systemFont[ps.CharSize.Lists],
+		ps.Brightness.Lists.ScaleRGB(STARSListColor), ctx.paneExtent, cb)
+}
+
 func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, paneExtent Extent2D,
 	transforms ScopeTransformations, cb *CommandBuffer) {
 	ps := sp.CurrentPreferenceSet
@@ -4921,6 +6297,11 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 			newline()
 		}
 
+		if (filter.All || filter.Status) && sp.weatherRadar.Stale() {
+			pw = td.AddText("WX STALE", pw, alertStyle)
+			newline()
+		}
+
 		if filter.All || filter.Codes {
 			if len(ps.SelectedBeaconCodes) > 0 {
 				pw = td.AddText(strings.Join(ps.SelectedBeaconCodes, " "), pw, style)
@@ -5135,6 +6516,10 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 			lists = append(lists, "CA")
 			n += len(sp.CAAircraft)
 		}
+		if sp.ModeCIntruderAlerts {
+			lists = append(lists, "MCI")
+			n += len(sp.MCIAircraft)
+		}
 
 		if len(lists) > 0 {
 			text.WriteString(strings.Join(lists, "/") + "\n")
@@ -5167,10 +6552,40 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 				}
 			}
 
+			// MCI, along with the information for a traffic call
+			if sp.ModeCIntruderAlerts {
+				for _, mci := range sp.MCIAircraft {
+					if n == 0 {
+						break
+					}
+
+					text.WriteString(fmt.Sprintf("%-16s MCI\n", mci.Callsigns[0]+"*"+mci.Callsigns[1]))
+					text.WriteString("  " + mci.TrafficCall + "\n")
+					n--
+				}
+			}
+
 			drawList(text.String(), ps.AlertList.Position)
 		}
 	}
 
+	if ps.EmergencyList.Visible {
+		// Listed in the alert color until they are acknowledged.
+		text.Reset()
+		text.WriteString("EMERG\n")
+		n := len(sp.emergencies)
+		if n > ps.EmergencyList.Lines {
+			text.WriteString(fmt.Sprintf("MORE: %d/%d\n", ps.EmergencyList.Lines, n))
+			n = ps.EmergencyList.Lines
+		}
+		for _, callsign := range sp.emergencies[:n] {
+			if ac, ok := ctx.world.Aircraft[callsign]; ok {
+				text.WriteString(fmt.Sprintf("%-10s %s %s\n", callsign, ac.Squawk, sp.Aircraft[callsign].Emergency))
+			}
+		}
+		td.AddText(text.String(), normalizedToWindow(ps.EmergencyList.Position), alertStyle)
+	}
+
 	if ps.CoastList.Visible {
 		text := "COAST/SUSPEND"
 		// TODO
@@ -5331,6 +6746,12 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 		drawList(text.String(), ps.SignOnList.Position)
 	}
 
+	if sp.scheduledBanner != "" && time.Now().Before(sp.scheduledBannerEnd) {
+		bx, _ := font.BoundText(sp.scheduledBanner, style.LineSpacing)
+		pw := normalizedToWindow([2]float32{.5, .97})
+		td.AddText(sp.scheduledBanner, [2]float32{pw[0] - float32(bx)/2, pw[1]}, alertStyle)
+	}
+
 	td.GenerateCommands(cb)
 }
 
@@ -5377,20 +6798,80 @@ func (sp *STARSPane) drawSelectedRoute(ctx *PaneContext, transforms ScopeTransfo
 		return
 	}
 
-	ld := GetLinesDrawBuilder()
-	defer ReturnLinesDrawBuilder(ld)
+	ld := GetWideLinesDrawBuilder()
+	defer ReturnWideLinesDrawBuilder(ld)
 
-	prev := ac.Position()
+	route := [][2]float32{transforms.WindowFromLatLongP(ac.Position())}
 	for _, wp := range ac.Nav.Waypoints {
-		ld.AddLine(prev, wp.Location)
-		prev = wp.Location
+		route = append(route, transforms.WindowFromLatLongP(wp.Location))
+	}
+
+	ps := sp.CurrentPreferenceSet
+	ld.AddPolyline(ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor), route)
+	transforms.LoadWindowViewingMatrices(cb)
+	ld.GenerateCommands(cb, 3)
+}
+
+// drawArrivalRoutes draws the remaining routes of all of the aircraft
+// arriving at the airport given by the .ROUTE command with thin lines,
+// colored by STAR, so that merging flows can be seen. Each STAR is
+// labeled once, at the first waypoint of one of its aircraft's routes.
+func (sp *STARSPane) drawArrivalRoutes(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if sp.drawRouteAirport == "" {
+		return
 	}
 
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
 	ps := sp.CurrentPreferenceSet
-	cb.LineWidth(3)
-	cb.SetRGB(ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor))
+	font := sp.systemFont[ps.CharSize.Tools]
+	// Colors are assigned based on the STAR's name so that they don't
+	// change as aircraft come and go.
+	starColor := func(star string) RGB {
+		if star == "" {
+			return ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor)
+		}
+		var h uint32
+		for _, ch := range star {
+			h = 31*h + uint32(ch)
+		}
+		return ps.Brightness.Lines.ScaleRGB(STARSArrivalRouteColors[h%uint32(len(STARSArrivalRouteColors))])
+	}
+
+	labeled := make(map[string]interface{})
+	for _, callsign := range SortedMapKeys(ctx.world.Aircraft) {
+		ac := ctx.world.Aircraft[callsign]
+		if ac.FlightPlan == nil || ac.FlightPlan.ArrivalAirport != sp.drawRouteAirport ||
+			len(ac.Nav.Waypoints) == 0 {
+			continue
+		}
+		state, ok := sp.Aircraft[callsign]
+		if !ok {
+			continue
+		}
+
+		color := starColor(ac.STAR)
+		p := state.TrackPosition()
+		for _, wp := range ac.Nav.Waypoints {
+			ld.AddLine(p, wp.Location, color)
+			p = wp.Location
+		}
+
+		if _, ok := labeled[ac.STAR]; !ok && ac.STAR != "" {
+			labeled[ac.STAR] = nil
+			pw := add2f(transforms.WindowFromLatLongP(ac.Nav.Waypoints[0].Location), [2]float32{5, -5})
+			td.AddText(ac.STAR, pw, TextStyle{Font: font, Color: color})
+		}
+	}
+
 	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
 	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
 }
 
 func (sp *STARSPane) datablockType(ctx *PaneContext, ac *Aircraft) DatablockType {
@@ -5460,18 +6941,17 @@ func (sp *STARSPane) drawTracks(aircraft []*Aircraft, ctx *PaneContext, transfor
 	cb *CommandBuffer) {
 	td := GetTextDrawBuilder()
 	defer ReturnTextDrawBuilder(td)
-	trackBuilder := GetColoredTrianglesDrawBuilder()
-	defer ReturnColoredTrianglesDrawBuilder(trackBuilder)
+	trackBuilder := GetSpriteDrawBuilder()
+	defer ReturnSpriteDrawBuilder(trackBuilder)
 	ld := GetColoredLinesDrawBuilder()
 	defer ReturnColoredLinesDrawBuilder(ld)
 	trid := GetColoredTrianglesDrawBuilder()
 	defer ReturnColoredTrianglesDrawBuilder(trid)
 	// TODO: square icon if it's squawking a beacon code we're monitoring
 
-	// Update cached command buffers for tracks
-	sp.fusedTrackVertices = getTrackVertices(ctx, sp.getTrackSize(ctx, transforms))
+	sp.fusedTrackRadius, sp.fusedTrackSides = getTrackShape(ctx, sp.getTrackSize(ctx, transforms))
 
-	scale := Select(runtime.GOOS == "windows", ctx.platform.DPIScale(), float32(1))
+	scale := UIScale(ctx.platform)
 
 	now := ctx.world.CurrentTime()
 	for _, ac := range aircraft {
@@ -5505,7 +6985,7 @@ func (sp *STARSPane) drawTracks(aircraft []*Aircraft, ctx *PaneContext, transfor
 	}
 
 	transforms.LoadWindowViewingMatrices(cb)
-	trackBuilder.GenerateCommands(cb)
+	trackBuilder.GenerateCommands(cb, ctx.renderer)
 
 	transforms.LoadLatLongViewingMatrices(cb)
 	trid.GenerateCommands(cb)
@@ -5553,7 +7033,10 @@ func (sp *STARSPane) getGhostAircraft(aircraft []*Aircraft, ctx *PaneContext) []
 
 			trackId := Select(pairState.Mode == CRDAModeStagger, sp.ConvergingRunways[i].StaggerSymbol,
 				sp.ConvergingRunways[i].TieSymbol)
-			offset := Select(pairState.Mode == CRDAModeTie, sp.ConvergingRunways[i].TieOffset, float32(0))
+			offset := float32(0)
+			if pairState.Mode == CRDAModeTie {
+				offset = Select(pairState.OverrideTieOffset, pairState.TieOffset, sp.ConvergingRunways[i].TieOffset)
+			}
 
 			for _, ac := range aircraft {
 				state := sp.Aircraft[ac.Callsign]
@@ -5571,6 +7054,7 @@ func (sp *STARSPane) getGhostAircraft(aircraft []*Aircraft, ctx *PaneContext) []
 					otherRegion)
 				if ghost != nil {
 					ghost.TrackId = trackId
+					ghost.PairIndex = i
 					ghosts = append(ghosts, ghost)
 				}
 			}
@@ -5591,7 +7075,7 @@ func (sp *STARSPane) drawGhosts(ghosts []*GhostAircraft, ctx *PaneContext, trans
 	color := ps.Brightness.OtherTracks.ScaleRGB(STARSGhostColor)
 	trackFont := sp.systemFont[ps.CharSize.PositionSymbols]
 	trackStyle := TextStyle{Font: trackFont, Color: color, LineSpacing: 0}
-	datablockFont := sp.systemFont[ps.CharSize.Datablocks]
+	datablockFont := sp.datablockFont()
 	datablockStyle := TextStyle{Font: datablockFont, Color: color, LineSpacing: 0}
 
 	for _, ghost := range ghosts {
@@ -5605,14 +7089,20 @@ func (sp *STARSPane) drawGhosts(ghosts []*GhostAircraft, ctx *PaneContext, trans
 		pw := transforms.WindowFromLatLongP(ghost.Position)
 		td.AddTextCentered(ghost.TrackId, pw, trackStyle)
 
+		pairState := ps.CRDA.RunwayPairState[ghost.PairIndex]
 		var datablockText string
 		if state.Ghost.PartialDatablock {
 			// Partial datablock is just airspeed and then aircraft type if it's ~heavy.
 			datablockText = fmt.Sprintf("%02d", (ghost.Groundspeed+5)/10)
-			datablockText += state.CWTCategory
+			if !pairState.ReducedGhostDatablock {
+				datablockText += state.WakeCategory
+			}
 		} else {
 			// The full datablock ain't much more...
-			datablockText = ghost.Callsign + "\n" + fmt.Sprintf("%02d", (ghost.Groundspeed+5)/10)
+			datablockText = ghost.Callsign + pairState.GhostSuffix
+			if !pairState.ReducedGhostDatablock {
+				datablockText += "\n" + fmt.Sprintf("%02d", (ghost.Groundspeed+5)/10)
+			}
 		}
 		w, h := datablockFont.BoundText(datablockText, datablockStyle.LineSpacing)
 		datablockOffset := sp.getDatablockOffset([2]float32{float32(w), float32(h)},
@@ -5634,7 +7124,7 @@ func (sp *STARSPane) drawGhosts(ghosts []*GhostAircraft, ctx *PaneContext, trans
 }
 
 func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, heading float32, ctx *PaneContext,
-	transforms ScopeTransformations, trackId string, trackBuilder *ColoredTrianglesDrawBuilder,
+	transforms ScopeTransformations, trackId string, trackBuilder *SpriteDrawBuilder,
 	ld *ColoredLinesDrawBuilder, trid *ColoredTrianglesDrawBuilder, td *TextDrawBuilder, scale float32) {
 	ps := sp.CurrentPreferenceSet
 	// TODO: orient based on radar center if just one radar
@@ -5665,7 +7155,7 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 				box[i] = transforms.LatLongFromWindowP(box[i])
 			}
 
-			color := primaryTargetBrightness.ScaleRGB(STARSTrackBlockColor)
+			color := primaryTargetBrightness.ScaleRGB(sp.trackBlockColor(state))
 			if primary {
 				// Draw a filled box
 				trid.AddQuad(box[0], box[1], box[2], box[3], color)
@@ -5695,7 +7185,7 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 				box[i] = transforms.LatLongFromWindowP(box[i])
 			}
 
-			color := primaryTargetBrightness.ScaleRGB(STARSTrackBlockColor)
+			color := primaryTargetBrightness.ScaleRGB(sp.trackBlockColor(state))
 			if primary {
 				// Draw a filled box
 				trid.AddQuad(box[0], box[1], box[2], box[3], color)
@@ -5707,8 +7197,8 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 
 		case RadarModeFused:
 			if ps.Brightness.PrimarySymbols > 0 {
-				color := primaryTargetBrightness.ScaleRGB(STARSTrackBlockColor)
-				drawTrack(trackBuilder, pw, sp.fusedTrackVertices, color)
+				color := primaryTargetBrightness.ScaleRGB(sp.trackBlockColor(state))
+				trackBuilder.AddPolygon(pw, sp.fusedTrackRadius, sp.fusedTrackSides, color)
 			}
 		}
 	}
@@ -5721,6 +7211,14 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 		if dt == PartialDatablock || dt == LimitedDatablock {
 			trackIdBrightness = ps.Brightness.LimitedDatablocks
 		}
+		if sp.FrequencyFilter == FrequencyFilterDim && !onFrequency(ctx.world, ac) {
+			trackIdBrightness /= 2
+		}
+		if sp.TintOutsideAirspace {
+			if _, outside := sp.WarnOutsideAirspace(ctx, ac); outside {
+				color = STARSTextAlertColor
+			}
+		}
 		if trackId != "" {
 			font := sp.systemFont[ps.CharSize.PositionSymbols]
 			outlineFont := sp.systemOutlineFont[ps.CharSize.PositionSymbols]
@@ -5751,36 +7249,27 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 	}
 }
 
-func drawTrack(ctd *ColoredTrianglesDrawBuilder, p [2]float32, vertices [][2]float32, color RGB) {
-	for i := range vertices {
-		v0, v1 := vertices[i], vertices[(i+1)%len(vertices)]
-		ctd.AddTriangle(p, add2f(p, v0), add2f(p, v1), color)
-	}
-}
-
-func getTrackVertices(ctx *PaneContext, diameter float32) [][2]float32 {
-	// Figure out how many points to use to approximate the circle; use
+// getTrackShape returns the radius in pixels and the number of sides of
+// the polygon used to draw a track of the given diameter.
+func getTrackShape(ctx *PaneContext, diameter float32) (float32, int) {
+	// Figure out how many sides to use to approximate the circle; use
 	// more the bigger it is on the screen, but, sadly, not enough to get a
-	// nice clean circle (matching real-world..)
+	// nice clean circle (matching real-world..) The sprite atlas's
+	// polygons are rotated by 1/2 their angular spacing so that there
+	// are vertical and horizontal edges at the sides (e.g., a octagon
+	// like a stop-sign with 8 points, rather than having a vertex at the
+	// top of the circle.)
 	np := 8
 	if diameter > 20 {
 		np = Select(diameter <= 40, 16, 32)
 	}
 
-	// Prepare the points around the unit circle; rotate them by 1/2 their
-	// angular spacing so that we have vertical and horizontal edges at the
-	// sides (e.g., a octagon like a stop-sign with 8 points, rather than
-	// having a vertex at the top of the circle.)
-	rot := rotator2f(360 / (2 * float32(np)))
-	pts := MapSlice(GetCirclePoints(np), func(p [2]float32) [2]float32 { return rot(p) })
-
-	// Scale the points based on the circle radius (and deal with the usual
-	// Windows high-DPI borkage...)
-	scale := Select(runtime.GOOS == "windows", ctx.platform.DPIScale(), float32(1))
+	// Scale based on the circle radius (and deal with the usual Windows
+	// high-DPI borkage...)
+	scale := UIScale(ctx.platform)
 	radius := scale * float32(int(diameter/2+0.5)) // round to integer
-	pts = MapSlice(pts, func(p [2]float32) [2]float32 { return scale2f(p, radius) })
 
-	return pts
+	return radius, np
 }
 
 func (sp *STARSPane) drawHistoryTrails(aircraft []*Aircraft, ctx *PaneContext, transforms ScopeTransformations,
@@ -5791,11 +7280,13 @@ func (sp *STARSPane) drawHistoryTrails(aircraft []*Aircraft, ctx *PaneContext, t
 		return
 	}
 
-	historyBuilder := GetColoredTrianglesDrawBuilder()
-	defer ReturnColoredTrianglesDrawBuilder(historyBuilder)
+	historyBuilder := GetSpriteDrawBuilder()
+	defer ReturnSpriteDrawBuilder(historyBuilder)
 
 	const historyTrackDiameter = 8
-	historyTrackVertices := getTrackVertices(ctx, historyTrackDiameter)
+	historyTrackRadius, historyTrackSides := getTrackShape(ctx, historyTrackDiameter)
+
+	g := sp.altitudeGradient()
 
 	now := ctx.world.CurrentTime()
 	for _, ac := range aircraft {
@@ -5812,15 +7303,93 @@ func (sp *STARSPane) drawHistoryTrails(aircraft []*Aircraft, ctx *PaneContext, t
 
 			if idx := (state.historyTracksIndex - 1 - i) % len(state.historyTracks); idx >= 0 {
 				if p := state.historyTracks[idx].Position; !p.IsZero() {
-					drawTrack(historyBuilder, transforms.WindowFromLatLongP(p), historyTrackVertices,
-						trackColor)
+					if sp.AltitudeColoring {
+						// Fade older history tracks as with the regular colors.
+						c := altitudeColor(g, state.historyTracks[idx].Altitude)
+						fade := 1 - float32(trackColorNum)/float32(len(STARSTrackHistoryColors))
+						trackColor = ps.Brightness.History.ScaleRGB(c.Scale(fade))
+					}
+					historyBuilder.AddPolygon(transforms.WindowFromLatLongP(p), historyTrackRadius,
+						historyTrackSides, trackColor)
 				}
 			}
 		}
 	}
 
 	transforms.LoadWindowViewingMatrices(cb)
-	historyBuilder.GenerateCommands(cb)
+	historyBuilder.GenerateCommands(cb, ctx.renderer)
+}
+
+// drawFullTrails draws the complete paths of the aircraft for which the
+// full trail has been requested, colored according to altitude.
+func (sp *STARSPane) drawFullTrails(aircraft []*Aircraft, ctx *PaneContext, transforms ScopeTransformations,
+	cb *CommandBuffer) {
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	g := sp.altitudeGradient()
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		if !state.ShowFullTrail {
+			continue
+		}
+		for i := 1; i < len(state.fullTrail); i++ {
+			t0, t1 := state.fullTrail[i-1], state.fullTrail[i]
+			ld.AddLine(t0.Position, t1.Position, altitudeColor(g, (t0.Altitude+t1.Altitude)/2))
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(2)
+	ld.GenerateCommands(cb)
+}
+
+func (sp *STARSPane) altitudeGradient() []AltitudeColorStop {
+	if len(sp.AltitudeGradient) > 0 {
+		return sp.AltitudeGradient
+	}
+	return STARSAltitudeGradient
+}
+
+// trackBlockColor returns the color to use for the aircraft's primary
+// target symbol.
+func (sp *STARSPane) trackBlockColor(state *STARSAircraftState) RGB {
+	if sp.AltitudeColoring {
+		return altitudeColor(sp.altitudeGradient(), state.TrackAltitude())
+	}
+	return STARSTrackBlockColor
+}
+
+// drawAltitudeLegend draws the altitudes of the gradient's stops in their
+// colors in the lower right corner of the scope when altitude coloring is
+// enabled.
+func (sp *STARSPane) drawAltitudeLegend(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if !sp.AltitudeColoring {
+		return
+	}
+
+	ps := sp.CurrentPreferenceSet
+	font := sp.systemFont[ps.CharSize.Lists]
+	g := sp.altitudeGradient()
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	// Highest altitude at the top
+	const margin = 10
+	lineHeight := float32(font.size + 2)
+	w, _ := font.BoundText("000", 0)
+	pw := [2]float32{ctx.paneExtent.Width() - float32(w) - margin, margin + lineHeight*float32(len(g))}
+	for i := len(g) - 1; i >= 0; i-- {
+		td.AddText(fmt.Sprintf("%03d", g[i].Altitude/100), pw, TextStyle{
+			Font:  font,
+			Color: ps.Brightness.Lists.ScaleRGB(g[i].Color),
+		})
+		pw[1] -= lineHeight
+	}
+
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
 }
 
 func (sp *STARSPane) getDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDatablock {
@@ -5960,6 +7529,90 @@ func (sp *STARSPane) updateCAAircraft(ctx *PaneContext, aircraft []*Aircraft) {
 	}
 }
 
+// updateMCIAircraft checks for untracked aircraft squawking VFR with Mode C
+// that are close to aircraft we are tracking so that traffic can be
+// called.
+func (sp *STARSPane) updateMCIAircraft(ctx *PaneContext, aircraft []*Aircraft) {
+	if !sp.ModeCIntruderAlerts {
+		sp.MCIAircraft = nil
+		return
+	}
+
+	w := ctx.world
+	nmPerLongitude, magneticVariation := w.NmPerLongitude, w.MagneticVariation
+	tracked := FilterSlice(aircraft, func(ac *Aircraft) bool { return ac.TrackingController == w.Callsign })
+	intruders := FilterSlice(aircraft, func(ac *Aircraft) bool {
+		return ac.TrackingController == "" && ac.Squawk == Squawk(0o1200) && ac.Mode == Charlie
+	})
+
+	proximate := func(ac, intruder *Aircraft) bool {
+		sa, si := sp.Aircraft[ac.Callsign], sp.Aircraft[intruder.Callsign]
+		if abs(sa.TrackAltitude()-si.TrackAltitude()) > STARSMCIVertical {
+			return false
+		}
+		// Check the current positions and then every 15 seconds over the
+		// next minute.
+		pa, pi := ll2nm(sa.TrackPosition(), nmPerLongitude), ll2nm(si.TrackPosition(), nmPerLongitude)
+		va := ll2nm(sa.HeadingVector(nmPerLongitude, magneticVariation), nmPerLongitude)
+		vi := ll2nm(si.HeadingVector(nmPerLongitude, magneticVariation), nmPerLongitude)
+		for i := 0; i <= 4; i++ {
+			t := float32(i) / 4
+			if distance2f(add2f(pa, scale2f(va, t)), add2f(pi, scale2f(vi, t))) <= STARSMCILateral {
+				return true
+			}
+		}
+		return false
+	}
+
+	var mci []MCIAircraft
+	for _, ac := range tracked {
+		for _, intruder := range intruders {
+			if ac == intruder || !proximate(ac, intruder) {
+				continue
+			}
+
+			m := MCIAircraft{
+				Callsigns:   [2]string{ac.Callsign, intruder.Callsign},
+				TrafficCall: sp.trafficCall(w, ac, intruder),
+			}
+			if idx := slices.IndexFunc(sp.MCIAircraft, func(prev MCIAircraft) bool {
+				return prev.Callsigns == m.Callsigns
+			}); idx != -1 {
+				m.Acknowledged = sp.MCIAircraft[idx].Acknowledged
+			} else {
+				globalConfig.Audio.PlayOnce(AudioModeCIntruder)
+			}
+			mci = append(mci, m)
+		}
+	}
+	sp.MCIAircraft = mci
+}
+
+// trafficCall returns a description of the intruder's position relative
+// to the aircraft, e.g. "2 O'CLOCK 3NM SB 500 ABV".
+func (sp *STARSPane) trafficCall(w *World, ac, intruder *Aircraft) string {
+	sa, si := sp.Aircraft[ac.Callsign], sp.Aircraft[intruder.Callsign]
+	pa, pi := sa.TrackPosition(), si.TrackPosition()
+
+	bearing := headingp2ll(pa, pi, w.NmPerLongitude, 0)
+	clock := headingAsHour(bearing - sa.TrackHeading(w.NmPerLongitude))
+	call := fmt.Sprintf("%d O'CLOCK %dNM", clock, int(nmdistance2ll(pa, pi)+0.5))
+
+	if si.HaveHeading() {
+		call += " " + shortCompass(si.TrackHeading(w.NmPerLongitude)) + "B"
+	}
+
+	dalt := 100 * int(math.Round(float64(si.TrackAltitude()-sa.TrackAltitude())/100))
+	if dalt > 0 {
+		call += fmt.Sprintf(" %d ABV", dalt)
+	} else if dalt < 0 {
+		call += fmt.Sprintf(" %d BLW", -dalt)
+	} else {
+		call += " SAME ALT"
+	}
+	return call
+}
+
 func (sp *STARSPane) updateInTrailDistance(aircraft []*Aircraft, w *World) {
 	// Zero out the previous distance
 	for _, ac := range aircraft {
@@ -5985,7 +7638,7 @@ func (sp *STARSPane) updateInTrailDistance(aircraft []*Aircraft, w *World) {
 
 	for _, ac := range aircraft {
 		vol := ac.ATPAVolume()
-		if vol == nil {
+		if vol == nil || !w.RunwayConfiguration().ATPAVolumeActive(vol) {
 			continue
 		}
 		if _, ok := handledVolumes[vol.Id]; ok {
@@ -6034,6 +7687,27 @@ func (sp *STARSPane) updateInTrailDistance(aircraft []*Aircraft, w *World) {
 	}
 }
 
+// updateGlidepathDeviations finds the aircraft that are established on a
+// final for an active arrival runway, as determined by its ATPA volume,
+// and records how far each one is from the nominal glidepath.
+func (sp *STARSPane) updateGlidepathDeviations(aircraft []*Aircraft, w *World) {
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		state.OnFinal = false
+
+		vol := ac.ATPAVolume()
+		if vol == nil || !w.RunwayConfiguration().ATPAVolumeActive(vol) || !state.HaveHeading() {
+			continue
+		}
+		pos, alt := state.TrackPosition(), float32(state.TrackAltitude())
+		if vol.Inside(pos, alt, state.TrackHeading(ac.NmPerLongitude())+ac.MagneticVariation(),
+			ac.NmPerLongitude(), ac.MagneticVariation()) {
+			state.OnFinal = true
+			state.GlidepathDeviation = int(alt - vol.GlidepathAltitude(pos, ac.NmPerLongitude()))
+		}
+	}
+}
+
 type ModeledAircraft struct {
 	callsign     string
 	p            [2]float32 // nm coords
@@ -6087,99 +7761,24 @@ func (ma *ModeledAircraft) NextPosition(p [2]float32) [2]float32 {
 	return add2f(p, scale2f(ma.v, gs))
 }
 
-func getCwtCategory(ac *Aircraft) string {
+func (sp *STARSPane) getWakeCategory(ac *Aircraft) string {
 	perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]
 	if !ok {
 		lg.Errorf("%s: unable to get performance model for %s", ac.Callsign, ac.FlightPlan.BaseType())
 		return "NOWGT"
 	}
-	wc := perf.Category.CWT
-	if len(wc) == 0 {
-		lg.Errorf("%s: no CWT category found for %s", ac.Callsign, ac.FlightPlan.BaseType())
-		return "NOWGT"
-	}
-
-	switch wc {
-	case "NOWGT":
-		return "NOWGT"
-	case "I":
-		return "I"
-	case "H":
-		return "H"
-	case "G":
-		return "G"
-	case "F":
-		return "F"
-	case "E":
-		return "E"
-	case "D":
-		return "D"
-	case "C":
-		return "C"
-	case "B":
-		return "B"
-	case "A":
-		return "A"
-	default:
-		lg.Errorf("%s: unexpected weight class \"%c\"", ac.Callsign, wc[0])
+	wc := perf.WakeCategory(sp.WakeStandard)
+	if wc == "" {
+		lg.Errorf("%s: no wake category found for %s", ac.Callsign, ac.FlightPlan.BaseType())
 		return "NOWGT"
 	}
-
+	return wc
 }
 
 func (sp *STARSPane) checkInTrailCwtSeparation(back, front *Aircraft) {
-	cwtClass := func(ac *Aircraft) int {
-		perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]
-		if !ok {
-			lg.Errorf("%s: unable to get performance model for %s", ac.Callsign, ac.FlightPlan.BaseType())
-			return 9
-		}
-		wc := perf.Category.CWT
-		if len(wc) == 0 {
-			lg.Errorf("%s: no CWT category found for %s", ac.Callsign, ac.FlightPlan.BaseType())
-			return 9
-		}
-		switch wc[0] {
-		case 'I':
-			return 0
-		case 'H':
-			return 1
-		case 'G':
-			return 2
-		case 'F':
-			return 3
-		case 'E':
-			return 4
-		case 'D':
-			return 5
-		case 'C':
-			return 6
-		case 'B':
-			return 7
-		case 'A':
-			return 8
-		default:
-			lg.Errorf("%s: unexpected weight class \"%c\"", ac.Callsign, wc[0])
-			return 9
-		}
-	}
-	// 7110.126B TBL 5-5-2
-	// 0 value means minimum radar separation
-	cwtOnApproachLookUp := [10][10]float32{ // [front][back]
-		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind I
-		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind H
-		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind G
-		{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind F
-		{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind E
-		{6, 6, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind D
-		{6, 5, 3.5, 3.5, 3.5, 0, 0, 0, 0, 10},    // Behind C
-		{6, 5, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind B
-		{8, 8, 7, 7, 7, 6, 6, 5, 0, 10},          // Behind A
-		{10, 10, 10, 10, 10, 10, 10, 10, 10, 10}, // Behind NOWGT (No weight: 7110.762)
-	}
-	cwtSeparation := cwtOnApproachLookUp[cwtClass(front)][cwtClass(back)]
-
-	state := sp.Aircraft[back.Callsign]
+	state, frontState := sp.Aircraft[back.Callsign], sp.Aircraft[front.Callsign]
+	cwtSeparation := WakeSeparation(sp.WakeStandard, frontState.WakeCategory, state.WakeCategory)
+
 	vol := back.ATPAVolume()
 	if cwtSeparation == 0 {
 		cwtSeparation = float32(LateralMinimum)
@@ -6211,7 +7810,7 @@ func (sp *STARSPane) checkInTrailCwtSeparation(back, front *Aircraft) {
 	}
 
 	// front, back aircraft
-	frontModel := MakeModeledAircraft(front, sp.Aircraft[front.Callsign], vol.Threshold)
+	frontModel := MakeModeledAircraft(front, frontState, vol.Threshold)
 	backModel := MakeModeledAircraft(back, state, vol.Threshold)
 
 	// Will there be a MIT violation s seconds in the future?  (Note that
@@ -6270,6 +7869,18 @@ func (sp *STARSPane) haveActiveWarnings(ctx *PaneContext, ac *Aircraft) bool {
 	if state.MSAW && !state.InhibitMSAW && !state.DisableMSAW && !ps.DisableMSAW {
 		return true
 	}
+	if state.SUAAlert != "" || state.GeofenceAlert || state.TriggerAlert != "" || state.Emergency == "MED" {
+		return true
+	}
+	if state.ProtectedAreaAlert != "" || state.NTZStatus == NTZStatusAlert {
+		return true
+	}
+	if slices.Contains(sp.duplicateBeacons[ac.Squawk], ac.Callsign) {
+		return true
+	}
+	if slices.ContainsFunc(sp.MCIAircraft, func(mci MCIAircraft) bool { return mci.Callsigns[0] == ac.Callsign }) {
+		return true
+	}
 	if ok, _ := SquawkIsSPC(ac.Squawk); ok {
 		return true
 	}
@@ -6304,7 +7915,31 @@ func (sp *STARSPane) getWarnings(ctx *PaneContext, ac *Aircraft) []string {
 	if state.MSAW && !state.InhibitMSAW && !state.DisableMSAW && !ps.DisableMSAW {
 		addWarning("LA")
 	}
-	if ok, code := SquawkIsSPC(ac.Squawk); ok {
+	if state.SUAAlert != "" {
+		addWarning("SUA")
+	}
+	if state.ProtectedAreaAlert != "" {
+		addWarning("ILS")
+	}
+	if state.NTZStatus == NTZStatusAlert {
+		addWarning("NTZ")
+	}
+	if state.GeofenceAlert {
+		addWarning("GF")
+	}
+	if state.TriggerAlert != "" {
+		addWarning(state.TriggerAlert)
+	}
+	if state.Emergency == "MED" {
+		addWarning("MED")
+	}
+	if slices.Contains(sp.duplicateBeacons[ac.Squawk], ac.Callsign) {
+		addWarning("DB")
+	}
+	if slices.ContainsFunc(sp.MCIAircraft, func(mci MCIAircraft) bool { return mci.Callsigns[0] == ac.Callsign }) {
+		addWarning("MCI")
+	}
+	if ok, code := SquawkIsSPC(ac.Squawk); ok {
 		addWarning(code)
 	}
 	for code := range ac.SPCOverrides {
@@ -6352,6 +7987,12 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 				Color: STARSTextAlertColor,
 			})
 	}
+	if state.Holding {
+		if baseDB.Lines[0].Text != "" {
+			baseDB.Lines[0].Text += " "
+		}
+		baseDB.Lines[0].Text += "HLD"
+	}
 
 	ty := sp.datablockType(ctx, ac)
 
@@ -6359,7 +8000,7 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 	case LimitedDatablock:
 		db := baseDB.Duplicate()
 		db.Lines[1].Text = fmt.Sprintf("%v", ac.Squawk)
-		db.Lines[2].Text = fmt.Sprintf("%03d", (state.TrackAltitude()+50)/100)
+		db.Lines[2].Text = state.AltitudeText()
 		if state.FullLDBEndTime.After(ctx.now) {
 			db.Lines[2].Text += fmt.Sprintf(" %02d", (state.TrackGroundspeed()+5)/10)
 		}
@@ -6393,7 +8034,7 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 		}
 
 		if state.Ident(ctx.now) {
-			alt := fmt.Sprintf("%03d", (state.TrackAltitude()+50)/100)
+			alt := state.AltitudeText()
 			dbs[0].Lines[1].Text = alt + " ID"
 			dbs[1].Lines[1].Text = alt + " ID"
 
@@ -6405,7 +8046,7 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 		}
 
 		if fp := ac.FlightPlan; fp != nil && fp.Rules == VFR {
-			as := fmt.Sprintf("%03d  %02d", (state.TrackAltitude()+50)/100, (state.TrackGroundspeed()+5)/10)
+			as := fmt.Sprintf("%s  %02d", state.AltitudeText(), (state.TrackGroundspeed()+5)/10)
 			dbs[0].Lines[1].Text = as
 			dbs[1].Lines[1].Text = as
 			return dbs
@@ -6435,21 +8076,25 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 		} else if sp.isOverflight(ctx, ac) {
 			field3 += "E"
 		}
-		field3 += state.CWTCategory
+		field3 += state.WakeCategory
 
 		// Field 1: alternate between altitude and either primary
-		// scratchpad or destination airport.
+		// scratchpad, secondary scratchpad, or destination airport.
 		ap := ac.FlightPlan.ArrivalAirport
 		if len(ap) == 4 {
 			ap = ap[1:] // drop the leading K
 		}
-		alt := fmt.Sprintf("%03d", (state.TrackAltitude()+50)/100)
+		alt := state.AltitudeText()
 		sp := fmt.Sprintf("%3s", ac.Scratchpad)
 
 		field1 := [2]string{}
 		field1[0] = alt
 		if ac.Scratchpad != "" {
 			field1[1] = sp
+		} else if ac.SecondaryScratchpad != "" {
+			// Secondary scratchpad is shown if there's no primary; it's
+			// followed by a "+" as in field 4 of the full datablock.
+			field1[1] = fmt.Sprintf("%3s", ac.SecondaryScratchpad) + "+"
 		} else if airport := ctx.world.GetAirport(ac.FlightPlan.ArrivalAirport); airport != nil && !airport.OmitArrivalScratchpad {
 			field1[1] = ap
 		} else {
@@ -6463,7 +8108,7 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 
 	case FullDatablock:
 		// Line 1: fields 1, 2, and 8 (surprisingly). Field 8 may be multiplexed.
-		field1 := ac.Callsign
+		field1 := screenshotCallsign(ac.Callsign)
 
 		field2 := ""
 		if state.InhibitMSAW || state.DisableMSAW {
@@ -6487,10 +8132,13 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			field8 = []string{"", " PO"}
 		} else if ac.RedirectedHandoff.ShowRDIndicator(ctx.world.Callsign, state.RDIndicatorEnd) {
 			field8 = []string{" RD"}
+		} else if coordinationPending(ac, ctx.world.Callsign) {
+			// Outstanding APREQ
+			field8 = []string{" AQ"}
 		}
 
 		// Line 2: fields 3, 4, 5
-		alt := fmt.Sprintf("%03d", (state.TrackAltitude()+50)/100)
+		alt := state.AltitudeText()
 		if state.LostTrack(ctx.world.CurrentTime()) {
 			alt = "CST"
 		}
@@ -6519,6 +8167,11 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 					field4 = append(field4, "")
 				}
 			}
+			if seq, ok := sp.arrivalSequence[ac.Callsign]; ok && sp.ShowSequenceNumbers {
+				// Time-shared with the altitude, scratchpads, and destination.
+				field3 = append(field3, fmt.Sprintf("#%-2d", seq.Number))
+				field4 = append(field4, "")
+			}
 		}
 
 		// Fill in empty field4 entries.
@@ -6572,14 +8225,33 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			} else {
 				modifier = " "
 			}
-			acCategory = modifier + state.CWTCategory
-
-			field5 = append(field5, speed+acCategory)
+			acCategory = modifier + state.WakeCategory
 
-			field5 = append(field5, actype)
-			if (state.DisplayRequestedAltitude != nil && *state.DisplayRequestedAltitude) ||
-				(state.DisplayRequestedAltitude == nil && sp.CurrentPreferenceSet.DisplayRequestedAltitude) {
-				field5 = append(field5, fmt.Sprintf("R%03d", ac.FlightPlan.Altitude/100))
+			for _, ts := range sp.Field5TimeSharing {
+				if !ts.Enabled {
+					continue
+				}
+				switch ts.Field {
+				case STARSTimeShareSpeed:
+					field5 = append(field5, speed+acCategory)
+				case STARSTimeShareType:
+					field5 = append(field5, actype)
+				case STARSTimeShareRequestedAltitude:
+					if (state.DisplayRequestedAltitude != nil && *state.DisplayRequestedAltitude) ||
+						(state.DisplayRequestedAltitude == nil && sp.CurrentPreferenceSet.DisplayRequestedAltitude) {
+						field5 = append(field5, fmt.Sprintf("R%03d", ac.FlightPlan.Altitude/100))
+					}
+				case STARSTimeShareDestination:
+					if ap := ac.FlightPlan.ArrivalAirport; len(ap) == 4 {
+						field5 = append(field5, ap[1:]) // drop the leading K
+					} else {
+						field5 = append(field5, ap)
+					}
+				}
+			}
+			if len(field5) == 0 {
+				// Always show something.
+				field5 = append(field5, speed+acCategory)
 			}
 		}
 		for i := range field5 {
@@ -6589,24 +8261,24 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 		}
 
 		field6 := ""
-		var line3FieldColors *STARSDatablockFieldColors
+		var line3FieldColors []STARSDatablockFieldColors
 		if state.DisplayATPAWarnAlert != nil && !*state.DisplayATPAWarnAlert {
 			field6 = "*TPA"
 		} else if state.IntrailDistance != 0 && sp.CurrentPreferenceSet.DisplayATPAInTrailDist {
 			field6 = fmt.Sprintf("%.2f", state.IntrailDistance)
 
 			if state.ATPAStatus == ATPAStatusWarning {
-				line3FieldColors = &STARSDatablockFieldColors{
+				line3FieldColors = append(line3FieldColors, STARSDatablockFieldColors{
 					Start: 0,
 					End:   len(field6),
 					Color: STARSATPAWarningColor,
-				}
+				})
 			} else if state.ATPAStatus == ATPAStatusAlert {
-				line3FieldColors = &STARSDatablockFieldColors{
+				line3FieldColors = append(line3FieldColors, STARSDatablockFieldColors{
 					Start: 0,
 					End:   len(field6),
 					Color: STARSATPAAlertColor,
-				}
+				})
 			}
 		}
 		for len(field6) < 5 {
@@ -6619,6 +8291,29 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			field7 = fmt.Sprintf("A%03d", ta)
 		}
 		line3 := field6 + "  " + field7
+		if sp.ShowAltitudeTrend {
+			line3 += sp.altitudeTrend(state)
+		}
+		if gp := sp.glidepathDeviation(state); gp != "" {
+			if state.GlidepathDeviation > STARSGlidepathHighDeviation {
+				line3FieldColors = append(line3FieldColors, STARSDatablockFieldColors{
+					Start: len(line3),
+					End:   len(line3) + len(gp),
+					Color: STARSATPAWarningColor,
+				})
+			}
+			line3 += gp
+		}
+		if c, color, highlight := sp.ntzClosure(state); c != "" {
+			if highlight {
+				line3FieldColors = append(line3FieldColors, STARSDatablockFieldColors{
+					Start: len(line3),
+					End:   len(line3) + len(c),
+					Color: color,
+				})
+			}
+			line3 += c
+		}
 
 		// Now make some datablocks. Note that line 1 has already been set
 		// in baseDB above.
@@ -6634,9 +8329,7 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			db.Lines[1].Text = field1 + field2 + field8[i%len(field8)]
 			db.Lines[2].Text = field3[i%len(field3)] + field4[i%len(field4)] + field5[i%len(field5)]
 			db.Lines[3].Text = line3
-			if line3FieldColors != nil {
-				db.Lines[3].Colors = append(db.Lines[3].Colors, *line3FieldColors)
-			}
+			db.Lines[3].Colors = append(db.Lines[3].Colors, line3FieldColors...)
 			if line5FieldColors != nil && i&1 == 1 {
 				// Flash "ID" for identing
 				fc := *line5FieldColors
@@ -6652,6 +8345,40 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 	return nil
 }
 
+// Aircraft with vertical rates less than this (in feet per minute) are
+// considered to be level for the altitude trend.
+const STARSLevelVerticalRate = 300
+
+// altitudeTrend returns the text for the altitude trend datablock field:
+// an up or down arrow for climbing or descending aircraft, optionally
+// followed by the vertical rate in hundreds of feet per minute.
+func (sp *STARSPane) altitudeTrend(state *STARSAircraftState) string {
+	fpm, ok := state.TrackVerticalRate()
+	if !ok || abs(fpm) < STARSLevelVerticalRate {
+		return ""
+	}
+
+	trend := " " + Select(fpm > 0, STARSUpArrow, STARSDownArrow)
+	if sp.ShowVerticalRate {
+		trend += fmt.Sprintf("%02d", min((abs(fpm)+50)/100, 99))
+	}
+	return trend
+}
+
+// Aircraft that are more than this many feet above the glidepath have the
+// deviation highlighted.
+const STARSGlidepathHighDeviation = 300
+
+// glidepathDeviation returns the text for the glidepath deviation
+// datablock field: "G" followed by the deviation in hundreds of feet.
+func (sp *STARSPane) glidepathDeviation(state *STARSAircraftState) string {
+	if !sp.ShowGlidepathDeviation || !state.OnFinal {
+		return ""
+	}
+	dev := (abs(state.GlidepathDeviation) + 50) / 100
+	return fmt.Sprintf(" G%s%02d", Select(state.GlidepathDeviation < 0, "-", "+"), min(dev, 99))
+}
+
 func sameFacility(ctx *PaneContext, receiving string) bool {
 	return ctx.world.GetControllerByCallsign(ctx.world.Callsign).FacilityIdentifier ==
 		ctx.world.GetControllerByCallsign(receiving).FacilityIdentifier
@@ -6664,11 +8391,15 @@ func (sp *STARSPane) datablockColor(ctx *PaneContext, ac *Aircraft) (color RGB,
 	brightness = Select(dt == PartialDatablock || dt == LimitedDatablock,
 		ps.Brightness.LimitedDatablocks, ps.Brightness.FullDatablocks)
 
+	w := ctx.world
+	if sp.FrequencyFilter == FrequencyFilterDim && !onFrequency(w, ac) {
+		brightness /= 2
+	}
+
 	if ac.Callsign == sp.dwellAircraft {
 		brightness = STARSBrightness(100)
 	}
 
-	w := ctx.world
 	for _, controller := range ac.RedirectedHandoff.Redirector {
 		if controller == w.Callsign && ac.RedirectedHandoff.RedirectedTo != w.Callsign {
 			color = STARSUntrackedAircraftColor
@@ -6680,12 +8411,18 @@ func (sp *STARSPane) datablockColor(ctx *PaneContext, ac *Aircraft) (color RGB,
 		if _, pointOut := sp.InboundPointOuts[ac.Callsign]; pointOut {
 			// point out
 			brightness /= 3
+		} else if state.Emergency != "" && !state.EmergencyAcknowledged {
+			// emergency that hasn't been acknowledged
+			brightness /= 3
 		} else if state.OutboundHandoffAccepted && ctx.now.Before(state.OutboundHandoffFlashEnd) {
 			// we handed it off, it was accepted, but we haven't yet acknowledged
 			brightness /= 3
 		} else if (ac.HandoffTrackController == w.Callsign && !slices.Contains(ac.RedirectedHandoff.Redirector, w.Callsign)) || // handing off to us
 			ac.RedirectedHandoff.RedirectedTo == w.Callsign {
 			brightness /= 3
+		} else if w.ReminderExpired(ac.Callsign) {
+			// user-set reminder timer expired
+			brightness /= 3
 		}
 	}
 
@@ -6697,7 +8434,9 @@ func (sp *STARSPane) datablockColor(ctx *PaneContext, ac *Aircraft) (color RGB,
 		}
 	}
 
-	if _, ok := sp.InboundPointOuts[ac.Callsign]; ok || state.PointedOut || state.ForceQL {
+	if state.Emergency != "" {
+		color = STARSEmergencyColor
+	} else if _, ok := sp.InboundPointOuts[ac.Callsign]; ok || state.PointedOut || state.ForceQL {
 		// yellow for pointed out by someone else or uncleared after acknowledged.
 		color = STARSInboundPointOutColor
 	} else if state.IsSelected {
@@ -6766,7 +8505,11 @@ func (sp *STARSPane) drawDatablocks(aircraft []*Aircraft, ctx *PaneContext,
 	now := ctx.world.CurrentTime()
 	realNow := ctx.now // for flashing rate...
 	ps := sp.CurrentPreferenceSet
-	font := sp.systemFont[ps.CharSize.Datablocks]
+	font := sp.datablockFont()
+	var scale float32
+	if ps.ScalableDatablocks.Enabled {
+		scale = Select(ps.ScalableDatablocks.Scale != 0, ps.ScalableDatablocks.Scale, 1)
+	}
 
 	for _, ac := range aircraft {
 		state := sp.Aircraft[ac.Callsign]
@@ -6787,15 +8530,15 @@ func (sp *STARSPane) drawDatablocks(aircraft []*Aircraft, ctx *PaneContext,
 		// Compute the bounds of the datablock; always use the first one so
 		// things don't jump around when it switches between multiple of
 		// them.
-		w, h := dbs[0].BoundText(font)
+		w, h := dbs[0].BoundText(font, scale)
 		datablockOffset := sp.getDatablockOffset([2]float32{float32(w), float32(h)},
 			sp.getLeaderLineDirection(ac, ctx.world))
 
 		// Draw characters starting at the upper left.
 		pac := transforms.WindowFromLatLongP(state.TrackPosition())
 		pt := add2f(datablockOffset, pac)
-		idx := (realNow.Second() / 2) % len(dbs) // 2 second cycle
-		dbs[idx].DrawText(td, pt, font, color, brightness)
+		idx := int(realNow.Unix()/int64(sp.DatablockCycleSeconds)) % len(dbs)
+		dbs[idx].DrawText(td, pt, font, color, brightness, scale)
 	}
 
 	transforms.LoadWindowViewingMatrices(cb)
@@ -6823,13 +8566,14 @@ func (sp *STARSPane) drawPTLs(aircraft []*Aircraft, ctx *PaneContext, transforms
 			continue
 		}
 
-		// convert PTL length (minutes) to estimated distance a/c will travel
-		dist := float32(state.TrackGroundspeed()) / 60 * ps.PTLLength
+		v, ok := sp.vectorVelocity(ctx.world, ac, state)
+		if !ok {
+			continue
+		}
 
-		// h is a vector in nm coordinates with length l=dist
-		hdg := state.TrackHeading(ac.NmPerLongitude())
-		h := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
-		h = scale2f(h, dist)
+		// Scale the velocity (in knots) by the PTL length (in minutes) to
+		// get the estimated distance the aircraft will travel.
+		h := scale2f(v, ps.PTLLength/60)
 		end := add2f(ll2nm(state.TrackPosition(), ac.NmPerLongitude()), h)
 
 		ld.AddLine(state.TrackPosition(), nm2ll(end, ac.NmPerLongitude()), color)
@@ -6839,6 +8583,26 @@ func (sp *STARSPane) drawPTLs(aircraft []*Aircraft, ctx *PaneContext, transforms
 	ld.GenerateCommands(cb)
 }
 
+const (
+	STARSVectorTrack   = iota // ground track
+	STARSVectorHeading        // heading; requires the wind to be known
+)
+
+// vectorVelocity returns the velocity in knots, in nm coordinates, that
+// the aircraft's vector line should follow. Heading vectors remove the
+// wind from the ground velocity; if the wind isn't known, the ground
+// track is used.
+func (sp *STARSPane) vectorVelocity(w *World, ac *Aircraft, state *STARSAircraftState) ([2]float32, bool) {
+	v, ok := state.GroundVelocity(ac.NmPerLongitude())
+	if !ok {
+		return v, false
+	}
+	if sp.VectorMode == STARSVectorHeading && w.Wind.Speed > 0 {
+		v = sub2f(v, w.AverageWindVector())
+	}
+	return v, true
+}
+
 func (sp *STARSPane) drawRingsAndCones(aircraft []*Aircraft, ctx *PaneContext, transforms ScopeTransformations,
 	cb *CommandBuffer) {
 	now := ctx.world.CurrentTime()
@@ -7142,10 +8906,24 @@ func (sp *STARSPane) drawMinSep(ctx *PaneContext, transforms ScopeTransformation
 		DrawBackground:  true,
 		BackgroundColor: RGB{},
 	}
-	text := fmt.Sprintf("%.2fNM", nmdistance2ll(p0tmin, p1tmin))
+	minDist := nmdistance2ll(p0tmin, p1tmin)
+	text := fmt.Sprintf("%.2fNM", minDist)
 	if tmin < 0 {
 		text = "NO XING\n" + text
 	}
+	// If they're headed the same way, one is following the other, so also
+	// show the required wake separation for the pair.
+	if dot(d0, d1) > 0 {
+		front, back := s0, s1
+		if dot(d0, sub2f(p1, p0)) > 0 {
+			front, back = s1, s0
+		}
+		required := max(WakeSeparation(sp.WakeStandard, front.WakeCategory, back.WakeCategory), LateralMinimum)
+		text += fmt.Sprintf("\nREQ %.1fNM", required)
+		if minDist < required {
+			style.Color = ps.Brightness.Lines.ScaleRGB(STARSATPAAlertColor)
+		}
+	}
 	td.AddTextCentered(text, pText, style)
 
 	// Add the corresponding drawing commands to the CommandBuffer.
@@ -7169,6 +8947,13 @@ func (sp *STARSPane) drawAirspace(ctx *PaneContext, transforms ScopeTransformati
 
 	drawSectors := func(volumes []ControllerAirspaceVolume) {
 		for _, v := range volumes {
+			if sp.FilterAirspaceAltitudes {
+				af := ps.AltitudeFilters.Associated
+				if v.UpperLimit < af[0] || v.LowerLimit > af[1] {
+					continue
+				}
+			}
+
 			e := EmptyExtent2D()
 
 			for _, pts := range v.Boundaries {
@@ -7200,6 +8985,20 @@ func (sp *STARSPane) drawAirspace(ctx *PaneContext, transforms ScopeTransformati
 		drawSectors(ctx.world.DepartureAirspace)
 	}
 
+	// We don't have actual per-controller airspace, so approximate it with
+	// the controller's scope range around its center.
+	if ctrl := ctx.world.GetControllerByCallsign(sp.highlightedController); ctrl != nil {
+		w := ctx.world
+		center, radius := w.ControllerCenter(ctrl.Callsign), w.ControllerRange(ctrl.Callsign)
+		ld.AddLatLongCircle(center, w.NmPerLongitude, radius, 360, rgb)
+		style := TextStyle{
+			Font:           sp.systemFont[ps.CharSize.Tools],
+			Color:          rgb,
+			DrawBackground: true,
+		}
+		td.AddTextCentered(ctrl.Callsign, transforms.WindowFromLatLongP(center), style)
+	}
+
 	transforms.LoadLatLongViewingMatrices(cb)
 	ld.GenerateCommands(cb)
 	transforms.LoadWindowViewingMatrices(cb)
@@ -7226,23 +9025,63 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 		wmTakeKeyboardFocus(sp, false)
 	}
 
+	var touch TouchGesture
+	if globalConfig.TouchMode {
+		touch = sp.touchGestures.Update(mouse, ctx.keyboard)
+
+		// Long-press toggles selection, like control-click.
+		if touch.LongPress {
+			if ac, _ := sp.tryGetClosestAircraft(ctx.world, touch.LongPressPos, transforms); ac != nil {
+				if state := sp.Aircraft[ac.Callsign]; state != nil {
+					state.IsSelected = !state.IsSelected
+				}
+			}
+		}
+		// One-finger drags would otherwise draw freehand annotations.
+		if sp.wipAnnotation != nil {
+			touch.Pan = [2]float32{}
+		}
+	}
+
+	// Alt-clicking an aircraft and dragging previews a vector with the
+	// vectoring aid until the button is released.
+	if va := sp.vectoringAid; va != nil {
+		if mouse.Down[MouseButtonPrimary] {
+			va.End = transforms.LatLongFromWindowP(mouse.Pos)
+		} else {
+			sp.vectoringAid = nil
+		}
+		return
+	}
+	if mouse.Clicked[MouseButtonPrimary] && ctx.keyboard != nil && ctx.keyboard.IsPressed(KeyAlt) {
+		if ac, _ := sp.tryGetClosestAircraft(ctx.world, mouse.Pos, transforms); ac != nil {
+			sp.vectoringAid = &STARSVectoringAid{
+				Callsign: ac.Callsign,
+				End:      transforms.LatLongFromWindowP(mouse.Pos),
+			}
+			return
+		}
+	}
+
 	if activeSpinner == nil && !sp.LockDisplay {
 		// Handle dragging the scope center
+		delta := touch.Pan
 		if mouse.Dragging[MouseButtonSecondary] {
-			delta := mouse.DragDelta
-			if delta[0] != 0 || delta[1] != 0 {
-				deltaLL := transforms.LatLongFromWindowV(delta)
-				ps.CurrentCenter = sub2f(ps.CurrentCenter, deltaLL)
-			}
+			delta = add2f(delta, mouse.DragDelta)
+		}
+		if delta[0] != 0 || delta[1] != 0 {
+			deltaLL := transforms.LatLongFromWindowV(delta)
+			ps.CurrentCenter = sub2f(ps.CurrentCenter, deltaLL)
 		}
 
-		// Consume mouse wheel
-		if mouse.Wheel[1] != 0 {
+		// Consume mouse wheel; in touch mode, only pinches zoom.
+		wheel := Select(globalConfig.TouchMode, touch.Zoom, mouse.Wheel[1])
+		if wheel != 0 {
 			r := ps.Range
-			if _, ok := ctx.keyboard.Pressed[KeyControl]; ok {
-				ps.Range += 3 * mouse.Wheel[1]
+			if ctx.keyboard != nil && ctx.keyboard.IsPressed(KeyControl) && !globalConfig.TouchMode {
+				ps.Range += 3 * wheel
 			} else {
-				ps.Range += mouse.Wheel[1]
+				ps.Range += wheel
 			}
 			ps.Range = clamp(ps.Range, 6, 256) // 4-33
 
@@ -7260,6 +9099,21 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 		}
 	}
 
+	// Freehand annotations follow the mouse while the button is down.
+	if an := sp.wipAnnotation; an != nil && an.Type == STARSAnnotationFreehand {
+		if mouse.Released[MouseButtonPrimary] {
+			if len(an.Points) > 1 {
+				sp.Annotations = append(sp.Annotations, *an)
+			}
+			sp.wipAnnotation = nil
+		} else if mouse.Down[MouseButtonPrimary] {
+			last := transforms.WindowFromLatLongP(an.Points[len(an.Points)-1])
+			if distance2f(last, mouse.Pos) > 3 {
+				an.Points = append(an.Points, transforms.LatLongFromWindowP(mouse.Pos))
+			}
+		}
+	}
+
 	if ctx.mouse.Clicked[MouseButtonPrimary] {
 		if ctx.keyboard != nil && ctx.keyboard.IsPressed(KeyShift) && ctx.keyboard.IsPressed(KeyControl) {
 			// Shift-Control-click anywhere -> copy current mouse lat-long to the clipboard.
@@ -7320,44 +9174,82 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 		}
 	} else {
 		if ac, _ := sp.tryGetClosestAircraft(ctx.world, ctx.mouse.Pos, transforms); ac != nil {
-			td := GetTextDrawBuilder()
-			defer ReturnTextDrawBuilder(td)
+			info := ac.NavSummary()
+			if sp.FlightPlanTooltip {
+				info += "\n\n" + flightPlanTooltip(ac)
+			}
+			sp.drawTooltip(ac, info, transforms, cb)
+		}
+		return
+	}
 
-			ps := sp.CurrentPreferenceSet
-			font := sp.systemFont[ps.CharSize.Datablocks]
-			style := TextStyle{
-				Font:        font,
-				Color:       ps.Brightness.FullDatablocks.ScaleRGB(STARSListColor),
-				LineSpacing: 0}
+	// When the sim is running, the flight plan of the aircraft under the
+	// mouse is shown if the user has asked for it.
+	if sp.FlightPlanTooltip {
+		if ac, _ := sp.tryGetClosestAircraft(ctx.world, ctx.mouse.Pos, transforms); ac != nil && ac.FlightPlan != nil {
+			sp.drawTooltip(ac, flightPlanTooltip(ac), transforms, cb)
+		}
+	}
+}
 
-			// Aircraft track position in window coordinates
-			state := sp.Aircraft[ac.Callsign]
-			pac := transforms.WindowFromLatLongP(state.TrackPosition())
+// flightPlanTooltip returns a multi-line summary of the aircraft's flight
+// plan.
+func flightPlanTooltip(ac *Aircraft) string {
+	fp := ac.FlightPlan
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\n", ac.Callsign, fp.Rules, fp.TypeWithoutSuffix())
+	if suffix := fp.EquipmentSuffix(); suffix != "" {
+		fmt.Fprintf(&b, "EQUIP /%s\n", suffix)
+	}
+	fmt.Fprintf(&b, "TAS %d ALT %d SQ %s\n", fp.CruiseSpeed, fp.Altitude, ac.AssignedSquawk)
+	fmt.Fprintf(&b, "%s-%s\n", fp.DepartureAirport, fp.ArrivalAirport)
+	route, _ := wrapText(fp.Route, 40, 2, false)
+	b.WriteString(route)
+	if fp.Remarks != "" {
+		rmks, _ := wrapText("RMK "+fp.Remarks, 40, 2, false)
+		b.WriteString("\n" + rmks)
+	}
+	return b.String()
+}
 
-			// Upper-left corner of where we start drawing the text
-			pad := float32(5)
-			ptext := add2f([2]float32{2 * pad, 0}, pac)
-			info := ac.NavSummary()
-			td.AddText(info, ptext, style)
+// drawTooltip draws the given text next to the aircraft's track with a
+// translucent background behind it.
+func (sp *STARSPane) drawTooltip(ac *Aircraft, info string, transforms ScopeTransformations, cb *CommandBuffer) {
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
 
-			// Draw an alpha-blended quad behind the text to make it more legible.
-			trid := GetTrianglesDrawBuilder()
-			defer ReturnTrianglesDrawBuilder(trid)
-			bx, by := font.BoundText(info, style.LineSpacing)
-			trid.AddQuad(add2f(ptext, [2]float32{-pad, 0}),
-				add2f(ptext, [2]float32{float32(bx) + pad, 0}),
-				add2f(ptext, [2]float32{float32(bx) + pad, -float32(by) - pad}),
-				add2f(ptext, [2]float32{-pad, -float32(by) - pad}))
+	ps := sp.CurrentPreferenceSet
+	font := sp.systemFont[ps.CharSize.Datablocks]
+	style := TextStyle{
+		Font:        font,
+		Color:       ps.Brightness.FullDatablocks.ScaleRGB(STARSListColor),
+		LineSpacing: 0}
 
-			// Get it all into the command buffer
-			transforms.LoadWindowViewingMatrices(cb)
-			cb.SetRGBA(RGBA{R: 0.25, G: 0.25, B: 0.25, A: 0.75})
-			cb.Blend()
-			trid.GenerateCommands(cb)
-			cb.DisableBlend()
-			td.GenerateCommands(cb)
-		}
-	}
+	// Aircraft track position in window coordinates
+	state := sp.Aircraft[ac.Callsign]
+	pac := transforms.WindowFromLatLongP(state.TrackPosition())
+
+	// Upper-left corner of where we start drawing the text
+	pad := float32(5)
+	ptext := add2f([2]float32{2 * pad, 0}, pac)
+	td.AddText(info, ptext, style)
+
+	// Draw an alpha-blended quad behind the text to make it more legible.
+	trid := GetTrianglesDrawBuilder()
+	defer ReturnTrianglesDrawBuilder(trid)
+	bx, by := font.BoundText(info, style.LineSpacing)
+	trid.AddQuad(add2f(ptext, [2]float32{-pad, 0}),
+		add2f(ptext, [2]float32{float32(bx) + pad, 0}),
+		add2f(ptext, [2]float32{float32(bx) + pad, -float32(by) - pad}),
+		add2f(ptext, [2]float32{-pad, -float32(by) - pad}))
+
+	// Get it all into the command buffer
+	transforms.LoadWindowViewingMatrices(cb)
+	cb.SetRGBA(RGBA{R: 0.25, G: 0.25, B: 0.25, A: 0.75})
+	cb.Blend()
+	trid.GenerateCommands(cb)
+	cb.DisableBlend()
+	td.GenerateCommands(cb)
 }
 
 func (sp *STARSPane) drawMouseCursor(ctx *PaneContext, paneExtent Extent2D, transforms ScopeTransformations,
@@ -7389,6 +9281,107 @@ func (sp *STARSPane) drawMouseCursor(ctx *PaneContext, paneExtent Extent2D, tran
 	}
 }
 
+///////////////////////////////////////////////////////////////////////////
+// Mouse readout
+
+// mouseReadout caches the nearest fix to the mouse position, which is
+// somewhat expensive to find, so that it is only recomputed when the
+// mouse moves.
+type mouseReadout struct {
+	pos             Point2LL
+	nearestFix      string
+	nearestDistance float32
+}
+
+// formatLatLong returns the position as degrees and decimal minutes, e.g.
+// N40 38.52 W073 46.80.
+func formatLatLong(p Point2LL) string {
+	format := func(v float32, pos, neg string, digits int) string {
+		hemi := Select(v >= 0, pos, neg)
+		v = abs(v)
+		deg := floor(v)
+		return fmt.Sprintf("%s%0*d %05.2f", hemi, digits, int(deg), 60*(v-deg))
+	}
+	return format(p[1], "N", "S", 2) + " " + format(p[0], "E", "W", 3)
+}
+
+// nearestFix returns the named fix, navaid, or airport closest to p.
+func (sp *STARSPane) nearestFix(w *World, p Point2LL) (string, float32) {
+	if sp.readout.pos == p {
+		return sp.readout.nearestFix, sp.readout.nearestDistance
+	}
+
+	name, dist := "", float32(1e30)
+	check := func(n string, loc Point2LL) {
+		// Cheap rejection before computing the distance.
+		if abs(loc[1]-p[1]) > 1 || abs(loc[0]-p[0]) > 1.5 {
+			return
+		}
+		if d := nmdistance2ll(p, loc); d < dist {
+			name, dist = n, d
+		}
+	}
+	for n, loc := range w.Fixes {
+		check(n, loc)
+	}
+	for n, f := range database.Fixes {
+		check(n, f.Location)
+	}
+	for n, nav := range database.Navaids {
+		check(n, nav.Location)
+	}
+	for n, ap := range w.Airports {
+		check(n, ap.Location)
+	}
+
+	sp.readout = mouseReadout{pos: p, nearestFix: name, nearestDistance: dist}
+	return name, dist
+}
+
+// drawMouseReadout draws a line of text at the bottom of the scope that
+// gives the mouse position's latitude and longitude, its bearing and
+// distance from the scope center or the reference fix, and the nearest
+// fix.
+func (sp *STARSPane) drawMouseReadout(ctx *PaneContext, paneExtent Extent2D, transforms ScopeTransformations,
+	cb *CommandBuffer) {
+	if !sp.ShowMouseReadout || ctx.mouse == nil {
+		return
+	}
+	if ctx.mouse.Pos[0] < 0 || ctx.mouse.Pos[0] >= paneExtent.Width() ||
+		ctx.mouse.Pos[1] < 0 || ctx.mouse.Pos[1] >= paneExtent.Height() {
+		return
+	}
+
+	ps := sp.CurrentPreferenceSet
+	w := ctx.world
+	p := transforms.LatLongFromWindowP(ctx.mouse.Pos)
+
+	var b strings.Builder
+	b.WriteString(formatLatLong(p))
+
+	ref, refName := ps.CurrentCenter, "CTR"
+	if sp.MouseReadoutFix != "" {
+		if loc, ok := w.Locate(sp.MouseReadoutFix); ok {
+			ref, refName = loc, sp.MouseReadoutFix
+		}
+	}
+	hdg := headingp2ll(ref, p, w.NmPerLongitude, w.MagneticVariation)
+	fmt.Fprintf(&b, "  %s %03d/%.1f", refName, int(hdg+0.5)%360, nmdistance2ll(ref, p))
+
+	if fix, d := sp.nearestFix(w, p); fix != "" {
+		fmt.Fprintf(&b, "  NEAR %s %.1f", fix, d)
+	}
+
+	font := sp.systemFont[ps.CharSize.Tools]
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	td.AddText(b.String(), [2]float32{4, float32(font.size) + 4},
+		TextStyle{Font: font, Color: ps.Brightness.Lists.ScaleRGB(STARSListColor)})
+
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
 ///////////////////////////////////////////////////////////////////////////
 // DCB menu on top
 
@@ -8073,9 +10066,12 @@ func amendFlightPlan(w *World, callsign string, amend func(fp *FlightPlan)) erro
 	if ac := w.GetAircraft(callsign, false); ac == nil {
 		return ErrNoAircraftForCallsign
 	} else {
-		fp := Select(ac.FlightPlan != nil, ac.FlightPlan, &FlightPlan{})
-		amend(fp)
-		return w.AmendFlightPlan(callsign, *fp)
+		var fp FlightPlan
+		if ac.FlightPlan != nil {
+			fp = *ac.FlightPlan
+		}
+		amend(&fp)
+		return w.AmendFlightPlan(callsign, fp)
 	}
 }
 
@@ -8155,6 +10151,17 @@ func (sp *STARSPane) radarVisibility(w *World, pos Point2LL, alt int) (primary,
 	return
 }
 
+const (
+	FrequencyFilterShow = iota
+	FrequencyFilterDim
+	FrequencyFilterHide
+)
+
+// onFrequency reports whether the aircraft is talking to the user.
+func onFrequency(w *World, ac *Aircraft) bool {
+	return ac.ControllingController == w.Callsign
+}
+
 func (sp *STARSPane) visibleAircraft(w *World) []*Aircraft {
 	var aircraft []*Aircraft
 	ps := sp.CurrentPreferenceSet
@@ -8228,6 +10235,9 @@ func (sp *STARSPane) datablockVisible(ac *Aircraft, ctx *PaneContext) bool {
 	} else if ok, _ := SquawkIsSPC(ac.Squawk); ok {
 		// Special purpose codes
 		return true
+	} else if sp.Aircraft[ac.Callsign].Emergency != "" {
+		// Emergencies, including medevac flights
+		return true
 	} else if sp.Aircraft[ac.Callsign].DatablockType == FullDatablock {
 		// If FDB, may trump others but idc
 		// This *should* be primarily doing CA and ATPA cones
@@ -8288,7 +10298,7 @@ func (sp *STARSPane) getLeaderLineVector(dir CardinalOrdinalDirection) [2]float3
 	angle := dir.Heading()
 	v := [2]float32{sin(radians(angle)), cos(radians(angle))}
 	ps := sp.CurrentPreferenceSet
-	return scale2f(v, float32(10+10*ps.LeaderLineLength))
+	return scale2f(v, float32(10+10*ps.LeaderLineLength)*UIScale(platform))
 }
 
 func (sp *STARSPane) isOverflight(ctx *PaneContext, ac *Aircraft) bool {
@@ -8299,7 +10309,7 @@ func (sp *STARSPane) isOverflight(ctx *PaneContext, ac *Aircraft) bool {
 
 func (sp *STARSPane) tryGetClosestAircraft(w *World, mousePosition [2]float32, transforms ScopeTransformations) (*Aircraft, float32) {
 	var ac *Aircraft
-	distance := float32(20) // in pixels; don't consider anything farther away
+	distance := HitTestDistance(20) // in pixels; don't consider anything farther away
 
 	for _, a := range sp.visibleAircraft(w) {
 		pw := transforms.WindowFromLatLongP(sp.Aircraft[a.Callsign].TrackPosition())
@@ -8315,7 +10325,7 @@ func (sp *STARSPane) tryGetClosestAircraft(w *World, mousePosition [2]float32, t
 
 func (sp *STARSPane) tryGetClosestGhost(ghosts []*GhostAircraft, mousePosition [2]float32, transforms ScopeTransformations) (*GhostAircraft, float32) {
 	var ghost *GhostAircraft
-	distance := float32(20) // in pixels; don't consider anything farther away
+	distance := HitTestDistance(20) // in pixels; don't consider anything farther away
 
 	for _, g := range ghosts {
 		pw := transforms.WindowFromLatLongP(g.Position)
@@ -8341,3 +10351,1193 @@ func (sp *STARSPane) radarSiteId(w *World) string {
 		return "UNKNOWN"
 	}
 }
+
+///////////////////////////////////////////////////////////////////////////
+// Special use airspace
+
+type STARSSUASettings struct {
+	Mode     int    // STARSSUAScheduled, STARSSUAHot, or STARSSUACold
+	Schedule string // Zulu, e.g. "1400-2200,0000-0200"; see ParseSUASchedule
+}
+
+const (
+	STARSSUAScheduled = iota
+	STARSSUAHot
+	STARSSUACold
+)
+
+// updateNearbySUAs finds the special use airspaces that are close enough
+// to the scope's center to be of interest.
+func (sp *STARSPane) updateNearbySUAs() {
+	center := sp.CurrentPreferenceSet.Center
+	sp.nearbySUAs = nil
+	for i := range database.SUAs {
+		sua := &database.SUAs[i]
+		if nmdistance2ll(center, sua.Bounds().Center()) < 150 {
+			sp.nearbySUAs = append(sp.nearbySUAs, sua)
+		}
+	}
+	slices.SortFunc(sp.nearbySUAs, func(a, b *SpecialUseAirspace) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// suaActive returns true if the special use airspace is hot at the given
+// time. Prohibited areas are always active.
+func (sp *STARSPane) suaActive(sua *SpecialUseAirspace, now time.Time) bool {
+	if sua.Type == SUAProhibited {
+		return true
+	}
+	settings, ok := sp.SUASettings[sua.Name]
+	if !ok {
+		return false
+	}
+	switch settings.Mode {
+	case STARSSUAHot:
+		return true
+	case STARSSUACold:
+		return false
+	default:
+		sched, err := ParseSUASchedule(settings.Schedule)
+		return err == nil && SUAScheduleActive(sched, now)
+	}
+}
+
+func (sp *STARSPane) drawSUAUI() {
+	imgui.Checkbox("Show special use airspace", &sp.ShowSUA)
+	imgui.Checkbox("Alert for aircraft predicted to enter active areas", &sp.SUAAlerts)
+
+	for _, sua := range sp.nearbySUAs {
+		if sua.Type == SUAProhibited {
+			continue
+		}
+
+		settings, ok := sp.SUASettings[sua.Name]
+		if !ok {
+			settings = &STARSSUASettings{}
+		}
+
+		imgui.PushID(sua.Name)
+		imgui.Text(fmt.Sprintf("%-20s %-10s", sua.Name, sua.Type))
+		imgui.SameLine()
+		imgui.RadioButtonInt("Scheduled", &settings.Mode, STARSSUAScheduled)
+		imgui.SameLine()
+		imgui.RadioButtonInt("Hot", &settings.Mode, STARSSUAHot)
+		imgui.SameLine()
+		imgui.RadioButtonInt("Cold", &settings.Mode, STARSSUACold)
+		if settings.Mode == STARSSUAScheduled {
+			imgui.SameLine()
+			imgui.InputTextV("Schedule (Zulu)", &settings.Schedule, 0, nil)
+			if _, err := ParseSUASchedule(settings.Schedule); err != nil {
+				imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+				imgui.Text(err.Error())
+				imgui.PopStyleColor()
+			}
+		}
+		imgui.PopID()
+
+		// Only save settings for SUAs the user has changed.
+		if settings.Mode != STARSSUAScheduled || settings.Schedule != "" {
+			sp.SUASettings[sua.Name] = settings
+		} else {
+			delete(sp.SUASettings, sua.Name)
+		}
+	}
+}
+
+func (sp *STARSPane) drawSUAs(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if !sp.ShowSUA {
+		return
+	}
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ps := sp.CurrentPreferenceSet
+	now := ctx.world.CurrentTime()
+	for _, sua := range sp.nearbySUAs {
+		color := STARSSUAInactiveColor
+		if sp.suaActive(sua, now) {
+			color = Select(sua.Type == SUAMOA, STARSSUAMOAColor, STARSSUAActiveColor)
+		}
+		color = ps.Brightness.Lists.ScaleRGB(color)
+
+		for _, v := range sua.Volumes {
+			for i := range v.Boundary {
+				ld.AddLine(v.Boundary[i], v.Boundary[(i+1)%len(v.Boundary)], color)
+			}
+		}
+
+		style := TextStyle{Font: sp.systemFont[ps.CharSize.Tools], Color: color}
+		td.AddTextCentered(sua.Name, transforms.WindowFromLatLongP(sua.Bounds().Center()), style)
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Geofences
+
+// STARSGeofence is an arbitrary region drawn on the scope by the user,
+// along with the conditions under which aircraft in it generate alerts.
+type STARSGeofence struct {
+	Name     string
+	Vertices []Point2LL
+
+	AlertEntry    bool
+	AlertExit     bool
+	BelowAltitude int // alert for aircraft inside below this; 0 if unused
+}
+
+// How long datablocks are flagged after an entry or exit.
+const STARSGeofenceAlertDuration = 30 * time.Second
+
+func (sp *STARSPane) drawTimeSharingUI() {
+	cycle := int32(sp.DatablockCycleSeconds)
+	imgui.SliderInt("Seconds per datablock cycle", &cycle, 1, 5)
+	sp.DatablockCycleSeconds = int(cycle)
+
+	imgui.Text("Full datablock field 5, in order:")
+	ts := sp.Field5TimeSharing
+	for i := range ts {
+		imgui.PushID(strconv.Itoa(i))
+		imgui.Checkbox(ts[i].Field.String(), &ts[i].Enabled)
+		if i > 0 {
+			imgui.SameLine()
+			if imgui.Button("Up") {
+				ts[i-1], ts[i] = ts[i], ts[i-1]
+			}
+		}
+		if i+1 < len(ts) {
+			imgui.SameLine()
+			if imgui.Button("Down") {
+				ts[i], ts[i+1] = ts[i+1], ts[i]
+			}
+		}
+		imgui.PopID()
+	}
+	if imgui.Button("Reset to defaults") {
+		sp.Field5TimeSharing = defaultField5TimeSharing()
+	}
+}
+
+func (sp *STARSPane) drawDuplicateBeaconUI() {
+	if len(sp.duplicateBeacons) == 0 {
+		imgui.Text("No duplicate beacon codes.")
+		return
+	}
+
+	for _, sq := range SortedMapKeys(sp.duplicateBeacons) {
+		imgui.PushID(sq.String())
+		imgui.Text(sq.String() + ":")
+		for _, callsign := range sp.duplicateBeacons[sq] {
+			imgui.SameLine()
+			if imgui.Button("Reassign " + callsign) {
+				sp.squawkReassignments = append(sp.squawkReassignments, callsign)
+			}
+		}
+		imgui.PopID()
+	}
+}
+
+func (sp *STARSPane) drawGeofenceUI() {
+	if sp.wipGeofence != nil {
+		imgui.Text("Click on the scope to add vertices; click the first one again to finish.")
+		if imgui.Button("Cancel") {
+			sp.wipGeofence = nil
+			sp.scopeClickHandler = nil
+		}
+	} else if imgui.Button("Draw new geofence") {
+		sp.wipGeofence = &STARSGeofence{Name: fmt.Sprintf("GF%d", len(sp.Geofences)+1), AlertEntry: true}
+		sp.scopeClickHandler = sp.geofenceClickHandler()
+	}
+
+	deleteIndex := -1
+	for i := range sp.Geofences {
+		gf := &sp.Geofences[i]
+		imgui.PushID(strconv.Itoa(i))
+		imgui.InputTextV("Name", &gf.Name, imgui.InputTextFlagsCharsUppercase, nil)
+		imgui.Checkbox("Alert on entry", &gf.AlertEntry)
+		imgui.SameLine()
+		imgui.Checkbox("Alert on exit", &gf.AlertExit)
+		alt := int32(gf.BelowAltitude)
+		imgui.SliderInt("Alert below altitude (0 to disable)", &alt, 0, 18000)
+		gf.BelowAltitude = int(alt) / 100 * 100
+		if imgui.Button("Delete") {
+			deleteIndex = i
+		}
+		imgui.Separator()
+		imgui.PopID()
+	}
+	if deleteIndex != -1 {
+		sp.Geofences = DeleteSliceElement(sp.Geofences, deleteIndex)
+	}
+}
+
+// geofenceClickHandler returns a scope click handler that adds a vertex
+// to the geofence being drawn. It stays registered until the user clicks
+// on the first vertex, which closes the region.
+func (sp *STARSPane) geofenceClickHandler() func([2]float32, ScopeTransformations) STARSCommandStatus {
+	return func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
+		gf := sp.wipGeofence
+		if gf == nil {
+			status.clear = true
+			return
+		}
+
+		if len(gf.Vertices) >= 3 && distance2f(pw, transforms.WindowFromLatLongP(gf.Vertices[0])) < 10 {
+			sp.Geofences = append(sp.Geofences, *gf)
+			sp.wipGeofence = nil
+			status.clear = true
+			status.output = gf.Name
+		} else {
+			gf.Vertices = append(gf.Vertices, transforms.LatLongFromWindowP(pw))
+		}
+		return
+	}
+}
+
+// updateGeofenceAlerts checks each aircraft against the geofences' alert
+// rules.
+func (sp *STARSPane) updateGeofenceAlerts(w *World) {
+	now := w.CurrentTime()
+	for callsign, ac := range w.Aircraft {
+		state := sp.Aircraft[callsign]
+		if state.insideGeofences == nil {
+			state.insideGeofences = make(map[string]interface{})
+		}
+
+		alert, newAlert := false, false
+		for _, gf := range sp.Geofences {
+			_, wasInside := state.insideGeofences[gf.Name]
+			inside := ac.IsAirborne() && PointInPolygon2LL(state.track.Position, gf.Vertices)
+			if inside {
+				state.insideGeofences[gf.Name] = nil
+			} else {
+				delete(state.insideGeofences, gf.Name)
+			}
+
+			// Entries and exits are only flagged for a little while, but
+			// altitude alerts persist as long as the condition holds.
+			if (gf.AlertEntry && inside && !wasInside) || (gf.AlertExit && !inside && wasInside) {
+				state.GeofenceAlertEnd = now.Add(STARSGeofenceAlertDuration)
+				newAlert = true
+			}
+			if inside && gf.BelowAltitude > 0 && state.track.Altitude < gf.BelowAltitude {
+				newAlert = newAlert || !state.GeofenceAlert
+				alert = true
+			}
+		}
+		alert = alert || now.Before(state.GeofenceAlertEnd)
+
+		if newAlert && ac.TrackingController == w.Callsign {
+			globalConfig.Audio.PlayOnce(AudioConflictAlert)
+		}
+		state.GeofenceAlert = alert
+	}
+}
+
+func (sp *STARSPane) drawGeofences(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if len(sp.Geofences) == 0 && sp.wipGeofence == nil {
+		return
+	}
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor)
+	style := TextStyle{Font: sp.systemFont[ps.CharSize.Tools], Color: color}
+
+	for _, gf := range sp.Geofences {
+		e := EmptyExtent2D()
+		for i, v := range gf.Vertices {
+			ld.AddLine(v, gf.Vertices[(i+1)%len(gf.Vertices)], color)
+			e = Union(e, v)
+		}
+		td.AddTextCentered(gf.Name, transforms.WindowFromLatLongP(e.Center()), style)
+	}
+
+	// The one being drawn is left open, with a line to the mouse position.
+	if gf := sp.wipGeofence; gf != nil && len(gf.Vertices) > 0 {
+		for i := 0; i+1 < len(gf.Vertices); i++ {
+			ld.AddLine(gf.Vertices[i], gf.Vertices[i+1], color)
+		}
+		if ctx.mouse != nil {
+			ld.AddLine(gf.Vertices[len(gf.Vertices)-1], transforms.LatLongFromWindowP(ctx.mouse.Pos), color)
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Alert triggers
+
+// STARSAlertTrigger is a user-defined rule for an alert: when an aircraft
+// matching its filters meets all of its conditions, the datablock is
+// flagged with the trigger's name and a sound is played. For example, any
+// arrival to KJFK below 11,000' more than 30nm from the airport, or AAL123
+// within 3nm of LENDY.
+type STARSAlertTrigger struct {
+	Name    string
+	Enabled bool
+
+	// Filters; aircraft must match all of the ones that are set.
+	Callsign   string
+	Arrivals   bool
+	Departures bool
+	Airport    string // arrival or departure airport
+
+	// Conditions; 0 or "" if unused.
+	BelowAltitude  int
+	AboveAltitude  int
+	Fix            string  // distances are measured from here, or Airport if it's empty
+	WithinDistance float32 // nm
+	BeyondDistance float32 // nm
+}
+
+// How long datablocks are flagged after a trigger fires.
+const STARSAlertTriggerDuration = 30 * time.Second
+
+// HasConditions returns true if the trigger has at least one condition;
+// triggers without conditions would fire for every matching aircraft and
+// are ignored.
+func (t *STARSAlertTrigger) HasConditions() bool {
+	return t.BelowAltitude > 0 || t.AboveAltitude > 0 || t.WithinDistance > 0 || t.BeyondDistance > 0
+}
+
+// Matches returns true if the aircraft with the given radar track position
+// and altitude satisfies the trigger's filters and conditions.
+func (t *STARSAlertTrigger) Matches(ac *Aircraft, pos Point2LL, alt int, w *World) bool {
+	if !t.Enabled || !t.HasConditions() {
+		return false
+	}
+	if t.Callsign != "" && ac.Callsign != t.Callsign {
+		return false
+	}
+
+	if t.Arrivals || t.Departures || t.Airport != "" {
+		fp := ac.FlightPlan
+		if fp == nil {
+			return false
+		}
+		isAirport := func(ap string, airports map[string]*Airport) bool {
+			if t.Airport != "" {
+				return ap == t.Airport
+			}
+			_, ok := airports[ap]
+			return ok
+		}
+		arrival := isAirport(fp.ArrivalAirport, w.ArrivalAirports)
+		departure := isAirport(fp.DepartureAirport, w.DepartureAirports)
+		if !((t.Arrivals && arrival) || (t.Departures && departure) ||
+			(!t.Arrivals && !t.Departures && (arrival || departure))) {
+			return false
+		}
+	}
+
+	if t.BelowAltitude > 0 && alt >= t.BelowAltitude {
+		return false
+	}
+	if t.AboveAltitude > 0 && alt <= t.AboveAltitude {
+		return false
+	}
+
+	if t.WithinDistance > 0 || t.BeyondDistance > 0 {
+		p, ok := w.Locate(Select(t.Fix != "", t.Fix, t.Airport))
+		if !ok {
+			return false
+		}
+		d := nmdistance2ll(pos, p)
+		if (t.WithinDistance > 0 && d > t.WithinDistance) || (t.BeyondDistance > 0 && d <= t.BeyondDistance) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (sp *STARSPane) drawAlertTriggerUI() {
+	deleteIndex := -1
+	for i := range sp.AlertTriggers {
+		t := &sp.AlertTriggers[i]
+		imgui.PushID(strconv.Itoa(i))
+
+		imgui.Checkbox("##enabled", &t.Enabled)
+		imgui.SameLine()
+		imgui.InputTextV("Name", &t.Name, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+
+		imgui.Text("Aircraft:")
+		imgui.SameLine()
+		imgui.Checkbox("Arrivals", &t.Arrivals)
+		imgui.SameLine()
+		imgui.Checkbox("Departures", &t.Departures)
+		imgui.InputTextV("Callsign (optional)", &t.Callsign, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+		imgui.InputTextV("Airport (optional)", &t.Airport, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+
+		imgui.Text("Alert when:")
+		below, above := int32(t.BelowAltitude), int32(t.AboveAltitude)
+		imgui.SliderInt("Below altitude (0 to disable)", &below, 0, 40000)
+		imgui.SliderInt("Above altitude (0 to disable)", &above, 0, 40000)
+		t.BelowAltitude, t.AboveAltitude = int(below)/100*100, int(above)/100*100
+		imgui.InputTextV("Fix (default: airport)", &t.Fix, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+		imgui.SliderFloatV("Within distance (nm, 0 to disable)", &t.WithinDistance, 0, 100, "%.1f", 0)
+		imgui.SliderFloatV("Beyond distance (nm, 0 to disable)", &t.BeyondDistance, 0, 100, "%.1f", 0)
+
+		if !t.HasConditions() {
+			imgui.Text("At least one altitude or distance condition must be set.")
+		}
+		if imgui.Button("Delete") {
+			deleteIndex = i
+		}
+		imgui.Separator()
+		imgui.PopID()
+	}
+	if deleteIndex != -1 {
+		sp.AlertTriggers = DeleteSliceElement(sp.AlertTriggers, deleteIndex)
+	}
+
+	if imgui.Button("Add trigger") {
+		sp.AlertTriggers = append(sp.AlertTriggers, STARSAlertTrigger{
+			Name:    fmt.Sprintf("TR%d", len(sp.AlertTriggers)+1),
+			Enabled: true,
+		})
+	}
+}
+
+// updateAlertTriggers checks each aircraft against the user's alert
+// triggers. Triggers fire when an aircraft first meets their conditions;
+// they may fire again after it no longer does.
+func (sp *STARSPane) updateAlertTriggers(w *World) {
+	now := w.CurrentTime()
+	for callsign, ac := range w.Aircraft {
+		state := sp.Aircraft[callsign]
+		if state.matchedTriggers == nil {
+			state.matchedTriggers = make(map[string]interface{})
+		}
+
+		for _, t := range sp.AlertTriggers {
+			_, matched := state.matchedTriggers[t.Name]
+			if t.Matches(ac, state.track.Position, state.track.Altitude, w) {
+				if !matched {
+					state.matchedTriggers[t.Name] = nil
+					state.TriggerAlert = t.Name
+					state.TriggerAlertEnd = now.Add(STARSAlertTriggerDuration)
+					lg.Infof("%s: alert trigger %s", callsign, t.Name)
+					globalConfig.Audio.PlayOnce(AudioTriggerAlert)
+				}
+			} else {
+				delete(state.matchedTriggers, t.Name)
+			}
+		}
+
+		if now.After(state.TriggerAlertEnd) {
+			state.TriggerAlert = ""
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Range rings
+
+// STARSRangeRings is a set of range rings around a fix or airport that is
+// drawn in addition to the main ones.
+type STARSRangeRings struct {
+	Center   string // fix, navaid, or airport
+	Interval int    // nm
+	Radius   int    // nm; 0 to draw them off the scope
+}
+
+func (sp *STARSPane) drawRangeRingsUI() {
+	ps := &sp.CurrentPreferenceSet
+	imgui.Checkbox("Label rings with their distances", &ps.RangeRingLabels)
+	imgui.Text("Additional range rings:")
+
+	deleteIndex := -1
+	for i := range ps.AdditionalRangeRings {
+		rr := &ps.AdditionalRangeRings[i]
+		imgui.PushID(strconv.Itoa(i))
+		imgui.InputTextV("Center (fix or airport)", &rr.Center, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+		interval, radius := int32(rr.Interval), int32(rr.Radius)
+		imgui.SliderInt("Interval (nm)", &interval, 1, 20)
+		imgui.SliderInt("Radius (nm, 0 for unlimited)", &radius, 0, 100)
+		rr.Interval, rr.Radius = int(interval), int(radius)
+		if imgui.Button("Delete") {
+			deleteIndex = i
+		}
+		imgui.Separator()
+		imgui.PopID()
+	}
+	if deleteIndex != -1 {
+		ps.AdditionalRangeRings = DeleteSliceElement(ps.AdditionalRangeRings, deleteIndex)
+	}
+	if imgui.Button("Add range rings") {
+		ps.AdditionalRangeRings = append(ps.AdditionalRangeRings, STARSRangeRings{Interval: 5, Radius: 20})
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Bookmarks
+
+// STARSView is the part of the display's state that a bookmark records.
+type STARSView struct {
+	Center   Point2LL
+	Range    float32
+	Rotation float32 // degrees
+}
+
+// How long it takes to move between bookmarked views when animation is
+// enabled.
+const STARSBookmarkAnimationDuration = 600 * time.Millisecond
+
+func (sp *STARSPane) saveBookmark(idx int) {
+	ps := &sp.CurrentPreferenceSet
+	bm := &ps.Bookmarks[idx]
+	bm.Center = ps.CurrentCenter
+	bm.Range = ps.Range
+	bm.Rotation = ps.Rotation
+	bm.TopDownMode = ps.TopDownMode
+}
+
+func (sp *STARSPane) recallBookmark(idx int) {
+	ps := &sp.CurrentPreferenceSet
+	bm := ps.Bookmarks[idx]
+	if bm.Range == 0 {
+		// Never saved
+		return
+	}
+
+	ps.Center = bm.Center
+	ps.TopDownMode = bm.TopDownMode
+	to := STARSView{Center: bm.Center, Range: bm.Range, Rotation: bm.Rotation}
+	if sp.AnimateBookmarks {
+		sp.viewAnimation = &struct {
+			From, To STARSView
+			Start    time.Time
+		}{
+			From:  STARSView{Center: ps.CurrentCenter, Range: ps.Range, Rotation: ps.Rotation},
+			To:    to,
+			Start: time.Now(),
+		}
+	} else {
+		ps.CurrentCenter, ps.Range, ps.Rotation = to.Center, to.Range, to.Rotation
+	}
+}
+
+// updateViewAnimation moves the display's center, range, and rotation
+// toward those of a bookmark that is being recalled.
+func (sp *STARSPane) updateViewAnimation() {
+	va := sp.viewAnimation
+	if va == nil {
+		return
+	}
+
+	ps := &sp.CurrentPreferenceSet
+	t := float32(time.Since(va.Start)) / float32(STARSBookmarkAnimationDuration)
+	if t >= 1 {
+		ps.CurrentCenter, ps.Range, ps.Rotation = va.To.Center, va.To.Range, va.To.Rotation
+		sp.viewAnimation = nil
+		return
+	}
+
+	// Ease in and out
+	t = t * t * (3 - 2*t)
+	ps.CurrentCenter = lerp2f(t, va.From.Center, va.To.Center)
+	ps.Range = lerp(t, va.From.Range, va.To.Range)
+	// Take the shorter way around for the rotation.
+	d := va.To.Rotation - va.From.Rotation
+	if d > 180 {
+		d -= 360
+	} else if d < -180 {
+		d += 360
+	}
+	ps.Rotation = va.From.Rotation + t*d
+}
+
+func (sp *STARSPane) drawBookmarksUI() {
+	ps := &sp.CurrentPreferenceSet
+
+	imgui.Checkbox("Animate pan and zoom when recalling bookmarks", &sp.AnimateBookmarks)
+	imgui.SliderFloatV("Scope rotation (degrees)", &ps.Rotation, -180, 180, "%.0f", 0)
+	imgui.Text("Control-# recalls a bookmark; Control-Alt-# saves the current view to it.")
+
+	if imgui.BeginTableV("bookmarks", 4, imgui.TableFlagsBordersV|imgui.TableFlagsBordersOuterH|imgui.TableFlagsRowBg, imgui.Vec2{}, 0) {
+		imgui.TableSetupColumn("#")
+		imgui.TableSetupColumn("Name")
+		imgui.TableSetupColumn("View")
+		imgui.TableSetupColumn("")
+		imgui.TableHeadersRow()
+
+		for i := range ps.Bookmarks {
+			bm := &ps.Bookmarks[i]
+			imgui.PushID(strconv.Itoa(i))
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.Text(strconv.Itoa(i))
+			imgui.TableNextColumn()
+			imgui.InputTextV("##name", &bm.Name, 0, nil)
+			imgui.TableNextColumn()
+			if bm.Range != 0 {
+				imgui.Text(fmt.Sprintf("%s %.0fnm %.0f deg", bm.Center.DDString(), bm.Range, bm.Rotation))
+			}
+			imgui.TableNextColumn()
+			if imgui.Button("Save") {
+				sp.saveBookmark(i)
+			}
+			imgui.SameLine()
+			uiStartDisable(bm.Range == 0)
+			if imgui.Button("Go") {
+				sp.recallBookmark(i)
+			}
+			uiEndDisable(bm.Range == 0)
+			imgui.PopID()
+		}
+		imgui.EndTable()
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Scheduled events
+
+// STARSScheduledEvent is an action that is performed at a given Zulu time
+// each day, e.g. to switch runway configurations or to post a banner
+// when an event's arrival push begins.
+type STARSScheduledEvent struct {
+	Time    string // Zulu, HHMM
+	Action  int    // STARSScheduled* value below
+	Enabled bool
+
+	Text string // banner text, ATIS code, or SUA name
+
+	// Runway configuration changes
+	Airport          string
+	ArrivalRunways   string // space-separated
+	DepartureRunways string
+}
+
+const (
+	STARSScheduledBanner = iota
+	STARSScheduledRunways
+	STARSScheduledATIS
+	STARSScheduledSUAHot
+	STARSScheduledSUACold
+)
+
+var starsScheduledActionNames = [...]string{"Banner", "Runway configuration", "ATIS", "Activate SUA", "Deactivate SUA"}
+
+// How long a scheduled banner is displayed.
+const STARSScheduledBannerDuration = 2 * time.Minute
+
+// Minute returns the time of the event as minutes after midnight Zulu,
+// or -1 if it is invalid.
+func (se *STARSScheduledEvent) Minute() int {
+	hhmm, err := strconv.Atoi(se.Time)
+	if err != nil || len(se.Time) != 4 || hhmm/100 > 23 || hhmm%100 > 59 {
+		return -1
+	}
+	return 60*(hhmm/100) + hhmm%100
+}
+
+func (se *STARSScheduledEvent) String() string {
+	s := se.Time + "Z " + starsScheduledActionNames[se.Action]
+	switch se.Action {
+	case STARSScheduledRunways:
+		return s + fmt.Sprintf(" %s ARR %s DEP %s", se.Airport, se.ArrivalRunways, se.DepartureRunways)
+	default:
+		return s + " " + se.Text
+	}
+}
+
+func (sp *STARSPane) drawScheduledEventsUI() {
+	imgui.Text(fmt.Sprintf("Current time: %sZ", time.Now().UTC().Format("1504")))
+
+	deleteIndex := -1
+	for i := range sp.ScheduledEvents {
+		se := &sp.ScheduledEvents[i]
+		imgui.PushID(strconv.Itoa(i))
+
+		imgui.Checkbox("##enabled", &se.Enabled)
+		imgui.SameLine()
+		imgui.SetNextItemWidth(60)
+		imgui.InputTextV("Time (Zulu HHMM)", &se.Time, imgui.InputTextFlagsCharsDecimal, nil)
+		if se.Minute() == -1 {
+			imgui.SameLine()
+			imgui.Text("Invalid time")
+		}
+
+		if imgui.BeginComboV("Action", starsScheduledActionNames[se.Action], 0) {
+			for action, name := range starsScheduledActionNames {
+				if imgui.SelectableV(name, action == se.Action, 0, imgui.Vec2{}) {
+					se.Action = action
+				}
+			}
+			imgui.EndCombo()
+		}
+
+		switch se.Action {
+		case STARSScheduledBanner:
+			imgui.InputTextV("Text", &se.Text, imgui.InputTextFlagsCharsUppercase, nil)
+		case STARSScheduledRunways:
+			imgui.InputTextV("Airport", &se.Airport, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+			imgui.InputTextV("Arrival runways", &se.ArrivalRunways, imgui.InputTextFlagsCharsUppercase, nil)
+			imgui.InputTextV("Departure runways", &se.DepartureRunways, imgui.InputTextFlagsCharsUppercase, nil)
+		case STARSScheduledATIS:
+			imgui.InputTextV("ATIS code", &se.Text, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+			if len(se.Text) > 1 {
+				se.Text = se.Text[:1]
+			}
+		case STARSScheduledSUAHot, STARSScheduledSUACold:
+			imgui.InputTextV("SUA name", &se.Text, imgui.InputTextFlagsCharsUppercase, nil)
+		}
+
+		if imgui.Button("Delete") {
+			deleteIndex = i
+		}
+		imgui.Separator()
+		imgui.PopID()
+	}
+	if deleteIndex != -1 {
+		sp.ScheduledEvents = DeleteSliceElement(sp.ScheduledEvents, deleteIndex)
+	}
+
+	if imgui.Button("Add scheduled event") {
+		sp.ScheduledEvents = append(sp.ScheduledEvents, STARSScheduledEvent{
+			Time:    time.Now().UTC().Add(time.Hour).Format("1500"),
+			Enabled: true,
+		})
+	}
+}
+
+// updateScheduledEvents performs the actions for the scheduled events
+// whose times have passed since it was last called.
+func (sp *STARSPane) updateScheduledEvents(ctx *PaneContext) {
+	now := time.Now().UTC()
+	if sp.lastScheduleCheck.IsZero() || now.Sub(sp.lastScheduleCheck) > time.Hour {
+		// Don't fire everything that was scheduled before we started or
+		// while the computer was asleep.
+		sp.lastScheduleCheck = now
+		return
+	}
+
+	prev := 60*sp.lastScheduleCheck.Hour() + sp.lastScheduleCheck.Minute()
+	cur := 60*now.Hour() + now.Minute()
+	sp.lastScheduleCheck = now
+	if prev == cur {
+		return
+	}
+
+	for _, se := range sp.ScheduledEvents {
+		m := se.Minute()
+		if !se.Enabled || m == -1 {
+			continue
+		}
+		if (prev < cur && (m <= prev || m > cur)) || (prev > cur && m <= prev && m > cur) {
+			// Not in (prev, cur], accounting for midnight.
+			continue
+		}
+
+		lg.Infof("scheduled event: %s", se.String())
+		switch se.Action {
+		case STARSScheduledBanner:
+			sp.scheduledBanner = se.Text
+			sp.scheduledBannerEnd = now.Add(STARSScheduledBannerDuration)
+			globalConfig.Audio.PlayOnce(AudioMessageAlert)
+
+		case STARSScheduledRunways:
+			ctx.world.RunwayConfiguration().SetRunways(se.Airport, strings.Fields(se.ArrivalRunways),
+				strings.Fields(se.DepartureRunways))
+
+		case STARSScheduledATIS:
+			sp.CurrentPreferenceSet.CurrentATIS = se.Text
+
+		case STARSScheduledSUAHot, STARSScheduledSUACold:
+			settings, ok := sp.SUASettings[se.Text]
+			if !ok {
+				settings = &STARSSUASettings{}
+				sp.SUASettings[se.Text] = settings
+			}
+			settings.Mode = Select(se.Action == STARSScheduledSUAHot, STARSSUAHot, STARSSUACold)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Annotations
+
+// STARSAnnotation is a text label or symbol that the user has placed on
+// the scope, e.g. to mark a closed runway or the location of a holding
+// stack.
+type STARSAnnotation struct {
+	Type int // STARSAnnotation* value below
+	// Text: its center. Arrow: tail and head. Circle: center and a point
+	// on the circle. Freehand: the path.
+	Points []Point2LL
+	Text   string
+}
+
+const (
+	STARSAnnotationText = iota
+	STARSAnnotationArrow
+	STARSAnnotationCircle
+	STARSAnnotationFreehand
+)
+
+var STARSAnnotationColor = RGB{.9, .9, .6}
+
+func (sp *STARSPane) drawAnnotationUI() {
+	imgui.RadioButtonInt("Text", &sp.annotationTool, STARSAnnotationText)
+	imgui.SameLine()
+	imgui.RadioButtonInt("Arrow", &sp.annotationTool, STARSAnnotationArrow)
+	imgui.SameLine()
+	imgui.RadioButtonInt("Circle", &sp.annotationTool, STARSAnnotationCircle)
+	imgui.SameLine()
+	imgui.RadioButtonInt("Freehand", &sp.annotationTool, STARSAnnotationFreehand)
+	if sp.annotationTool == STARSAnnotationText {
+		imgui.InputTextV("Text", &sp.annotationText, imgui.InputTextFlagsCharsUppercase, nil)
+	}
+
+	if sp.scopeClickHandler != nil && sp.wipGeofence == nil {
+		imgui.Text([...]string{"Click to place the text.", "Click at the tail and then the head of the arrow.",
+			"Click at the center and then on the circle.", "Drag to draw the line."}[sp.annotationTool])
+		if imgui.Button("Cancel") {
+			sp.wipAnnotation = nil
+			sp.scopeClickHandler = nil
+		}
+	} else if imgui.Button("Place annotation") &&
+		(sp.annotationTool != STARSAnnotationText || sp.annotationText != "") {
+		sp.scopeClickHandler = sp.annotationClickHandler(sp.annotationTool, sp.annotationText)
+	}
+
+	deleteIndex := -1
+	for i, an := range sp.Annotations {
+		imgui.PushID(strconv.Itoa(i))
+		if imgui.Button("Delete") {
+			deleteIndex = i
+		}
+		imgui.SameLine()
+		desc := [...]string{"Text", "Arrow", "Circle", "Freehand"}[an.Type]
+		if an.Text != "" {
+			desc += ": " + an.Text
+		}
+		imgui.Text(desc)
+		imgui.PopID()
+	}
+	if deleteIndex != -1 {
+		sp.Annotations = DeleteSliceElement(sp.Annotations, deleteIndex)
+	}
+	if len(sp.Annotations) > 0 && imgui.Button("Delete all") {
+		sp.Annotations = nil
+	}
+}
+
+// annotationClickHandler returns a scope click handler that places an
+// annotation of the given type, remaining registered until it has as many
+// clicks as the annotation needs. (Freehand lines are handled in
+// consumeMouseEvents after the first click.)
+func (sp *STARSPane) annotationClickHandler(tool int, text string) func([2]float32, ScopeTransformations) STARSCommandStatus {
+	return func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
+		p := transforms.LatLongFromWindowP(pw)
+		if sp.wipAnnotation == nil {
+			sp.wipAnnotation = &STARSAnnotation{Type: tool, Text: text}
+		}
+		an := sp.wipAnnotation
+		an.Points = append(an.Points, p)
+
+		switch tool {
+		case STARSAnnotationText:
+			sp.Annotations = append(sp.Annotations, *an)
+			sp.wipAnnotation = nil
+			status.clear = true
+
+		case STARSAnnotationArrow, STARSAnnotationCircle:
+			if len(an.Points) == 2 {
+				sp.Annotations = append(sp.Annotations, *an)
+				sp.wipAnnotation = nil
+				status.clear = true
+			}
+
+		case STARSAnnotationFreehand:
+			status.clear = true
+		}
+		return
+	}
+}
+
+func (sp *STARSPane) drawAnnotations(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	annotations := sp.Annotations
+	if sp.wipAnnotation != nil {
+		annotations = append(DuplicateSlice(annotations), *sp.wipAnnotation)
+	}
+	if len(annotations) == 0 {
+		return
+	}
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	// Arrowheads are drawn in window coordinates so that they are the
+	// same size regardless of the scope range.
+	wld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(wld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSAnnotationColor)
+	style := TextStyle{Font: sp.systemFont[ps.CharSize.Tools], Color: color}
+
+	for _, an := range annotations {
+		switch an.Type {
+		case STARSAnnotationText:
+			td.AddTextCentered(an.Text, transforms.WindowFromLatLongP(an.Points[0]), style)
+
+		case STARSAnnotationArrow:
+			if len(an.Points) < 2 {
+				break
+			}
+			ld.AddLine(an.Points[0], an.Points[1], color)
+			p0, p1 := transforms.WindowFromLatLongP(an.Points[0]), transforms.WindowFromLatLongP(an.Points[1])
+			if distance2f(p0, p1) > 0 {
+				v := scale2f(normalize2f(sub2f(p0, p1)), 10)
+				perp := [2]float32{-v[1], v[0]}
+				wld.AddLine(p1, add2f(p1, add2f(v, scale2f(perp, 0.5))), color)
+				wld.AddLine(p1, add2f(p1, sub2f(v, scale2f(perp, 0.5))), color)
+			}
+
+		case STARSAnnotationCircle:
+			if len(an.Points) < 2 {
+				break
+			}
+			r := nmdistance2ll(an.Points[0], an.Points[1])
+			ld.AddLatLongCircle(an.Points[0], ctx.world.NmPerLongitude, r, 360, color)
+
+		case STARSAnnotationFreehand:
+			for i := 0; i+1 < len(an.Points); i++ {
+				ld.AddLine(an.Points[i], an.Points[i+1], color)
+			}
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	wld.GenerateCommands(cb)
+	td.GenerateCommands(cb)
+}
+
+// drawHolds draws the holding patterns that have been defined in the
+// HoldsPane, if there is one.
+func (sp *STARSPane) drawHolds(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSAnnotationColor)
+	style := TextStyle{Font: sp.systemFont[ps.CharSize.Tools], Color: color}
+
+	globalConfig.DisplayRoot.VisitPanes(func(pane Pane) {
+		hp, ok := pane.(*HoldsPane)
+		if !ok {
+			return
+		}
+		for _, h := range hp.Holds {
+			pts := h.Racetrack(ctx.world)
+			if len(pts) == 0 {
+				continue
+			}
+			for i := range pts {
+				ld.AddLine(pts[i], pts[(i+1)%len(pts)], color)
+			}
+			// Label the fix with the number of aircraft in the hold.
+			label := h.Fix
+			if n := len(h.Aircraft); n > 0 {
+				label += fmt.Sprintf(" (%d)", n)
+			}
+			pw := add2f(transforms.WindowFromLatLongP(pts[1]), [2]float32{8, 8})
+			td.AddText(label, pw, style)
+		}
+	})
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
+// Default length of extended runway centerlines, in nm.
+const STARSDefaultCenterlineLength = 15
+
+// drawCenterlines draws the extended centerlines that are enabled in the
+// runway configuration, marking the final approach fixes of the runway's
+// approaches if requested.
+func (sp *STARSPane) drawCenterlines(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.VideoGroupA.ScaleRGB(STARSMapColor)
+	style := TextStyle{Font: sp.systemFont[ps.CharSize.Tools], Color: color}
+	length := Select(sp.CenterlineLength > 0, sp.CenterlineLength, STARSDefaultCenterlineLength)
+
+	w := ctx.world
+	rc := w.RunwayConfiguration()
+	for _, icao := range SortedMapKeys(rc.Airports) {
+		for _, id := range rc.Airports[icao].Centerlines {
+			rwy, ok := LookupRunway(icao, id)
+			if !ok {
+				continue
+			}
+			for _, seg := range ExtendedCenterline(rwy, float32(length), w.NmPerLongitude, w.MagneticVariation) {
+				ld.AddLine(seg[0], seg[1], color)
+			}
+
+			if _, faf := rc.ShowCenterline(icao, id); !faf {
+				continue
+			}
+			ap, ok := w.Airports[icao]
+			if !ok {
+				continue
+			}
+			// Mark each distinct final approach fix with a diamond.
+			var fixes []string
+			for _, appr := range ap.Approaches {
+				if appr.Runway != id {
+					continue
+				}
+				for _, wps := range appr.Waypoints {
+					for _, wp := range wps {
+						if !wp.FAF || slices.Contains(fixes, wp.Fix) {
+							continue
+						}
+						fixes = append(fixes, wp.Fix)
+
+						pw := transforms.WindowFromLatLongP(wp.Location)
+						diamond := [4][2]float32{{0, 5}, {5, 0}, {0, -5}, {-5, 0}}
+						for i := range diamond {
+							ld.AddLine(transforms.LatLongFromWindowP(add2f(pw, diamond[i])),
+								transforms.LatLongFromWindowP(add2f(pw, diamond[(i+1)%4])), color)
+						}
+						td.AddText(wp.Fix, add2f(pw, [2]float32{8, 8}), style)
+					}
+				}
+			}
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Hold detection
+
+type STARSHoldSample struct {
+	Time     time.Time
+	Heading  float32
+	Position Point2LL
+}
+
+const (
+	// Track history that is considered when looking for holds; long
+	// enough to include two turns of a typical 1-minute-leg hold.
+	STARSHoldDetectionWindow = 6 * time.Minute
+	// All of the positions must be within this distance of their
+	// centroid for the aircraft to be considered to be holding.
+	STARSHoldDetectionRadius = 8 // nm
+)
+
+// updateHoldDetection marks the aircraft whose recent tracks look like a
+// holding pattern: repeated 180 degree turns in the same direction
+// without going very far.
+func (sp *STARSPane) updateHoldDetection(w *World) {
+	now := w.CurrentTime()
+	for callsign, ac := range w.Aircraft {
+		state := sp.Aircraft[callsign]
+		if !ac.IsAirborne() || !state.HaveHeading() {
+			state.Holding = false
+			state.holdSamples = nil
+			continue
+		}
+
+		state.holdSamples = append(state.holdSamples, STARSHoldSample{
+			Time:     now,
+			Heading:  state.TrackHeading(w.NmPerLongitude),
+			Position: state.track.Position,
+		})
+		for len(state.holdSamples) > 0 && now.Sub(state.holdSamples[0].Time) > STARSHoldDetectionWindow {
+			state.holdSamples = state.holdSamples[1:]
+		}
+
+		state.Holding = looksLikeHold(state.holdSamples, w.NmPerLongitude)
+	}
+}
+
+func looksLikeHold(samples []STARSHoldSample, nmPerLongitude float32) bool {
+	if len(samples) < 2 {
+		return false
+	}
+
+	// Holding aircraft stay in a small area.
+	var centroid [2]float32
+	for _, s := range samples {
+		centroid = add2f(centroid, ll2nm(s.Position, nmPerLongitude))
+	}
+	centroid = scale2f(centroid, 1/float32(len(samples)))
+	for _, s := range samples {
+		if distance2f(centroid, ll2nm(s.Position, nmPerLongitude)) > STARSHoldDetectionRadius {
+			return false
+		}
+	}
+
+	// Find the turns: runs of consecutive heading changes in the same
+	// direction. Count the ones that are roughly 180 degrees, separately
+	// for left and right turns, since holds only turn one way.
+	var turns [2]int // left, right
+	run := float32(0)
+	endRun := func() {
+		if run >= 150 {
+			turns[1]++
+		} else if run <= -150 {
+			turns[0]++
+		}
+		run = 0
+	}
+	for i := 1; i < len(samples); i++ {
+		delta := samples[i].Heading - samples[i-1].Heading
+		if delta > 180 {
+			delta -= 360
+		} else if delta < -180 {
+			delta += 360
+		}
+
+		if abs(delta) < 0.5 || (run != 0 && (delta > 0) != (run > 0)) {
+			// Straight flight or a change in the turn direction.
+			endRun()
+		}
+		if abs(delta) >= 0.5 {
+			run += delta
+		}
+	}
+	endRun()
+
+	return turns[0] >= 2 || turns[1] >= 2
+}
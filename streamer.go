@@ -0,0 +1,127 @@
+// streamer.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Streamer mode makes vice friendlier to stream (e.g., on Twitch): private
+// messages aren't shown so that they don't leak to viewers, datablocks are
+// drawn larger so that they're readable after video compression, and the
+// scope can show a legend of what the colors mean along with the
+// controller's position, frequency, and ATIS.
+
+type StreamerMode struct {
+	Enabled           bool
+	ShowLegend        bool
+	ShowFrequencyInfo bool
+	Corner            StreamerOverlayCorner
+}
+
+type StreamerOverlayCorner int
+
+const (
+	StreamerOverlayUpperLeft StreamerOverlayCorner = iota
+	StreamerOverlayUpperRight
+	StreamerOverlayLowerLeft
+	StreamerOverlayLowerRight
+)
+
+func (c StreamerOverlayCorner) String() string {
+	return []string{"Upper left", "Upper right", "Lower left", "Lower right"}[c]
+}
+
+// streamerModeEnabled indicates whether streamer mode is active.
+func streamerModeEnabled() bool {
+	return globalConfig.StreamerMode.Enabled
+}
+
+type streamerLegendEntry struct {
+	label string
+	color RGB
+}
+
+func (sm *StreamerMode) DrawUI() {
+	imgui.Checkbox(Tr("Enable streamer mode"), &sm.Enabled)
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip(Tr("Hides private messages and enlarges datablocks"))
+	}
+
+	uiStartDisable(!sm.Enabled)
+	imgui.Checkbox(Tr("Show color legend"), &sm.ShowLegend)
+	imgui.Checkbox(Tr("Show position, frequency, and ATIS"), &sm.ShowFrequencyInfo)
+	if imgui.BeginComboV(Tr("Overlay corner"), Tr(sm.Corner.String()), 0) {
+		for c := StreamerOverlayUpperLeft; c <= StreamerOverlayLowerRight; c++ {
+			if imgui.SelectableV(Tr(c.String()), c == sm.Corner, 0, imgui.Vec2{}) {
+				sm.Corner = c
+			}
+		}
+		imgui.EndCombo()
+	}
+	uiEndDisable(!sm.Enabled)
+}
+
+// DrawOverlay draws the streamer information and legend, as enabled, in
+// the selected corner of the pane. Window coordinate matrices should
+// already be loaded in the command buffer.
+func (sm *StreamerMode) DrawOverlay(info []string, legend []streamerLegendEntry, font *Font,
+	textColor RGB, paneExtent Extent2D, cb *CommandBuffer) {
+	if !sm.Enabled {
+		return
+	}
+
+	type line struct {
+		text  string
+		color RGB
+	}
+	var lines []line
+	if sm.ShowFrequencyInfo {
+		for _, s := range info {
+			lines = append(lines, line{text: s, color: textColor})
+		}
+	}
+	if sm.ShowLegend {
+		for _, e := range legend {
+			lines = append(lines, line{text: e.label, color: e.color})
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	// Figure out the size of the block of text so that it can be placed
+	// in the corner.
+	var width float32
+	for _, l := range lines {
+		w, _ := font.BoundText(l.text, 0)
+		width = max(width, float32(w))
+	}
+	lineHeight := float32(font.size + 2)
+	height := lineHeight * float32(len(lines))
+
+	const margin = 10
+	x := float32(margin)
+	if sm.Corner == StreamerOverlayUpperRight || sm.Corner == StreamerOverlayLowerRight {
+		x = paneExtent.Width() - width - margin
+	}
+	y := paneExtent.Height() - margin
+	if sm.Corner == StreamerOverlayLowerLeft || sm.Corner == StreamerOverlayLowerRight {
+		y = height + margin
+	}
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	for _, l := range lines {
+		td.AddText(l.text, [2]float32{x, y}, TextStyle{
+			Font:            font,
+			Color:           l.color,
+			DrawBackground:  true,
+			BackgroundColor: RGB{},
+		})
+		y -= lineHeight
+	}
+	td.GenerateCommands(cb)
+}
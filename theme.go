@@ -0,0 +1,98 @@
+// theme.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/mmp/imgui-go/v4"
+)
+
+// UITheme specifies the colors used for the imgui windows, dialogs, and
+// menus. It only affects that "chrome"; the scope and the other panes
+// have their own colors, so that, for example, the settings windows can
+// be made readable in a bright room while the scope stays dark.
+type UITheme struct {
+	Light bool
+	// Name of the accent color from uiAccentColors; the empty string is
+	// imgui's standard blue.
+	Accent string
+}
+
+var uiAccentColors = map[string]RGB{
+	"Blue":   RGBFromHex(0x4296FA),
+	"Green":  RGBFromHex(0x3FA34D),
+	"Orange": RGBFromHex(0xE0812B),
+	"Purple": RGBFromHex(0x8E5CD9),
+	"Red":    RGBFromHex(0xD64545),
+	"Teal":   RGBFromHex(0x2AA198),
+	"Gray":   RGBFromHex(0x8A8A8A),
+}
+
+// Activate sets the imgui style colors for the theme.
+func (t *UITheme) Activate() {
+	if t.Light {
+		imgui.StyleColorsLight()
+	} else {
+		imgui.StyleColorsDark()
+	}
+
+	accent, ok := uiAccentColors[t.Accent]
+	if !ok {
+		return
+	}
+
+	style := imgui.CurrentStyle()
+	set := func(id imgui.StyleColorID, alpha float32) {
+		style.SetColor(id, imgui.Vec4{X: accent.R, Y: accent.G, Z: accent.B, W: alpha})
+	}
+	// The alpha values follow the ones imgui uses for its own colors so
+	// that the accent blends with the backgrounds in the same way.
+	set(imgui.StyleColorCheckMark, 1)
+	set(imgui.StyleColorSliderGrab, 0.78)
+	set(imgui.StyleColorSliderGrabActive, 1)
+	set(imgui.StyleColorButton, 0.4)
+	set(imgui.StyleColorButtonHovered, 1)
+	set(imgui.StyleColorButtonActive, 1)
+	set(imgui.StyleColorHeader, 0.31)
+	set(imgui.StyleColorHeaderHovered, 0.8)
+	set(imgui.StyleColorHeaderActive, 1)
+	set(imgui.StyleColorFrameBgHovered, 0.4)
+	set(imgui.StyleColorFrameBgActive, 0.67)
+	set(imgui.StyleColorResizeGrip, 0.2)
+	set(imgui.StyleColorResizeGripHovered, 0.67)
+	set(imgui.StyleColorResizeGripActive, 0.95)
+	set(imgui.StyleColorTextSelectedBg, 0.35)
+}
+
+func (t *UITheme) DrawUI() {
+	changed := false
+
+	if imgui.BeginComboV(Tr("UI theme"), Tr(Select(t.Light, "Light", "Dark")), 0) {
+		if imgui.SelectableV(Tr("Dark"), !t.Light, 0, imgui.Vec2{}) {
+			t.Light, changed = false, true
+		}
+		if imgui.SelectableV(Tr("Light"), t.Light, 0, imgui.Vec2{}) {
+			t.Light, changed = true, true
+		}
+		imgui.EndCombo()
+	}
+
+	accent := Select(t.Accent == "", Tr("Default"), Tr(t.Accent))
+	if imgui.BeginComboV(Tr("Accent color"), accent, imgui.ComboFlagsHeightLarge) {
+		if imgui.SelectableV(Tr("Default"), t.Accent == "", 0, imgui.Vec2{}) {
+			t.Accent, changed = "", true
+		}
+		for _, name := range SortedMapKeys(uiAccentColors) {
+			if imgui.SelectableV(Tr(name), t.Accent == name, 0, imgui.Vec2{}) {
+				t.Accent, changed = name, true
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.Text(Tr("The UI theme doesn't affect the scope's colors."))
+
+	if changed {
+		t.Activate()
+	}
+}
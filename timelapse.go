@@ -0,0 +1,225 @@
+// timelapse.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+	"github.com/nfnt/resize"
+)
+
+// TimelapseRecorder captures images of the STARS scope at a fixed
+// interval of simulation time while a session is running and then writes
+// them out as an animated GIF.
+type TimelapseRecorder struct {
+	SimSecondsPerFrame int32
+	FramesPerSecond    int32   // playback rate
+	Scale              float32 // resolution, relative to the scope's size
+	Labels             bool    // draw the time and callsign on the scope
+
+	recording   bool
+	lastCapture time.Time // sim time
+	nFrames     int
+
+	// Captured images are downscaled and quantized by a separate
+	// goroutine; the finished frames are returned via done when frameCh
+	// is closed. frameBytes tracks the memory used by the finished frames.
+	frameCh    chan *image.RGBA
+	done       chan []*image.Paletted
+	frameBytes atomic.Int64
+
+	dirDialog *FileSelectDialogBox
+
+	mu     sync.Mutex
+	status string
+}
+
+// Recording stops automatically once the frames use this much memory;
+// the GIF encoder needs all of them at once, so they can't be streamed to
+// disk.
+const TimelapseMaxFrameBytes = 512 * 1024 * 1024
+
+func (t *TimelapseRecorder) setDefaults() {
+	if t.SimSecondsPerFrame == 0 {
+		t.SimSecondsPerFrame = 10
+	}
+	if t.FramesPerSecond == 0 {
+		t.FramesPerSecond = 10
+	}
+	if t.Scale == 0 {
+		t.Scale = 0.5
+	}
+}
+
+func (t *TimelapseRecorder) DrawUI() {
+	t.setDefaults()
+
+	uiStartDisable(t.recording)
+	imgui.SliderIntV("Simulation seconds per frame", &t.SimSecondsPerFrame, 1, 120, "%d", 0)
+	imgui.SliderIntV("Playback frames per second", &t.FramesPerSecond, 1, 50, "%d", 0)
+	imgui.SliderFloatV("Resolution scale", &t.Scale, 0.1, 1, "%.2f", 0)
+	imgui.Checkbox("Show time and callsign", &t.Labels)
+	uiEndDisable(t.recording)
+
+	if !t.recording {
+		if imgui.Button("Start recording") {
+			t.start()
+		}
+	} else {
+		imgui.Text(fmt.Sprintf("Recording: %d frames (%.1fs of video, %d of %d MB)", t.nFrames,
+			float32(t.nFrames)/float32(t.FramesPerSecond), t.frameBytes.Load()/(1024*1024),
+			TimelapseMaxFrameBytes/(1024*1024)))
+		if imgui.Button("Stop and save") {
+			t.dirDialog = NewDirectorySelectDialogBox("Save timelapse to...", "",
+				func(dir string) { t.stop(dir) })
+			t.dirDialog.Activate()
+		}
+		imgui.SameLine()
+		if imgui.Button("Discard") {
+			t.stop("")
+		}
+	}
+
+	t.mu.Lock()
+	if t.status != "" {
+		imgui.Text(t.status)
+	}
+	t.mu.Unlock()
+
+	if t.dirDialog != nil {
+		t.dirDialog.Draw()
+	}
+}
+
+func (t *TimelapseRecorder) start() {
+	t.recording = true
+	t.nFrames = 0
+	t.lastCapture = time.Time{}
+	t.frameCh = make(chan *image.RGBA, 16)
+	t.done = make(chan []*image.Paletted, 1)
+	t.frameBytes.Store(0)
+
+	scale := t.Scale
+	go func(frameCh chan *image.RGBA, done chan []*image.Paletted) {
+		var frames []*image.Paletted
+		for img := range frameCh {
+			if t.frameBytes.Load() >= TimelapseMaxFrameBytes {
+				// Frames that were queued before the recording was stopped.
+				continue
+			}
+			b := img.Bounds()
+			small := resize.Resize(uint(float32(b.Dx())*scale), 0, img, resize.Bilinear)
+			pal := image.NewPaletted(small.Bounds(), palette.Plan9)
+			draw.Draw(pal, pal.Bounds(), small, small.Bounds().Min, draw.Src)
+			frames = append(frames, pal)
+			t.frameBytes.Add(int64(len(pal.Pix)))
+		}
+		done <- frames
+	}(t.frameCh, t.done)
+
+	t.setStatus("")
+}
+
+// stop ends the recording and saves the GIF in the given directory; if
+// it is empty, the frames are discarded.
+func (t *TimelapseRecorder) stop(dir string) {
+	if !t.recording {
+		return
+	}
+	t.recording = false
+	close(t.frameCh)
+
+	delay := int(100 / t.FramesPerSecond) // hundredths of a second
+	go func(done chan []*image.Paletted) {
+		frames := <-done
+		if dir == "" || len(frames) == 0 {
+			return
+		}
+
+		fn := path.Join(dir, "vice-timelapse-"+time.Now().Format("20060102-150405")+".gif")
+		anim := gif.GIF{Image: frames, Delay: make([]int, len(frames))}
+		for i := range anim.Delay {
+			anim.Delay[i] = delay
+		}
+
+		f, err := os.Create(fn)
+		if err == nil {
+			err = gif.EncodeAll(f, &anim)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			lg.Errorf("%s: %v", fn, err)
+			t.setStatus("Error saving timelapse: " + err.Error())
+		} else {
+			lg.Infof("%s: saved timelapse with %d frames", fn, len(frames))
+			t.setStatus("Saved " + fn)
+		}
+	}(t.done)
+}
+
+func (t *TimelapseRecorder) setStatus(s string) {
+	t.mu.Lock()
+	t.status = s
+	t.mu.Unlock()
+}
+
+// Capture should be called after the panes have been rendered but before
+// the UI is drawn. While recording, it draws the label on the scope (so
+// that it appears consistently, not just in captured frames) and captures
+// a frame if enough simulation time has passed since the last one.
+func (t *TimelapseRecorder) Capture(p Platform, r Renderer, w *World) {
+	if !t.recording {
+		return
+	}
+	extent, ok := starsPaneExtent()
+	if !ok {
+		return
+	}
+
+	now := w.CurrentTime()
+	if t.Labels {
+		cb := GetCommandBuffer()
+		defer ReturnCommandBuffer(cb)
+		td := GetTextDrawBuilder()
+		defer ReturnTextDrawBuilder(td)
+
+		ctx := PaneContext{paneExtent: extent}
+		cb.SetDrawBounds(extent)
+		ctx.SetWindowCoordinateMatrices(cb)
+		label := now.UTC().Format("15:04:05Z") + " " + w.Callsign
+		td.AddText(label, [2]float32{10, extent.Height() - 10},
+			TextStyle{Font: GetDefaultFont(), Color: RGB{1, 1, 1}, DrawBackground: true})
+		td.GenerateCommands(cb)
+		cb.ResetState()
+		r.RenderCommandBuffer(cb)
+	}
+
+	if !t.lastCapture.IsZero() && now.Sub(t.lastCapture) < time.Duration(t.SimSecondsPerFrame)*time.Second {
+		return
+	}
+	t.lastCapture = now
+
+	if len(t.frameCh) == cap(t.frameCh) {
+		// The conversion goroutine has fallen behind; skip this frame.
+		return
+	}
+	t.frameCh <- readPanePixels(p, r, extent)
+	t.nFrames++
+	if t.frameBytes.Load() >= TimelapseMaxFrameBytes {
+		t.stop(defaultDirectory(""))
+	}
+}
@@ -0,0 +1,269 @@
+// timeline.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+///////////////////////////////////////////////////////////////////////////
+// TimelinePane
+
+// TimelinePane shows the projected runway threshold crossing times of
+// arrivals to a set of selected runways, one timeline per runway, in the
+// spirit of a (much) simplified TBFM timeline. Arrivals to converging
+// runways that are predicted to cross their thresholds within the
+// dependency window of each other are highlighted.
+type TimelinePane struct {
+	FontIdentifier FontIdentifier
+	font           *Font
+
+	// Keyed by airport ICAO + " " + runway
+	SelectedRunways map[string]bool
+	// The runways in the current scenario that may be selected, sorted.
+	availableRunways []string
+	// Maximum time in the future that is shown, in minutes.
+	Horizon int
+	// Converging arrivals whose threshold crossings are closer than this
+	// many seconds apart are highlighted.
+	DependencyWindow int
+}
+
+type timelineArrival struct {
+	callsign string
+	airport  string
+	runway   string
+	eta      time.Duration
+	conflict bool
+}
+
+func NewTimelinePane() *TimelinePane {
+	return &TimelinePane{
+		FontIdentifier:   FontIdentifier{Name: "Inconsolata Condensed Regular", Size: 16},
+		SelectedRunways:  make(map[string]bool),
+		Horizon:          20,
+		DependencyWindow: 60,
+	}
+}
+
+func (tp *TimelinePane) Name() string { return "Converging Timeline" }
+
+func (tp *TimelinePane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if tp.font = GetFont(tp.FontIdentifier); tp.font == nil {
+		tp.font = GetDefaultFont()
+		tp.FontIdentifier = tp.font.id
+	}
+	if tp.SelectedRunways == nil {
+		tp.SelectedRunways = make(map[string]bool)
+	}
+	if tp.Horizon == 0 {
+		tp.Horizon = 20
+	}
+	if tp.DependencyWindow == 0 {
+		tp.DependencyWindow = 60
+	}
+	if w != nil {
+		tp.updateAvailableRunways(w)
+	}
+}
+
+func (tp *TimelinePane) Deactivate()                {}
+func (tp *TimelinePane) CanTakeKeyboardFocus() bool { return false }
+
+func (tp *TimelinePane) ResetWorld(w *World) {
+	tp.updateAvailableRunways(w)
+}
+
+func (tp *TimelinePane) updateAvailableRunways(w *World) {
+	// Offer the runways that are part of converging runway pairs and all
+	// of the active arrival runways. Previous selections are kept; runways
+	// that haven't been seen before start out selected if they're part of
+	// a converging pair.
+	tp.availableRunways = nil
+	add := func(icao, rwy string, selected bool) {
+		key := icao + " " + rwy
+		if !slices.Contains(tp.availableRunways, key) {
+			tp.availableRunways = append(tp.availableRunways, key)
+		}
+		if _, ok := tp.SelectedRunways[key]; !ok {
+			tp.SelectedRunways[key] = selected
+		}
+	}
+	for icao, ap := range w.Airports {
+		for _, pair := range ap.ConvergingRunways {
+			add(icao, pair.Runways[0], true)
+			add(icao, pair.Runways[1], true)
+		}
+	}
+	for icao, ap := range w.RunwayConfiguration().Airports {
+		for _, rwy := range ap.ArrivalRunways {
+			add(icao, rwy, false)
+		}
+	}
+	slices.Sort(tp.availableRunways)
+}
+
+func (tp *TimelinePane) DrawUI() {
+	if newFont, changed := DrawFontPicker(&tp.FontIdentifier, "Font"); changed {
+		tp.font = newFont
+	}
+	horizon := int32(tp.Horizon)
+	imgui.SliderInt("Time horizon (minutes)", &horizon, 5, 60)
+	tp.Horizon = int(horizon)
+	window := int32(tp.DependencyWindow)
+	imgui.SliderInt("Dependency window (seconds)", &window, 0, 180)
+	tp.DependencyWindow = int(window)
+
+	imgui.Text("Runways")
+	for _, rwy := range tp.availableRunways {
+		sel := tp.SelectedRunways[rwy]
+		if imgui.Checkbox(rwy, &sel) {
+			tp.SelectedRunways[rwy] = sel
+		}
+	}
+}
+
+// runways returns the selected runways in sorted order.
+func (tp *TimelinePane) runways() []string {
+	var rwys []string
+	for _, rwy := range tp.availableRunways {
+		if tp.SelectedRunways[rwy] {
+			rwys = append(rwys, rwy)
+		}
+	}
+	return rwys
+}
+
+// getArrivals returns the arrivals to the selected runways that are
+// predicted to reach the runway threshold within the time horizon.
+func (tp *TimelinePane) getArrivals(w *World) []timelineArrival {
+	var arrivals []timelineArrival
+	for _, ac := range w.Aircraft {
+		appr := ac.Nav.Approach.Assigned
+		if appr == nil || ac.FlightPlan == nil || !ac.IsAirborne() || ac.GS() < 1 {
+			continue
+		}
+		airport := ac.FlightPlan.ArrivalAirport
+		if !tp.SelectedRunways[airport+" "+appr.Runway] {
+			continue
+		}
+
+		rwy, ok := LookupRunway(airport, appr.Runway)
+		if !ok {
+			continue
+		}
+
+		// Straight-line distance over current groundspeed; crude, but
+		// good enough once aircraft are being vectored for the approach.
+		hours := nmdistance2ll(ac.Position(), rwy.Threshold) / ac.GS()
+		eta := time.Duration(hours * float32(time.Hour))
+		if eta > time.Duration(tp.Horizon)*time.Minute {
+			continue
+		}
+
+		arrivals = append(arrivals, timelineArrival{
+			callsign: ac.Callsign,
+			airport:  airport,
+			runway:   appr.Runway,
+			eta:      eta,
+		})
+	}
+
+	// Flag arrivals to the two runways of a converging pair that are
+	// predicted to be within the dependency window of each other.
+	window := time.Duration(tp.DependencyWindow) * time.Second
+	for i := range arrivals {
+		for j := i + 1; j < len(arrivals); j++ {
+			a, b := &arrivals[i], &arrivals[j]
+			if a.airport != b.airport || a.runway == b.runway {
+				continue
+			}
+			ap := w.GetAirport(a.airport)
+			if ap == nil || !slices.ContainsFunc(ap.ConvergingRunways, func(c ConvergingRunways) bool {
+				return (c.Runways[0] == a.runway && c.Runways[1] == b.runway) ||
+					(c.Runways[0] == b.runway && c.Runways[1] == a.runway)
+			}) {
+				continue
+			}
+			if abs(a.eta-b.eta) < window {
+				a.conflict, b.conflict = true, true
+			}
+		}
+	}
+
+	return arrivals
+}
+
+func (tp *TimelinePane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	if ctx.world == nil {
+		return
+	}
+
+	rwys := tp.runways()
+	if len(rwys) == 0 {
+		return
+	}
+	arrivals := tp.getArrivals(ctx.world)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	textStyle := TextStyle{Font: tp.font, Color: UITextColor}
+	conflictStyle := TextStyle{Font: tp.font, Color: UIErrorColor}
+
+	// The bottom of the pane is now and the top is the horizon; the
+	// runway identifiers are drawn across the top.
+	lineHeight := float32(tp.font.size + 1)
+	bx, _ := tp.font.BoundText("X", 0)
+	fw := float32(bx)
+	height := ctx.paneExtent.Height()
+	y0, y1 := lineHeight/2, height-2*lineHeight
+	if y1 <= y0 {
+		return
+	}
+	horizon := time.Duration(tp.Horizon) * time.Minute
+	yForETA := func(eta time.Duration) float32 {
+		return lerp(float32(eta)/float32(horizon), y0, y1)
+	}
+
+	// Minute ticks along the left side.
+	labelWidth := 3 * fw
+	for m := 0; m <= tp.Horizon; m++ {
+		y := yForETA(time.Duration(m) * time.Minute)
+		ld.AddLine([2]float32{labelWidth, y}, [2]float32{ctx.paneExtent.Width(), y}, UIControlColor)
+		if m%5 == 0 {
+			td.AddText(fmt.Sprintf("%2d", m), [2]float32{0, y + lineHeight/2}, textStyle)
+		}
+	}
+
+	columnWidth := (ctx.paneExtent.Width() - labelWidth) / float32(len(rwys))
+	for i, rwy := range rwys {
+		x := labelWidth + float32(i)*columnWidth
+		td.AddText(rwy, [2]float32{x + fw, height - 1}, textStyle)
+		ld.AddLine([2]float32{x + fw/2, y0}, [2]float32{x + fw/2, y1}, UITextColor)
+
+		for _, arr := range arrivals {
+			if arr.airport+" "+arr.runway != rwy {
+				continue
+			}
+			y := yForETA(arr.eta)
+			style := Select(arr.conflict, conflictStyle, textStyle)
+			ld.AddLine([2]float32{x, y}, [2]float32{x + fw, y}, style.Color)
+			secs := int(arr.eta.Seconds())
+			label := fmt.Sprintf("%s %d:%02d", arr.callsign, secs/60, secs%60)
+			td.AddText(label, [2]float32{x + 1.5*fw, y + lineHeight/2}, style)
+		}
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	ld.GenerateCommands(cb)
+	td.GenerateCommands(cb)
+}
@@ -0,0 +1,94 @@
+// touch.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"time"
+)
+
+// GLFW doesn't provide touch events, but the operating systems translate
+// touch input into mouse events for applications that don't handle touch
+// themselves: a one-finger touch is the primary mouse button, and on
+// Windows, two-finger pans are delivered as mouse wheel events and pinches
+// as mouse wheel events with Control held. TouchGestures recognizes
+// gestures from those so that panes can respond to them when
+// globalConfig.TouchMode is enabled.
+
+const (
+	// A touch that's held in place at least this long is a long-press.
+	TouchLongPressDuration = 600 * time.Millisecond
+	// Touches that move more than this many pixels are drags, not presses.
+	TouchLongPressSlop = 8
+	// Scale factor for hit-testing distances in touch mode, since fingers
+	// are less precise than a mouse pointer.
+	TouchHitTargetScale = 2.5
+	// Number of pixels to pan per unit of mouse wheel movement.
+	TouchWheelPanScale = 20
+)
+
+type TouchGestures struct {
+	pressStart time.Time
+	pressPos   [2]float32
+	longPress  bool // whether the current press has already been reported as a long-press
+}
+
+// TouchGesture describes the gestures recognized in a frame.
+type TouchGesture struct {
+	// Pan gives the offset in window coordinates that the contents of the
+	// pane should move by.
+	Pan [2]float32
+	// Zoom is positive when the user is zooming in and negative when
+	// zooming out.
+	Zoom float32
+	// LongPress is set for the frame in which a long-press is recognized.
+	LongPress    bool
+	LongPressPos [2]float32
+}
+
+// Update recognizes gestures from the mouse and keyboard state for the
+// current frame.
+func (tg *TouchGestures) Update(mouse *MouseState, keyboard *KeyboardState) (g TouchGesture) {
+	if mouse == nil {
+		return
+	}
+
+	if mouse.Clicked[MouseButtonPrimary] {
+		tg.pressStart, tg.pressPos, tg.longPress = time.Now(), mouse.Pos, false
+	}
+
+	if mouse.Down[MouseButtonPrimary] && !tg.pressStart.IsZero() {
+		if distance2f(mouse.Pos, tg.pressPos) > TouchLongPressSlop {
+			// It's a drag; one-finger drags pan.
+			tg.pressStart = time.Time{}
+		} else if !tg.longPress && time.Since(tg.pressStart) > TouchLongPressDuration {
+			tg.longPress = true
+			g.LongPress, g.LongPressPos = true, tg.pressPos
+		}
+	}
+	if mouse.Dragging[MouseButtonPrimary] && tg.pressStart.IsZero() {
+		g.Pan = mouse.DragDelta
+	}
+	if mouse.Released[MouseButtonPrimary] {
+		tg.pressStart = time.Time{}
+	}
+
+	if keyboard != nil && keyboard.IsPressed(KeyControl) {
+		g.Zoom = mouse.Wheel[1]
+	} else {
+		g.Pan = add2f(g.Pan, scale2f([2]float32{-mouse.Wheel[0], mouse.Wheel[1]}, TouchWheelPanScale))
+	}
+	return
+}
+
+// HitTestDistance returns the given distance in pixels for hit-testing,
+// scaled for the display's DPI and scaled up further if touch mode is
+// enabled.
+func HitTestDistance(d float32) float32 {
+	d *= UIScale(platform)
+	if globalConfig.TouchMode {
+		return d * TouchHitTargetScale
+	}
+	return d
+}
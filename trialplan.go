@@ -0,0 +1,232 @@
+// trialplan.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A trial plan is a hypothetical altitude and/or route for an aircraft
+// that is probed for conflicts before the clearance is issued: the
+// aircraft's trajectory under the trial plan is predicted along with
+// those of the other aircraft and the points where separation would be
+// lost are shown. It's entered by slewing an aircraft with "TP" followed
+// by an altitude in hundreds of feet and/or fixes to proceed direct to,
+// e.g., "TP120 CAMRN"; "TP" alone removes it.
+
+type STARSTrialPlan struct {
+	Callsign string
+	Altitude int      // 0 -> current altitude
+	Fixes    []string // route, if non-empty
+	Route    []Point2LL
+
+	// Updated each frame
+	Trajectory []STARSTrajectoryPoint
+	Conflicts  []STARSTrialPlanConflict
+}
+
+type STARSTrajectoryPoint struct {
+	Position Point2LL
+	Altitude float32
+}
+
+type STARSTrialPlanConflict struct {
+	Callsign string
+	Time     time.Duration // from now
+	Position Point2LL      // of the trial plan aircraft
+}
+
+const (
+	// How far ahead trajectories are predicted and the time between
+	// their points.
+	STARSTrialPlanHorizon  = 10 * time.Minute
+	STARSTrialPlanTimeStep = 10 * time.Second
+	// Assumed climb and descent rate for the trial plan aircraft.
+	STARSTrialPlanVerticalRate = 1500 // fpm
+)
+
+// parseTrialPlan parses the text entered after "TP".
+func parseTrialPlan(w *World, ac *Aircraft, args string) (*STARSTrialPlan, error) {
+	tp := &STARSTrialPlan{Callsign: ac.Callsign}
+	for i, f := range strings.Fields(args) {
+		if i == 0 && len(f) <= 3 && strings.IndexFunc(f, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+			alt, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, ErrSTARSCommandFormat
+			}
+			tp.Altitude = 100 * alt
+		} else if p, ok := w.Locate(f); ok {
+			tp.Fixes = append(tp.Fixes, f)
+			tp.Route = append(tp.Route, p)
+		} else {
+			return nil, ErrSTARSIllegalFix
+		}
+	}
+	if tp.Altitude == 0 && len(tp.Route) == 0 {
+		return nil, ErrSTARSCommandFormat
+	}
+	return tp, nil
+}
+
+// predictTrajectory extrapolates an aircraft's track: it flies the given
+// route (if any) at its groundspeed, or otherwise continues with its
+// current ground velocity, and climbs or descends toward the target
+// altitude at the given rate.
+func predictTrajectory(state *STARSAircraftState, route []Point2LL, targetAlt, fpm float32,
+	nmPerLongitude float32) []STARSTrajectoryPoint {
+	v, ok := state.GroundVelocity(nmPerLongitude)
+	if !ok {
+		return nil
+	}
+	gs := length2f(v)
+
+	p := ll2nm(state.TrackPosition(), nmPerLongitude)
+	alt := float32(state.TrackAltitude())
+	dt := float32(STARSTrialPlanTimeStep.Hours())
+	dalt := fpm * float32(STARSTrialPlanTimeStep.Minutes())
+
+	var traj []STARSTrajectoryPoint
+	for t := time.Duration(0); t <= STARSTrialPlanHorizon; t += STARSTrialPlanTimeStep {
+		traj = append(traj, STARSTrajectoryPoint{Position: nm2ll(p, nmPerLongitude), Altitude: alt})
+
+		// Lateral: fly to the next point of the route, if there is one.
+		dist := gs * dt
+		for len(route) > 0 && dist > 0 {
+			wp := ll2nm(route[0], nmPerLongitude)
+			d := distance2f(p, wp)
+			if d > dist {
+				v = scale2f(normalize2f(sub2f(wp, p)), gs)
+				break
+			}
+			// Reached the fix; keep going in the same direction after
+			// the last one.
+			if d > 0 {
+				v = scale2f(normalize2f(sub2f(wp, p)), gs)
+			}
+			p, dist = wp, dist-d
+			route = route[1:]
+		}
+		p = add2f(p, scale2f(v, dist/max(gs, 1)))
+
+		// Vertical
+		if alt < targetAlt {
+			alt = min(alt+dalt, targetAlt)
+		} else if alt > targetAlt {
+			alt = max(alt-dalt, targetAlt)
+		}
+	}
+	return traj
+}
+
+// otherTrajectory predicts the trajectory of an aircraft that isn't the
+// one the trial plan is for, assuming it continues with its current
+// ground velocity and vertical rate, leveling at its temporary altitude
+// if one has been entered.
+func otherTrajectory(ac *Aircraft, state *STARSAircraftState, nmPerLongitude float32) []STARSTrajectoryPoint {
+	fpm, ok := state.TrackVerticalRate()
+	if !ok || abs(fpm) < STARSLevelVerticalRate {
+		fpm = 0
+	}
+	target := float32(state.TrackAltitude())
+	if ac.TempAltitude != 0 {
+		target = float32(ac.TempAltitude)
+	} else if fpm > 0 {
+		target = 60000
+	} else if fpm < 0 {
+		target = 0
+	}
+	return predictTrajectory(state, nil, target, float32(abs(fpm)), nmPerLongitude)
+}
+
+// updateTrialPlan re-probes the trial plan using the aircraft's current
+// tracks.
+func (sp *STARSPane) updateTrialPlan(aircraft []*Aircraft, w *World) {
+	tp := sp.trialPlan
+	if tp == nil {
+		return
+	}
+	if _, ok := w.Aircraft[tp.Callsign]; !ok {
+		sp.trialPlan = nil
+		return
+	}
+	state := sp.Aircraft[tp.Callsign]
+
+	alt := Select(tp.Altitude != 0, float32(tp.Altitude), float32(state.TrackAltitude()))
+	tp.Trajectory = predictTrajectory(state, tp.Route, alt, STARSTrialPlanVerticalRate, w.NmPerLongitude)
+	tp.Conflicts = nil
+
+	for _, other := range aircraft {
+		if other.Callsign == tp.Callsign {
+			continue
+		}
+		traj := otherTrajectory(other, sp.Aircraft[other.Callsign], w.NmPerLongitude)
+		for i := 0; i < min(len(traj), len(tp.Trajectory)); i++ {
+			a, b := tp.Trajectory[i], traj[i]
+			if nmdistance2ll(a.Position, b.Position) < LateralMinimum &&
+				abs(a.Altitude-b.Altitude) < VerticalMinimum-5 {
+				tp.Conflicts = append(tp.Conflicts, STARSTrialPlanConflict{
+					Callsign: other.Callsign,
+					Time:     time.Duration(i) * STARSTrialPlanTimeStep,
+					Position: a.Position,
+				})
+				break
+			}
+		}
+	}
+}
+
+// output returns the text summarizing the trial plan's probe
+// results for the preview area.
+func (tp *STARSTrialPlan) output() string {
+	s := "TP " + tp.Callsign
+	if tp.Altitude != 0 {
+		s += fmt.Sprintf(" %03d", tp.Altitude/100)
+	}
+	if len(tp.Fixes) > 0 {
+		s += " " + strings.Join(tp.Fixes, " ")
+	}
+	if len(tp.Conflicts) == 0 {
+		return s + "\nNO CONFLICTS"
+	}
+	for _, c := range tp.Conflicts {
+		s += fmt.Sprintf("\nCONFLICT %s %.1f MIN", c.Callsign, c.Time.Minutes())
+	}
+	return s
+}
+
+func (sp *STARSPane) drawTrialPlan(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	tp := sp.trialPlan
+	if tp == nil || len(tp.Trajectory) == 0 {
+		return
+	}
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSATPAWarningColor)
+	alertColor := ps.Brightness.Lines.ScaleRGB(STARSTextAlertColor)
+
+	for i := 1; i < len(tp.Trajectory); i++ {
+		ld.AddLine(tp.Trajectory[i-1].Position, tp.Trajectory[i].Position, color)
+	}
+
+	// Draw a circle with the lateral separation minimum's radius where
+	// each conflict would happen and connect it to the other aircraft.
+	for _, c := range tp.Conflicts {
+		ld.AddLatLongCircle(c.Position, ctx.world.NmPerLongitude, LateralMinimum, 360, alertColor)
+		if state, ok := sp.Aircraft[c.Callsign]; ok {
+			ld.AddLine(c.Position, state.TrackPosition(), alertColor)
+		}
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+}
@@ -35,7 +35,13 @@ var (
 
 		menuBarHeight float32
 
-		showAboutDialog bool
+		showAboutDialog       bool
+		showPerformanceWindow bool
+		showProfiler          bool
+		showReliefBriefing    bool
+		reliefBriefing        string
+		showInstructorConsole bool
+		searchPalette         *SearchPalette
 
 		iconTextureID     uint32
 		sadTowerTextureID uint32
@@ -233,6 +239,7 @@ func uiInit(r Renderer, p Platform, es *EventStream) {
 	if runtime.GOOS == "windows" {
 		imgui.CurrentStyle().ScaleAllSizes(p.DPIScale())
 	}
+	globalConfig.UITheme.Activate()
 
 	ui.font = GetFont(FontIdentifier{Name: "Roboto Regular", Size: globalConfig.UIFontSize})
 	ui.aboutFont = GetFont(FontIdentifier{Name: "Roboto Regular", Size: 18})
@@ -329,14 +336,14 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 					w.ToggleSimPause()
 				}
 				if imgui.IsItemHovered() {
-					imgui.SetTooltip("Resume simulation")
+					imgui.SetTooltip(Tr("Resume simulation"))
 				}
 			} else {
 				if imgui.Button(FontAwesomeIconPauseCircle) {
 					w.ToggleSimPause()
 				}
 				if imgui.IsItemHovered() {
-					imgui.SetTooltip("Pause simulation")
+					imgui.SetTooltip(Tr("Pause simulation"))
 				}
 			}
 		}
@@ -345,7 +352,7 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			uiShowConnectDialog(true)
 		}
 		if imgui.IsItemHovered() {
-			imgui.SetTooltip("Start new simulation")
+			imgui.SetTooltip(Tr("Start new simulation"))
 		}
 
 		if w != nil && w.Connected() {
@@ -353,14 +360,30 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 				w.ToggleActivateSettingsWindow()
 			}
 			if imgui.IsItemHovered() {
-				imgui.SetTooltip("Open settings window")
+				imgui.SetTooltip(Tr("Open settings window"))
 			}
 
 			if imgui.Button(FontAwesomeIconQuestionCircle) {
 				w.ToggleShowScenarioInfoWindow()
 			}
 			if imgui.IsItemHovered() {
-				imgui.SetTooltip("Show available departures, arrivals, and approaches")
+				imgui.SetTooltip(Tr("Show available departures, arrivals, and approaches"))
+			}
+
+			if imgui.Button(FontAwesomeIconClipboardList) {
+				ui.showReliefBriefing = !ui.showReliefBriefing
+			}
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip(Tr("Show position relief briefing"))
+			}
+
+			if w.LaunchConfig.Controller == w.Callsign {
+				if imgui.Button(FontAwesomeIconChalkboardTeacher) {
+					ui.showInstructorConsole = !ui.showInstructorConsole
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip(Tr("Show instructor console"))
+				}
 			}
 		}
 
@@ -368,7 +391,7 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			uiToggleShowKeyboardWindow()
 		}
 		if imgui.IsItemHovered() {
-			imgui.SetTooltip("Show summary of keyboard commands")
+			imgui.SetTooltip(Tr("Show summary of keyboard commands"))
 		}
 
 		enableLaunch := w != nil &&
@@ -378,10 +401,10 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			w.TakeOrReturnLaunchControl(eventStream)
 		}
 		if imgui.IsItemHovered() {
-			verb := Select(w.LaunchConfig.Controller == "", "Start", "Stop")
-			tip := verb + " manually control spawning new aircraft"
+			tip := Tr(Select(w.LaunchConfig.Controller == "", "Start manually control spawning new aircraft",
+				"Stop manually control spawning new aircraft"))
 			if w.LaunchConfig.Controller != "" {
-				tip += "\nCurrent controller: " + w.LaunchConfig.Controller
+				tip += "\n" + Tr("Current controller:") + " " + w.LaunchConfig.Controller
 			}
 			imgui.SetTooltip(tip)
 		}
@@ -391,7 +414,14 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			browser.OpenURL("https://pharr.org/vice/index.html")
 		}
 		if imgui.IsItemHovered() {
-			imgui.SetTooltip("Display online vice documentation")
+			imgui.SetTooltip(Tr("Display online vice documentation"))
+		}
+
+		if imgui.Button(FontAwesomeIconBug) {
+			ui.showPerformanceWindow = !ui.showPerformanceWindow
+		}
+		if imgui.IsItemHovered() {
+			imgui.SetTooltip(Tr("Show rendering performance statistics"))
 		}
 
 		width, _ := ui.font.BoundText(FontAwesomeIconInfoCircle, 0)
@@ -400,7 +430,7 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			ui.showAboutDialog = !ui.showAboutDialog
 		}
 		if imgui.IsItemHovered() {
-			imgui.SetTooltip("Display information about vice")
+			imgui.SetTooltip(Tr("Display information about vice"))
 		}
 		if imgui.Button(FontAwesomeIconDiscord) {
 			browser.OpenURL("https://discord.gg/y993vgQxhY")
@@ -410,7 +440,7 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			platform.EnableFullScreen(!platform.IsFullScreen())
 		}
 		if imgui.IsItemHovered() {
-			imgui.SetTooltip(Select(platform.IsFullScreen(), "Exit", "Enter") + " full-screen mode")
+			imgui.SetTooltip(Tr(Select(platform.IsFullScreen(), "Exit full-screen mode", "Enter full-screen mode")))
 		}
 
 		imgui.PopStyleColor()
@@ -420,7 +450,7 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 	ui.menuBarHeight = imgui.CursorPos().Y - 1
 
 	if w != nil {
-		w.DrawSettingsWindow()
+		w.DrawSettingsWindow(r, eventStream)
 
 		w.DrawScenarioInfoWindow()
 
@@ -431,6 +461,22 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 				w.launchControlWindow = MakeLaunchControlWindow(w)
 			}
 			w.launchControlWindow.Draw(w, eventStream)
+
+			if ui.showInstructorConsole {
+				if w.instructorConsole == nil {
+					w.instructorConsole = &InstructorConsole{}
+				}
+				w.instructorConsole.Draw(w, &ui.showInstructorConsole)
+			}
+		}
+	}
+
+	if w != nil {
+		if io := imgui.CurrentIO(); io.KeyCtrlPressed() && imgui.IsKeyPressed(imguiKeyP) {
+			ui.searchPalette = Select(ui.searchPalette == nil, NewSearchPalette(eventStream), nil)
+		}
+		if ui.searchPalette != nil && !ui.searchPalette.Draw(w, p) {
+			ui.searchPalette = nil
 		}
 	}
 
@@ -446,6 +492,16 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 
 	uiDrawKeyboardWindow(w)
 
+	if ui.showPerformanceWindow {
+		drawPerformanceWindow(stats)
+	}
+	if profilerEnabled() {
+		profiler.Draw()
+	}
+	if ui.showReliefBriefing {
+		drawReliefBriefingWindow(w, p)
+	}
+
 	imgui.PopFont()
 
 	// Finalize and submit the imgui draw lists
@@ -472,6 +528,36 @@ func drawActiveDialogBoxes() {
 	}
 }
 
+// drawPerformanceWindow shows a breakdown of where time was spent in the
+// previous frame along with statistics about the rendering work done.
+func drawPerformanceWindow(stats *Stats) {
+	imgui.BeginV("Performance", &ui.showPerformanceWindow, imgui.WindowFlagsAlwaysAutoResize)
+
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	total := stats.update + stats.drawPanes + stats.drawImgui
+	imgui.Text(fmt.Sprintf("Frame: %.2f ms (%.1f fps)", ms(total), 1/max(total.Seconds(), 1e-6)))
+	imgui.Text(fmt.Sprintf("  Update:      %6.2f ms", ms(stats.update)))
+	imgui.Text(fmt.Sprintf("  Draw panes:  %6.2f ms", ms(stats.drawPanes)))
+	imgui.Text(fmt.Sprintf("  Draw UI:     %6.2f ms", ms(stats.drawImgui)))
+
+	imgui.Separator()
+	imgui.Text("Panes: " + stats.render.String())
+	imgui.Text("UI:    " + stats.renderUI.String())
+
+	cs := &commandBufferCacheStats
+	imgui.Text(fmt.Sprintf("Cached command buffers: %d reused, %d regenerated", cs.hits, cs.misses))
+	if imgui.Button("Reset") {
+		cs.hits, cs.misses = 0, 0
+	}
+
+	if profiler != nil {
+		imgui.Separator()
+		imgui.Checkbox("Show profiler overlay", &ui.showProfiler)
+	}
+
+	imgui.End()
+}
+
 func setCursorForRightButtons(text []string) {
 	style := imgui.CurrentStyle()
 	width := float32(0)
@@ -656,7 +742,7 @@ func (m *ModalDialogBox) Draw() {
 		return
 	}
 
-	title := fmt.Sprintf("%s##%p", m.client.Title(), m)
+	title := fmt.Sprintf("%s##%p", Tr(m.client.Title()), m)
 	imgui.OpenPopup(title)
 
 	flags := imgui.WindowFlagsNoResize | imgui.WindowFlagsAlwaysAutoResize | imgui.WindowFlagsNoSavedSettings
@@ -677,7 +763,7 @@ func (m *ModalDialogBox) Draw() {
 		// https://github.com/ocornut/imgui/discussions/3862
 		var allButtonText []string
 		for _, b := range buttons {
-			allButtonText = append(allButtonText, b.text)
+			allButtonText = append(allButtonText, Tr(b.text))
 		}
 		setCursorForRightButtons(allButtonText)
 
@@ -686,7 +772,7 @@ func (m *ModalDialogBox) Draw() {
 			if i > 0 {
 				imgui.SameLine()
 			}
-			if (imgui.Button(b.text) || i == selIndex) && !b.disabled {
+			if (imgui.Button(Tr(b.text)) || i == selIndex) && !b.disabled {
 				if b.action == nil || b.action() {
 					imgui.CloseCurrentPopup()
 					m.closed = true
@@ -818,7 +904,108 @@ func (yn *YesOrNoModalClient) Buttons() []ModalDialogButton {
 }
 
 func (yn *YesOrNoModalClient) Draw() int {
-	imgui.Text(yn.query)
+	imgui.Text(Tr(yn.query))
+	return -1
+}
+
+// RouteAmendmentModalClient lets the user edit an aircraft's route; the
+// route is checked against the navdata as it is edited and its expansion
+// is drawn on the scope.
+type RouteAmendmentModalClient struct {
+	w        *World
+	callsign string
+
+	route      string
+	newElement string
+	err        error
+	expanded   []string
+}
+
+func (ra *RouteAmendmentModalClient) Title() string { return "Amend Route: " + ra.callsign }
+
+func (ra *RouteAmendmentModalClient) Opening() {
+	if ac := ra.w.GetAircraft(ra.callsign, false); ac != nil && ac.FlightPlan != nil {
+		ra.route = ac.FlightPlan.Route
+	}
+	ra.update()
+}
+
+// update validates the route and updates the preview that is drawn on
+// the scope.
+func (ra *RouteAmendmentModalClient) update() {
+	ra.route = strings.Join(strings.Fields(strings.ToUpper(ra.route)), " ")
+	ra.expanded, ra.err = database.ExpandRoute(ra.route)
+
+	globalConfig.previewRoute = nil
+	for _, fix := range ra.expanded {
+		if p, ok := ra.w.Locate(fix); ok {
+			globalConfig.previewRoute = append(globalConfig.previewRoute, p)
+		}
+	}
+}
+
+func (ra *RouteAmendmentModalClient) Buttons() []ModalDialogButton {
+	var b []ModalDialogButton
+	b = append(b, ModalDialogButton{text: "Cancel", action: func() bool {
+		globalConfig.previewRoute = nil
+		return true
+	}})
+	b = append(b, ModalDialogButton{text: "Amend", disabled: ra.err != nil || ra.route == "",
+		action: func() bool {
+			globalConfig.previewRoute = nil
+			if err := amendFlightPlan(ra.w, ra.callsign, func(fp *FlightPlan) { fp.Route = ra.route }); err != nil {
+				ra.err = err
+				return false
+			}
+			return true
+		}})
+	return b
+}
+
+func (ra *RouteAmendmentModalClient) Draw() int {
+	if imgui.InputTextV("Route", &ra.route, imgui.InputTextFlagsCharsUppercase, nil) {
+		ra.update()
+	}
+
+	// Edits to the elements are applied after they have all been drawn.
+	imgui.Text("Route elements")
+	elements := strings.Fields(ra.route)
+	var edited []string
+	for i, elt := range elements {
+		imgui.PushID(strconv.Itoa(i))
+		if imgui.Button("Delete") {
+			edited = DeleteSliceElement(DuplicateSlice(elements), i)
+		}
+		imgui.SameLine()
+		if imgui.Button("Insert before") && ra.newElement != "" {
+			edited = InsertSliceElement(DuplicateSlice(elements), i, ra.newElement)
+			ra.newElement = ""
+		}
+		imgui.SameLine()
+		imgui.Text(elt)
+		imgui.PopID()
+	}
+	if edited != nil {
+		ra.route = strings.Join(edited, " ")
+		ra.update()
+	}
+	imgui.InputTextV("##new", &ra.newElement, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCharsNoBlank, nil)
+	imgui.SameLine()
+	if imgui.Button("Append") && ra.newElement != "" {
+		ra.route = strings.TrimSpace(ra.route + " " + ra.newElement)
+		ra.newElement = ""
+		ra.update()
+	}
+
+	imgui.Text("\nExpanded route")
+	text, _ := wrapText(strings.Join(ra.expanded, " "), 60, 2, false)
+	imgui.Text(text)
+	if ra.err != nil {
+		imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+		imgui.Text(ra.err.Error())
+		imgui.PopStyleColor()
+	}
+
 	return -1
 }
 
@@ -1025,6 +1212,9 @@ func (d *DiscordOptInModalClient) Draw() int {
 	update := !globalConfig.InhibitDiscordActivity.Load()
 	imgui.Checkbox("Update Discord activity status", &update)
 	globalConfig.InhibitDiscordActivity.Store(!update)
+	private := globalConfig.PrivateDiscordActivity.Load()
+	imgui.Checkbox("Don't include my position, frequency, or traffic count", &private)
+	globalConfig.PrivateDiscordActivity.Store(private)
 
 	return -1
 }
@@ -1350,7 +1540,7 @@ func (m *MessageModalClient) Buttons() []ModalDialogButton {
 }
 
 func (m *MessageModalClient) Draw() int {
-	text, _ := wrapText(m.message, 80, 0, true)
+	text, _ := wrapText(Tr(m.message), 80, 0, true)
 	imgui.Text("\n\n" + text + "\n\n")
 	return -1
 }
@@ -1389,7 +1579,8 @@ func (e *ErrorModalClient) Draw() int {
 }
 
 func ShowErrorDialog(s string, args ...interface{}) {
-	d := NewModalDialogBox(&ErrorModalClient{message: fmt.Sprintf(s, args...)})
+	// The dialog is translated but the log isn't.
+	d := NewModalDialogBox(&ErrorModalClient{message: Trf(s, args...)})
 	uiShowModalDialog(d, true)
 
 	lg.Errorf(s, args...)
@@ -1736,14 +1927,14 @@ func (lc *LaunchControlWindow) Draw(w *World, eventStream *EventStream) {
 				lc.w.ToggleSimPause()
 			}
 			if imgui.IsItemHovered() {
-				imgui.SetTooltip("Resume simulation")
+				imgui.SetTooltip(Tr("Resume simulation"))
 			}
 		} else {
 			if imgui.Button(FontAwesomeIconPauseCircle) {
 				lc.w.ToggleSimPause()
 			}
 			if imgui.IsItemHovered() {
-				imgui.SetTooltip("Pause simulation")
+				imgui.SetTooltip(Tr("Pause simulation"))
 			}
 		}
 	}
@@ -1767,7 +1958,7 @@ func (lc *LaunchControlWindow) Draw(w *World, eventStream *EventStream) {
 		}), true)
 	}
 	if imgui.IsItemHovered() {
-		imgui.SetTooltip("Delete all aircraft and restart")
+		imgui.SetTooltip(Tr("Delete all aircraft and restart"))
 	}
 
 	imgui.Separator()
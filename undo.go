@@ -0,0 +1,105 @@
+// undo.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	// Maximum number of changes that can be undone.
+	UndoHistoryLength = 100
+	// A value must stay the same for this long before its change is
+	// recorded, so that continuous changes like dragging to pan or
+	// animated transitions are undone all at once.
+	UndoSettleTime = 500 * time.Millisecond
+)
+
+// UndoHistory records the history of a value that is edited interactively
+// so that changes to it can be undone and redone. Rather than requiring
+// each place that modifies the value to record the change, Update is
+// called each frame with the current value and changes are found by
+// comparing it to the last recorded one.
+type UndoHistory[T any] struct {
+	undo, redo []T
+	// Most recently recorded value.
+	current T
+	// Latest value if it differs from current but hasn't settled yet.
+	pending     *T
+	changed     time.Time
+	initialized bool
+	// Returns a deep copy of a value.
+	dup func(T) T
+}
+
+func NewUndoHistory[T any](dup func(T) T) *UndoHistory[T] {
+	return &UndoHistory[T]{dup: dup}
+}
+
+// Update should be called each frame with the value's current state.
+func (u *UndoHistory[T]) Update(v T) {
+	if !u.initialized {
+		u.current, u.initialized = u.dup(v), true
+		return
+	}
+
+	if reflect.DeepEqual(v, u.current) {
+		// Unchanged or changed back.
+		u.pending = nil
+	} else if u.pending == nil || !reflect.DeepEqual(v, *u.pending) {
+		// Still changing.
+		p := u.dup(v)
+		u.pending, u.changed = &p, time.Now()
+	} else if time.Since(u.changed) > UndoSettleTime {
+		u.record()
+	}
+}
+
+func (u *UndoHistory[T]) record() {
+	u.undo = append(u.undo, u.current)
+	if len(u.undo) > UndoHistoryLength {
+		u.undo = u.undo[1:]
+	}
+	u.current, u.pending = *u.pending, nil
+	u.redo = nil
+}
+
+// Undo returns the value before the most recent change, given the current
+// value. The returned bool indicates whether there was a change to undo.
+func (u *UndoHistory[T]) Undo(v T) (T, bool) {
+	if !u.initialized {
+		return v, false
+	}
+	if !reflect.DeepEqual(v, u.current) {
+		// Record the latest change, even if it hasn't settled, so that
+		// it's the one that's undone.
+		p := u.dup(v)
+		u.pending = &p
+		u.record()
+	}
+	if len(u.undo) == 0 {
+		return v, false
+	}
+
+	u.redo = append(u.redo, u.current)
+	u.current = u.undo[len(u.undo)-1]
+	u.undo = u.undo[:len(u.undo)-1]
+	return u.dup(u.current), true
+}
+
+// Redo returns the value from before the most recent Undo, given the
+// current value. The returned bool indicates whether there was a change to
+// redo; there isn't if the value has been changed since the last Undo.
+func (u *UndoHistory[T]) Redo(v T) (T, bool) {
+	if !u.initialized || len(u.redo) == 0 || !reflect.DeepEqual(v, u.current) {
+		return v, false
+	}
+
+	u.undo = append(u.undo, u.current)
+	u.current = u.redo[len(u.redo)-1]
+	u.redo = u.redo[:len(u.redo)-1]
+	return u.dup(u.current), true
+}
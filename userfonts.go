@@ -0,0 +1,339 @@
+// userfonts.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Users can add their own fonts by putting them in the fonts directory
+// next to the config file. TrueType and OpenType fonts (.ttf, .otf) are
+// rasterized at the standard font sizes (optionally limited to a range of
+// them); bitmap fonts in BDF format (.bdf), as are commonly used for ATC
+// display fonts, are used as-is at their native size.
+
+type UserFontsConfig struct {
+	// Range of sizes to rasterize TTF fonts at; 0 means no limit.
+	MinSize, MaxSize int
+	// Oversampling factor for rasterization; higher gives smoother
+	// glyphs at the cost of memory. 0 uses imgui's default.
+	Oversample int
+	// Snap glyphs to integer pixel positions for sharper text.
+	PixelSnap bool
+}
+
+// Set to request that the fonts be reloaded at the start of the next
+// frame, e.g., after the user fonts have changed.
+var fontsReloadRequested bool
+
+func userFontsDirectory() string {
+	return path.Join(path.Dir(configFilePath()), "fonts")
+}
+
+// userFontFiles returns the paths of the font files in the user fonts
+// directory with the given extensions.
+func userFontFiles(exts ...string) []string {
+	entries, err := os.ReadDir(userFontsDirectory())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			lg.Errorf("%s: %v", userFontsDirectory(), err)
+		}
+		return nil
+	}
+
+	var files []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		for _, x := range exts {
+			if ext == x && !e.IsDir() {
+				files = append(files, filepath.Join(userFontsDirectory(), e.Name()))
+			}
+		}
+	}
+	return files
+}
+
+// userFontName returns the name to use for the font in the given file.
+func userFontName(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Sizes returns the standard font sizes limited to the configured range.
+func (uf *UserFontsConfig) Sizes(sizes []int) []int {
+	return FilterSlice(sizes, func(s int) bool {
+		return (uf.MinSize == 0 || s >= uf.MinSize) && (uf.MaxSize == 0 || s <= uf.MaxSize)
+	})
+}
+
+// FontConfig returns the imgui font configuration to use for rasterizing
+// user fonts.
+func (uf *UserFontsConfig) FontConfig() imgui.FontConfig {
+	config := imgui.NewFontConfig()
+	if uf.Oversample > 0 {
+		config.SetOversampleH(uf.Oversample)
+		config.SetOversampleV(uf.Oversample)
+	}
+	config.SetPixelSnapH(uf.PixelSnap)
+	return config
+}
+
+func (uf *UserFontsConfig) DrawUI() {
+	imgui.Text("Font directory: " + userFontsDirectory())
+	imgui.Text("TrueType/OpenType (.ttf, .otf) and BDF bitmap (.bdf) fonts found there can be selected for panes.")
+
+	minSize, maxSize := int32(uf.MinSize), int32(uf.MaxSize)
+	if imgui.SliderIntV("Smallest size", &minSize, 0, 28, Select(minSize == 0, "No limit", "%d"), 0) {
+		uf.MinSize = int(minSize)
+	}
+	if imgui.SliderIntV("Largest size", &maxSize, 0, 28, Select(maxSize == 0, "No limit", "%d"), 0) {
+		uf.MaxSize = int(maxSize)
+	}
+	oversample := int32(uf.Oversample)
+	if imgui.SliderIntV("Oversampling", &oversample, 0, 4, Select(oversample == 0, "Default", "%dx"), 0) {
+		uf.Oversample = int(oversample)
+	}
+	imgui.Checkbox("Snap glyphs to pixels", &uf.PixelSnap)
+
+	if imgui.Button("Reload fonts") {
+		fontsReloadRequested = true
+	}
+}
+
+// loadUserTTFFonts adds the user's TTF and OTF fonts using the given
+// function to add a font.
+func loadUserTTFFonts(sizes []int, add func(ttf []byte, mono bool, name string, sizes []int, config imgui.FontConfig)) {
+	uf := &globalConfig.UserFonts
+	userSizes := uf.Sizes(sizes)
+	if len(userSizes) == 0 {
+		lg.Errorf("No font sizes in the range %d-%d", uf.MinSize, uf.MaxSize)
+		return
+	}
+
+	for _, fn := range userFontFiles(".ttf", ".otf") {
+		name := userFontName(fn)
+		if GetFont(FontIdentifier{Name: name, Size: userSizes[0]}) != nil && !isUserFont(name) {
+			lg.Errorf("%s: font with the same name is already loaded", fn)
+			continue
+		}
+		ttf, err := os.ReadFile(fn)
+		if err != nil {
+			lg.Errorf("%s: %v", fn, err)
+			continue
+		}
+		lg.Infof("Loading user font %s", fn)
+		add(ttf, false, name, userSizes, uf.FontConfig())
+		userFontNames[name] = nil
+	}
+}
+
+var userFontNames = make(map[string]interface{})
+
+func isUserFont(name string) bool {
+	_, ok := userFontNames[name]
+	return ok
+}
+
+///////////////////////////////////////////////////////////////////////////
+// BDF bitmap fonts
+
+type bdfGlyph struct {
+	encoding   rune
+	dwidth     int
+	w, h       int // BBX
+	xoff, yoff int
+	bitmap     [][]byte
+}
+
+type bdfFont struct {
+	ascent, descent int
+	glyphs          []bdfGlyph
+}
+
+// parseBDF parses a font in the Glyph Bitmap Distribution Format; see
+// https://adobe-type-tools.github.io/font-tech-notes/pdfs/5005.BDF_Spec.pdf.
+func parseBDF(b []byte) (*bdfFont, error) {
+	f := &bdfFont{}
+	var g *bdfGlyph
+	inBitmap := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	lineno := 0
+	atoi := func(s string) int {
+		v, _ := strconv.Atoi(s)
+		return v
+	}
+	for scanner.Scan() {
+		lineno++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if inBitmap {
+			if fields[0] == "ENDCHAR" {
+				inBitmap = false
+				if g.encoding >= 0 {
+					f.glyphs = append(f.glyphs, *g)
+				}
+				continue
+			}
+			row := make([]byte, len(fields[0])/2)
+			for i := range row {
+				v, err := strconv.ParseUint(fields[0][2*i:2*i+2], 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineno, err)
+				}
+				row[i] = byte(v)
+			}
+			g.bitmap = append(g.bitmap, row)
+			continue
+		}
+
+		switch fields[0] {
+		case "FONT_ASCENT":
+			if len(fields) > 1 {
+				f.ascent = atoi(fields[1])
+			}
+		case "FONT_DESCENT":
+			if len(fields) > 1 {
+				f.descent = atoi(fields[1])
+			}
+		case "STARTCHAR":
+			g = &bdfGlyph{encoding: -1}
+		case "ENCODING":
+			if g != nil && len(fields) > 1 {
+				g.encoding = rune(atoi(fields[1]))
+			}
+		case "DWIDTH":
+			if g != nil && len(fields) > 1 {
+				g.dwidth = atoi(fields[1])
+			}
+		case "BBX":
+			if g == nil || len(fields) < 5 {
+				return nil, fmt.Errorf("line %d: malformed BBX", lineno)
+			}
+			g.w, g.h, g.xoff, g.yoff = atoi(fields[1]), atoi(fields[2]), atoi(fields[3]), atoi(fields[4])
+		case "BITMAP":
+			if g == nil {
+				return nil, fmt.Errorf("line %d: BITMAP outside of character", lineno)
+			}
+			inBitmap = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(f.glyphs) == 0 {
+		return nil, fmt.Errorf("no glyphs found")
+	}
+	return f, nil
+}
+
+// loadUserBitmapFonts loads the user's BDF fonts, each into its own atlas
+// texture.
+func loadUserBitmapFonts(r Renderer) {
+	for _, fn := range userFontFiles(".bdf") {
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			lg.Errorf("%s: %v", fn, err)
+			continue
+		}
+		bdf, err := parseBDF(b)
+		if err != nil {
+			lg.Errorf("%s: %v", fn, err)
+			continue
+		}
+
+		height := bdf.ascent + bdf.descent
+		id := FontIdentifier{Name: userFontName(fn), Size: height}
+		if _, ok := fonts[id]; ok && !isUserFont(id.Name) {
+			lg.Errorf("%s: font with the same name is already loaded", fn)
+			continue
+		}
+		lg.Infof("Loading user bitmap font %s", fn)
+
+		// Lay out the glyphs in rows in the atlas.
+		const res = 512
+		atlas := image.NewRGBA(image.Rectangle{Max: image.Point{X: res, Y: res}})
+		font := &Font{glyphs: make(map[rune]*Glyph), size: height, mono: true, id: id}
+		x, y, rowHeight := 0, 0, 0
+		for _, g := range bdf.glyphs {
+			if x+g.w+1 > res {
+				x, y, rowHeight = 0, y+rowHeight+1, 0
+			}
+			if y+g.h > res {
+				lg.Errorf("%s: too many glyphs for the font atlas", fn)
+				break
+			}
+			for yy, row := range g.bitmap {
+				for xx := 0; xx < g.w; xx++ {
+					if xx/8 < len(row) && row[xx/8]&(0x80>>(xx%8)) != 0 {
+						atlas.SetRGBA(x+xx, y+yy, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+					}
+				}
+			}
+
+			// Glyph coordinates are w.r.t. the upper left of the line
+			// with y increasing downward.
+			y0 := float32(bdf.ascent - g.yoff - g.h)
+			glyph := &Glyph{
+				X0:       float32(g.xoff),
+				Y0:       y0,
+				X1:       float32(g.xoff + g.w),
+				Y1:       y0 + float32(g.h),
+				U0:       float32(x) / res,
+				V0:       float32(y) / res,
+				U1:       float32(x+g.w) / res,
+				V1:       float32(y+g.h) / res,
+				AdvanceX: float32(g.dwidth),
+				Visible:  g.w > 0 && g.h > 0,
+			}
+			if g.encoding < 128 {
+				font.lowGlyphs[g.encoding] = glyph
+			} else {
+				font.glyphs[g.encoding] = glyph
+			}
+
+			x += g.w + 1
+			rowHeight = max(rowHeight, g.h)
+		}
+
+		// Fill in any missing ASCII glyphs so that lookups don't go to
+		// imgui, which doesn't know about this font.
+		for ch := range font.lowGlyphs {
+			if font.lowGlyphs[ch] == nil {
+				font.lowGlyphs[ch] = &Glyph{AdvanceX: float32(height) / 2}
+			}
+		}
+
+		font.texId = r.CreateTextureFromImage(atlas, true /* nearest */)
+		if old, ok := fonts[id]; ok {
+			// Reloading; update it in place so that existing references
+			// to it remain valid.
+			r.DestroyTexture(old.texId)
+			if old.scalable != nil {
+				r.DestroyTexture(old.scalable.texId)
+			}
+			*old = *font
+			font = old
+		} else {
+			fonts[id] = font
+		}
+		userFontNames[id.Name] = nil
+		buildScalableFonts(r, atlas, font, nil)
+	}
+}
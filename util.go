@@ -130,6 +130,35 @@ func stopShouting(orig string) string {
 	return s.String()
 }
 
+// fuzzyMatch reports whether all of the characters in pattern appear in s
+// in order, ignoring case. If so, it also returns a score that is higher
+// for better matches: runs of consecutive characters and matches at the
+// start of s or of a word in s are favored, and shorter strings are
+// favored over longer ones.
+func fuzzyMatch(pattern, s string) (int, bool) {
+	pattern, ls := strings.ToLower(pattern), strings.ToLower(s)
+	score, pi, run := 0, 0, 0
+	for i := 0; i < len(ls) && pi < len(pattern); i++ {
+		if ls[i] != pattern[pi] {
+			run = 0
+			continue
+		}
+
+		run++
+		score += run
+		if i == 0 {
+			score += 10
+		} else if ls[i-1] == ' ' || ls[i-1] == '-' {
+			score += 5
+		}
+		pi++
+	}
+	if pi < len(pattern) {
+		return 0, false
+	}
+	return 2*score - len(s), true
+}
+
 // atof is a utility for parsing floating point values that sends errors to
 // the logging system.
 func atof(s string) float64 {
@@ -668,6 +697,30 @@ func FetchURL(url string) ([]byte, error) {
 	return text, nil
 }
 
+// Backoff computes the delays to use between successive attempts at
+// something that may fail repeatedly, such as reconnecting to a server.
+// Each delay is twice the previous one, starting at Min and limited to
+// Max.
+type Backoff struct {
+	Min, Max time.Duration
+	attempts int
+}
+
+// Next returns the delay to wait before the next attempt.
+func (b *Backoff) Next() time.Duration {
+	d := b.Min
+	for i := 0; i < b.attempts && d < b.Max; i++ {
+		d *= 2
+	}
+	b.attempts++
+	return min(d, b.Max)
+}
+
+// Reset should be called after a successful attempt.
+func (b *Backoff) Reset() {
+	b.attempts = 0
+}
+
 ///////////////////////////////////////////////////////////////////////////
 // Image processing
 
@@ -1341,10 +1394,14 @@ func (l *LoggingMutex) LogValue() slog.Value {
 ///////////////////////////////////////////////////////////////////////////
 
 // discordStatus encapsulates the user's current vice activity; if the user is not
-// currently controlling, callsign should be an empty string.
+// currently controlling, callsign should be an empty string. If private is
+// set, only the fact that the user is controlling is reported.
 type discordStatus struct {
 	totalDepartures, totalArrivals int
 	callsign                       string
+	frequency                      Frequency
+	aircraft                       int // number of aircraft tracked by the user
+	private                        bool
 	start                          time.Time
 }
 
@@ -1366,10 +1423,7 @@ func SetDiscordStatus(s discordStatus) {
 	discord.mu.Lock()
 	defer discord.mu.Unlock()
 
-	if s.totalDepartures != discord.status.totalDepartures ||
-		s.totalArrivals != discord.status.totalArrivals ||
-		s.callsign != discord.status.callsign ||
-		s.start != discord.status.start {
+	if s != discord.status {
 		discord.statusChanged = true
 	}
 
@@ -1418,10 +1472,16 @@ func updateDiscordStatus() {
 				// Disconnected
 				activity.State = "In the main menu"
 				activity.Details = "On Break"
+			} else if status.private {
+				activity.Details = "Controlling"
 			} else {
-				activity.State = strconv.Itoa(status.totalDepartures) + " departures" + " | " +
+				activity.State = strconv.Itoa(status.aircraft) + " aircraft | " +
+					strconv.Itoa(status.totalDepartures) + " departures" + " | " +
 					strconv.Itoa(status.totalArrivals) + " arrivals"
 				activity.Details = "Controlling " + status.callsign
+				if status.frequency != 0 {
+					activity.Details += " on " + status.frequency.String()
+				}
 			}
 
 			if err := discord_client.SetActivity(activity); err != nil {
@@ -31,6 +31,30 @@ func TestStopShouting(t *testing.T) {
 	}
 }
 
+func TestFuzzyMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"aal", "AAL123", true},
+		{"a12", "AAL123", true},
+		{"set", "Open settings window", true},
+		{"321", "AAL123", false},
+		{"aal1234", "AAL123", false},
+	} {
+		if _, ok := fuzzyMatch(test.pattern, test.s); ok != test.match {
+			t.Errorf("fuzzyMatch(%q, %q) gave %v, expected %v", test.pattern, test.s, ok, test.match)
+		}
+	}
+
+	// Prefix and consecutive matches should be preferred.
+	prefix, _ := fuzzyMatch("cam", "CAMRN")
+	scattered, _ := fuzzyMatch("cam", "CLAYM")
+	if prefix <= scattered {
+		t.Errorf("expected CAMRN (%d) to score higher than CLAYM (%d)", prefix, scattered)
+	}
+}
+
 func TestTransientMap(t *testing.T) {
 	ts := NewTransientMap[int, int]()
 	ts.Add(1, 10, 250*time.Millisecond)
@@ -172,6 +196,21 @@ func TestRingBuffer(t *testing.T) {
 	}
 }
 
+func TestBackoff(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 10 * time.Second}
+	expected := []time.Duration{1, 2, 4, 8, 10, 10}
+	for i, e := range expected {
+		if d := b.Next(); d != e*time.Second {
+			t.Errorf("attempt %d: got %s, expected %s", i, d, e*time.Second)
+		}
+	}
+
+	b.Reset()
+	if d := b.Next(); d != time.Second {
+		t.Errorf("after reset: got %s, expected %s", d, time.Second)
+	}
+}
+
 func TestReduceSlice(t *testing.T) {
 	v := []int{1, -2, 3, 4}
 
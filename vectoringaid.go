@@ -0,0 +1,184 @@
+// vectoringaid.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// The vectoring aid previews a vector before it is issued: alt-clicking
+// an aircraft and dragging shows the heading to the mouse position and,
+// if that track crosses the final approach course of an active arrival
+// runway, where the aircraft would intercept it and how far behind the
+// preceding arrival it would be at that point.
+
+type STARSVectoringAid struct {
+	Callsign string
+	End      Point2LL // mouse position
+}
+
+// Finals farther out than this from the threshold aren't considered.
+const STARSVectoringAidMaxFinal = 30 // nm
+
+type vectoringAidPrediction struct {
+	Heading float32 // magnetic
+
+	Intercepted      bool
+	Airport, Runway  string
+	Intercept        Point2LL
+	InterceptAngle   float32
+	FinalDistance    float32 // from the threshold, at the intercept
+	Preceding        string  // callsign of the preceding arrival, if any
+	PrecedingSpacing float32 // nm
+}
+
+// predictVector computes the vectoring aid's prediction for the aircraft
+// flying from its current position toward the given point.
+func (sp *STARSPane) predictVector(w *World, ac *Aircraft, end Point2LL) vectoringAidPrediction {
+	state := sp.Aircraft[ac.Callsign]
+	nmPerLongitude := w.NmPerLongitude
+	pos := state.TrackPosition()
+
+	course := headingp2ll(pos, end, nmPerLongitude, w.MagneticVariation)
+	pred := vectoringAidPrediction{Heading: course}
+
+	p0, p1 := ll2nm(pos, nmPerLongitude), ll2nm(end, nmPerLongitude)
+	v := sub2f(p1, p0)
+	if length2f(v) == 0 {
+		return pred
+	}
+	v = normalize2f(v)
+
+	// If the wind is known, correct the heading for it, assuming that the
+	// aircraft maintains its current groundspeed.
+	gs := float32(max(state.TrackGroundspeed(), 1))
+	if w.Wind.Speed > 0 {
+		a := sub2f(scale2f(v, gs), w.AverageWindVector())
+		pred.Heading = NormalizeHeading(degrees(atan2(a[0], a[1])) + w.MagneticVariation)
+	}
+
+	// The runway for the aircraft's assigned approach is preferred;
+	// otherwise the nearest intercept ahead of the aircraft is used.
+	preferred := ""
+	if ap := ac.Nav.Approach.Assigned; ap != nil && ac.FlightPlan != nil {
+		preferred = ac.FlightPlan.ArrivalAirport + ap.Runway
+	}
+	bestDist := float32(math.MaxFloat32)
+	var bestAlong float32
+	var bestU, bestThreshold [2]float32
+	rc := w.RunwayConfiguration()
+	for _, icao := range SortedMapKeys(rc.Airports) {
+		for _, id := range rc.Airports[icao].ArrivalRunways {
+			rwy, ok := LookupRunway(icao, id)
+			if !ok {
+				continue
+			}
+			t := ll2nm(rwy.Threshold, nmPerLongitude)
+			hdg := rwy.Heading - w.MagneticVariation + 180
+			u := [2]float32{sin(radians(hdg)), cos(radians(hdg))}
+
+			ip, ok := LineLineIntersect(p0, p1, t, add2f(t, u))
+			if !ok {
+				continue
+			}
+			along := dot(sub2f(ip, t), u)
+			dist := dot(sub2f(ip, p0), v)
+			if along < 0 || along > STARSVectoringAidMaxFinal || dist < 0 {
+				continue
+			}
+			if icao+id == preferred {
+				dist = -1 // always take it
+			}
+			if dist < bestDist {
+				bestDist = dist
+				pred.Intercepted = true
+				pred.Airport, pred.Runway = icao, id
+				pred.Intercept = nm2ll(ip, nmPerLongitude)
+				pred.InterceptAngle = headingDifference(course, rwy.Heading)
+				pred.FinalDistance = along
+				bestAlong, bestU, bestThreshold = along, u, t
+			}
+		}
+	}
+	if !pred.Intercepted {
+		return pred
+	}
+
+	// Estimate how long it will take to reach the intercept and where the
+	// other arrivals to the runway will be by then.
+	hours := distance2f(p0, ll2nm(pred.Intercept, nmPerLongitude)) / gs
+	pred.PrecedingSpacing = float32(math.MaxFloat32)
+	for callsign, other := range w.Aircraft {
+		if callsign == ac.Callsign || other.FlightPlan == nil || other.FlightPlan.ArrivalAirport != pred.Airport {
+			continue
+		}
+		if vol := other.ATPAVolume(); vol == nil || vol.Id != pred.Airport+pred.Runway {
+			continue
+		}
+		os := sp.Aircraft[callsign]
+		along := dot(sub2f(ll2nm(os.TrackPosition(), nmPerLongitude), bestThreshold), bestU)
+		along = max(0, along-float32(os.TrackGroundspeed())*hours)
+		if along < bestAlong && bestAlong-along < pred.PrecedingSpacing {
+			pred.Preceding, pred.PrecedingSpacing = callsign, bestAlong-along
+		}
+	}
+
+	return pred
+}
+
+func (sp *STARSPane) drawVectoringAid(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	va := sp.vectoringAid
+	if va == nil {
+		return
+	}
+	ac, ok := ctx.world.Aircraft[va.Callsign]
+	if !ok {
+		sp.vectoringAid = nil
+		return
+	}
+	state := sp.Aircraft[va.Callsign]
+	pred := sp.predictVector(ctx.world, ac, va.End)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSAnnotationColor)
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	text := fmt.Sprintf("HDG %03d", int(pred.Heading+0.5)%360)
+	if pred.Intercepted {
+		ld.AddLine(state.TrackPosition(), pred.Intercept, color)
+		// Mark the intercept with an X.
+		pw := transforms.WindowFromLatLongP(pred.Intercept)
+		for _, d := range [][2][2]float32{{{-5, -5}, {5, 5}}, {{-5, 5}, {5, -5}}} {
+			ld.AddLine(transforms.LatLongFromWindowP(add2f(pw, d[0])),
+				transforms.LatLongFromWindowP(add2f(pw, d[1])), color)
+		}
+
+		text += fmt.Sprintf("\nINT %s %.1f %02d", pred.Runway, pred.FinalDistance, int(pred.InterceptAngle+0.5))
+		if pred.Preceding != "" {
+			text += fmt.Sprintf("\nSPC %.1f %s", pred.PrecedingSpacing, pred.Preceding)
+		}
+	} else {
+		ld.AddLine(state.TrackPosition(), va.End, color)
+	}
+
+	pw := add2f(transforms.WindowFromLatLongP(va.End), [2]float32{10, -10})
+	td.AddText(text, pw, TextStyle{
+		Font:            sp.systemFont[ps.CharSize.Tools],
+		Color:           color,
+		DrawBackground:  true,
+		BackgroundColor: RGB{},
+	})
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
@@ -0,0 +1,274 @@
+// webhooks.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Webhooks post notifications about the session to Discord, Slack, or any
+// other HTTP endpoint when the controller connects or disconnects, when
+// an aircraft squawks an emergency code, and when the amount of traffic
+// crosses a threshold, so that facility staff can keep track of event
+// staffing without having to ask.
+
+type WebhookFormat int
+
+const (
+	WebhookFormatDiscord WebhookFormat = iota
+	WebhookFormatSlack
+	WebhookFormatGeneric
+)
+
+func (f WebhookFormat) String() string {
+	return []string{"Discord", "Slack", "Generic JSON"}[f]
+}
+
+type Webhook struct {
+	URL         string
+	Format      WebhookFormat
+	OnConnect   bool // connect and disconnect
+	OnEmergency bool
+	// If non-zero, a notification is sent when the number of aircraft
+	// tracked by the controller reaches this many.
+	TrafficThreshold int32
+
+	aboveThreshold bool
+}
+
+type WebhookConfig struct {
+	Hooks []Webhook
+
+	// Session state, used to detect changes.
+	callsign    string // controller callsign if connected
+	emergencies map[string]string
+
+	// UI state
+	status chan string
+	result string
+}
+
+// Webhook event types; they're reported in the "event" field of generic
+// notifications.
+const (
+	WebhookEventConnect    = "connect"
+	WebhookEventDisconnect = "disconnect"
+	WebhookEventEmergency  = "emergency"
+	WebhookEventTraffic    = "traffic"
+	WebhookEventTest       = "test"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Update should be called regularly with the current World (or nil if
+// there isn't one); it sends notifications for anything that has changed
+// since the last call.
+func (wc *WebhookConfig) Update(w *World) {
+	if len(wc.Hooks) == 0 {
+		return
+	}
+
+	callsign := ""
+	if w != nil {
+		callsign = w.Callsign
+	}
+	if callsign != wc.callsign {
+		if wc.callsign != "" {
+			wc.post(WebhookEventDisconnect, wc.callsign, wc.callsign+" disconnected",
+				func(h *Webhook) bool { return h.OnConnect })
+		}
+		if callsign != "" {
+			wc.post(WebhookEventConnect, callsign, callsign+" connected to "+w.SimDescription,
+				func(h *Webhook) bool { return h.OnConnect })
+		}
+		wc.callsign = callsign
+		wc.emergencies = nil
+		for i := range wc.Hooks {
+			wc.Hooks[i].aboveThreshold = false
+		}
+	}
+	if w == nil {
+		return
+	}
+
+	// Emergencies: only report each aircraft's condition once.
+	if wc.emergencies == nil {
+		wc.emergencies = make(map[string]string)
+	}
+	tracked := 0
+	for _, ac := range w.Aircraft {
+		if ac.TrackingController == callsign {
+			tracked++
+		}
+
+		code := EmergencyCondition(ac.Squawk, ac.Callsign, "")
+		if code != "" && code != wc.emergencies[ac.Callsign] {
+			wc.post(WebhookEventEmergency, callsign,
+				fmt.Sprintf("%s: %s squawking %s (%s)", callsign, ac.Callsign, ac.Squawk, code),
+				func(h *Webhook) bool { return h.OnEmergency })
+		}
+		wc.emergencies[ac.Callsign] = code
+	}
+	for cs := range wc.emergencies {
+		if _, ok := w.Aircraft[cs]; !ok {
+			delete(wc.emergencies, cs)
+		}
+	}
+
+	for i := range wc.Hooks {
+		h := &wc.Hooks[i]
+		if h.URL == "" || h.TrafficThreshold <= 0 {
+			continue
+		}
+		if above := tracked >= int(h.TrafficThreshold); above != h.aboveThreshold {
+			h.aboveThreshold = above
+			if above {
+				msg := fmt.Sprintf("%s is working %d aircraft", callsign, tracked)
+				h.send(i, WebhookEventTraffic, callsign, msg, nil)
+			}
+		}
+	}
+}
+
+// post sends the notification to all of the webhooks for which send
+// returns true.
+func (wc *WebhookConfig) post(event, callsign, message string, send func(h *Webhook) bool) {
+	for i := range wc.Hooks {
+		if h := &wc.Hooks[i]; h.URL != "" && send(h) {
+			h.send(i, event, callsign, message, nil)
+		}
+	}
+}
+
+func (h *Webhook) payload(event, callsign, message string) ([]byte, error) {
+	message = "vice: " + message
+	switch h.Format {
+	case WebhookFormatDiscord:
+		return json.Marshal(map[string]string{"content": message})
+	case WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": message})
+	default:
+		return json.Marshal(map[string]string{
+			"event":      event,
+			"controller": callsign,
+			"message":    message,
+			"time":       time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// send posts the notification in the background; if result is non-nil,
+// the outcome is sent to it. index is the webhook's index in the
+// configuration; it's used to identify the webhook in log messages, which
+// must not include its URL since the URL is effectively a secret and the
+// log may end up in crash reports.
+func (h *Webhook) send(index int, event, callsign, message string, result chan<- string) {
+	name := fmt.Sprintf("webhook %d", index+1)
+	body, err := h.payload(event, callsign, message)
+	if err != nil {
+		lg.Errorf("%s: %v", name, err)
+		return
+	}
+
+	hookURL, sent := h.URL, Tr("Sent")
+	go func() {
+		err := func() error {
+			resp, err := webhookClient.Post(hookURL, "application/json", bytes.NewReader(body))
+			if err != nil {
+				// The error returned by the http.Client includes the URL.
+				if uerr, ok := err.(*url.Error); ok {
+					return uerr.Err
+				}
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return errors.New(resp.Status)
+			}
+			return nil
+		}()
+
+		if err != nil {
+			lg.Category(LogCategoryNetwork).Warnf("%s %s: %v", name, event, err)
+		} else {
+			lg.Category(LogCategoryNetwork).Infof("%s %s: sent", name, event)
+		}
+		if result != nil {
+			s := sent
+			if err != nil {
+				s = err.Error()
+			}
+			select {
+			case result <- s:
+			default:
+			}
+		}
+	}()
+}
+
+func (wc *WebhookConfig) DrawUI() {
+	select {
+	case s := <-wc.status:
+		wc.result = s
+	default:
+	}
+
+	for i := 0; i < len(wc.Hooks); i++ {
+		h := &wc.Hooks[i]
+		imgui.PushID(strconv.Itoa(i))
+
+		imgui.InputTextV(Tr("URL"), &h.URL, imgui.InputTextFlagsCharsNoBlank, nil)
+		if imgui.BeginComboV(Tr("Format"), h.Format.String(), 0) {
+			for f := WebhookFormatDiscord; f <= WebhookFormatGeneric; f++ {
+				if imgui.SelectableV(f.String(), f == h.Format, 0, imgui.Vec2{}) {
+					h.Format = f
+				}
+			}
+			imgui.EndCombo()
+		}
+		imgui.Checkbox(Tr("Connect and disconnect"), &h.OnConnect)
+		imgui.SameLine()
+		imgui.Checkbox(Tr("Emergency squawks"), &h.OnEmergency)
+		imgui.InputIntV(Tr("Traffic threshold"), &h.TrafficThreshold, 0, 0, 0)
+		if imgui.IsItemHovered() {
+			imgui.SetTooltip(Tr("Notify when this many aircraft are tracked; 0 disables the notification"))
+		}
+
+		uiStartDisable(h.URL == "")
+		if imgui.Button(Tr("Send test")) {
+			if wc.status == nil {
+				wc.status = make(chan string, 1)
+			}
+			wc.result = Tr("Sending...")
+			h.send(i, WebhookEventTest, wc.callsign, "test notification", wc.status)
+		}
+		uiEndDisable(h.URL == "")
+		imgui.SameLine()
+		if imgui.Button(Tr("Remove")) {
+			wc.Hooks = DeleteSliceElement(wc.Hooks, i)
+			i--
+		}
+
+		imgui.Separator()
+		imgui.PopID()
+	}
+
+	if imgui.Button(Tr("Add webhook")) {
+		wc.Hooks = append(wc.Hooks, Webhook{OnConnect: true, OnEmergency: true})
+	}
+	if wc.result != "" {
+		imgui.Text(wc.result)
+	}
+}
@@ -41,6 +41,9 @@ var (
 		// back to the previous one (e.g., the CLIPane.)
 		keyboardFocusStack []Pane
 
+		// Window-coordinate extent of each Pane when it was last drawn.
+		paneExtents map[Pane]Extent2D
+
 		lastAircraftResponse string
 	}
 )
@@ -373,6 +376,7 @@ func (d *DisplayNode) getString(indent string) string {
 func wmInit() {
 	wm.showPaneSettings = make(map[Pane]*bool)
 	wm.showPaneName = make(map[Pane]string)
+	wm.paneExtents = make(map[Pane]Extent2D)
 }
 
 // wmAddPaneMenuSettings is called to populate the top-level "Subwindows"
@@ -418,6 +422,60 @@ func wmReleaseKeyboardFocus() {
 	}
 }
 
+// wmAddPane adds the given Pane to the display hierarchy, to the right of
+// the existing panes, and activates it.
+func wmAddPane(pane Pane, w *World, r Renderer, eventStream *EventStream) {
+	pane.Activate(w, r, eventStream)
+	if w != nil {
+		pane.ResetWorld(w)
+	}
+
+	globalConfig.DisplayRoot = &DisplayNode{
+		SplitLine: SplitLine{Pos: 0.8, Axis: SplitAxisX},
+		Children:  [2]*DisplayNode{globalConfig.DisplayRoot, &DisplayNode{Pane: pane}},
+	}
+}
+
+// wmRemovePane removes the given Pane from the display hierarchy; its
+// sibling takes over the space it occupied.
+func wmRemovePane(pane Pane) {
+	parent, idx := globalConfig.DisplayRoot.ParentNodeForPane(pane)
+	if parent == nil {
+		lg.Errorf("%s: couldn't find pane's parent node", pane.Name())
+		return
+	}
+
+	*parent = *parent.Children[1-idx]
+	pane.Deactivate()
+
+	delete(wm.showPaneSettings, pane)
+	delete(wm.showPaneName, pane)
+	if wm.mouseConsumerOverride == pane {
+		wm.mouseConsumerOverride = nil
+	}
+}
+
+// wmPaneCheckbox draws a checkbox in the UI that allows the user to add
+// or remove a pane of type T from the display hierarchy; create is called
+// to make a new one when it is enabled.
+func wmPaneCheckbox[T Pane](label string, create func() T, w *World, r Renderer, eventStream *EventStream) {
+	var existing Pane
+	globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
+		if _, ok := p.(T); ok {
+			existing = p
+		}
+	})
+
+	show := existing != nil
+	if imgui.Checkbox(label, &show) {
+		if show {
+			wmAddPane(create(), w, r, eventStream)
+		} else {
+			wmRemovePane(existing)
+		}
+	}
+}
+
 // wmPaneIsPresent checks to see if the specified Pane is present in the
 // display hierarchy.
 func wmPaneIsPresent(pane Pane, root *DisplayNode) bool {
@@ -509,12 +567,15 @@ func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
 	commandBuffer.ClearRGB(RGB{})
 
 	// Actually visit the panes.
+	clear(wm.paneExtents)
 	var keyboard *KeyboardState
 	if !imgui.CurrentIO().WantCaptureKeyboard() {
 		keyboard = NewKeyboardState(p)
 	}
 	root.VisitPanesWithBounds(paneDisplayExtent, paneDisplayExtent,
 		func(paneExtent Extent2D, parentExtent Extent2D, pane Pane) {
+			wm.paneExtents[pane] = paneExtent
+
 			haveFocus := pane == wm.keyboardFocusPane && !imgui.CurrentIO().WantCaptureKeyboard()
 			ctx := PaneContext{
 				paneExtent:       paneExtent,
@@ -548,7 +609,13 @@ func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
 			commandBuffer.SetDrawBounds(paneExtent)
 
 			// Let the Pane do its thing
-			pane.Draw(&ctx, commandBuffer)
+			if profilerEnabled() {
+				start, n := time.Now(), len(commandBuffer.Buf)
+				pane.Draw(&ctx, commandBuffer)
+				profiler.RecordPane(pane, time.Since(start), 4*(len(commandBuffer.Buf)-n))
+			} else {
+				pane.Draw(&ctx, commandBuffer)
+			}
 
 			// And reset the graphics state to the standard baseline,
 			// so no state changes leak and affect subsequent drawing.
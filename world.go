@@ -40,11 +40,34 @@ type World struct {
 	showScenarioInfo  bool
 
 	launchControlWindow *LaunchControlWindow
+	instructorConsole   *InstructorConsole
+
+	timelapse *TimelapseRecorder // client-side only
+
+	liveTraffic             *LiveTraffic // client-side only
+	liveTrafficRadius       float32
+	liveTrafficSource       string
+	liveTrafficSettings     NetworkSettings
+	liveTrafficRelay        bool
+	liveTrafficRelayAddress string
+	liveTrafficErr          error
+	scenarioRecorder        *ScenarioRecorder // client-side only
+
+	reconnector *SimReconnector // client-side only
+	simPassword string          // for rejoining a remote sim
+
+	positionReports map[string]positionReport // client-side only
+
+	sessionStats  *SessionStats  // client-side only
+	separationLog *SeparationLog // client-side only
+	reminders     []*Reminder    // client-side only
 
 	pendingCalls []*PendingCall
 
 	missingPrimaryDialog *ModalDialogBox
 
+	runwayConfig *RunwayConfiguration
+
 	sameGateDepartures int
 	sameDepartureCap   int
 
@@ -118,6 +141,15 @@ func (w *World) AverageWindVector() [2]float32 {
 	return scale2f(v, float32(w.Wind.Speed))
 }
 
+// RunwayConfiguration returns the active runway configuration, creating
+// it from the scenario's runways the first time it is requested.
+func (w *World) RunwayConfiguration() *RunwayConfiguration {
+	if w.runwayConfig == nil {
+		w.runwayConfig = MakeRunwayConfiguration(w)
+	}
+	return w.runwayConfig
+}
+
 func (w *World) GetAirport(icao string) *Airport {
 	return w.Airports[icao]
 }
@@ -150,12 +182,32 @@ func (w *World) AllAirports() map[string]*Airport {
 	return all
 }
 
-func (w *World) SetSquawk(callsign string, squawk Squawk) error {
-	return nil // UNIMPLEMENTED
+// assignSquawk returns a beacon code from the facility's banks that isn't
+// currently in use.
+func (w *World) assignSquawk() (Squawk, error) {
+	return AssignBeaconCode(w.STARSFacilityAdaptation.BeaconCodeBanks, squawksInUse(w.Aircraft))
 }
 
-func (w *World) SetSquawkAutomatic(callsign string) error {
-	return nil // UNIMPLEMENTED
+func (w *World) SetSquawk(callsign string, squawk Squawk, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.SetSquawk(callsign, squawk),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+// SetSquawkAutomatic has the server assign the aircraft a new beacon code
+// from the facility's banks.
+func (w *World) SetSquawkAutomatic(callsign string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.SetSquawkAutomatic(callsign),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
 }
 
 func (w *World) TakeOrReturnLaunchControl(eventStream *EventStream) {
@@ -231,7 +283,21 @@ func (w *World) SetTemporaryAltitude(callsign string, alt int, success func(any)
 }
 
 func (w *World) AmendFlightPlan(callsign string, fp FlightPlan) error {
-	return nil // UNIMPLEMENTED
+	ac := w.Aircraft[callsign]
+	if ac == nil {
+		return ErrNoAircraftForCallsign
+	} else if ac.TrackingController != w.Callsign {
+		return ErrOtherControllerHasTrack
+	}
+	// As with the scratchpads, update it locally right away.
+	ac.FlightPlan = &fp
+
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.AmendFlightPlan(callsign, fp),
+			IssueTime: time.Now(),
+		})
+	return nil
 }
 
 func (w *World) SetGlobalLeaderLine(callsign string, dir *CardinalOrdinalDirection, success func(any), err func(error)) {
@@ -385,6 +451,36 @@ func (w *World) RejectPointOut(callsign string, success func(any), err func(erro
 		})
 }
 
+func (w *World) RequestCoordination(callsign, controller, request string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.RequestCoordination(callsign, controller, request),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (w *World) AcceptCoordination(callsign string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.AcceptCoordination(callsign),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (w *World) DenyCoordination(callsign string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.DenyCoordination(callsign),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
 func (w *World) ToggleSPCOverride(callsign string, spc string, success func(any), err func(error)) {
 	if ac := w.Aircraft[callsign]; ac != nil && ac.TrackingController == w.Callsign {
 		ac.ToggleSPCOverride(spc)
@@ -408,6 +504,23 @@ func (w *World) ChangeControlPosition(callsign string, keepTracks bool) error {
 }
 
 func (w *World) Disconnect() {
+	if w.liveTraffic != nil {
+		w.liveTraffic.Stop()
+		w.liveTraffic = nil
+	}
+	if w.sessionStats != nil {
+		w.sessionStats.Close()
+		w.sessionStats = nil
+	}
+	if w.separationLog != nil && len(w.separationLog.Events) > 0 {
+		// Save the post-session report for debriefing.
+		if fn, err := w.separationLog.WriteReport(defaultDirectory(""), w.Callsign, w.CurrentTime()); err != nil {
+			lg.Errorf("%s: %v", fn, err)
+		} else {
+			lg.Infof("%s: saved separation report", fn)
+		}
+	}
+	w.separationLog = nil
 	if err := w.simProxy.SignOff(nil, nil); err != nil {
 		lg.Errorf("Error signing off from sim: %v", err)
 	}
@@ -497,16 +610,47 @@ func (w *World) GetUpdates(eventStream *EventStream, onErr func(error)) {
 		return
 	}
 
+	if w.sessionStats == nil {
+		w.sessionStats = NewSessionStats(w, eventStream)
+	}
+	defer w.sessionStats.Update(w)
+	defer w.updateReminders()
+
+	if w.liveTraffic != nil {
+		// Keep handling RPC results but show the live aircraft rather
+		// than the simulation's.
+		if w.updateCall != nil && w.updateCall.CheckFinished(eventStream) {
+			w.updateCall = nil
+		}
+		w.checkPendingRPCs(eventStream)
+		w.liveTraffic.Update(w)
+		return
+	}
+
+	// Positions are extrapolated every frame, including when we've just
+	// received an update, since it was a snapshot at an earlier time.
+	defer w.deadReckonAircraft()
+
+	if w.Reconnecting() {
+		w.updateReconnect(eventStream, onErr)
+		return
+	}
+
 	if w.updateCall != nil && w.updateCall.CheckFinished(eventStream) {
 		w.updateCall = nil
 		return
 	}
+	if w.updateCall != nil && time.Since(w.updateCall.IssueTime) > SimConnectionTimeout && w.TryReconnect() {
+		eventStream.Post(Event{
+			Type:    StatusMessageEvent,
+			Message: "No response from the vice server; reconnecting...",
+		})
+		return
+	}
 
 	w.checkPendingRPCs(eventStream)
 
-	// Wait in seconds between update fetches; no less than 50ms
-	rate := clamp(1/w.SimRate, 0.05, 1)
-	if d := time.Since(w.lastUpdateRequest); d > time.Duration(rate*float32(time.Second)) {
+	if d := time.Since(w.lastUpdateRequest); d > w.updateInterval() {
 		if w.updateCall != nil {
 			lg.Warnf("GetUpdates still waiting for %s on last update call", d)
 			return
@@ -519,6 +663,12 @@ func (w *World) GetUpdates(eventStream *EventStream, onErr func(error)) {
 			IssueTime: time.Now(),
 			OnSuccess: func(any) {
 				d := time.Since(w.updateCall.IssueTime)
+				if profilerEnabled() {
+					profiler.RecordNetworkLatency(d)
+				}
+				if metrics != nil {
+					metrics.RecordNetworkLatency(d)
+				}
 				if d > 250*time.Millisecond {
 					lg.Warnf("Slow world update response %s", d)
 				} else {
@@ -582,6 +732,14 @@ func (w *World) SetLaunchConfig(lc LaunchConfig) {
 	w.LaunchConfig = lc // for the UI's benefit...
 }
 
+func (w *World) InjectInstructorEvent(ev InstructorEvent, onErr func(error)) {
+	w.pendingCalls = append(w.pendingCalls, &PendingCall{
+		Call:      w.simProxy.InjectInstructorEvent(ev),
+		IssueTime: time.Now(),
+		OnErr:     onErr,
+	})
+}
+
 // CurrentTime returns an extrapolated value that models the current Sim's time.
 // (Because the Sim may be running remotely, we have to make some approximations,
 // though they shouldn't cause much trouble since we get an update from the Sim
@@ -589,7 +747,7 @@ func (w *World) SetLaunchConfig(lc LaunchConfig) {
 func (w *World) CurrentTime() time.Time {
 	t := w.SimTime
 
-	if !w.SimIsPaused && !w.lastUpdateRequest.IsZero() {
+	if !w.SimIsPaused && !w.Reconnecting() && !w.lastUpdateRequest.IsZero() {
 		d := time.Since(w.lastUpdateRequest)
 
 		// Roughly account for RPC overhead; more for a remote server (where
@@ -621,6 +779,9 @@ func (w *World) GetWindowTitle() string {
 		return "(disconnected)"
 	} else {
 		deparr := fmt.Sprintf(" [ %d departures %d arrivals ]", w.TotalDepartures, w.TotalArrivals)
+		if status := w.ConnectionStatus(); status != "" {
+			deparr += " [" + status + "]"
+		}
 		if w.SimName == "" {
 			return w.Callsign + ": " + w.SimDescription + deparr
 		} else {
@@ -629,6 +790,26 @@ func (w *World) GetWindowTitle() string {
 	}
 }
 
+// DiscordStatus returns the user's current activity to report to Discord.
+func (w *World) DiscordStatus(start time.Time) discordStatus {
+	s := discordStatus{
+		totalDepartures: w.TotalDepartures,
+		totalArrivals:   w.TotalArrivals,
+		callsign:        w.Callsign,
+		private:         globalConfig.PrivateDiscordActivity.Load(),
+		start:           start,
+	}
+	if ctrl := w.GetControllerByCallsign(w.Callsign); ctrl != nil {
+		s.frequency = ctrl.Frequency
+	}
+	for _, ac := range w.Aircraft {
+		if ac.TrackingController == w.Callsign {
+			s.aircraft++
+		}
+	}
+	return s
+}
+
 func (w *World) GetVideoMaps() ([]STARSMap, []string) {
 	if config, ok := w.STARSFacilityAdaptation.ControllerConfigs[w.Callsign]; ok {
 		return config.VideoMaps, config.DefaultMaps
@@ -637,14 +818,28 @@ func (w *World) GetVideoMaps() ([]STARSMap, []string) {
 }
 
 func (w *World) GetInitialRange() float32 {
-	if config, ok := w.STARSFacilityAdaptation.ControllerConfigs[w.Callsign]; ok && config.Range != 0 {
+	return w.ControllerRange(w.Callsign)
+}
+
+func (w *World) GetInitialCenter() Point2LL {
+	return w.ControllerCenter(w.Callsign)
+}
+
+// ControllerRange returns the radar range of the given controller's
+// scope, falling back to the scenario's default range if the controller
+// doesn't have one specified.
+func (w *World) ControllerRange(callsign string) float32 {
+	if config, ok := w.STARSFacilityAdaptation.ControllerConfigs[callsign]; ok && config.Range != 0 {
 		return config.Range
 	}
 	return w.Range
 }
 
-func (w *World) GetInitialCenter() Point2LL {
-	if config, ok := w.STARSFacilityAdaptation.ControllerConfigs[w.Callsign]; ok && !config.Center.IsZero() {
+// ControllerCenter returns the center of the given controller's scope,
+// falling back to the scenario's center if the controller doesn't have one
+// specified.
+func (w *World) ControllerCenter(callsign string) Point2LL {
+	if config, ok := w.STARSFacilityAdaptation.ControllerConfigs[callsign]; ok && !config.Center.IsZero() {
 		return config.Center
 	}
 	return w.Center
@@ -797,7 +992,11 @@ func (w *World) sampleAircraft(icao, fleet string) (*Aircraft, string) {
 		}
 	}
 
-	squawk := Squawk(rand.Intn(0o7000))
+	squawk, err := w.assignSquawk()
+	if err != nil {
+		lg.Errorf("%s: %v", callsign, err)
+		return nil, ""
+	}
 
 	acType := aircraft
 	if perf.WeightClass == "H" {
@@ -1202,7 +1401,7 @@ func (w *World) DrawScenarioInfoWindow() {
 }
 
 func (w *World) DrawScenarioRoutes(transforms ScopeTransformations, font *Font, color RGB,
-	cb *CommandBuffer) {
+	drawLabels bool, cb *CommandBuffer) {
 	if !w.showScenarioInfo {
 		return
 	}
@@ -1314,7 +1513,9 @@ func (w *World) DrawScenarioRoutes(transforms ScopeTransformations, font *Font,
 
 	transforms.LoadWindowViewingMatrices(cb)
 	pd.GenerateCommands(cb)
-	td.GenerateCommands(cb)
+	if drawLabels {
+		td.GenerateCommands(cb)
+	}
 	cb.LineWidth(1)
 	ldr.GenerateCommands(cb)
 }
@@ -1635,22 +1836,27 @@ func (w *World) drawWaypoints(waypoints []Waypoint, drawnWaypoints map[string]in
 	}
 }
 
-func (w *World) DrawSettingsWindow() {
+func (w *World) DrawSettingsWindow(r Renderer, eventStream *EventStream) {
 	if !w.showSettings {
 		return
 	}
 
-	imgui.BeginV("Settings", &w.showSettings, imgui.WindowFlagsAlwaysAutoResize)
+	imgui.BeginV(Tr("Settings")+"###Settings", &w.showSettings, imgui.WindowFlagsAlwaysAutoResize)
 
-	if imgui.SliderFloatV("Simulation speed", &w.SimRate, 1, 20, "%.1f", 0) {
+	if imgui.SliderFloatV(Tr("Simulation speed"), &w.SimRate, 1, 20, "%.1f", 0) {
 		w.SetSimRate(w.SimRate)
 	}
 
 	update := !globalConfig.InhibitDiscordActivity.Load()
-	imgui.Checkbox("Update Discord activity status", &update)
+	imgui.Checkbox(Tr("Update Discord activity status"), &update)
 	globalConfig.InhibitDiscordActivity.Store(!update)
+	uiStartDisable(!update)
+	private := globalConfig.PrivateDiscordActivity.Load()
+	imgui.Checkbox(Tr("Hide position, frequency, and traffic in Discord activity"), &private)
+	globalConfig.PrivateDiscordActivity.Store(private)
+	uiEndDisable(!update)
 
-	if imgui.BeginComboV("UI Font Size", strconv.Itoa(globalConfig.UIFontSize), imgui.ComboFlagsHeightLarge) {
+	if imgui.BeginComboV(Tr("UI Font Size"), strconv.Itoa(globalConfig.UIFontSize), imgui.ComboFlagsHeightLarge) {
 		sizes := make(map[int]interface{})
 		for fontid := range fonts {
 			if fontid.Name == "Roboto Regular" {
@@ -1684,23 +1890,39 @@ func (w *World) DrawSettingsWindow() {
 
 	imgui.Separator()
 
-	if imgui.CollapsingHeader("Audio") {
+	drawLanguageUI()
+
+	if imgui.CollapsingHeader(Tr("Audio")) {
 		globalConfig.Audio.DrawUI()
 	}
-	if imgui.CollapsingHeader("Display") {
-		if imgui.Checkbox("Enable anti-aliasing", &globalConfig.EnableMSAA) {
+	if imgui.CollapsingHeader(Tr("Display")) {
+		if imgui.Checkbox(Tr("Enable anti-aliasing"), &globalConfig.EnableMSAA) {
 			uiShowModalDialog(NewModalDialogBox(
 				&MessageModalClient{
-					title: "Alert",
-					message: "You must restart vice for changes to the anti-aliasing " +
-						"mode to take effect.",
+					title:   "Alert",
+					message: "You must restart vice for changes to the anti-aliasing mode to take effect.",
 				}), true)
 		}
 
-		imgui.Checkbox("Start in full-screen", &globalConfig.StartInFullScreen)
+		globalConfig.UITheme.DrawUI()
+
+		imgui.Checkbox(Tr("Start in full-screen"), &globalConfig.StartInFullScreen)
+		imgui.Checkbox(Tr("Touchscreen mode"), &globalConfig.TouchMode)
+		if imgui.IsItemHovered() {
+			imgui.SetTooltip(Tr("Drag to pan, pinch to zoom, and long-press to select aircraft"))
+		}
+
+		fps := int32(globalConfig.FrameRateLimit)
+		if imgui.SliderIntV(Tr("Frame rate limit"), &fps, 0, 240, Tr(Select(fps == 0, "Unlimited", "%d fps")), 0) {
+			globalConfig.FrameRateLimit = int(fps)
+		}
+		idle := !globalConfig.DisableIdleFrameRate
+		if imgui.Checkbox(Tr("Reduce frame rate when idle"), &idle) {
+			globalConfig.DisableIdleFrameRate = !idle
+		}
 
 		monitorNames := platform.GetAllMonitorNames()
-		if imgui.BeginComboV("Monitor", monitorNames[globalConfig.FullScreenMonitor], imgui.ComboFlagsHeightLarge) {
+		if imgui.BeginComboV(Tr("Monitor"), monitorNames[globalConfig.FullScreenMonitor], imgui.ComboFlagsHeightLarge) {
 			for index, monitor := range monitorNames {
 				if imgui.SelectableV(monitor, monitor == monitorNames[globalConfig.FullScreenMonitor], 0, imgui.Vec2{}) {
 					globalConfig.FullScreenMonitor = index
@@ -1712,12 +1934,77 @@ func (w *World) DrawSettingsWindow() {
 			imgui.EndCombo()
 		}
 	}
-	if fsp != nil && imgui.CollapsingHeader("Flight Strips") {
+	if imgui.CollapsingHeader(Tr("Fonts")) {
+		globalConfig.UserFonts.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Keyboard Shortcuts")) {
+		globalConfig.KeyBindings.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Input Devices")) {
+		globalConfig.HardwareInput.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Network")) {
+		interval := int32(max(globalConfig.RemoteUpdateInterval, MinRemoteUpdateInterval))
+		if imgui.SliderIntV(Tr("Multi-controller update interval"), &interval, MinRemoteUpdateInterval,
+			MaxRemoteUpdateInterval, "%d s", 0) {
+			globalConfig.RemoteUpdateInterval = int(interval)
+		}
+		imgui.Text(Tr("Longer intervals use less bandwidth; aircraft positions are extrapolated between updates."))
+	}
+	if imgui.CollapsingHeader(Tr("Timelapse Recording")) {
+		if w.timelapse == nil {
+			w.timelapse = &TimelapseRecorder{}
+		}
+		w.timelapse.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Screenshots")) {
+		globalConfig.Screenshots.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Streamer Mode")) {
+		globalConfig.StreamerMode.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Webhooks")) {
+		globalConfig.Webhooks.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Live Traffic")) {
+		w.drawLiveTrafficUI()
+		imgui.Separator()
+		globalConfig.Retention.DrawUI()
+	}
+	if fsp != nil && imgui.CollapsingHeader(Tr("Flight Strips")) {
 		fsp.DrawUI()
 	}
-	if messages != nil && imgui.CollapsingHeader("Messages") {
+	if messages != nil && imgui.CollapsingHeader(Tr("Messages")) {
 		messages.DrawUI()
 	}
+	if imgui.CollapsingHeader(Tr("Configuration")) {
+		autosave := !globalConfig.DisableSessionAutosave
+		if imgui.Checkbox(Tr("Autosave the session for crash recovery"), &autosave) {
+			globalConfig.DisableSessionAutosave = !autosave
+		}
+		imgui.Separator()
+		globalConfig.Sync.DrawUI(w, r, eventStream)
+	}
+	if imgui.CollapsingHeader(Tr("Diagnostics")) {
+		globalConfig.CrashReports.DrawUI()
+	}
+	if imgui.CollapsingHeader(Tr("Panes")) {
+		wmPaneCheckbox(Tr("Runway configuration"), NewRunwayConfigPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Session statistics"), NewSessionStatsPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Reminders"), NewRemindersPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Clock"), NewClockPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Converging timeline"), NewTimelinePane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Departures"), NewDeparturePane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Arrivals"), NewArrivalPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Coordination"), NewCoordinationPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Controllers"), NewControllerPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Crossing restrictions"), NewCrossingRestrictionPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Airport diagram"), NewAirportDiagramPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Holds"), NewHoldsPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Speed advisory"), NewSpeedAdvisoryPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Sector load"), NewSectorLoadPane, w, r, eventStream)
+		wmPaneCheckbox(Tr("Log viewer"), NewLogViewerPane, w, r, eventStream)
+	}
 
 	imgui.End()
 }